@@ -0,0 +1,65 @@
+// Package main provides helixctl, a command-line companion for operating a running HelixOps agent.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"helixops/internal/capture"
+	"helixops/internal/httpx"
+)
+
+const usage = `Usage: helixctl <command> [flags]
+
+Commands:
+  replay [-url URL] <file>...   re-send captured webhook payloads (see alerting.capture)
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "replay":
+		os.Exit(replay(os.Args[2:]))
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "helixctl: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// replay POSTs each captured payload file to the webhook URL in order and reports the outcome of each.
+func replay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	url := fs.String("url", "http://localhost:8080/webhook", "webhook URL; use /webhook/<receiver> for a receiver's captures")
+	timeout := fs.Duration("timeout", 30*time.Second, "request timeout")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "helixctl replay: at least one capture file is required")
+		return 2
+	}
+
+	client := httpx.NewClient(*timeout)
+	failed := 0
+	for _, path := range fs.Args() {
+		resp, err := capture.Replay(context.Background(), client, *url, path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %s\n", path, strings.TrimSpace(resp))
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
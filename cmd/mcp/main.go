@@ -2,11 +2,13 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"log/slog"
 	
 	"github.com/mark3labs/mcp-go/server"
 	"helixops/internal/config"
+	"helixops/internal/logging"
 	mcpsrv "helixops/internal/mcp"
 	"helixops/internal/orchestrator"
 	"helixops/internal/analyzer"
@@ -17,15 +19,38 @@ import (
 )
 
 func main() {
-	cfg, err := config.Load()
+	configPath := flag.String("config", "", "config file to load (default: $"+config.ConfigFileEnv+", else config.yaml in ., ./config, or /etc/helixops)")
+	flag.Parse()
+
+	cfg, err := config.LoadFile(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	if _, err := logging.Setup(cfg.App); err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+
 	// Initialize the minimal set of clients required to run the MCP tools.
-	promClient := prometheus.NewClient(cfg.Prometheus.URL, cfg.Prometheus.GetTimeoutDuration())
-	githubClient := github.NewClient(cfg.GitHub.APIURL, cfg.GitHub.Token)
-	lokiClient := loki.NewClient(cfg.Loki.URL, cfg.Loki.GetTimeoutDuration())
+	promClient, err := prometheus.New(cfg.Prometheus.URL, cfg.Prometheus.GetTimeoutDuration())
+	if err != nil {
+		log.Fatalf("Failed to create Prometheus client: %v", err)
+	}
+	promClient.UseStatusBreakdownQuery(cfg.Prometheus.StatusBreakdownQuery)
+	promClient.UseHeaders(cfg.Prometheus.Headers)
+	githubClient, err := github.New(cfg.GitHub.APIURL, cfg.GitHub.Token)
+	if err != nil {
+		log.Fatalf("Failed to create GitHub client: %v", err)
+	}
+	githubClient.AllowRepos(cfg.GitHub.AllowedRepos)
+	lokiClient, err := loki.New(cfg.Loki.URL, cfg.Loki.GetTimeoutDuration())
+	if err != nil {
+		log.Fatalf("Failed to create Loki client: %v", err)
+	}
+	lokiClient.UseHeaders(cfg.Loki.Headers)
+	lokiClient.UseConcurrencyLimit(cfg.Loki.Concurrency.GetMaxQueries(), cfg.Loki.Concurrency.GetQueueTimeoutDuration())
+	lokiClient.UseQuerySplitting(cfg.Loki.GetMaxQueryWindowDuration())
+	lokiClient.UseRetries(cfg.Loki.GetRetries(), cfg.Loki.GetRetryBackoffDuration())
 
 	llmProvider, err := llm.NewProvider(cfg.LLM)
 	if err != nil {
@@ -33,7 +58,7 @@ func main() {
 	}
 
 	orch := orchestrator.New(promClient, githubClient, lokiClient, nil, cfg)
-	anlz := analyzer.New(llmProvider)
+	anlz := analyzer.New(llmProvider, cfg.Analysis)
 
 	// Initialize the core MCP server instance.
 	s := server.NewMCPServer(
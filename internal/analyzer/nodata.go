@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"helixops/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// dataSource is a built-in context source whose results the analyzer can check for evidence.
+type dataSource struct {
+	name  string // as shown to responders
+	empty func(ac *models.AnalysisContext) bool
+}
+
+// dataSources are keyed by collector name, as recorded in AnalysisContext.Sources and SourceErrors.
+var dataSources = map[string]dataSource{
+	"metrics": {"Prometheus", func(ac *models.AnalysisContext) bool {
+		m := ac.Metrics
+		return m.LatencyP99 == 0 && m.LatencyAvg == 0 && m.ErrorRate == 0 && m.RPS == 0 && m.MemoryUsage == 0 && len(m.StatusBreakdown) == 0
+	}},
+	"logs":    {"Loki", func(ac *models.AnalysisContext) bool { return len(ac.ErrorLogs) == 0 }},
+	"commits": {"GitHub", func(ac *models.AnalysisContext) bool { return len(ac.RecentCommits) == 0 }},
+	"traces": {"Tempo", func(ac *models.AnalysisContext) bool {
+		return ac.Traces.TraceCount == 0 && len(ac.Traces.SlowSpans) == 0 && len(ac.Traces.ErrorSpans) == 0
+	}},
+}
+
+// emptySources describes every queried source that contributed nothing, with the error it failed with
+// if any, and reports whether all of them came back empty. A custom collector only counts as empty when
+// it failed, since its data cannot be inspected; a context with no queried sources is never all empty.
+func emptySources(ac *models.AnalysisContext) (empty []string, all bool) {
+	for _, collector := range ac.Sources {
+		err := ac.SourceErrors[collector]
+		name := collector
+		if s, ok := dataSources[collector]; ok {
+			if !s.empty(ac) {
+				continue
+			}
+			name = s.name
+		} else if err == "" {
+			continue
+		}
+
+		if err != "" {
+			empty = append(empty, fmt.Sprintf("%s (error: %s)", name, err))
+		} else {
+			empty = append(empty, name+" (no data)")
+		}
+	}
+	return empty, len(ac.Sources) > 0 && len(empty) == len(ac.Sources)
+}
+
+// insufficientDataResult reports that no source returned evidence instead of asking the LLM, which would
+// otherwise invent a plausible but baseless root cause. It is flagged for a human.
+func (a *Analyzer) insufficientDataResult(ac *models.AnalysisContext, checked []string) *models.AnalysisResult {
+	return &models.AnalysisResult{
+		ID:          uuid.New().String(),
+		ServiceName: ac.ServiceName,
+		AlertName:   ac.Alert.Name,
+		Severity:    ac.Alert.Severity,
+		Summary:     ac.Alert.Summary,
+		RootCause: "INSUFFICIENT DATA: no context source returned evidence, so no root cause was inferred. Checked: " +
+			strings.Join(checked, ", ") + ".",
+		Confidence: "n/a",
+		NextSteps: []string{
+			"Check that HelixOps can reach each source listed above",
+			"Check that the alert's service label matches the service's metrics, logs, and repository mapping",
+		},
+		AnalyzedAt:   time.Now(),
+		Language:     a.language,
+		Dashboards:   ac.Dashboards,
+		Triage:       TriageNeedsHuman,
+		TriageReason: "no context source returned data",
+	}
+}
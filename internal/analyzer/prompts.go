@@ -0,0 +1,234 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/clients/tempo"
+	"helixops/internal/config"
+	"helixops/internal/models"
+)
+
+// promptTemplates holds every RCA prompt. The rapid and context prompts share the same role,
+// constraints, output format, and alert block so both paths request an identical response schema.
+var promptTemplates = template.Must(template.New("prompts").Funcs(template.FuncMap{
+	"pct":     func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"ms":      func(v float64) string { return fmt.Sprintf("%.2fms", v) },
+	"num":     func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"rfc3339": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"add":     func(a, b int) int { return a + b },
+	"tooling": platformTooling,
+}).Parse(`
+{{- define "preamble"}}
+### ROLE
+You are the Lead SRE Investigator for HelixOps. Your mission is to perform a high-fidelity Root Cause Analysis (RCA) based on provided Telemetry Context{{if .WithTelemetry}} (Metrics, Logs, and Git Commits){{end}}.
+
+### OPERATIONAL CONSTRAINTS
+1. EVIDENCE-ONLY: Never assume a cause. Every claim must be backed by a specific log entry, a metric spike, or a code diff provided in the context.
+2. ADMIT IGNORANCE: If the provided data is insufficient to identify the root cause, state "INSUFFICIENT DATA" and list specifically what is missing.
+3. NO HALLUCINATION: Do not invent service names, error codes, or timestamps. Use only what is in the prompt context.
+{{template "format"}}
+{{- with .Platform}}
+### PLATFORM
+The services run on {{tooling .}}. Write every command under Recommended Action for that platform, and do not suggest commands for other platforms.
+{{end}}
+{{- with .Language}}
+### LANGUAGE
+Write all prose in {{.}}. Keep the Markdown headings, the bold field labels (Confidence Score, Status, Assessed Severity), and the severity values exactly as shown above in English so the response can be parsed.
+{{end}}
+---
+TELEMETRY CONTEXT:
+{{end}}
+
+{{- define "format"}}
+### OUTPUT FORMAT (Markdown)
+Your response must strictly follow this structure:
+
+# Incident Analysis: [Brief Title]
+**Confidence Score:** [0-100%]
+**Status:** [Confirmed / Probable / Inconclusive]
+**Assessed Severity:** [critical / warning / info] (your judgement of the actual impact, which may differ from the alert's severity)
+
+## 1. Executive Summary
+[A 2-sentence summary of what happened and the immediate impact.]
+
+## 2. Evidence Trail
+- **Metric Spike:** [Describe metric change and timestamp]
+- **Key Log Entry:** [Quote the specific log line]
+- **Suspect Commit:** [Commit Hash/Author] - [Briefly explain the link]
+
+## 3. Root Cause Analysis
+[Detailed explanation of the failure chain.]
+
+## 4. Recommended Action
+- [Immediate Mitigation Step]
+- [Long-term Prevention Step]
+{{end}}
+
+{{- define "alert"}}
+ALERT:
+- Service: {{.ServiceName}}
+- Alert Name: {{.Alert.Name}}
+- Severity: {{.Alert.Severity}}
+- Started: {{rfc3339 .Alert.StartedAt}}
+{{- if not .Alert.EndsAt.IsZero}}
+- Ends: {{rfc3339 .Alert.EndsAt}}
+{{- end}}
+{{- if .Alert.Fingerprint}}
+- Fingerprint: {{.Alert.Fingerprint}}
+{{- end}}
+- Summary: {{.Alert.Summary}}
+{{- with .Labels}}
+
+LABELS:
+{{- range .}}
+- {{.Key}}: {{.Value}}
+{{- end}}
+{{- end}}
+{{- with .Annotations}}
+
+ANNOTATIONS:
+{{- range .}}
+- {{.Key}}: {{.Value}}
+{{- end}}
+{{- end}}
+{{- with .Hints}}
+
+OPERATOR-PROVIDED HINTS (institutional knowledge from configuration, not telemetry; use them to guide the investigation but confirm or refute them with the evidence, and do not cite them as evidence):
+{{- range .}}
+- {{.}}
+{{- end}}
+{{- end}}
+{{end}}
+
+{{- define "rapid"}}
+{{- template "preamble" .}}{{template "alert" .}}
+{{- end}}
+
+{{- define "context"}}
+{{- template "preamble" .}}{{template "alert" .}}
+{{- with .EmptySources}}
+MISSING DATA: these sources returned nothing: {{.}}. Treat this as missing evidence, not as a sign of health, and do not infer causes from it; say "INSUFFICIENT DATA" if what remains cannot support a root cause.
+
+{{end -}}
+METRICS:
+{{- with .MetricsTargets}}{{if .Degraded}}
+- WARNING: metrics may be incomplete: target down ({{.Down}} of {{add .Up .Down}} scrape targets down); missing or low values are not evidence of health
+{{- end}}{{end}}
+{{- with .StaleFor}}
+- WARNING: metrics are stale: the newest sample is {{.}} old, so these values may predate the incident and are weak evidence
+{{- end}}
+- Latency P99: {{ms .Metrics.LatencyP99}}
+- Error Rate: {{pct .Metrics.ErrorRate}}
+- Requests/sec: {{num .Metrics.RPS}}
+{{- with .StatusBreakdown}}
+- Requests by status code: {{.}}
+{{- end}}
+
+BASELINE:
+- Latency: {{ms .Metrics.BaselineLatency}}
+- Error Rate: {{pct .Metrics.BaselineErrorRate}}
+
+DISTRIBUTED TRACES:
+- P99 Latency: {{ms .Traces.P99Latency}}
+- Slow Spans (>500ms): {{len .Traces.SlowSpans}}
+- Error Spans: {{len .Traces.ErrorSpans}}
+
+{{.Operations}}{{.Spans}}
+RECENT COMMITS ({{len .Commits}} commits):
+{{.CommitList}}
+{{- with .FileChanges}}
+FILES IN STACK TRACES (last commit touching each file; a recent change here is a strong suspect):
+{{.}}
+{{- end}}
+{{- with .Deploys}}
+DEPLOYS (reported by the CD system, oldest first; a deploy shortly before the alert is a strong suspect):
+{{.}}
+{{- end}}
+SUSPECTED CAUSES (pre-computed correlation, ranked; verify against the evidence above):
+{{.Hypotheses}}
+{{- with .PriorIncidents}}
+PRIOR INCIDENTS (same alert on this service, most recent first):
+{{.}}
+{{- end}}
+{{- end}}
+`))
+
+// promptData is the view rendered by promptTemplates.
+type promptData struct {
+	WithTelemetry bool
+	ServiceName   string
+	Alert         models.AlertInfo
+	Labels        []pair // allowlisted alert labels
+	Annotations   []pair // allowlisted alert annotations
+	Metrics       models.MetricsSummary
+	Traces        tempo.TraceContext
+	// MetricsTargets is nil when target health was not checked
+	MetricsTargets *prometheus.TargetHealth
+	Commits        []models.CommitInfo
+	Spans          string
+	Operations     string
+	CommitList     string
+	Hypotheses     string
+	// Language is set only when responses should not be in English
+	Language string
+	// Platform is set only when analysis.platform is configured
+	Platform string
+	// PriorIncidents lists earlier resolved incidents of the same alert, empty when there are none
+	PriorIncidents string
+	// StaleFor is the age of the newest metrics sample when metrics are stale, otherwise empty
+	StaleFor string
+	// StatusBreakdown lists request rates per status code, busiest first, empty when not collected
+	StatusBreakdown string
+	// Hints are the operator-provided prompt hints matching the alert
+	Hints []string
+	// EmptySources lists the context sources that returned no data, empty when all contributed
+	EmptySources string
+	// FileChanges lists the last commit touching each stack trace file, empty when none were looked up
+	FileChanges string
+	// Deploys lists the recorded deploys before the alert, empty when none were reported
+	Deploys string
+}
+
+// platformTooling describes a platform and the CLI its remediation commands use.
+func platformTooling(platform string) string {
+	switch platform {
+	case config.PlatformECS:
+		return "AWS ECS (use `aws ecs` commands; a service belongs to a cluster)"
+	case config.PlatformSystemd:
+		return "bare-metal or VM hosts as systemd units (use `systemctl` and `journalctl` commands)"
+	default:
+		return "Kubernetes (use `kubectl` commands)"
+	}
+}
+
+// pair is a sorted key/value entry for deterministic label rendering.
+type pair struct {
+	Key   string
+	Value string
+}
+
+// allowedPairs keeps only the allowlisted keys that are set, in key order so prompts are stable across runs.
+func allowedPairs(m map[string]string, allow []string) []pair {
+	var pairs []pair
+	for _, k := range allow {
+		if v, ok := m[k]; ok && v != "" {
+			pairs = append(pairs, pair{Key: k, Value: v})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+	return pairs
+}
+
+// renderPrompt executes a named prompt template.
+func renderPrompt(name string, data promptData) (string, error) {
+	var buf bytes.Buffer
+	if err := promptTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -5,11 +5,14 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"helixops/internal/clients/tempo"
+	"helixops/internal/config"
 	"helixops/internal/models"
+	"helixops/internal/remediation"
 	"helixops/pkg/llm"
 
 	"github.com/google/uuid"
@@ -18,103 +21,137 @@ import (
 // Analyzer utilizes an underlying LLM provider to perform Root Cause Analysis on incident data.
 type Analyzer struct {
 	provider llm.Provider
+
+	// allowlists of alert labels and annotations rendered into prompts
+	labels      []string
+	annotations []string
+
+	// language responses are written in; promptLanguage is empty for English
+	language       string
+	promptLanguage string
+	// platform the recommended commands should target; empty leaves the recommendations generic
+	platform string
+
+	// operator-provided hints injected into the prompts of matching alerts
+	hints []config.PromptHint
+
+	// triage settings; Classify runs before each context analysis when enabled
+	triage    bool
+	transient []string
+	rules     *remediation.Engine
+
+	// noAnomaly skips the LLM for contexts without any sign of a problem
+	noAnomaly bool
+
+	// maxPromptTokens rejects larger prompts before they reach the provider; 0 means no cap
+	maxPromptTokens int
 }
 
-// New initializes a new Analyzer with the given LLM provider.
-func New(provider llm.Provider) *Analyzer {
-	return &Analyzer{
-		provider: provider,
+// New initializes a new Analyzer with the given LLM provider and analysis settings.
+func New(provider llm.Provider, cfg config.AnalysisConfig) *Analyzer {
+	a := &Analyzer{
+		provider:    provider,
+		labels:      cfg.GetPromptLabels(),
+		annotations: cfg.GetPromptAnnotations(),
+		language:    cfg.GetLanguage(),
+		hints:       cfg.PromptHints,
+		triage:      cfg.Triage.Enabled,
+		transient:   cfg.Triage.TransientAlerts,
+		rules:       remediation.NewEngineFromConfig(nil, cfg.GetPlatform()),
+		noAnomaly:   cfg.GetNoAnomaly() != config.NoAnomalyAnalyze,
+
+		maxPromptTokens: cfg.MaxPromptTokens,
 	}
+	if !cfg.IsEnglish() {
+		a.promptLanguage = a.language
+	}
+	if strings.TrimSpace(cfg.Platform) != "" {
+		a.platform = cfg.GetPlatform()
+	}
+	return a
 }
 
 // Analyze performs a rapid RCA on a firing alert without full diagnostic context.
 func (a *Analyzer) Analyze(ctx context.Context, alert models.AlertItem) (*models.AnalysisResult, error) {
 	// Build prompt
-	prompt := a.buildPrompt(alert)
+	prompt, err := a.buildPrompt(alert)
+	if err != nil {
+		return nil, err
+	}
 
 	// Call LLM
-	response, err := a.provider.Analyze(ctx, prompt)
+	reply, err := a.complete(ctx, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("LLM analysis failed: %w", err)
 	}
 
-	// Parse response
 	result := &models.AnalysisResult{
 		ID:          uuid.New().String(),
 		ServiceName: alert.GetLabel("service_name"),
 		AlertName:   alert.Labels["alertname"],
 		Severity:    alert.Labels["severity"],
 		Summary:     alert.GetAnnotation("summary"),
-		RootCause:   response,
+		RootCause:   reply.text,
 		Confidence:  "medium",
 		AnalyzedAt:  time.Now(),
+		Language:    a.language,
+
+		AssessedSeverity: reply.assessedSeverity,
+		LLMResponse:      reply.raw,
+
+		PromptChars:           reply.promptChars,
+		EstimatedPromptTokens: reply.promptTokens,
 	}
 
 	return result, nil
 }
 
-// buildPrompt creates the RCA prompt for the LLM
-func (a *Analyzer) buildPrompt(alert models.AlertItem) string {
-	return fmt.Sprintf(`
-### ROLE
-You are the Lead SRE Investigator for HelixOps. Your mission is to perform a high-fidelity Root Cause Analysis (RCA) based on provided Telemetry Context.
-
-### OPERATIONAL CONSTRAINTS
-1. EVIDENCE-ONLY: Never assume a cause. Every claim must be backed by a specific log entry, a metric spike, or a code diff provided in the context.
-2. ADMIT IGNORANCE: If the provided data is insufficient to identify the root cause, state "INSUFFICIENT DATA" and list specifically what is missing.
-3. NO HALLUCINATION: Do not invent service names, error codes, or timestamps. Use only what is in the prompt context.
-
-### OUTPUT FORMAT (Markdown)
-Your response must strictly follow this structure:
-
-# Incident Analysis: [Brief Title]
-**Confidence Score:** [0-100%%]
-**Status:** [Confirmed / Probable / Inconclusive]
-
-## 1. Executive Summary
-[A 2-sentence summary of what happened and the immediate impact.]
-
-## 2. Evidence Trail
-- **Metric Spike:** [Describe metric change and timestamp]
-- **Key Log Entry:** [Quote the specific log line]
-- **Suspect Commit:** [Commit Hash/Author] - [Briefly explain the link]
-
-## 3. Root Cause Analysis
-[Detailed explanation of the failure chain.]
-
-## 4. Recommended Action
-- [Immediate Mitigation Step]
-- [Long-term Prevention Step]
-
----
-TELEMETRY CONTEXT:
-
-ALERT:
-- Service: %s
-- Alert Name: %s
-- Severity: %s
-- Started: %s
-- Summary: %s
-`,
-		alert.GetLabel("service_name"),
-		alert.Labels["alertname"],
-		alert.Labels["severity"],
-		alert.StartsAt.Format(time.RFC3339),
-		alert.GetAnnotation("summary"),
-	)
+// buildPrompt creates the rapid RCA prompt from everything present on the alert itself
+func (a *Analyzer) buildPrompt(alert models.AlertItem) (string, error) {
+	info := alert.ToAlertInfo()
+	service := alert.GetLabel("service_name")
+	return renderPrompt("rapid", promptData{
+		Language:    a.promptLanguage,
+		Platform:    a.platform,
+		ServiceName: service,
+		Alert:       info,
+		Labels:      allowedPairs(info.Labels, a.labels),
+		Annotations: allowedPairs(info.Annotations, a.annotations),
+		Hints:       a.hintsFor(info.Name, service),
+	})
 }
 
 // AnalyzeWithContext performs a comprehensive RCA utilizing metrics, distributed traces, logs, and recent code commits.
+// With triage enabled, alerts classified auto_resolved are returned without calling the LLM, as are
+// contexts without any sign of a problem when analysis.no_anomaly is notify or suppress.
 func (a *Analyzer) AnalyzeWithContext(ctx context.Context, ctxData *models.AnalysisContext) (*models.AnalysisResult, error) {
-	prompt := a.buildContextPrompt(ctxData)
+	var triage Triage
+	if a.triage {
+		triage = a.Classify(ctxData)
+		if triage.Label == TriageAutoResolved {
+			return triagedResult(ctxData, triage), nil
+		}
+	}
+
+	if empty, all := emptySources(ctxData); all {
+		return a.insufficientDataResult(ctxData, empty), nil
+	}
+	if a.noAnomaly && noAnomaly(ctxData) {
+		return noAnomalyResult(ctxData), nil
+	}
 
-	response, err := a.provider.Analyze(ctx, prompt)
+	prompt, err := a.buildContextPrompt(ctxData)
 	if err != nil {
-		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+		return nil, err
 	}
 
-	// Parse JSON response to extract structured data
-	rootCause, confidence, nextSteps := parseLLMResponse(response)
+	reply, err := a.complete(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("LLM analysis failed: %w", err)
+	}
+	if ctxData.MetricsStale {
+		reply.confidence = reduceConfidence(reply.confidence)
+	}
 
 	result := &models.AnalysisResult{
 		ID:          uuid.New().String(),
@@ -122,17 +159,52 @@ func (a *Analyzer) AnalyzeWithContext(ctx context.Context, ctxData *models.Analy
 		AlertName:   ctxData.Alert.Name,
 		Severity:    ctxData.Alert.Severity,
 		Summary:     ctxData.Alert.Summary,
-		RootCause:   rootCause,
+		RootCause:   reply.rootCause,
 		Metrics:     ctxData.Metrics,
 		Commits:     ctxData.RecentCommits,
-		Confidence:  confidence,
-		NextSteps:   nextSteps,
+		Confidence:  reply.confidence,
+		NextSteps:   reply.nextSteps,
 		AnalyzedAt:  time.Now(),
+		BlastRadius: ctxData.BlastRadius,
+		Language:    a.language,
+
+		AssessedSeverity: reply.assessedSeverity,
+		Triage:           triage.Label,
+		TriageReason:     triage.Reason,
+		PriorIncidents:   len(ctxData.PriorIncidents),
+		LLMResponse:      reply.raw,
+
+		PromptChars:           reply.promptChars,
+		EstimatedPromptTokens: reply.promptTokens,
+
+		Dashboards: ctxData.Dashboards,
 	}
 
 	return result, nil
 }
 
+// assessedSeverityRe captures the value of the model's severity reassessment line.
+var assessedSeverityRe = regexp.MustCompile(`(?i)\*\*Assessed Severity:\*\*\s*(.+)`)
+
+// parseAssessedSeverity returns the model's reassessed severity, or "" when it gave none, echoed
+// the template's list of choices, or answered outside critical, warning, and info.
+func parseAssessedSeverity(response string) string {
+	match := assessedSeverityRe.FindStringSubmatch(response)
+	if len(match) < 2 || strings.Contains(match[1], "/") {
+		return ""
+	}
+	fields := strings.Fields(match[1])
+	if len(fields) == 0 {
+		return ""
+	}
+	value := strings.ToLower(strings.Trim(fields[0], "[]*.,"))
+	switch value {
+	case "critical", "warning", "info":
+		return value
+	}
+	return ""
+}
+
 // parseLLMResponse extracts structured data from the Markdown response
 func parseLLMResponse(response string) (rootCause, confidence string, nextSteps []string) {
 	confidence = "medium"
@@ -166,84 +238,134 @@ func parseLLMResponse(response string) (rootCause, confidence string, nextSteps
 }
 
 // buildContextPrompt creates a detailed RCA prompt with metrics and commits
-func (a *Analyzer) buildContextPrompt(ctx *models.AnalysisContext) string {
-	return fmt.Sprintf(`
-### ROLE
-You are the Lead SRE Investigator for HelixOps. Your mission is to perform a high-fidelity Root Cause Analysis (RCA) based on provided Telemetry Context (Metrics, Logs, and Git Commits).
-
-### OPERATIONAL CONSTRAINTS
-1. EVIDENCE-ONLY: Never assume a cause. Every claim must be backed by a specific log entry, a metric spike, or a code diff provided in the context.
-2. ADMIT IGNORANCE: If the provided data is insufficient to identify the root cause, state "INSUFFICIENT DATA" and list specifically what is missing.
-3. NO HALLUCINATION: Do not invent service names, error codes, or timestamps. Use only what is in the prompt context.
-
-### OUTPUT FORMAT (Markdown)
-Your response must strictly follow this structure:
-
-# Incident Analysis: [Brief Title]
-**Confidence Score:** [0-100%%]
-**Status:** [Confirmed / Probable / Inconclusive]
-
-## 1. Executive Summary
-[A 2-sentence summary of what happened and the immediate impact.]
-
-## 2. Evidence Trail
-- **Metric Spike:** [Describe metric change and timestamp]
-- **Key Log Entry:** [Quote the specific log line]
-- **Suspect Commit:** [Commit Hash/Author] - [Briefly explain the link]
-
-## 3. Root Cause Analysis
-[Detailed explanation of the failure chain.]
-
-## 4. Recommended Action
-- [Immediate Mitigation Step]
-- [Long-term Prevention Step]
-
----
-TELEMETRY CONTEXT:
-
-ALERT:
-- Service: %s
-- Alert Name: %s
-- Severity: %s
-- Started: %s
-- Summary: %s
-
-METRICS:
-- Latency P99: %.2fms
-- Error Rate: %.2f%%
-- Requests/sec: %.2f
-
-BASELINE:
-- Latency: %.2fms
-- Error Rate: %.2f%%
-
-DISTRIBUTED TRACES:
-- P99 Latency: %.2fms
-- Slow Spans (>500ms): %d
-- Error Spans: %d
-
-%s
-
-RECENT COMMITS (%d commits):
-%s
-`,
-		ctx.ServiceName,
-		ctx.Alert.Name,
-		ctx.Alert.Severity,
-		ctx.Alert.StartedAt.Format(time.RFC3339),
-		ctx.Alert.Summary,
-		ctx.Metrics.LatencyP99,
-		ctx.Metrics.ErrorRate*100,
-		ctx.Metrics.RPS,
-		ctx.Metrics.BaselineLatency,
-		ctx.Metrics.BaselineErrorRate*100,
-		ctx.Traces.P99Latency,
-		len(ctx.Traces.SlowSpans),
-		len(ctx.Traces.ErrorSpans),
-		formatSpans(ctx.Traces.SlowSpans),
-		len(ctx.RecentCommits),
-		formatCommits(ctx.RecentCommits),
-	)
+// and the queried sources that returned nothing, so the model does not read their absence as evidence
+func (a *Analyzer) buildContextPrompt(ctx *models.AnalysisContext) (string, error) {
+	empty, _ := emptySources(ctx)
+	return renderPrompt("context", promptData{
+		WithTelemetry:  true,
+		Language:       a.promptLanguage,
+		Platform:       a.platform,
+		ServiceName:    ctx.ServiceName,
+		Alert:          ctx.Alert,
+		Labels:         allowedPairs(ctx.Alert.Labels, a.labels),
+		Annotations:    allowedPairs(ctx.Alert.Annotations, a.annotations),
+		Hints:          a.hintsFor(ctx.Alert.Name, ctx.ServiceName),
+		Metrics:        ctx.Metrics,
+		Traces:         ctx.Traces,
+		MetricsTargets: ctx.MetricsTargets,
+		Commits:        ctx.RecentCommits,
+		Spans:          formatSpans(ctx.Traces.SlowSpans),
+		Operations:     formatOperations(ctx.Traces.OperationStats),
+		CommitList:     formatCommits(ctx.RecentCommits),
+		FileChanges:    formatFileChanges(ctx.FileChanges),
+		Deploys:        formatDeploys(ctx.DeployEvents, ctx.Alert.StartedAt),
+		Hypotheses:     formatHypotheses(ctx.SuspectedCauses),
+		StaleFor:       staleFor(ctx),
+		PriorIncidents: formatPriorIncidents(ctx.PriorIncidents),
+
+		StatusBreakdown: formatStatusBreakdown(ctx.Metrics.StatusBreakdown),
+		EmptySources:    strings.Join(empty, ", "),
+	})
+}
+
+// hintsFor returns the configured hints matching an alert, in configuration order.
+func (a *Analyzer) hintsFor(alertName, service string) []string {
+	var hints []string
+	for _, h := range a.hints {
+		if h.Matches(alertName, service) {
+			hints = append(hints, strings.TrimSpace(h.Hint))
+		}
+	}
+	return hints
+}
+
+// formatStatusBreakdown renders per-status request rates busiest first, with each code's share of traffic.
+func formatStatusBreakdown(breakdown map[string]float64) string {
+	var total float64
+	codes := make([]string, 0, len(breakdown))
+	for code, rate := range breakdown {
+		total += rate
+		codes = append(codes, code)
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if breakdown[codes[i]] != breakdown[codes[j]] {
+			return breakdown[codes[i]] > breakdown[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%s %.2f/s (%.1f%%)", code, breakdown[code], breakdown[code]/total*100)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// staleFor describes how old stale metrics are, or returns "" when they are fresh.
+func staleFor(ctx *models.AnalysisContext) string {
+	if !ctx.MetricsStale {
+		return ""
+	}
+	return time.Since(ctx.MetricsLastSample).Round(time.Minute).String()
+}
+
+// maxStaleConfidence caps the confidence of an RCA built on stale metrics.
+const maxStaleConfidence = 50
+
+// reduceConfidence lowers a parsed confidence because the metrics behind it are stale: percentages
+// are capped at maxStaleConfidence and word ratings drop one level.
+func reduceConfidence(confidence string) string {
+	var pct float64
+	if _, err := fmt.Sscanf(strings.TrimSpace(confidence), "%f%%", &pct); err == nil {
+		if pct > maxStaleConfidence {
+			pct = maxStaleConfidence
+		}
+		return fmt.Sprintf("%.0f%% (metrics stale)", pct)
+	}
+	switch strings.ToLower(strings.TrimSpace(confidence)) {
+	case "high":
+		return "medium (metrics stale)"
+	case "medium":
+		return "low (metrics stale)"
+	}
+	return confidence + " (metrics stale)"
+}
+
+// formatHypotheses formats ranked suspected causes for the prompt
+func formatHypotheses(hypotheses []models.Hypothesis) string {
+	if len(hypotheses) == 0 {
+		return "No correlated suspects."
+	}
+
+	result := ""
+	for i, h := range hypotheses {
+		result += fmt.Sprintf("%d. %s (score %.2f)\n", i+1, h.Cause, h.Score)
+		for _, e := range h.Evidence {
+			result += fmt.Sprintf("   - %s\n", e)
+		}
+	}
+	return result
+}
+
+// formatPriorIncidents flags a recurrence and lists the earlier incidents' root causes for the prompt
+func formatPriorIncidents(prior []models.PriorIncident) string {
+	if len(prior) == 0 {
+		return ""
+	}
+
+	result := fmt.Sprintf("RECURRENCE: this alert was resolved %d time(s) before on this service. Check whether the earlier fix regressed or was incomplete.\n", len(prior))
+	for _, p := range prior {
+		rootCause := strings.Join(strings.Fields(p.RootCause), " ")
+		if rootCause == "" {
+			rootCause = "root cause not recorded"
+		}
+		result += fmt.Sprintf("- %s (resolved after %s): %s\n", p.StartedAt.Format(time.RFC3339),
+			p.ResolvedAt.Sub(p.StartedAt).Round(time.Minute), truncate(rootCause, 300))
+	}
+	return result
 }
 
 // formatCommits formats commits for the prompt
@@ -257,7 +379,34 @@ func formatCommits(commits []models.CommitInfo) string {
 		if i >= 10 {
 			break
 		}
-		result += fmt.Sprintf("- %s: %s (by %s)\n", c.SHA[:7], truncate(c.Message, 50), c.Author)
+		result += fmt.Sprintf("- %s: %s (by %s)\n", models.ShortSHA(c.SHA), truncate(c.Message, 50), c.Author)
+	}
+	return result
+}
+
+// formatFileChanges formats the last change to each stack trace file for the prompt
+func formatFileChanges(changes []models.FileChange) string {
+	result := ""
+	for _, f := range changes {
+		location := f.Path
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+		}
+		result += fmt.Sprintf("- %s: last changed in %s at %s: %s (by %s)\n", location, models.ShortSHA(f.Commit.SHA),
+			f.Commit.Timestamp.Format(time.RFC3339), truncate(f.Commit.Message, 50), f.Commit.Author)
+	}
+	return result
+}
+
+// formatDeploys places each recorded deploy relative to the alert for the prompt
+func formatDeploys(deploys []models.DeployEvent, alertTime time.Time) string {
+	result := ""
+	for _, d := range deploys {
+		line := fmt.Sprintf("- deploy %s at %s", d.Label(), d.Time.UTC().Format("15:04 UTC"))
+		if !alertTime.IsZero() {
+			line += fmt.Sprintf(", alert at %s (%s later)", alertTime.UTC().Format("15:04 UTC"), alertTime.Sub(d.Time).Round(time.Minute))
+		}
+		result += line + "\n"
 	}
 	return result
 }
@@ -270,6 +419,22 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
+// formatOperations formats the worst span operations for the prompt
+func formatOperations(stats []tempo.OperationStats) string {
+	if len(stats) == 0 {
+		return ""
+	}
+
+	result := "TOP OPERATIONS (by errors, then p99):\n"
+	for i, s := range stats {
+		if i >= 5 {
+			break
+		}
+		result += fmt.Sprintf("- %s: %d spans, p99 %.0fms, %d errors\n", s.Operation, s.Count, s.P99Ms, s.ErrorCount)
+	}
+	return result + "\n"
+}
+
 // formatSpans formats spans for the prompt
 func formatSpans(spans []tempo.Span) string {
 	if len(spans) == 0 {
@@ -0,0 +1,451 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/clients/tempo"
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleResponse = `# Incident Analysis: Connection pool exhaustion
+**Confidence Score:** 85%
+**Status:** Probable
+
+## 1. Executive Summary
+Checkout latency spiked after a deploy.
+
+## 3. Root Cause Analysis
+Pool size was reduced in abc1234.
+
+## 4. Recommended Action
+- Roll back abc1234
+- Add a pool saturation alert
+`
+
+func sampleContext() *models.AnalysisContext {
+	return &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert: models.AlertInfo{
+			Name:      "HighLatency",
+			Severity:  "critical",
+			Summary:   "p99 above 1s",
+			StartedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		Metrics: models.MetricsSummary{
+			LatencyP99: 1250,
+			ErrorRate:  0.05,
+			RPS:        42,
+		},
+		RecentCommits: []models.CommitInfo{
+			{SHA: "abc1234def", Message: "Reduce DB pool size", Author: "dev"},
+		},
+		SuspectedCauses: []models.Hypothesis{
+			{Cause: "Regression introduced by commit abc1234", Score: 0.9, Evidence: []string{"Commit abc1234 landed 4m0s before the alert"}},
+		},
+	}
+}
+
+func TestAnalyzeWithContextPromptAndParsing(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	require.Equal(t, 1, fake.CallCount())
+
+	prompt := fake.LastPrompt()
+	assert.Contains(t, prompt, "- Service: checkout")
+	assert.Contains(t, prompt, "- Alert Name: HighLatency")
+	assert.Contains(t, prompt, "- Latency P99: 1250.00ms")
+	assert.Contains(t, prompt, "- Error Rate: 5.00%")
+	assert.Contains(t, prompt, "abc1234: Reduce DB pool size (by dev)")
+	assert.Contains(t, prompt, "1. Regression introduced by commit abc1234 (score 0.90)")
+	assert.Contains(t, prompt, "   - Commit abc1234 landed 4m0s before the alert")
+
+	assert.Equal(t, "checkout", result.ServiceName)
+	assert.Equal(t, "HighLatency", result.AlertName)
+	assert.Equal(t, "85%", result.Confidence)
+	assert.Equal(t, []string{"Roll back abc1234", "Add a pool saturation alert"}, result.NextSteps)
+	assert.Contains(t, result.RootCause, "Pool size was reduced")
+	assert.NotContains(t, result.RootCause, "Recommended Action")
+
+	require.NotNil(t, result.LLMResponse)
+	assert.Equal(t, "fake", result.LLMResponse.Provider)
+	assert.Equal(t, sampleResponse, result.LLMResponse.Response)
+}
+
+func TestAnalyzeWithContextProviderError(t *testing.T) {
+	fake := llm.NewFakeProvider()
+	fake.Err = errors.New("boom")
+	a := New(fake, config.AnalysisConfig{})
+
+	_, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LLM analysis failed")
+}
+
+func TestBlankReplyIsAnError(t *testing.T) {
+	fake := llm.NewFakeProvider("  \n")
+	a := New(fake, config.AnalysisConfig{})
+
+	_, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.ErrorIs(t, err, llm.ErrEmptyResponse, "a blank reply must not become an empty root cause")
+}
+
+func TestPromptSizeIsReportedOnResult(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.Equal(t, len([]rune(fake.LastPrompt())), result.PromptChars)
+	assert.Equal(t, llm.EstimateTokens(fake.LastPrompt()), result.EstimatedPromptTokens)
+	assert.Greater(t, result.EstimatedPromptTokens, 0)
+}
+
+func TestPromptOverTokenCapIsRejected(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{MaxPromptTokens: 50})
+
+	_, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.ErrorIs(t, err, ErrPromptTooLarge)
+	assert.Contains(t, err.Error(), "limit 50")
+	assert.Zero(t, fake.CallCount(), "an over-cap prompt is never sent")
+}
+
+func TestProviderWithoutJSONModeUsesTextParsing(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.Zero(t, fake.JSONCallCount(), "a provider reporting no JSON mode is never asked for JSON")
+	assert.NotContains(t, fake.LastPrompt(), "### JSON RESPONSE")
+	assert.Equal(t, "85%", result.Confidence)
+	assert.Equal(t, []string{"Roll back abc1234", "Add a pool saturation alert"}, result.NextSteps)
+}
+
+func TestProviderWithJSONModeUsesStructuredOutput(t *testing.T) {
+	fake := llm.NewFakeProvider(`{"analysis": "# Incident Analysis: Pool exhaustion\nPool size was reduced in abc1234.", "confidence": "90%", "assessed_severity": "Warning", "next_steps": ["Roll back abc1234"]}`)
+	fake.Caps = llm.Capabilities{JSONMode: true}
+	a := New(fake, config.AnalysisConfig{})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.JSONCallCount())
+	assert.Contains(t, fake.LastPrompt(), "### JSON RESPONSE")
+	assert.Equal(t, "90%", result.Confidence)
+	assert.Equal(t, "warning", result.AssessedSeverity)
+	assert.Equal(t, []string{"Roll back abc1234"}, result.NextSteps)
+	assert.Contains(t, result.RootCause, "Pool size was reduced")
+}
+
+func TestJSONModeFallsBackToTextWhenReplyIsNotJSON(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	fake.Caps = llm.Capabilities{JSONMode: true}
+	a := New(fake, config.AnalysisConfig{})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.Equal(t, "85%", result.Confidence)
+	assert.Contains(t, result.RootCause, "Pool size was reduced")
+}
+
+func TestEmptyContextYieldsInsufficientData(t *testing.T) {
+	fake := llm.NewFakeProvider("# Incident Analysis: Memory leak in the checkout cache")
+	a := New(fake, config.AnalysisConfig{})
+
+	ac := &models.AnalysisContext{
+		ServiceName:  "checkout",
+		Alert:        models.AlertInfo{Name: "HighLatency", Severity: "critical"},
+		Sources:      []string{"metrics", "commits", "traces", "logs"},
+		SourceErrors: map[string]string{"logs": "connection refused"},
+	}
+	result, err := a.AnalyzeWithContext(context.Background(), ac)
+	require.NoError(t, err)
+
+	assert.Zero(t, fake.CallCount(), "the LLM is not asked to explain an empty context")
+	assert.True(t, strings.HasPrefix(result.RootCause, "INSUFFICIENT DATA"))
+	assert.NotContains(t, result.RootCause, "Memory leak")
+	for _, source := range []string{"Prometheus (no data)", "GitHub (no data)", "Tempo (no data)", "Loki (error: connection refused)"} {
+		assert.Contains(t, result.RootCause, source)
+	}
+	assert.Equal(t, TriageNeedsHuman, result.Triage)
+}
+
+func TestPartialContextListsEmptySourcesInPrompt(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	ac := sampleContext()
+	ac.Sources = []string{"metrics", "commits", "logs"}
+	_, err := a.AnalyzeWithContext(context.Background(), ac)
+	require.NoError(t, err)
+
+	assert.Contains(t, fake.LastPrompt(), "MISSING DATA: these sources returned nothing: Loki (no data).")
+	assert.NotContains(t, fake.LastPrompt(), "Tempo", "sources that were not queried are not reported as empty")
+}
+
+func TestPromptHintsInjectedOnlyForMatchingAlerts(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{PromptHints: []config.PromptHint{
+		{Alert: "HighLatency", Service: "checkout", Hint: "HighLatency on checkout is usually the Redis session cache."},
+		{Alert: "DiskFull*", Hint: "Log rotation on this fleet is known to lag."},
+	}})
+
+	_, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	prompt := fake.LastPrompt()
+	assert.Contains(t, prompt, "OPERATOR-PROVIDED HINTS")
+	assert.Contains(t, prompt, "- HighLatency on checkout is usually the Redis session cache.")
+	assert.NotContains(t, prompt, "Log rotation")
+
+	other := sampleContext()
+	other.Alert.Name = "HighErrorRate"
+	_, err = a.AnalyzeWithContext(context.Background(), other)
+	require.NoError(t, err)
+	assert.NotContains(t, fake.LastPrompt(), "OPERATOR-PROVIDED HINTS")
+}
+
+func TestAnalyzeRapidPromptIncludesAllAnnotations(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	alert := models.AlertItem{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname":    "HighLatency",
+			"service_name": "checkout",
+			"severity":     "warning",
+			"namespace":    "prod",
+		},
+		Annotations: map[string]string{
+			"summary":     "p99 above 1s",
+			"description": "Checkout p99 latency has been above 1s for 10 minutes",
+			"runbook_url": "https://runbooks.example.com/high-latency",
+		},
+		StartsAt:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		EndsAt:      time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+		Fingerprint: "f00dfeed",
+	}
+
+	result, err := a.Analyze(context.Background(), alert)
+	require.NoError(t, err)
+
+	prompt := fake.LastPrompt()
+	assert.Contains(t, prompt, "- Summary: p99 above 1s")
+	assert.Contains(t, prompt, "- description: Checkout p99 latency has been above 1s for 10 minutes")
+	assert.Contains(t, prompt, "- runbook_url: https://runbooks.example.com/high-latency")
+	assert.Contains(t, prompt, "- summary: p99 above 1s")
+	assert.Contains(t, prompt, "- namespace: prod")
+	assert.Contains(t, prompt, "- Ends: 2024-01-01T13:00:00Z")
+	assert.Contains(t, prompt, "- Fingerprint: f00dfeed")
+	assert.Equal(t, sampleResponse, result.RootCause)
+	assert.Equal(t, "medium", result.Confidence)
+}
+
+func TestPromptFallsBackToCommonAnnotations(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	payload := models.AlertManagerPayload{
+		CommonAnnotations: map[string]string{
+			"description": "Checkout pods are exhausting their DB connection pool",
+			"runbook_url": "https://runbooks.example.com/common",
+		},
+		Alerts: []models.AlertItem{{
+			Status:      "firing",
+			Labels:      map[string]string{"alertname": "HighLatency", "service_name": "checkout", "severity": "warning"},
+			Annotations: map[string]string{"runbook_url": "https://runbooks.example.com/high-latency"},
+			StartsAt:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+	payload.ApplyCommonAnnotations()
+
+	_, err := a.Analyze(context.Background(), payload.Alerts[0])
+	require.NoError(t, err)
+
+	prompt := fake.LastPrompt()
+	assert.Contains(t, prompt, "- description: Checkout pods are exhausting their DB connection pool")
+	assert.Contains(t, prompt, "- runbook_url: https://runbooks.example.com/high-latency", "per-alert annotations win")
+	assert.NotContains(t, prompt, "runbooks.example.com/common")
+}
+
+func TestPromptRequestsConfiguredLanguage(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{Language: "German"})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	prompt := fake.LastPrompt()
+	assert.Contains(t, prompt, "Write all prose in German.")
+	assert.Contains(t, prompt, "## 3. Root Cause Analysis", "headings stay English so responses still parse")
+	assert.Equal(t, "German", result.Language)
+	assert.Equal(t, "85%", result.Confidence)
+
+	english := llm.NewFakeProvider(sampleResponse)
+	result, err = New(english, config.AnalysisConfig{}).AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.NotContains(t, english.LastPrompt(), "### LANGUAGE")
+	assert.Equal(t, "English", result.Language)
+}
+
+func TestPromptTargetsConfiguredPlatform(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	_, err := New(fake, config.AnalysisConfig{Platform: "ECS"}).AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	prompt := fake.LastPrompt()
+	assert.Contains(t, prompt, "### PLATFORM")
+	assert.Contains(t, prompt, "The services run on AWS ECS (use `aws ecs` commands")
+
+	systemd := llm.NewFakeProvider(sampleResponse)
+	_, err = New(systemd, config.AnalysisConfig{Platform: config.PlatformSystemd}).Analyze(context.Background(), models.AlertItem{
+		Labels: map[string]string{"alertname": "HighLatency", "service_name": "checkout"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, systemd.LastPrompt(), "use `systemctl` and `journalctl` commands", "the rapid prompt carries the hint too")
+
+	generic := llm.NewFakeProvider(sampleResponse)
+	_, err = New(generic, config.AnalysisConfig{}).AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.NotContains(t, generic.LastPrompt(), "### PLATFORM")
+}
+
+func TestStaleMetricsAreFlaggedAndLowerConfidence(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	ac := sampleContext()
+	ac.MetricsLastSample = time.Now().Add(-2 * time.Hour)
+	ac.MetricsStale = true
+
+	result, err := New(fake, config.AnalysisConfig{}).AnalyzeWithContext(context.Background(), ac)
+	require.NoError(t, err)
+	assert.Contains(t, fake.LastPrompt(), "- WARNING: metrics are stale: the newest sample is 2h0m0s old")
+	assert.Equal(t, "50% (metrics stale)", result.Confidence, "85% is capped")
+
+	assert.Equal(t, "low (metrics stale)", reduceConfidence("medium"))
+	assert.Equal(t, "30% (metrics stale)", reduceConfidence("30%"))
+}
+
+func TestRapidAndContextPromptsShareOutputFormat(t *testing.T) {
+	a := New(llm.NewFakeProvider(), config.AnalysisConfig{})
+
+	rapid, err := a.buildPrompt(models.AlertItem{Labels: map[string]string{"alertname": "X"}})
+	require.NoError(t, err)
+	full, err := a.buildContextPrompt(sampleContext())
+	require.NoError(t, err)
+
+	for _, section := range []string{"**Confidence Score:** [0-100%]", "## 4. Recommended Action", "ADMIT IGNORANCE"} {
+		assert.Contains(t, rapid, section)
+		assert.Contains(t, full, section)
+	}
+}
+
+func TestContextPromptIncludesTopOperations(t *testing.T) {
+	ac := sampleContext()
+	ac.Traces = tempo.TraceContext{
+		TraceCount: 3,
+		OperationStats: []tempo.OperationStats{
+			{Operation: "db.query", Count: 3, P99Ms: 1200, ErrorCount: 2},
+			{Operation: "GET /cart", Count: 100, P99Ms: 99},
+		},
+	}
+
+	prompt, err := New(llm.NewFakeProvider(), config.AnalysisConfig{}).buildContextPrompt(ac)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "TOP OPERATIONS (by errors, then p99):\n- db.query: 3 spans, p99 1200ms, 2 errors\n- GET /cart: 100 spans, p99 99ms, 0 errors")
+}
+
+func TestContextPromptWarnsWhenTargetDown(t *testing.T) {
+	ac := sampleContext()
+	a := New(llm.NewFakeProvider(), config.AnalysisConfig{})
+
+	prompt, err := a.buildContextPrompt(ac)
+	require.NoError(t, err)
+	assert.NotContains(t, prompt, "metrics may be incomplete")
+
+	ac.MetricsTargets = &prometheus.TargetHealth{Up: 1, Down: 2}
+	prompt, err = a.buildContextPrompt(ac)
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "METRICS:\n- WARNING: metrics may be incomplete: target down (2 of 3 scrape targets down)")
+}
+
+func TestPromptRendersOnlyAllowlistedLabels(t *testing.T) {
+	a := New(llm.NewFakeProvider(), config.AnalysisConfig{
+		PromptLabels:      []string{"cluster", "region"},
+		PromptAnnotations: []string{"runbook_url"},
+	})
+
+	alert := models.AlertItem{
+		Labels: map[string]string{
+			"alertname":  "HighLatency",
+			"cluster":    "prod-eu-1",
+			"region":     "eu-west-1",
+			"namespace":  "checkout",
+			"user_email": "oncall@example.com",
+		},
+		Annotations: map[string]string{
+			"runbook_url": "https://runbooks.example.com/high-latency",
+			"description": "internal ticket notes",
+		},
+	}
+
+	rapid, err := a.buildPrompt(alert)
+	require.NoError(t, err)
+	ac := sampleContext()
+	ac.Alert = alert.ToAlertInfo()
+	full, err := a.buildContextPrompt(ac)
+	require.NoError(t, err)
+
+	for _, prompt := range []string{rapid, full} {
+		assert.Contains(t, prompt, "LABELS:\n- cluster: prod-eu-1\n- region: eu-west-1\n")
+		assert.Contains(t, prompt, "ANNOTATIONS:\n- runbook_url: https://runbooks.example.com/high-latency\n")
+		assert.NotContains(t, prompt, "namespace")
+		assert.NotContains(t, prompt, "oncall@example.com")
+		assert.NotContains(t, prompt, "internal ticket notes")
+	}
+}
+
+func TestParseAssessedSeverity(t *testing.T) {
+	assert.Equal(t, "info", parseAssessedSeverity("**Confidence Score:** 70%\n**Assessed Severity:** Info (no user impact)\n"))
+	assert.Equal(t, "critical", parseAssessedSeverity("**Assessed Severity:** [critical]"))
+	assert.Empty(t, parseAssessedSeverity("**Assessed Severity:** [critical / warning / info]"), "an echoed template is not an answer")
+	assert.Empty(t, parseAssessedSeverity("**Assessed Severity:** catastrophic"))
+	assert.Empty(t, parseAssessedSeverity(sampleResponse))
+}
+
+func TestContextPromptIncludesStatusBreakdown(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{})
+
+	_, err := a.AnalyzeWithContext(context.Background(), &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert:       models.AlertInfo{Name: "HighErrorRate", Severity: "critical"},
+		Metrics: models.MetricsSummary{
+			ErrorRate:       0.05,
+			StatusBreakdown: map[string]float64{"200": 38, "503": 1.5, "502": 0.5},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, fake.LastPrompt(), "- Requests by status code: 200 38.00/s (95.0%), 503 1.50/s (3.8%), 502 0.50/s (1.2%)")
+}
+
+func TestFormatCommitsHandlesShortSHAs(t *testing.T) {
+	commits := []models.CommitInfo{
+		{SHA: "ab12", Message: "Mocked commit", Author: "dev"},
+		{SHA: "abc1234def567890", Message: "Full commit", Author: "dev"},
+	}
+
+	var out string
+	require.NotPanics(t, func() { out = formatCommits(commits) })
+	assert.Equal(t, "- ab12: Mocked commit (by dev)\n- abc1234: Full commit (by dev)\n", out)
+}
@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"helixops/internal/models"
+	"helixops/pkg/llm"
+)
+
+// jsonInstruction replaces the Markdown output format when the model supports JSON mode, so the
+// reply is parsed as structured output instead of scraped from headings.
+const jsonInstruction = `
+### JSON RESPONSE
+Return a single JSON object instead of the Markdown document, with these keys:
+- "analysis": the title, Confidence Score, Status, Assessed Severity, and sections 1 to 3 above as one Markdown string
+- "confidence": the confidence score, e.g. "85%"
+- "assessed_severity": critical, warning, or info
+- "next_steps": the section 4 recommended actions as an array of strings
+`
+
+// ErrPromptTooLarge is returned when a prompt's estimated size exceeds analysis.max_prompt_tokens.
+var ErrPromptTooLarge = errors.New("prompt exceeds analysis.max_prompt_tokens")
+
+// reply is the parsed LLM answer, whichever path produced it.
+type reply struct {
+	// text is the whole answer; rootCause and nextSteps are split out of it
+	text             string
+	rootCause        string
+	confidence       string
+	nextSteps        []string
+	assessedSeverity string
+	raw              *models.LLMResponse
+
+	// size of the prompt that was sent; promptTokens is an estimate
+	promptChars  int
+	promptTokens int
+}
+
+// jsonReply is the object requested by jsonInstruction.
+type jsonReply struct {
+	Analysis         string   `json:"analysis"`
+	Confidence       string   `json:"confidence"`
+	AssessedSeverity string   `json:"assessed_severity"`
+	NextSteps        []string `json:"next_steps"`
+}
+
+// complete sends prompt to the provider and parses the answer. Models with JSON mode are asked for
+// structured output; every other model, and any JSON reply that does not parse, takes the text path.
+// The redacted raw reply is kept with its model and token usage for auditing. Prompts estimated above
+// analysis.max_prompt_tokens are rejected with ErrPromptTooLarge before anything is sent.
+func (a *Analyzer) complete(ctx context.Context, prompt string) (reply, error) {
+	ctx, completion := llm.CaptureCompletion(ctx)
+
+	j, jsonMode := a.provider.(llm.JSONAnalyzer)
+	jsonMode = jsonMode && llm.CapabilitiesOf(a.provider).JSONMode
+	if jsonMode {
+		prompt += jsonInstruction
+	}
+	chars, tokens := utf8.RuneCountInString(prompt), llm.EstimateTokens(prompt)
+	slog.Debug("LLM prompt size", "provider", a.provider.Name(), "chars", chars, "estimated_tokens", tokens)
+	if a.maxPromptTokens > 0 && tokens > a.maxPromptTokens {
+		return reply{}, fmt.Errorf("%w: about %d tokens (%d characters), limit %d", ErrPromptTooLarge, tokens, chars, a.maxPromptTokens)
+	}
+
+	var r reply
+	if jsonMode {
+		response, err := j.AnalyzeJSON(ctx, prompt)
+		if err = a.checkResponse(response, err); err != nil {
+			return reply{}, err
+		}
+		var parsed bool
+		if r, parsed = parseJSONReply(response); !parsed {
+			r = parseTextReply(response)
+		}
+		r.raw = a.rawResponse(response, completion)
+	} else {
+		response, err := a.provider.Analyze(ctx, prompt)
+		if err = a.checkResponse(response, err); err != nil {
+			return reply{}, err
+		}
+		r = parseTextReply(response)
+		r.raw = a.rawResponse(response, completion)
+	}
+	r.promptChars, r.promptTokens = chars, tokens
+	return r, nil
+}
+
+// checkResponse passes through a provider error and turns a blank reply into llm.ErrEmptyResponse, so
+// providers that do not check their own replies still never produce a report with an empty root cause.
+func (a *Analyzer) checkResponse(response string, err error) error {
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(response) == "" {
+		return fmt.Errorf("%s: %w", a.provider.Name(), llm.ErrEmptyResponse)
+	}
+	return nil
+}
+
+// rawResponse pairs the redacted reply with the model and usage the provider captured, if any.
+func (a *Analyzer) rawResponse(response string, c *llm.Completion) *models.LLMResponse {
+	return &models.LLMResponse{
+		Provider:         a.provider.Name(),
+		Model:            c.Model,
+		Response:         llm.Redact(response),
+		PromptTokens:     c.Usage.PromptTokens,
+		CompletionTokens: c.Usage.CompletionTokens,
+		TotalTokens:      c.Usage.TotalTokens,
+	}
+}
+
+// parseTextReply scrapes the Markdown response for its fields.
+func parseTextReply(response string) reply {
+	rootCause, confidence, nextSteps := parseLLMResponse(response)
+	return reply{
+		text:             response,
+		rootCause:        rootCause,
+		confidence:       confidence,
+		nextSteps:        nextSteps,
+		assessedSeverity: parseAssessedSeverity(response),
+	}
+}
+
+// parseJSONReply decodes a JSON-mode response, reporting false when it is not the requested object.
+func parseJSONReply(response string) (reply, bool) {
+	var j jsonReply
+	if err := json.Unmarshal([]byte(strings.TrimSpace(response)), &j); err != nil || strings.TrimSpace(j.Analysis) == "" {
+		return reply{}, false
+	}
+
+	r := reply{
+		text:       strings.TrimSpace(j.Analysis),
+		rootCause:  strings.TrimSpace(j.Analysis),
+		confidence: strings.TrimSpace(j.Confidence),
+		nextSteps:  j.NextSteps,
+	}
+	if r.confidence == "" {
+		r.confidence = "medium"
+	}
+	switch severity := strings.ToLower(strings.TrimSpace(j.AssessedSeverity)); severity {
+	case "critical", "warning", "info":
+		r.assessedSeverity = severity
+	}
+	return r, true
+}
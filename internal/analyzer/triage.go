@@ -0,0 +1,126 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Triage labels assigned before the LLM call.
+const (
+	TriageAutoResolved = "auto_resolved" // known transient or informational noise; the LLM is skipped
+	TriageNeedsLLM     = "needs_llm"     // worth a full RCA
+	TriageNeedsHuman   = "needs_human"   // telemetry is too incomplete for the LLM to be trusted; analyzed but flagged
+	TriageNoAnomaly    = "no_anomaly"    // nothing in the telemetry points at a problem; the LLM is skipped
+)
+
+// minSuspectScore is the correlation score above which a suspected cause warrants LLM analysis.
+const minSuspectScore = 0.5
+
+// Triage is the fast-path classification of an alert and why it was chosen.
+type Triage struct {
+	Label  string
+	Reason string
+}
+
+// Classify labels an alert from its gathered context using metric deviation, configured transient
+// alert patterns, and the remediation rules, without calling the LLM. Critical alerts and any sign of
+// real impact always go to the LLM; only quiet, non-critical alerts are auto-resolved.
+func (a *Analyzer) Classify(ac *models.AnalysisContext) Triage {
+	if ac.MetricsDegraded() {
+		return Triage{TriageNeedsHuman, "scrape targets are down, so metrics cannot confirm the impact"}
+	}
+	if ac.Alert.Severity == config.SeverityCritical {
+		return Triage{TriageNeedsLLM, "critical alerts are always analyzed"}
+	}
+	if anomaly, ok := ac.Metrics.Anomaly(); ok {
+		return Triage{TriageNeedsLLM, "metrics deviate from baseline: " + anomaly}
+	}
+	for _, h := range ac.SuspectedCauses {
+		if h.Score >= minSuspectScore {
+			return Triage{TriageNeedsLLM, fmt.Sprintf("suspected cause %q scored %.2f", h.Cause, h.Score)}
+		}
+	}
+
+	for _, pattern := range a.transient {
+		if ok, _ := path.Match(pattern, ac.Alert.Name); ok {
+			return Triage{TriageAutoResolved, fmt.Sprintf("matches transient alert pattern %q with metrics at baseline", pattern)}
+		}
+	}
+	if ac.Alert.Severity == config.SeverityInfo && len(a.rules.GetSuggestions(ac.Alert)) == 0 {
+		return Triage{TriageAutoResolved, "informational alert with no matching remediation rule and metrics at baseline"}
+	}
+	return Triage{TriageNeedsLLM, "no triage rule matched"}
+}
+
+// triagedResult records an auto-resolved alert without an LLM call.
+func triagedResult(ac *models.AnalysisContext, t Triage) *models.AnalysisResult {
+	return &models.AnalysisResult{
+		ID:           uuid.New().String(),
+		ServiceName:  ac.ServiceName,
+		AlertName:    ac.Alert.Name,
+		Severity:     ac.Alert.Severity,
+		Summary:      ac.Alert.Summary,
+		RootCause:    "Auto-resolved by triage without LLM analysis: " + t.Reason,
+		Confidence:   "n/a",
+		Metrics:      ac.Metrics,
+		Commits:      ac.RecentCommits,
+		AnalyzedAt:   time.Now(),
+		BlastRadius:  ac.BlastRadius,
+		Dashboards:   ac.Dashboards,
+		Triage:       t.Label,
+		TriageReason: t.Reason,
+	}
+}
+
+// noAnomaly reports whether the gathered context shows nothing wrong: metrics were collected, are fresh,
+// and sit at baseline, and no error logs, error spans, recent commits, or strong suspected causes were
+// found. Critical alerts and contexts with failed sources never qualify, so a gap in the telemetry is
+// not mistaken for health.
+func noAnomaly(ac *models.AnalysisContext) bool {
+	if ac.Alert.Severity == config.SeverityCritical || len(ac.SourceErrors) > 0 {
+		return false
+	}
+	if !slices.Contains(ac.Sources, "metrics") || dataSources["metrics"].empty(ac) || ac.MetricsDegraded() || ac.MetricsStale {
+		return false
+	}
+	if _, ok := ac.Metrics.Anomaly(); ok {
+		return false
+	}
+	if len(ac.ErrorLogs) > 0 || len(ac.Traces.ErrorSpans) > 0 || len(ac.RecentCommits) > 0 {
+		return false
+	}
+	for _, h := range ac.SuspectedCauses {
+		if h.Score >= minSuspectScore {
+			return false
+		}
+	}
+	return true
+}
+
+// noAnomalyResult records an alert whose context shows no problem without an LLM call, so responders
+// are not handed a confident-sounding root cause for a blip that cleared on its own.
+func noAnomalyResult(ac *models.AnalysisContext) *models.AnalysisResult {
+	const reason = "metrics are at baseline and no error logs, error spans, or recent commits were found"
+	return &models.AnalysisResult{
+		ID:           uuid.New().String(),
+		ServiceName:  ac.ServiceName,
+		AlertName:    ac.Alert.Name,
+		Severity:     ac.Alert.Severity,
+		Summary:      ac.Alert.Summary,
+		RootCause:    "No anomaly detected: " + reason,
+		Confidence:   "n/a",
+		Metrics:      ac.Metrics,
+		AnalyzedAt:   time.Now(),
+		BlastRadius:  ac.BlastRadius,
+		Dashboards:   ac.Dashboards,
+		Triage:       TriageNoAnomaly,
+		TriageReason: reason,
+	}
+}
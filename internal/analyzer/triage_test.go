@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"context"
+	"testing"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func triageAnalyzer(provider llm.Provider) *Analyzer {
+	return New(provider, config.AnalysisConfig{Triage: config.TriageConfig{
+		Enabled:         true,
+		TransientAlerts: []string{"KubePodRestarted*"},
+	}})
+}
+
+func TestTransientAlertBypassesLLM(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	ac := &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert:       models.AlertInfo{Name: "KubePodRestartedOnce", Severity: "warning"},
+		Metrics:     models.MetricsSummary{LatencyP99: 110, BaselineLatency: 100, RPS: 40},
+	}
+
+	result, err := triageAnalyzer(fake).AnalyzeWithContext(context.Background(), ac)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, fake.CallCount())
+	assert.Equal(t, TriageAutoResolved, result.Triage)
+	assert.Contains(t, result.TriageReason, `"KubePodRestarted*"`)
+	assert.Equal(t, "checkout", result.ServiceName)
+	assert.NotEmpty(t, result.ID)
+}
+
+func TestClassify(t *testing.T) {
+	a := triageAnalyzer(llm.NewFakeProvider())
+	quiet := models.MetricsSummary{LatencyP99: 110, BaselineLatency: 100}
+
+	cases := []struct {
+		name  string
+		ac    models.AnalysisContext
+		label string
+	}{
+		{"transient pattern", models.AnalysisContext{Alert: models.AlertInfo{Name: "KubePodRestartedOnce", Severity: "warning"}, Metrics: quiet}, TriageAutoResolved},
+		{"critical transient", models.AnalysisContext{Alert: models.AlertInfo{Name: "KubePodRestartedOnce", Severity: "critical"}, Metrics: quiet}, TriageNeedsLLM},
+		{"transient with latency spike", models.AnalysisContext{Alert: models.AlertInfo{Name: "KubePodRestartedOnce", Severity: "warning"}, Metrics: models.MetricsSummary{LatencyP99: 900, BaselineLatency: 100}}, TriageNeedsLLM},
+		{"transient with strong suspect", models.AnalysisContext{Alert: models.AlertInfo{Name: "KubePodRestartedOnce", Severity: "warning"}, Metrics: quiet,
+			SuspectedCauses: []models.Hypothesis{{Cause: "Regression introduced by commit abc1234", Score: 0.7}}}, TriageNeedsLLM},
+		{"info without rules", models.AnalysisContext{Alert: models.AlertInfo{Name: "CertificateRenewed", Severity: "info"}, Metrics: quiet}, TriageAutoResolved},
+		{"info with a matching rule", models.AnalysisContext{Alert: models.AlertInfo{Name: "HighMemory", Severity: "info"}, Metrics: quiet}, TriageNeedsLLM},
+		{"targets down", models.AnalysisContext{Alert: models.AlertInfo{Name: "KubePodRestartedOnce", Severity: "warning"},
+			MetricsTargets: &prometheus.TargetHealth{Down: 1}}, TriageNeedsHuman},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.label, a.Classify(&tc.ac).Label)
+		})
+	}
+}
+
+func TestTriageDisabledAlwaysCallsLLM(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{Triage: config.TriageConfig{TransientAlerts: []string{"*"}}})
+
+	result, err := a.AnalyzeWithContext(context.Background(), sampleContext())
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.CallCount())
+	assert.Empty(t, result.Triage)
+}
+
+func TestNormalContextYieldsNoAnomaly(t *testing.T) {
+	fake := llm.NewFakeProvider(sampleResponse)
+	a := New(fake, config.AnalysisConfig{NoAnomaly: config.NoAnomalyNotify})
+	normal := func() *models.AnalysisContext {
+		return &models.AnalysisContext{
+			ServiceName: "checkout",
+			Alert:       models.AlertInfo{Name: "HighLatency", Severity: "warning"},
+			Metrics:     models.MetricsSummary{LatencyP99: 110, BaselineLatency: 100, ErrorRate: 0.001, BaselineErrorRate: 0.001, RPS: 40},
+			Sources:     []string{"metrics", "commits", "logs"},
+		}
+	}
+
+	result, err := a.AnalyzeWithContext(context.Background(), normal())
+	require.NoError(t, err)
+	assert.Equal(t, 0, fake.CallCount())
+	assert.Equal(t, TriageNoAnomaly, result.Triage)
+	assert.Contains(t, result.RootCause, "No anomaly detected")
+
+	withCommit := normal()
+	withCommit.RecentCommits = []models.CommitInfo{{SHA: "abc1234", Message: "Tune cache"}}
+	critical := normal()
+	critical.Alert.Severity = "critical"
+	failed := normal()
+	failed.SourceErrors = map[string]string{"logs": "timeout"}
+	for name, ac := range map[string]*models.AnalysisContext{"commit": withCommit, "critical": critical, "failed source": failed} {
+		assert.False(t, noAnomaly(ac), name)
+	}
+
+	_, err = New(fake, config.AnalysisConfig{}).AnalyzeWithContext(context.Background(), normal())
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.CallCount(), "analyze mode keeps the full RCA")
+}
@@ -0,0 +1,137 @@
+// Package capture keeps redacted copies of raw webhook payloads so a misbehaving analysis can be
+// reproduced, and replays them against a running HelixOps.
+package capture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"helixops/internal/httpx"
+	"helixops/pkg/llm"
+)
+
+// fileSuffix marks captured payloads; other files in the directory are never pruned.
+const fileSuffix = ".json"
+
+// ReplayHeader marks a webhook request sent by Replay, so the replayed payload is not captured again.
+// Its value is the name of the replayed file.
+const ReplayHeader = "X-HelixOps-Replay"
+
+// unsafeName matches characters not allowed in the receiver and fingerprint parts of a file name.
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Store writes captured payloads to a directory, keeping only the newest max of them.
+type Store struct {
+	dir string
+	max int
+	now func() time.Time
+
+	// mu serializes saves so pruning never races a concurrent write
+	mu sync.Mutex
+}
+
+// New creates dir when missing and returns a store that keeps at most max payloads in it.
+func New(dir string, max int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	return &Store{dir: dir, max: max, now: time.Now}, nil
+}
+
+// Save writes body as <timestamp>_<receiver>_<fingerprint>.json and deletes the oldest captures beyond
+// the limit. It returns the written file's path. Only credentials are redacted: addresses such as
+// instance labels are kept, so a replay is grouped, routed, and analyzed like the original.
+func (s *Store) Save(receiver, fingerprint string, body []byte) (string, error) {
+	if receiver == "" {
+		receiver = "default"
+	}
+	if fingerprint == "" {
+		fingerprint = "unknown"
+	}
+	name := fmt.Sprintf("%s_%s_%s%s", s.now().UTC().Format("20060102T150405.000000000Z"),
+		unsafeName.ReplaceAllString(receiver, "-"), unsafeName.ReplaceAllString(fingerprint, "-"), fileSuffix)
+	path := filepath.Join(s.dir, name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.WriteFile(path, []byte(llm.RedactSecrets(string(body))), 0o640); err != nil {
+		return "", fmt.Errorf("failed to write capture: %w", err)
+	}
+	if err := s.prune(); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// List returns the captured payload files, oldest first.
+func (s *Store) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), fileSuffix) {
+			files = append(files, filepath.Join(s.dir, e.Name()))
+		}
+	}
+	// names start with a fixed-width UTC timestamp, so name order is capture order
+	sort.Strings(files)
+	return files, nil
+}
+
+// prune deletes the oldest captures beyond the limit.
+func (s *Store) prune() error {
+	files, err := s.List()
+	if err != nil {
+		return err
+	}
+	for len(files) > s.max {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune capture: %w", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// Replay POSTs a captured payload file to a HelixOps webhook URL, e.g. http://localhost:8080/webhook,
+// and returns the response body. The request carries ReplayHeader. A non-2xx response is an error.
+func Replay(ctx context.Context, client *http.Client, url, path string) (string, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ReplayHeader, filepath.Base(path))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return "", httpx.StatusError("webhook returned status", resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,40 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveRedactsAndKeepsNewestPayloads(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "captures")
+	store, err := New(dir, 2)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	first, err := store.Save("", "fp-1", []byte(`{"alerts":[{"annotations":{"description":"token=abc123 from 10.0.0.7"}}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "20240101T120001.000000000Z_default_fp-1.json", filepath.Base(first))
+	data, err := os.ReadFile(first)
+	require.NoError(t, err)
+	assert.Equal(t, `{"alerts":[{"annotations":{"description":"token=[REDACTED] from 10.0.0.7"}}]}`, string(data))
+
+	_, err = store.Save("infra/team", "fp-2", []byte(`{}`))
+	require.NoError(t, err)
+	third, err := store.Save("infra", "", []byte(`{}`))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0o600))
+
+	files, err := store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "20240101T120002.000000000Z_infra-team_fp-2.json"),
+		third,
+	}, files, "only the newest max payloads are kept, oldest first")
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"), "files that are not captures are left alone")
+}
@@ -0,0 +1,142 @@
+// Package alertmanager provides a client for the Alertmanager v2 API, used to honour active silences.
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"helixops/internal/httpx"
+)
+
+// Client queries an Alertmanager instance for silences.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates a new Alertmanager client
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = "http://localhost:9093"
+	}
+	return &Client{
+		baseURL: baseURL,
+		client:  httpx.NewClient(timeout),
+	}
+}
+
+// New is NewClient that validates baseURL first, returning an *httpx.BaseURLError when it is malformed.
+func New(baseURL string, timeout time.Duration) (*Client, error) {
+	c := NewClient(baseURL, timeout)
+	if err := httpx.CheckBaseURL("alertmanager", c.baseURL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Matcher is a single label matcher of a silence.
+type Matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	// IsEqual is absent in older Alertmanager versions, where every matcher is an equality match
+	IsEqual *bool `json:"isEqual,omitempty"`
+}
+
+// Silence represents a silence as returned by GET /api/v2/silences.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	Status    struct {
+		State string `json:"state"` // active, pending, expired
+	} `json:"status"`
+}
+
+// Matches reports whether every matcher of the silence matches the given label set.
+func (s Silence) Matches(labels map[string]string) bool {
+	if len(s.Matchers) == 0 {
+		return false
+	}
+	for _, m := range s.Matchers {
+		if !m.matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Matcher) matches(value string) bool {
+	matched := value == m.Value
+	if m.IsRegex {
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return false
+		}
+		matched = re.MatchString(value)
+	}
+	if m.IsEqual != nil && !*m.IsEqual {
+		return !matched
+	}
+	return matched
+}
+
+// ActiveSilences returns all silences currently in the active state.
+func (c *Client) ActiveSilences(ctx context.Context) ([]Silence, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	u.Path = "/api/v2/silences"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var silences []Silence
+	if err := json.NewDecoder(resp.Body).Decode(&silences); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	active := silences[:0]
+	for _, s := range silences {
+		if s.Status.State == "active" {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+// MatchingSilence returns the first active silence that matches the labels, or nil if the alert is not silenced.
+func (c *Client) MatchingSilence(ctx context.Context, labels map[string]string) (*Silence, error) {
+	silences, err := c.ActiveSilences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range silences {
+		if silences[i].Matches(labels) {
+			return &silences[i], nil
+		}
+	}
+	return nil, nil
+}
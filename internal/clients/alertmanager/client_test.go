@@ -0,0 +1,73 @@
+package alertmanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const silencesResponse = `[
+	{
+		"id": "expired-1",
+		"matchers": [{"name": "alertname", "value": "HighLatency", "isRegex": false}],
+		"status": {"state": "expired"}
+	},
+	{
+		"id": "active-1",
+		"matchers": [
+			{"name": "alertname", "value": "High.*", "isRegex": true},
+			{"name": "service_name", "value": "checkout", "isRegex": false, "isEqual": true},
+			{"name": "env", "value": "staging", "isRegex": false, "isEqual": false}
+		],
+		"createdBy": "oncall",
+		"status": {"state": "active"}
+	}
+]`
+
+func TestMatchingSilence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/silences", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(silencesResponse))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 5*time.Second)
+
+	silence, err := client.MatchingSilence(context.Background(), map[string]string{
+		"alertname": "HighLatency", "service_name": "checkout", "env": "prod",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, silence)
+	assert.Equal(t, "active-1", silence.ID)
+	assert.Equal(t, "oncall", silence.CreatedBy)
+
+	// The negative matcher excludes staging
+	silence, err = client.MatchingSilence(context.Background(), map[string]string{
+		"alertname": "HighLatency", "service_name": "checkout", "env": "staging",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, silence)
+
+	// Regex matchers are anchored
+	silence, err = client.MatchingSilence(context.Background(), map[string]string{
+		"alertname": "NotHighLatency", "service_name": "checkout",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, silence)
+}
+
+func TestActiveSilencesError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, 5*time.Second).ActiveSilences(context.Background())
+	assert.ErrorContains(t, err, "unexpected status code: 500")
+}
@@ -2,12 +2,18 @@
 package github
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"path"
+	"strings"
 	"time"
+
+	"helixops/internal/httpx"
 )
 
 // Client wraps standard HTTP calls to the GitHub API, handling authentication and rate-limiting where applicable.
@@ -15,6 +21,36 @@ type Client struct {
 	baseURL string
 	token   string
 	client  *http.Client
+	// allowedRepos are owner/repo glob patterns the client may query; empty allows every repo
+	allowedRepos []string
+}
+
+// RepoNotAllowedError is returned for a repository outside the configured allowlist.
+type RepoNotAllowedError struct {
+	Repo string
+}
+
+func (e *RepoNotAllowedError) Error() string {
+	return fmt.Sprintf("repository %s is not in github.allowed_repos", e.Repo)
+}
+
+// AllowRepos restricts repo-scoped calls to repositories matching one of the owner/repo glob
+// patterns (e.g. "acme/*"), compared case-insensitively. An empty list allows every repository.
+func (c *Client) AllowRepos(patterns []string) {
+	c.allowedRepos = patterns
+}
+
+// checkRepo returns a *RepoNotAllowedError when repo is outside the allowlist.
+func (c *Client) checkRepo(repo string) error {
+	if len(c.allowedRepos) == 0 {
+		return nil
+	}
+	for _, pattern := range c.allowedRepos {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(repo)); ok {
+			return nil
+		}
+	}
+	return &RepoNotAllowedError{Repo: repo}
 }
 
 // NewClient creates a new GitHub client
@@ -25,10 +61,18 @@ func NewClient(baseURL, token string) *Client {
 	return &Client{
 		baseURL: baseURL,
 		token:   token,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client:  httpx.NewClient(30 * time.Second),
+	}
+}
+
+// New is NewClient that validates the API URL (api.github.com when empty) and returns an
+// *httpx.BaseURLError when it is malformed.
+func New(baseURL, token string) (*Client, error) {
+	c := NewClient(baseURL, token)
+	if err := httpx.CheckBaseURL("github", c.baseURL); err != nil {
+		return nil, err
 	}
+	return c, nil
 }
 
 // Commit represents a GitHub commit
@@ -66,14 +110,33 @@ type ListCommitsResponse struct {
 	HTMLURL string `json:"html_url"`
 }
 
+// CommitFilter narrows a commit listing to a branch and/or a directory within the repository.
+type CommitFilter struct {
+	Branch string // sent as the sha param; empty uses the repository's default branch
+	Path   string // only commits touching this path are returned
+}
+
 // FetchCommits fetches a set of recent commits for a repository within a specified time window.
-func (c *Client) FetchCommits(ctx context.Context, owner, repo string, since time.Time) ([]Commit, error) {
+func (c *Client) FetchCommits(ctx context.Context, owner, repo string, since time.Time, filter CommitFilter) ([]Commit, error) {
+	if err := c.checkRepo(owner + "/" + repo); err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("/repos/%s/%s/commits", owner, repo)
-	
+
 	params := url.Values{}
 	params.Set("since", since.Format(time.RFC3339))
 	params.Set("per_page", "10")
+	if filter.Branch != "" {
+		params.Set("sha", filter.Branch)
+	}
+	if filter.Path != "" {
+		params.Set("path", filter.Path)
+	}
+	return c.listCommits(ctx, path, params)
+}
 
+// listCommits requests a commit listing and converts it to Commits.
+func (c *Client) listCommits(ctx context.Context, path string, params url.Values) ([]Commit, error) {
 	req, err := c.newRequest(ctx, http.MethodGet, path, params, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -126,10 +189,22 @@ func (c *Client) newRequest(ctx context.Context, method, path string, params url
 	u.Path = path
 	u.RawQuery = params.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	if c.token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.token)
@@ -140,12 +215,130 @@ func (c *Client) newRequest(ctx context.Context, method, path string, params url
 }
 
 // FetchCommitsByRepo fetches commits using repo name format (owner/repo)
-func (c *Client) FetchCommitsByRepo(ctx context.Context, repo string, since time.Time) ([]Commit, error) {
+func (c *Client) FetchCommitsByRepo(ctx context.Context, repo string, since time.Time, filter CommitFilter) ([]Commit, error) {
 	parts := splitRepo(repo)
 	if len(parts) != 2 {
 		return nil, fmt.Errorf("invalid repo format: %s (expected owner/repo)", repo)
 	}
-	return c.FetchCommits(ctx, parts[0], parts[1], since)
+	return c.FetchCommits(ctx, parts[0], parts[1], since, filter)
+}
+
+// GetFileLastCommit returns the most recent commit touching path in repo (owner/repo) on branch, or
+// the default branch when branch is empty. It returns nil when no commit touched the path, e.g. because
+// it does not exist in the repository.
+func (c *Client) GetFileLastCommit(ctx context.Context, repo, path, branch string) (*Commit, error) {
+	if err := c.checkRepo(repo); err != nil {
+		return nil, err
+	}
+	parts := splitRepo(repo)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repo format: %s (expected owner/repo)", repo)
+	}
+
+	params := url.Values{}
+	params.Set("path", path)
+	params.Set("per_page", "1")
+	if branch != "" {
+		params.Set("sha", branch)
+	}
+	commits, err := c.listCommits(ctx, fmt.Sprintf("/repos/%s/%s/commits", parts[0], parts[1]), params)
+	if err != nil || len(commits) == 0 {
+		return nil, err
+	}
+	return &commits[0], nil
+}
+
+// IssueRequest is the payload for creating a GitHub issue.
+type IssueRequest struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Issue is the subset of a created GitHub issue that callers need.
+type Issue struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+}
+
+// CreateIssue opens an issue in the given repository (owner/repo format).
+func (c *Client) CreateIssue(ctx context.Context, repo string, issue IssueRequest) (*Issue, error) {
+	parts := splitRepo(repo)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid repo format: %s (expected owner/repo)", repo)
+	}
+	if err := c.checkRepo(repo); err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues", parts[0], parts[1]), nil, issue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var created Issue
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &created, nil
+}
+
+// Repository is the subset of a GitHub repository used to discover service mappings.
+type Repository struct {
+	Name        string   `json:"name"`
+	FullName    string   `json:"full_name"`
+	Description string   `json:"description"`
+	Topics      []string `json:"topics"`
+	Archived    bool     `json:"archived"`
+}
+
+// reposPerPage is the page size requested when listing an organization's repositories.
+const reposPerPage = 100
+
+// ListOrgRepos lists every repository in an organization, following pagination.
+func (c *Client) ListOrgRepos(ctx context.Context, org string) ([]Repository, error) {
+	var repos []Repository
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("per_page", fmt.Sprint(reposPerPage))
+		params.Set("page", fmt.Sprint(page))
+
+		req, err := c.newRequest(ctx, http.MethodGet, fmt.Sprintf("/orgs/%s/repos", org), params, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+		var batch []Repository
+		err = json.NewDecoder(resp.Body).Decode(&batch)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		repos = append(repos, batch...)
+		if len(batch) < reposPerPage {
+			return repos, nil
+		}
+	}
 }
 
 // splitRepo splits "owner/repo" into [owner, repo]
@@ -0,0 +1,117 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCommitsByRepoSendsBranchAndPath(t *testing.T) {
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/acme/monorepo/commits", r.URL.Path)
+		assert.Equal(t, "release/2024.1", r.URL.Query().Get("sha"))
+		assert.Equal(t, "services/checkout", r.URL.Query().Get("path"))
+		assert.Equal(t, since.Format(time.RFC3339), r.URL.Query().Get("since"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{
+			"sha": "abc1234def",
+			"commit": {
+				"message": "Reduce DB pool size",
+				"author": {"name": "dev", "email": "dev@example.com", "date": "2024-01-01T11:50:00Z"}
+			},
+			"html_url": "https://github.com/acme/monorepo/commit/abc1234def"
+		}]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	commits, err := client.FetchCommitsByRepo(context.Background(), "acme/monorepo", since, CommitFilter{
+		Branch: "release/2024.1",
+		Path:   "services/checkout",
+	})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	assert.Equal(t, "abc1234def", commits[0].SHA)
+	assert.Equal(t, "dev", commits[0].Author.Name)
+}
+
+func TestAllowedReposPolicy(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	client.AllowRepos([]string{"acme/*", "partner/shared-lib"})
+
+	_, err := client.FetchCommitsByRepo(context.Background(), "Acme/Checkout", time.Now(), CommitFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	_, err = client.FetchCommitsByRepo(context.Background(), "evil/exfiltrate", time.Now(), CommitFilter{})
+	var denied *RepoNotAllowedError
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "evil/exfiltrate", denied.Repo)
+
+	_, err = client.CreateIssue(context.Background(), "partner/other", IssueRequest{Title: "x"})
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, 1, calls, "denied repos are never requested")
+}
+
+func TestFetchCommitsOmitsEmptyFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasSHA := r.URL.Query()["sha"]
+		_, hasPath := r.URL.Query()["path"]
+		assert.False(t, hasSHA)
+		assert.False(t, hasPath)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "")
+	commits, err := client.FetchCommits(context.Background(), "acme", "checkout", time.Now(), CommitFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, commits)
+}
+
+func TestCreateIssue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/repos/acme/checkout/issues", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req IssueRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "Incident: HighLatency on checkout", req.Title)
+		assert.Equal(t, []string{"incident"}, req.Labels)
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 42, "html_url": "https://github.com/acme/checkout/issues/42"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "token")
+	issue, err := client.CreateIssue(context.Background(), "acme/checkout", IssueRequest{
+		Title:  "Incident: HighLatency on checkout",
+		Body:   "- [ ] REM-1",
+		Labels: []string{"incident"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 42, issue.Number)
+	assert.Equal(t, "https://github.com/acme/checkout/issues/42", issue.HTMLURL)
+
+	_, err = client.CreateIssue(context.Background(), "checkout", IssueRequest{Title: "x"})
+	assert.ErrorContains(t, err, "invalid repo format")
+}
@@ -0,0 +1,91 @@
+package github
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repoLister lists the repositories of an organization.
+type repoLister interface {
+	ListOrgRepos(ctx context.Context, org string) ([]Repository, error)
+}
+
+// Discoverer maps service names to repositories in an organization, caching the org's repo
+// listing so repeated alerts do not page through the GitHub API each time.
+type Discoverer struct {
+	client repoLister
+	org    string
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	repos     []Repository
+	fetchedAt time.Time
+}
+
+// NewDiscoverer returns a Discoverer over org whose repo listing is reused for ttl.
+func NewDiscoverer(client *Client, org string, ttl time.Duration) *Discoverer {
+	return &Discoverer{client: client, org: org, ttl: ttl, now: time.Now}
+}
+
+// Discover returns the full name (owner/repo) of the repository matching serviceName, or ""
+// when none matches.
+func (d *Discoverer) Discover(ctx context.Context, serviceName string) (string, error) {
+	repos, err := d.list(ctx)
+	if err != nil {
+		return "", err
+	}
+	if repo, ok := MatchRepo(repos, serviceName); ok {
+		return repo.FullName, nil
+	}
+	return "", nil
+}
+
+// list returns the cached repo listing, refreshing it once the TTL has passed.
+func (d *Discoverer) list(ctx context.Context) ([]Repository, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.repos != nil && d.now().Sub(d.fetchedAt) < d.ttl {
+		return d.repos, nil
+	}
+	repos, err := d.client.ListOrgRepos(ctx, d.org)
+	if err != nil {
+		return nil, err
+	}
+	d.repos, d.fetchedAt = repos, d.now()
+	return repos, nil
+}
+
+// MatchRepo picks the repository for a service: an exact name match first, then a repo tagged
+// with a topic equal to the service name. Names are compared case-insensitively with "_" and "-"
+// treated alike; archived repositories are never matched.
+func MatchRepo(repos []Repository, serviceName string) (Repository, bool) {
+	want := normalizeName(serviceName)
+	if want == "" {
+		return Repository{}, false
+	}
+	for _, r := range repos {
+		if !r.Archived && normalizeName(r.Name) == want {
+			return r, true
+		}
+	}
+	for _, r := range repos {
+		if r.Archived {
+			continue
+		}
+		for _, topic := range r.Topics {
+			if normalizeName(topic) == want {
+				return r, true
+			}
+		}
+	}
+	return Repository{}, false
+}
+
+// normalizeName folds case and separators so checkout_api and Checkout-API compare equal.
+func normalizeName(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "_", "-")
+}
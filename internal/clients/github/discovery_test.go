@@ -0,0 +1,85 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOrgReposFollowsPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/orgs/acme/repos", r.URL.Path)
+		assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+
+		var page []Repository
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < reposPerPage; i++ {
+				page = append(page, Repository{Name: fmt.Sprintf("repo-%d", i), FullName: fmt.Sprintf("acme/repo-%d", i)})
+			}
+		} else {
+			page = []Repository{{Name: "checkout", FullName: "acme/checkout"}}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	repos, err := NewClient(server.URL, "").ListOrgRepos(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Len(t, repos, reposPerPage+1)
+	assert.Equal(t, "acme/checkout", repos[reposPerPage].FullName)
+}
+
+func TestMatchRepoPrefersSameNamedRepo(t *testing.T) {
+	repos := []Repository{
+		{Name: "platform", FullName: "acme/platform", Topics: []string{"checkout"}},
+		{Name: "Checkout", FullName: "acme/Checkout"},
+		{Name: "cart_api", FullName: "acme/cart_api", Archived: true},
+		{Name: "storefront", FullName: "acme/storefront", Topics: []string{"cart-api"}},
+	}
+
+	repo, ok := MatchRepo(repos, "checkout")
+	require.True(t, ok)
+	assert.Equal(t, "acme/Checkout", repo.FullName)
+
+	repo, ok = MatchRepo(repos, "cart-api")
+	require.True(t, ok)
+	assert.Equal(t, "acme/storefront", repo.FullName, "archived repos are skipped; topics match next")
+
+	_, ok = MatchRepo(repos, "payments")
+	assert.False(t, ok)
+}
+
+type countingLister struct {
+	calls int
+	repos []Repository
+}
+
+func (c *countingLister) ListOrgRepos(ctx context.Context, org string) ([]Repository, error) {
+	c.calls++
+	return c.repos, nil
+}
+
+func TestDiscovererCachesListing(t *testing.T) {
+	lister := &countingLister{repos: []Repository{{Name: "checkout", FullName: "acme/checkout"}}}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	d := &Discoverer{client: lister, org: "acme", ttl: time.Hour, now: func() time.Time { return now }}
+
+	for _, service := range []string{"checkout", "payments", "checkout"} {
+		_, err := d.Discover(context.Background(), service)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, lister.calls)
+
+	now = now.Add(2 * time.Hour)
+	repo, err := d.Discover(context.Background(), "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, "acme/checkout", repo)
+	assert.Equal(t, 2, lister.calls)
+}
@@ -4,17 +4,33 @@ package loki
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"helixops/internal/httpx"
 )
 
+// minSplitWindow is the narrowest window a query rejected as too wide is halved down to.
+const minSplitWindow = time.Minute
+
 // Client handles authenticated LogQL queries against a specified Loki instance.
 type Client struct {
 	baseURL string
 	client  *http.Client
 	timeout time.Duration
+
+	// maxWindow splits wider queries into consecutive sub-queries; 0 sends every window whole
+	maxWindow time.Duration
+	// retries is how many times a sub-query answered with 429 or 5xx is resent, after backoff doubling each time
+	retries int
+	backoff time.Duration
 }
 
 // NewClient creates a new Loki client
@@ -24,13 +40,46 @@ func NewClient(baseURL string, timeout time.Duration) *Client {
 	}
 	return &Client{
 		baseURL: baseURL,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client:  httpx.NewClient(timeout),
 		timeout: timeout,
 	}
 }
 
+// New is NewClient that also validates the base URL, returning an *httpx.BaseURLError when
+// it cannot be used. An empty URL still falls back to the local default.
+func New(baseURL string, timeout time.Duration) (*Client, error) {
+	c := NewClient(baseURL, timeout)
+	if err := httpx.CheckBaseURL("loki", c.baseURL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UseHeaders sends the given headers (e.g. X-Scope-OrgID for a multi-tenant gateway) on every
+// request, alongside any the client sets itself.
+func (c *Client) UseHeaders(headers map[string]string) {
+	httpx.WithHeaders(c.client, headers)
+}
+
+// UseConcurrencyLimit bounds the queries in flight to Loki at once, so an alert storm cannot overload
+// it; queries that wait longer than queueTimeout for a slot fail with an *httpx.BackpressureError.
+func (c *Client) UseConcurrencyLimit(limit int, queueTimeout time.Duration) {
+	httpx.WithConcurrencyLimit(c.client, "loki", limit, queueTimeout)
+}
+
+// UseQuerySplitting sends queries wider than maxWindow as consecutive sub-queries of at most maxWindow,
+// newest first, merged into one result. A zero maxWindow sends every query whole.
+func (c *Client) UseQuerySplitting(maxWindow time.Duration) {
+	c.maxWindow = maxWindow
+}
+
+// UseRetries resends a sub-query answered with 429 or a 5xx up to retries times, waiting backoff
+// before the first retry and doubling it for each one after.
+func (c *Client) UseRetries(retries int, backoff time.Duration) {
+	c.retries = retries
+	c.backoff = backoff
+}
+
 // LogEntry represents a single log line directly mapped from a Loki stream value.
 type LogEntry struct {
 	Timestamp time.Time
@@ -51,8 +100,67 @@ type LogResponse struct {
 	} `json:"data"`
 }
 
-// Query executes a LogQL query and returns log entries
+// Query executes a LogQL query and returns up to limit log entries, newest first. Windows wider than
+// the configured maximum are queried in chunks, and a chunk Loki rejects as too wide is halved and
+// queried again. When a chunk fails after newer ones succeeded, the entries gathered so far are
+// returned together with the error.
 func (c *Client) Query(ctx context.Context, query string, start, end time.Time, limit int) ([]LogEntry, error) {
+	var entries []LogEntry
+	for _, w := range splitWindow(start, end, c.maxWindow) {
+		remaining := limit - len(entries)
+		if limit > 0 && remaining <= 0 {
+			break
+		}
+		chunk, err := c.queryWindow(ctx, query, w[0], w[1], remaining)
+		entries = append(entries, chunk...)
+		if err != nil {
+			if len(entries) == 0 {
+				return nil, err
+			}
+			return mergeEntries(entries, limit), fmt.Errorf("partial results, %d entries before %s: %w", len(entries), w[1].Format(time.RFC3339), err)
+		}
+	}
+	return mergeEntries(entries, limit), nil
+}
+
+// queryWindow queries one chunk, halving it when Loki rejects its time range as too wide.
+func (c *Client) queryWindow(ctx context.Context, query string, start, end time.Time, limit int) ([]LogEntry, error) {
+	entries, err := c.queryRange(ctx, query, start, end, limit)
+	var tooWide *windowTooWideError
+	if !errors.As(err, &tooWide) || end.Sub(start) < 2*minSplitWindow {
+		return entries, err
+	}
+
+	mid := start.Add(end.Sub(start) / 2)
+	slog.Debug("Loki rejected query window as too wide, splitting", "start", start, "end", end)
+	newer, err := c.queryWindow(ctx, query, mid, end, limit)
+	if err != nil || (limit > 0 && len(newer) >= limit) {
+		return newer, err
+	}
+	older, err := c.queryWindow(ctx, query, start, mid, limit-len(newer))
+	return append(newer, older...), err
+}
+
+// queryRange sends one query_range request, retrying 429 and 5xx responses with doubling backoff.
+func (c *Client) queryRange(ctx context.Context, query string, start, end time.Time, limit int) ([]LogEntry, error) {
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		entries, retry, err := c.doQuery(ctx, query, start, end, limit)
+		if err == nil || !retry || attempt >= c.retries {
+			return entries, err
+		}
+		slog.Warn("Loki query failed, retrying", "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// doQuery makes one query_range request and reports whether a failure is worth retrying.
+func (c *Client) doQuery(ctx context.Context, query string, start, end time.Time, limit int) ([]LogEntry, bool, error) {
 	params := url.Values{}
 	params.Set("query", query)
 	params.Set("start", start.Format(time.RFC3339Nano))
@@ -61,22 +169,26 @@ func (c *Client) Query(ctx context.Context, query string, start, end time.Time,
 
 	req, err := c.newRequest(ctx, http.MethodGet, "/loki/api/v1/query_range", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, false, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		err := httpx.StatusError("unexpected status code", resp)
+		if resp.StatusCode == http.StatusBadRequest && isWindowTooWide(err) {
+			return nil, false, &windowTooWideError{err: err}
+		}
+		return nil, resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
 	}
 
 	var result LogResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, false, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	entries := make([]LogEntry, 0)
@@ -85,7 +197,7 @@ func (c *Client) Query(ctx context.Context, query string, start, end time.Time,
 			if len(value) < 2 {
 				continue
 			}
-			timestamp, err := time.Parse(time.RFC3339Nano, value[0])
+			timestamp, err := parseTimestamp(value[0])
 			if err != nil {
 				continue
 			}
@@ -98,12 +210,69 @@ func (c *Client) Query(ctx context.Context, query string, start, end time.Time,
 		}
 	}
 
-	return entries, nil
+	return entries, false, nil
+}
+
+// windowTooWideError is a 400 from Loki saying the query's time range exceeds its limits.
+type windowTooWideError struct {
+	err error
+}
+
+func (e *windowTooWideError) Error() string { return e.err.Error() }
+func (e *windowTooWideError) Unwrap() error { return e.err }
+
+// isWindowTooWide matches the messages Loki gives when a query spans more time than it allows,
+// e.g. "the query time range exceeds the limit (query length: 800h0m0s, limit: 721h0m0s)".
+func isWindowTooWide(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "time range") || strings.Contains(msg, "query length")
+}
+
+// splitWindow cuts [start, end] into consecutive windows of at most maxWindow, newest first.
+func splitWindow(start, end time.Time, maxWindow time.Duration) [][2]time.Time {
+	if maxWindow <= 0 || end.Sub(start) <= maxWindow {
+		return [][2]time.Time{{start, end}}
+	}
+	var windows [][2]time.Time
+	for to := end; to.After(start); to = to.Add(-maxWindow) {
+		from := to.Add(-maxWindow)
+		if from.Before(start) {
+			from = start
+		}
+		windows = append(windows, [2]time.Time{from, to})
+	}
+	return windows
+}
+
+// mergeEntries orders entries newest first, drops the duplicates adjacent chunks return for a line on
+// their shared boundary, and keeps at most limit.
+func mergeEntries(entries []LogEntry, limit int) []LogEntry {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Timestamp.After(entries[j].Timestamp) })
+	merged := entries[:0]
+	for i, e := range entries {
+		if i > 0 && e == entries[i-1] {
+			continue
+		}
+		merged = append(merged, e)
+	}
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
+
+// parseTimestamp reads a stream value's timestamp: nanoseconds since the epoch, as Loki sends it, or RFC 3339.
+func parseTimestamp(s string) (time.Time, error) {
+	if ns, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, ns).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
 }
 
-// QueryErrorLogs fetches error logs for a service
-func (c *Client) QueryErrorLogs(ctx context.Context, serviceName string, start, end time.Time, limit int) ([]LogEntry, error) {
-	query := fmt.Sprintf(`{service="%s"} |= "error"`, serviceName)
+// QueryErrorLogs fetches error logs from the streams matched by selector, a LogQL stream selector
+// such as {service="checkout"}
+func (c *Client) QueryErrorLogs(ctx context.Context, selector string, start, end time.Time, limit int) ([]LogEntry, error) {
+	query := selector + ` |= "error"`
 	return c.Query(ctx, query, start, end, limit)
 }
 
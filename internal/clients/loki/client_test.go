@@ -0,0 +1,191 @@
+package loki
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseConcurrencyLimitBoundsInFlightQueries(t *testing.T) {
+	const limit = 3
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL, 5*time.Second)
+	require.NoError(t, err)
+	client.UseConcurrencyLimit(limit, 5*time.Second)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.QueryErrorLogs(context.Background(), `{service="checkout"}`, time.Now().Add(-time.Hour), time.Now(), 10)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.LessOrEqual(t, peak.Load(), int32(limit))
+	assert.Equal(t, int32(limit), peak.Load(), "queries still run up to the limit in parallel")
+}
+
+// lokiStreams renders a query_range response with one stream holding the given timestamps.
+func lokiStreams(times ...time.Time) string {
+	var values []string
+	for _, ts := range times {
+		values = append(values, fmt.Sprintf(`[%q, "error at %s"]`, strconv.FormatInt(ts.UnixNano(), 10), ts.Format(time.RFC3339)))
+	}
+	return `{"status":"success","data":{"resultType":"streams","result":[{"stream":{"service":"checkout"},"values":[` +
+		strings.Join(values, ",") + `]}]}}`
+}
+
+func TestWideWindowIsSplitAndMerged(t *testing.T) {
+	end := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := end.Add(-3 * time.Hour)
+
+	var mu sync.Mutex
+	var windows [][2]time.Time
+	var limits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("start"))
+		to, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("end"))
+		mu.Lock()
+		windows = append(windows, [2]time.Time{from, to})
+		limits = append(limits, r.URL.Query().Get("limit"))
+		mu.Unlock()
+		// two lines per hour, returned oldest first as a forward query would
+		w.Write([]byte(lokiStreams(from.Add(10*time.Minute), from.Add(40*time.Minute))))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL, 5*time.Second)
+	require.NoError(t, err)
+	client.UseQuerySplitting(time.Hour)
+
+	entries, err := client.Query(context.Background(), `{service="checkout"}`, start, end, 5)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2]time.Time{
+		{end.Add(-time.Hour), end},
+		{end.Add(-2 * time.Hour), end.Add(-time.Hour)},
+		{start, end.Add(-2 * time.Hour)},
+	}, windows, "the window is queried in hour-long chunks, newest first")
+	assert.Equal(t, []string{"5", "3", "1"}, limits, "each chunk asks only for what the limit leaves")
+
+	require.Len(t, entries, 5)
+	var got []time.Time
+	for _, e := range entries {
+		got = append(got, e.Timestamp)
+	}
+	assert.Equal(t, []time.Time{
+		end.Add(-20 * time.Minute), end.Add(-50 * time.Minute),
+		end.Add(-80 * time.Minute), end.Add(-110 * time.Minute),
+		end.Add(-140 * time.Minute),
+	}, got, "chunks merge into one newest-first result")
+	assert.Equal(t, "checkout", entries[0].Service)
+}
+
+func TestQueryRejectedAsTooWideIsHalved(t *testing.T) {
+	end := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := end.Add(-2 * time.Hour)
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		from, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("start"))
+		to, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("end"))
+		if to.Sub(from) > time.Hour {
+			http.Error(w, "the query time range exceeds the limit (query length: 2h0m0s, limit: 1h0m0s)", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(lokiStreams(from.Add(time.Minute))))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL, 5*time.Second)
+	require.NoError(t, err)
+
+	entries, err := client.Query(context.Background(), `{service="checkout"}`, start, end, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load(), "the rejected window is retried as two halves")
+	require.Len(t, entries, 2)
+	assert.Equal(t, end.Add(-59*time.Minute), entries[0].Timestamp)
+	assert.Equal(t, start.Add(time.Minute), entries[1].Timestamp)
+}
+
+func TestServerErrorsAreRetried(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(lokiStreams(time.Now())))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL, 5*time.Second)
+	require.NoError(t, err)
+
+	_, err = client.Query(context.Background(), `{service="checkout"}`, time.Now().Add(-time.Hour), time.Now(), 10)
+	require.Error(t, err, "without retries the first 502 fails the query")
+	assert.Contains(t, err.Error(), "502: upstream unavailable")
+
+	requests.Store(0)
+	client.UseRetries(2, time.Millisecond)
+	entries, err := client.Query(context.Background(), `{service="checkout"}`, time.Now().Add(-time.Hour), time.Now(), 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestFailedOlderChunkKeepsNewerEntries(t *testing.T) {
+	end := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		from, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("start"))
+		if from.Before(end.Add(-time.Hour)) {
+			http.Error(w, "max entries limit per query exceeded", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(lokiStreams(end.Add(-time.Minute))))
+	}))
+	defer srv.Close()
+
+	client, err := New(srv.URL, 5*time.Second)
+	require.NoError(t, err)
+	client.UseQuerySplitting(time.Hour)
+
+	entries, err := client.Query(context.Background(), `{service="checkout"}`, end.Add(-2*time.Hour), end, 10)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "partial results")
+	require.Len(t, entries, 1, "entries from the newer chunk are kept")
+	assert.Equal(t, end.Add(-time.Minute), entries[0].Timestamp)
+}
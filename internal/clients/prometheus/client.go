@@ -8,7 +8,11 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"helixops/internal/httpx"
 )
 
 // Client implements HTTP interaction with the Prometheus API for instant and range queries.
@@ -16,19 +20,48 @@ type Client struct {
 	baseURL string
 	client  *http.Client
 	timeout time.Duration
+	// statusQuery is the PromQL behind QueryStatusBreakdown, with $service as the placeholder
+	statusQuery string
 }
 
+// DefaultStatusBreakdownQuery is the per-status request rate query; $service is replaced with the service name.
+const DefaultStatusBreakdownQuery = "sum by (status) (rate(http_requests_total{service='$service'}[5m]))"
+
 // NewClient creates a new Prometheus client
 func NewClient(baseURL string, timeout time.Duration) *Client {
 	return &Client{
 		baseURL: baseURL,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		client:  httpx.NewClient(timeout),
 		timeout: timeout,
+
+		statusQuery: DefaultStatusBreakdownQuery,
+	}
+}
+
+// New is NewClient that rejects a malformed base URL with an *httpx.BaseURLError, so a bad
+// prometheus.url fails at startup instead of on the first query.
+func New(baseURL string, timeout time.Duration) (*Client, error) {
+	c := NewClient(baseURL, timeout)
+	if err := httpx.CheckBaseURL("prometheus", c.baseURL); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UseStatusBreakdownQuery replaces DefaultStatusBreakdownQuery, e.g. for metrics that label status
+// codes differently. An empty query keeps the default.
+func (c *Client) UseStatusBreakdownQuery(query string) {
+	if query != "" {
+		c.statusQuery = query
 	}
 }
 
+// UseHeaders sends the given headers (e.g. X-Scope-OrgID for a multi-tenant gateway) on every
+// request, alongside any the client sets itself.
+func (c *Client) UseHeaders(headers map[string]string) {
+	httpx.WithHeaders(c.client, headers)
+}
+
 // QueryResult represents a Prometheus query result
 type QueryResult struct {
 	Status string `json:"status"`
@@ -37,51 +70,87 @@ type QueryResult struct {
 		Result     []struct {
 			Metric map[string]string `json:"metric"`
 			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
 		} `json:"result"`
 	} `json:"data"`
 }
 
+// Sample is one value of an instant query together with its timestamp.
+type Sample struct {
+	Value     float64
+	Timestamp time.Time
+}
+
 // Query executes an instant query and returns the first value
 func (c *Client) Query(ctx context.Context, query string) (float64, error) {
+	sample, err := c.QuerySample(ctx, query)
+	return sample.Value, err
+}
+
+// QueryAt executes an instant query evaluated at t rather than now.
+func (c *Client) QueryAt(ctx context.Context, query string, t time.Time) (float64, error) {
+	sample, err := c.querySample(ctx, query, t)
+	return sample.Value, err
+}
+
+// QuerySample executes an instant query and returns the first value with its timestamp. A query
+// without results returns a zero Sample.
+func (c *Client) QuerySample(ctx context.Context, query string) (Sample, error) {
+	return c.querySample(ctx, query, time.Time{})
+}
+
+// querySample runs an instant query at t, or at the server's current time when t is zero.
+func (c *Client) querySample(ctx context.Context, query string, t time.Time) (Sample, error) {
 	params := url.Values{
 		"query": []string{query},
 	}
+	if !t.IsZero() {
+		params.Set("time", t.Format(time.RFC3339))
+	}
 
 	resp, err := c.doRequest(ctx, "/api/v1/query", params)
 	if err != nil {
-		return 0, err
+		return Sample{}, err
 	}
 
 	var result QueryResult
 	if err := json.Unmarshal(resp, &result); err != nil {
-		return 0, fmt.Errorf("failed to parse response: %w", err)
+		return Sample{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if result.Status != "success" {
-		return 0, fmt.Errorf("query failed: %s", result.Status)
+		return Sample{}, fmt.Errorf("query failed: %s", result.Status)
 	}
 
 	if len(result.Data.Result) == 0 {
-		return 0, nil
+		return Sample{}, nil
 	}
 
 	// Get the first value
 	if len(result.Data.Result[0].Value) < 2 {
-		return 0, nil
+		return Sample{}, nil
 	}
 
 	value, ok := result.Data.Result[0].Value[1].(string)
 	if !ok {
-		return 0, fmt.Errorf("invalid value type")
+		return Sample{}, fmt.Errorf("invalid value type")
 	}
 
-	var f float64
-	_, err = fmt.Sscanf(value, "%f", &f)
+	var sample Sample
+	_, err = fmt.Sscanf(value, "%f", &sample.Value)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse value: %w", err)
+		return Sample{}, fmt.Errorf("failed to parse value: %w", err)
+	}
+	if ts, ok := result.Data.Result[0].Value[0].(float64); ok {
+		sample.Timestamp = unixSeconds(ts)
 	}
 
-	return f, nil
+	return sample, nil
+}
+
+// unixSeconds converts a Prometheus float timestamp to a time.Time.
+func unixSeconds(ts float64) time.Time {
+	return time.Unix(0, int64(ts*float64(time.Second))).UTC()
 }
 
 // QueryRange executes a range query
@@ -132,7 +201,7 @@ func (c *Client) doRequest(ctx context.Context, path string, params url.Values)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, httpx.StatusError("unexpected status code", resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -145,27 +214,203 @@ func (c *Client) doRequest(ctx context.Context, path string, params url.Values)
 
 // QueryLatencyP99 executes a predefined PromQL query returning the p99 latency for a service over the last 5 minutes.
 func (c *Client) QueryLatencyP99(ctx context.Context, serviceName string, start, end time.Time) (float64, error) {
-	query := fmt.Sprintf(
-		"histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{service='%s'}[5m])) by (le))",
-		serviceName,
-	)
-	return c.Query(ctx, query)
+	return c.Query(ctx, latencyP99Query(serviceName))
 }
 
 // QueryErrorRate returns the error rate for a service
 func (c *Client) QueryErrorRate(ctx context.Context, serviceName string, start, end time.Time) (float64, error) {
-	query := fmt.Sprintf(
-		"sum(rate(http_requests_total{service='%s',status=~'5..'}[5m])) / sum(rate(http_requests_total{service='%s'}[5m]))",
-		serviceName, serviceName,
-	)
-	return c.Query(ctx, query)
+	return c.Query(ctx, errorRateQuery(serviceName))
 }
 
 // QueryRPS returns requests per second for a service
 func (c *Client) QueryRPS(ctx context.Context, serviceName string, start, end time.Time) (float64, error) {
-	query := fmt.Sprintf(
-		"sum(rate(http_requests_total{service='%s'}[5m]))",
-		serviceName,
-	)
-	return c.Query(ctx, query)
+	return c.Query(ctx, rpsQuery(serviceName))
+}
+
+// Signals are a service's golden signals at one instant, each a 5-minute rate ending then.
+type Signals struct {
+	Time       time.Time `json:"time"`
+	LatencyP99 float64   `json:"latency_p99"`
+	ErrorRate  float64   `json:"error_rate"`
+	RPS        float64   `json:"requests_per_second"`
+}
+
+// SignalsWindow is the rate window of the golden signal queries: a reading at t covers the requests
+// served in the SignalsWindow before t.
+const SignalsWindow = 5 * time.Minute
+
+// QuerySignalsAt reads the p99 latency, error rate, and request rate of a service as they were at t.
+func (c *Client) QuerySignalsAt(ctx context.Context, serviceName string, t time.Time) (Signals, error) {
+	signals := Signals{Time: t}
+	var err error
+	if signals.LatencyP99, err = c.QueryAt(ctx, latencyP99Query(serviceName), t); err != nil {
+		return signals, fmt.Errorf("latency p99: %w", err)
+	}
+	if signals.ErrorRate, err = c.QueryAt(ctx, errorRateQuery(serviceName), t); err != nil {
+		return signals, fmt.Errorf("error rate: %w", err)
+	}
+	if signals.RPS, err = c.QueryAt(ctx, rpsQuery(serviceName), t); err != nil {
+		return signals, fmt.Errorf("rps: %w", err)
+	}
+	return signals, nil
+}
+
+func latencyP99Query(serviceName string) string {
+	return fmt.Sprintf("histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{service='%s'}[5m])) by (le))", serviceName)
+}
+
+func errorRateQuery(serviceName string) string {
+	return fmt.Sprintf("sum(rate(http_requests_total{service='%s',status=~'5..'}[5m])) / sum(rate(http_requests_total{service='%s'}[5m]))", serviceName, serviceName)
+}
+
+func rpsQuery(serviceName string) string {
+	return fmt.Sprintf("sum(rate(http_requests_total{service='%s'}[5m]))", serviceName)
+}
+
+// QueryLastSample returns when the newest raw request sample for a service was scraped within lookback.
+// The golden signal queries are evaluated at query time, so their own timestamps cannot reveal stale
+// data, and a plain timestamp() only sees samples inside Prometheus's 5m lookback delta, so the newest
+// timestamp is carried forward with last_over_time over a subquery. It returns the zero time when the
+// service has no samples within lookback.
+func (c *Client) QueryLastSample(ctx context.Context, serviceName string, lookback time.Duration) (time.Time, error) {
+	query := lastSampleQuery(serviceName, lookback)
+	sample, err := c.QuerySample(ctx, query)
+	if err != nil || sample.Value == 0 {
+		return time.Time{}, err
+	}
+	return unixSeconds(sample.Value), nil
+}
+
+func lastSampleQuery(serviceName string, lookback time.Duration) string {
+	return fmt.Sprintf("max(last_over_time(timestamp(http_requests_total{service='%s'})[%ds:]))", serviceName, int(lookback.Seconds()))
+}
+
+// StatusBreakdownQuery returns the PromQL QueryStatusBreakdown runs for serviceName.
+func (c *Client) StatusBreakdownQuery(serviceName string) string {
+	return strings.ReplaceAll(c.statusQuery, "$service", serviceName)
+}
+
+// statusLabels are the series labels read as the status code, in order of preference.
+var statusLabels = []string{"status", "code", "status_code"}
+
+// statusBreakdownPoints is about how many steps QueryStatusBreakdown evaluates across its window.
+const statusBreakdownPoints = 60
+
+// QueryStatusBreakdown returns the request rate of a service per HTTP status code averaged over
+// start to end, so a 5% error rate can be told apart as all 503s or a mix of 500/502/504 during the
+// incident rather than at query time. Each series is keyed by its status, code, or status_code label;
+// series with none of them are skipped. A status missing from some steps counts as zero there, so a
+// burst of 503s is not reported as if it lasted the whole window.
+func (c *Client) QueryStatusBreakdown(ctx context.Context, serviceName string, start, end time.Time) (map[string]float64, error) {
+	step := end.Sub(start) / statusBreakdownPoints
+	if step < 15*time.Second {
+		step = 15 * time.Second
+	}
+	step = step.Truncate(time.Second)
+	result, err := c.QueryRange(ctx, c.StatusBreakdownQuery(serviceName), start, end, fmt.Sprintf("%ds", int(step.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	steps := float64(end.Sub(start)/step + 1)
+
+	breakdown := make(map[string]float64)
+	for _, series := range result.Data.Result {
+		var status string
+		for _, label := range statusLabels {
+			if status = series.Metric[label]; status != "" {
+				break
+			}
+		}
+		if status == "" {
+			continue
+		}
+		for _, value := range series.Values {
+			if len(value) < 2 {
+				continue
+			}
+			raw, _ := value[1].(string)
+			rate, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value for status %s: %w", status, err)
+			}
+			breakdown[status] += rate / steps
+		}
+	}
+	return breakdown, nil
+}
+
+// TargetHealth summarizes the scrape targets behind a service at query time. Golden-signal
+// queries against a down target return no series, which otherwise reads as "no anomaly".
+type TargetHealth struct {
+	Up   int `json:"up"`
+	Down int `json:"down"`
+}
+
+// Degraded reports whether any scrape target is down, meaning metrics may be incomplete.
+func (h TargetHealth) Degraded() bool {
+	return h.Down > 0
+}
+
+// QueryTargetUp counts the service's scrape targets by their current "up" value.
+func (c *Client) QueryTargetUp(ctx context.Context, serviceName string) (TargetHealth, error) {
+	var health TargetHealth
+
+	params := url.Values{
+		"query": []string{fmt.Sprintf("up{service='%s'}", serviceName)},
+	}
+	resp, err := c.doRequest(ctx, "/api/v1/query", params)
+	if err != nil {
+		return health, err
+	}
+
+	var result QueryResult
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return health, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Status != "success" {
+		return health, fmt.Errorf("query failed: %s", result.Status)
+	}
+
+	for _, series := range result.Data.Result {
+		if len(series.Value) < 2 {
+			continue
+		}
+		if v, _ := series.Value[1].(string); v == "1" {
+			health.Up++
+		} else {
+			health.Down++
+		}
+	}
+	return health, nil
+}
+
+// Alert is an alert reported by the Prometheus /api/v1/alerts endpoint.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	State       string            `json:"state"` // pending or firing
+	ActiveAt    time.Time         `json:"activeAt"`
+	Value       string            `json:"value"`
+}
+
+// ActiveAlerts lists the alerts Prometheus is currently evaluating as pending or firing.
+func (c *Client) ActiveAlerts(ctx context.Context) ([]Alert, error) {
+	resp, err := c.doRequest(ctx, "/api/v1/alerts", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Status string `json:"status"`
+		Data   struct {
+			Alerts []Alert `json:"alerts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("alerts request failed: %s", result.Status)
+	}
+	return result.Data.Alerts, nil
 }
@@ -2,8 +2,10 @@ package prometheus
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -76,3 +78,121 @@ func TestNewClient(t *testing.T) {
 	assert.NotNil(t, client)
 	assert.Equal(t, "http://localhost:9090", client.baseURL)
 }
+
+func TestQueryTargetUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "up{service='checkout'}", r.URL.Query().Get("query"))
+		w.Write([]byte(`{
+			"status": "success",
+			"data": {
+				"resultType": "vector",
+				"result": [
+					{"metric": {"instance": "a"}, "value": [1234567890, "1"]},
+					{"metric": {"instance": "b"}, "value": [1234567890, "0"]}
+				]
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	health, err := NewClient(server.URL, 10*time.Second).QueryTargetUp(context.Background(), "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, TargetHealth{Up: 1, Down: 1}, health)
+	assert.True(t, health.Degraded())
+	assert.False(t, TargetHealth{Up: 2}.Degraded())
+}
+
+func TestQueryLastSampleLooksBackPastTheLookbackDelta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "max(last_over_time(timestamp(http_requests_total{service='checkout'})[3600s:]))", r.URL.Query().Get("query"))
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1704114000, "1704110400"]}]}}`))
+	}))
+	defer server.Close()
+
+	last, err := NewClient(server.URL, 10*time.Second).QueryLastSample(context.Background(), "checkout", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, time.Unix(1704110400, 0).Equal(last), "a sample an hour old is still found")
+}
+
+func TestActiveAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/alerts", r.URL.Path)
+		w.Write([]byte(`{
+			"status": "success",
+			"data": {
+				"alerts": [{
+					"labels": {"alertname": "HighLatency", "service": "checkout"},
+					"annotations": {"summary": "p99 above 1s"},
+					"state": "firing",
+					"activeAt": "2024-01-01T12:00:00Z",
+					"value": "1.25e+00"
+				}]
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	alerts, err := NewClient(server.URL, 10*time.Second).ActiveAlerts(context.Background())
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "firing", alerts[0].State)
+	assert.Equal(t, "HighLatency", alerts[0].Labels["alertname"])
+	assert.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), alerts[0].ActiveAt)
+}
+
+func TestQueryStatusBreakdown(t *testing.T) {
+	start := time.Date(2024, 1, 1, 11, 30, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, start.Format(time.RFC3339), r.URL.Query().Get("start"))
+		assert.Equal(t, end.Format(time.RFC3339), r.URL.Query().Get("end"))
+		assert.Equal(t, "30s", r.URL.Query().Get("step"))
+		query = r.URL.Query().Get("query")
+
+		// 61 steps: 200s and 500s throughout, 503s only in the last 10 steps
+		series := func(labels string, value string, from int) string {
+			var points []string
+			for i := from; i <= 60; i++ {
+				points = append(points, fmt.Sprintf(`[%d, "%s"]`, start.Unix()+int64(i*30), value))
+			}
+			return fmt.Sprintf(`{"metric": %s, "values": [%s]}`, labels, strings.Join(points, ","))
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "matrix", "result": [%s, %s, %s, %s]}}`,
+			series(`{"status": "200"}`, "41.5", 0),
+			series(`{"status": "503"}`, "6.1", 51),
+			series(`{"code": "500"}`, "0.5", 0),
+			series(`{}`, "9", 0))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 10*time.Second)
+	breakdown, err := client.QueryStatusBreakdown(context.Background(), "checkout", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, "sum by (status) (rate(http_requests_total{service='checkout'}[5m]))", query)
+	require.Len(t, breakdown, 3)
+	assert.InDelta(t, 41.5, breakdown["200"], 1e-9)
+	assert.InDelta(t, 1.0, breakdown["503"], 1e-9, "a burst is averaged over the whole window")
+	assert.InDelta(t, 0.5, breakdown["500"], 1e-9)
+
+	client.UseStatusBreakdownQuery("sum by (code) (rate(requests{app='$service'}[1m]))")
+	_, err = client.QueryStatusBreakdown(context.Background(), "checkout", start, end)
+	require.NoError(t, err)
+	assert.Equal(t, "sum by (code) (rate(requests{app='checkout'}[1m]))", query)
+}
+
+func TestClientSendsConfiguredHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret-key", r.Header.Get("X-Api-Key"))
+		assert.Equal(t, "team-a", r.Header.Get("X-Scope-OrgID"))
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [1234567890, "1"]}]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 10*time.Second)
+	client.UseHeaders(map[string]string{"x-api-key": "secret-key", "x-scope-orgid": "team-a"})
+	_, err := client.Query(context.Background(), "up")
+	require.NoError(t, err)
+}
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"helixops/internal/httpx"
 )
 
 // Client implements HTTP interaction with the Tempo API to fetch traces and spans.
@@ -25,12 +27,32 @@ func NewClient(baseURL string, timeout time.Duration, logger *slog.Logger) *Clie
 		logger = slog.Default()
 	}
 	return &Client{
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
-		logger: logger,
+		baseURL:    baseURL,
+		httpClient: httpx.NewClient(timeout),
+		logger:     logger,
+	}
+}
+
+// New is NewClient for a configured Tempo URL. A malformed URL is rejected with an
+// *httpx.BaseURLError rather than surfacing later from every trace query.
+func New(baseURL string, timeout time.Duration, logger *slog.Logger) (*Client, error) {
+	c := NewClient(baseURL, timeout, logger)
+	if err := httpx.CheckBaseURL("tempo", c.baseURL); err != nil {
+		return nil, err
 	}
+	return c, nil
+}
+
+// UseHeaders sends the given headers (e.g. X-Scope-OrgID for a multi-tenant gateway) on every
+// request, alongside any the client sets itself.
+func (c *Client) UseHeaders(headers map[string]string) {
+	httpx.WithHeaders(c.httpClient, headers)
+}
+
+// UseConcurrencyLimit bounds the queries in flight to Tempo at once, so an alert storm cannot overload
+// it; queries that wait longer than queueTimeout for a slot fail with an *httpx.BackpressureError.
+func (c *Client) UseConcurrencyLimit(limit int, queueTimeout time.Duration) {
+	httpx.WithConcurrencyLimit(c.httpClient, "tempo", limit, queueTimeout)
 }
 
 // QueryResult represents a Tempo query response
@@ -40,7 +62,7 @@ type QueryResult struct {
 		// Note: the exact structure depends on Tempo API version
 		RootServiceName   string `json:"rootServiceName"`
 		RootTraceName     string `json:"rootTraceName"`
-		StartTimeUnixNano uint64 `json:"startTimeUnixNano"`
+		StartTimeUnixNano string `json:"startTimeUnixNano"` // Tempo encodes nanoseconds as a string
 		DurationMs        int64  `json:"durationMs"`
 	} `json:"traces"`
 }
@@ -69,7 +91,7 @@ func (c *Client) doRequest(ctx context.Context, apiPath string, params url.Value
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code from tempo: %d", resp.StatusCode)
+		return nil, httpx.StatusError("unexpected status code from tempo", resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -121,20 +143,20 @@ func (c *Client) GetTraceByID(ctx context.Context, traceID string) (*Trace, erro
 		return nil, err
 	}
 
-	// In reality we would decode the full OTLP JSON trace format here.
-	// For the MVP structural implementation, we represent it as parsed struct.
-	var trace Trace
-	// We'd unmarshal `resp` appropriately into `trace` here.
-	_ = resp
-	
-	return &trace, nil
+	trace, err := decodeTrace(traceID, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace response: %w", err)
+	}
+	return trace, nil
 }
 
-// SearchSlowSpans finds spans exceeding a latency threshold using TraceQL
-func (c *Client) SearchSlowSpans(ctx context.Context, service string, thresholdMs int) ([]Span, error) {
+// SearchSlowSpans finds spans exceeding a latency threshold within the time window using TraceQL
+func (c *Client) SearchSlowSpans(ctx context.Context, service string, thresholdMs int, start, end time.Time) ([]Span, error) {
 	query := BuildSlowSpansQuery(service, thresholdMs)
 	params := url.Values{
-		"q": []string{query},
+		"q":     []string{query},
+		"start": []string{fmt.Sprintf("%d", start.Unix())},
+		"end":   []string{fmt.Sprintf("%d", end.Unix())},
 	}
 
 	resp, err := c.doRequest(ctx, "/api/search", params)
@@ -143,10 +165,31 @@ func (c *Client) SearchSlowSpans(ctx context.Context, service string, thresholdM
 		return nil, err
 	}
 
-	// Dummy parsing block: real implementation parses TraceQL span results 
-	_ = resp
-	var slowSpans []Span
-	
-	// Assume we append successfully matched spans into slowSpans
-	return slowSpans, nil
+	var result searchResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+	return result.spans(service), nil
+}
+
+// SearchErrorSpans finds spans of the service that ended with an ERROR status within the time window
+func (c *Client) SearchErrorSpans(ctx context.Context, service string, start, end time.Time) ([]Span, error) {
+	query := BuildErrorSpansQuery(service)
+	params := url.Values{
+		"q":     []string{query},
+		"start": []string{fmt.Sprintf("%d", start.Unix())},
+		"end":   []string{fmt.Sprintf("%d", end.Unix())},
+	}
+
+	resp, err := c.doRequest(ctx, "/api/search", params)
+	if err != nil {
+		c.logger.Error("Failed to search error spans", "query", query, "error", err)
+		return nil, err
+	}
+
+	var result searchResponse
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse search response: %w", err)
+	}
+	return result.spans(service), nil
 }
@@ -4,17 +4,20 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
+	"helixops/internal/httpx"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestBuildQueries(t *testing.T) {
 	assert.Equal(t, "{ resource.service.name = \"cart\" }", BuildServiceQuery("cart"))
-	assert.Equal(t, "{ resource.service.name = \"login\" && duration > 500ms }", BuildSlowSpansQuery("login", 500))
-	assert.Equal(t, "{ resource.service.name = \"checkout\" && status = \"error\" }", BuildErrorSpansQuery("checkout"))
+	assert.Equal(t, "{ resource.service.name = \"login\" && duration > 500ms } | select(span.peer.service)", BuildSlowSpansQuery("login", 500))
+	assert.Equal(t, "{ resource.service.name = \"checkout\" && status = error } | select(span.peer.service)", BuildErrorSpansQuery("checkout"))
 }
 
 func TestGetTracesByService(t *testing.T) {
@@ -43,16 +46,99 @@ func TestGetTracesByService(t *testing.T) {
 }
 
 func TestGetTraceByID(t *testing.T) {
+	body, err := os.ReadFile("testdata/trace.json")
+	require.NoError(t, err)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		assert.Equal(t, "/api/traces/abc-123", r.URL.Path)
+		assert.Equal(t, "/api/traces/2f3e0cee77ae5dc9c17ade3689eb2e54", r.URL.Path)
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"traceID": "abc-123"}`))
+		w.Write(body)
 	}))
 	defer server.Close()
 
 	client := NewClient(server.URL, 5*time.Second, nil)
-	trace, err := client.GetTraceByID(context.Background(), "abc-123")
-	
+	trace, err := client.GetTraceByID(context.Background(), "2f3e0cee77ae5dc9c17ade3689eb2e54")
+
+	require.NoError(t, err)
+	assert.Equal(t, "2f3e0cee77ae5dc9c17ade3689eb2e54", trace.TraceID)
+	require.Len(t, trace.Spans, 2)
+
+	query := trace.Spans[0]
+	assert.Equal(t, "563d623c76514f8e", query.SpanID)
+	assert.Equal(t, "checkout", query.ServiceName)
+	assert.Equal(t, "SELECT orders", query.OperationName)
+	assert.Equal(t, "postgres", query.PeerService)
+	assert.Equal(t, SpanStatusError, query.Status)
+	assert.True(t, query.IsError())
+	assert.Equal(t, int64(1102), query.DurationMs)
+	assert.Equal(t, time.Unix(0, 1704110400035077898).UTC(), query.StartTime)
+
+	charge := trace.Spans[1]
+	assert.Equal(t, "9a1b2c3d4e5f6071", charge.SpanID)
+	assert.Equal(t, "payments", charge.ServiceName)
+	assert.Empty(t, charge.PeerService)
+	assert.Equal(t, SpanStatusUnset, charge.Status, "an empty OTLP status object is unset, not an error")
+}
+
+func TestSearchSlowSpans(t *testing.T) {
+	body, err := os.ReadFile("testdata/search_slow_spans.json")
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/search", r.URL.Path)
+		assert.Equal(t, BuildSlowSpansQuery("checkout", 500), r.URL.Query().Get("q"))
+		assert.Equal(t, "1704110400", r.URL.Query().Get("start"))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 5*time.Second, nil)
+	spans, err := client.SearchSlowSpans(context.Background(), "checkout", 500, start, start.Add(time.Hour))
+
+	require.NoError(t, err)
+	require.Len(t, spans, 2, "spanSet repeats the first of spanSets and is not counted twice")
+	assert.Equal(t, Span{
+		SpanID:        "563d623c76514f8e",
+		TraceID:       "2f3e0cee77ae5dc9c17ade3689eb2e54",
+		ServiceName:   "checkout",
+		OperationName: "SELECT orders",
+		StartTime:     time.Unix(0, 1704110400035077898).UTC(),
+		DurationMs:    1102,
+		Status:        SpanStatusUnset,
+		PeerService:   "postgres",
+	}, spans[0])
+	assert.Equal(t, "POST /checkout", spans[1].OperationName)
+	assert.Empty(t, spans[1].PeerService)
+}
+
+func TestSearchErrorSpans(t *testing.T) {
+	body, err := os.ReadFile("testdata/search_error_spans.json")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BuildErrorSpansQuery("checkout"), r.URL.Query().Get("q"))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, 5*time.Second, nil)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	spans, err := client.SearchErrorSpans(context.Background(), "checkout", start, start.Add(time.Hour))
+
+	require.NoError(t, err)
+	require.Len(t, spans, 1)
+	assert.Equal(t, "POST /charge", spans[0].OperationName)
+	assert.Equal(t, "payments", spans[0].PeerService)
+	assert.True(t, spans[0].IsError())
+}
+
+func TestNewRejectsMalformedBaseURL(t *testing.T) {
+	_, err := New("tempo:3200", time.Second, nil)
+	var urlErr *httpx.BaseURLError
+	require.ErrorAs(t, err, &urlErr)
+	assert.Equal(t, "tempo", urlErr.Backend)
+
+	client, err := New("http://tempo:3200", time.Second, nil)
 	require.NoError(t, err)
-	assert.NotNil(t, trace)
+	assert.NotNil(t, client)
 }
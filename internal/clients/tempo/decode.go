@@ -0,0 +1,197 @@
+package tempo
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// unixNano is a nanosecond timestamp or duration. Tempo encodes these as JSON strings, older versions
+// and OTLP encoders as numbers; both decode.
+type unixNano int64
+
+func (n *unixNano) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		if text == "" {
+			*n = 0
+			return nil
+		}
+		v, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		*n = unixNano(v)
+		return nil
+	}
+	var v int64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*n = unixNano(v)
+	return nil
+}
+
+func (n unixNano) time() time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(n)).UTC()
+}
+
+// attribute is an OTLP key/value attribute as Tempo returns it in search results and traces.
+type attribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string          `json:"stringValue"`
+		IntValue    json.RawMessage `json:"intValue"`
+		BoolValue   *bool           `json:"boolValue"`
+	} `json:"value"`
+}
+
+// lookup returns the string form of the attribute named key, or "" when it is absent.
+func lookup(attrs []attribute, key string) string {
+	for _, a := range attrs {
+		if a.Key != key {
+			continue
+		}
+		switch {
+		case a.Value.StringValue != "":
+			return a.Value.StringValue
+		case len(a.Value.IntValue) > 0:
+			var n unixNano
+			if n.UnmarshalJSON(a.Value.IntValue) == nil {
+				return strconv.FormatInt(int64(n), 10)
+			}
+		case a.Value.BoolValue != nil:
+			return strconv.FormatBool(*a.Value.BoolValue)
+		}
+	}
+	return ""
+}
+
+// searchSpan is a span matched by a TraceQL search. Only the attributes the query referenced or
+// selected are present; the status intrinsic is one of them when the query filters on it.
+type searchSpan struct {
+	SpanID            string      `json:"spanID"`
+	Name              string      `json:"name"`
+	StartTimeUnixNano unixNano    `json:"startTimeUnixNano"`
+	DurationNanos     unixNano    `json:"durationNanos"`
+	Attributes        []attribute `json:"attributes"`
+}
+
+type spanSet struct {
+	Spans []searchSpan `json:"spans"`
+}
+
+// searchResponse is the body of GET /api/search. Tempo 2.2+ returns every matching span set in
+// spanSets and repeats the first one in spanSet; older versions only return spanSet.
+type searchResponse struct {
+	Traces []struct {
+		TraceID           string    `json:"traceID"`
+		RootServiceName   string    `json:"rootServiceName"`
+		RootTraceName     string    `json:"rootTraceName"`
+		StartTimeUnixNano unixNano  `json:"startTimeUnixNano"`
+		DurationMs        int64     `json:"durationMs"`
+		SpanSet           *spanSet  `json:"spanSet"`
+		SpanSets          []spanSet `json:"spanSets"`
+	} `json:"traces"`
+}
+
+// spans flattens the matched spans of every trace. All matches belong to service, since every search
+// query filters on resource.service.name.
+func (r searchResponse) spans(service string) []Span {
+	var spans []Span
+	for _, t := range r.Traces {
+		sets := t.SpanSets
+		if len(sets) == 0 && t.SpanSet != nil {
+			sets = []spanSet{*t.SpanSet}
+		}
+		for _, set := range sets {
+			for _, s := range set.Spans {
+				spans = append(spans, Span{
+					SpanID:        s.SpanID,
+					TraceID:       t.TraceID,
+					ServiceName:   service,
+					OperationName: s.Name,
+					StartTime:     s.StartTimeUnixNano.time(),
+					DurationMs:    int64(s.DurationNanos) / int64(time.Millisecond),
+					Status:        NormalizeStatus(lookup(s.Attributes, "status")),
+					PeerService:   lookup(s.Attributes, "peer.service"),
+				})
+			}
+		}
+	}
+	return spans
+}
+
+// otlpTrace is the body of GET /api/traces/{id}: OTLP JSON, with the resource spans under "batches"
+// (Tempo 1.x/2.x) or "resourceSpans", and scope spans under "scopeSpans" or the older
+// "instrumentationLibrarySpans".
+type otlpTrace struct {
+	Batches       []resourceSpans `json:"batches"`
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource struct {
+		Attributes []attribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans                  []scopeSpans `json:"scopeSpans"`
+	InstrumentationLibrarySpans []scopeSpans `json:"instrumentationLibrarySpans"`
+}
+
+type scopeSpans struct {
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano unixNano        `json:"startTimeUnixNano"`
+	EndTimeUnixNano   unixNano        `json:"endTimeUnixNano"`
+	Attributes        []attribute     `json:"attributes"`
+	Status            json.RawMessage `json:"status"`
+}
+
+// decodeTrace parses an OTLP JSON trace into a Trace with one Span per OTLP span.
+func decodeTrace(traceID string, body []byte) (*Trace, error) {
+	var raw otlpTrace
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
+	}
+
+	trace := &Trace{TraceID: traceID}
+	for _, rs := range append(raw.Batches, raw.ResourceSpans...) {
+		service := lookup(rs.Resource.Attributes, "service.name")
+		for _, ss := range append(rs.ScopeSpans, rs.InstrumentationLibrarySpans...) {
+			for _, s := range ss.Spans {
+				trace.Spans = append(trace.Spans, Span{
+					SpanID:        otlpID(s.SpanID),
+					TraceID:       traceID,
+					ServiceName:   service,
+					OperationName: s.Name,
+					StartTime:     s.StartTimeUnixNano.time(),
+					DurationMs:    int64(s.EndTimeUnixNano-s.StartTimeUnixNano) / int64(time.Millisecond),
+					Status:        NormalizeStatus(statusCode(s.Status)),
+					PeerService:   lookup(s.Attributes, "peer.service"),
+				})
+			}
+		}
+	}
+	return trace, nil
+}
+
+// otlpID returns an OTLP span ID in the hex form search results use. Tempo's trace endpoint encodes
+// IDs as base64 bytes; an ID that is already hex is returned unchanged.
+func otlpID(id string) string {
+	if _, err := hex.DecodeString(id); err == nil && len(id) == 16 {
+		return id
+	}
+	if b, err := base64.StdEncoding.DecodeString(id); err == nil && len(b) == 8 {
+		return hex.EncodeToString(b)
+	}
+	return id
+}
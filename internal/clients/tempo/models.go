@@ -1,6 +1,10 @@
 package tempo
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 // Trace represents a complete distributed trace containing multiple spans.
 type Trace struct {
@@ -16,7 +20,80 @@ type Span struct {
 	OperationName string    `json:"operationName"`
 	StartTime     time.Time `json:"startTime"`
 	DurationMs    int64     `json:"durationMs"`
-	Status        string    `json:"status"` // e.g., "ok", "error"
+	Status        string    `json:"status"` // SpanStatusUnset, SpanStatusOK, or SpanStatusError
+	// PeerService is the span's peer.service attribute: the remote service a client span called
+	PeerService string `json:"peerService,omitempty"`
+}
+
+// Span statuses, normalized from the OTLP status code enum.
+const (
+	SpanStatusUnset = "unset" // the instrumentation did not set a status; this is the default and not an error
+	SpanStatusOK    = "ok"
+	SpanStatusError = "error"
+)
+
+// NormalizeStatus maps an OTLP status code in any of the forms Tempo and OTLP JSON use, the enum name
+// (STATUS_CODE_ERROR), its number (2), or a plain word ("error"), to SpanStatusUnset, SpanStatusOK, or
+// SpanStatusError. Anything it does not recognize is unset.
+func NormalizeStatus(code string) string {
+	switch strings.ToUpper(strings.TrimSpace(code)) {
+	case "2", "ERROR", "STATUS_CODE_ERROR":
+		return SpanStatusError
+	case "1", "OK", "STATUS_CODE_OK":
+		return SpanStatusOK
+	}
+	return SpanStatusUnset
+}
+
+// IsError reports whether the span ended with an ERROR status. Unset spans are not errors.
+func (s Span) IsError() bool {
+	return NormalizeStatus(s.Status) == SpanStatusError
+}
+
+// UnmarshalJSON decodes a span and normalizes its status, which may be a plain string, an OTLP status
+// object such as {"code": 2} or {"code": "STATUS_CODE_ERROR"}, or a separate statusCode field. A span
+// without any status keeps an empty Status, which IsError treats as unset.
+func (s *Span) UnmarshalJSON(data []byte) error {
+	type plain Span
+	var raw struct {
+		*plain
+		Status     json.RawMessage `json:"status"`
+		StatusCode json.RawMessage `json:"statusCode"`
+	}
+	raw.plain = (*plain)(s)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	code := statusCode(raw.Status)
+	if code == "" {
+		code = statusCode(raw.StatusCode)
+	}
+	if code != "" {
+		s.Status = NormalizeStatus(code)
+	}
+	return nil
+}
+
+// statusCode extracts the status code from a JSON string, number, or {"code": ...} object.
+func statusCode(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return text
+	}
+	var number json.Number
+	if json.Unmarshal(raw, &number) == nil {
+		return number.String()
+	}
+	var object struct {
+		Code json.RawMessage `json:"code"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		return statusCode(object.Code)
+	}
+	return ""
 }
 
 // TraceContext aggregates related traces and spans for use in RCA prompts.
@@ -25,4 +102,14 @@ type TraceContext struct {
 	ErrorSpans []Span  `json:"errorSpans"`
 	TraceCount int     `json:"traceCount"`
 	P99Latency float64 `json:"p99Latency"`
+	// OperationStats breaks the fetched spans down per operation, worst offenders first
+	OperationStats []OperationStats `json:"operationStats,omitempty"`
+}
+
+// OperationStats summarizes latency and errors for one span operation.
+type OperationStats struct {
+	Operation  string  `json:"operation"`
+	Count      int     `json:"count"`
+	P99Ms      float64 `json:"p99Ms"`
+	ErrorCount int     `json:"errorCount"`
 }
@@ -9,11 +9,13 @@ func BuildServiceQuery(serviceName string) string {
 }
 
 // BuildSlowSpansQuery constructs a TraceQL query to discover spans for a service that exceed a given latency threshold.
+// It selects peer.service, which search results only include when the query asks for it.
 func BuildSlowSpansQuery(serviceName string, thresholdMs int) string {
-	return fmt.Sprintf("{ resource.service.name = \"%s\" && duration > %dms }", serviceName, thresholdMs)
+	return fmt.Sprintf("{ resource.service.name = \"%s\" && duration > %dms } | select(span.peer.service)", serviceName, thresholdMs)
 }
 
 // BuildErrorSpansQuery constructs a TraceQL query to retrieve spans marked with an error status for a specific service.
+// status is an intrinsic compared against the bare error keyword; a quoted "error" matches nothing.
 func BuildErrorSpansQuery(serviceName string) string {
-	return fmt.Sprintf("{ resource.service.name = \"%s\" && status = \"error\" }", serviceName)
+	return fmt.Sprintf("{ resource.service.name = \"%s\" && status = error } | select(span.peer.service)", serviceName)
 }
@@ -3,43 +3,115 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"helixops/internal/schedule"
+
 	"github.com/spf13/viper"
 )
 
 // Config represents the root configuration structure for the HelixOps agent.
 type Config struct {
-	App        AppConfig        `mapstructure:"app"`
-	Prometheus PrometheusConfig `mapstructure:"prometheus"`
-	Loki       LokiConfig       `mapstructure:"loki"`
-	Tempo      TempoConfig      `mapstructure:"tempo"`
-	GitHub     GitHubConfig     `mapstructure:"github"`
-	LLM        LLMConfig        `mapstructure:"llm"`
-	Output     OutputConfig     `mapstructure:"output"`
-	Analysis   AnalysisConfig   `mapstructure:"analysis"`
-	Database   DatabaseConfig   `mapstructure:"database"`
+	App        AppConfig                 `mapstructure:"app"`
+	Prometheus PrometheusConfig          `mapstructure:"prometheus"`
+	Loki       LokiConfig                `mapstructure:"loki"`
+	Tempo      TempoConfig               `mapstructure:"tempo"`
+	GitHub     GitHubConfig              `mapstructure:"github"`
+	LLM        LLMConfig                 `mapstructure:"llm"`
+	Output     OutputConfig              `mapstructure:"output"`
+	Analysis   AnalysisConfig            `mapstructure:"analysis"`
+	Database   DatabaseConfig            `mapstructure:"database"`
+	Alerting   AlertingConfig            `mapstructure:"alerting"`
+	Services   map[string]ServiceConfig  `mapstructure:"services"`  // per-service overrides keyed by service_name
+	Receivers  map[string]ReceiverConfig `mapstructure:"receivers"` // named profiles served at /webhook/{name}
+	MCP        MCPConfig                 `mapstructure:"mcp"`
+	Postmortem PostmortemConfig          `mapstructure:"postmortem"`
+
+	// AnalysisProfiles are named bundles of model, prompt, and source settings, selected per receiver or severity
+	AnalysisProfiles map[string]AnalysisProfile `mapstructure:"analysis_profiles"`
+
+	// Dashboards are linked from alert notifications and postmortems for every service without its own list
+	Dashboards []DashboardConfig `mapstructure:"dashboards"`
 }
 
 // AppConfig defines application-level settings such as host and port.
 type AppConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	LogLevel string `mapstructure:"log_level"`
+	Host      string `mapstructure:"host"`
+	Port      int    `mapstructure:"port"`
+	LogLevel  string `mapstructure:"log_level"`
+	LogFormat string `mapstructure:"log_format"` // text or json
+	// AdminToken guards admin endpoints such as GET /config; read from HELIX_ADMIN_TOKEN or
+	// admin_token_file. Admin endpoints are disabled while it is empty
+	AdminTokenFile string `mapstructure:"admin_token_file"`
+	AdminToken     string `mapstructure:"-"`
+	// RequestIDHeader carries the incident ID on every Prometheus, Loki, Tempo, and GitHub request made for
+	// it (default X-Request-Id); empty sends no correlation header
+	RequestIDHeader string `mapstructure:"request_id_header"`
 }
 
 // PrometheusConfig defines connection and timeout settings for the Prometheus TSDB.
 type PrometheusConfig struct {
 	URL     string `mapstructure:"url"`
 	Timeout string `mapstructure:"timeout"`
+	// StalenessThreshold flags metrics whose newest sample is older than this, e.g. during a scrape outage
+	StalenessThreshold string `mapstructure:"staleness_threshold"`
+	// StatusBreakdownQuery overrides the per-status-code request rate query; $service is the service name
+	StatusBreakdownQuery string `mapstructure:"status_breakdown_query"`
+	// Headers are sent on every request, e.g. X-Api-Key or a tenant header for a gateway
+	Headers map[string]string `mapstructure:"headers"`
 }
 
 // LokiConfig defines connection and timeout settings for the Grafana Loki log aggregation system.
 type LokiConfig struct {
 	URL     string `mapstructure:"url"`
 	Timeout string `mapstructure:"timeout"`
+	// Headers are sent on every request, e.g. X-Scope-OrgID for multi-tenant Loki
+	Headers map[string]string `mapstructure:"headers"`
+	// Concurrency bounds the queries in flight to Loki across all alerts being analyzed
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
+	// MaxQueryWindow splits wider log queries into consecutive sub-queries merged into one result
+	MaxQueryWindow string `mapstructure:"max_query_window"`
+	// Retries is how many times a query answered with 429 or 5xx is resent
+	Retries      int    `mapstructure:"retries"`
+	RetryBackoff string `mapstructure:"retry_backoff"`
+}
+
+// ConcurrencyConfig bounds how many queries a client sends to its backend at once, so an alert storm
+// queues up in HelixOps instead of overloading the backend.
+type ConcurrencyConfig struct {
+	// MaxQueries is the number of queries in flight at once; 0 uses DefaultMaxConcurrentQueries
+	MaxQueries int `mapstructure:"max_queries"`
+	// QueueTimeout is how long a query waits for a free slot before failing with backpressure
+	QueueTimeout string `mapstructure:"queue_timeout"`
+}
+
+// DefaultMaxConcurrentQueries is the per-backend query limit used when concurrency.max_queries is unset.
+const DefaultMaxConcurrentQueries = 10
+
+// GetMaxQueries returns the configured query limit, or DefaultMaxConcurrentQueries when unset.
+func (c ConcurrencyConfig) GetMaxQueries() int {
+	if c.MaxQueries <= 0 {
+		return DefaultMaxConcurrentQueries
+	}
+	return c.MaxQueries
+}
+
+// GetQueueTimeoutDuration returns how long a query may wait for a slot. Defaults to 10s.
+func (c ConcurrencyConfig) GetQueueTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.QueueTimeout)
+	if d <= 0 {
+		return 10 * time.Second
+	}
+	return d
 }
 
 // TempoConfig defines connection settings for the Grafana Tempo distributed tracing backend.
@@ -49,15 +121,195 @@ type TempoConfig struct {
 	Enabled             bool   `mapstructure:"enabled"`
 	SlowSpanThresholdMs int    `mapstructure:"slow_span_threshold_ms"`
 	SearchLimit         int    `mapstructure:"search_limit"`
+	// Headers are sent on every request, e.g. X-Scope-OrgID for multi-tenant Tempo
+	Headers map[string]string `mapstructure:"headers"`
+	// Concurrency bounds the queries in flight to Tempo across all alerts being analyzed
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
 }
 
 // GitHubConfig defines settings for interacting with the GitHub REST API.
 type GitHubConfig struct {
 	APIURL         string            `mapstructure:"api_url"`
 	TokenEnv       string            `mapstructure:"token_env"`
+	TokenFile      string            `mapstructure:"token_file"` // used when the token env var is unset
 	Token          string            `mapstructure:"-"`
 	DefaultOrg     string            `mapstructure:"default_org"`
 	ServiceMapping map[string]string `mapstructure:"service_mapping"` // service_name -> owner/repo
+	// CreateIssues opens an issue in the service's mapped repo for each generated postmortem
+	CreateIssues bool     `mapstructure:"create_issues"`
+	IssueLabels  []string `mapstructure:"issue_labels"`
+	// Discovery maps unmapped services to repos in default_org by repo name or topic
+	Discovery RepoDiscoveryConfig `mapstructure:"discovery"`
+	// AllowedRepos are owner/repo glob patterns (e.g. "acme/*") the token may read commits from or
+	// open issues in; empty allows every repo
+	AllowedRepos []string `mapstructure:"allowed_repos"`
+	// BlameFiles looks up the last commit touching each source file named in error log stack traces
+	BlameFiles bool `mapstructure:"blame_files"`
+	// CommitFilter drops bot and housekeeping commits before they reach the prompt
+	CommitFilter CommitFilterConfig `mapstructure:"commit_filter"`
+}
+
+// CommitFilterConfig excludes commits by author or message, so dependency bumps and merge commits do
+// not bury the change that caused an incident. Filtering is on by default.
+type CommitFilterConfig struct {
+	// Disabled keeps every commit
+	Disabled bool `mapstructure:"disabled"`
+	// ExcludeAuthors are regular expressions matched against a commit's author name and email;
+	// defaults to DefaultExcludeAuthors
+	ExcludeAuthors []string `mapstructure:"exclude_authors"`
+	// ExcludeMessages are regular expressions matched against the first line of a commit message;
+	// defaults to DefaultExcludeMessages
+	ExcludeMessages []string `mapstructure:"exclude_messages"`
+}
+
+// DefaultExcludeAuthors match GitHub App bots such as dependabot[bot] and github-actions[bot], and
+// self-hosted Dependabot and Renovate.
+var DefaultExcludeAuthors = []string{`\[bot\]`, `(?i)^dependabot\b`, `(?i)^renovate\b`}
+
+// DefaultExcludeMessages match merge commits and dependency bumps.
+var DefaultExcludeMessages = []string{
+	`^Merge pull request #\d+`,
+	`^Merge (remote-tracking )?branch `,
+	`^Bump \S+ from \S+ to \S+`,
+	`^chore\(deps(-dev)?\):`,
+}
+
+// GetExcludeAuthors returns the author patterns to exclude.
+func (f CommitFilterConfig) GetExcludeAuthors() []string {
+	if len(f.ExcludeAuthors) == 0 {
+		return DefaultExcludeAuthors
+	}
+	return f.ExcludeAuthors
+}
+
+// GetExcludeMessages returns the message patterns to exclude.
+func (f CommitFilterConfig) GetExcludeMessages() []string {
+	if len(f.ExcludeMessages) == 0 {
+		return DefaultExcludeMessages
+	}
+	return f.ExcludeMessages
+}
+
+// RepoDiscoveryConfig enables seeding service_mappings from the repositories in github.default_org.
+// Discovered mappings are used to read commits right away but only open issues once an operator confirms them.
+type RepoDiscoveryConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	CacheTTL string `mapstructure:"cache_ttl"` // how long the org's repo listing is reused
+}
+
+// ServiceConfig holds per-service settings used when gathering context for that service's alerts.
+type ServiceConfig struct {
+	Repo   string `mapstructure:"repo"`   // owner/repo; overrides github.service_mapping
+	Branch string `mapstructure:"branch"` // release branch to read commits from (default: repo default branch)
+	Path   string `mapstructure:"path"`   // only include commits touching this directory (monorepos)
+	// Kubernetes workload targeted by remediation commands such as kubectl scale
+	Deployment string `mapstructure:"deployment"`
+	Namespace  string `mapstructure:"namespace"`
+	// LogSelector is the LogQL stream selector for the service's logs, with %s standing for the service
+	// name, e.g. {app="%s", namespace="prod"}; defaults to DefaultLogSelector
+	LogSelector string `mapstructure:"log_selector"`
+	// Dashboards replace the top-level dashboards list for this service
+	Dashboards []DashboardConfig `mapstructure:"dashboards"`
+}
+
+// DefaultLogSelector matches logs labeled with the service name, HelixOps' original convention.
+const DefaultLogSelector = `{service="%s"}`
+
+// logMatcherRe matches one LogQL label matcher such as app="%s" or namespace=~"prod|staging".
+var logMatcherRe = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=|!=|=~|!~)\s*"[^"]*"\s*$`)
+
+// GetLogSelector returns the LogQL stream selector for serviceName.
+func (s ServiceConfig) GetLogSelector(serviceName string) string {
+	selector := s.LogSelector
+	if selector == "" {
+		selector = DefaultLogSelector
+	}
+	return fmt.Sprintf(selector, serviceName)
+}
+
+// validateLogSelector checks that a selector template is a LogQL stream selector: braces around one
+// or more comma-separated label matchers, with exactly one %s for the service name.
+func validateLogSelector(selector string) error {
+	if strings.Count(selector, "%s") != 1 || strings.Count(selector, "%") != 1 {
+		return fmt.Errorf("%q must contain exactly one %%s for the service name", selector)
+	}
+	trimmed := strings.TrimSpace(selector)
+	if !strings.HasPrefix(trimmed, "{") || !strings.HasSuffix(trimmed, "}") {
+		return fmt.Errorf("%q is not a LogQL stream selector in braces", selector)
+	}
+	body := trimmed[1 : len(trimmed)-1]
+	for _, matcher := range strings.Split(body, ",") {
+		if !logMatcherRe.MatchString(matcher) {
+			return fmt.Errorf("%q: invalid label matcher %q", selector, strings.TrimSpace(matcher))
+		}
+	}
+	return nil
+}
+
+// DashboardConfig is a named dashboard deep link. URL is a template in which {service} is replaced by
+// the service name and {from} and {to} by the incident window in Unix milliseconds, the format Grafana
+// expects, e.g. https://grafana.example.com/d/abc/overview?var-service={service}&from={from}&to={to}.
+type DashboardConfig struct {
+	Name string `mapstructure:"name"`
+	URL  string `mapstructure:"url"`
+}
+
+// Render fills in the URL template for service over the window from..to.
+func (d DashboardConfig) Render(service string, from, to time.Time) string {
+	return strings.NewReplacer(
+		"{service}", url.QueryEscape(service),
+		"{from}", strconv.FormatInt(from.UnixMilli(), 10),
+		"{to}", strconv.FormatInt(to.UnixMilli(), 10),
+	).Replace(d.URL)
+}
+
+// validate checks that the dashboard has a name and that its URL is absolute once rendered.
+func (d DashboardConfig) validate() error {
+	if d.Name == "" || d.URL == "" {
+		return fmt.Errorf("name and url are required")
+	}
+	u, err := url.Parse(d.Render("service", time.Unix(0, 0), time.Unix(0, 0)))
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", d.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http(s) URL", d.URL)
+	}
+	return nil
+}
+
+// DashboardsFor returns the dashboards linked for serviceName: its services entry's list when set,
+// otherwise the top-level list.
+func (c *Config) DashboardsFor(serviceName string) []DashboardConfig {
+	if svc, ok := c.Services[serviceName]; ok && len(svc.Dashboards) > 0 {
+		return svc.Dashboards
+	}
+	return c.Dashboards
+}
+
+// ReceiverConfig is a named webhook profile served at /webhook/{name}, letting separate Alertmanager
+// receivers (e.g. infra and app teams) use their own model, output channels, and service mappings.
+// Each section that is set replaces the matching global section wholesale; unset sections inherit it.
+type ReceiverConfig struct {
+	LLM      *LLMConfig               `mapstructure:"llm"`
+	Output   *OutputConfig            `mapstructure:"output"`
+	Services map[string]ServiceConfig `mapstructure:"services"` // scopes service -> repo mapping to this receiver
+	// Profile names the analysis profile applied to this receiver; its own llm section still wins
+	Profile string `mapstructure:"profile"`
+}
+
+// AnalysisProfile bundles the settings of one kind of analysis, e.g. a cheap model with metrics only for
+// warnings and a large model with every source for critical alerts. Unset fields keep the base settings.
+type AnalysisProfile struct {
+	LLM *LLMConfig `mapstructure:"llm"`
+	// Sources are the built-in context sources gathered for every severity, replacing analysis.enrichment
+	Sources           []string     `mapstructure:"sources"`
+	PromptLabels      []string     `mapstructure:"prompt_labels"`
+	PromptAnnotations []string     `mapstructure:"prompt_annotations"`
+	PromptHints       []PromptHint `mapstructure:"prompt_hints"`
+	Language          string       `mapstructure:"language"`
+	Platform          string       `mapstructure:"platform"`
+	MaxPromptTokens   int          `mapstructure:"max_prompt_tokens"`
 }
 
 // LLMConfig defines the selected Language Model provider and its operational parameters.
@@ -65,24 +317,134 @@ type LLMConfig struct {
 	Provider    string  `mapstructure:"provider"`
 	Model       string  `mapstructure:"model"`
 	Temperature float64 `mapstructure:"temperature"`
-	MaxTokens   int     `mapstructure:"max_tokens"`
+	MaxTokens   int     `mapstructure:"max_tokens"` // 0 uses the model's registered default
 	OllamaURL   string  `mapstructure:"ollama_url"`
 	OllamaModel string  `mapstructure:"ollama_model"`
-	APIKey      string  `mapstructure:"-"`
+	BaseURL     string  `mapstructure:"base_url"`     // OpenAI-compatible endpoint (vLLM, LocalAI, Together)
+	APIKeyFile  string  `mapstructure:"api_key_file"` // used when the provider's API key env var is unset
+	// LogPrompts logs redacted prompts and raw responses at debug level; off by default as prompts carry telemetry
+	LogPrompts bool `mapstructure:"log_prompts"`
+	// VerifyModel checks at startup that the Ollama model is pulled and logs a warning if not
+	VerifyModel bool   `mapstructure:"verify_model"`
+	APIKey      string `mapstructure:"-"`
+
+	// PostmortemMaxTokens is the completion budget for postmortems, which run longer than an RCA; 0 uses MaxTokens
+	PostmortemMaxTokens int `mapstructure:"postmortem_max_tokens"`
+	// MaxConcurrentRequests caps in-flight requests to this provider account; excess requests queue. 0 is unlimited
+	MaxConcurrentRequests int `mapstructure:"max_concurrent_requests"`
+}
+
+// ForPostmortem returns the settings postmortems are generated with: these, with the postmortem
+// completion budget when one is set.
+func (c LLMConfig) ForPostmortem() LLMConfig {
+	if c.PostmortemMaxTokens > 0 {
+		c.MaxTokens = c.PostmortemMaxTokens
+	}
+	return c
 }
 
 // OutputConfig defines the notification channels and serialization targets for RCA reports.
 type OutputConfig struct {
 	Slack    SlackOutputConfig    `mapstructure:"slack"`
 	Markdown MarkdownOutputConfig `mapstructure:"markdown"`
-	// Future: Discord, Teams, PagerDuty, Webhooks
+	Digest   DigestConfig         `mapstructure:"digest"`
+	Webhook  WebhookOutputConfig  `mapstructure:"webhook"`
+	Metrics  MetricsOutputConfig  `mapstructure:"metrics"`
+	// Recurring mutes full analysis notifications while a service keeps producing the same root cause
+	Recurring RecurringConfig `mapstructure:"recurring"`
+	// Future: Discord, Teams, PagerDuty
+}
+
+// RecurringConfig detects a service whose consecutive incidents name effectively the same root cause.
+// From the Threshold-th such incident on, Slack gets a short "recurring" notice instead of the full RCA.
+type RecurringConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Threshold is how many consecutive incidents with the same cause it takes to mute (default 3)
+	Threshold int `mapstructure:"threshold"`
+	// Similarity is the word overlap of two root causes' first sentences, from 0 to 1, at which they count
+	// as the same (default 0.8)
+	Similarity float64 `mapstructure:"similarity"`
+	// Window ends a streak when the previous analysis of the service is older than this (default 24h)
+	Window string `mapstructure:"window"`
+}
+
+// GetThreshold returns how many consecutive similar analyses mute notifications. Defaults to 3.
+func (c RecurringConfig) GetThreshold() int {
+	if c.Threshold <= 0 {
+		return 3
+	}
+	return c.Threshold
+}
+
+// GetSimilarity returns the word overlap at which root causes count as the same. Defaults to 0.8.
+func (c RecurringConfig) GetSimilarity() float64 {
+	if c.Similarity <= 0 {
+		return 0.8
+	}
+	return c.Similarity
+}
+
+// GetWindowDuration parses Window. Defaults to 24 hours.
+func (c RecurringConfig) GetWindowDuration() time.Duration {
+	d, err := time.ParseDuration(c.Window)
+	if err != nil || d <= 0 {
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// MetricsOutputConfig counts analyses by root-cause category on /metrics, and optionally pushes the
+// counters to a Prometheus Pushgateway after each analysis.
+type MetricsOutputConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// PushgatewayURL is the Pushgateway base URL; empty exposes the counters on /metrics only
+	PushgatewayURL string `mapstructure:"pushgateway_url"`
+	Job            string `mapstructure:"job"` // Pushgateway job the counters are grouped under (default: helixops)
+	Timeout        string `mapstructure:"timeout"`
+}
+
+// WebhookOutputConfig POSTs analysis results and postmortems as JSON to an arbitrary endpoint,
+// signed with an HMAC-SHA256 of the body when a secret is configured.
+type WebhookOutputConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	URL        string `mapstructure:"url"`
+	SecretEnv  string `mapstructure:"secret_env"`
+	SecretFile string `mapstructure:"secret_file"`
+	Secret     string `mapstructure:"-"`
+	// Headers are added to every request, e.g. an API key expected by the receiving service
+	Headers map[string]string `mapstructure:"headers"`
+	Timeout string            `mapstructure:"timeout"`
+	// Retries is how many times a failed delivery (network error, 429, or 5xx) is retried
+	Retries      int    `mapstructure:"retries"`
+	RetryBackoff string `mapstructure:"retry_backoff"`
 }
 
 // SlackOutputConfig defines settings for the Slack incoming webhook integration.
 type SlackOutputConfig struct {
-	WebhookURLEnv string `mapstructure:"webhook_url_env"`
-	WebhookURL    string `mapstructure:"-"`
-	Enabled       bool   `mapstructure:"enabled"`
+	WebhookURLEnv  string `mapstructure:"webhook_url_env"`
+	WebhookURLFile string `mapstructure:"webhook_url_file"`
+	WebhookURL     string `mapstructure:"-"`
+	Enabled        bool   `mapstructure:"enabled"`
+	// Bot-token mode posts via chat.postMessage so RCA and postmortem replies thread under the firing message
+	BotTokenEnv  string `mapstructure:"bot_token_env"`
+	BotTokenFile string `mapstructure:"bot_token_file"`
+	BotToken     string `mapstructure:"-"`
+	Channel      string `mapstructure:"channel"`
+	APIURL       string `mapstructure:"api_url"` // Optional override of https://slack.com/api
+	// MaxTextChars caps the analysis text in a Slack message; at most and by default MaxSlackTextChars
+	MaxTextChars int `mapstructure:"max_text_chars"`
+}
+
+// MaxSlackTextChars keeps truncated analysis text, with its notice and kept headings, under Slack's
+// 3000-character limit on a section block.
+const MaxSlackTextChars = 2500
+
+// GetMaxTextChars returns the Slack analysis text cap.
+func (c SlackOutputConfig) GetMaxTextChars() int {
+	if c.MaxTextChars <= 0 || c.MaxTextChars > MaxSlackTextChars {
+		return MaxSlackTextChars
+	}
+	return c.MaxTextChars
 }
 
 // MarkdownOutputConfig defines settings for locally generating Markdown incident reports.
@@ -91,22 +453,463 @@ type MarkdownOutputConfig struct {
 	Enabled   bool   `mapstructure:"enabled"`
 }
 
+// DigestConfig schedules a periodic incident summary (count, MTTR, top offender) posted to Slack.
+type DigestConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the posting period, e.g. "24h"; each digest covers the time since the previous one
+	Interval string `mapstructure:"interval"`
+	// Schedule is a five-field cron expression that replaces Interval, e.g. "0 9 * * 1-5" for 09:00 on weekdays
+	Schedule string `mapstructure:"schedule"`
+	// Timezone is the IANA name Schedule is evaluated in; defaults to UTC
+	Timezone string `mapstructure:"timezone"`
+	// Jitter adds a random delay of up to this much to each post so replicas do not post in lockstep
+	Jitter string `mapstructure:"jitter"`
+	// PostEmpty posts a digest even when no incidents started in the window
+	PostEmpty bool `mapstructure:"post_empty"`
+	// Channel overrides output.slack.channel in bot-token mode; webhooks always post to their own channel
+	Channel string `mapstructure:"channel"`
+}
+
+// PostmortemConfig customizes the structure of generated postmortems. Empty values keep the built-in
+// six-section layout.
+type PostmortemConfig struct {
+	// Sections are the headings the LLM is asked to write, in order
+	Sections []string `mapstructure:"sections"`
+	// TemplateFile is a Go text/template that renders the final postmortem Markdown
+	TemplateFile string `mapstructure:"template_file"`
+	// MaxDuration caps the displayed incident duration; longer ones, and unset start times, are flagged
+	// as suspect, e.g. clock skew or an alert whose StartsAt is zero. A "d" suffix means days
+	MaxDuration string `mapstructure:"max_duration"`
+	// Policy limits which resolved incidents get a full LLM postmortem
+	Policy PostmortemPolicy `mapstructure:"policy"`
+	// MaxBodyChars caps the LLM-written body of the postmortem Markdown; longer bodies are truncated
+	// with their section headings kept. Defaults to DefaultMaxBodyChars
+	MaxBodyChars int `mapstructure:"max_body_chars"`
+	// ResolveDelay postpones the postmortem after a resolved alert arrives, so logs and traces that are
+	// still being flushed make it in. "0" generates it immediately
+	ResolveDelay string `mapstructure:"resolve_delay"`
+}
+
+// GetResolveDelayDuration parses ResolveDelay; zero means no delay.
+func (c PostmortemConfig) GetResolveDelayDuration() time.Duration {
+	d, _ := time.ParseDuration(c.ResolveDelay)
+	return d
+}
+
+// DefaultMaxBodyChars is the postmortem body cap used when postmortem.max_body_chars is unset.
+const DefaultMaxBodyChars = 20000
+
+// GetMaxBodyChars returns the postmortem body cap.
+func (c PostmortemConfig) GetMaxBodyChars() int {
+	if c.MaxBodyChars <= 0 {
+		return DefaultMaxBodyChars
+	}
+	return c.MaxBodyChars
+}
+
+// PostmortemPolicy samples which resolved incidents get a full LLM postmortem in high-volume
+// environments; the rest are resolved with a stored record only. Critical incidents always qualify.
+type PostmortemPolicy struct {
+	// Severities are the non-critical severities that qualify; empty allows every severity
+	Severities []string `mapstructure:"severities"`
+	// MinDuration is how long a non-critical incident must have lasted to qualify, e.g. "10m"
+	MinDuration string `mapstructure:"min_duration"`
+	// MaxPerHour caps non-critical postmortems per rolling hour; 0 is unlimited
+	MaxPerHour int `mapstructure:"max_per_hour"`
+}
+
+// GetMinDurationDuration parses MinDuration; zero means no minimum.
+func (p PostmortemPolicy) GetMinDurationDuration() time.Duration {
+	d, _ := time.ParseDuration(p.MinDuration)
+	return d
+}
+
+// GetMaxDuration parses the longest plausible incident duration. Defaults to 7 days.
+func (c PostmortemConfig) GetMaxDuration() time.Duration {
+	d, _ := parseDays(c.MaxDuration)
+	if d <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return d
+}
+
 // AnalysisConfig defines the time boundaries and lookback windows for fetching RCA data.
 type AnalysisConfig struct {
 	MetricsWindow   string `mapstructure:"metrics_window"`
 	CommitsLookback string `mapstructure:"commits_lookback"`
 	LogsLookback    string `mapstructure:"logs_lookback"`
+	MaxConcurrency  int    `mapstructure:"max_concurrency"` // collectors run in parallel while gathering context
+	// ContextDeadline is a soft deadline for gathering context: once it passes and every RequiredSources
+	// collector has finished, analysis starts without the stragglers. Empty waits for every collector.
+	ContextDeadline string   `mapstructure:"context_deadline"`
+	RequiredSources []string `mapstructure:"required_sources"` // collector names always waited for (default: metrics)
+	// EnrichmentBudget is a hard ceiling on gathering context, shared by every source: when it runs out,
+	// in-flight queries are cancelled and analysis starts with whatever arrived, required sources included.
+	// Empty sets no ceiling beyond each client's own timeout.
+	EnrichmentBudget string `mapstructure:"enrichment_budget"`
+	// Per-alert processing (context + analysis) is retried before the incident is recorded as failed
+	ProcessingRetries int    `mapstructure:"processing_retries"`
+	ProcessingBackoff string `mapstructure:"processing_backoff"`
+	// Only these alert labels and annotations are rendered into LLM prompts; the rest are noise or may carry PII
+	PromptLabels      []string `mapstructure:"prompt_labels"`
+	PromptAnnotations []string `mapstructure:"prompt_annotations"`
+	// PriorIncidents is how many earlier resolved incidents of the same alert and service are shown to
+	// the LLM (default 3; requires the database)
+	PriorIncidents int `mapstructure:"prior_incidents"`
+	// Language the LLM writes RCA and postmortem prose in; headings and parsed field labels stay English
+	Language string `mapstructure:"language"`
+	// Platform the services run on (kubernetes, ecs, or systemd); the RCA prompt asks for commands for it
+	// and remediation rules generate them. Defaults to kubernetes.
+	Platform string `mapstructure:"platform"`
+	// UseAssessedSeverity routes and renders by the model's reassessed severity instead of the alert's
+	UseAssessedSeverity bool `mapstructure:"use_assessed_severity"`
+	// Triage classifies alerts before the LLM call; auto_resolved alerts skip the LLM entirely
+	Triage TriageConfig `mapstructure:"triage"`
+	// NoAnomaly decides what happens when metrics are at baseline and no logs, error spans, or commits
+	// point at a problem: analyze (default), notify with a compact message, or suppress notifications
+	NoAnomaly string `mapstructure:"no_anomaly"`
+	// Enrichment lists the built-in context sources (metrics, commits, traces, logs) gathered per alert
+	// severity; entries replace the default for that severity only
+	Enrichment map[string][]string `mapstructure:"enrichment"`
+	// PromptHints add operator knowledge to the RCA prompt of matching alerts
+	PromptHints []PromptHint `mapstructure:"prompt_hints"`
+	// MaxPromptTokens rejects prompts whose estimated size exceeds it before they are sent; 0 sends any size
+	MaxPromptTokens int `mapstructure:"max_prompt_tokens"`
+	// SeverityProfiles maps a normalized severity to the analysis profile its alerts are analyzed with
+	SeverityProfiles map[string]string `mapstructure:"severity_profiles"`
+}
+
+// PromptHint is operator knowledge about an alert type, e.g. "HighLatency on cart is usually Redis",
+// shown to the LLM as an unverified hint alongside the telemetry.
+type PromptHint struct {
+	Alert   string `mapstructure:"alert"`   // alertname glob, e.g. "HighLatency*"
+	Service string `mapstructure:"service"` // service glob; empty matches every service
+	Hint    string `mapstructure:"hint"`
+}
+
+// Matches reports whether the hint applies to an alert on service.
+func (h PromptHint) Matches(alertName, service string) bool {
+	if ok, _ := path.Match(h.Alert, alertName); !ok {
+		return false
+	}
+	if h.Service == "" {
+		return true
+	}
+	ok, _ := path.Match(h.Service, service)
+	return ok
+}
+
+// Platforms accepted by analysis.platform.
+const (
+	PlatformKubernetes = "kubernetes"
+	PlatformECS        = "ecs"
+	PlatformSystemd    = "systemd" // bare-metal or VM hosts running services as systemd units
+)
+
+// Outcomes for analysis.no_anomaly.
+const (
+	NoAnomalyAnalyze  = "analyze"
+	NoAnomalyNotify   = "notify"
+	NoAnomalySuppress = "suppress"
+)
+
+// TriageConfig enables the LLM-free fast path for high-volume, low-severity alerts.
+type TriageConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TransientAlerts are alertname glob patterns known to clear on their own, e.g. "KubePodRestarted*"
+	TransientAlerts []string `mapstructure:"transient_alerts"`
 }
 
 // DatabaseConfig defines PostgreSQL database settings.
 type DatabaseConfig struct {
-	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
-	User     string `mapstructure:"user"`
-	Password string `mapstructure:"-"` // from env var
-	DBName   string `mapstructure:"dbname"`
-	SSLMode  string `mapstructure:"sslmode"`
+	// Driver is "postgres" (the default) or "sqlite"
+	Driver string `mapstructure:"driver"`
+	// DSN is the connection string; for postgres it replaces host, port, user, password, dbname, and sslmode
+	DSN          string `mapstructure:"dsn"`
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	User         string `mapstructure:"user"`
+	Password     string `mapstructure:"-"` // from HELIX_DB_PASSWORD or password_file
+	PasswordFile string `mapstructure:"password_file"`
+	// Resolved and failed incidents older than Retention are purged every CleanupInterval
+	Retention       string `mapstructure:"retention"` // e.g. "90d", "720h"
+	CleanupInterval string `mapstructure:"cleanup_interval"`
+	DBName          string `mapstructure:"dbname"`
+	SSLMode         string `mapstructure:"sslmode"`
+	Enabled         bool   `mapstructure:"enabled"`
+	// MemoryIncidents bounds the in-memory incident registry used while the database is disabled or
+	// unreachable; 0 means 1000 and a negative value turns the registry off
+	MemoryIncidents int `mapstructure:"memory_incidents"`
+}
+
+// Database drivers accepted by database.driver.
+const (
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
+// Connection returns the database/sql driver name and DSN to open: dsn when set, otherwise a
+// PostgreSQL connection string built from the individual settings.
+func (d DatabaseConfig) Connection() (driver, dsn string) {
+	if strings.EqualFold(d.Driver, DatabaseDriverSQLite) || strings.EqualFold(d.Driver, "sqlite3") {
+		return "sqlite3", d.DSN
+	}
+	if d.DSN != "" {
+		return "postgres", d.DSN
+	}
+	return "postgres", fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.DBName, d.SSLMode)
+}
+
+// MemoryRegistryEnabled reports whether incidents are kept in memory when the database is unavailable.
+func (d DatabaseConfig) MemoryRegistryEnabled() bool {
+	return d.MemoryIncidents >= 0
+}
+
+// AlertingConfig defines gating rules applied to incoming alerts before any analysis is started.
+type AlertingConfig struct {
+	InhibitRules []InhibitRule      `mapstructure:"inhibit_rules"`
+	Alertmanager AlertmanagerConfig `mapstructure:"alertmanager"`
+	Poll         AlertPollConfig    `mapstructure:"poll"`
+	// SeverityMap normalizes source-specific severities (P1, sev2, ...) to critical, warning, or info;
+	// entries extend and override the built-in mapping. Keys are case-insensitive.
+	SeverityMap map[string]string `mapstructure:"severity_map"`
+	// IgnoreServices drops every alert for these services before any processing, e.g. HelixOps itself
+	IgnoreServices []string `mapstructure:"ignore_services"`
+	// IgnoreMatchers drops alerts whose labels match every pair of any entry, e.g. synthetic probes
+	IgnoreMatchers []map[string]string `mapstructure:"ignore_matchers"`
+	// MaintenanceWindows record firing alerts without analysis or notification during planned work
+	MaintenanceWindows []MaintenanceWindow `mapstructure:"maintenance_windows"`
+	// Aggregation merges alerts that share a root cause into one incident with a single postmortem
+	Aggregation AggregationConfig `mapstructure:"aggregation"`
+	// AnnotationFallbacks supply the service and severity from annotations when the labels lack them
+	AnnotationFallbacks AnnotationFallbacks `mapstructure:"annotation_fallbacks"`
+	// Capture keeps redacted copies of raw webhook bodies for replay with helixctl
+	Capture CaptureConfig `mapstructure:"capture"`
+}
+
+// CaptureConfig writes each raw webhook body, redacted, to a rolling directory so the exact payload
+// behind a misbehaving analysis can be replayed with `helixctl replay`.
+type CaptureConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Dir         string `mapstructure:"dir"`          // default ./captures
+	MaxPayloads int    `mapstructure:"max_payloads"` // newest payloads kept; older ones are deleted (default 100)
+}
+
+// GetDir returns the directory captured payloads are written to. Defaults to ./captures.
+func (c CaptureConfig) GetDir() string {
+	if c.Dir == "" {
+		return "./captures"
+	}
+	return c.Dir
+}
+
+// GetMaxPayloads returns how many captured payloads are kept. Defaults to 100.
+func (c CaptureConfig) GetMaxPayloads() int {
+	if c.MaxPayloads <= 0 {
+		return 100
+	}
+	return c.MaxPayloads
+}
+
+// AnnotationFallbacks lists annotation keys consulted, in order, for an alert's service and severity
+// when its labels carry none, for teams that template routing info into annotations. Labels always win.
+type AnnotationFallbacks struct {
+	Service  []string `mapstructure:"service"`
+	Severity []string `mapstructure:"severity"`
+}
+
+// AggregationConfig groups related firing alerts into one incident. An alert joins an open incident
+// that started within Window of it when they share a value of any MatchLabels label, or when its
+// service appeared as a trace peer in that incident's blast radius. Requires the database.
+type AggregationConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Window      string   `mapstructure:"window"`
+	MatchLabels []string `mapstructure:"match_labels"` // e.g. cluster, namespace, region
+}
+
+// Canonical alert severities used for routing and rendering.
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// defaultSeverityMap covers the severity schemes most alert sources use.
+var defaultSeverityMap = map[string]string{
+	"critical": SeverityCritical, "page": SeverityCritical, "high": SeverityCritical, "p1": SeverityCritical, "sev1": SeverityCritical,
+	"warning": SeverityWarning, "warn": SeverityWarning, "medium": SeverityWarning, "p2": SeverityWarning, "p3": SeverityWarning, "sev2": SeverityWarning, "sev3": SeverityWarning,
+	"info": SeverityInfo, "low": SeverityInfo, "none": SeverityInfo, "p4": SeverityInfo, "p5": SeverityInfo, "sev4": SeverityInfo, "sev5": SeverityInfo,
+}
+
+// AlertPollConfig enables pulling active alerts from the Prometheus /api/v1/alerts endpoint
+// for setups that do not route Alertmanager webhooks to HelixOps.
+type AlertPollConfig struct {
 	Enabled  bool   `mapstructure:"enabled"`
+	Interval string `mapstructure:"interval"`
+}
+
+// AlertmanagerConfig points at the Alertmanager API used to skip alerts that are actively silenced.
+// Silence checks are disabled when URL is empty.
+type AlertmanagerConfig struct {
+	URL     string `mapstructure:"url"`
+	Timeout string `mapstructure:"timeout"`
+}
+
+// MCPConfig bounds how long and how many MCP tool calls may run, so a hung backend cannot block the agent.
+type MCPConfig struct {
+	ToolTimeout        string `mapstructure:"tool_timeout"`
+	MaxConcurrentTools int    `mapstructure:"max_concurrent_tools"`
+}
+
+// InhibitRule suppresses analysis of target alerts while a matching source alert fires, mirroring Alertmanager inhibition.
+type InhibitRule struct {
+	SourceMatch map[string]string `mapstructure:"source_match"` // labels the parent alert must carry
+	TargetMatch map[string]string `mapstructure:"target_match"` // labels identifying the child alerts to suppress
+	Equal       []string          `mapstructure:"equal"`        // labels that must match between parent and child
+}
+
+// MaintenanceWindow is a period of planned work during which firing alerts are recorded but not
+// analyzed or notified. A window is either one-off (Start and End, RFC 3339) or weekly (From and To
+// as HH:MM on the listed Days, in Timezone); a weekly window whose To is before From ends the next day.
+type MaintenanceWindow struct {
+	Name     string   `mapstructure:"name"`
+	Services []string `mapstructure:"services"` // empty applies the window to every service
+	Start    string   `mapstructure:"start"`
+	End      string   `mapstructure:"end"`
+	Days     []string `mapstructure:"days"` // mon..sun or full names; empty means every day
+	From     string   `mapstructure:"from"`
+	To       string   `mapstructure:"to"`
+	Timezone string   `mapstructure:"timezone"` // IANA name for From/To; defaults to UTC
+}
+
+// weekdays maps the accepted day names to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// AppliesTo reports whether the window covers the service.
+func (w MaintenanceWindow) AppliesTo(service string) bool {
+	if len(w.Services) == 0 {
+		return true
+	}
+	for _, s := range w.Services {
+		if strings.EqualFold(s, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether t falls inside the window. Windows that fail validation never match.
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	if w.Start != "" || w.End != "" {
+		start, end, err := w.bounds()
+		return err == nil && !t.Before(start) && t.Before(end)
+	}
+
+	loc, from, to, days, err := w.weekly()
+	if err != nil {
+		return false
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	if from <= to {
+		return minute >= from && minute < to && days[day]
+	}
+	// Overnight window: the part after midnight belongs to the previous day's window
+	if minute >= from {
+		return days[day]
+	}
+	return minute < to && days[(day+6)%7]
+}
+
+// Validate checks that the window is either a well-formed one-off or weekly window.
+func (w MaintenanceWindow) Validate() error {
+	if w.Start != "" || w.End != "" {
+		if w.From != "" || w.To != "" || len(w.Days) > 0 {
+			return fmt.Errorf("set either start/end or from/to/days, not both")
+		}
+		_, _, err := w.bounds()
+		return err
+	}
+	_, _, _, _, err := w.weekly()
+	return err
+}
+
+func (w MaintenanceWindow) bounds() (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, w.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("start: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, w.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("end: %w", err)
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end must be after start")
+	}
+	return start, end, nil
+}
+
+func (w MaintenanceWindow) weekly() (*time.Location, int, int, [7]bool, error) {
+	var days [7]bool
+	loc := time.UTC
+	if w.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return nil, 0, 0, days, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	from, err := minuteOfDay(w.From)
+	if err != nil {
+		return nil, 0, 0, days, fmt.Errorf("from: %w", err)
+	}
+	to, err := minuteOfDay(w.To)
+	if err != nil {
+		return nil, 0, 0, days, fmt.Errorf("to: %w", err)
+	}
+	if from == to {
+		return nil, 0, 0, days, fmt.Errorf("from and to must differ")
+	}
+	if len(w.Days) == 0 {
+		days = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, name := range w.Days {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if len(key) > 3 {
+			key = key[:3] // accept full names such as "Saturday"
+		}
+		d, ok := weekdays[key]
+		if !ok {
+			return nil, 0, 0, days, fmt.Errorf("days: unknown day %q", name)
+		}
+		days[d] = true
+	}
+	return loc, from, to, days, nil
+}
+
+// minuteOfDay parses an HH:MM clock time into minutes after midnight.
+func minuteOfDay(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// GetLogLevel parses the configured log level (debug, info, warn, error) into a slog.Level.
+func (c *AppConfig) GetLogLevel() (slog.Level, error) {
+	var level slog.Level
+	if strings.TrimSpace(c.LogLevel) == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", c.LogLevel)
+	}
+	return level, nil
 }
 
 // GetTimeoutDuration returns the timeout as a time.Duration
@@ -118,6 +921,15 @@ func (c *PrometheusConfig) GetTimeoutDuration() time.Duration {
 	return d
 }
 
+// GetStalenessThresholdDuration returns how old the newest sample may be before metrics are stale. Defaults to 5 minutes.
+func (c *PrometheusConfig) GetStalenessThresholdDuration() time.Duration {
+	d, _ := time.ParseDuration(c.StalenessThreshold)
+	if d <= 0 {
+		return 5 * time.Minute
+	}
+	return d
+}
+
 // GetTimeoutDuration parses the configured string timeout into a time.Duration.
 func (c *LokiConfig) GetTimeoutDuration() time.Duration {
 	d, _ := time.ParseDuration(c.Timeout)
@@ -127,96 +939,993 @@ func (c *LokiConfig) GetTimeoutDuration() time.Duration {
 	return d
 }
 
-// GetCommitsLookbackDuration returns the commits lookback as a time.Duration
-func (c *AnalysisConfig) GetCommitsLookbackDuration() time.Duration {
-	d, _ := time.ParseDuration(c.CommitsLookback)
-	if d == 0 {
-		return 24 * time.Hour
+// GetMaxQueryWindowDuration parses the widest window sent to Loki as one query. Defaults to 6h.
+func (c *LokiConfig) GetMaxQueryWindowDuration() time.Duration {
+	d, _ := time.ParseDuration(c.MaxQueryWindow)
+	if d <= 0 {
+		return 6 * time.Hour
 	}
 	return d
 }
 
-// GetLogsLookbackDuration parses the configured log lookback window into a time.Duration.
-func (c *AnalysisConfig) GetLogsLookbackDuration() time.Duration {
-	d, _ := time.ParseDuration(c.LogsLookback)
-	if d == 0 {
-		return 1 * time.Hour
+// GetRetries returns how many times a failed Loki query is retried. Defaults to 2; negative disables retries.
+func (c *LokiConfig) GetRetries() int {
+	if c.Retries < 0 {
+		return 0
 	}
-	return d
+	if c.Retries == 0 {
+		return 2
+	}
+	return c.Retries
 }
 
-// GetMetricsWindowDuration parses the configured metrics gathering window into a time.Duration.
-func (c *AnalysisConfig) GetMetricsWindowDuration() time.Duration {
-	d, _ := time.ParseDuration(c.MetricsWindow)
-	if d == 0 {
-		return 15 * time.Minute
+// GetRetryBackoffDuration parses the wait before the first Loki retry; it doubles per attempt. Defaults to 500ms.
+func (c *LokiConfig) GetRetryBackoffDuration() time.Duration {
+	d, _ := time.ParseDuration(c.RetryBackoff)
+	if d <= 0 {
+		return 500 * time.Millisecond
 	}
 	return d
 }
 
-// Load loads configuration from config.yaml or environment variables
-func Load() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./config")
-	viper.AddConfigPath("/etc/helixops")
-
-	// Allow environment variables to override config
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-
-	// Set defaults
-	viper.SetDefault("app.host", "0.0.0.0")
-	viper.SetDefault("app.port", 8080)
-	viper.SetDefault("app.log_level", "info")
-	viper.SetDefault("prometheus.timeout", "30s")
-	viper.SetDefault("loki.timeout", "30s")
-	viper.SetDefault("tempo.timeout", "30s")
-	viper.SetDefault("tempo.enabled", true)
-	viper.SetDefault("tempo.slow_span_threshold_ms", 500)
-	viper.SetDefault("tempo.search_limit", 20)
-	viper.SetDefault("llm.provider", "openai")
-	viper.SetDefault("llm.model", "gpt-4o")
-	viper.SetDefault("llm.temperature", 0.1)
-	viper.SetDefault("llm.max_tokens", 1000)
-	viper.SetDefault("analysis.metrics_window", "15m")
-	viper.SetDefault("analysis.commits_lookback", "24h")
-	viper.SetDefault("analysis.logs_lookback", "1h")
-
-	// Read config file
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("failed to read config file: %w", err)
-		}
+// GetTimeoutDuration parses the configured Alertmanager API timeout into a time.Duration.
+func (c *AlertmanagerConfig) GetTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Timeout)
+	if d == 0 {
+		return 10 * time.Second
 	}
+	return d
+}
 
-	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+// GetToolTimeoutDuration returns the deadline applied to each MCP tool call.
+func (c *MCPConfig) GetToolTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.ToolTimeout)
+	if d <= 0 {
+		return 2 * time.Minute
 	}
+	return d
+}
 
-	// Get API keys from environment if token_env is set
-	if cfg.GitHub.TokenEnv != "" {
-		cfg.GitHub.Token = os.Getenv(cfg.GitHub.TokenEnv)
+// GetMaxConcurrentTools returns how many MCP tool calls may execute at once.
+func (c *MCPConfig) GetMaxConcurrentTools() int {
+	if c.MaxConcurrentTools <= 0 {
+		return 4
 	}
+	return c.MaxConcurrentTools
+}
 
-	if cfg.LLM.Provider != "ollama" {
-		apiKeyEnv := "OPENAI_API_KEY"
-		if cfg.LLM.Provider == "anthropic" {
-			apiKeyEnv = "ANTHROPIC_API_KEY"
+// NormalizeSeverity maps a raw severity label to critical, warning, or info. The second result is
+// false when the value is not mapped, in which case info is returned.
+func (c *AlertingConfig) NormalizeSeverity(raw string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	for k, v := range c.SeverityMap {
+		if strings.ToLower(k) == key {
+			return strings.ToLower(v), true
 		}
-		cfg.LLM.APIKey = os.Getenv(apiKeyEnv)
 	}
-
-	if cfg.Output.Slack.WebhookURLEnv != "" {
-		cfg.Output.Slack.WebhookURL = os.Getenv(cfg.Output.Slack.WebhookURLEnv)
+	if v, ok := defaultSeverityMap[key]; ok {
+		return v, true
 	}
-
-	return &cfg, nil
+	return SeverityInfo, false
+}
+
+// GetCacheTTLDuration returns how long a listing of the org's repositories is cached.
+func (c *RepoDiscoveryConfig) GetCacheTTLDuration() time.Duration {
+	d, _ := time.ParseDuration(c.CacheTTL)
+	if d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// GetIntervalDuration returns how often Prometheus alerts are polled.
+func (c *AlertPollConfig) GetIntervalDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Interval)
+	if d <= 0 {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// GetIntervalDuration parses the digest period. Defaults to 24 hours.
+func (c *DigestConfig) GetIntervalDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Interval)
+	if d <= 0 {
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// GetWindowDuration parses the correlation window for grouping alerts. Defaults to 10m.
+func (c *AggregationConfig) GetWindowDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Window)
+	if d <= 0 {
+		return 10 * time.Minute
+	}
+	return d
+}
+
+// GetTimeoutDuration parses the per-request webhook timeout. Defaults to 10s.
+func (c *WebhookOutputConfig) GetTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Timeout)
+	if d <= 0 {
+		return 10 * time.Second
+	}
+	return d
+}
+
+// GetJob returns the Pushgateway job name. Defaults to helixops.
+func (c *MetricsOutputConfig) GetJob() string {
+	if c.Job == "" {
+		return "helixops"
+	}
+	return c.Job
+}
+
+// GetTimeoutDuration parses the Pushgateway request timeout. Defaults to 10s.
+func (c *MetricsOutputConfig) GetTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Timeout)
+	if d <= 0 {
+		return 10 * time.Second
+	}
+	return d
+}
+
+// GetRetries returns how many times a failed webhook delivery is retried. Defaults to 3, at most 10.
+func (c *WebhookOutputConfig) GetRetries() int {
+	if c.Retries < 0 {
+		return 0
+	}
+	if c.Retries == 0 {
+		return 3
+	}
+	return min(c.Retries, 10)
+}
+
+// GetRetryBackoffDuration parses the wait before the first webhook retry; it doubles per attempt. Defaults to 1s.
+func (c *WebhookOutputConfig) GetRetryBackoffDuration() time.Duration {
+	d, _ := time.ParseDuration(c.RetryBackoff)
+	if d <= 0 {
+		return time.Second
+	}
+	return d
+}
+
+// GetSchedule parses Schedule in Timezone, or returns nil when the digest runs every Interval.
+func (c *DigestConfig) GetSchedule() (*schedule.Cron, error) {
+	if c.Schedule == "" {
+		return nil, nil
+	}
+	loc := time.UTC
+	if c.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(c.Timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	return schedule.Parse(c.Schedule, loc)
+}
+
+// GetJitterDuration parses the maximum random delay added to each digest. Defaults to none.
+func (c *DigestConfig) GetJitterDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Jitter)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetRetentionDuration parses the incident retention period; a "d" suffix means days. Defaults to 90 days.
+func (c *DatabaseConfig) GetRetentionDuration() time.Duration {
+	d, _ := parseDays(c.Retention)
+	if d <= 0 {
+		return 90 * 24 * time.Hour
+	}
+	return d
+}
+
+// GetCleanupIntervalDuration returns how often the retention janitor runs.
+func (c *DatabaseConfig) GetCleanupIntervalDuration() time.Duration {
+	d, _ := parseDays(c.CleanupInterval)
+	if d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// parseDays extends time.ParseDuration with a whole-day "d" suffix (e.g. "90d").
+func parseDays(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// GetCommitsLookbackDuration returns the commits lookback as a time.Duration
+func (c *AnalysisConfig) GetCommitsLookbackDuration() time.Duration {
+	d, _ := time.ParseDuration(c.CommitsLookback)
+	if d == 0 {
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// GetLogsLookbackDuration parses the configured log lookback window into a time.Duration.
+func (c *AnalysisConfig) GetLogsLookbackDuration() time.Duration {
+	d, _ := time.ParseDuration(c.LogsLookback)
+	if d == 0 {
+		return 1 * time.Hour
+	}
+	return d
+}
+
+// GetMetricsWindowDuration parses the configured metrics gathering window into a time.Duration.
+func (c *AnalysisConfig) GetMetricsWindowDuration() time.Duration {
+	d, _ := time.ParseDuration(c.MetricsWindow)
+	if d == 0 {
+		return 15 * time.Minute
+	}
+	return d
+}
+
+// GetMaxConcurrency returns how many context collectors may run at once.
+func (c *AnalysisConfig) GetMaxConcurrency() int {
+	if c.MaxConcurrency <= 0 {
+		return 4
+	}
+	return c.MaxConcurrency
+}
+
+// GetContextDeadlineDuration returns the soft deadline for gathering context; zero waits for every collector.
+func (c *AnalysisConfig) GetContextDeadlineDuration() time.Duration {
+	d, _ := time.ParseDuration(c.ContextDeadline)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetEnrichmentBudgetDuration returns the hard ceiling on gathering context; zero means none.
+func (c *AnalysisConfig) GetEnrichmentBudgetDuration() time.Duration {
+	d, _ := time.ParseDuration(c.EnrichmentBudget)
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// GetRequiredSources returns the collectors analysis always waits for, even past the context deadline.
+func (c *AnalysisConfig) GetRequiredSources() []string {
+	if len(c.RequiredSources) == 0 {
+		return []string{"metrics"}
+	}
+	return c.RequiredSources
+}
+
+// ContextSources are the built-in collectors an enrichment policy can select.
+var ContextSources = []string{"metrics", "commits", "traces", "logs"}
+
+// defaultEnrichment keeps Tempo and Loki out of the way of lower-severity alert storms.
+var defaultEnrichment = map[string][]string{
+	SeverityCritical: {"metrics", "commits", "traces", "logs"},
+	SeverityWarning:  {"metrics", "commits"},
+	SeverityInfo:     {"metrics"},
+}
+
+// GetEnrichmentSources returns the built-in context sources gathered for an alert of the given
+// normalized severity, or nil (every source) when the severity is unknown or empty.
+func (c *AnalysisConfig) GetEnrichmentSources(severity string) []string {
+	severity = strings.ToLower(severity)
+	if sources, ok := c.Enrichment[severity]; ok {
+		return sources
+	}
+	return defaultEnrichment[severity]
+}
+
+// GetPriorIncidents returns how many prior incidents are included in RCA prompts.
+func (c *AnalysisConfig) GetPriorIncidents() int {
+	if c.PriorIncidents <= 0 {
+		return 3
+	}
+	return c.PriorIncidents
+}
+
+// GetProcessingRetries returns how many attempts are made to process an alert before giving up.
+func (c *AnalysisConfig) GetProcessingRetries() int {
+	if c.ProcessingRetries <= 0 {
+		return 3
+	}
+	return c.ProcessingRetries
+}
+
+// GetProcessingBackoffDuration returns the wait before the first processing retry; it doubles per attempt.
+func (c *AnalysisConfig) GetProcessingBackoffDuration() time.Duration {
+	d, _ := time.ParseDuration(c.ProcessingBackoff)
+	if d == 0 {
+		return 2 * time.Second
+	}
+	return d
+}
+
+// GetNoAnomaly returns the outcome for alerts without any sign of a problem. Defaults to analyze.
+func (c *AnalysisConfig) GetNoAnomaly() string {
+	if mode := strings.ToLower(strings.TrimSpace(c.NoAnomaly)); mode != "" {
+		return mode
+	}
+	return NoAnomalyAnalyze
+}
+
+// GetLanguage returns the language LLM responses are written in. Defaults to English.
+func (c *AnalysisConfig) GetLanguage() string {
+	if strings.TrimSpace(c.Language) == "" {
+		return "English"
+	}
+	return strings.TrimSpace(c.Language)
+}
+
+// GetPlatform returns the platform remediation commands target. Defaults to kubernetes.
+func (c *AnalysisConfig) GetPlatform() string {
+	if platform := strings.ToLower(strings.TrimSpace(c.Platform)); platform != "" {
+		return platform
+	}
+	return PlatformKubernetes
+}
+
+// validatePlatform rejects a platform remediation commands cannot be generated for.
+func (c *AnalysisConfig) validatePlatform() error {
+	switch c.GetPlatform() {
+	case PlatformKubernetes, PlatformECS, PlatformSystemd:
+		return nil
+	}
+	return fmt.Errorf("unsupported value %q (expected kubernetes, ecs, or systemd)", c.Platform)
+}
+
+// IsEnglish reports whether responses use the default language, in which case prompts carry no
+// language instruction.
+func (c *AnalysisConfig) IsEnglish() bool {
+	lang := c.GetLanguage()
+	return strings.EqualFold(lang, "english") || strings.EqualFold(lang, "en")
+}
+
+// defaultPromptLabels are environment labels that commonly help localize a failure.
+var defaultPromptLabels = []string{"namespace", "cluster", "region", "zone", "environment", "pod", "instance", "job"}
+
+// defaultPromptAnnotations are the standard Prometheus alerting rule annotations.
+var defaultPromptAnnotations = []string{"summary", "description", "runbook_url"}
+
+// GetPromptLabels returns the alert labels allowed into LLM prompts.
+func (c *AnalysisConfig) GetPromptLabels() []string {
+	if len(c.PromptLabels) == 0 {
+		return defaultPromptLabels
+	}
+	return c.PromptLabels
+}
+
+// GetPromptAnnotations returns the alert annotations allowed into LLM prompts.
+func (c *AnalysisConfig) GetPromptAnnotations() []string {
+	if len(c.PromptAnnotations) == 0 {
+		return defaultPromptAnnotations
+	}
+	return c.PromptAnnotations
+}
+
+// ConfigFileEnv names the environment variable that points Load at an exact config file.
+const ConfigFileEnv = "HELIXOPS_CONFIG"
+
+// Load loads configuration from config.yaml or environment variables
+func Load() (*Config, error) {
+	return LoadFile("")
+}
+
+// LoadFile is Load reading exactly the config file at path, or the one named by HELIXOPS_CONFIG when
+// path is empty. With neither set, config.yaml is searched for in ., ./config, and /etc/helixops.
+// Unlike a searched-for file, an explicit file that does not exist is an error.
+func LoadFile(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+	viper.SetConfigType("yaml")
+	if path != "" {
+		viper.SetConfigFile(path)
+	} else {
+		viper.SetConfigName("config")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("./config")
+		viper.AddConfigPath("/etc/helixops")
+	}
+
+	// Allow environment variables to override config
+	viper.AutomaticEnv()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	// Set defaults
+	viper.SetDefault("app.host", "0.0.0.0")
+	viper.SetDefault("app.port", 8080)
+	viper.SetDefault("app.log_level", "info")
+	viper.SetDefault("app.log_format", "text")
+	viper.SetDefault("app.request_id_header", "X-Request-Id")
+	viper.SetDefault("prometheus.timeout", "30s")
+	viper.SetDefault("loki.timeout", "30s")
+	viper.SetDefault("loki.max_query_window", "6h")
+	viper.SetDefault("loki.retries", 2)
+	viper.SetDefault("loki.retry_backoff", "500ms")
+	viper.SetDefault("tempo.timeout", "30s")
+	viper.SetDefault("tempo.enabled", true)
+	viper.SetDefault("tempo.slow_span_threshold_ms", 500)
+	viper.SetDefault("tempo.search_limit", 20)
+	viper.SetDefault("llm.provider", "openai")
+	viper.SetDefault("llm.model", "gpt-4o")
+	viper.SetDefault("llm.temperature", 0.1)
+	viper.SetDefault("analysis.metrics_window", "15m")
+	viper.SetDefault("analysis.commits_lookback", "24h")
+	viper.SetDefault("analysis.logs_lookback", "1h")
+	viper.SetDefault("analysis.max_concurrency", 4)
+	viper.SetDefault("database.retention", "90d")
+	viper.SetDefault("database.cleanup_interval", "1h")
+	viper.SetDefault("analysis.processing_retries", 3)
+	viper.SetDefault("analysis.processing_backoff", "2s")
+	viper.SetDefault("alerting.poll.interval", "30s")
+	viper.SetDefault("github.discovery.cache_ttl", "1h")
+	viper.SetDefault("output.digest.interval", "24h")
+	viper.SetDefault("mcp.tool_timeout", "2m")
+	viper.SetDefault("mcp.max_concurrent_tools", 4)
+	viper.SetDefault("postmortem.resolve_delay", "2m")
+
+	// Read config file
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	var cfg Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// resolveSecrets loads credentials from their environment variables, falling back to files
+// mounted as Docker/Kubernetes secrets.
+func (c *Config) resolveSecrets() error {
+	var err error
+
+	if c.GitHub.Token, err = resolveSecret(c.GitHub.TokenEnv, c.GitHub.TokenFile); err != nil {
+		return fmt.Errorf("github token: %w", err)
+	}
+
+	if err := c.LLM.resolveSecrets(); err != nil {
+		return err
+	}
+
+	if err := c.Output.Slack.resolveSecrets(); err != nil {
+		return err
+	}
+
+	if c.Output.Webhook.Secret, err = resolveSecret(c.Output.Webhook.SecretEnv, c.Output.Webhook.SecretFile); err != nil {
+		return fmt.Errorf("webhook secret: %w", err)
+	}
+
+	if c.Database.Password, err = resolveSecret("HELIX_DB_PASSWORD", c.Database.PasswordFile); err != nil {
+		return fmt.Errorf("database password: %w", err)
+	}
+
+	if c.App.AdminToken, err = resolveSecret("HELIX_ADMIN_TOKEN", c.App.AdminTokenFile); err != nil {
+		return fmt.Errorf("admin token: %w", err)
+	}
+
+	for name, p := range c.AnalysisProfiles {
+		if p.LLM != nil {
+			if err := p.LLM.resolveSecrets(); err != nil {
+				return fmt.Errorf("analysis_profiles.%s: %w", name, err)
+			}
+		}
+	}
+
+	for name, rc := range c.Receivers {
+		if rc.LLM != nil {
+			if err := rc.LLM.resolveSecrets(); err != nil {
+				return fmt.Errorf("receivers.%s: %w", name, err)
+			}
+		}
+		if rc.Output != nil {
+			if err := rc.Output.Slack.resolveSecrets(); err != nil {
+				return fmt.Errorf("receivers.%s: %w", name, err)
+			}
+			if rc.Output.Webhook.Secret, err = resolveSecret(rc.Output.Webhook.SecretEnv, rc.Output.Webhook.SecretFile); err != nil {
+				return fmt.Errorf("receivers.%s: webhook secret: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveSecrets loads the provider API key; Ollama needs none.
+func (c *LLMConfig) resolveSecrets() error {
+	if c.ProviderType() == "ollama" {
+		return nil
+	}
+	apiKeyEnv := "OPENAI_API_KEY"
+	if c.ProviderType() == "anthropic" {
+		apiKeyEnv = "ANTHROPIC_API_KEY"
+	}
+	var err error
+	if c.APIKey, err = resolveSecret(apiKeyEnv, c.APIKeyFile); err != nil {
+		return fmt.Errorf("llm api key: %w", err)
+	}
+	return nil
+}
+
+// resolveSecrets loads the Slack webhook URL and bot token.
+func (c *SlackOutputConfig) resolveSecrets() error {
+	var err error
+	if c.WebhookURL, err = resolveSecret(c.WebhookURLEnv, c.WebhookURLFile); err != nil {
+		return fmt.Errorf("slack webhook url: %w", err)
+	}
+	if c.BotToken, err = resolveSecret(c.BotTokenEnv, c.BotTokenFile); err != nil {
+		return fmt.Errorf("slack bot token: %w", err)
+	}
+	return nil
+}
+
+// resolveSecret returns a secret from, in order of precedence: the envVar environment variable,
+// a file named by the <envVar>_FILE environment variable, or the configured file path.
+// File contents are trimmed of surrounding whitespace.
+func resolveSecret(envVar, file string) (string, error) {
+	if envVar != "" {
+		if v := os.Getenv(envVar); v != "" {
+			return v, nil
+		}
+		if path := os.Getenv(envVar + "_FILE"); path != "" {
+			file = path
+		}
+	}
+	if file == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Validate rejects settings that would otherwise be silently ignored at runtime.
+func (c *Config) Validate() error {
+	if _, err := c.App.GetLogLevel(); err != nil {
+		return fmt.Errorf("app.log_level: %w", err)
+	}
+
+	if c.Postmortem.Policy.MinDuration != "" {
+		if _, err := time.ParseDuration(c.Postmortem.Policy.MinDuration); err != nil {
+			return fmt.Errorf("postmortem.policy.min_duration: %w", err)
+		}
+	}
+
+	for field, patterns := range map[string][]string{"exclude_authors": c.GitHub.CommitFilter.ExcludeAuthors, "exclude_messages": c.GitHub.CommitFilter.ExcludeMessages} {
+		for i, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("github.commit_filter.%s[%d]: %w", field, i, err)
+			}
+		}
+	}
+
+	if c.LLM.MaxTokens < 0 {
+		return fmt.Errorf("llm.max_tokens: must not be negative")
+	}
+	if c.LLM.PostmortemMaxTokens < 0 {
+		return fmt.Errorf("llm.postmortem_max_tokens: must not be negative")
+	}
+	if r := c.Output.Recurring; r.Enabled {
+		if r.Threshold == 1 || r.Threshold < 0 {
+			return fmt.Errorf("output.recurring.threshold: must be at least 2")
+		}
+		if r.Similarity < 0 || r.Similarity > 1 {
+			return fmt.Errorf("output.recurring.similarity: must be between 0 and 1")
+		}
+		if r.Window != "" {
+			if d, err := time.ParseDuration(r.Window); err != nil {
+				return fmt.Errorf("output.recurring.window: %w", err)
+			} else if d <= 0 {
+				return fmt.Errorf("output.recurring.window: must be positive")
+			}
+		}
+	}
+
+	if err := c.validateAccountLimits(); err != nil {
+		return err
+	}
+
+	for key, value := range map[string]string{"loki.max_query_window": c.Loki.MaxQueryWindow, "loki.retry_backoff": c.Loki.RetryBackoff} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		} else if d < 0 {
+			return fmt.Errorf("%s: must not be negative", key)
+		}
+	}
+
+	for name, cc := range map[string]ConcurrencyConfig{"loki": c.Loki.Concurrency, "tempo": c.Tempo.Concurrency} {
+		if cc.MaxQueries < 0 {
+			return fmt.Errorf("%s.concurrency.max_queries: must not be negative", name)
+		}
+		if cc.QueueTimeout != "" {
+			if _, err := time.ParseDuration(cc.QueueTimeout); err != nil {
+				return fmt.Errorf("%s.concurrency.queue_timeout: %w", name, err)
+			}
+		}
+	}
+
+	if c.Postmortem.MaxBodyChars < 0 {
+		return fmt.Errorf("postmortem.max_body_chars: must not be negative")
+	}
+	if c.Output.Slack.MaxTextChars < 0 {
+		return fmt.Errorf("output.slack.max_text_chars: must not be negative")
+	}
+
+	if c.Postmortem.ResolveDelay != "" {
+		d, err := time.ParseDuration(c.Postmortem.ResolveDelay)
+		if err != nil {
+			return fmt.Errorf("postmortem.resolve_delay: %w", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("postmortem.resolve_delay: must not be negative")
+		}
+	}
+
+	if c.Postmortem.MaxDuration != "" {
+		if _, err := parseDays(c.Postmortem.MaxDuration); err != nil {
+			return fmt.Errorf("postmortem.max_duration: %w", err)
+		}
+	}
+
+	switch strings.ToLower(c.Database.Driver) {
+	case "", DatabaseDriverPostgres:
+	case DatabaseDriverSQLite, "sqlite3":
+		if c.Database.Enabled && c.Database.DSN == "" {
+			return fmt.Errorf("database.dsn: required for the sqlite driver, e.g. a file path")
+		}
+	default:
+		return fmt.Errorf("database.driver: unsupported driver %q (valid drivers: %s, %s)", c.Database.Driver, DatabaseDriverPostgres, DatabaseDriverSQLite)
+	}
+
+	if c.Database.Retention != "" {
+		if _, err := parseDays(c.Database.Retention); err != nil {
+			return fmt.Errorf("database.retention: %w", err)
+		}
+	}
+
+	for raw, severity := range c.Alerting.SeverityMap {
+		switch strings.ToLower(severity) {
+		case SeverityCritical, SeverityWarning, SeverityInfo:
+		default:
+			return fmt.Errorf("alerting.severity_map.%s: unsupported severity %q (expected critical, warning, or info)", raw, severity)
+		}
+	}
+
+	for i, w := range c.Alerting.MaintenanceWindows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("alerting.maintenance_windows[%d]: %w", i, err)
+		}
+	}
+
+	for severity, sources := range c.Analysis.Enrichment {
+		if _, ok := defaultEnrichment[strings.ToLower(severity)]; !ok {
+			return fmt.Errorf("analysis.enrichment.%s: unsupported severity (expected critical, warning, or info)", severity)
+		}
+		for _, source := range sources {
+			if !slices.Contains(ContextSources, source) {
+				return fmt.Errorf("analysis.enrichment.%s: unknown source %q (expected one of %s)", severity, source, strings.Join(ContextSources, ", "))
+			}
+		}
+	}
+
+	if c.Alerting.Aggregation.Window != "" {
+		if _, err := time.ParseDuration(c.Alerting.Aggregation.Window); err != nil {
+			return fmt.Errorf("alerting.aggregation.window: %w", err)
+		}
+	}
+
+	for _, pattern := range c.Analysis.Triage.TransientAlerts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("analysis.triage.transient_alerts: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	switch c.Analysis.GetNoAnomaly() {
+	case NoAnomalyAnalyze, NoAnomalyNotify, NoAnomalySuppress:
+	default:
+		return fmt.Errorf("analysis.no_anomaly: unsupported value %q (expected analyze, notify, or suppress)", c.Analysis.NoAnomaly)
+	}
+
+	if err := c.Analysis.validatePlatform(); err != nil {
+		return fmt.Errorf("analysis.platform: %w", err)
+	}
+
+	for i, h := range c.Analysis.PromptHints {
+		if h.Alert == "" || strings.TrimSpace(h.Hint) == "" {
+			return fmt.Errorf("analysis.prompt_hints[%d]: alert and hint are required", i)
+		}
+		for _, pattern := range []string{h.Alert, h.Service} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("analysis.prompt_hints[%d]: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+
+	for name, svc := range c.Services {
+		if svc.LogSelector != "" {
+			if err := validateLogSelector(svc.LogSelector); err != nil {
+				return fmt.Errorf("services.%s.log_selector: %w", name, err)
+			}
+		}
+		for i, d := range svc.Dashboards {
+			if err := d.validate(); err != nil {
+				return fmt.Errorf("services.%s.dashboards[%d]: %w", name, i, err)
+			}
+		}
+	}
+	for i, d := range c.Dashboards {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("dashboards[%d]: %w", i, err)
+		}
+	}
+	for name, p := range c.AnalysisProfiles {
+		for _, source := range p.Sources {
+			if !slices.Contains(ContextSources, source) {
+				return fmt.Errorf("analysis_profiles.%s.sources: unknown source %q (expected one of %s)", name, source, strings.Join(ContextSources, ", "))
+			}
+		}
+		if p.MaxPromptTokens < 0 {
+			return fmt.Errorf("analysis_profiles.%s.max_prompt_tokens: must not be negative", name)
+		}
+		if p.Platform != "" {
+			if err := (&AnalysisConfig{Platform: p.Platform}).validatePlatform(); err != nil {
+				return fmt.Errorf("analysis_profiles.%s.platform: %w", name, err)
+			}
+		}
+	}
+	for severity, name := range c.Analysis.SeverityProfiles {
+		if _, ok := defaultEnrichment[strings.ToLower(severity)]; !ok {
+			return fmt.Errorf("analysis.severity_profiles.%s: unsupported severity (expected critical, warning, or info)", severity)
+		}
+		if _, ok := c.AnalysisProfiles[name]; !ok {
+			return fmt.Errorf("analysis.severity_profiles.%s: unknown analysis profile %q", severity, name)
+		}
+	}
+	for receiver, rc := range c.Receivers {
+		if _, ok := c.AnalysisProfiles[rc.Profile]; rc.Profile != "" && !ok {
+			return fmt.Errorf("receivers.%s.profile: unknown analysis profile %q", receiver, rc.Profile)
+		}
+		for name, svc := range rc.Services {
+			if svc.LogSelector != "" {
+				if err := validateLogSelector(svc.LogSelector); err != nil {
+					return fmt.Errorf("receivers.%s.services.%s.log_selector: %w", receiver, name, err)
+				}
+			}
+		}
+	}
+
+	if c.Analysis.MaxPromptTokens < 0 {
+		return fmt.Errorf("analysis.max_prompt_tokens: must not be negative")
+	}
+
+	if c.Analysis.ContextDeadline != "" {
+		if _, err := time.ParseDuration(c.Analysis.ContextDeadline); err != nil {
+			return fmt.Errorf("analysis.context_deadline: %w", err)
+		}
+	}
+
+	if c.Analysis.EnrichmentBudget != "" {
+		budget, err := time.ParseDuration(c.Analysis.EnrichmentBudget)
+		if err != nil {
+			return fmt.Errorf("analysis.enrichment_budget: %w", err)
+		}
+		if budget <= 0 {
+			return fmt.Errorf("analysis.enrichment_budget: must be positive")
+		}
+		if c.Analysis.GetContextDeadlineDuration() > budget {
+			return fmt.Errorf("analysis.enrichment_budget: must not be shorter than analysis.context_deadline")
+		}
+	}
+
+	if c.Output.Digest.Jitter != "" {
+		if _, err := time.ParseDuration(c.Output.Digest.Jitter); err != nil {
+			return fmt.Errorf("output.digest.jitter: %w", err)
+		}
+	}
+	if _, err := c.Output.Digest.GetSchedule(); err != nil {
+		return fmt.Errorf("output.digest.schedule: %w", err)
+	}
+
+	for _, pattern := range c.GitHub.AllowedRepos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("github.allowed_repos: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.Output.Webhook.Enabled && c.Output.Webhook.URL == "" {
+		return fmt.Errorf("output.webhook: url is required when enabled")
+	}
+	if c.Output.Metrics.Timeout != "" {
+		if _, err := time.ParseDuration(c.Output.Metrics.Timeout); err != nil {
+			return fmt.Errorf("output.metrics.timeout: %w", err)
+		}
+	}
+
+	if c.GitHub.Discovery.Enabled && c.GitHub.DefaultOrg == "" {
+		return fmt.Errorf("github.discovery: default_org is required to list repositories")
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.App.LogFormat)) {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("app.log_format: unsupported format %q (expected text or json)", c.App.LogFormat)
+	}
+
+	return nil
+}
+
+// validateAccountLimits checks llm.max_concurrent_requests of the base LLM settings and of every receiver
+// and analysis profile overriding them. Settings for the same account share one limit, so they must
+// agree on it; otherwise the limits would add up past what the account allows.
+func (c *Config) validateAccountLimits() error {
+	fields := []string{"llm"}
+	llms := map[string]*LLMConfig{"llm": &c.LLM}
+	for _, name := range slices.Sorted(maps.Keys(c.AnalysisProfiles)) {
+		if p := c.AnalysisProfiles[name]; p.LLM != nil {
+			field := "analysis_profiles." + name + ".llm"
+			fields, llms[field] = append(fields, field), p.LLM
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Receivers)) {
+		if rc := c.Receivers[name]; rc.LLM != nil {
+			field := "receivers." + name + ".llm"
+			fields, llms[field] = append(fields, field), rc.LLM
+		}
+	}
+
+	first := make(map[string]string)
+	for _, field := range fields {
+		llm := llms[field]
+		if llm.MaxConcurrentRequests < 0 {
+			return fmt.Errorf("%s.max_concurrent_requests: must not be negative", field)
+		}
+		prev, ok := first[llm.Account()]
+		if !ok {
+			first[llm.Account()] = field
+			continue
+		}
+		if limit := llms[prev].MaxConcurrentRequests; llm.MaxConcurrentRequests != limit {
+			return fmt.Errorf("%s.max_concurrent_requests: %d conflicts with %d in %s for the same provider account",
+				field, llm.MaxConcurrentRequests, limit, prev)
+		}
+	}
+	return nil
+}
+
+// ForReceiver returns the effective configuration for a named webhook receiver: a copy of the
+// global config with the receiver's analysis profile applied, then its LLM, output, and services
+// sections swapped in. The second result is false when no receiver with that name is configured.
+func (c *Config) ForReceiver(name string) (*Config, bool) {
+	rc, ok := c.Receivers[name]
+	if !ok {
+		return nil, false
+	}
+
+	eff := *c
+	if rc.Profile != "" {
+		if p, ok := c.ForAnalysisProfile(rc.Profile); ok {
+			eff = *p
+		}
+	}
+	eff.Receivers = nil
+	if rc.LLM != nil {
+		eff.LLM = *rc.LLM
+	}
+	if rc.Output != nil {
+		eff.Output = *rc.Output
+	}
+	if rc.Services != nil {
+		eff.Services = rc.Services
+	}
+	return &eff, true
+}
+
+// ForAnalysisProfile returns a copy of the config with the named analysis profile's settings in place
+// of the base LLM and analysis settings. Severity profiles are dropped from the copy, so alerts analyzed
+// with a profile do not switch profiles again. The second result is false for an unknown profile.
+func (c *Config) ForAnalysisProfile(name string) (*Config, bool) {
+	p, ok := c.AnalysisProfiles[name]
+	if !ok {
+		return nil, false
+	}
+
+	eff := *c
+	eff.Analysis.SeverityProfiles = nil
+	if p.LLM != nil {
+		eff.LLM = *p.LLM
+	}
+	if p.Sources != nil {
+		eff.Analysis.Enrichment = make(map[string][]string, len(defaultEnrichment))
+		for severity := range defaultEnrichment {
+			eff.Analysis.Enrichment[severity] = p.Sources
+		}
+	}
+	if p.PromptLabels != nil {
+		eff.Analysis.PromptLabels = p.PromptLabels
+	}
+	if p.PromptAnnotations != nil {
+		eff.Analysis.PromptAnnotations = p.PromptAnnotations
+	}
+	if p.PromptHints != nil {
+		eff.Analysis.PromptHints = p.PromptHints
+	}
+	if p.Language != "" {
+		eff.Analysis.Language = p.Language
+	}
+	if p.Platform != "" {
+		eff.Analysis.Platform = p.Platform
+	}
+	if p.MaxPromptTokens > 0 {
+		eff.Analysis.MaxPromptTokens = p.MaxPromptTokens
+	}
+	return &eff, true
+}
+
+// MappedRepo returns the repository explicitly configured for a service (services block first,
+// then github.service_mapping), or "" when the service has no mapping.
+func (c *Config) MappedRepo(serviceName string) string {
+	if repo := c.Services[serviceName].Repo; repo != "" {
+		return repo
+	}
+	return c.GitHub.ServiceMapping[serviceName]
+}
+
+// ResolveService returns the settings for a service, resolving its repository from the
+// services block, then github.service_mapping, then github.default_org/<service>.
+func (c *Config) ResolveService(serviceName string) ServiceConfig {
+	svc := c.Services[serviceName]
+	svc.Repo = c.MappedRepo(serviceName)
+	if svc.Repo == "" {
+		if c.GitHub.DefaultOrg != "" {
+			svc.Repo = c.GitHub.DefaultOrg + "/" + serviceName
+		} else {
+			svc.Repo = serviceName // Last resort fallback
+		}
+	}
+	return svc
 }
 
 // ProviderType returns the LLM provider type
 func (c *LLMConfig) ProviderType() string {
 	return strings.ToLower(c.Provider)
 }
+
+// Account identifies the provider account requests are made on: the provider, its endpoint
+// (base_url, or ollama_url for Ollama), and the API key.
+func (c *LLMConfig) Account() string {
+	endpoint := c.BaseURL
+	if c.ProviderType() == "ollama" {
+		endpoint = c.OllamaURL
+	}
+	return c.ProviderType() + "|" + endpoint + "|" + c.APIKey
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// redactedValue replaces every secret in Redacted's output.
+const redactedValue = "***"
+
+// secretSuffixes mark keys whose values are credentials; *_env and *_file keys name where a secret
+// comes from and are shown as is.
+var secretSuffixes = []string{"token", "secret", "password", "api_key", "webhook_url", "dsn"}
+
+// sensitiveHeaders are request header names whose values are redacted.
+var sensitiveHeaders = []string{"authorization", "cookie", "token", "key", "secret"}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Redacted returns the effective configuration as nested maps keyed like config.yaml, for display.
+// Resolved secrets (API keys, tokens, passwords, webhook URLs), sensitive request headers, and URL
+// credentials are replaced with "***". Settings with a Get*Duration accessor show the duration actually
+// used, defaults and "Nd" day values included, rather than the raw string.
+func (c *Config) Redacted() map[string]interface{} {
+	out, _ := redactValue(reflect.ValueOf(*c), "").(map[string]interface{})
+	return out
+}
+
+// redactValue converts v into maps, slices, and scalars, redacting secrets found at path.
+func redactValue(v reflect.Value, path string) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactValue(v.Elem(), path)
+	case reflect.Struct:
+		return redactStruct(v, path)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if v.Type().Elem().Kind() == reflect.String && strings.HasSuffix(path, "headers") && sensitiveHeader(key) {
+				out[key] = redactedValue
+				continue
+			}
+			out[key] = redactValue(iter.Value(), joinPath(path, key))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = redactValue(v.Index(i), path)
+		}
+		return out
+	case reflect.String:
+		return redactURL(v.String())
+	default:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()).String()
+		}
+		return v.Interface()
+	}
+}
+
+// redactStruct maps each field to its mapstructure key, then overlays the resolved durations.
+func redactStruct(v reflect.Value, path string) map[string]interface{} {
+	out := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		resolvedSecret := key == "-"
+		if resolvedSecret || key == "" {
+			key = snakeCase(field.Name)
+		}
+		fieldPath := joinPath(path, key)
+		if resolvedSecret || secretKey(fieldPath) {
+			if v.Field(i).IsZero() {
+				out[key] = ""
+			} else {
+				out[key] = redactedValue
+			}
+			continue
+		}
+		out[key] = redactValue(v.Field(i), fieldPath)
+	}
+
+	// Accessors are defined on pointers, so call them on an addressable copy
+	ptr := reflect.New(t)
+	ptr.Elem().Set(v)
+	for i := 0; i < ptr.NumMethod(); i++ {
+		name := ptr.Type().Method(i).Name
+		m := ptr.Method(i)
+		if !strings.HasPrefix(name, "Get") || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 || m.Type().Out(0) != durationType {
+			continue
+		}
+		key := snakeCase(strings.TrimSuffix(strings.TrimPrefix(name, "Get"), "Duration"))
+		if _, ok := out[key]; !ok {
+			key = snakeCase(strings.TrimPrefix(name, "Get"))
+		}
+		if _, ok := out[key]; ok {
+			out[key] = m.Call(nil)[0].Interface().(time.Duration).String()
+		}
+	}
+	return out
+}
+
+// secretKey reports whether the setting at path holds a credential.
+func secretKey(path string) bool {
+	if strings.HasSuffix(path, "webhook.url") {
+		return true
+	}
+	key := path[strings.LastIndex(path, ".")+1:]
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveHeader reports whether a header name suggests its value is a credential.
+func sensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveHeaders {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL masks the credentials of a URL that has them; other strings are returned unchanged.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	// A username without a password is often a token itself, so it is masked too
+	userinfo := redactedValue
+	if _, ok := u.User.Password(); ok {
+		userinfo = url.User(u.User.Username()).String() + ":" + redactedValue
+	}
+	u.User = nil
+	scheme, rest, _ := strings.Cut(u.String(), "://")
+	return scheme + "://" + userinfo + "@" + rest
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// snakeCase converts a Go field name such as APIKey or WebhookURL to api_key or webhook_url.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && unicode.IsLower(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -1,18 +1,23 @@
-// Package db provides structured access and database migrations for the PostgreSQL persistence layer.
+// Package db provides structured access and database migrations for the persistence layer, on
+// PostgreSQL or SQLite.
 package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
 
+	"helixops/internal/models"
+
 	_ "github.com/lib/pq"
 )
 
-// DB wraps the PostgreSQL database connection
+// DB implements Store over a database/sql connection to PostgreSQL or SQLite
 type DB struct {
 	*sql.DB
+	driver string
 }
 
 // New creates a new database connection
@@ -20,7 +25,13 @@ func New(host string, port int, user, password, dbname, sslmode string) (*DB, er
 	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		host, port, user, password, dbname, sslmode)
 
-	db, err := sql.Open("postgres", dsn)
+	return Open("postgres", dsn)
+}
+
+// Open connects using an explicit database/sql driver name and DSN: "postgres" or "sqlite3". Queries
+// are written for PostgreSQL and rewritten where SQLite differs, and Migrate creates the same schema on both.
+func Open(driver, dsn string) (*DB, error) {
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -35,10 +46,20 @@ func New(host string, port int, user, password, dbname, sslmode string) (*DB, er
 	}
 
 	return &DB{
-		DB: db,
+		DB:     db,
+		driver: driver,
 	}, nil
 }
 
+// addColumn adds a column to an existing table if it is not already present.
+func (db *DB) addColumn(table, column, definition string) error {
+	if db.isSQLite() {
+		return db.addSQLiteColumn(table, column, definition)
+	}
+	_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, definition))
+	return err
+}
+
 // Migrate runs database migrations
 func (db *DB) Migrate() error {
 	migrations := []string{
@@ -81,6 +102,55 @@ func (db *DB) Migrate() error {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (incident_id) REFERENCES incidents(id)
 		)`,
+		// Received alert payloads, persisted before processing so they survive a restart
+		`CREATE TABLE IF NOT EXISTS pending_alerts (
+			id SERIAL PRIMARY KEY,
+			receiver TEXT NOT NULL DEFAULT '',
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_pending_alerts_status ON pending_alerts(status)`,
+		// Last commit listing per repository, branch, and path, so alerts only fetch newer commits
+		`CREATE TABLE IF NOT EXISTS commit_cursors (
+			repo_key TEXT PRIMARY KEY,
+			head_sha TEXT NOT NULL,
+			cursor_data TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// Notifications already delivered per incident and channel, so duplicates are not re-sent
+		`CREATE TABLE IF NOT EXISTS sent_notifications (
+			incident_key TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (incident_key, channel)
+		)`,
+		// End of the window the last posted incident digest covered, so the next one starts there
+		`CREATE TABLE IF NOT EXISTS digest_state (
+			id INTEGER PRIMARY KEY,
+			last_end TIMESTAMP NOT NULL
+		)`,
+		// Responder verdicts on whether an incident's RCA was right, one per incident
+		`CREATE TABLE IF NOT EXISTS incident_feedback (
+			incident_id TEXT PRIMARY KEY,
+			correct BOOLEAN NOT NULL,
+			actual_cause TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (incident_id) REFERENCES incidents(id)
+		)`,
+		// Deploys reported by CD systems, correlated with alerts on the same service
+		`CREATE TABLE IF NOT EXISTS deploy_events (
+			id SERIAL PRIMARY KEY,
+			service_name TEXT NOT NULL,
+			version TEXT,
+			sha TEXT,
+			deployed_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deploy_events_service ON deploy_events(service_name, deployed_at)`,
 		// Indexes
 		`CREATE INDEX IF NOT EXISTS idx_incidents_service ON incidents(service_name)`,
 		`CREATE INDEX IF NOT EXISTS idx_incidents_status ON incidents(status)`,
@@ -88,11 +158,36 @@ func (db *DB) Migrate() error {
 	}
 
 	for _, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
+		if _, err := db.Exec(db.dialect(migration)); err != nil {
 			return fmt.Errorf("migration failed: %w", err)
 		}
 	}
 
+	// Columns added after the initial schema
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"incidents", "fingerprint", "TEXT"},
+		{"incidents", "slack_thread_ts", "TEXT"},
+		{"incidents", "last_error", "TEXT"},
+		{"incidents", "group_id", "TEXT"},
+		{"incidents", "confidence", "TEXT"},
+		{"incidents", "merged_into", "TEXT"},
+		{"service_mappings", "confirmed", "BOOLEAN DEFAULT FALSE"},
+		{"pending_alerts", "not_before", "TIMESTAMP"},
+		{"sent_notifications", "pending", "BOOLEAN DEFAULT FALSE"},
+		{"sent_notifications", "ref", "TEXT"},
+	}
+	for _, c := range columns {
+		if err := db.addColumn(c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("migration failed: add %s.%s: %w", c.table, c.column, err)
+		}
+	}
+
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_incidents_fingerprint ON incidents(fingerprint)`); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
 	return nil
 }
 
@@ -112,20 +207,77 @@ type Incident struct {
 	RootCause   *string
 	AISummary   *string
 	Status      string
+	Fingerprint string
+	// SlackThreadTS is the Slack message timestamp that RCA and postmortem replies thread under
+	SlackThreadTS string
+	// LastError explains why processing gave up on a failed incident
+	LastError string
+	// GroupID is the incident this alert was merged into by alert aggregation; empty for standalone
+	// incidents and for the incident that leads a group
+	GroupID string
+	// Confidence is the confidence the RCA stated, as written by the model (e.g. "85%" or "high")
+	Confidence string
+	// MergedInto is the incident an operator merged this duplicate into; set with status merged
+	MergedInto string
+}
+
+// Incident statuses
+const (
+	IncidentStatusOpen     = "open"
+	IncidentStatusResolved = "resolved"
+	IncidentStatusFailed   = "failed" // analysis gave up after retries; replay from the stored alert payload
+	// IncidentStatusMaintenance marks an alert that fired inside a maintenance window and was not analyzed;
+	// it keeps this status once closed, with resolved_at set
+	IncidentStatusMaintenance = "maintenance"
+	// IncidentStatusTriaged marks an alert that triage auto-resolved without an LLM call; like maintenance
+	// incidents it keeps this status once closed, and its resolution generates no postmortem
+	IncidentStatusTriaged = "triaged"
+	// IncidentStatusMerged marks a duplicate folded into another incident by POST /incidents/merge; its
+	// analysis rows moved to that incident and it is left out of incident listings
+	IncidentStatusMerged = "merged"
+	// IncidentStatusGrouped marks an alert merged into another incident by alert aggregation that resolved
+	// while the rest of its group is still firing; the group's postmortem resolves it
+	IncidentStatusGrouped = "grouped"
+)
+
+// incidentColumns is the column list scanned by scanIncident.
+const incidentColumns = `id, service_name, alert_name, severity, started_at, resolved_at, root_cause, ai_summary, status,
+	COALESCE(fingerprint, ''), COALESCE(slack_thread_ts, ''), COALESCE(last_error, ''), COALESCE(group_id, ''), COALESCE(confidence, ''), COALESCE(merged_into, '')`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanIncident reads a row selected with incidentColumns.
+func scanIncident(row rowScanner) (*Incident, error) {
+	var i Incident
+	err := row.Scan(&i.ID, &i.ServiceName, &i.AlertName, &i.Severity, &i.StartedAt, &i.ResolvedAt,
+		&i.RootCause, &i.AISummary, &i.Status, &i.Fingerprint, &i.SlackThreadTS, &i.LastError, &i.GroupID, &i.Confidence, &i.MergedInto)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
 }
 
-// CreateIncident inserts a new incident
+// CreateIncident inserts a new incident; Status defaults to open
 func (db *DB) CreateIncident(incident *Incident) error {
+	status := incident.Status
+	if status == "" {
+		status = IncidentStatusOpen
+	}
+
 	stmt, err := db.Prepare(`
-		INSERT INTO incidents (id, service_name, alert_name, severity, started_at, status)
-		VALUES ($1, $2, $3, $4, $5, 'open')
+		INSERT INTO incidents (id, service_name, alert_name, severity, started_at, status, fingerprint, slack_thread_ts, last_error, group_id, confidence)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(incident.ID, incident.ServiceName, incident.AlertName, incident.Severity, incident.StartedAt)
+	_, err = stmt.Exec(incident.ID, incident.ServiceName, incident.AlertName, incident.Severity, incident.StartedAt,
+		status, incident.Fingerprint, incident.SlackThreadTS, incident.LastError, incident.GroupID, incident.Confidence)
 	if err != nil {
 		return fmt.Errorf("failed to insert incident: %w", err)
 	}
@@ -134,67 +286,165 @@ func (db *DB) CreateIncident(incident *Incident) error {
 
 // ResolveIncident marks an incident as resolved
 func (db *DB) ResolveIncident(id, rootCause, aiSummary string) error {
+	return db.ResolveIncidentAt(id, time.Now().UTC(), rootCause, aiSummary)
+}
+
+// ResolveIncidentAt marks an incident as resolved at the given time (e.g. a manual resolution)
+func (db *DB) ResolveIncidentAt(id string, resolvedAt time.Time, rootCause, aiSummary string) error {
 	stmt, err := db.Prepare(`
 		UPDATE incidents 
-		SET status = 'resolved', resolved_at = NOW(), root_cause = $1, ai_summary = $2
-		WHERE id = $3
+		SET status = 'resolved', resolved_at = $1, root_cause = $2, ai_summary = $3
+		WHERE id = $4
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(rootCause, aiSummary, id)
+	_, err = stmt.Exec(resolvedAt, rootCause, aiSummary, id)
 	if err != nil {
 		return fmt.Errorf("failed to resolve incident: %w", err)
 	}
 	return nil
 }
 
+// UpdateIncident stores what analysis found on an incident recorded before it ran: its severity,
+// status, Slack thread, last error, and confidence. Status defaults to open.
+func (db *DB) UpdateIncident(incident *Incident) error {
+	status := incident.Status
+	if status == "" {
+		status = IncidentStatusOpen
+	}
+	_, err := db.Exec(`UPDATE incidents SET severity = $1, status = $2, slack_thread_ts = $3, last_error = $4, confidence = $5 WHERE id = $6`,
+		incident.Severity, status, incident.SlackThreadTS, incident.LastError, incident.Confidence, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update incident: %w", err)
+	}
+	return nil
+}
+
+// CloseGroupMember closes an incident merged into a group whose other alerts are still firing, with
+// status grouped
+func (db *DB) CloseGroupMember(id string, closedAt time.Time) error {
+	if _, err := db.Exec(`UPDATE incidents SET status = 'grouped', resolved_at = $1 WHERE id = $2`, closedAt, id); err != nil {
+		return fmt.Errorf("failed to close grouped incident: %w", err)
+	}
+	return nil
+}
+
+// CloseIncident sets resolved_at without changing the status, e.g. for maintenance incidents
+func (db *DB) CloseIncident(id string, closedAt time.Time) error {
+	if _, err := db.Exec(`UPDATE incidents SET resolved_at = $1 WHERE id = $2`, closedAt, id); err != nil {
+		return fmt.Errorf("failed to close incident: %w", err)
+	}
+	return nil
+}
+
 // GetIncident retrieves an incident by ID
 func (db *DB) GetIncident(id string) (*Incident, error) {
-	stmt, err := db.Prepare(`
-		SELECT id, service_name, alert_name, severity, started_at, resolved_at, root_cause, ai_summary, status
-		FROM incidents WHERE id = $1
-	`)
+	stmt, err := db.Prepare(`SELECT ` + incidentColumns + ` FROM incidents WHERE id = $1`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
-	var i Incident
-	err = stmt.QueryRow(id).Scan(
-		&i.ID,
-		&i.ServiceName,
-		&i.AlertName,
-		&i.Severity,
-		&i.StartedAt,
-		&i.ResolvedAt,
-		&i.RootCause,
-		&i.AISummary,
-		&i.Status,
-	)
+	i, err := scanIncident(stmt.QueryRow(id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query incident: %w", err)
 	}
-	return &i, nil
+	return i, nil
+}
+
+// FindOpenIncident returns the most recent open (or maintenance) incident for an alert fingerprint
+// that has not been closed yet, or nil if none exists
+func (db *DB) FindOpenIncident(fingerprint string) (*Incident, error) {
+	row := db.QueryRow(`SELECT `+incidentColumns+` FROM incidents
+		WHERE fingerprint = $1 AND status IN ('open', 'maintenance', 'triaged') AND resolved_at IS NULL
+		ORDER BY started_at DESC LIMIT 1`, fingerprint)
+
+	i, err := scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incident: %w", err)
+	}
+	return i, nil
 }
 
-// ListIncidents retrieves all incidents (optionally filtered by status)
+// OpenGroupLeaders returns open incidents that started at or after since and were not merged into
+// another incident, i.e. the incidents a new alert can be grouped under, most recent first
+func (db *DB) OpenGroupLeaders(since time.Time) ([]Incident, error) {
+	return db.queryIncidents(`SELECT `+incidentColumns+` FROM incidents
+		WHERE status = 'open' AND COALESCE(group_id, '') = '' AND started_at >= $1
+		ORDER BY started_at DESC`, since)
+}
+
+// IncidentGroup returns the incident leading a group followed by the incidents merged into it, oldest first
+func (db *DB) IncidentGroup(leaderID string) ([]Incident, error) {
+	return db.queryIncidents(`SELECT `+incidentColumns+` FROM incidents
+		WHERE id = $1 OR group_id = $1
+		ORDER BY CASE WHEN id = $1 THEN 0 ELSE 1 END, started_at`, leaderID)
+}
+
+// UngroupIncidents detaches the incidents merged into leaderID from its group, reopening those already
+// closed as grouped, and returns them as they were before, oldest first
+func (db *DB) UngroupIncidents(leaderID string) ([]Incident, error) {
+	members, err := db.queryIncidents(`SELECT `+incidentColumns+` FROM incidents
+		WHERE group_id = $1 ORDER BY started_at`, leaderID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`UPDATE incidents SET group_id = NULL,
+		resolved_at = CASE WHEN status = 'grouped' THEN NULL ELSE resolved_at END,
+		status = CASE WHEN status = 'grouped' THEN 'open' ELSE status END
+		WHERE group_id = $1`, leaderID); err != nil {
+		return nil, fmt.Errorf("failed to ungroup incidents: %w", err)
+	}
+	return members, nil
+}
+
+// FindGroupedIncident returns the closed group member recorded for the alert firing with fingerprint
+// at startedAt, or nil when that firing is not one.
+func (db *DB) FindGroupedIncident(fingerprint string, startedAt time.Time) (*Incident, error) {
+	return db.findFiring(fingerprint, IncidentStatusGrouped, startedAt)
+}
+
+// queryIncidents scans every row of a query selecting incidentColumns.
+func (db *DB) queryIncidents(query string, args ...interface{}) ([]Incident, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, *i)
+	}
+	return incidents, rows.Err()
+}
+
+// ListIncidents retrieves all incidents (optionally filtered by status). Merged duplicates are only
+// listed when asked for by status.
 func (db *DB) ListIncidents(status string) ([]Incident, error) {
 	var query string
 	var args []interface{}
 
 	if status != "" {
-		query = `SELECT id, service_name, alert_name, severity, started_at, resolved_at, root_cause, ai_summary, status 
+		query = `SELECT ` + incidentColumns + `
 		        FROM incidents WHERE status = $1 ORDER BY started_at DESC LIMIT 100`
 		args = []interface{}{status}
 	} else {
-		query = `SELECT id, service_name, alert_name, severity, started_at, resolved_at, root_cause, ai_summary, status 
-		        FROM incidents ORDER BY started_at DESC LIMIT 100`
+		query = `SELECT ` + incidentColumns + `
+		        FROM incidents WHERE status <> 'merged' ORDER BY started_at DESC LIMIT 100`
 	}
 
 	rows, err := db.Query(query, args...)
@@ -205,16 +455,544 @@ func (db *DB) ListIncidents(status string) ([]Incident, error) {
 
 	var incidents []Incident
 	for rows.Next() {
-		var i Incident
-		err := rows.Scan(&i.ID, &i.ServiceName, &i.AlertName, &i.Severity, &i.StartedAt, &i.ResolvedAt, &i.RootCause, &i.AISummary, &i.Status)
+		i, err := scanIncident(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan incident: %w", err)
 		}
-		incidents = append(incidents, i)
+		incidents = append(incidents, *i)
 	}
 	return incidents, nil
 }
 
+// ListIncidentsStartedBetween returns every incident that started within [from, to), oldest first.
+// Merged duplicates are left out so they are not counted twice.
+func (db *DB) ListIncidentsStartedBetween(from, to time.Time) ([]Incident, error) {
+	rows, err := db.Query(`SELECT `+incidentColumns+` FROM incidents
+		WHERE started_at >= $1 AND started_at < $2 AND status <> 'merged' ORDER BY started_at, id`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var incidents []Incident
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		incidents = append(incidents, *i)
+	}
+	return incidents, rows.Err()
+}
+
+// PriorIncidents returns up to limit resolved incidents of alertName on serviceName that started
+// before the given time, most recently resolved first.
+func (db *DB) PriorIncidents(serviceName, alertName string, before time.Time, limit int) ([]models.PriorIncident, error) {
+	rows, err := db.Query(`SELECT id, started_at, resolved_at, COALESCE(root_cause, '') FROM incidents
+		WHERE service_name = $1 AND alert_name = $2 AND status = 'resolved' AND resolved_at IS NOT NULL AND started_at < $3
+		ORDER BY resolved_at DESC LIMIT $4`, serviceName, alertName, before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prior incidents: %w", err)
+	}
+	defer rows.Close()
+
+	var prior []models.PriorIncident
+	for rows.Next() {
+		var p models.PriorIncident
+		if err := rows.Scan(&p.ID, &p.StartedAt, &p.ResolvedAt, &p.RootCause); err != nil {
+			return nil, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		prior = append(prior, p)
+	}
+	return prior, rows.Err()
+}
+
+// ForEachPostmortem calls fn, oldest first, for every resolved incident with a stored postmortem
+// that was resolved within [from, to). A zero from or to leaves that side unbounded. Rows are
+// streamed rather than loaded at once; iteration stops at the first error fn returns.
+func (db *DB) ForEachPostmortem(from, to time.Time, fn func(Incident) error) error {
+	query := `SELECT ` + incidentColumns + ` FROM incidents
+		WHERE status = 'resolved' AND ai_summary IS NOT NULL AND ai_summary <> ''`
+	var args []interface{}
+	if !from.IsZero() {
+		args = append(args, from.UTC())
+		query += fmt.Sprintf(" AND resolved_at >= $%d", len(args))
+	}
+	if !to.IsZero() {
+		args = append(args, to.UTC())
+		query += fmt.Sprintf(" AND resolved_at < $%d", len(args))
+	}
+	query += " ORDER BY resolved_at, id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query postmortems: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		i, err := scanIncident(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan incident: %w", err)
+		}
+		if err := fn(*i); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// PurgeBefore deletes resolved, failed, merged, and closed maintenance and triaged incidents that ended before cutoff, together with their
+// analysis results and any orphaned analysis rows, processed queue entries, sent-notification markers, and deploy events. Open incidents are never purged.
+// It returns the number of incidents deleted.
+func (db *DB) PurgeBefore(cutoff time.Time) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin purge: %w", err)
+	}
+	defer tx.Rollback()
+
+	const expired = `SELECT id FROM incidents
+		WHERE (status IN ('resolved', 'failed', 'merged', 'grouped') OR (status IN ('maintenance', 'triaged') AND resolved_at IS NOT NULL))
+		AND COALESCE(resolved_at, started_at) < $1`
+
+	if _, err := tx.Exec(`DELETE FROM analysis_results WHERE incident_id IN (`+expired+`)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge analysis results: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM incident_feedback WHERE incident_id IN (`+expired+`)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge incident feedback: %w", err)
+	}
+
+	res, err := tx.Exec(`DELETE FROM incidents WHERE id IN (`+expired+`)`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge incidents: %w", err)
+	}
+	purged, _ := res.RowsAffected()
+
+	if _, err := tx.Exec(`DELETE FROM analysis_results
+		WHERE created_at < $1 AND incident_id NOT IN (SELECT id FROM incidents)`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge orphaned analysis results: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM pending_alerts WHERE status IN ('done', 'failed') AND updated_at < $1`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge processed alerts: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sent_notifications WHERE sent_at < $1`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge sent notifications: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM deploy_events WHERE deployed_at < $1`, cutoff); err != nil {
+		return 0, fmt.Errorf("failed to purge deploy events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return purged, nil
+}
+
+// AnalysisTypeContextSnapshot marks analysis_results rows holding the serialized AnalysisContext
+// (metrics, baselines, spans, commits, logs) that an incident's RCA was based on.
+const AnalysisTypeContextSnapshot = "context_snapshot"
+
+// AnalysisTypeAlertPayload marks analysis_results rows holding the raw Alertmanager alert of a
+// failed incident, so processing can be replayed.
+const AnalysisTypeAlertPayload = "alert_payload"
+
+// AnalysisTypeRawLLM marks analysis_results rows holding the redacted raw LLM reply of an incident's
+// RCA with its model name and token usage.
+const AnalysisTypeRawLLM = "raw_llm"
+
+// SaveAnalysisResult stores v as JSON in an analysis_results row of the given type
+func (db *DB) SaveAnalysisResult(incidentID, analysisType string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", analysisType, err)
+	}
+
+	_, err = db.Exec(`INSERT INTO analysis_results (incident_id, analysis_type, result_data) VALUES ($1, $2, $3)`,
+		incidentID, analysisType, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to insert %s: %w", analysisType, err)
+	}
+	return nil
+}
+
+// SaveContextSnapshot stores the full analysis context for an incident so it can be re-analyzed or audited later
+func (db *DB) SaveContextSnapshot(incidentID string, ac *models.AnalysisContext) error {
+	return db.SaveAnalysisResult(incidentID, AnalysisTypeContextSnapshot, ac)
+}
+
+// LoadAnalysisResult decodes the most recent analysis_results row of the given type into v,
+// reporting false when the incident has no such row
+func (db *DB) LoadAnalysisResult(incidentID, analysisType string, v interface{}) (bool, error) {
+	var data string
+	err := db.QueryRow(`SELECT result_data FROM analysis_results
+		WHERE incident_id = $1 AND analysis_type = $2 ORDER BY id DESC LIMIT 1`,
+		incidentID, analysisType).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query %s: %w", analysisType, err)
+	}
+
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return false, fmt.Errorf("failed to decode %s: %w", analysisType, err)
+	}
+	return true, nil
+}
+
+// LoadContextSnapshot returns the most recent context snapshot for an incident, or nil if none was stored
+func (db *DB) LoadContextSnapshot(incidentID string) (*models.AnalysisContext, error) {
+	var ac models.AnalysisContext
+	found, err := db.LoadAnalysisResult(incidentID, AnalysisTypeContextSnapshot, &ac)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &ac, nil
+}
+
+// SaveRawLLMResponse stores the raw LLM reply an incident's analysis was parsed from
+func (db *DB) SaveRawLLMResponse(incidentID string, r *models.LLMResponse) error {
+	return db.SaveAnalysisResult(incidentID, AnalysisTypeRawLLM, r)
+}
+
+// LoadRawLLMResponse returns the most recent raw LLM reply for an incident, or nil if none was stored
+func (db *DB) LoadRawLLMResponse(incidentID string) (*models.LLMResponse, error) {
+	var r models.LLMResponse
+	found, err := db.LoadAnalysisResult(incidentID, AnalysisTypeRawLLM, &r)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Queue states of a pending_alerts row.
+const (
+	PendingAlertPending = "pending" // received, not yet fully processed
+	PendingAlertDone    = "done"
+	PendingAlertFailed  = "failed" // processing failed or was abandoned after too many attempts
+)
+
+// PendingAlert is a received alert payload in the processing queue.
+type PendingAlert struct {
+	ID       int64
+	Receiver string // webhook receiver profile; empty for the default /webhook
+	Payload  models.AlertManagerPayload
+	Attempts int
+	// NotBefore is when a scheduled payload becomes due; zero for payloads processed on arrival
+	NotBefore time.Time
+}
+
+// EnqueueAlerts persists a received payload as pending, counting the processing attempt that starts now,
+// and returns its queue ID.
+func (db *DB) EnqueueAlerts(receiver string, payload models.AlertManagerPayload) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal alert payload: %w", err)
+	}
+
+	var id int64
+	err = db.QueryRow(`INSERT INTO pending_alerts (receiver, payload, status, attempts) VALUES ($1, $2, 'pending', 1) RETURNING id`,
+		receiver, string(data)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue alerts: %w", err)
+	}
+	return id, nil
+}
+
+// ScheduleAlerts persists a payload to be processed no earlier than notBefore and returns its queue
+// ID. Unlike EnqueueAlerts no attempt is counted, since processing has not started.
+func (db *DB) ScheduleAlerts(receiver string, payload models.AlertManagerPayload, notBefore time.Time) (int64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal alert payload: %w", err)
+	}
+
+	var id int64
+	err = db.QueryRow(`INSERT INTO pending_alerts (receiver, payload, status, attempts, not_before) VALUES ($1, $2, 'pending', 0, $3) RETURNING id`,
+		receiver, string(data), notBefore.UTC()).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to schedule alerts: %w", err)
+	}
+	return id, nil
+}
+
+// StartPendingAlert counts an attempt on a scheduled payload whose processing starts now.
+func (db *DB) StartPendingAlert(id int64) error {
+	if _, err := db.Exec(`UPDATE pending_alerts SET attempts = attempts + 1, updated_at = $1 WHERE id = $2`, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to start queued alerts: %w", err)
+	}
+	return nil
+}
+
+// FinishPendingAlert marks a queued payload done, or failed with procErr. The attempts it took stay
+// recorded with it.
+func (db *DB) FinishPendingAlert(id int64, procErr error) error {
+	status, lastError := PendingAlertDone, sql.NullString{}
+	if procErr != nil {
+		status, lastError = PendingAlertFailed, sql.NullString{String: procErr.Error(), Valid: true}
+	}
+	_, err := db.Exec(`UPDATE pending_alerts SET status = $1, last_error = $2, updated_at = $3 WHERE id = $4`,
+		status, lastError, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to finish queued alerts: %w", err)
+	}
+	return nil
+}
+
+// ResumePendingAlerts returns the payloads still pending, typically because the process stopped while
+// they were being processed or before a scheduled payload was due, oldest first, and counts the new
+// attempt on each payload processed on arrival; scheduled payloads count theirs with StartPendingAlert
+// once they are due. Payloads that already had maxAttempts attempts are marked failed instead of
+// returned, so one that crashes the process every time is not retried forever.
+func (db *DB) ResumePendingAlerts(maxAttempts int) ([]PendingAlert, error) {
+	now := time.Now().UTC()
+	if _, err := db.Exec(`UPDATE pending_alerts SET status = 'failed', last_error = $1, updated_at = $2
+		WHERE status = 'pending' AND attempts >= $3`,
+		fmt.Sprintf("abandoned after %d attempts", maxAttempts), now, maxAttempts); err != nil {
+		return nil, fmt.Errorf("failed to abandon queued alerts: %w", err)
+	}
+
+	rows, err := db.Query(`SELECT id, receiver, payload, attempts, not_before FROM pending_alerts WHERE status = 'pending' ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query queued alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		pending []PendingAlert
+		corrupt = make(map[int64]error)
+	)
+	for rows.Next() {
+		var (
+			p         PendingAlert
+			data      string
+			notBefore sql.NullTime
+		)
+		if err := rows.Scan(&p.ID, &p.Receiver, &data, &p.Attempts, &notBefore); err != nil {
+			return nil, fmt.Errorf("failed to scan queued alerts: %w", err)
+		}
+		if notBefore.Valid {
+			p.NotBefore = notBefore.Time
+		}
+		if err := json.Unmarshal([]byte(data), &p.Payload); err != nil {
+			corrupt[p.ID] = fmt.Errorf("undecodable payload: %w", err)
+			continue
+		}
+		if p.NotBefore.IsZero() {
+			p.Attempts++
+		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for id, decodeErr := range corrupt {
+		if err := db.FinishPendingAlert(id, decodeErr); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range pending {
+		if !p.NotBefore.IsZero() {
+			continue
+		}
+		if _, err := db.Exec(`UPDATE pending_alerts SET attempts = $1, updated_at = $2 WHERE id = $3`, p.Attempts, now, p.ID); err != nil {
+			return nil, fmt.Errorf("failed to update queued alerts %d: %w", p.ID, err)
+		}
+	}
+	return pending, nil
+}
+
+// LastDigestEnd returns where the last posted incident digest ended, or the zero time before the first.
+func (db *DB) LastDigestEnd() (time.Time, error) {
+	var end time.Time
+	err := db.QueryRow(`SELECT last_end FROM digest_state WHERE id = 1`).Scan(&end)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load digest state: %w", err)
+	}
+	return end, nil
+}
+
+// SaveDigestEnd records where the incident digest just posted ended.
+func (db *DB) SaveDigestEnd(end time.Time) error {
+	_, err := db.Exec(`INSERT INTO digest_state (id, last_end) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET last_end = EXCLUDED.last_end`, end.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save digest state: %w", err)
+	}
+	return nil
+}
+
+// LoadCommitCursor returns the stored commit cursor for key, or nil if none exists
+func (db *DB) LoadCommitCursor(key string) (*models.CommitCursor, error) {
+	var data string
+	err := db.QueryRow(`SELECT cursor_data FROM commit_cursors WHERE repo_key = $1`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query commit cursor: %w", err)
+	}
+
+	var c models.CommitCursor
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, fmt.Errorf("failed to decode commit cursor: %w", err)
+	}
+	return &c, nil
+}
+
+// SaveCommitCursor stores c as the commit cursor for key, replacing any earlier one
+func (db *DB) SaveCommitCursor(key string, c *models.CommitCursor) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal commit cursor: %w", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO commit_cursors (repo_key, head_sha, cursor_data, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (repo_key) DO UPDATE SET head_sha = EXCLUDED.head_sha, cursor_data = EXCLUDED.cursor_data, updated_at = EXCLUDED.updated_at`,
+		key, c.HeadSHA, string(data), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save commit cursor: %w", err)
+	}
+	return nil
+}
+
+// ClaimNotification records that the notification for incidentKey on channel is being sent, as a pending
+// marker. It reports false when a marker already exists, together with the reference of a sent one.
+func (db *DB) ClaimNotification(incidentKey, channel string) (bool, string, error) {
+	res, err := db.Exec(`INSERT INTO sent_notifications (incident_key, channel, sent_at, pending) VALUES ($1, $2, $3, TRUE)
+		ON CONFLICT (incident_key, channel) DO NOTHING`, incidentKey, channel, time.Now().UTC())
+	if err != nil {
+		return false, "", fmt.Errorf("failed to claim notification: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to claim notification: %w", err)
+	}
+	if n == 1 {
+		return true, "", nil
+	}
+
+	var ref string
+	err = db.QueryRow(`SELECT COALESCE(ref, '') FROM sent_notifications WHERE incident_key = $1 AND channel = $2`,
+		incidentKey, channel).Scan(&ref)
+	if err != nil && err != sql.ErrNoRows {
+		return false, "", fmt.Errorf("failed to read notification marker: %w", err)
+	}
+	return false, ref, nil
+}
+
+// CompleteNotification marks the notification for incidentKey on channel sent and stores ref with it
+func (db *DB) CompleteNotification(incidentKey, channel, ref string) error {
+	_, err := db.Exec(`INSERT INTO sent_notifications (incident_key, channel, sent_at, pending, ref) VALUES ($1, $2, $3, FALSE, $4)
+		ON CONFLICT (incident_key, channel) DO UPDATE SET pending = FALSE, ref = EXCLUDED.ref, sent_at = EXCLUDED.sent_at`,
+		incidentKey, channel, time.Now().UTC(), ref)
+	if err != nil {
+		return fmt.Errorf("failed to complete notification: %w", err)
+	}
+	return nil
+}
+
+// ReleaseNotification removes the marker for incidentKey on channel so the notification can be sent again
+func (db *DB) ReleaseNotification(incidentKey, channel string) error {
+	if _, err := db.Exec(`DELETE FROM sent_notifications WHERE incident_key = $1 AND channel = $2`, incidentKey, channel); err != nil {
+		return fmt.Errorf("failed to release notification: %w", err)
+	}
+	return nil
+}
+
+// ReleasePendingNotifications removes the markers of notifications that were claimed but never sent
+func (db *DB) ReleasePendingNotifications() error {
+	if _, err := db.Exec(`DELETE FROM sent_notifications WHERE pending = TRUE`); err != nil {
+		return fmt.Errorf("failed to release pending notifications: %w", err)
+	}
+	return nil
+}
+
+// ServiceMapping links a service to its GitHub repository. Discovered mappings start unconfirmed
+// until an operator confirms or corrects them.
+type ServiceMapping struct {
+	ServiceName string `json:"service_name"`
+	Repo        string `json:"repo"`
+	Confirmed   bool   `json:"confirmed"`
+}
+
+// GetServiceMapping returns the stored mapping for a service, or nil if none exists
+func (db *DB) GetServiceMapping(serviceName string) (*ServiceMapping, error) {
+	m := ServiceMapping{ServiceName: serviceName}
+	err := db.QueryRow(`SELECT github_repo, COALESCE(confirmed, FALSE) FROM service_mappings WHERE service_name = $1`,
+		serviceName).Scan(&m.Repo, &m.Confirmed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service mapping: %w", err)
+	}
+	return &m, nil
+}
+
+// ListServiceMappings returns every stored mapping ordered by service name
+func (db *DB) ListServiceMappings() ([]ServiceMapping, error) {
+	rows, err := db.Query(`SELECT service_name, github_repo, COALESCE(confirmed, FALSE) FROM service_mappings ORDER BY service_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []ServiceMapping
+	for rows.Next() {
+		var m ServiceMapping
+		if err := rows.Scan(&m.ServiceName, &m.Repo, &m.Confirmed); err != nil {
+			return nil, fmt.Errorf("failed to scan service mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, rows.Err()
+}
+
+// SeedServiceMapping records a discovered, unconfirmed mapping. Existing mappings are left untouched
+// so discovery never overrides an operator's choice.
+func (db *DB) SeedServiceMapping(serviceName, repo string) error {
+	_, err := db.Exec(`INSERT INTO service_mappings (service_name, github_repo, confirmed) VALUES ($1, $2, FALSE)
+		ON CONFLICT (service_name) DO NOTHING`, serviceName, repo)
+	if err != nil {
+		return fmt.Errorf("failed to seed service mapping: %w", err)
+	}
+	return nil
+}
+
+// ConfirmServiceMapping marks a service's mapping as operator-confirmed, replacing the repo when
+// one is given. It returns sql.ErrNoRows when no mapping exists and repo is empty.
+func (db *DB) ConfirmServiceMapping(serviceName, repo string) error {
+	if repo != "" {
+		_, err := db.Exec(`INSERT INTO service_mappings (service_name, github_repo, confirmed) VALUES ($1, $2, TRUE)
+			ON CONFLICT (service_name) DO UPDATE SET github_repo = EXCLUDED.github_repo, confirmed = TRUE, updated_at = CURRENT_TIMESTAMP`,
+			serviceName, repo)
+		if err != nil {
+			return fmt.Errorf("failed to confirm service mapping: %w", err)
+		}
+		return nil
+	}
+
+	res, err := db.Exec(`UPDATE service_mappings SET confirmed = TRUE, updated_at = CURRENT_TIMESTAMP WHERE service_name = $1`, serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to confirm service mapping: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // GetEnv gets environment variable with fallback
 func GetEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -0,0 +1,406 @@
+package db_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/tempo"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidentLifecycle(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			require.NoError(t, database.CreateIncident(&db.Incident{
+				ID:            "inc-1",
+				ServiceName:   "checkout",
+				AlertName:     "HighLatency",
+				Severity:      "critical",
+				StartedAt:     started,
+				Fingerprint:   "fp-1",
+				SlackThreadTS: "1700000000.000100",
+			}))
+
+			open, err := database.FindOpenIncident("fp-1")
+			require.NoError(t, err)
+			require.NotNil(t, open)
+			assert.Equal(t, "inc-1", open.ID)
+			assert.Equal(t, "1700000000.000100", open.SlackThreadTS)
+			assert.True(t, started.Equal(open.StartedAt))
+
+			require.NoError(t, database.ResolveIncident("inc-1", "pool exhausted", "# Postmortem"))
+
+			open, err = database.FindOpenIncident("fp-1")
+			require.NoError(t, err)
+			assert.Nil(t, open)
+
+			resolved, err := database.GetIncident("inc-1")
+			require.NoError(t, err)
+			require.NotNil(t, resolved)
+			assert.Equal(t, "resolved", resolved.Status)
+			assert.NotNil(t, resolved.ResolvedAt)
+			assert.Equal(t, "pool exhausted", *resolved.RootCause)
+
+			list, err := database.ListIncidents("resolved")
+			require.NoError(t, err)
+			assert.Len(t, list, 1)
+		})
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, database.Migrate())
+
+			missing, err := database.GetIncident("nope")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+		})
+	}
+}
+
+func TestContextSnapshotRoundTrip(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			require.NoError(t, database.CreateIncident(&db.Incident{
+				ID:          "inc-1",
+				ServiceName: "checkout",
+				AlertName:   "HighLatency",
+				Severity:    "critical",
+				StartedAt:   started,
+			}))
+
+			missing, err := database.LoadContextSnapshot("inc-1")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+
+			snapshot := &models.AnalysisContext{
+				ServiceName: "checkout",
+				Alert:       models.AlertInfo{Name: "HighLatency", Severity: "critical", StartedAt: started, Fingerprint: "fp-1"},
+				Metrics: models.MetricsSummary{
+					LatencyP99:        1250,
+					ErrorRate:         0.05,
+					BaselineLatency:   200,
+					BaselineErrorRate: 0.001,
+				},
+				RecentCommits: []models.CommitInfo{{SHA: "abc1234def", Message: "Reduce DB pool size", Timestamp: started.Add(-5 * time.Minute)}},
+				ErrorLogs:     []models.LogEntry{{Timestamp: started, Level: "error", Message: "connection pool exhausted"}},
+				Traces: tempo.TraceContext{
+					TraceCount: 12,
+					SlowSpans:  []tempo.Span{{ServiceName: "postgres", OperationName: "SELECT", DurationMs: 900}},
+				},
+				TimeWindow:      models.TimeWindow{Start: started.Add(-15 * time.Minute), End: started, Duration: "15m0s"},
+				SuspectedCauses: []models.Hypothesis{{Cause: "Connection pool exhaustion", Score: 0.7, Evidence: []string{"error logs"}}},
+			}
+			require.NoError(t, database.SaveContextSnapshot("inc-1", snapshot))
+
+			loaded, err := database.LoadContextSnapshot("inc-1")
+			require.NoError(t, err)
+			require.NotNil(t, loaded)
+			assert.Equal(t, snapshot, loaded)
+		})
+	}
+}
+
+func TestRawLLMResponseRoundTrip(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, database.CreateIncident(&db.Incident{
+				ID:          "inc-1",
+				ServiceName: "checkout",
+				AlertName:   "HighLatency",
+				Severity:    "critical",
+				StartedAt:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			}))
+
+			missing, err := database.LoadRawLLMResponse("inc-1")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+
+			raw := &models.LLMResponse{
+				Provider:         "openai",
+				Model:            "gpt-4o-2024-08-06",
+				Response:         "# Incident Analysis: Pool exhaustion\n**Confidence Score:** 85%",
+				PromptTokens:     1200,
+				CompletionTokens: 300,
+				TotalTokens:      1500,
+			}
+			require.NoError(t, database.SaveRawLLMResponse("inc-1", raw))
+
+			loaded, err := database.LoadRawLLMResponse("inc-1")
+			require.NoError(t, err)
+			assert.Equal(t, raw, loaded)
+		})
+	}
+}
+
+func TestPurgeBeforeKeepsOpenAndRecentIncidents(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+			old := now.Add(-100 * 24 * time.Hour)
+			recent := now.Add(-10 * 24 * time.Hour)
+			cutoff := now.Add(-90 * 24 * time.Hour)
+
+			seed := func(id, status string, started time.Time, resolved bool) {
+				require.NoError(t, database.CreateIncident(&db.Incident{
+					ID: id, ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+					StartedAt: started, Status: status,
+				}))
+				require.NoError(t, database.SaveContextSnapshot(id, &models.AnalysisContext{ServiceName: "checkout"}))
+				if resolved {
+					require.NoError(t, database.ResolveIncidentAt(id, started.Add(time.Hour), "cause", "summary"))
+				}
+			}
+			seed("old-resolved", db.IncidentStatusOpen, old, true)
+			seed("old-failed", db.IncidentStatusFailed, old, false)
+			seed("old-open", db.IncidentStatusOpen, old, false)
+			seed("recent-resolved", db.IncidentStatusOpen, recent, true)
+
+			purged, err := database.PurgeBefore(cutoff)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), purged)
+
+			for id, kept := range map[string]bool{
+				"old-resolved":    false,
+				"old-failed":      false,
+				"old-open":        true,
+				"recent-resolved": true,
+			} {
+				incident, err := database.GetIncident(id)
+				require.NoError(t, err)
+				assert.Equal(t, kept, incident != nil, id)
+
+				snapshot, err := database.LoadContextSnapshot(id)
+				require.NoError(t, err)
+				assert.Equal(t, kept, snapshot != nil, id)
+			}
+		})
+	}
+}
+
+func TestServiceMappingSeedAndConfirm(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, database.SeedServiceMapping("checkout", "acme/checkout"))
+			require.NoError(t, database.SeedServiceMapping("checkout", "acme/other"), "seeding never overrides an existing mapping")
+
+			m, err := database.GetServiceMapping("checkout")
+			require.NoError(t, err)
+			require.NotNil(t, m)
+			assert.Equal(t, "acme/checkout", m.Repo)
+			assert.False(t, m.Confirmed)
+
+			require.NoError(t, database.ConfirmServiceMapping("checkout", ""))
+			m, err = database.GetServiceMapping("checkout")
+			require.NoError(t, err)
+			assert.True(t, m.Confirmed)
+
+			require.NoError(t, database.ConfirmServiceMapping("payments", "acme/payments-api"))
+			assert.ErrorIs(t, database.ConfirmServiceMapping("unknown", ""), sql.ErrNoRows)
+
+			mappings, err := database.ListServiceMappings()
+			require.NoError(t, err)
+			assert.Equal(t, []db.ServiceMapping{
+				{ServiceName: "checkout", Repo: "acme/checkout", Confirmed: true},
+				{ServiceName: "payments", Repo: "acme/payments-api", Confirmed: true},
+			}, mappings)
+
+			missing, err := database.GetServiceMapping("unknown")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+		})
+	}
+}
+
+func TestCommitCursorRoundTrip(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			missing, err := database.LoadCommitCursor("acme/checkout@:")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+
+			at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			first := &models.CommitCursor{HeadSHA: "abc1234", HeadAt: at, Since: at.Add(-24 * time.Hour),
+				Commits: []models.CommitInfo{{SHA: "abc1234", Message: "Tune pool", Timestamp: at}}}
+			require.NoError(t, database.SaveCommitCursor("acme/checkout@:", first))
+			second := &models.CommitCursor{HeadSHA: "def5678", HeadAt: at.Add(time.Hour), Since: at.Add(-24 * time.Hour),
+				Commits: []models.CommitInfo{{SHA: "def5678", Timestamp: at.Add(time.Hour)}, {SHA: "abc1234", Message: "Tune pool", Timestamp: at}}}
+			require.NoError(t, database.SaveCommitCursor("acme/checkout@:", second))
+
+			loaded, err := database.LoadCommitCursor("acme/checkout@:")
+			require.NoError(t, err)
+			assert.Equal(t, second, loaded)
+		})
+	}
+}
+
+func TestFeedbackCalibrationByConfidenceBand(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			seed := func(id, confidence string, correct bool) {
+				require.NoError(t, database.CreateIncident(&db.Incident{
+					ID: id, ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+					StartedAt: started, Confidence: confidence,
+				}))
+				require.NoError(t, database.SaveFeedback(db.Feedback{IncidentID: id, Correct: correct}))
+			}
+			seed("inc-1", "90%", true)
+			seed("inc-2", "high", true)
+			seed("inc-3", "85% (metrics stale)", false)
+			seed("inc-4", "60%", true)
+			seed("inc-5", "low (metrics stale)", false)
+			seed("inc-6", "n/a", true)
+			require.NoError(t, database.CreateIncident(&db.Incident{
+				ID: "no-feedback", ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+				StartedAt: started, Confidence: "95%",
+			}))
+
+			// A later verdict replaces the earlier one
+			require.NoError(t, database.SaveFeedback(db.Feedback{IncidentID: "inc-4", Correct: false, ActualCause: "DNS outage"}))
+
+			bands, err := database.ConfidenceCalibration()
+			require.NoError(t, err)
+			assert.Equal(t, []db.CalibrationBand{
+				{Band: db.ConfidenceBandHigh, Total: 3, Correct: 2, Accuracy: 2.0 / 3},
+				{Band: db.ConfidenceBandMedium, Total: 1, Correct: 0, Accuracy: 0},
+				{Band: db.ConfidenceBandLow, Total: 1, Correct: 0, Accuracy: 0},
+				{Band: db.ConfidenceBandUnknown, Total: 1, Correct: 1, Accuracy: 1},
+			}, bands)
+
+			incident, err := database.GetIncident("inc-1")
+			require.NoError(t, err)
+			assert.Equal(t, "90%", incident.Confidence)
+		})
+	}
+}
+
+func TestFeedbackIsPurgedWithItsIncident(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			require.NoError(t, database.CreateIncident(&db.Incident{
+				ID: "inc-1", ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+				StartedAt: started, Confidence: "90%",
+			}))
+			require.NoError(t, database.ResolveIncidentAt("inc-1", started.Add(time.Hour), "cause", "summary"))
+			require.NoError(t, database.SaveFeedback(db.Feedback{IncidentID: "inc-1", Correct: true}))
+
+			purged, err := database.PurgeBefore(started.Add(48 * time.Hour))
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), purged)
+
+			bands, err := database.ConfidenceCalibration()
+			require.NoError(t, err)
+			assert.Empty(t, bands)
+		})
+	}
+}
+
+func TestDeployEventsWithinWindow(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			at := time.Date(2024, 1, 1, 14, 3, 0, 0, time.UTC)
+			require.NoError(t, database.SaveDeployEvent(models.DeployEvent{Service: "checkout", Version: "v1.2.3", SHA: "abc1234def", Time: at}))
+			require.NoError(t, database.SaveDeployEvent(models.DeployEvent{Service: "checkout", Version: "v1.2.2", Time: at.Add(-48 * time.Hour)}))
+			require.NoError(t, database.SaveDeployEvent(models.DeployEvent{Service: "cart", Version: "v9", Time: at}))
+
+			events, err := database.DeployEvents("checkout", at.Add(-time.Hour), at.Add(time.Hour))
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, "v1.2.3", events[0].Version)
+			assert.Equal(t, "abc1234def", events[0].SHA)
+			assert.True(t, at.Equal(events[0].Time))
+
+			_, err = database.PurgeBefore(at.Add(-24 * time.Hour))
+			require.NoError(t, err)
+			events, err = database.DeployEvents("checkout", at.Add(-72*time.Hour), at)
+			require.NoError(t, err)
+			assert.Len(t, events, 1, "deploys older than the retention cutoff are purged")
+		})
+	}
+}
+
+func TestMergeIncidentsReassignsAnalysisAndPointsAtPrimary(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			for i, id := range []string{"primary", "dup-1", "dup-2"} {
+				require.NoError(t, database.CreateIncident(&db.Incident{
+					ID: id, ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+					StartedAt: started.Add(time.Duration(i) * time.Minute), Fingerprint: "fp-" + id,
+				}))
+			}
+			require.NoError(t, database.SaveRawLLMResponse("dup-1", &models.LLMResponse{Provider: "openai", Response: "dup analysis"}))
+
+			mergedAt := started.Add(time.Hour)
+			require.NoError(t, database.MergeIncidents("primary", []string{"dup-1"}, mergedAt))
+			// a later merge of dup-1's new primary re-points dup-1 as well
+			require.NoError(t, database.MergeIncidents("dup-2", []string{"primary"}, mergedAt))
+
+			dup, err := database.GetIncident("dup-1")
+			require.NoError(t, err)
+			assert.Equal(t, db.IncidentStatusMerged, dup.Status)
+			assert.Equal(t, "dup-2", dup.MergedInto)
+			require.NotNil(t, dup.ResolvedAt)
+			assert.True(t, mergedAt.Equal(*dup.ResolvedAt), "merging closes the duplicate")
+
+			raw, err := database.LoadRawLLMResponse("dup-2")
+			require.NoError(t, err)
+			require.NotNil(t, raw, "analysis rows move to the primary")
+			assert.Equal(t, "dup analysis", raw.Response)
+			raw, err = database.LoadRawLLMResponse("dup-1")
+			require.NoError(t, err)
+			assert.Nil(t, raw)
+
+			merged, err := database.MergedIncidents("dup-2")
+			require.NoError(t, err)
+			require.Len(t, merged, 2)
+			assert.Equal(t, "primary", merged[0].ID)
+			assert.Equal(t, "dup-1", merged[1].ID)
+
+			assert.Error(t, database.MergeIncidents("dup-2", []string{"dup-1"}, mergedAt), "already merged")
+			assert.Error(t, database.MergeIncidents("dup-2", []string{"missing"}, mergedAt))
+			assert.Error(t, database.MergeIncidents("dup-2", []string{"dup-2"}, mergedAt))
+		})
+	}
+}
+
+func TestListingsHideMergedDuplicates(t *testing.T) {
+	for name, database := range dbtest.Stores(t) {
+		t.Run(name, func(t *testing.T) {
+			started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			for _, id := range []string{"primary", "dup"} {
+				require.NoError(t, database.CreateIncident(&db.Incident{
+					ID: id, ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical", StartedAt: started,
+				}))
+			}
+			require.NoError(t, database.MergeIncidents("primary", []string{"dup"}, started.Add(time.Hour)))
+
+			list, err := database.ListIncidents("")
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			assert.Equal(t, "primary", list[0].ID)
+
+			between, err := database.ListIncidentsStartedBetween(started, started.Add(time.Minute))
+			require.NoError(t, err)
+			require.Len(t, between, 1)
+			assert.Equal(t, "primary", between[0].ID)
+
+			merged, err := database.ListIncidents(db.IncidentStatusMerged)
+			require.NoError(t, err)
+			require.Len(t, merged, 1, "merged duplicates are still listed when asked for")
+			assert.Equal(t, "primary", merged[0].MergedInto)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package dbtest provides migrated databases for repository and handler tests: a file-backed SQLite
+// database always, and PostgreSQL when a DSN is provided.
+package dbtest
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"helixops/internal/db"
+)
+
+// PostgresDSNEnv names the environment variable holding a PostgreSQL DSN for the store contract tests;
+// without it they run against SQLite only.
+const PostgresDSNEnv = "HELIX_TEST_POSTGRES_DSN"
+
+// New opens a fresh SQLite database in a temporary directory and runs all migrations.
+func New(t testing.TB) *db.DB {
+	t.Helper()
+
+	database, err := db.Open("sqlite3", filepath.Join(t.TempDir(), "helixops.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return database
+}
+
+// Postgres opens the PostgreSQL database named by PostgresDSNEnv in a fresh schema, dropped when the
+// test ends, and runs all migrations. It returns nil when the variable is unset.
+func Postgres(t testing.TB) *db.DB {
+	t.Helper()
+
+	dsn := os.Getenv(PostgresDSNEnv)
+	if dsn == "" {
+		return nil
+	}
+	admin, err := db.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open postgres test database: %v", err)
+	}
+	schema := fmt.Sprintf("helixops_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatalf("create postgres test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	database, err := db.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatalf("open postgres test schema: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("migrate postgres test database: %v", err)
+	}
+	return database
+}
+
+// Stores returns a fresh database per available driver, keyed by driver, so the same contract tests
+// run against every Store implementation: SQLite always, PostgreSQL when PostgresDSNEnv is set.
+func Stores(t testing.TB) map[string]*db.DB {
+	t.Helper()
+
+	stores := map[string]*db.DB{"sqlite": New(t)}
+	if pg := Postgres(t); pg != nil {
+		stores["postgres"] = pg
+	}
+	return stores
+}
+
+// withSearchPath points every connection opened with dsn at schema, for URL and key=value DSNs alike.
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"helixops/internal/models"
+)
+
+// SaveDeployEvent records a deploy reported by a CD system. A zero Time is recorded as now.
+func (db *DB) SaveDeployEvent(e models.DeployEvent) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	_, err := db.Exec(`INSERT INTO deploy_events (service_name, version, sha, deployed_at) VALUES ($1, $2, $3, $4)`,
+		e.Service, e.Version, e.SHA, e.Time.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert deploy event: %w", err)
+	}
+	return nil
+}
+
+// DeployEvents returns the deploys of serviceName within [from, to], oldest first.
+func (db *DB) DeployEvents(serviceName string, from, to time.Time) ([]models.DeployEvent, error) {
+	rows, err := db.Query(`SELECT service_name, COALESCE(version, ''), COALESCE(sha, ''), deployed_at FROM deploy_events
+		WHERE service_name = $1 AND deployed_at >= $2 AND deployed_at <= $3
+		ORDER BY deployed_at, id`, serviceName, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deploy events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.DeployEvent
+	for rows.Next() {
+		var e models.DeployEvent
+		if err := rows.Scan(&e.Service, &e.Version, &e.SHA, &e.Time); err != nil {
+			return nil, fmt.Errorf("failed to scan deploy event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
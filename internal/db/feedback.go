@@ -0,0 +1,115 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Feedback is a responder's verdict on whether an incident's RCA named the right cause.
+type Feedback struct {
+	IncidentID string `json:"incident_id"`
+	Correct    bool   `json:"correct"`
+	// ActualCause is what the responder found the cause to be; typically set when Correct is false
+	ActualCause string    `json:"actual_cause,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Confidence bands that stated RCA confidences are grouped into for calibration.
+const (
+	ConfidenceBandHigh    = "high"    // 80% and above, or rated "high"
+	ConfidenceBandMedium  = "medium"  // 50% to 79%, or rated "medium"
+	ConfidenceBandLow     = "low"     // below 50%, or rated "low"
+	ConfidenceBandUnknown = "unknown" // no confidence given, or the LLM was skipped ("n/a")
+)
+
+// confidenceBands is the order bands are reported in.
+var confidenceBands = []string{ConfidenceBandHigh, ConfidenceBandMedium, ConfidenceBandLow, ConfidenceBandUnknown}
+
+// ConfidenceBand places a stated confidence, either a percentage or a word rating and possibly
+// followed by a note such as "(metrics stale)", into one of the calibration bands.
+func ConfidenceBand(confidence string) string {
+	confidence = strings.ToLower(strings.TrimSpace(confidence))
+	var pct float64
+	if _, err := fmt.Sscanf(confidence, "%f%%", &pct); err == nil {
+		switch {
+		case pct >= 80:
+			return ConfidenceBandHigh
+		case pct >= 50:
+			return ConfidenceBandMedium
+		default:
+			return ConfidenceBandLow
+		}
+	}
+	for _, band := range []string{ConfidenceBandHigh, ConfidenceBandMedium, ConfidenceBandLow} {
+		if strings.HasPrefix(confidence, band) {
+			return band
+		}
+	}
+	return ConfidenceBandUnknown
+}
+
+// CalibrationBand compares the RCAs of one confidence band with the verdicts responders gave them.
+type CalibrationBand struct {
+	Band    string `json:"band"`
+	Total   int    `json:"total"`
+	Correct int    `json:"correct"`
+	// Accuracy is Correct over Total; a well-calibrated high band scores above the medium and low bands
+	Accuracy float64 `json:"accuracy"`
+}
+
+// SaveFeedback records the verdict on an incident's RCA, replacing an earlier verdict.
+func (db *DB) SaveFeedback(f Feedback) error {
+	if f.CreatedAt.IsZero() {
+		f.CreatedAt = time.Now().UTC()
+	}
+	_, err := db.Exec(`INSERT INTO incident_feedback (incident_id, correct, actual_cause, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (incident_id) DO UPDATE SET correct = EXCLUDED.correct, actual_cause = EXCLUDED.actual_cause, created_at = EXCLUDED.created_at`,
+		f.IncidentID, f.Correct, f.ActualCause, f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save feedback: %w", err)
+	}
+	return nil
+}
+
+// ConfidenceCalibration groups every incident with feedback by the band of its stated confidence and
+// counts how many of each band's RCAs were judged correct. Bands without feedback are left out.
+func (db *DB) ConfidenceCalibration() ([]CalibrationBand, error) {
+	rows, err := db.Query(`SELECT COALESCE(i.confidence, ''), f.correct
+		FROM incident_feedback f JOIN incidents i ON i.id = f.incident_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]*CalibrationBand)
+	for rows.Next() {
+		var confidence string
+		var correct bool
+		if err := rows.Scan(&confidence, &correct); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		band := ConfidenceBand(confidence)
+		c, ok := counts[band]
+		if !ok {
+			c = &CalibrationBand{Band: band}
+			counts[band] = c
+		}
+		c.Total++
+		if correct {
+			c.Correct++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+
+	var bands []CalibrationBand
+	for _, band := range confidenceBands {
+		if c, ok := counts[band]; ok {
+			c.Accuracy = float64(c.Correct) / float64(c.Total)
+			bands = append(bands, *c)
+		}
+	}
+	return bands, nil
+}
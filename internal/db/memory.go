@@ -0,0 +1,246 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRegistryCapacity is the number of incidents an IncidentRegistry keeps when none is configured.
+const DefaultRegistryCapacity = 1000
+
+// sentPerIncident is how many sent-notification markers a registry keeps per incident of capacity.
+const sentPerIncident = 4
+
+// listLimit matches the row limit ListIncidents applies in the database.
+const listLimit = 100
+
+// IncidentRegistry is an in-memory IncidentStore for deployments without a database. It holds at
+// most capacity incidents; beyond that the oldest closed incident is evicted, or the oldest open one
+// when every incident is still open. Contents are lost on restart.
+type IncidentRegistry struct {
+	capacity int
+
+	mu        sync.Mutex
+	incidents map[string]*Incident
+	// order holds incident IDs oldest first, for eviction
+	order []string
+
+	// sent holds the claimed notification markers, and sentOrder their keys oldest first, for eviction
+	sent      map[string]sentMarker
+	sentOrder []string
+}
+
+// sentMarker is a claimed notification: pending until its send succeeded, then holding the send's reference.
+type sentMarker struct {
+	pending bool
+	ref     string
+}
+
+// NewIncidentRegistry returns an empty registry holding up to capacity incidents
+// (DefaultRegistryCapacity when capacity is not positive).
+func NewIncidentRegistry(capacity int) *IncidentRegistry {
+	if capacity <= 0 {
+		capacity = DefaultRegistryCapacity
+	}
+	return &IncidentRegistry{
+		capacity:  capacity,
+		incidents: make(map[string]*Incident),
+		sent:      make(map[string]sentMarker),
+	}
+}
+
+// Len returns the number of incidents currently held.
+func (r *IncidentRegistry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.incidents)
+}
+
+// CreateIncident stores a copy of incident; Status defaults to open.
+func (r *IncidentRegistry) CreateIncident(incident *Incident) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.incidents[incident.ID]; ok {
+		return fmt.Errorf("failed to insert incident: duplicate id %q", incident.ID)
+	}
+	stored := *incident
+	if stored.Status == "" {
+		stored.Status = IncidentStatusOpen
+	}
+	r.incidents[stored.ID] = &stored
+	r.order = append(r.order, stored.ID)
+	for len(r.order) > r.capacity {
+		r.evict()
+	}
+	return nil
+}
+
+// evict drops the oldest incident that is no longer open, or the oldest incident when all are open.
+func (r *IncidentRegistry) evict() {
+	victim := 0
+	for i, id := range r.order {
+		if !isOpen(r.incidents[id]) {
+			victim = i
+			break
+		}
+	}
+	delete(r.incidents, r.order[victim])
+	r.order = append(r.order[:victim], r.order[victim+1:]...)
+}
+
+// ClaimNotification records that the notification for incidentKey on channel is being sent and reports
+// false when it already was or is being sent, together with the reference of a sent one. The oldest
+// markers are dropped beyond sentPerIncident per incident of capacity.
+func (r *IncidentRegistry) ClaimNotification(incidentKey, channel string) (bool, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := incidentKey + "\x00" + channel
+	if marker, ok := r.sent[key]; ok {
+		return false, marker.ref, nil
+	}
+	r.setMarker(key, sentMarker{pending: true})
+	return true, "", nil
+}
+
+// CompleteNotification marks the notification for incidentKey on channel sent and stores ref with it.
+func (r *IncidentRegistry) CompleteNotification(incidentKey, channel, ref string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setMarker(incidentKey+"\x00"+channel, sentMarker{ref: ref})
+	return nil
+}
+
+// setMarker stores the marker under key, tracking new keys for eviction. The caller holds r.mu.
+func (r *IncidentRegistry) setMarker(key string, marker sentMarker) {
+	if _, ok := r.sent[key]; !ok {
+		r.sentOrder = append(r.sentOrder, key)
+	}
+	r.sent[key] = marker
+	for len(r.sentOrder) > r.capacity*sentPerIncident {
+		delete(r.sent, r.sentOrder[0])
+		r.sentOrder = r.sentOrder[1:]
+	}
+}
+
+// ReleasePendingNotifications forgets the markers of notifications that were claimed but never sent.
+func (r *IncidentRegistry) ReleasePendingNotifications() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sentOrder = slices.DeleteFunc(r.sentOrder, func(k string) bool { return r.sent[k].pending })
+	for k, marker := range r.sent {
+		if marker.pending {
+			delete(r.sent, k)
+		}
+	}
+	return nil
+}
+
+// ReleaseNotification forgets the marker for incidentKey on channel so the notification can be sent again.
+func (r *IncidentRegistry) ReleaseNotification(incidentKey, channel string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := incidentKey + "\x00" + channel
+	if _, ok := r.sent[key]; ok {
+		delete(r.sent, key)
+		r.sentOrder = slices.DeleteFunc(r.sentOrder, func(k string) bool { return k == key })
+	}
+	return nil
+}
+
+// isOpen reports whether FindOpenIncident can still return i.
+func isOpen(i *Incident) bool {
+	switch i.Status {
+	case IncidentStatusOpen, IncidentStatusMaintenance, IncidentStatusTriaged:
+		return i.ResolvedAt == nil
+	}
+	return false
+}
+
+// ResolveIncident marks an incident as resolved now.
+func (r *IncidentRegistry) ResolveIncident(id, rootCause, aiSummary string) error {
+	return r.ResolveIncidentAt(id, time.Now().UTC(), rootCause, aiSummary)
+}
+
+// ResolveIncidentAt marks an incident as resolved at the given time. Like the database, an unknown
+// (or already evicted) ID is not an error.
+func (r *IncidentRegistry) ResolveIncidentAt(id string, resolvedAt time.Time, rootCause, aiSummary string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i, ok := r.incidents[id]; ok {
+		i.Status = IncidentStatusResolved
+		i.ResolvedAt = &resolvedAt
+		i.RootCause = &rootCause
+		i.AISummary = &aiSummary
+	}
+	return nil
+}
+
+// CloseIncident sets ResolvedAt without changing the status.
+func (r *IncidentRegistry) CloseIncident(id string, closedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i, ok := r.incidents[id]; ok {
+		i.ResolvedAt = &closedAt
+	}
+	return nil
+}
+
+// GetIncident returns a copy of the incident with the given ID, or nil if it is unknown or evicted.
+func (r *IncidentRegistry) GetIncident(id string) (*Incident, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.incidents[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *i
+	return &found, nil
+}
+
+// FindOpenIncident returns the most recent open (or maintenance) incident for an alert fingerprint
+// that has not been closed yet, or nil if none exists.
+func (r *IncidentRegistry) FindOpenIncident(fingerprint string) (*Incident, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found *Incident
+	for _, i := range r.incidents {
+		if i.Fingerprint == fingerprint && isOpen(i) && (found == nil || i.StartedAt.After(found.StartedAt)) {
+			found = i
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+	open := *found
+	return &open, nil
+}
+
+// ListIncidents returns up to 100 incidents, optionally filtered by status, most recently started first.
+func (r *IncidentRegistry) ListIncidents(status string) ([]Incident, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var incidents []Incident
+	for _, i := range r.incidents {
+		if status == "" || i.Status == status {
+			incidents = append(incidents, *i)
+		}
+	}
+	sort.Slice(incidents, func(a, b int) bool { return incidents[a].StartedAt.After(incidents[b].StartedAt) })
+	if len(incidents) > listLimit {
+		incidents = incidents[:listLimit]
+	}
+	return incidents, nil
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// MergeIncidents folds the duplicates mergeIDs into primaryID: their analysis rows are reassigned to
+// the primary, and each is marked merged with a pointer to it and closed at mergedAt unless it already
+// ended. Duplicates earlier merged into one of mergeIDs are re-pointed at the primary. Nothing is
+// changed unless every duplicate exists and is not merged already.
+func (db *DB) MergeIncidents(primaryID string, mergeIDs []string, mergedAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin merge: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range mergeIDs {
+		if id == primaryID {
+			return fmt.Errorf("cannot merge incident %s into itself", id)
+		}
+		res, err := tx.Exec(`UPDATE incidents SET status = 'merged', merged_into = $1, resolved_at = COALESCE(resolved_at, $2)
+			WHERE id = $3 AND status <> 'merged'`, primaryID, mergedAt.UTC(), id)
+		if err != nil {
+			return fmt.Errorf("failed to merge incident %s: %w", id, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to merge incident %s: %w", id, err)
+		} else if n == 0 {
+			return fmt.Errorf("incident %s does not exist or is already merged", id)
+		}
+		if _, err := tx.Exec(`UPDATE incidents SET merged_into = $1 WHERE merged_into = $2`, primaryID, id); err != nil {
+			return fmt.Errorf("failed to re-point incidents merged into %s: %w", id, err)
+		}
+		if _, err := tx.Exec(`UPDATE analysis_results SET incident_id = $1 WHERE incident_id = $2`, primaryID, id); err != nil {
+			return fmt.Errorf("failed to reassign analysis results of %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
+
+// MergedIncidents returns the duplicates merged into primaryID, oldest first.
+func (db *DB) MergedIncidents(primaryID string) ([]Incident, error) {
+	return db.queryIncidents(`SELECT `+incidentColumns+` FROM incidents
+		WHERE merged_into = $1 ORDER BY started_at, id`, primaryID)
+}
+
+// FindMergedIncident returns the merged duplicate recorded for the alert firing with fingerprint at
+// startedAt, or nil when that firing was not merged.
+func (db *DB) FindMergedIncident(fingerprint string, startedAt time.Time) (*Incident, error) {
+	return db.findFiring(fingerprint, IncidentStatusMerged, startedAt)
+}
+
+// findFiring returns the incident with status recorded for the alert firing with fingerprint at
+// startedAt, or nil. Start times are compared in Go, since SQLite and PostgreSQL store them differently.
+func (db *DB) findFiring(fingerprint, status string, startedAt time.Time) (*Incident, error) {
+	incidents, err := db.queryIncidents(`SELECT `+incidentColumns+` FROM incidents
+		WHERE fingerprint = $1 AND status = $2 ORDER BY started_at DESC`, fingerprint, status)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range incidents {
+		if i.StartedAt.Equal(startedAt) {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	// The SQLite driver is registered here so database.driver: sqlite works without a PostgreSQL server.
+	// It needs cgo; a binary built with CGO_ENABLED=0 fails to open the database with an error saying so.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// isSQLite reports whether the connection uses the SQLite driver.
+func (db *DB) isSQLite() bool {
+	return db.driver == "sqlite3"
+}
+
+// dialect rewrites PostgreSQL-only DDL for the active driver.
+func (db *DB) dialect(stmt string) string {
+	if db.isSQLite() {
+		stmt = strings.ReplaceAll(stmt, "SERIAL PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT")
+	}
+	return stmt
+}
+
+// addSQLiteColumn adds a column unless the table already has it; SQLite has no ADD COLUMN IF NOT EXISTS.
+func (db *DB) addSQLiteColumn(table, column, definition string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2`, table, column).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
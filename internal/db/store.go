@@ -0,0 +1,108 @@
+package db
+
+import (
+	"time"
+
+	"helixops/internal/models"
+)
+
+// Store is everything HelixOps persists: incidents with their analysis artifacts and postmortems,
+// sent-notification markers, responder feedback, the alert queue, commit cursors, deploy events, and
+// service mappings. *DB implements it on PostgreSQL and on SQLite, picked by database.driver; the server and
+// its background jobs only depend on this interface.
+type Store interface {
+	IncidentStore
+	NotificationLog
+
+	Migrate() error
+	Close() error
+
+	// Incident queries beyond the lifecycle
+	UpdateIncident(incident *Incident) error
+	OpenGroupLeaders(since time.Time) ([]Incident, error)
+	IncidentGroup(leaderID string) ([]Incident, error)
+	CloseGroupMember(id string, closedAt time.Time) error
+	UngroupIncidents(leaderID string) ([]Incident, error)
+	FindGroupedIncident(fingerprint string, startedAt time.Time) (*Incident, error)
+	ListIncidentsStartedBetween(from, to time.Time) ([]Incident, error)
+	PriorIncidents(serviceName, alertName string, before time.Time, limit int) ([]models.PriorIncident, error)
+	ForEachPostmortem(from, to time.Time, fn func(Incident) error) error
+	PurgeBefore(cutoff time.Time) (int64, error)
+	MergeIncidents(primaryID string, mergeIDs []string, mergedAt time.Time) error
+	MergedIncidents(primaryID string) ([]Incident, error)
+	FindMergedIncident(fingerprint string, startedAt time.Time) (*Incident, error)
+
+	// Analysis artifacts stored per incident
+	SaveAnalysisResult(incidentID, analysisType string, v interface{}) error
+	LoadAnalysisResult(incidentID, analysisType string, v interface{}) (bool, error)
+	SaveContextSnapshot(incidentID string, ac *models.AnalysisContext) error
+	LoadContextSnapshot(incidentID string) (*models.AnalysisContext, error)
+	SaveRawLLMResponse(incidentID string, r *models.LLMResponse) error
+	LoadRawLLMResponse(incidentID string) (*models.LLMResponse, error)
+
+	// Alert queue
+	EnqueueAlerts(receiver string, payload models.AlertManagerPayload) (int64, error)
+	ScheduleAlerts(receiver string, payload models.AlertManagerPayload, notBefore time.Time) (int64, error)
+	StartPendingAlert(id int64) error
+	FinishPendingAlert(id int64, procErr error) error
+	ResumePendingAlerts(maxAttempts int) ([]PendingAlert, error)
+
+	// Incident digest state
+	LastDigestEnd() (time.Time, error)
+	SaveDigestEnd(end time.Time) error
+
+	// Commit cursors
+	LoadCommitCursor(key string) (*models.CommitCursor, error)
+	SaveCommitCursor(key string, c *models.CommitCursor) error
+
+	// Deploy events reported by CD systems
+	SaveDeployEvent(e models.DeployEvent) error
+	DeployEvents(serviceName string, from, to time.Time) ([]models.DeployEvent, error)
+
+	// Responder feedback on RCAs
+	SaveFeedback(f Feedback) error
+	ConfidenceCalibration() ([]CalibrationBand, error)
+
+	// Service mappings
+	GetServiceMapping(serviceName string) (*ServiceMapping, error)
+	ListServiceMappings() ([]ServiceMapping, error)
+	SeedServiceMapping(serviceName, repo string) error
+	ConfirmServiceMapping(serviceName, repo string) error
+}
+
+// IncidentStore is the incident lifecycle the alert handlers need: record an incident when an alert
+// fires, find and resolve it when the alert clears, and list or fetch it for the API. *DB implements
+// it durably; IncidentRegistry keeps a bounded set in memory for deployments without a database.
+type IncidentStore interface {
+	CreateIncident(incident *Incident) error
+	ResolveIncident(id, rootCause, aiSummary string) error
+	ResolveIncidentAt(id string, resolvedAt time.Time, rootCause, aiSummary string) error
+	CloseIncident(id string, closedAt time.Time) error
+	GetIncident(id string) (*Incident, error)
+	FindOpenIncident(fingerprint string) (*Incident, error)
+	ListIncidents(status string) ([]Incident, error)
+}
+
+// NotificationLog remembers which notifications went out for an incident, so a retried or duplicated
+// analysis does not post the same message twice. A pending claim is taken before sending, completed once
+// the send succeeded, and released when it fails, so the next attempt can deliver it.
+type NotificationLog interface {
+	// ClaimNotification records that the notification for incidentKey on channel is being sent and
+	// reports false when it already was or is being sent. For a sent notification it also returns the
+	// reference its send recorded, such as the Slack thread it started.
+	ClaimNotification(incidentKey, channel string) (bool, string, error)
+	// CompleteNotification marks the notification sent and stores ref with it
+	CompleteNotification(incidentKey, channel, ref string) error
+	ReleaseNotification(incidentKey, channel string) error
+	// ReleasePendingNotifications drops the claims that were never completed, left behind when the
+	// process stopped between claiming and sending, so resumed processing sends them
+	ReleasePendingNotifications() error
+}
+
+var (
+	_ Store           = (*DB)(nil)
+	_ IncidentStore   = (*DB)(nil)
+	_ IncidentStore   = (*IncidentRegistry)(nil)
+	_ NotificationLog = (*DB)(nil)
+	_ NotificationLog = (*IncidentRegistry)(nil)
+)
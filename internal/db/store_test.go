@@ -0,0 +1,199 @@
+package db_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// incidentStores returns each IncidentStore implementation, fresh, so the same behavior is checked
+// against every database driver available and the in-memory registry.
+func incidentStores(t *testing.T) map[string]db.IncidentStore {
+	stores := map[string]db.IncidentStore{"memory": db.NewIncidentRegistry(0)}
+	for name, database := range dbtest.Stores(t) {
+		stores[name] = database
+	}
+	return stores
+}
+
+func TestIncidentStoreLifecycle(t *testing.T) {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range incidentStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.CreateIncident(&db.Incident{ID: "inc-1", ServiceName: "checkout", AlertName: "HighLatency",
+				Severity: "critical", StartedAt: started, Fingerprint: "fp-1"}))
+			require.NoError(t, store.CreateIncident(&db.Incident{ID: "inc-2", ServiceName: "checkout", AlertName: "HighLatency",
+				Severity: "critical", StartedAt: started.Add(time.Hour), Fingerprint: "fp-1"}))
+			require.NoError(t, store.CreateIncident(&db.Incident{ID: "inc-3", ServiceName: "cart", AlertName: "Deploy",
+				StartedAt: started, Status: db.IncidentStatusMaintenance, Fingerprint: "fp-3"}))
+			assert.Error(t, store.CreateIncident(&db.Incident{ID: "inc-1", StartedAt: started}), "IDs are unique")
+
+			open, err := store.FindOpenIncident("fp-1")
+			require.NoError(t, err)
+			require.NotNil(t, open)
+			assert.Equal(t, "inc-2", open.ID, "the most recent open incident wins")
+			assert.Equal(t, db.IncidentStatusOpen, open.Status)
+
+			resolvedAt := started.Add(2 * time.Hour)
+			require.NoError(t, store.ResolveIncidentAt("inc-2", resolvedAt, "pool exhausted", "# Postmortem"))
+			require.NoError(t, store.ResolveIncidentAt("missing", resolvedAt, "", ""))
+
+			got, err := store.GetIncident("inc-2")
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, db.IncidentStatusResolved, got.Status)
+			require.NotNil(t, got.ResolvedAt)
+			assert.True(t, resolvedAt.Equal(*got.ResolvedAt))
+			require.NotNil(t, got.RootCause)
+			assert.Equal(t, "pool exhausted", *got.RootCause)
+			require.NotNil(t, got.AISummary)
+			assert.Equal(t, "# Postmortem", *got.AISummary)
+
+			open, err = store.FindOpenIncident("fp-1")
+			require.NoError(t, err)
+			require.NotNil(t, open)
+			assert.Equal(t, "inc-1", open.ID)
+
+			require.NoError(t, store.CloseIncident("inc-3", resolvedAt))
+			open, err = store.FindOpenIncident("fp-3")
+			require.NoError(t, err)
+			assert.Nil(t, open, "closed maintenance incidents are no longer open")
+			closed, err := store.GetIncident("inc-3")
+			require.NoError(t, err)
+			assert.Equal(t, db.IncidentStatusMaintenance, closed.Status)
+
+			missing, err := store.GetIncident("missing")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+
+			resolved, err := store.ListIncidents(db.IncidentStatusResolved)
+			require.NoError(t, err)
+			require.Len(t, resolved, 1)
+			assert.Equal(t, "inc-2", resolved[0].ID)
+
+			all, err := store.ListIncidents("")
+			require.NoError(t, err)
+			require.Len(t, all, 3)
+			assert.Equal(t, "inc-2", all[0].ID, "most recently started first")
+		})
+	}
+}
+
+func TestTriagedIncidentsCloseLikeMaintenance(t *testing.T) {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for name, store := range incidentStores(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.CreateIncident(&db.Incident{ID: "inc-1", ServiceName: "cart", AlertName: "KubePodRestarted",
+				StartedAt: started, Status: db.IncidentStatusTriaged, Fingerprint: "fp-1"}))
+
+			open, err := store.FindOpenIncident("fp-1")
+			require.NoError(t, err)
+			require.NotNil(t, open, "triaged incidents stay open until their alert resolves")
+
+			require.NoError(t, store.CloseIncident("inc-1", started.Add(time.Minute)))
+			open, err = store.FindOpenIncident("fp-1")
+			require.NoError(t, err)
+			assert.Nil(t, open)
+			closed, err := store.GetIncident("inc-1")
+			require.NoError(t, err)
+			assert.Equal(t, db.IncidentStatusTriaged, closed.Status)
+		})
+	}
+}
+
+func TestNotificationLogClaimsOncePerIncidentAndChannel(t *testing.T) {
+	logs := map[string]db.NotificationLog{"memory": db.NewIncidentRegistry(0)}
+	for name, database := range dbtest.Stores(t) {
+		logs[name] = database
+	}
+	for name, log := range logs {
+		t.Run(name, func(t *testing.T) {
+			const key = "fp-1@2024-01-01T12:00:00Z"
+			claimed, _, err := log.ClaimNotification(key, "slack_analysis")
+			require.NoError(t, err)
+			assert.True(t, claimed)
+
+			claimed, _, err = log.ClaimNotification(key, "slack_analysis")
+			require.NoError(t, err)
+			assert.False(t, claimed, "a second send for the same incident and channel is suppressed")
+
+			claimed, _, err = log.ClaimNotification(key, "webhook_analysis")
+			require.NoError(t, err)
+			assert.True(t, claimed, "other channels are tracked separately")
+
+			require.NoError(t, log.ReleaseNotification(key, "slack_analysis"))
+			claimed, _, err = log.ClaimNotification(key, "slack_analysis")
+			require.NoError(t, err)
+			assert.True(t, claimed, "a released marker can be claimed again")
+
+			require.NoError(t, log.CompleteNotification(key, "slack_firing", "1700000000.000100"))
+			claimed, ref, err := log.ClaimNotification(key, "slack_firing")
+			require.NoError(t, err)
+			assert.False(t, claimed)
+			assert.Equal(t, "1700000000.000100", ref, "a sent notification returns its stored reference")
+
+			require.NoError(t, log.ReleasePendingNotifications())
+			claimed, _, err = log.ClaimNotification(key, "webhook_analysis")
+			require.NoError(t, err)
+			assert.True(t, claimed, "a claim never completed is released, e.g. after a crash")
+			claimed, _, err = log.ClaimNotification(key, "slack_firing")
+			require.NoError(t, err)
+			assert.False(t, claimed, "sent notifications stay recorded")
+		})
+	}
+}
+
+func TestIncidentRegistryEvictsClosedIncidentsFirst(t *testing.T) {
+	registry := db.NewIncidentRegistry(3)
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	create := func(id string) {
+		require.NoError(t, registry.CreateIncident(&db.Incident{ID: id, StartedAt: started, Fingerprint: "fp-" + id}))
+	}
+
+	create("a")
+	create("b")
+	create("c")
+	require.NoError(t, registry.ResolveIncident("b", "", ""))
+	create("d")
+
+	assert.Equal(t, 3, registry.Len())
+	evicted, err := registry.GetIncident("b")
+	require.NoError(t, err)
+	assert.Nil(t, evicted, "the resolved incident goes before older open ones")
+	kept, err := registry.GetIncident("a")
+	require.NoError(t, err)
+	assert.NotNil(t, kept)
+
+	create("e")
+	evicted, err = registry.GetIncident("a")
+	require.NoError(t, err)
+	assert.Nil(t, evicted, "with every incident open, the oldest goes")
+	assert.Equal(t, 3, registry.Len())
+}
+
+func TestIncidentRegistryConcurrentUse(t *testing.T) {
+	registry := db.NewIncidentRegistry(50)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				id := fmt.Sprintf("inc-%d-%d", i, j)
+				assert.NoError(t, registry.CreateIncident(&db.Incident{ID: id, StartedAt: time.Now(), Fingerprint: id}))
+				_, _ = registry.FindOpenIncident(id)
+				assert.NoError(t, registry.ResolveIncident(id, "", ""))
+				_, _ = registry.ListIncidents("")
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 50, registry.Len())
+}
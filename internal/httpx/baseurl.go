@@ -0,0 +1,39 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// BaseURLError reports a backend base URL that cannot be turned into request URLs.
+type BaseURLError struct {
+	// Backend names the client the URL was configured for, e.g. "tempo"
+	Backend string
+	URL     string
+	Err     error
+}
+
+func (e *BaseURLError) Error() string {
+	return fmt.Sprintf("invalid %s base URL %q: %v", e.Backend, e.URL, e.Err)
+}
+
+func (e *BaseURLError) Unwrap() error {
+	return e.Err
+}
+
+// CheckBaseURL verifies that raw is an absolute http(s) URL with a host, so a typo in the
+// configuration is reported once at startup rather than by every request the client makes.
+func CheckBaseURL(backend, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return &BaseURLError{Backend: backend, URL: raw, Err: err}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &BaseURLError{Backend: backend, URL: raw, Err: errors.New("scheme must be http or https")}
+	}
+	if u.Host == "" {
+		return &BaseURLError{Backend: backend, URL: raw, Err: errors.New("missing host")}
+	}
+	return nil
+}
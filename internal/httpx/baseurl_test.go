@@ -0,0 +1,23 @@
+package httpx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBaseURL(t *testing.T) {
+	for _, raw := range []string{"http://tempo:3200", "https://api.github.com", "http://loki.monitoring.svc:3100/"} {
+		assert.NoError(t, CheckBaseURL("tempo", raw), raw)
+	}
+
+	for _, raw := range []string{"", "tempo:3200", "localhost:3200", "ftp://tempo", "http://", "http://tempo:port", "http://%zz"} {
+		err := CheckBaseURL("tempo", raw)
+		var urlErr *BaseURLError
+		require.True(t, errors.As(err, &urlErr), raw)
+		assert.Equal(t, "tempo", urlErr.Backend)
+		assert.Equal(t, raw, urlErr.URL)
+	}
+}
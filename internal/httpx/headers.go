@@ -0,0 +1,41 @@
+package httpx
+
+import "net/http"
+
+// headerTransport adds configured headers to every request without overriding ones the client set
+// itself, so gateway headers like X-Api-Key or X-Scope-OrgID sit alongside auth headers.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+// RoundTrip sends a copy of req carrying every configured header req does not already have.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header[name] = values
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// WithHeaders makes client send headers on every outbound request. Empty names are ignored and an
+// empty map leaves the client unchanged.
+func WithHeaders(client *http.Client, headers map[string]string) {
+	h := make(http.Header, len(headers))
+	for name, value := range headers {
+		if name != "" {
+			h.Set(name, value)
+		}
+	}
+	if len(h) == 0 {
+		return
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &headerTransport{base: base, headers: h}
+}
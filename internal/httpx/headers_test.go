@@ -0,0 +1,42 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHeadersMergesWithRequestHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	client := NewClient(0)
+	WithHeaders(client, map[string]string{
+		"x-api-key":     "gateway-key",
+		"Authorization": "Bearer configured",
+	})
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer from-client")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "gateway-key", got.Get("X-Api-Key"))
+	assert.Equal(t, "Bearer from-client", got.Get("Authorization"), "headers the client sets are not overridden")
+	assert.Empty(t, req.Header.Get("X-Api-Key"), "the caller's request is not mutated")
+}
+
+func TestWithHeadersEmptyLeavesClientUnchanged(t *testing.T) {
+	client := NewClient(0)
+	transport := client.Transport
+	WithHeaders(client, nil)
+	assert.Same(t, transport, client.Transport)
+}
@@ -0,0 +1,82 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BackpressureError is returned when a request waited the whole queue timeout for a free slot of a
+// backend's concurrency limit, so an overloaded backend is reported rather than piled onto.
+type BackpressureError struct {
+	Backend string
+	Limit   int
+	Waited  time.Duration
+}
+
+func (e *BackpressureError) Error() string {
+	return fmt.Sprintf("%s backpressure: all %d query slots stayed busy for %s", e.Backend, e.Limit, e.Waited)
+}
+
+// limitTransport lets at most cap(slots) requests be in flight at once. A slot is held until the
+// response body is closed, since reading it still loads the backend.
+type limitTransport struct {
+	base         http.RoundTripper
+	backend      string
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+// RoundTrip waits for a free slot, up to the queue timeout or until the request is cancelled.
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var expired <-chan time.Time
+	if t.queueTimeout > 0 {
+		timer := time.NewTimer(t.queueTimeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-expired:
+		return nil, &BackpressureError{Backend: t.backend, Limit: cap(t.slots), Waited: t.queueTimeout}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-t.slots
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: sync.OnceFunc(func() { <-t.slots })}
+	return resp, nil
+}
+
+// releaseBody frees the request's slot when the body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// WithConcurrencyLimit lets at most limit requests of client be in flight at once. Further requests
+// queue for up to queueTimeout (indefinitely when 0, still bounded by their context) and then fail with
+// a *BackpressureError naming backend. Every copy of client shares the limit; limit <= 0 leaves it
+// unchanged.
+func WithConcurrencyLimit(client *http.Client, backend string, limit int, queueTimeout time.Duration) {
+	if limit <= 0 {
+		return
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &limitTransport{base: base, backend: backend, slots: make(chan struct{}, limit), queueTimeout: queueTimeout}
+}
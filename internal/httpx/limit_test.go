@@ -0,0 +1,44 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithConcurrencyLimitReportsBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewClient(0)
+	WithConcurrencyLimit(client, "loki", 1, 50*time.Millisecond)
+
+	held := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		held <- err
+	}()
+	require.Eventually(t, func() bool { return len(client.Transport.(*limitTransport).slots) == 1 }, time.Second, time.Millisecond)
+
+	_, err := client.Get(srv.URL)
+	var bp *BackpressureError
+	require.True(t, errors.As(err, &bp), "got %v", err)
+	assert.Equal(t, "loki", bp.Backend)
+	assert.Equal(t, 1, bp.Limit)
+
+	release <- struct{}{}
+	require.NoError(t, <-held)
+	assert.Empty(t, client.Transport.(*limitTransport).slots, "closing the body frees the slot")
+}
@@ -0,0 +1,111 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestKey identifies one series of the request counter.
+type requestKey struct {
+	host   string
+	status string
+}
+
+// durationStats accumulates request latency for one host.
+type durationStats struct {
+	sum   float64
+	count uint64
+}
+
+// Metrics records outbound request counts and durations per host. It renders itself in the
+// Prometheus text exposition format so /metrics can be scraped without a client library.
+type Metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[string]*durationStats
+}
+
+// NewMetrics returns an empty registry.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[string]*durationStats),
+	}
+}
+
+// DefaultMetrics is the registry shared by clients built with NewClient.
+var DefaultMetrics = NewMetrics()
+
+// Observe records one completed request. status is the HTTP status code, or "error" when the
+// request failed before a response arrived.
+func (m *Metrics) Observe(host, status string, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{host: host, status: status}]++
+	d, ok := m.durations[host]
+	if !ok {
+		d = &durationStats{}
+		m.durations[host] = d
+	}
+	d.sum += elapsed.Seconds()
+	d.count++
+}
+
+// Requests reports how many requests to host finished with the given status.
+func (m *Metrics) Requests(host, status string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[requestKey{host: host, status: status}]
+}
+
+// WriteTo renders every series in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].host != keys[j].host {
+			return keys[i].host < keys[j].host
+		}
+		return keys[i].status < keys[j].status
+	})
+	hosts := make([]string, 0, len(m.durations))
+	for h := range m.durations {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+
+	var b strings.Builder
+	b.WriteString("# HELP helixops_http_client_requests_total Outbound HTTP requests by host and status.\n")
+	b.WriteString("# TYPE helixops_http_client_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "helixops_http_client_requests_total{host=%q,status=%q} %d\n", k.host, k.status, m.requests[k])
+	}
+	b.WriteString("# HELP helixops_http_client_request_duration_seconds Outbound HTTP request latency by host.\n")
+	b.WriteString("# TYPE helixops_http_client_request_duration_seconds summary\n")
+	for _, h := range hosts {
+		d := m.durations[h]
+		fmt.Fprintf(&b, "helixops_http_client_request_duration_seconds_sum{host=%q} %g\n", h, d.sum)
+		fmt.Fprintf(&b, "helixops_http_client_request_duration_seconds_count{host=%q} %d\n", h, d.count)
+	}
+	m.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteTo(w)
+	})
+}
@@ -0,0 +1,30 @@
+package httpx
+
+import "context"
+
+// DefaultRequestIDHeader is the header correlation IDs are sent in unless app.request_id_header says otherwise.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// requestID is a correlation ID and the header it travels in.
+type requestID struct {
+	header string
+	id     string
+}
+
+// WithRequestID returns a context under which every request sent through Transport carries id in the
+// given header, so downstream systems can tie their logs to the incident that caused the request. An
+// empty header or id returns ctx unchanged.
+func WithRequestID(ctx context.Context, header, id string) context.Context {
+	if header == "" || id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID{header: header, id: id})
+}
+
+// RequestID returns the correlation ID carried by ctx, or "" when there is none.
+func RequestID(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDKey{}).(requestID)
+	return rid.id
+}
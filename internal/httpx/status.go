@@ -0,0 +1,37 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorDetail caps how much of an error response body is read for its message.
+const maxErrorDetail = 512
+
+// StatusError describes a response with an unexpected status as "<msg>: <code>", followed by the
+// backend's explanation when the body carries one: the "error" field of a JSON body, as Prometheus
+// sends for a query that does not parse, or else the body text, as Loki and Tempo send.
+func StatusError(msg string, resp *http.Response) error {
+	if detail := errorDetail(resp.Body); detail != "" {
+		return fmt.Errorf("%s: %d: %s", msg, resp.StatusCode, detail)
+	}
+	return fmt.Errorf("%s: %d", msg, resp.StatusCode)
+}
+
+// errorDetail reads the start of an error body and returns its message, or "" when there is none.
+func errorDetail(body io.Reader) string {
+	if body == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(io.LimitReader(body, maxErrorDetail))
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+		return apiErr.Error
+	}
+	return strings.Join(strings.Fields(string(data)), " ")
+}
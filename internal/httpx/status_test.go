@@ -0,0 +1,22 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusErrorIncludesBackendExplanation(t *testing.T) {
+	resp := func(code int, body string) *http.Response {
+		return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	assert.EqualError(t, StatusError("unexpected status code", resp(400, `{"status":"error","errorType":"bad_data","error":"parse error at char 5"}`)),
+		"unexpected status code: 400: parse error at char 5")
+	assert.EqualError(t, StatusError("unexpected status code", resp(403, "tenant\nnot allowed\n")),
+		"unexpected status code: 403: tenant not allowed")
+	assert.EqualError(t, StatusError("unexpected status code", resp(502, "")), "unexpected status code: 502")
+}
@@ -0,0 +1,58 @@
+// Package httpx provides the instrumented HTTP transport shared by every outbound client, so
+// request logging, metrics, and correlation IDs live in one place instead of each client's bare http.Client.
+package httpx
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Transport wraps a base RoundTripper, recording per-host request count, duration, and status.
+type Transport struct {
+	// Base performs the request; http.DefaultTransport when nil.
+	Base http.RoundTripper
+	// Metrics receives one observation per request; DefaultMetrics when nil.
+	Metrics *Metrics
+}
+
+// RoundTrip executes the request through Base and records its outcome. A correlation ID in the
+// request's context is added as a header unless the client already set that header.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if rid, ok := req.Context().Value(requestIDKey{}).(requestID); ok && req.Header.Get(rid.header) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(rid.header, rid.id)
+	}
+	metrics := t.Metrics
+	if metrics == nil {
+		metrics = DefaultMetrics
+	}
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	metrics.Observe(req.URL.Host, status, elapsed)
+	slog.Debug("Outbound HTTP request",
+		"method", req.Method, "host", req.URL.Host, "path", req.URL.Path,
+		"status", status, "duration", elapsed, "error", err)
+
+	return resp, err
+}
+
+// NewClient returns an http.Client with the given timeout that reports to DefaultMetrics.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &Transport{},
+	}
+}
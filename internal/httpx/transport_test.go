@@ -0,0 +1,87 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportRecordsRequestMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	metrics := NewMetrics()
+	client := &http.Client{Transport: &Transport{Metrics: metrics}}
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for _, path := range []string{"/", "/", "/missing"} {
+		resp, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, uint64(2), metrics.Requests(host, "200"))
+	assert.Equal(t, uint64(1), metrics.Requests(host, "404"))
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, `helixops_http_client_requests_total{host="`+host+`",status="200"} 2`)
+	assert.Contains(t, body, `helixops_http_client_request_duration_seconds_count{host="`+host+`"} 3`)
+}
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestTransportRecordsFailedRequests(t *testing.T) {
+	metrics := NewMetrics()
+	tr := &Transport{Base: failingTransport{}, Metrics: metrics}
+
+	_, err := tr.RoundTrip(&http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "http", Host: "prom:9090", Path: "/api/v1/query"}})
+	require.Error(t, err)
+	assert.Equal(t, uint64(1), metrics.Requests("prom:9090", "error"))
+}
+
+func TestTransportPropagatesRequestID(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Request-Id"))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: &Transport{Metrics: NewMetrics()}}
+
+	send := func(ctx context.Context, preset string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		if preset != "" {
+			req.Header.Set("X-Request-Id", preset)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	ctx := WithRequestID(context.Background(), DefaultRequestIDHeader, "inc-42")
+	send(ctx, "")
+	send(ctx, "caller-set")
+	send(context.Background(), "")
+	send(WithRequestID(context.Background(), "", "inc-42"), "")
+
+	assert.Equal(t, []string{"inc-42", "caller-set", "", ""}, got)
+	assert.Equal(t, "inc-42", RequestID(ctx))
+}
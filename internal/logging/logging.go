@@ -0,0 +1,54 @@
+// Package logging configures the process-wide structured logger from application settings.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"helixops/internal/config"
+)
+
+// Supported log output formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New builds a slog.Logger writing to w in the given format, dropping records below level.
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q (expected text or json)", format)
+	}
+}
+
+// NewFromConfig builds a logger writing to w using the configured level and format.
+func NewFromConfig(w io.Writer, cfg config.AppConfig) (*slog.Logger, error) {
+	level, err := cfg.GetLogLevel()
+	if err != nil {
+		return nil, err
+	}
+	return New(w, cfg.LogFormat, level)
+}
+
+// Setup installs a logger built from the app config as the slog default. Because slog.SetDefault
+// also redirects the standard log package, any remaining log.Printf output shares the same handler.
+// Logs go to stderr so stdio-based transports (MCP) keep stdout for protocol traffic.
+func Setup(cfg config.AppConfig) (*slog.Logger, error) {
+	logger, err := NewFromConfig(os.Stderr, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.SetDefault(logger)
+	return logger, nil
+}
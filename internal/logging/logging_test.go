@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "json", slog.LevelInfo)
+	require.NoError(t, err)
+
+	logger.Debug("hidden", "service", "cart")
+	logger.Info("Processing alert", "alert", "HighLatency", "service", "cart")
+	logger.Error("Failed to analyze alert", "error", "boom")
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		lines = append(lines, entry)
+	}
+
+	require.Len(t, lines, 2)
+	assert.Equal(t, "INFO", lines[0]["level"])
+	assert.Equal(t, "Processing alert", lines[0]["msg"])
+	assert.Equal(t, "cart", lines[0]["service"])
+	assert.Contains(t, lines[0], "time")
+	assert.Equal(t, "ERROR", lines[1]["level"])
+	assert.Equal(t, "boom", lines[1]["error"])
+}
+
+func TestNewRejectsUnknownFormat(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "xml", slog.LevelInfo)
+	assert.Error(t, err)
+}
+
+func TestNewFromConfigHonorsLevel(t *testing.T) {
+	var info bytes.Buffer
+	logger, err := NewFromConfig(&info, config.AppConfig{LogLevel: "info"})
+	require.NoError(t, err)
+	logger.Debug("per-query detail", "query", "up")
+	logger.Info("visible")
+	assert.NotContains(t, info.String(), "per-query detail")
+	assert.Contains(t, info.String(), "visible")
+
+	var debug bytes.Buffer
+	logger, err = NewFromConfig(&debug, config.AppConfig{LogLevel: "debug", LogFormat: "json"})
+	require.NoError(t, err)
+	logger.Debug("per-query detail", "query", "up")
+	assert.Contains(t, debug.String(), `"level":"DEBUG"`)
+	assert.Contains(t, debug.String(), `"query":"up"`)
+}
+
+func TestNewFromConfigRejectsInvalidLevel(t *testing.T) {
+	_, err := NewFromConfig(&bytes.Buffer{}, config.AppConfig{LogLevel: "verbose"})
+	assert.Error(t, err)
+}
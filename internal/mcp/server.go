@@ -3,13 +3,18 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"helixops/internal/analyzer"
 	"helixops/internal/config"
 	"helixops/internal/models"
 	"helixops/internal/orchestrator"
+	"helixops/internal/remediation"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -20,6 +25,11 @@ type Server struct {
 	cfg          *config.Config
 	orchestrator *orchestrator.Orchestrator
 	analyzer     *analyzer.Analyzer
+	rules        *remediation.Engine
+
+	// timeout bounds each tool call; slots bounds how many run at once
+	timeout time.Duration
+	slots   chan struct{}
 }
 
 // New creates a new MCP server wrapper
@@ -28,9 +38,58 @@ func New(cfg *config.Config, orch *orchestrator.Orchestrator, anlz *analyzer.Ana
 		cfg:          cfg,
 		orchestrator: orch,
 		analyzer:     anlz,
+		rules:        remediation.NewEngineFromConfig(cfg.Services, cfg.Analysis.GetPlatform()),
+		timeout:      cfg.MCP.GetToolTimeoutDuration(),
+		slots:        make(chan struct{}, cfg.MCP.GetMaxConcurrentTools()),
 	}
 }
 
+// guard wraps a tool handler with the configured per-call timeout and concurrency limit.
+// The deadline covers waiting for a slot; when it passes, the agent gets a tool error
+// immediately even if a backend ignores cancellation.
+func (s *Server) guard(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, cancel := context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+		if ctx.Err() != nil {
+			return toolContextError(ctx, name, s.timeout), nil
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			return toolContextError(ctx, name, s.timeout), nil
+		}
+
+		type outcome struct {
+			result *mcp.CallToolResult
+			err    error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			defer func() { <-s.slots }()
+			result, err := handler(ctx, request)
+			done <- outcome{result, err}
+		}()
+
+		select {
+		case o := <-done:
+			return o.result, o.err
+		case <-ctx.Done():
+			return toolContextError(ctx, name, s.timeout), nil
+		}
+	}
+}
+
+// toolContextError explains why a tool call was abandoned.
+func toolContextError(ctx context.Context, name string, timeout time.Duration) *mcp.CallToolResult {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		slog.Warn("MCP tool timed out", "tool", name, "timeout", timeout)
+		return mcp.NewToolResultError(fmt.Sprintf("%s timed out after %s", name, timeout))
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("%s cancelled: %v", name, ctx.Err()))
+}
+
 // RegisterTools registers the HelixOps tools with the MCP server
 func (s *Server) RegisterTools(mcpServer *server.MCPServer) {
 	// 1. Analyze Alert Tool
@@ -40,28 +99,73 @@ func (s *Server) RegisterTools(mcpServer *server.MCPServer) {
 		mcp.WithString("alert_name", mcp.Required(), mcp.Description("Name of the alert rule firing")),
 		mcp.WithString("summary", mcp.Required(), mcp.Description("Alert summary text")),
 	)
-	mcpServer.AddTool(analyzeTool, s.HandleAnalyzeAlert)
+	mcpServer.AddTool(analyzeTool, s.guard(analyzeTool.Name, s.HandleAnalyzeAlert))
 
 	// 2. Get Service Metrics Tool
 	metricsTool := mcp.NewTool("get_service_metrics",
-		mcp.WithDescription("Fetches golden signals for a service."),
-		mcp.WithString("service_name", mcp.Required(), mcp.Description("Name of the service")),
+		mcp.WithDescription("Fetches golden signals for one or more services."),
+		mcp.WithAny("service_name", mcp.Required(), stringOrStringArray(), mcp.Description("Name of the service, or an array of service names")),
 	)
-	mcpServer.AddTool(metricsTool, s.HandleGetServiceMetrics)
+	mcpServer.AddTool(metricsTool, s.guard(metricsTool.Name, s.HandleGetServiceMetrics))
 
 	// 3. Search Logs Tool
 	logsTool := mcp.NewTool("search_logs",
-		mcp.WithDescription("Queries Loki for error patterns."),
-		mcp.WithString("service_name", mcp.Required(), mcp.Description("Name of the service")),
+		mcp.WithDescription("Queries Loki for error patterns in one or more services."),
+		mcp.WithAny("service_name", mcp.Required(), stringOrStringArray(), mcp.Description("Name of the service, or an array of service names")),
 	)
-	mcpServer.AddTool(logsTool, s.HandleSearchLogs)
+	mcpServer.AddTool(logsTool, s.guard(logsTool.Name, s.HandleSearchLogs))
 
 	// 4. Get Recent Commits Tool
 	commitsTool := mcp.NewTool("get_recent_commits",
 		mcp.WithDescription("Finds code changes near the incident start."),
 		mcp.WithString("repo_name", mcp.Required(), mcp.Description("Github Repository Name")),
 	)
-	mcpServer.AddTool(commitsTool, s.HandleGetRecentCommits)
+	mcpServer.AddTool(commitsTool, s.guard(commitsTool.Name, s.HandleGetRecentCommits))
+
+	// 5. Get Remediations Tool
+	remediationsTool := mcp.NewTool("get_remediations",
+		mcp.WithDescription("Returns rule-based remediation suggestions for an alert without running the LLM."),
+		mcp.WithString("alert_name", mcp.Required(), mcp.Description("Name of the alert rule firing")),
+		mcp.WithObject("labels", mcp.Description("Alert labels, e.g. service_name, namespace, deployment")),
+	)
+	mcpServer.AddTool(remediationsTool, s.guard(remediationsTool.Name, s.HandleGetRemediations))
+}
+
+// stringOrStringArray constrains a property to a string or an array of strings.
+func stringOrStringArray() mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["oneOf"] = []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 1},
+		}
+	}
+}
+
+// serviceNames reads service_name as either a single string or an array of strings.
+func serviceNames(args map[string]interface{}) ([]string, error) {
+	switch v := args["service_name"].(type) {
+	case string:
+		if v != "" {
+			return []string{v}, nil
+		}
+	case []interface{}:
+		names := make([]string, 0, len(v))
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok || name == "" {
+				return nil, fmt.Errorf("service_name entries must be non-empty strings")
+			}
+			names = append(names, name)
+		}
+		if len(names) > 0 {
+			return names, nil
+		}
+	case []string:
+		if len(v) > 0 {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("service_name must be a string or a non-empty array of strings")
 }
 
 // HandleAnalyzeAlert performs a full RCA via the Analyzer
@@ -116,19 +220,25 @@ func (s *Server) HandleGetServiceMetrics(ctx context.Context, request mcp.CallTo
 		return mcp.NewToolResultError("Invalid arguments"), nil
 	}
 
-	serviceName := args["service_name"].(string)
-	tEnd := time.Now()
-
-	// Since prometheus client isn't exported in Orchestrator, we prepare context then pluck metrics
-	ac, err := s.orchestrator.PrepareContext(ctx, serviceName, tEnd)
+	services, err := serviceNames(args)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	tEnd := time.Now()
 
-	report := fmt.Sprintf("Metrics for %s (Last 15m):\n- P99 Latency: %.2fms\n- Error Rate: %.2f%%\n- Requests/Sec: %.2f",
-		serviceName, ac.Metrics.LatencyP99, ac.Metrics.ErrorRate*100, ac.Metrics.RPS)
+	// Since prometheus client isn't exported in Orchestrator, we prepare context then pluck metrics
+	reports := make([]string, 0, len(services))
+	for _, serviceName := range services {
+		ac, err := s.orchestrator.PrepareContext(ctx, serviceName, tEnd)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
-	return mcp.NewToolResultText(report), nil
+		reports = append(reports, fmt.Sprintf("Metrics for %s (Last 15m):\n- P99 Latency: %.2fms\n- Error Rate: %.2f%%\n- Requests/Sec: %.2f",
+			serviceName, ac.Metrics.LatencyP99, ac.Metrics.ErrorRate*100, ac.Metrics.RPS))
+	}
+
+	return mcp.NewToolResultText(strings.Join(reports, "\n\n")), nil
 }
 
 func (s *Server) HandleSearchLogs(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -137,9 +247,15 @@ func (s *Server) HandleSearchLogs(ctx context.Context, request mcp.CallToolReque
 		return mcp.NewToolResultError("Invalid arguments"), nil
 	}
 
-	serviceName := args["service_name"].(string)
-	report := fmt.Sprintf("[MCP Stub] Fetched simulated error logs for %s from Loki.", serviceName)
-	return mcp.NewToolResultText(report), nil
+	services, err := serviceNames(args)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	reports := make([]string, 0, len(services))
+	for _, serviceName := range services {
+		reports = append(reports, fmt.Sprintf("[MCP Stub] Fetched simulated error logs for %s from Loki.", serviceName))
+	}
+	return mcp.NewToolResultText(strings.Join(reports, "\n")), nil
 }
 
 func (s *Server) HandleGetRecentCommits(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -164,8 +280,39 @@ func (s *Server) HandleGetRecentCommits(ctx context.Context, request mcp.CallToo
 		if i >= 5 {
 			break
 		}
-		report += fmt.Sprintf("- %s: %s (%s)\n", c.SHA[:7], c.Message, c.Author)
+		report += fmt.Sprintf("- %s: %s (%s)\n", models.ShortSHA(c.SHA), c.Message, c.Author)
 	}
 
 	return mcp.NewToolResultText(report), nil
 }
+
+// HandleGetRemediations returns the remediation engine's suggestions for an alert as JSON.
+func (s *Server) HandleGetRemediations(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args, ok := request.Params.Arguments.(map[string]interface{})
+	if !ok {
+		return mcp.NewToolResultError("Invalid arguments"), nil
+	}
+
+	alertName, _ := args["alert_name"].(string)
+	if alertName == "" {
+		return mcp.NewToolResultError("alert_name is required"), nil
+	}
+	labels := map[string]string{}
+	if raw, ok := args["labels"].(map[string]interface{}); ok {
+		for k, v := range raw {
+			if str, ok := v.(string); ok {
+				labels[k] = str
+			}
+		}
+	}
+
+	suggestions := s.rules.GetSuggestions(models.AlertInfo{Name: alertName, Labels: labels})
+	if suggestions == nil {
+		suggestions = []remediation.Suggestion{}
+	}
+	data, err := json.Marshal(suggestions)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode remediations: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helixops/internal/analyzer"
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/orchestrator"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolRequest(args map[string]any) mcp.CallToolRequest {
+	var req mcp.CallToolRequest
+	req.Params.Arguments = args
+	return req
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	require.Len(t, result.Content, 1)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text
+}
+
+func TestToolTimesOutOnSlowOrchestrator(t *testing.T) {
+	cfg := &config.Config{MCP: config.MCPConfig{ToolTimeout: "50ms"}}
+	orch := orchestrator.New(nil, nil, nil, nil, cfg)
+	orch.Register(orchestrator.NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	// The analyzer ignores cancellation entirely, so only the guard can end the call
+	provider := llm.NewFakeProvider()
+	provider.AnalyzeFunc = func(ctx context.Context, prompt string) (string, error) {
+		time.Sleep(time.Hour)
+		return "", nil
+	}
+	s := New(cfg, orch, analyzer.New(provider, cfg.Analysis))
+
+	handler := s.guard("analyze_alert", s.HandleAnalyzeAlert)
+	start := time.Now()
+	result, err := handler(context.Background(), toolRequest(map[string]any{
+		"service_name": "checkout", "alert_name": "HighLatency", "summary": "p99 above 1s",
+	}))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "analyze_alert timed out after 50ms", resultText(t, result))
+}
+
+func TestToolConcurrencyIsBounded(t *testing.T) {
+	cfg := &config.Config{MCP: config.MCPConfig{ToolTimeout: "5s", MaxConcurrentTools: 2}}
+	s := New(cfg, nil, nil)
+
+	var running, peak atomic.Int32
+	handler := s.guard("slow", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	done := make(chan *mcp.CallToolResult)
+	for i := 0; i < 6; i++ {
+		go func() {
+			result, _ := handler(context.Background(), toolRequest(nil))
+			done <- result
+		}()
+	}
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, "ok", resultText(t, <-done))
+	}
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
+
+func TestToolReportsCallerCancellation(t *testing.T) {
+	cfg := &config.Config{}
+	s := New(cfg, nil, nil)
+	handler := s.guard("search_logs", func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := handler(ctx, toolRequest(nil))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(t, result), "search_logs cancelled")
+}
+
+func TestServiceMetricsAcceptsOneOrManyServices(t *testing.T) {
+	cfg := &config.Config{}
+	orch := orchestrator.New(nil, nil, nil, nil, cfg)
+	latency := map[string]float64{"checkout": 1250, "payments": 300}
+	orch.Register(orchestrator.NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		return func(ac *models.AnalysisContext) { ac.Metrics.LatencyP99 = latency[service] }, nil
+	}))
+	s := New(cfg, orch, analyzer.New(llm.NewFakeProvider(), cfg.Analysis))
+
+	result, err := s.HandleGetServiceMetrics(context.Background(), toolRequest(map[string]any{"service_name": "checkout"}))
+	require.NoError(t, err)
+	text := resultText(t, result)
+	assert.Contains(t, text, "Metrics for checkout (Last 15m):\n- P99 Latency: 1250.00ms")
+	assert.NotContains(t, text, "payments")
+
+	result, err = s.HandleGetServiceMetrics(context.Background(), toolRequest(map[string]any{"service_name": []any{"checkout", "payments"}}))
+	require.NoError(t, err)
+	text = resultText(t, result)
+	assert.Contains(t, text, "Metrics for checkout (Last 15m):\n- P99 Latency: 1250.00ms")
+	assert.Contains(t, text, "Metrics for payments (Last 15m):\n- P99 Latency: 300.00ms")
+
+	result, err = s.HandleSearchLogs(context.Background(), toolRequest(map[string]any{"service_name": []any{"checkout", "payments"}}))
+	require.NoError(t, err)
+	assert.Equal(t, "[MCP Stub] Fetched simulated error logs for checkout from Loki.\n[MCP Stub] Fetched simulated error logs for payments from Loki.", resultText(t, result))
+
+	for _, bad := range []any{[]any{}, []any{"checkout", 7}, 42} {
+		result, err = s.HandleGetServiceMetrics(context.Background(), toolRequest(map[string]any{"service_name": bad}))
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "%v", bad)
+	}
+}
+
+func TestGetRemediationsReturnsRuleSuggestions(t *testing.T) {
+	s := New(&config.Config{}, nil, nil)
+
+	result, err := s.HandleGetRemediations(context.Background(), toolRequest(map[string]any{
+		"alert_name": "HighLatency",
+		"labels":     map[string]any{"service_name": "checkout", "namespace": "shop", "deployment": "checkout-api"},
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var suggestions []remediation.Suggestion
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &suggestions))
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "Check Database Query Performance", suggestions[0].Title)
+	assert.Equal(t, "kubectl -n shop scale deployment/checkout-api --replicas=3", suggestions[1].Action)
+
+	result, err = s.HandleGetRemediations(context.Background(), toolRequest(map[string]any{}))
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
@@ -1,7 +1,12 @@
 // Package models defines the shared core data structures used throughout the HelixOps agent.
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+)
 
 // AlertManagerPayload represents the Prometheus AlertManager webhook payload
 type AlertManagerPayload struct {
@@ -16,6 +21,24 @@ type AlertManagerPayload struct {
 	Alerts            []AlertItem       `json:"alerts"`
 }
 
+// ApplyCommonAnnotations merges the group-level annotations into every alert so a shared summary
+// or description reaches analysis when per-alert annotations are sparse. Per-alert values win.
+func (p *AlertManagerPayload) ApplyCommonAnnotations() {
+	if len(p.CommonAnnotations) == 0 {
+		return
+	}
+	for i := range p.Alerts {
+		merged := make(map[string]string, len(p.CommonAnnotations)+len(p.Alerts[i].Annotations))
+		for k, v := range p.CommonAnnotations {
+			merged[k] = v
+		}
+		for k, v := range p.Alerts[i].Annotations {
+			merged[k] = v
+		}
+		p.Alerts[i].Annotations = merged
+	}
+}
+
 // AlertItem represents a single alert from AlertManager
 type AlertItem struct {
 	Status       string            `json:"status"`
@@ -47,3 +70,39 @@ func (a *AlertItem) GetAnnotation(key string) string {
 	}
 	return a.Annotations[key]
 }
+
+// GetFingerprint returns the Alertmanager fingerprint, deriving a stable one from the labels when absent
+func (a *AlertItem) GetFingerprint() string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+
+	keys := make([]string, 0, len(a.Labels))
+	for k := range a.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(a.Labels[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ToAlertInfo converts the raw webhook alert into the simplified form used for analysis
+func (a *AlertItem) ToAlertInfo() AlertInfo {
+	return AlertInfo{
+		Name:        a.GetLabel("alertname"),
+		Severity:    a.GetLabel("severity"),
+		Summary:     a.GetAnnotation("summary"),
+		Labels:      a.Labels,
+		Annotations: a.Annotations,
+		Fingerprint: a.GetFingerprint(),
+		StartedAt:   a.StartsAt,
+		EndsAt:      a.EndsAt,
+	}
+}
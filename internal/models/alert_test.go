@@ -54,3 +54,16 @@ func TestAlertItemGetAnnotation(t *testing.T) {
 	assert.Equal(t, "", alert.GetAnnotation("nonexistent"))
 	assert.Equal(t, "", (&AlertItem{}).GetAnnotation("any"))
 }
+
+func TestAlertItemGetFingerprint(t *testing.T) {
+	withFP := AlertItem{Fingerprint: "abc", Labels: map[string]string{"alertname": "X"}}
+	assert.Equal(t, "abc", withFP.GetFingerprint())
+
+	a := AlertItem{Labels: map[string]string{"alertname": "HighLatency", "service": "cart"}}
+	b := AlertItem{Labels: map[string]string{"service": "cart", "alertname": "HighLatency"}}
+	c := AlertItem{Labels: map[string]string{"alertname": "HighLatency", "service": "checkout"}}
+
+	assert.Len(t, a.GetFingerprint(), 16)
+	assert.Equal(t, a.GetFingerprint(), b.GetFingerprint())
+	assert.NotEqual(t, a.GetFingerprint(), c.GetFingerprint())
+}
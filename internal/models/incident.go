@@ -1,8 +1,12 @@
 package models
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"helixops/internal/clients/prometheus"
 	"helixops/internal/clients/tempo"
 )
 
@@ -19,6 +23,56 @@ type AnalysisResult struct {
 	Metrics     MetricsSummary `json:"metrics"`
 	Commits     []CommitInfo    `json:"commits"`
 	AnalyzedAt  time.Time `json:"analyzed_at"`
+
+	// AssessedSeverity is the model's view of the actual impact (critical, warning, or info); empty if it gave none
+	AssessedSeverity string `json:"assessed_severity,omitempty"`
+
+	// BlastRadius estimates the impact beyond the alerting service; nil when no telemetry supported an estimate
+	BlastRadius *BlastRadius `json:"blast_radius,omitempty"`
+
+	// PriorIncidents counts earlier resolved incidents of the same alert and service; non-zero means a recurrence
+	PriorIncidents int `json:"prior_incidents,omitempty"`
+
+	// Recurrence is how many consecutive analyses of the service named this root cause; set only once
+	// output.recurring mutes the full notification
+	Recurrence int `json:"recurrence,omitempty"`
+
+	// Language the analysis prose was requested in (e.g. "English", "German")
+	Language string `json:"language,omitempty"`
+
+	// Dashboards are deep links to the service's dashboards over the incident window
+	Dashboards []DashboardLink `json:"dashboards,omitempty"`
+
+	// PromptChars and EstimatedPromptTokens size the prompt sent to the LLM; the token count is an
+	// approximation made before sending. Both are zero when the LLM was skipped
+	PromptChars           int `json:"prompt_chars,omitempty"`
+	EstimatedPromptTokens int `json:"estimated_prompt_tokens,omitempty"`
+
+	// Triage is the pre-LLM classification (auto_resolved, needs_llm, needs_human, no_anomaly); empty when triage is disabled
+	Triage       string `json:"triage,omitempty"`
+	TriageReason string `json:"triage_reason,omitempty"`
+
+	// LLMResponse is the raw reply the analysis was parsed from; it is persisted on its own and never
+	// sent with the result. Nil when triage skipped the LLM
+	LLMResponse *LLMResponse `json:"-"`
+}
+
+// LLMResponse is the redacted raw model output behind an analysis, kept to audit or reproduce what the model said
+type LLMResponse struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model,omitempty"`
+	Response         string `json:"response"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+	TotalTokens      int    `json:"total_tokens,omitempty"`
+}
+
+// BlastRadius estimates how far an incident reached beyond the alerting service
+type BlastRadius struct {
+	// Services are the upstream and downstream services seen in the incident's traces, sorted
+	Services []string `json:"services"`
+	// AffectedRPS is the request rate through the alerting service during the incident window
+	AffectedRPS float64 `json:"affected_rps"`
 }
 
 // MetricsSummary represents golden signals metrics
@@ -28,6 +82,8 @@ type MetricsSummary struct {
 	ErrorRate    float64 `json:"error_rate"`
 	RPS          float64 `json:"requests_per_second"`
 	MemoryUsage  float64 `json:"memory_usage"`
+	// StatusBreakdown is the request rate per HTTP status code, e.g. {"200": 41.2, "503": 2.1}
+	StatusBreakdown map[string]float64 `json:"status_breakdown,omitempty"`
 	
 	// Baseline values for comparison
 	BaselineLatency   float64 `json:"baseline_latency"`
@@ -35,6 +91,21 @@ type MetricsSummary struct {
 	BaselineRPS       float64 `json:"baseline_rps"`
 }
 
+// Anomaly reports whether current golden signals deviate materially from baseline, describing how.
+func (m MetricsSummary) Anomaly() (string, bool) {
+	var parts []string
+	if m.BaselineLatency > 0 && m.LatencyP99 >= 2*m.BaselineLatency {
+		parts = append(parts, fmt.Sprintf("latency p99 %.0fms is %.1fx baseline", m.LatencyP99, m.LatencyP99/m.BaselineLatency))
+	}
+	if m.ErrorRate > 0 && m.ErrorRate >= 2*m.BaselineErrorRate && m.ErrorRate >= 0.01 {
+		parts = append(parts, fmt.Sprintf("error rate %.2f%% (baseline %.2f%%)", m.ErrorRate*100, m.BaselineErrorRate*100))
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, ", "), true
+}
+
 // CommitInfo represents a GitHub commit
 type CommitInfo struct {
 	SHA       string    `json:"sha"`
@@ -46,6 +117,27 @@ type CommitInfo struct {
 	PRNumber  int       `json:"pr_number,omitempty"`
 }
 
+// shortSHALength is the abbreviated SHA length used in prompts and reports, as in git log --oneline.
+const shortSHALength = 7
+
+// ShortSHA abbreviates a commit SHA for display. SHAs that are already short, e.g. from mocked or
+// abbreviated API responses, are returned unchanged.
+func ShortSHA(sha string) string {
+	if len(sha) > shortSHALength {
+		return sha[:shortSHALength]
+	}
+	return sha
+}
+
+// CommitCursor is the last commit listing fetched for a repository, branch, and path, so later alerts
+// only fetch commits newer than HeadSHA and merge them with Commits
+type CommitCursor struct {
+	HeadSHA string       `json:"head_sha"`
+	HeadAt  time.Time    `json:"head_at"`
+	Since   time.Time    `json:"since"` // start of the window Commits is complete for
+	Commits []CommitInfo `json:"commits"` // newest first
+}
+
 // AnalysisContext holds all data needed for RCA
 type AnalysisContext struct {
 	ServiceName   string                 `json:"service_name"`
@@ -55,15 +147,151 @@ type AnalysisContext struct {
 	ErrorLogs     []LogEntry             `json:"error_logs,omitempty"`
 	Traces        tempo.TraceContext     `json:"traces,omitempty"`
 	TimeWindow    TimeWindow             `json:"time_window"`
+
+	// SuspectedCauses are ranked candidate causes correlated across signals before the LLM call
+	SuspectedCauses []Hypothesis `json:"suspected_causes,omitempty"`
+
+	// Sources names the collectors queried for this context, in registration order
+	Sources []string `json:"sources,omitempty"`
+	// SourceErrors maps a collector name to the error it reported; the context is partial when non-empty
+	SourceErrors map[string]string `json:"source_errors,omitempty"`
+	// Backpressure names the collectors whose backend was saturated, so their queries were not sent
+	Backpressure []string `json:"backpressure,omitempty"`
+
+	// MetricsTargets is the service's scrape target health; nil when it was not checked
+	MetricsTargets *prometheus.TargetHealth `json:"metrics_targets,omitempty"`
+
+	// MetricsLastSample is when the newest raw sample behind the golden signals was scraped; zero when unknown
+	MetricsLastSample time.Time `json:"metrics_last_sample"`
+	// MetricsStale is set when MetricsLastSample is older than prometheus.staleness_threshold
+	MetricsStale bool `json:"metrics_stale,omitempty"`
+
+	// BlastRadius is estimated from trace peers and metrics once collection finishes
+	BlastRadius *BlastRadius `json:"blast_radius,omitempty"`
+
+	// PriorIncidents are earlier resolved incidents of the same alert on the same service, most recent first
+	PriorIncidents []PriorIncident `json:"prior_incidents,omitempty"`
+
+	// RelatedAlerts are the other alerts merged into this incident by alert aggregation or POST /incidents/merge
+	RelatedAlerts []RelatedAlert `json:"related_alerts,omitempty"`
+
+	// FileChanges are the last commits touching source files named in error log stack traces
+	FileChanges []FileChange `json:"file_changes,omitempty"`
+
+	// DeployEvents are deploys of the service reported by a CD system before the alert, oldest first
+	DeployEvents []DeployEvent `json:"deploy_events,omitempty"`
+
+	// Dashboards are the configured dashboard links rendered for the service and incident window
+	Dashboards []DashboardLink `json:"dashboards,omitempty"`
+
+	// Recovery compares the golden signals at resolution with those before the incident; nil until resolved
+	Recovery *Recovery `json:"recovery,omitempty"`
+}
+
+// Recovery statuses
+const (
+	RecoveryRecovered = "recovered"     // latency and error rate are back at baseline
+	RecoveryElevated  = "not_recovered" // the alert resolved but the signals are still elevated or traffic is gone
+	RecoveryUnknown   = "unknown"       // no baseline traffic to compare against
+)
+
+// Recovery records whether a resolved incident's service actually returned to baseline
+type Recovery struct {
+	Baseline     prometheus.Signals `json:"baseline"` // one metrics window before the alert started
+	AtStart      prometheus.Signals `json:"at_start"`
+	AtResolution prometheus.Signals `json:"at_resolution"`
+	Status       string             `json:"status"`
+	Note         string             `json:"note"`
+}
+
+// Recovered reports whether the signals were verified to be back at baseline
+func (r *Recovery) Recovered() bool {
+	return r != nil && r.Status == RecoveryRecovered
+}
+
+// DashboardLink is a named URL into a dashboard, scoped to a service and time range
+type DashboardLink struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// FileChange is the most recent commit touching a source file that appeared in a stack trace
+type FileChange struct {
+	Path   string     `json:"path"` // repository-relative
+	Line   int        `json:"line,omitempty"`
+	Commit CommitInfo `json:"commit"`
+}
+
+// DeployEvent is a deploy of a service reported by a CD system through POST /events/deploy
+type DeployEvent struct {
+	Service string    `json:"service"`
+	Version string    `json:"version,omitempty"`
+	SHA     string    `json:"sha,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Label names the deployed version, falling back to the abbreviated SHA, e.g. "v1.2.3 (abc1234)"
+func (d DeployEvent) Label() string {
+	switch {
+	case d.Version != "" && d.SHA != "":
+		return fmt.Sprintf("%s (%s)", d.Version, ShortSHA(d.SHA))
+	case d.Version != "":
+		return d.Version
+	case d.SHA != "":
+		return ShortSHA(d.SHA)
+	}
+	return "unversioned build"
+}
+
+// RelatedAlert is an alert on another service grouped into the same incident
+type RelatedAlert struct {
+	ServiceName string    `json:"service_name"`
+	AlertName   string    `json:"alert_name"`
+	StartedAt   time.Time `json:"started_at"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+}
+
+// AffectedServices lists the incident's service followed by the distinct services of its related alerts.
+func (ac *AnalysisContext) AffectedServices() []string {
+	services := []string{ac.ServiceName}
+	for _, r := range ac.RelatedAlerts {
+		if !slices.Contains(services, r.ServiceName) {
+			services = append(services, r.ServiceName)
+		}
+	}
+	return services
+}
+
+// PriorIncident is a resolved incident used as history for a recurring alert
+type PriorIncident struct {
+	ID         string    `json:"id"`
+	StartedAt  time.Time `json:"started_at"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	RootCause  string    `json:"root_cause"`
+}
+
+// MetricsDegraded reports whether a scrape target was down, so absent metrics are not evidence of health.
+func (ac *AnalysisContext) MetricsDegraded() bool {
+	return ac.MetricsTargets != nil && ac.MetricsTargets.Degraded()
+}
+
+// Hypothesis is a candidate root cause backed by pointers to the signals that support it
+type Hypothesis struct {
+	Cause    string   `json:"cause"`
+	Score    float64  `json:"score"`
+	Evidence []string `json:"evidence"`
 }
 
 // AlertInfo represents simplified alert data for analysis
 type AlertInfo struct {
-	Name      string            `json:"name"`
-	Severity  string            `json:"severity"`
-	Summary   string            `json:"summary"`
-	Labels    map[string]string `json:"labels"`
-	StartedAt time.Time         `json:"started_at"`
+	Name        string            `json:"name"`
+	Severity    string            `json:"severity"`
+	Summary     string            `json:"summary"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Fingerprint string            `json:"fingerprint,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	EndsAt      time.Time         `json:"ends_at"`
 }
 
 // TimeWindow represents the time range for queries
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortSHA(t *testing.T) {
+	assert.Equal(t, "abc1234", ShortSHA("abc1234def567890"))
+	assert.Equal(t, "abc1234", ShortSHA("abc1234"))
+	assert.Equal(t, "ab12", ShortSHA("ab12"))
+	assert.Equal(t, "", ShortSHA(""))
+}
@@ -0,0 +1,138 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"helixops/internal/models"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// maxBlamedFiles bounds the GitHub lookups made per alert for stack trace files.
+const maxBlamedFiles = 3
+
+// blameTimeout bounds the stack trace file lookups when analysis.enrichment_budget does not.
+const blameTimeout = 10 * time.Second
+
+// stackFrameRe matches source locations in stack traces: Python's `File "x.py", line 42` and the
+// path:line form used by Go, Node, Ruby, and most others.
+var stackFrameRe = regexp.MustCompile(`File "([^"]+)", line (\d+)|((?:[\w.@+-]+/)+[\w.-]+\.(?:go|py|js|mjs|cjs|ts|tsx|jsx|java|kt|scala|rb|rs|php|cs|ex|exs|c|cc|cpp|h|swift)):(\d+)`)
+
+// thirdPartyDirs mark dependency and runtime paths that are not in the service's repository.
+var thirdPartyDirs = []string{"vendor", "node_modules", "site-packages", "dist-packages", "pkg/mod", "usr/local", "usr/lib"}
+
+// sourceLocation is a file and line named in a stack trace.
+type sourceLocation struct {
+	path string
+	line int
+}
+
+// stackTraceFiles returns the distinct first-party source files named in error log stack traces, in
+// order of appearance, up to maxBlamedFiles. Bare file names are skipped; without a directory they
+// cannot be located in the repository.
+func stackTraceFiles(logs []models.LogEntry) []sourceLocation {
+	var (
+		files []sourceLocation
+		seen  = make(map[string]bool)
+	)
+	for _, entry := range logs {
+		for _, text := range []string{entry.StackTrace, entry.Error, entry.Message} {
+			for _, m := range stackFrameRe.FindAllStringSubmatch(text, -1) {
+				file, line := m[1], m[2]
+				if file == "" {
+					file, line = m[3], m[4]
+				}
+				file = strings.TrimLeft(path.Clean(file), "./")
+				if !strings.Contains(file, "/") || seen[file] || thirdParty(file) {
+					continue
+				}
+				seen[file] = true
+				n, _ := strconv.Atoi(line)
+				files = append(files, sourceLocation{path: file, line: n})
+				if len(files) == maxBlamedFiles {
+					return files
+				}
+			}
+		}
+	}
+	return files
+}
+
+// thirdParty reports whether p is inside a dependency or runtime directory.
+func thirdParty(p string) bool {
+	for _, dir := range thirdPartyDirs {
+		if strings.HasPrefix(p, dir+"/") || strings.Contains(p, "/"+dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// repoPaths guesses where a stack trace path lives in repo. Paths are usually prefixed by the build
+// or container directory, so the part after the repository's name is tried first, then the path
+// as-is, then without its first directory (e.g. /app/internal/db.go → internal/db.go).
+func repoPaths(p, repo string) []string {
+	name := repo[strings.LastIndex(repo, "/")+1:]
+	if i := strings.LastIndex(p, "/"+name+"/"); i >= 0 {
+		return []string{p[i+len(name)+2:]}
+	}
+	if strings.HasPrefix(p, name+"/") {
+		return []string{p[len(name)+1:]}
+	}
+	paths := []string{p}
+	if i := strings.Index(p, "/"); i >= 0 && strings.Contains(p[i+1:], "/") {
+		paths = append(paths, p[i+1:])
+	}
+	return paths
+}
+
+// attachFileChanges adds the last commit touching each source file named in the error logs' stack
+// traces to ac. The files are looked up concurrently within blameTimeout; a failed lookup cancels the
+// others and is recorded in SourceErrors under "blame", keeping the commits already found.
+func (o *Orchestrator) attachFileChanges(ctx context.Context, ac *models.AnalysisContext) {
+	files := stackTraceFiles(ac.ErrorLogs)
+	if len(files) == 0 {
+		return
+	}
+	svc := o.resolveService(ctx, ac.ServiceName)
+
+	ctx, cancel := context.WithTimeout(ctx, blameTimeout)
+	defer cancel()
+	changes := make([]*models.FileChange, len(files))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, f := range files {
+		g.Go(func() error {
+			// Candidates are tried in order of likelihood, so they are not raced
+			for _, candidate := range repoPaths(f.path, svc.Repo) {
+				commit, err := o.githubClient.GetFileLastCommit(gctx, svc.Repo, candidate, svc.Branch)
+				if err != nil {
+					return fmt.Errorf("%s: %w", candidate, err)
+				}
+				if commit != nil {
+					changes[i] = &models.FileChange{Path: candidate, Line: f.line, Commit: commitInfo(*commit)}
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		slog.Warn("Error fetching data", "service", ac.ServiceName, "source", "blame", "error", err)
+		if ac.SourceErrors == nil {
+			ac.SourceErrors = make(map[string]string)
+		}
+		ac.SourceErrors["blame"] = err.Error()
+	}
+	for _, change := range changes {
+		if change != nil {
+			ac.FileChanges = append(ac.FileChanges, *change)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package orchestrator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/github"
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackTraceFiles(t *testing.T) {
+	logs := []models.LogEntry{
+		{Message: "panic: nil map", StackTrace: "goroutine 1 [running]:\nmain.handle()\n\t/app/internal/db/pool.go:42 +0x1d\n\t/usr/local/go/src/net/http/server.go:2136 +0x29\n\t/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go:12"},
+		{Error: `Traceback (most recent call last):\n  File "/srv/app/views.py", line 17, in checkout`},
+		{Message: "at handler (/app/src/db.js:8:13) at Pool.get(Pool.java:3)"},
+		{StackTrace: "\t/app/internal/db/pool.go:42 +0x1d"},
+	}
+
+	assert.Equal(t, []sourceLocation{
+		{path: "app/internal/db/pool.go", line: 42},
+		{path: "srv/app/views.py", line: 17},
+		{path: "app/src/db.js", line: 8},
+	}, stackTraceFiles(logs))
+}
+
+func TestRepoPaths(t *testing.T) {
+	assert.Equal(t, []string{"internal/db/pool.go"}, repoPaths("go/src/github.com/acme/checkout/internal/db/pool.go", "acme/checkout"))
+	assert.Equal(t, []string{"app/internal/db/pool.go", "internal/db/pool.go"}, repoPaths("app/internal/db/pool.go", "acme/checkout"))
+	assert.Equal(t, []string{"app/main.go"}, repoPaths("app/main.go", "acme/checkout"))
+}
+
+func TestPrepareContextAttachesLastChangeToStackTraceFiles(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/acme/checkout/commits", r.URL.Path)
+		q := r.URL.Query()
+		if q.Get("since") != "" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		assert.Equal(t, "1", q.Get("per_page"))
+		assert.Equal(t, "release", q.Get("sha"))
+		requested = append(requested, q.Get("path"))
+		if q.Get("path") != "internal/db/pool.go" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"sha": "abc1234def", "html_url": "https://github.com/acme/checkout/commit/abc1234def",
+			"commit": {"message": "Shrink pool", "author": {"name": "dev", "email": "dev@example.com", "date": "2024-01-01T11:00:00Z"}}}]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GitHub:   config.GitHubConfig{DefaultOrg: "acme", BlameFiles: true},
+		Services: map[string]config.ServiceConfig{"checkout": {Branch: "release"}},
+	}
+	o := New(nil, github.NewClient(server.URL, "token"), nil, nil, cfg)
+	o.Register(NewCollector("logs", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		return func(ac *models.AnalysisContext) {
+			ac.ErrorLogs = []models.LogEntry{{Level: "error", StackTrace: "main.handle()\n\t/app/internal/db/pool.go:42 +0x1d"}}
+		}, nil
+	}))
+
+	ac, err := o.PrepareContext(context.Background(), "checkout", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app/internal/db/pool.go", "internal/db/pool.go"}, requested)
+	require.Len(t, ac.FileChanges, 1)
+	assert.Equal(t, "internal/db/pool.go", ac.FileChanges[0].Path)
+	assert.Equal(t, 42, ac.FileChanges[0].Line)
+	assert.Equal(t, "abc1234def", ac.FileChanges[0].Commit.SHA)
+	assert.Equal(t, "dev", ac.FileChanges[0].Commit.Author)
+
+	cfg.GitHub.BlameFiles = false
+	requested = nil
+	ac, err = o.PrepareContext(context.Background(), "checkout", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, requested)
+	assert.Empty(t, ac.FileChanges)
+}
+
+func TestAttachFileChangesKeepsStackTraceOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Query().Get("path")
+		if path == "internal/a.go" {
+			time.Sleep(20 * time.Millisecond) // answers last
+		}
+		w.Write([]byte(`[{"sha": "` + path + `", "commit": {"author": {"date": "2024-01-01T11:00:00Z"}}}]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{GitHub: config.GitHubConfig{DefaultOrg: "acme"}}
+	o := New(nil, github.NewClient(server.URL, "token"), nil, nil, cfg)
+	ac := &models.AnalysisContext{
+		ServiceName: "checkout",
+		ErrorLogs:   []models.LogEntry{{StackTrace: "internal/a.go:1\ninternal/b.go:2\ninternal/c.go:3"}},
+	}
+	o.attachFileChanges(context.Background(), ac)
+
+	require.Len(t, ac.FileChanges, 3)
+	for i, want := range []string{"internal/a.go", "internal/b.go", "internal/c.go"} {
+		assert.Equal(t, want, ac.FileChanges[i].Path)
+		assert.Equal(t, want, ac.FileChanges[i].Commit.SHA)
+	}
+	assert.Empty(t, ac.SourceErrors)
+}
@@ -0,0 +1,40 @@
+package orchestrator
+
+import (
+	"sort"
+
+	"helixops/internal/clients/tempo"
+	"helixops/internal/models"
+)
+
+// EstimateBlastRadius infers which other services an incident touched from the fetched spans:
+// services that own spans in the incident's traces, plus the peer.service each span called.
+// The affected request rate is the alerting service's current RPS. It returns nil when neither
+// traces nor metrics support an estimate.
+func EstimateBlastRadius(ac *models.AnalysisContext) *models.BlastRadius {
+	seen := make(map[string]bool)
+	for _, spans := range [][]tempo.Span{ac.Traces.SlowSpans, ac.Traces.ErrorSpans} {
+		for _, s := range spans {
+			for _, name := range []string{s.ServiceName, s.PeerService} {
+				if name != "" && name != ac.ServiceName {
+					seen[name] = true
+				}
+			}
+		}
+	}
+
+	if len(seen) == 0 && ac.Metrics.RPS == 0 {
+		return nil
+	}
+
+	services := make([]string, 0, len(seen))
+	for name := range seen {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+
+	return &models.BlastRadius{
+		Services:    services,
+		AffectedRPS: ac.Metrics.RPS,
+	}
+}
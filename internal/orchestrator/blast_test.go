@@ -0,0 +1,38 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"helixops/internal/clients/tempo"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateBlastRadiusFromSpanPeers(t *testing.T) {
+	ac := &models.AnalysisContext{
+		ServiceName: "checkout",
+		Metrics:     models.MetricsSummary{RPS: 42},
+		Traces: tempo.TraceContext{
+			SlowSpans: []tempo.Span{
+				{ServiceName: "checkout", OperationName: "SELECT orders", PeerService: "postgres"},
+				{ServiceName: "checkout", OperationName: "POST /charge", PeerService: "payments"},
+				{ServiceName: "frontend", OperationName: "GET /cart", PeerService: "checkout"},
+			},
+			ErrorSpans: []tempo.Span{
+				{ServiceName: "checkout", OperationName: "POST /charge", PeerService: "payments"},
+				{ServiceName: "checkout", OperationName: "render"},
+			},
+		},
+	}
+
+	br := EstimateBlastRadius(ac)
+	require.NotNil(t, br)
+	assert.Equal(t, []string{"frontend", "payments", "postgres"}, br.Services)
+	assert.Equal(t, 42.0, br.AffectedRPS)
+}
+
+func TestEstimateBlastRadiusWithoutTelemetry(t *testing.T) {
+	assert.Nil(t, EstimateBlastRadius(&models.AnalysisContext{ServiceName: "checkout"}))
+}
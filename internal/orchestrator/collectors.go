@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"helixops/internal/models"
+)
+
+// Collector gathers one source of telemetry for an incident. Collect returns a function that
+// merges its data into the analysis context; it may return partial data together with an error,
+// in which case the data is still applied and the error is recorded under the collector's name.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context, service string, window models.TimeWindow) (func(*models.AnalysisContext), error)
+}
+
+// CollectorFunc adapts a plain function into a named Collector.
+type CollectorFunc struct {
+	name string
+	fn   func(ctx context.Context, service string, window models.TimeWindow) (func(*models.AnalysisContext), error)
+}
+
+// NewCollector wraps fn as a Collector registered under name.
+func NewCollector(name string, fn func(ctx context.Context, service string, window models.TimeWindow) (func(*models.AnalysisContext), error)) Collector {
+	return CollectorFunc{name: name, fn: fn}
+}
+
+// Name identifies the collector in logs and SourceErrors.
+func (c CollectorFunc) Name() string {
+	return c.name
+}
+
+// Collect invokes the wrapped function.
+func (c CollectorFunc) Collect(ctx context.Context, service string, window models.TimeWindow) (func(*models.AnalysisContext), error) {
+	return c.fn(ctx, service, window)
+}
+
+// metricsCollector reads golden signals from Prometheus over the metrics window, together with
+// the health of the service's scrape targets so empty results from a down target are flagged,
+// and the age of the newest sample so numbers left over from a scrape outage are flagged as stale.
+func (o *Orchestrator) metricsCollector() Collector {
+	return NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		targets, targetErr := o.fetchTargetHealth(ctx, service)
+		lastSample, sampleErr := o.fetchLastSample(ctx, service)
+		metrics, err := o.fetchMetrics(ctx, service, w.Start, w.End)
+		return func(ac *models.AnalysisContext) {
+			ac.MetricsTargets = targets
+			ac.MetricsLastSample = lastSample
+			ac.MetricsStale = !lastSample.IsZero() && time.Since(lastSample) > o.cfg.Prometheus.GetStalenessThresholdDuration()
+			if metrics.LatencyP99 > 0 || metrics.ErrorRate > 0 {
+				ac.Metrics = metrics
+			}
+		}, errors.Join(targetErr, sampleErr, err)
+	})
+}
+
+// commitsCollector reads recent commits from GitHub over the commits lookback.
+func (o *Orchestrator) commitsCollector() Collector {
+	return NewCollector("commits", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		commits, err := o.fetchCommits(ctx, service, w.End.Add(-o.cfg.Analysis.GetCommitsLookbackDuration()))
+		return func(ac *models.AnalysisContext) {
+			if len(commits) > 0 {
+				ac.RecentCommits = commits
+			}
+		}, err
+	})
+}
+
+// tracesCollector reads slow spans from Tempo over the metrics window.
+func (o *Orchestrator) tracesCollector() Collector {
+	return NewCollector("traces", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		traces, err := o.fetchTraces(ctx, service, w.Start, w.End)
+		return func(ac *models.AnalysisContext) {
+			if traces.TraceCount > 0 {
+				ac.Traces = traces
+			}
+		}, err
+	})
+}
+
+// logsCollector reads error logs from Loki over the logs lookback.
+func (o *Orchestrator) logsCollector() Collector {
+	return NewCollector("logs", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		logs, err := o.fetchLogs(ctx, service, w.End.Add(-o.cfg.Analysis.GetLogsLookbackDuration()), w.End)
+		return func(ac *models.AnalysisContext) {
+			if len(logs) > 0 {
+				ac.ErrorLogs = logs
+			}
+		}, err
+	})
+}
+
+// deploysCollector reads recorded deploy events over the commits lookback, which bounds how far back
+// a code change is considered a suspect.
+func (o *Orchestrator) deploysCollector(h DeployHistory) Collector {
+	return NewCollector("deploys", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		events, err := h.DeployEvents(service, w.End.Add(-o.cfg.Analysis.GetCommitsLookbackDuration()), w.End)
+		return func(ac *models.AnalysisContext) {
+			if len(events) > 0 {
+				ac.DeployEvents = events
+			}
+		}, err
+	})
+}
+
+// windowFor returns the metrics window ending at the alert time.
+func (o *Orchestrator) windowFor(alertTime time.Time) models.TimeWindow {
+	metricsWindow := o.cfg.Analysis.GetMetricsWindowDuration()
+	return models.TimeWindow{
+		Start:    alertTime.Add(-metricsWindow),
+		End:      alertTime,
+		Duration: metricsWindow.String(),
+	}
+}
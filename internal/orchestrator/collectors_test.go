@@ -0,0 +1,306 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/loki"
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/clients/tempo"
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareContextRecordsCollectorFailures(t *testing.T) {
+	cfg := &config.Config{}
+	o := New(nil, nil, nil, nil, cfg)
+	require.Empty(t, o.collectors, "no collectors without configured clients")
+
+	alertTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	o.Register(NewCollector("commits", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		assert.Equal(t, "checkout", service)
+		assert.Equal(t, alertTime, w.End)
+		assert.Equal(t, alertTime.Add(-15*time.Minute), w.Start)
+		return func(ac *models.AnalysisContext) {
+			ac.RecentCommits = []models.CommitInfo{{SHA: "abc1234"}}
+		}, nil
+	}))
+	o.Register(NewCollector("k8s", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		return nil, errors.New("forbidden")
+	}))
+
+	ac, err := o.PrepareContext(context.Background(), "checkout", alertTime)
+	require.NoError(t, err)
+	assert.Equal(t, "checkout", ac.ServiceName)
+	assert.Equal(t, []models.CommitInfo{{SHA: "abc1234"}}, ac.RecentCommits)
+	assert.Equal(t, map[string]string{"k8s": "forbidden"}, ac.SourceErrors)
+}
+
+func TestPrepareContextBoundsConcurrency(t *testing.T) {
+	cfg := &config.Config{Analysis: config.AnalysisConfig{MaxConcurrency: 2}}
+	o := New(nil, nil, nil, nil, cfg)
+
+	var running, peak atomic.Int32
+	for i := 0; i < 6; i++ {
+		o.Register(NewCollector("slow", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			return nil, nil
+		}))
+	}
+
+	ac, err := o.PrepareContext(context.Background(), "checkout", time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, ac.SourceErrors)
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+}
+
+func TestPrepareContextDoesNotWaitForSlowSourcePastDeadline(t *testing.T) {
+	cfg := &config.Config{Analysis: config.AnalysisConfig{ContextDeadline: "50ms"}}
+	o := New(nil, nil, nil, nil, cfg)
+
+	o.Register(NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		time.Sleep(100 * time.Millisecond) // required: waited for even past the deadline
+		return func(ac *models.AnalysisContext) { ac.Metrics.LatencyP99 = 1250 }, nil
+	}))
+	o.Register(NewCollector("commits", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		return func(ac *models.AnalysisContext) { ac.RecentCommits = []models.CommitInfo{{SHA: "abc1234"}} }, nil
+	}))
+	traceCancelled := make(chan struct{})
+	o.Register(NewCollector("traces", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		select {
+		case <-ctx.Done():
+			close(traceCancelled)
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Second):
+			return func(ac *models.AnalysisContext) { ac.Traces.TraceCount = 1 }, nil
+		}
+	}))
+
+	start := time.Now()
+	ac, err := o.PrepareContext(context.Background(), "checkout", start)
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond, "required metrics are awaited")
+	assert.Less(t, elapsed, 2*time.Second, "slow traces do not block analysis")
+	assert.Equal(t, 1250.0, ac.Metrics.LatencyP99)
+	assert.Equal(t, []models.CommitInfo{{SHA: "abc1234"}}, ac.RecentCommits)
+	assert.Zero(t, ac.Traces.TraceCount)
+	assert.Contains(t, ac.SourceErrors["traces"], "context_deadline")
+
+	select {
+	case <-traceCancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("straggling collector was not cancelled")
+	}
+}
+
+func TestPrepareContextReturnsWithinEnrichmentBudget(t *testing.T) {
+	cfg := &config.Config{Analysis: config.AnalysisConfig{EnrichmentBudget: "100ms"}}
+	o := New(nil, nil, nil, nil, cfg)
+
+	var budgetLeft time.Duration
+	o.Register(NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		deadline, _ := ctx.Deadline()
+		budgetLeft = time.Until(deadline)
+		return func(ac *models.AnalysisContext) { ac.Metrics.LatencyP99 = 1250 }, nil
+	}))
+	o.Register(NewCollector("logs", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		time.Sleep(2 * time.Second) // ignores cancellation, like a client stuck on its own timeout
+		return func(ac *models.AnalysisContext) { ac.ErrorLogs = []models.LogEntry{{Message: "late"}} }, nil
+	}))
+
+	start := time.Now()
+	ac, err := o.PrepareContext(context.Background(), "checkout", start)
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+
+	assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+	assert.Less(t, elapsed, time.Second, "a source over budget does not delay the context")
+	assert.Positive(t, budgetLeft)
+	assert.LessOrEqual(t, budgetLeft, 100*time.Millisecond, "sources query under the shared budget")
+	assert.Equal(t, 1250.0, ac.Metrics.LatencyP99, "sources that finished in time are kept")
+	assert.Empty(t, ac.ErrorLogs)
+	assert.Contains(t, ac.SourceErrors["logs"], "enrichment_budget")
+}
+
+func TestEnrichmentBudgetIsAHardCeilingOverRequiredSources(t *testing.T) {
+	cfg := &config.Config{Analysis: config.AnalysisConfig{ContextDeadline: "20ms", EnrichmentBudget: "80ms"}}
+	o := New(nil, nil, nil, nil, cfg)
+	o.Register(NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		time.Sleep(2 * time.Second)
+		return nil, nil
+	}))
+
+	start := time.Now()
+	ac, err := o.PrepareContext(context.Background(), "checkout", start)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "required sources are not awaited past the budget")
+	assert.Contains(t, ac.SourceErrors["metrics"], "enrichment_budget")
+}
+
+func TestMetricsCollectorFlagsDownTarget(t *testing.T) {
+	promAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := `[]`
+		if strings.HasPrefix(r.URL.Query().Get("query"), "up{") {
+			value = `[{"metric": {"instance": "checkout:8080"}, "value": [1700000000, "0"]}]`
+		}
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": ` + value + `}}`))
+	}))
+	defer promAPI.Close()
+
+	cfg := &config.Config{}
+	o := New(prometheus.NewClient(promAPI.URL, time.Second), nil, nil, nil, cfg)
+
+	ac, err := o.PrepareContext(context.Background(), "checkout", time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, ac.SourceErrors)
+	require.NotNil(t, ac.MetricsTargets)
+	assert.Equal(t, prometheus.TargetHealth{Down: 1}, *ac.MetricsTargets)
+	assert.True(t, ac.MetricsDegraded(), "empty golden signals from a down target are flagged")
+}
+
+func TestMetricsCollectorFlagsStaleSamples(t *testing.T) {
+	lastScrape := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	promAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().Unix()
+		value := fmt.Sprintf(`[{"metric": {}, "value": [%d, "0.5"]}]`, now)
+		switch q := r.URL.Query().Get("query"); {
+		case strings.HasPrefix(q, "max(last_over_time(timestamp("):
+			value = fmt.Sprintf(`[{"metric": {}, "value": [%d, "%d"]}]`, now, lastScrape.Unix())
+		case strings.HasPrefix(q, "up{"):
+			value = `[{"metric": {"instance": "checkout:8080"}, "value": [1700000000, "1"]}]`
+		}
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": ` + value + `}}`))
+	}))
+	defer promAPI.Close()
+
+	cfg := &config.Config{Prometheus: config.PrometheusConfig{StalenessThreshold: "10m"}}
+	o := New(prometheus.NewClient(promAPI.URL, time.Second), nil, nil, nil, cfg)
+
+	ac, err := o.PrepareContext(context.Background(), "checkout", time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, ac.SourceErrors)
+	assert.True(t, lastScrape.Equal(ac.MetricsLastSample))
+	assert.True(t, ac.MetricsStale, "a two-hour-old sample is past the 10m threshold")
+	assert.Equal(t, 0.5, ac.Metrics.ErrorRate, "stale values are kept but flagged")
+
+	cfg.Prometheus.StalenessThreshold = "3h"
+	ac, err = o.PrepareContext(context.Background(), "checkout", time.Now())
+	require.NoError(t, err)
+	assert.False(t, ac.MetricsStale)
+}
+
+func TestEnrichmentPolicySkipsTracesForWarnings(t *testing.T) {
+	var tempoCalls atomic.Int32
+	tempoAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tempoCalls.Add(1)
+		w.Write([]byte(`{"traces": []}`))
+	}))
+	defer tempoAPI.Close()
+
+	cfg := &config.Config{}
+	o := New(nil, nil, nil, tempo.NewClient(tempoAPI.URL, time.Second, nil), cfg)
+	var custom atomic.Int32
+	o.Register(NewCollector("k8s", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		custom.Add(1)
+		return nil, nil
+	}))
+
+	_, err := o.PrepareAlertContext(context.Background(), "checkout", config.SeverityWarning, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, tempoCalls.Load(), "warnings get metrics and commits only")
+	assert.Equal(t, int32(1), custom.Load(), "custom collectors are not gated by severity")
+
+	_, err = o.PrepareAlertContext(context.Background(), "checkout", config.SeverityCritical, time.Now())
+	require.NoError(t, err)
+	assert.NotZero(t, tempoCalls.Load(), "criticals get full enrichment")
+}
+
+func TestFetchLogsUsesServiceLogSelector(t *testing.T) {
+	var queries []string
+	lokiAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("query"))
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "streams", "result": []}}`))
+	}))
+	defer lokiAPI.Close()
+
+	cfg := &config.Config{Services: map[string]config.ServiceConfig{
+		"checkout": {LogSelector: `{app="%s", namespace="prod"}`},
+	}}
+	o := New(nil, nil, loki.NewClient(lokiAPI.URL, time.Second), nil, cfg)
+
+	_, err := o.fetchLogs(context.Background(), "checkout", time.Now().Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+	_, err = o.fetchLogs(context.Background(), "cart", time.Now().Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`{app="checkout", namespace="prod"} |= "error"`, `{service="cart"} |= "error"`}, queries)
+}
+
+func TestFetchTracesCountsErrorSpansFromTempo(t *testing.T) {
+	tempoAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case tempo.BuildErrorSpansQuery("checkout"):
+			w.Write([]byte(`{"traces": [{"traceID": "t1", "spanSet": {"spans": [
+				{"spanID": "a1", "name": "POST /charge", "durationNanos": "61000000",
+				 "attributes": [{"key": "status", "value": {"stringValue": "error"}}, {"key": "peer.service", "value": {"stringValue": "payments"}}]}
+			]}}]}`))
+		case tempo.BuildSlowSpansQuery("checkout", 500):
+			w.Write([]byte(`{"traces": [{"traceID": "t2", "spanSet": {"spans": [
+				{"spanID": "b1", "name": "SELECT orders", "durationNanos": "900000000"}
+			]}}]}`))
+		default:
+			w.Write([]byte(`{"traces": [{"traceID": "t1"}, {"traceID": "t2"}]}`))
+		}
+	}))
+	defer tempoAPI.Close()
+
+	o := New(nil, nil, nil, tempo.NewClient(tempoAPI.URL, time.Second, nil), &config.Config{})
+	traces, err := o.fetchTraces(context.Background(), "checkout", time.Now().Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, traces.TraceCount)
+	require.Len(t, traces.ErrorSpans, 1)
+	assert.Equal(t, "payments", traces.ErrorSpans[0].PeerService)
+	require.Len(t, traces.OperationStats, 2)
+	assert.Equal(t, tempo.OperationStats{Operation: "POST /charge", Count: 1, P99Ms: 61, ErrorCount: 1}, traces.OperationStats[0])
+	assert.Zero(t, traces.OperationStats[1].ErrorCount, "slow spans without an error status are not errors")
+}
+
+func TestPrepareContextTellsCancellationFromBudget(t *testing.T) {
+	cfg := &config.Config{Analysis: config.AnalysisConfig{EnrichmentBudget: "10s"}}
+	o := New(nil, nil, nil, nil, cfg)
+	o.Register(NewCollector("logs", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		time.Sleep(2 * time.Second)
+		return nil, nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	ac, err := o.PrepareContext(ctx, "checkout", start)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "shutdown does not wait for sources")
+	assert.Contains(t, ac.SourceErrors["logs"], "cancelled")
+	assert.NotContains(t, ac.SourceErrors["logs"], "enrichment_budget")
+}
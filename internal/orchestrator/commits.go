@@ -0,0 +1,130 @@
+package orchestrator
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"helixops/internal/clients/github"
+	"helixops/internal/config"
+	"helixops/internal/models"
+)
+
+// CommitCache stores the commit cursor of each repository, branch, and path.
+type CommitCache interface {
+	LoadCommitCursor(key string) (*models.CommitCursor, error)
+	SaveCommitCursor(key string, c *models.CommitCursor) error
+}
+
+// cursorKey identifies a commit listing; the same repository filtered by another branch or path has its own cursor.
+func cursorKey(repo string, filter github.CommitFilter) string {
+	return repo + "@" + filter.Branch + ":" + filter.Path
+}
+
+// fetchCommitsSinceCursor returns the commits since a time, fetching only those newer than the stored
+// cursor when the cached listing already covers the start of the window. The cursor commit must come
+// back in the newer listing; when it does not, history was rewritten (e.g. a force push) or too many
+// commits landed to tell, so the whole window is fetched again. A failed fetch leaves the cursor
+// untouched, so the next alert retries from the same point.
+func (o *Orchestrator) fetchCommitsSinceCursor(ctx context.Context, repo string, filter github.CommitFilter, since time.Time) ([]models.CommitInfo, error) {
+	key := cursorKey(repo, filter)
+	cursor, err := o.commitCache.LoadCommitCursor(key)
+	if err != nil {
+		slog.Warn("Failed to load commit cursor; fetching the full window", "repo", repo, "error", err)
+		cursor = nil
+	}
+
+	var commits []models.CommitInfo
+	resumed := false
+	if cursor != nil && cursor.HeadSHA != "" && !cursor.Since.After(since) {
+		newer, err := o.listCommits(ctx, repo, filter, cursor.HeadAt)
+		if err != nil {
+			return nil, err
+		}
+		if i := slices.IndexFunc(newer, func(c models.CommitInfo) bool { return c.SHA == cursor.HeadSHA }); i >= 0 {
+			commits, resumed = mergeCommits(newer[:i], cursor.Commits, since), true
+		} else {
+			slog.Info("Commit cursor not found in newer commits; fetching the full window", "repo", repo, "cursor", models.ShortSHA(cursor.HeadSHA))
+		}
+	}
+	if !resumed {
+		if commits, err = o.listCommits(ctx, repo, filter, since); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(commits) > 0 {
+		next := &models.CommitCursor{HeadSHA: commits[0].SHA, HeadAt: commits[0].Timestamp, Since: since, Commits: commits}
+		if err := o.commitCache.SaveCommitCursor(key, next); err != nil {
+			slog.Warn("Failed to save commit cursor", "repo", repo, "error", err)
+		}
+	}
+	return commits, nil
+}
+
+// mergeCommits combines newly fetched and cached commits, newest first, without duplicates, dropping
+// cached commits older than since.
+func mergeCommits(newer, cached []models.CommitInfo, since time.Time) []models.CommitInfo {
+	merged := make([]models.CommitInfo, 0, len(newer)+len(cached))
+	seen := make(map[string]bool)
+	for _, c := range append(slices.Clone(newer), cached...) {
+		if seen[c.SHA] || (!c.Timestamp.IsZero() && c.Timestamp.Before(since)) {
+			continue
+		}
+		seen[c.SHA] = true
+		merged = append(merged, c)
+	}
+	slices.SortStableFunc(merged, func(a, b models.CommitInfo) int { return b.Timestamp.Compare(a.Timestamp) })
+	return merged
+}
+
+// commitFilter drops commits whose author or message summary matches an exclude pattern.
+type commitFilter struct {
+	authors  []*regexp.Regexp
+	messages []*regexp.Regexp
+}
+
+// newCommitFilter compiles github.commit_filter; it returns nil when filtering is disabled. Invalid
+// patterns are rejected by config validation and skipped here.
+func newCommitFilter(cfg config.CommitFilterConfig) *commitFilter {
+	if cfg.Disabled {
+		return nil
+	}
+	compile := func(patterns []string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, p := range patterns {
+			if re, err := regexp.Compile(p); err == nil {
+				res = append(res, re)
+			}
+		}
+		return res
+	}
+	return &commitFilter{authors: compile(cfg.GetExcludeAuthors()), messages: compile(cfg.GetExcludeMessages())}
+}
+
+// excludes reports whether c is bot or housekeeping noise.
+func (f *commitFilter) excludes(c models.CommitInfo) bool {
+	for _, re := range f.authors {
+		if re.MatchString(c.Author) || (c.Email != "" && re.MatchString(c.Email)) {
+			return true
+		}
+	}
+	summary, _, _ := strings.Cut(c.Message, "\n")
+	for _, re := range f.messages {
+		if re.MatchString(summary) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply returns the commits f does not exclude, in order. A nil filter keeps everything.
+func (f *commitFilter) apply(commits []models.CommitInfo) []models.CommitInfo {
+	if f == nil {
+		return commits
+	}
+	return slices.DeleteFunc(slices.Clone(commits), f.excludes)
+}
@@ -0,0 +1,122 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/github"
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryCommitCache is an in-memory CommitCache.
+type memoryCommitCache map[string]*models.CommitCursor
+
+func (m memoryCommitCache) LoadCommitCursor(key string) (*models.CommitCursor, error) {
+	return m[key], nil
+}
+
+func (m memoryCommitCache) SaveCommitCursor(key string, c *models.CommitCursor) error {
+	m[key] = c
+	return nil
+}
+
+// commitsJSON renders commits as a GitHub list commits response.
+func commitsJSON(commits ...models.CommitInfo) string {
+	items := make([]string, len(commits))
+	for i, c := range commits {
+		items[i] = fmt.Sprintf(`{"sha": %q, "commit": {"message": %q, "author": {"name": "dev", "date": %q}}}`,
+			c.SHA, c.Message, c.Timestamp.Format(time.RFC3339))
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestFetchCommitsOnlyRequestsCommitsAfterCursor(t *testing.T) {
+	alertTime := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	c1 := models.CommitInfo{SHA: "aaa1111", Message: "Add cache", Timestamp: alertTime.Add(-3 * time.Hour)}
+	c2 := models.CommitInfo{SHA: "bbb2222", Message: "Tune pool", Timestamp: alertTime.Add(-2 * time.Hour)}
+	c3 := models.CommitInfo{SHA: "ccc3333", Message: "Raise timeout", Timestamp: alertTime.Add(-time.Hour)}
+	rewritten := models.CommitInfo{SHA: "ddd4444", Message: "Squashed", Timestamp: alertTime.Add(-30 * time.Minute)}
+
+	var (
+		sinces    []string
+		responses []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sinces = append(sinces, r.URL.Query().Get("since"))
+		w.Write([]byte(responses[0]))
+		responses = responses[1:]
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{GitHub: config.GitHubConfig{DefaultOrg: "acme"}}
+	o := New(nil, github.NewClient(server.URL, "token"), nil, nil, cfg)
+	o.UseCommitCache(memoryCommitCache{})
+	since := alertTime.Add(-24 * time.Hour)
+
+	responses = []string{commitsJSON(c2, c1)}
+	commits, err := o.fetchCommits(context.Background(), "checkout", since)
+	require.NoError(t, err)
+	assert.Equal(t, since.Format(time.RFC3339), sinces[0])
+	assert.Equal(t, []string{"bbb2222", "aaa1111"}, shas(commits))
+
+	// The second alert only asks for commits from the cursor on and merges them with the cache
+	responses = []string{commitsJSON(c3, c2)}
+	commits, err = o.fetchCommits(context.Background(), "checkout", since.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, c2.Timestamp.Format(time.RFC3339), sinces[1])
+	assert.Equal(t, []string{"ccc3333", "bbb2222", "aaa1111"}, shas(commits))
+
+	// After a force push the cursor commit is gone, so the whole window is fetched again
+	responses = []string{commitsJSON(rewritten), commitsJSON(rewritten, c1)}
+	commits, err = o.fetchCommits(context.Background(), "checkout", since.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.Len(t, sinces, 4)
+	assert.Equal(t, c3.Timestamp.Format(time.RFC3339), sinces[2])
+	assert.Equal(t, since.Add(2*time.Minute).Format(time.RFC3339), sinces[3])
+	assert.Equal(t, []string{"ddd4444", "aaa1111"}, shas(commits))
+}
+
+func shas(commits []models.CommitInfo) []string {
+	out := make([]string, len(commits))
+	for i, c := range commits {
+		out[i] = c.SHA
+	}
+	return out
+}
+
+func TestFetchCommitsDropsBotAndNoiseCommits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"sha": "aaa1111", "commit": {"message": "Bump lodash from 4.17.20 to 4.17.21", "author": {"name": "dependabot[bot]", "email": "49699333+dependabot[bot]@users.noreply.github.com"}}},
+			{"sha": "bbb2222", "commit": {"message": "Merge pull request #42 from acme/pool\n\nTune pool", "author": {"name": "Alice"}}},
+			{"sha": "ccc3333", "commit": {"message": "Reduce DB pool size to 5", "author": {"name": "Alice", "email": "alice@acme.io"}}},
+			{"sha": "ddd4444", "commit": {"message": "style: gofmt", "author": {"name": "format-bot", "email": "ci@acme.io"}}},
+			{"sha": "eee5555", "commit": {"message": "Raise checkout timeout", "author": {"name": "Bob"}}}
+		]`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{GitHub: config.GitHubConfig{DefaultOrg: "acme"}}
+	commits, err := New(nil, github.NewClient(server.URL, "token"), nil, nil, cfg).fetchCommits(context.Background(), "checkout", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ccc3333", "ddd4444", "eee5555"}, shas(commits), "default patterns drop bots, bumps, and merges")
+
+	cfg.GitHub.CommitFilter = config.CommitFilterConfig{ExcludeAuthors: []string{`^format-bot$`}, ExcludeMessages: []string{`^Merge `}}
+	commits, err = New(nil, github.NewClient(server.URL, "token"), nil, nil, cfg).fetchCommits(context.Background(), "checkout", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aaa1111", "ccc3333", "eee5555"}, shas(commits), "configured patterns replace the defaults")
+
+	cfg.GitHub.CommitFilter = config.CommitFilterConfig{Disabled: true}
+	commits, err = New(nil, github.NewClient(server.URL, "token"), nil, nil, cfg).fetchCommits(context.Background(), "checkout", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, commits, 5)
+}
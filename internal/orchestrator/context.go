@@ -3,7 +3,10 @@ package orchestrator
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
 	"time"
 
 	"helixops/internal/clients/github"
@@ -11,7 +14,10 @@ import (
 	"helixops/internal/clients/prometheus"
 	"helixops/internal/clients/tempo"
 	"helixops/internal/config"
+	"helixops/internal/httpx"
 	"helixops/internal/models"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // Orchestrator coordinates asynchronous data collection from multiple external APIs to build a unified incident context.
@@ -21,166 +27,465 @@ type Orchestrator struct {
 	lokiClient   *loki.Client
 	tempoClient  *tempo.Client
 	cfg          *config.Config
+	collectors   []Collector
+	repos        RepoResolver
+	history      IncidentHistory
+	commitCache  CommitCache
+	commitFilter *commitFilter
+}
+
+// IncidentHistory looks up earlier resolved incidents of an alert.
+type IncidentHistory interface {
+	PriorIncidents(serviceName, alertName string, before time.Time, limit int) ([]models.PriorIncident, error)
+}
+
+// DeployHistory looks up deploys reported by CD systems.
+type DeployHistory interface {
+	DeployEvents(serviceName string, from, to time.Time) ([]models.DeployEvent, error)
+}
+
+// RepoResolver finds the repository of a service that has no explicit mapping in config.
+type RepoResolver interface {
+	// ResolveRepo returns "owner/repo", or "" when the service is unknown.
+	ResolveRepo(ctx context.Context, serviceName string) (string, error)
 }
 
 // New initializes a new Orchestrator instance with the necessary infrastructure clients.
+// A collector is registered for each client that is configured.
 func New(prom *prometheus.Client, gh *github.Client, loki *loki.Client, tempoClient *tempo.Client, cfg *config.Config) *Orchestrator {
-	return &Orchestrator{
+	o := &Orchestrator{
 		promClient:   prom,
 		githubClient: gh,
 		lokiClient:   loki,
 		tempoClient:  tempoClient,
 		cfg:          cfg,
+		commitFilter: newCommitFilter(cfg.GitHub.CommitFilter),
 	}
+	if prom != nil {
+		o.Register(o.metricsCollector())
+	}
+	if gh != nil {
+		o.Register(o.commitsCollector())
+	}
+	if tempoClient != nil {
+		o.Register(o.tracesCollector())
+	}
+	if loki != nil {
+		o.Register(o.logsCollector())
+	}
+	return o
 }
 
-// PrepareContext gathers metrics, traces, and commits concurrently for a given service within an incident time window.
-func (o *Orchestrator) PrepareContext(ctx context.Context, serviceName string, alertTime time.Time) (*models.AnalysisContext, error) {
-	log.Printf("Preparing context for service: %s", serviceName)
+// Register adds a collector to the fan-out run by PrepareContext.
+func (o *Orchestrator) Register(c Collector) {
+	o.collectors = append(o.collectors, c)
+}
+
+// UseRepoResolver consults r for services without a services.<name>.repo or github.service_mapping
+// entry before falling back to github.default_org/<service>.
+func (o *Orchestrator) UseRepoResolver(r RepoResolver) {
+	o.repos = r
+}
 
-	// Calculate time windows
-	metricsWindow := o.cfg.Analysis.GetMetricsWindowDuration()
-	commitsLookback := o.cfg.Analysis.GetCommitsLookbackDuration()
-	logsLookback := o.cfg.Analysis.GetLogsLookbackDuration()
+// UseCommitCache keeps a commit cursor per repository in c, so alerts whose lookback overlaps an
+// earlier fetch only request newer commits from GitHub.
+func (o *Orchestrator) UseCommitCache(c CommitCache) {
+	o.commitCache = c
+}
 
-	metricsStart := alertTime.Add(-metricsWindow)
-	metricsEnd := alertTime
+// UseIncidentHistory enables AttachPriorIncidents.
+func (o *Orchestrator) UseIncidentHistory(h IncidentHistory) {
+	o.history = h
+}
 
-	commitsSince := alertTime.Add(-commitsLookback)
-	logsStart := alertTime.Add(-logsLookback)
+// UseDeployEvents registers a "deploys" collector that reads the deploys recorded through
+// POST /events/deploy within the commits lookback before the alert.
+func (o *Orchestrator) UseDeployEvents(h DeployHistory) {
+	o.Register(o.deploysCollector(h))
+}
 
-	// Fetch data concurrently
-	type result struct {
-		metrics models.MetricsSummary
-		commits []models.CommitInfo
-		traces  tempo.TraceContext
-		logs    []models.LogEntry
-		err     error
+// AttachPriorIncidents adds the most recent resolved incidents of the same alert on the same service
+// to ac, up to analysis.prior_incidents. It needs ac.Alert, so it runs after PrepareContext once the
+// alert is mapped; lookup failures are recorded in SourceErrors.
+func (o *Orchestrator) AttachPriorIncidents(ac *models.AnalysisContext) {
+	if o.history == nil {
+		return
+	}
+	prior, err := o.history.PriorIncidents(ac.ServiceName, ac.Alert.Name, ac.Alert.StartedAt, o.cfg.Analysis.GetPriorIncidents())
+	if err != nil {
+		slog.Warn("Error fetching data", "service", ac.ServiceName, "source", "history", "error", err)
+		if ac.SourceErrors == nil {
+			ac.SourceErrors = make(map[string]string)
+		}
+		ac.SourceErrors["history"] = err.Error()
+		return
+	}
+	ac.PriorIncidents = prior
+}
+
+// AttachDashboards renders the service's configured dashboard links over the incident window: from the
+// start of the analysis window before the alert fired until it ended, or until now while it is still
+// firing. It replaces any links already on ac, so calling it again once the alert resolves widens the
+// window to the whole incident.
+func (o *Orchestrator) AttachDashboards(ac *models.AnalysisContext) {
+	dashboards := o.cfg.DashboardsFor(ac.ServiceName)
+	if len(dashboards) == 0 {
+		return
+	}
+	from := ac.Alert.StartedAt.Add(-o.cfg.Analysis.GetMetricsWindowDuration())
+	// Alertmanager sends firing alerts with a zero or future endsAt
+	now := time.Now()
+	to := ac.Alert.EndsAt
+	if to.IsZero() || to.After(now) || to.Before(ac.Alert.StartedAt) {
+		to = now
+	}
+	links := make([]models.DashboardLink, 0, len(dashboards))
+	for _, d := range dashboards {
+		links = append(links, models.DashboardLink{Name: d.Name, URL: d.Render(ac.ServiceName, from, to)})
 	}
+	ac.Dashboards = links
+}
 
-	resultCh := make(chan result, 4)
+// PrepareContext runs every registered collector concurrently, bounded by analysis.max_concurrency,
+// for a given service within an incident time window. Collector failures are recorded in
+// SourceErrors rather than failing the whole context.
+//
+// When analysis.context_deadline is set, PrepareContext returns once the deadline has passed and
+// the analysis.required_sources collectors are in, so a slow source cannot hold up the RCA.
+// Collectors still running are cancelled and recorded in SourceErrors.
+//
+// analysis.enrichment_budget is a hard ceiling on top of that: every collector runs under one shared
+// deadline, so their client timeouts cannot add up, and PrepareContext returns when it passes even if
+// required sources are still out. It also returns as soon as ctx is cancelled, e.g. on shutdown,
+// recording the sources it did not wait for as cancelled rather than over budget.
+func (o *Orchestrator) PrepareContext(ctx context.Context, serviceName string, alertTime time.Time) (*models.AnalysisContext, error) {
+	return o.PrepareAlertContext(ctx, serviceName, "", alertTime)
+}
 
-	go func() {
-		metrics, err := o.fetchMetrics(ctx, serviceName, metricsStart, metricsEnd)
-		resultCh <- result{metrics: metrics, err: err}
-	}()
+// PrepareAlertContext is PrepareContext limited by the analysis.enrichment policy for the alert's
+// severity: built-in sources the policy leaves out are not queried at all, so low-severity alert
+// storms do not load Tempo and Loki. Custom collectors always run. An empty severity gathers everything.
+func (o *Orchestrator) PrepareAlertContext(ctx context.Context, serviceName, severity string, alertTime time.Time) (*models.AnalysisContext, error) {
+	collectors := o.collectorsFor(severity)
+	slog.Debug("Preparing context", "service", serviceName, "severity", severity, "collectors", len(collectors))
+
+	window := o.windowFor(alertTime)
+
+	applies := make([]func(*models.AnalysisContext), len(collectors))
+	errs := make([]error, len(collectors))
+
+	budget := o.cfg.Analysis.GetEnrichmentBudgetDuration()
+	var (
+		collectCtx context.Context
+		cancel     context.CancelFunc
+	)
+	if budget > 0 {
+		collectCtx, cancel = context.WithTimeout(ctx, budget)
+	} else {
+		collectCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
 
+	type collected struct {
+		i     int
+		apply func(*models.AnalysisContext)
+		err   error
+	}
+	// Buffered so collectors finishing after an early return never block
+	results := make(chan collected, len(collectors))
+	var g errgroup.Group
+	g.SetLimit(o.cfg.Analysis.GetMaxConcurrency())
+	// Go blocks while max_concurrency collectors run, so they are started apart from the wait below;
+	// collectors still queued when gathering ends return without running
 	go func() {
-		commits, err := o.fetchCommits(ctx, serviceName, commitsSince)
-		resultCh <- result{commits: commits, err: err}
+		for i, c := range collectors {
+			g.Go(func() error {
+				if err := collectCtx.Err(); err != nil {
+					results <- collected{i: i, err: err}
+					return nil
+				}
+				apply, err := c.Collect(collectCtx, serviceName, window)
+				results <- collected{i: i, apply: apply, err: err}
+				return nil
+			})
+		}
 	}()
 
-	go func() {
-		traces, err := o.fetchTraces(ctx, serviceName, metricsStart, metricsEnd)
-		resultCh <- result{traces: traces, err: err}
-	}()
+	done := make([]bool, len(collectors))
+	var deadline <-chan time.Time
+	if d := o.cfg.Analysis.GetContextDeadlineDuration(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	record := func(r collected) {
+		applies[r.i], errs[r.i] = r.apply, r.err
+		done[r.i] = true
+	}
+	pastDeadline, stopped := false, false
+	for pending := len(collectors); pending > 0 && !stopped; {
+		if pastDeadline && o.requiredDone(collectors, done) {
+			break
+		}
+		select {
+		case r := <-results:
+			record(r)
+			pending--
+		case <-deadline:
+			deadline = nil
+			pastDeadline = true
+		case <-collectCtx.Done():
+			stopped = true
+		}
+	}
+	// Collectors that finished as gathering stopped still count; those cut short are skipped below
+	for drained := !stopped; !drained; {
+		select {
+		case r := <-results:
+			if !errors.Is(r.err, collectCtx.Err()) {
+				record(r)
+			}
+		default:
+			drained = true
+		}
+	}
 
-	go func() {
-		logs, err := o.fetchLogs(ctx, serviceName, logsStart, metricsEnd)
-		resultCh <- result{logs: logs, err: err}
-	}()
+	skipped := fmt.Errorf("skipped: not finished within analysis.context_deadline")
+	switch {
+	case ctx.Err() != nil:
+		skipped = fmt.Errorf("skipped: analysis cancelled before it finished: %w", ctx.Err())
+	case stopped:
+		skipped = fmt.Errorf("skipped: not finished within analysis.enrichment_budget of %s", budget)
+	}
+	for i, c := range collectors {
+		if !done[i] {
+			errs[i] = skipped
+			slog.Info("Starting analysis without slow source", "service", serviceName, "source", c.Name())
+		}
+	}
 
-	// Collect results
-	var aggregatedErr error
 	ctxResult := &models.AnalysisContext{
 		ServiceName: serviceName,
-		TimeWindow: models.TimeWindow{
-			Start:    metricsStart,
-			End:      metricsEnd,
-			Duration: metricsWindow.String(),
-		},
+		TimeWindow:  window,
 	}
 
-	for i := 0; i < 4; i++ {
-		r := <-resultCh
-		if r.err != nil {
-			log.Printf("Error fetching data: %v", r.err)
+	// Merge in registration order so the result does not depend on scheduling
+	for i, c := range collectors {
+		ctxResult.Sources = append(ctxResult.Sources, c.Name())
+		if errs[i] != nil {
+			slog.Warn("Error fetching data", "service", serviceName, "source", c.Name(), "error", errs[i])
+			if ctxResult.SourceErrors == nil {
+				ctxResult.SourceErrors = make(map[string]string)
+			}
+			ctxResult.SourceErrors[c.Name()] = errs[i].Error()
+			if errors.As(errs[i], new(*httpx.BackpressureError)) {
+				ctxResult.Backpressure = append(ctxResult.Backpressure, c.Name())
+			}
 		}
-		if len(r.commits) > 0 {
-			ctxResult.RecentCommits = r.commits
+		if applies[i] != nil {
+			applies[i](ctxResult)
 		}
-		if r.metrics.LatencyP99 > 0 || r.metrics.ErrorRate > 0 {
-			ctxResult.Metrics = r.metrics
-		}
-		if r.traces.TraceCount > 0 {
-			ctxResult.Traces = r.traces
+	}
+
+	if o.cfg.GitHub.BlameFiles && o.githubClient != nil && len(ctxResult.ErrorLogs) > 0 {
+		o.attachFileChanges(collectCtx, ctxResult)
+	}
+
+	ctxResult.SuspectedCauses = Correlate(ctxResult)
+	ctxResult.BlastRadius = EstimateBlastRadius(ctxResult)
+
+	return ctxResult, nil
+}
+
+// collectorsFor returns the registered collectors the enrichment policy allows for severity.
+func (o *Orchestrator) collectorsFor(severity string) []Collector {
+	allowed := o.cfg.Analysis.GetEnrichmentSources(severity)
+	if allowed == nil {
+		return o.collectors
+	}
+	var selected []Collector
+	for _, c := range o.collectors {
+		if slices.Contains(config.ContextSources, c.Name()) && !slices.Contains(allowed, c.Name()) {
+			slog.Debug("Skipping source for alert severity", "source", c.Name(), "severity", severity)
+			continue
 		}
-		if len(r.logs) > 0 {
-			ctxResult.ErrorLogs = r.logs
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// requiredDone reports whether every collector named in analysis.required_sources has finished.
+func (o *Orchestrator) requiredDone(collectors []Collector, done []bool) bool {
+	for _, name := range o.cfg.Analysis.GetRequiredSources() {
+		for i, c := range collectors {
+			if c.Name() == name && !done[i] {
+				return false
+			}
 		}
 	}
+	return true
+}
 
-	return ctxResult, aggregatedErr
+// RequiredSourceErrors returns an error naming each analysis.required_sources collector that failed or
+// was skipped while gathering ac, or nil when all of them delivered.
+func (o *Orchestrator) RequiredSourceErrors(ac *models.AnalysisContext) error {
+	var errs []error
+	for _, name := range o.cfg.Analysis.GetRequiredSources() {
+		if msg, ok := ac.SourceErrors[name]; ok {
+			errs = append(errs, fmt.Errorf("required source %s: %s", name, msg))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // fetchMetrics retrieves golden signals metrics from Prometheus
 func (o *Orchestrator) fetchMetrics(ctx context.Context, serviceName string, start, end time.Time) (models.MetricsSummary, error) {
 	metrics := models.MetricsSummary{}
+	var errs []error
+
+	if o.promClient == nil {
+		return metrics, nil
+	}
 
 	latency, err := o.promClient.QueryLatencyP99(ctx, serviceName, start, end)
 	if err != nil {
-		log.Printf("Failed to query latency: %v", err)
+		errs = append(errs, fmt.Errorf("latency p99: %w", err))
 	} else {
 		metrics.LatencyP99 = latency
 	}
 
 	errorRate, err := o.promClient.QueryErrorRate(ctx, serviceName, start, end)
 	if err != nil {
-		log.Printf("Failed to query error rate: %v", err)
+		errs = append(errs, fmt.Errorf("error rate: %w", err))
 	} else {
 		metrics.ErrorRate = errorRate
 	}
 
 	rps, err := o.promClient.QueryRPS(ctx, serviceName, start, end)
 	if err != nil {
-		log.Printf("Failed to query RPS: %v", err)
+		errs = append(errs, fmt.Errorf("rps: %w", err))
 	} else {
 		metrics.RPS = rps
 	}
 
-	return metrics, nil
+	breakdown, err := o.promClient.QueryStatusBreakdown(ctx, serviceName, start, end)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("status breakdown: %w", err))
+	} else if len(breakdown) > 0 {
+		metrics.StatusBreakdown = breakdown
+	}
+
+	// Individual query failures still return whatever signals succeeded
+	return metrics, errors.Join(errs...)
+}
+
+// fetchTargetHealth checks whether Prometheus is successfully scraping the service.
+func (o *Orchestrator) fetchTargetHealth(ctx context.Context, serviceName string) (*prometheus.TargetHealth, error) {
+	if o.promClient == nil {
+		return nil, nil
+	}
+
+	health, err := o.promClient.QueryTargetUp(ctx, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("target up: %w", err)
+	}
+	if health.Degraded() {
+		slog.Warn("Scrape targets down; metrics may be incomplete", "service", serviceName, "up", health.Up, "down", health.Down)
+	}
+	return &health, nil
+}
+
+// fetchLastSample returns when Prometheus last scraped the service's request counter. It looks back
+// well past the staleness threshold, at least an hour, so a sample that went stale is still found.
+func (o *Orchestrator) fetchLastSample(ctx context.Context, serviceName string) (time.Time, error) {
+	if o.promClient == nil {
+		return time.Time{}, nil
+	}
+
+	lookback := 4 * o.cfg.Prometheus.GetStalenessThresholdDuration()
+	if lookback < time.Hour {
+		lookback = time.Hour
+	}
+	last, err := o.promClient.QueryLastSample(ctx, serviceName, lookback)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("last sample: %w", err)
+	}
+	if !last.IsZero() && time.Since(last) > o.cfg.Prometheus.GetStalenessThresholdDuration() {
+		slog.Warn("Metrics are stale", "service", serviceName, "last_sample", last, "age", time.Since(last).Round(time.Second))
+	}
+	return last, nil
+}
+
+// resolveService maps a service name to its GitHub repo, branch, and path using the config mapping,
+// falling back to repository discovery for services without one.
+func (o *Orchestrator) resolveService(ctx context.Context, serviceName string) config.ServiceConfig {
+	svc := o.cfg.ResolveService(serviceName)
+	if o.repos != nil && o.cfg.MappedRepo(serviceName) == "" {
+		repo, err := o.repos.ResolveRepo(ctx, serviceName)
+		if err != nil {
+			slog.Warn("Repository discovery failed; using default repo", "service", serviceName, "repo", svc.Repo, "error", err)
+		} else if repo != "" {
+			svc.Repo = repo
+		}
+	}
+	return svc
 }
 
 // fetchCommits retrieves recent commits from GitHub
 func (o *Orchestrator) fetchCommits(ctx context.Context, serviceName string, since time.Time) ([]models.CommitInfo, error) {
-	// Map service name to GitHub repo using config mapping
-	repo := ""
-	if o.cfg.GitHub.ServiceMapping != nil {
-		if mapped, ok := o.cfg.GitHub.ServiceMapping[serviceName]; ok {
-			repo = mapped
-		}
+	if o.githubClient == nil {
+		return nil, nil
 	}
 
-	// Fallback: use default org + service name as repo
-	if repo == "" {
-		if o.cfg.GitHub.DefaultOrg != "" {
-			repo = o.cfg.GitHub.DefaultOrg + "/" + serviceName
-		} else {
-			repo = serviceName // Last resort fallback
-		}
+	svc := o.resolveService(ctx, serviceName)
+	filter := github.CommitFilter{Branch: svc.Branch, Path: svc.Path}
+	var (
+		commits []models.CommitInfo
+		err     error
+	)
+	if o.commitCache != nil {
+		commits, err = o.fetchCommitsSinceCursor(ctx, svc.Repo, filter, since)
+	} else {
+		commits, err = o.listCommits(ctx, svc.Repo, filter, since)
+	}
+	if err != nil {
+		slog.Warn("Failed to fetch commits", "service", serviceName, "repo", svc.Repo, "error", err)
+		return nil, err
 	}
+	// Filtered after the cache so a changed filter applies to cached commits too
+	kept := o.commitFilter.apply(commits)
+	if dropped := len(commits) - len(kept); dropped > 0 {
+		slog.Debug("Dropped bot and noise commits", "service", serviceName, "repo", svc.Repo, "dropped", dropped)
+	}
+	return kept, nil
+}
 
-	commits, err := o.githubClient.FetchCommitsByRepo(ctx, repo, since)
+// listCommits fetches the commits of a repository since a time, newest first.
+func (o *Orchestrator) listCommits(ctx context.Context, repo string, filter github.CommitFilter, since time.Time) ([]models.CommitInfo, error) {
+	commits, err := o.githubClient.FetchCommitsByRepo(ctx, repo, since, filter)
 	if err != nil {
-		log.Printf("Failed to fetch commits: %v", err)
 		return nil, err
 	}
 
 	result := make([]models.CommitInfo, len(commits))
 	for i, c := range commits {
-		result[i] = models.CommitInfo{
-			SHA:       c.SHA,
-			Message:   c.Message,
-			Author:    c.Author.Name,
-			Email:     c.Author.Email,
-			URL:       c.URL,
-			Timestamp: parseTime(c.Author.Date),
-		}
+		result[i] = commitInfo(c)
 	}
-
 	return result, nil
 }
 
+// commitInfo converts a GitHub commit for the analysis context.
+func commitInfo(c github.Commit) models.CommitInfo {
+	return models.CommitInfo{
+		SHA:       c.SHA,
+		Message:   c.Message,
+		Author:    c.Author.Name,
+		Email:     c.Author.Email,
+		URL:       c.URL,
+		Timestamp: parseTime(c.Author.Date),
+	}
+}
+
 // HealthCheck verifies that orchestrator is properly initialized
 func (o *Orchestrator) HealthCheck(ctx context.Context) bool {
 	// Basic check: orchestrator is initialized with clients
@@ -203,15 +508,20 @@ func (o *Orchestrator) fetchTraces(ctx context.Context, serviceName string, star
 
 	traces, err := o.tempoClient.GetTracesByService(ctx, serviceName, start, end)
 	if err != nil {
-		log.Printf("Failed to fetch traces: %v", err)
+		slog.Warn("Failed to fetch traces", "service", serviceName, "error", err)
 		return traceCtx, err
 	}
 	traceCtx.TraceCount = len(traces)
 
-	slowSpans, err := o.tempoClient.SearchSlowSpans(ctx, serviceName, 500)
+	slowSpans, err := o.tempoClient.SearchSlowSpans(ctx, serviceName, 500, start, end)
 	if err == nil {
 		traceCtx.SlowSpans = slowSpans
 	}
+	errorSpans, err := o.tempoClient.SearchErrorSpans(ctx, serviceName, start, end)
+	if err == nil {
+		traceCtx.ErrorSpans = errorSpans
+	}
+	traceCtx.OperationStats = aggregateOperations(traceCtx.SlowSpans, traceCtx.ErrorSpans)
 
 	return traceCtx, nil
 }
@@ -222,11 +532,15 @@ func (o *Orchestrator) fetchLogs(ctx context.Context, serviceName string, start,
 		return nil, nil
 	}
 
-	// Fetch error logs for the service
-	logs, err := o.lokiClient.QueryErrorLogs(ctx, serviceName, start, end, 50)
+	// Fetch error logs for the service, using its configured stream selector
+	selector := o.cfg.Services[serviceName].GetLogSelector(serviceName)
+	// A failed chunk of a split query still returns the newer logs gathered before it, alongside the error
+	logs, err := o.lokiClient.QueryErrorLogs(ctx, selector, start, end, 50)
 	if err != nil {
-		log.Printf("Failed to fetch error logs: %v", err)
-		return nil, err
+		slog.Warn("Failed to fetch error logs", "service", serviceName, "selector", selector, "error", err)
+		if len(logs) == 0 {
+			return nil, err
+		}
 	}
 
 	// Convert Loki LogEntry to models.LogEntry
@@ -240,6 +554,6 @@ func (o *Orchestrator) fetchLogs(ctx context.Context, serviceName string, start,
 		}
 	}
 
-	log.Printf("Fetched %d error logs for service %s", len(result), serviceName)
-	return result, nil
+	slog.Debug("Fetched error logs", "service", serviceName, "count", len(result))
+	return result, err
 }
@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachDashboardsSubstitutesIncidentWindow(t *testing.T) {
+	cfg := &config.Config{
+		Analysis: config.AnalysisConfig{MetricsWindow: "15m"},
+		Dashboards: []config.DashboardConfig{
+			{Name: "Overview", URL: "https://grafana.example.com/d/abc?var-service={service}&from={from}&to={to}"},
+		},
+		Services: map[string]config.ServiceConfig{
+			"payments": {Dashboards: []config.DashboardConfig{
+				{Name: "Payments", URL: "https://grafana.example.com/d/pay?from={from}&to={to}"},
+				{Name: "Ledger", URL: "https://grafana.example.com/d/ledger?from={from}&to={to}"},
+			}},
+		},
+	}
+	o := New(nil, nil, nil, nil, cfg)
+
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ended := started.Add(40 * time.Minute)
+	ac := &models.AnalysisContext{
+		ServiceName: "checkout api",
+		Alert:       models.AlertInfo{Name: "HighLatency", StartedAt: started, EndsAt: ended},
+	}
+	o.AttachDashboards(ac)
+
+	from := started.Add(-15 * time.Minute).UnixMilli()
+	require.Len(t, ac.Dashboards, 1)
+	assert.Equal(t, "Overview", ac.Dashboards[0].Name)
+	assert.Equal(t, fmt.Sprintf("https://grafana.example.com/d/abc?var-service=checkout+api&from=%d&to=%d", from, ended.UnixMilli()), ac.Dashboards[0].URL)
+
+	// A service's own list replaces the global one
+	ac = &models.AnalysisContext{ServiceName: "payments", Alert: models.AlertInfo{StartedAt: started, EndsAt: ended}}
+	o.AttachDashboards(ac)
+	require.Len(t, ac.Dashboards, 2)
+	assert.Equal(t, []string{"Payments", "Ledger"}, []string{ac.Dashboards[0].Name, ac.Dashboards[1].Name})
+	assert.Contains(t, ac.Dashboards[1].URL, fmt.Sprintf("from=%d&to=%d", from, ended.UnixMilli()))
+}
+
+func TestAttachDashboardsEndsAtNowWhileFiring(t *testing.T) {
+	cfg := &config.Config{Dashboards: []config.DashboardConfig{{Name: "Overview", URL: "https://grafana.example.com/d/abc?to={to}"}}}
+	o := New(nil, nil, nil, nil, cfg)
+
+	started := time.Now().Add(-10 * time.Minute)
+	// Alertmanager sets endsAt in the future on firing alerts
+	ac := &models.AnalysisContext{ServiceName: "checkout", Alert: models.AlertInfo{StartedAt: started, EndsAt: time.Now().Add(time.Hour)}}
+	before := time.Now().UnixMilli()
+	o.AttachDashboards(ac)
+	after := time.Now().UnixMilli()
+
+	require.Len(t, ac.Dashboards, 1)
+	var to int64
+	_, err := fmt.Sscanf(ac.Dashboards[0].URL, "https://grafana.example.com/d/abc?to=%d", &to)
+	require.NoError(t, err)
+	assert.True(t, to >= before && to <= after, "to=%d outside [%d, %d]", to, before, after)
+}
@@ -0,0 +1,230 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"helixops/internal/clients/tempo"
+	"helixops/internal/models"
+)
+
+// maxHypotheses caps how many suspected causes are handed to the LLM.
+const maxHypotheses = 5
+
+// deployWindow is how close to the alert a commit must land to be treated as a deploy suspect.
+const deployWindow = 2 * time.Hour
+
+// logSignatures maps well-known error phrases to the failure class they indicate.
+var logSignatures = []struct {
+	pattern *regexp.Regexp
+	cause   string
+}{
+	{regexp.MustCompile(`(?i)pool (is )?exhausted|too many connections|connection pool`), "Connection pool exhaustion"},
+	{regexp.MustCompile(`(?i)out of memory|oomkilled|heap space`), "Memory exhaustion"},
+	{regexp.MustCompile(`(?i)timed? ?out|deadline exceeded`), "Downstream timeouts"},
+	{regexp.MustCompile(`(?i)connection refused|no route to host|unavailable`), "Dependency unavailable"},
+	{regexp.MustCompile(`(?i)deadlock`), "Database lock contention"},
+}
+
+// digits normalizes variable parts of log lines so repeated errors group together.
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// Correlate scores candidate causes across metrics, commits, deploys, logs, and spans and returns them ranked.
+func Correlate(ac *models.AnalysisContext) []models.Hypothesis {
+	candidates := make(map[string]*models.Hypothesis)
+	add := func(cause string, score float64, evidence ...string) {
+		h, ok := candidates[cause]
+		if !ok {
+			h = &models.Hypothesis{Cause: cause}
+			candidates[cause] = h
+		}
+		h.Score += score
+		for _, e := range evidence {
+			if e != "" && !containsString(h.Evidence, e) {
+				h.Evidence = append(h.Evidence, e)
+			}
+		}
+	}
+
+	alertTime := ac.TimeWindow.End
+	if !ac.Alert.StartedAt.IsZero() {
+		alertTime = ac.Alert.StartedAt
+	}
+
+	metricEvidence, anomalous := ac.Metrics.Anomaly()
+	topPattern, patternCount := topLogPattern(ac.ErrorLogs)
+	logEvidence := ""
+	if topPattern != "" {
+		logEvidence = fmt.Sprintf("error logs mention %q (%d occurrences)", truncate(topPattern, 80), patternCount)
+	}
+
+	// Recent commits are the most common trigger; weight by proximity to the alert.
+	for _, c := range ac.RecentCommits {
+		if c.Timestamp.IsZero() || c.Timestamp.After(alertTime) {
+			continue
+		}
+		gap := alertTime.Sub(c.Timestamp)
+		if gap > deployWindow {
+			continue
+		}
+		score := 0.4 * (1 - gap.Hours()/deployWindow.Hours())
+		evidence := []string{fmt.Sprintf("Commit %s landed %s before the alert: %s", models.ShortSHA(c.SHA), gap.Round(time.Minute), truncate(firstLine(c.Message), 60))}
+		if anomalous {
+			score += 0.3
+			evidence = append(evidence, metricEvidence)
+		}
+		if logEvidence != "" {
+			score += 0.2
+			evidence = append(evidence, logEvidence)
+		}
+		add(fmt.Sprintf("Regression introduced by commit %s", models.ShortSHA(c.SHA)), score, evidence...)
+	}
+
+	// A deploy reported by the CD system pins down when a change actually went live, so it outweighs a commit.
+	for _, d := range ac.DeployEvents {
+		if d.Time.IsZero() || d.Time.After(alertTime) {
+			continue
+		}
+		gap := alertTime.Sub(d.Time)
+		if gap > deployWindow {
+			continue
+		}
+		score := 0.5 * (1 - gap.Hours()/deployWindow.Hours())
+		evidence := []string{fmt.Sprintf("Deploy %s at %s, alert at %s (%s later)", d.Label(), d.Time.UTC().Format("15:04"),
+			alertTime.UTC().Format("15:04"), gap.Round(time.Minute))}
+		if anomalous {
+			score += 0.3
+			evidence = append(evidence, metricEvidence)
+		}
+		if logEvidence != "" {
+			score += 0.2
+			evidence = append(evidence, logEvidence)
+		}
+		add(fmt.Sprintf("Regression introduced by deploy %s", d.Label()), score, evidence...)
+	}
+
+	// Error log signatures point to a failure class even without a code change.
+	if topPattern != "" {
+		cause := "Application errors: " + truncate(topPattern, 60)
+		for _, sig := range logSignatures {
+			if sig.pattern.MatchString(topPattern) {
+				cause = sig.cause
+				break
+			}
+		}
+		score := 0.2 + minFloat(float64(patternCount)/20, 0.3)
+		if anomalous {
+			add(cause, score+0.1, logEvidence, metricEvidence)
+		} else {
+			add(cause, score, logEvidence)
+		}
+	}
+
+	// Slow spans in another service suggest a downstream dependency is the bottleneck. Each operation
+	// counts once, with its slowest span as evidence, so one chatty operation does not dominate.
+	slowest := make(map[string]tempo.Span)
+	var operations []string
+	for _, span := range ac.Traces.SlowSpans {
+		if span.ServiceName == "" || span.ServiceName == ac.ServiceName {
+			continue
+		}
+		key := span.ServiceName + "/" + span.OperationName
+		prev, seen := slowest[key]
+		if !seen {
+			operations = append(operations, key)
+		}
+		if !seen || span.DurationMs > prev.DurationMs {
+			slowest[key] = span
+		}
+	}
+	for _, key := range operations {
+		span := slowest[key]
+		add(fmt.Sprintf("Slow downstream dependency %s", span.ServiceName), 0.25,
+			fmt.Sprintf("span %s took %dms", key, span.DurationMs))
+	}
+
+	// A traffic surge with no other explanation.
+	if ac.Metrics.BaselineRPS > 0 && ac.Metrics.RPS >= 2*ac.Metrics.BaselineRPS {
+		add("Traffic surge", 0.3, fmt.Sprintf("RPS %.1f vs baseline %.1f", ac.Metrics.RPS, ac.Metrics.BaselineRPS))
+	}
+
+	hypotheses := make([]models.Hypothesis, 0, len(candidates))
+	for _, h := range candidates {
+		if h.Score > 1 {
+			h.Score = 1
+		}
+		hypotheses = append(hypotheses, *h)
+	}
+	sort.SliceStable(hypotheses, func(i, j int) bool {
+		if hypotheses[i].Score != hypotheses[j].Score {
+			return hypotheses[i].Score > hypotheses[j].Score
+		}
+		return hypotheses[i].Cause < hypotheses[j].Cause
+	})
+	if len(hypotheses) > maxHypotheses {
+		hypotheses = hypotheses[:maxHypotheses]
+	}
+	return hypotheses
+}
+
+// topLogPattern returns the most frequent normalized error message and its count.
+func topLogPattern(logs []models.LogEntry) (string, int) {
+	counts := make(map[string]int)
+	examples := make(map[string]string)
+	for _, l := range logs {
+		key := digits.ReplaceAllString(strings.TrimSpace(l.Message), "N")
+		if key == "" {
+			continue
+		}
+		counts[key]++
+		if _, ok := examples[key]; !ok {
+			examples[key] = strings.TrimSpace(l.Message)
+		}
+	}
+
+	best, bestCount := "", 0
+	for key, n := range counts {
+		if n > bestCount || (n == bestCount && key < best) {
+			best, bestCount = key, n
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	return examples[best], bestCount
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// truncate shortens s to at most maxLen runes, so multi-byte characters are never split.
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen]) + "..."
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
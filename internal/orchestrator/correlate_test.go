@@ -0,0 +1,106 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"helixops/internal/clients/tempo"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelateRanksRecentCommitWithCorroboratingSignals(t *testing.T) {
+	alertTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ac := &models.AnalysisContext{
+		ServiceName: "checkout",
+		TimeWindow:  models.TimeWindow{End: alertTime},
+		Metrics: models.MetricsSummary{
+			LatencyP99:      840,
+			BaselineLatency: 200,
+		},
+		RecentCommits: []models.CommitInfo{
+			{SHA: "abc1234deadbeef", Message: "Shrink DB pool\n\nDetails", Timestamp: alertTime.Add(-4 * time.Minute)},
+			{SHA: "0000000old", Message: "Docs", Timestamp: alertTime.Add(-20 * time.Hour)},
+		},
+		ErrorLogs: []models.LogEntry{
+			{Message: "connection pool exhausted after 30s"},
+			{Message: "connection pool exhausted after 31s"},
+			{Message: "unrelated warning"},
+		},
+		Traces: tempo.TraceContext{
+			SlowSpans: []tempo.Span{{ServiceName: "payments-db", OperationName: "SELECT", DurationMs: 900}},
+		},
+	}
+
+	hypotheses := Correlate(ac)
+	require.NotEmpty(t, hypotheses)
+
+	top := hypotheses[0]
+	assert.Equal(t, "Regression introduced by commit abc1234", top.Cause)
+	assert.Contains(t, top.Evidence[0], "Commit abc1234 landed 4m0s before the alert: Shrink DB pool")
+	assert.Contains(t, top.Evidence, "latency p99 840ms is 4.2x baseline")
+	assert.Contains(t, top.Evidence[2], "connection pool exhausted")
+
+	var causes []string
+	for i, h := range hypotheses {
+		causes = append(causes, h.Cause)
+		if i > 0 {
+			assert.GreaterOrEqual(t, hypotheses[i-1].Score, h.Score)
+		}
+	}
+	assert.Contains(t, causes, "Connection pool exhaustion")
+	assert.Contains(t, causes, "Slow downstream dependency payments-db")
+	assert.NotContains(t, causes, "Regression introduced by commit 0000000")
+}
+
+func TestCorrelateEmptyContext(t *testing.T) {
+	assert.Empty(t, Correlate(&models.AnalysisContext{ServiceName: "idle"}))
+}
+
+func TestCorrelateDeduplicatesEvidence(t *testing.T) {
+	ac := &models.AnalysisContext{
+		ServiceName: "cart",
+		Traces: tempo.TraceContext{
+			SlowSpans: []tempo.Span{
+				{ServiceName: "redis", OperationName: "GET", DurationMs: 700},
+				{ServiceName: "redis", OperationName: "GET", DurationMs: 700},
+			},
+		},
+	}
+
+	hypotheses := Correlate(ac)
+	require.Len(t, hypotheses, 1)
+	assert.Len(t, hypotheses[0].Evidence, 1)
+	assert.InDelta(t, 0.25, hypotheses[0].Score, 0.001)
+}
+
+func TestCorrelateScoresSlowSpansPerOperation(t *testing.T) {
+	ac := &models.AnalysisContext{
+		ServiceName: "cart",
+		Traces: tempo.TraceContext{
+			SlowSpans: []tempo.Span{
+				{ServiceName: "redis", OperationName: "GET", DurationMs: 700},
+				{ServiceName: "redis", OperationName: "GET", DurationMs: 900},
+				{ServiceName: "redis", OperationName: "GET", DurationMs: 800},
+				{ServiceName: "redis", OperationName: "GET", DurationMs: 650},
+				{ServiceName: "payments", OperationName: "Charge", DurationMs: 1200},
+				{ServiceName: "payments", OperationName: "Refund", DurationMs: 1100},
+			},
+		},
+	}
+
+	hypotheses := Correlate(ac)
+	require.Len(t, hypotheses, 2)
+	assert.Equal(t, "Slow downstream dependency payments", hypotheses[0].Cause)
+	assert.InDelta(t, 0.5, hypotheses[0].Score, 0.001)
+	assert.Equal(t, "Slow downstream dependency redis", hypotheses[1].Cause)
+	assert.InDelta(t, 0.25, hypotheses[1].Score, 0.001)
+	assert.Equal(t, []string{"span redis/GET took 900ms"}, hypotheses[1].Evidence)
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	assert.Equal(t, "héllo", truncate("héllo", 5))
+	assert.Equal(t, "日本...", truncate("日本語のログ", 2))
+}
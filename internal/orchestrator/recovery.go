@@ -0,0 +1,90 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/models"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// recoveryTimeout bounds the recovery readings when analysis.enrichment_budget is not set. They run
+// after the alert resolved, so a slow Prometheus must not hold up the postmortem.
+const recoveryTimeout = 30 * time.Second
+
+// AttachRecovery reads the service's golden signals one metrics window before the alert started, when
+// it started, and after it resolved, and records on ac whether latency and error rate returned to the
+// pre-incident baseline. An alert can resolve while the service is still degraded, e.g. when a
+// threshold was tuned or traffic moved elsewhere, and the postmortem should say so. The reading after
+// resolution covers the rate window starting at resolution, since one ending there would still
+// average in the incident; when that window has not passed yet it is read now. The three readings
+// run concurrently under analysis.enrichment_budget, or recoveryTimeout when none is set. It needs
+// Prometheus and the alert's start time; query failures are recorded in SourceErrors.
+func (o *Orchestrator) AttachRecovery(ctx context.Context, ac *models.AnalysisContext) {
+	if o.promClient == nil || ac.Alert.StartedAt.IsZero() {
+		return
+	}
+	now := time.Now()
+	resolved := ac.Alert.EndsAt
+	if resolved.IsZero() || resolved.After(now) {
+		resolved = now
+	}
+	afterResolution := resolved.Add(prometheus.SignalsWindow)
+	if afterResolution.After(now) {
+		afterResolution = now
+	}
+
+	timeout := o.cfg.Analysis.GetEnrichmentBudgetDuration()
+	if timeout <= 0 {
+		timeout = recoveryTimeout
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	gctx, cancel := context.WithTimeout(gctx, timeout)
+	defer cancel()
+
+	var readings [3]prometheus.Signals
+	for i, t := range []time.Time{ac.Alert.StartedAt.Add(-o.cfg.Analysis.GetMetricsWindowDuration()), ac.Alert.StartedAt, afterResolution} {
+		g.Go(func() error {
+			signals, err := o.promClient.QuerySignalsAt(gctx, ac.ServiceName, t)
+			readings[i] = signals
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		slog.Warn("Error fetching data", "service", ac.ServiceName, "source", "recovery", "error", err)
+		if ac.SourceErrors == nil {
+			ac.SourceErrors = make(map[string]string)
+		}
+		ac.SourceErrors["recovery"] = err.Error()
+		return
+	}
+
+	recovery := &models.Recovery{Baseline: readings[0], AtStart: readings[1], AtResolution: readings[2]}
+	recovery.Status, recovery.Note = assessRecovery(recovery.Baseline, recovery.AtResolution)
+	ac.Recovery = recovery
+}
+
+// assessRecovery compares the signals at resolution with the baseline using the same thresholds
+// triage uses to call metrics anomalous.
+func assessRecovery(baseline, resolved prometheus.Signals) (string, string) {
+	if baseline.RPS == 0 && baseline.LatencyP99 == 0 {
+		return models.RecoveryUnknown, "Recovery could not be verified: the service had no traffic before the incident to compare against."
+	}
+	if baseline.RPS > 0 && resolved.RPS == 0 {
+		return models.RecoveryElevated, fmt.Sprintf("Not fully recovered: the service had no traffic at resolution (baseline %.1f req/s).", baseline.RPS)
+	}
+	summary := models.MetricsSummary{
+		LatencyP99:        resolved.LatencyP99,
+		ErrorRate:         resolved.ErrorRate,
+		BaselineLatency:   baseline.LatencyP99,
+		BaselineErrorRate: baseline.ErrorRate,
+	}
+	if anomaly, ok := summary.Anomaly(); ok {
+		return models.RecoveryElevated, "Not fully recovered: at resolution " + anomaly + "."
+	}
+	return models.RecoveryRecovered, "Latency and error rate returned to their pre-incident baseline."
+}
@@ -0,0 +1,79 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signalsServer answers the golden signal queries like Prometheus rates would: a reading at t covers
+// the rate window before t, so the incident's signals show until a whole window has passed since it
+// resolved.
+func signalsServer(t *testing.T, started, resolved time.Time, before, during, after prometheus.Signals) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		at, err := time.Parse(time.RFC3339, r.URL.Query().Get("time"))
+		require.NoError(t, err, "recovery readings must be evaluated at a fixed time")
+		signals := before
+		switch {
+		case !at.Add(-prometheus.SignalsWindow).Before(resolved):
+			signals = after
+		case !at.Before(started):
+			signals = during
+		}
+		value := signals.RPS
+		switch q := r.URL.Query().Get("query"); {
+		case strings.HasPrefix(q, "histogram_quantile"):
+			value = signals.LatencyP99
+		case strings.Contains(q, "status=~"):
+			value = signals.ErrorRate
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": [{"metric": {}, "value": [%d, "%g"]}]}}`, at.Unix(), value)
+	}))
+}
+
+func TestAttachRecoveryFlagsStillElevatedMetrics(t *testing.T) {
+	started := time.Now().Add(-time.Hour).Truncate(time.Second)
+	resolved := started.Add(30 * time.Minute)
+	baseline := prometheus.Signals{LatencyP99: 120, ErrorRate: 0.001, RPS: 40}
+	incident := prometheus.Signals{LatencyP99: 900, ErrorRate: 0.08, RPS: 40}
+
+	cases := []struct {
+		name   string
+		after  prometheus.Signals
+		status string
+		note   string
+	}{
+		{"back to baseline", prometheus.Signals{LatencyP99: 130, ErrorRate: 0.001, RPS: 41}, models.RecoveryRecovered, "returned to their pre-incident baseline"},
+		{"error rate still elevated", prometheus.Signals{LatencyP99: 130, ErrorRate: 0.05, RPS: 40}, models.RecoveryElevated, "Not fully recovered: at resolution error rate 5.00%"},
+		{"traffic gone", prometheus.Signals{}, models.RecoveryElevated, "Not fully recovered: the service had no traffic at resolution"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			promAPI := signalsServer(t, started, resolved, baseline, incident, tc.after)
+			defer promAPI.Close()
+
+			o := New(prometheus.NewClient(promAPI.URL, time.Second), nil, nil, nil, &config.Config{Analysis: config.AnalysisConfig{MetricsWindow: "15m"}})
+			ac := &models.AnalysisContext{ServiceName: "checkout", Alert: models.AlertInfo{StartedAt: started, EndsAt: resolved}}
+			o.AttachRecovery(context.Background(), ac)
+
+			require.NotNil(t, ac.Recovery)
+			assert.Equal(t, tc.status, ac.Recovery.Status)
+			assert.Contains(t, ac.Recovery.Note, tc.note)
+			assert.Equal(t, started.Add(-15*time.Minute), ac.Recovery.Baseline.Time)
+			assert.Equal(t, incident.LatencyP99, ac.Recovery.AtStart.LatencyP99)
+			assert.Equal(t, tc.after.ErrorRate, ac.Recovery.AtResolution.ErrorRate)
+			assert.Equal(t, resolved.Add(prometheus.SignalsWindow), ac.Recovery.AtResolution.Time)
+		})
+	}
+}
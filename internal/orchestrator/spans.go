@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"math"
+	"sort"
+
+	"helixops/internal/clients/tempo"
+)
+
+// aggregateOperations groups spans by operation and computes count, p99 latency, and error count.
+// Spans present in several lists (e.g. both slow and errored) are counted once. Results are ordered
+// by error count, then p99, so the worst offenders come first.
+func aggregateOperations(spanLists ...[]tempo.Span) []tempo.OperationStats {
+	seen := make(map[string]bool)
+	durations := make(map[string][]int64)
+	errorCounts := make(map[string]int)
+
+	for _, spans := range spanLists {
+		for _, s := range spans {
+			if s.SpanID != "" {
+				key := s.TraceID + "/" + s.SpanID
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			durations[s.OperationName] = append(durations[s.OperationName], s.DurationMs)
+			if s.IsError() {
+				errorCounts[s.OperationName]++
+			}
+		}
+	}
+
+	stats := make([]tempo.OperationStats, 0, len(durations))
+	for op, ds := range durations {
+		stats = append(stats, tempo.OperationStats{
+			Operation:  op,
+			Count:      len(ds),
+			P99Ms:      percentile(ds, 0.99),
+			ErrorCount: errorCounts[op],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].ErrorCount != stats[j].ErrorCount {
+			return stats[i].ErrorCount > stats[j].ErrorCount
+		}
+		if stats[i].P99Ms != stats[j].P99Ms {
+			return stats[i].P99Ms > stats[j].P99Ms
+		}
+		return stats[i].Operation < stats[j].Operation
+	})
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of the given durations.
+func percentile(durations []int64, p float64) float64 {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return float64(sorted[rank])
+}
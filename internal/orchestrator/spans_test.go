@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"helixops/internal/clients/tempo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateOperations(t *testing.T) {
+	var slow []tempo.Span
+	// 100 GET /cart spans: 1..100ms, so the nearest-rank p99 is 99ms
+	for i := 1; i <= 100; i++ {
+		slow = append(slow, tempo.Span{TraceID: "t", SpanID: fmt.Sprintf("s%d", i), OperationName: "GET /cart", DurationMs: int64(i), Status: "ok"})
+	}
+	errored := []tempo.Span{
+		{TraceID: "t2", SpanID: "1", OperationName: "db.query", DurationMs: 900, Status: "error"},
+		{TraceID: "t2", SpanID: "2", OperationName: "db.query", DurationMs: 1200, Status: "error"},
+		{TraceID: "t2", SpanID: "3", OperationName: "db.query", DurationMs: 300, Status: "ok"},
+	}
+	// The same span reported as both slow and errored is counted once
+	slow = append(slow, errored[1])
+
+	stats := aggregateOperations(slow, errored)
+
+	assert.Equal(t, []tempo.OperationStats{
+		{Operation: "db.query", Count: 3, P99Ms: 1200, ErrorCount: 2},
+		{Operation: "GET /cart", Count: 100, P99Ms: 99, ErrorCount: 0},
+	}, stats)
+}
+
+func TestAggregateOperationsEmpty(t *testing.T) {
+	assert.Empty(t, aggregateOperations(nil, nil))
+	assert.Equal(t, 0.0, percentile(nil, 0.99))
+}
+
+func TestAggregateOperationsDoesNotCountUnsetSpansAsErrors(t *testing.T) {
+	var spans []tempo.Span
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"traceID": "t", "spanID": "1", "operationName": "charge", "durationMs": 50, "status": {"code": "STATUS_CODE_UNSET"}},
+		{"traceID": "t", "spanID": "2", "operationName": "charge", "durationMs": 60, "statusCode": 0},
+		{"traceID": "t", "spanID": "3", "operationName": "charge", "durationMs": 70},
+		{"traceID": "t", "spanID": "4", "operationName": "charge", "durationMs": 80, "status": {"code": 2, "message": "card declined"}},
+		{"traceID": "t", "spanID": "5", "operationName": "charge", "durationMs": 90, "statusCode": "STATUS_CODE_ERROR"},
+		{"traceID": "t", "spanID": "6", "operationName": "charge", "durationMs": 40, "status": "ok"}
+	]`), &spans))
+
+	statuses := make([]string, len(spans))
+	for i, s := range spans {
+		statuses[i] = s.Status
+	}
+	assert.Equal(t, []string{tempo.SpanStatusUnset, tempo.SpanStatusUnset, "",
+		tempo.SpanStatusError, tempo.SpanStatusError, tempo.SpanStatusOK}, statuses)
+
+	stats := aggregateOperations(spans)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].ErrorCount, "only ERROR spans are errors; UNSET is the default status")
+}
@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"helixops/internal/clients/tempo"
+)
+
+// Query languages reported by ValidateQueries.
+const (
+	QueryLanguagePromQL  = "promql"
+	QueryLanguageLogQL   = "logql"
+	QueryLanguageTraceQL = "traceql"
+)
+
+// validationWindow is the time range log and trace queries are checked over; small enough to be
+// cheap, since only whether the backend accepts the query matters.
+const validationWindow = time.Minute
+
+// QueryCheck is the outcome of running one service's query against its backend.
+type QueryCheck struct {
+	Service  string `json:"service"`
+	Language string `json:"language"`
+	Query    string `json:"query"`
+	OK       bool   `json:"ok"`
+	// Error is the backend's rejection, e.g. a parse error or a 403 for a tenant without access
+	Error string `json:"error,omitempty"`
+}
+
+// ValidateQueries runs the PromQL status breakdown, LogQL selector, and TraceQL search of every
+// service under services against the configured backends over a short window, so a template that
+// does not parse or is not permitted shows up before an incident needs it. Backends that are not
+// configured are skipped. Checks are ordered by service, then language.
+func (o *Orchestrator) ValidateQueries(ctx context.Context) []QueryCheck {
+	services := make([]string, 0, len(o.cfg.Services))
+	for name := range o.cfg.Services {
+		services = append(services, name)
+	}
+	slices.Sort(services)
+
+	end := time.Now()
+	start := end.Add(-validationWindow)
+	var checks []QueryCheck
+	check := func(service, language, query string, err error) {
+		c := QueryCheck{Service: service, Language: language, Query: query, OK: err == nil}
+		if err != nil {
+			c.Error = err.Error()
+		}
+		checks = append(checks, c)
+	}
+
+	for _, service := range services {
+		if o.promClient != nil {
+			_, err := o.promClient.QueryStatusBreakdown(ctx, service, start, end)
+			check(service, QueryLanguagePromQL, o.promClient.StatusBreakdownQuery(service), err)
+		}
+		if o.lokiClient != nil {
+			selector := o.cfg.Services[service].GetLogSelector(service)
+			_, err := o.lokiClient.QueryErrorLogs(ctx, selector, start, end, 1)
+			check(service, QueryLanguageLogQL, selector, err)
+		}
+		if o.tempoClient != nil {
+			_, err := o.tempoClient.GetTracesByService(ctx, service, start, end)
+			check(service, QueryLanguageTraceQL, tempo.BuildServiceQuery(service), err)
+		}
+	}
+	return checks
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/loki"
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateQueriesReportsRejectedTemplates(t *testing.T) {
+	promAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": []}}`))
+	}))
+	defer promAPI.Close()
+	lokiAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("query"), "namespace=prod") {
+			http.Error(w, `parse error at line 1, col 22: syntax error: unexpected IDENTIFIER, expecting STRING`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"status": "success", "data": {"resultType": "streams", "result": []}}`))
+	}))
+	defer lokiAPI.Close()
+
+	cfg := &config.Config{Services: map[string]config.ServiceConfig{
+		"payments": {LogSelector: `{app="%s", namespace=prod}`},
+		"checkout": {LogSelector: `{app="%s"}`},
+	}}
+	o := New(prometheus.NewClient(promAPI.URL, time.Second), nil, loki.NewClient(lokiAPI.URL, time.Second), nil, cfg)
+
+	checks := o.ValidateQueries(context.Background())
+	require.Len(t, checks, 4, "one PromQL and one LogQL check per service; Tempo is not configured")
+
+	assert.Equal(t, QueryCheck{Service: "checkout", Language: QueryLanguageLogQL, Query: `{app="checkout"}`, OK: true}, checks[1])
+	invalid := checks[3]
+	assert.Equal(t, "payments", invalid.Service)
+	assert.Equal(t, QueryLanguageLogQL, invalid.Language)
+	assert.False(t, invalid.OK)
+	assert.Contains(t, invalid.Error, "400: parse error at line 1", "the backend's explanation is reported")
+
+	for _, c := range []QueryCheck{checks[0], checks[2]} {
+		assert.Equal(t, QueryLanguagePromQL, c.Language)
+		assert.True(t, c.OK, c.Service)
+	}
+	assert.Equal(t, "sum by (status) (rate(http_requests_total{service='payments'}[5m]))", checks[2].Query)
+}
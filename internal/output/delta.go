@@ -0,0 +1,55 @@
+package output
+
+import "fmt"
+
+// Deviation thresholds (current / baseline) for the Slack severity marker.
+const (
+	deltaCritical = 2.0
+	deltaHigh     = 1.25
+	deltaElevated = 1.1
+)
+
+// metricDelta renders a golden signal against its baseline for at-a-glance triage,
+// e.g. "🔴 1250.00ms ▲ 5.0x baseline (250.00ms)". Increases are treated as bad since
+// the signals shown (latency, error rate) only regress upwards.
+func metricDelta(current, baseline float64, format func(float64) string) string {
+	if baseline <= 0 {
+		if current <= 0 {
+			return fmt.Sprintf("🟢 %s (no change)", format(current))
+		}
+		// No baseline to divide by; a signal appearing from nothing is still worth flagging
+		return fmt.Sprintf("⚪ %s ▲ (no baseline)", format(current))
+	}
+
+	ratio := current / baseline
+	var change string
+	switch {
+	case ratio >= deltaCritical:
+		change = fmt.Sprintf("▲ %.1fx baseline", ratio)
+	case ratio > 1:
+		change = fmt.Sprintf("▲ +%.0f%% vs baseline", (ratio-1)*100)
+	case ratio < 1:
+		change = fmt.Sprintf("▼ -%.0f%% vs baseline", (1-ratio)*100)
+	default:
+		change = "= baseline"
+	}
+
+	return fmt.Sprintf("%s %s %s (%s)", deviationMarker(ratio), format(current), change, format(baseline))
+}
+
+// deviationMarker colour-codes how far a signal has moved above its baseline.
+func deviationMarker(ratio float64) string {
+	switch {
+	case ratio >= deltaCritical:
+		return "🔴"
+	case ratio >= deltaHigh:
+		return "🟠"
+	case ratio > deltaElevated:
+		return "🟡"
+	default:
+		return "🟢"
+	}
+}
+
+func formatMs(v float64) string      { return fmt.Sprintf("%.2fms", v) }
+func formatPercent(v float64) string { return fmt.Sprintf("%.2f%%", v*100) }
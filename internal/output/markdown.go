@@ -3,7 +3,7 @@ package output
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -57,7 +57,7 @@ func (m *MarkdownReporter) Report(result *models.AnalysisResult) error {
 		return fmt.Errorf("failed to write report: %w", err)
 	}
 
-	log.Printf("Report generated: %s", filePath)
+	slog.Info("Report generated", "path", filePath)
 	return nil
 }
 
@@ -77,10 +77,38 @@ func (m *MarkdownReporter) SendPostmortem(pm *postmortem.Postmortem) error {
 		return fmt.Errorf("failed to write postmortem: %w", err)
 	}
 
-	log.Printf("Postmortem generated: %s", filePath)
+	slog.Info("Postmortem generated", "path", filePath)
 	return nil
 }
 
+// PurgeBefore removes Markdown reports last modified before cutoff and returns how many were deleted.
+func (m *MarkdownReporter) PurgeBefore(cutoff time.Time) (int, error) {
+	if m.outputDir == "" {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(m.outputDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read output directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(m.outputDir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
 // buildReport creates the Markdown content
 func (m *MarkdownReporter) buildReport(result *models.AnalysisResult) string {
 	return fmt.Sprintf(`# Incident Report
@@ -92,9 +120,12 @@ func (m *MarkdownReporter) buildReport(result *models.AnalysisResult) string {
 | **Service** | %s |
 | **Alert** | %s |
 | **Severity** | %s |
+| **Blast Radius** | %s |
+| **Language** | %s |
 | **Started** | %s |
 | **Analyzed** | %s |
 | **Report ID** | %s |
+| **Dashboards** | %s |
 
 ## AI Analysis
 
@@ -132,10 +163,13 @@ func (m *MarkdownReporter) buildReport(result *models.AnalysisResult) string {
 `,
 		result.ServiceName,
 		result.AlertName,
-		result.Severity,
+		severityText(result),
+		blastRadiusText(result.BlastRadius),
+		languageText(result),
 		result.AnalyzedAt.Add(-time.Hour).Format(time.RFC3339),
 		result.AnalyzedAt.Format(time.RFC3339),
 		result.ID,
+		dashboardsText(result.Dashboards),
 		result.RootCause,
 		result.Confidence,
 		result.Metrics.LatencyP99,
@@ -148,6 +182,18 @@ func (m *MarkdownReporter) buildReport(result *models.AnalysisResult) string {
 	)
 }
 
+// dashboardsText links each dashboard for the overview table.
+func dashboardsText(links []models.DashboardLink) string {
+	if len(links) == 0 {
+		return "None configured"
+	}
+	parts := make([]string, 0, len(links))
+	for _, l := range links {
+		parts = append(parts, fmt.Sprintf("[%s](%s)", l.Name, l.URL))
+	}
+	return strings.Join(parts, ", ")
+}
+
 // formatCommits formats commits for the report
 func (m *MarkdownReporter) formatCommits(commits []models.CommitInfo) string {
 	if len(commits) == 0 {
@@ -157,7 +203,7 @@ func (m *MarkdownReporter) formatCommits(commits []models.CommitInfo) string {
 	result := "| SHA | Author | Message | Time |\n|------|--------|---------|------|\n"
 	for _, c := range commits {
 		timestamp := c.Timestamp.Format(time.RFC3339)
-		result += fmt.Sprintf("| `%s` | %s | %s | %s |\n", c.SHA[:7], c.Author, truncate(c.Message, 50), timestamp)
+		result += fmt.Sprintf("| `%s` | %s | %s | %s |\n", models.ShortSHA(c.SHA), c.Author, truncate(c.Message, 50), timestamp)
 	}
 	return result
 }
@@ -0,0 +1,151 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"helixops/internal/config"
+	"helixops/internal/httpx"
+	"helixops/internal/models"
+)
+
+// Root-cause categories that analyses are counted under.
+const (
+	RootCauseDeploy     = "deploy-related"
+	RootCauseSaturation = "resource-saturation"
+	RootCauseDependency = "dependency"
+	RootCauseUnknown    = "unknown"
+)
+
+// rootCauseKeywords are checked in order, so a deploy that exhausted a pool counts as deploy-related.
+var rootCauseKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{RootCauseDeploy, []string{"deploy", "commit", "release", "rollout", "roll out", "rollback", "config change", "configuration change", "regression", "merged", "new version"}},
+	{RootCauseSaturation, []string{"cpu", "memory", "oom", "saturat", "exhaust", "throttl", "disk", "pool", "capacity", "leak", "overload", "queue depth"}},
+	{RootCauseDependency, []string{"dependency", "downstream", "upstream", "third-party", "external", "database", "redis", "dns", "connection refused", "timed out", "timeout", "unavailable"}},
+}
+
+// ClassifyRootCause places a root cause into a category with a keyword match over its text. Text that
+// matches nothing, including an "INSUFFICIENT DATA" verdict, is unknown.
+func ClassifyRootCause(rootCause string) string {
+	text := strings.ToLower(rootCause)
+	if strings.Contains(text, "insufficient data") {
+		return RootCauseUnknown
+	}
+	for _, c := range rootCauseKeywords {
+		for _, k := range c.keywords {
+			if strings.Contains(text, k) {
+				return c.category
+			}
+		}
+	}
+	return RootCauseUnknown
+}
+
+// rootCauseKey identifies one series of the root-cause counter.
+type rootCauseKey struct {
+	service  string
+	category string
+}
+
+// RootCauseMetrics counts analyses by service and root-cause category. It renders itself in the
+// Prometheus text exposition format for /metrics and, when a Pushgateway is configured, pushes the
+// counters there after each analysis.
+type RootCauseMetrics struct {
+	mu     sync.Mutex
+	counts map[rootCauseKey]uint64
+
+	// pushURL is the Pushgateway group the counters replace; empty when only /metrics exposes them
+	pushURL string
+	client  *http.Client
+}
+
+// NewRootCauseMetrics returns an empty counter set for the output.metrics section.
+func NewRootCauseMetrics(cfg config.MetricsOutputConfig) *RootCauseMetrics {
+	m := &RootCauseMetrics{counts: make(map[rootCauseKey]uint64)}
+	if cfg.PushgatewayURL != "" {
+		m.pushURL = strings.TrimSuffix(cfg.PushgatewayURL, "/") + "/metrics/job/" + url.PathEscape(cfg.GetJob())
+		m.client = httpx.NewClient(cfg.GetTimeoutDuration())
+	}
+	return m
+}
+
+// Record classifies an analysis's root cause, counts it, and pushes the counters when a Pushgateway is
+// configured. A failed push is logged; the count is kept and goes out with the next push.
+func (m *RootCauseMetrics) Record(result *models.AnalysisResult) string {
+	category := ClassifyRootCause(result.RootCause)
+	m.mu.Lock()
+	m.counts[rootCauseKey{service: result.ServiceName, category: category}]++
+	m.mu.Unlock()
+
+	if m.pushURL != "" {
+		if err := m.push(); err != nil {
+			slog.Warn("Failed to push root cause metrics", "url", m.pushURL, "error", err)
+		}
+	}
+	return category
+}
+
+// Count reports how many analyses of service were placed in category.
+func (m *RootCauseMetrics) Count(service, category string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[rootCauseKey{service: service, category: category}]
+}
+
+// WriteTo renders every series in the Prometheus text exposition format.
+func (m *RootCauseMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	keys := make([]rootCauseKey, 0, len(m.counts))
+	for k := range m.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].category < keys[j].category
+	})
+
+	var b strings.Builder
+	b.WriteString("# HELP helixops_root_cause_analyses_total Completed analyses by service and root-cause category.\n")
+	b.WriteString("# TYPE helixops_root_cause_analyses_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "helixops_root_cause_analyses_total{service=%q,category=%q} %d\n", k.service, k.category, m.counts[k])
+	}
+	m.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// push replaces the Pushgateway group with the current counters.
+func (m *RootCauseMetrics) push() error {
+	var body bytes.Buffer
+	m.WriteTo(&body)
+
+	req, err := http.NewRequest(http.MethodPut, m.pushURL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return httpx.StatusError("pushgateway returned status", resp)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRootCause(t *testing.T) {
+	cases := map[string]string{
+		"Commit abc1234 reduced the DB connection pool from 50 to 5.":                RootCauseDeploy,
+		"The v2.3 rollout introduced a nil pointer dereference in the cart handler.": RootCauseDeploy,
+		"Pods were OOMKilled after memory climbed steadily for an hour.":             RootCauseSaturation,
+		"CPU throttling on checkout pods saturated the request workers.":             RootCauseSaturation,
+		"The payments provider's API started returning connection refused.":          RootCauseDependency,
+		"Calls to the downstream inventory service timed out.":                       RootCauseDependency,
+		"INSUFFICIENT DATA: no logs or commits were available.":                      RootCauseUnknown,
+		"": RootCauseUnknown,
+	}
+	for rootCause, want := range cases {
+		assert.Equal(t, want, ClassifyRootCause(rootCause), rootCause)
+	}
+}
+
+func TestRootCauseMetricsCountsAndPushes(t *testing.T) {
+	var pushedPath, pushed string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		body, _ := io.ReadAll(r.Body)
+		pushedPath, pushed = r.URL.Path, string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	m := NewRootCauseMetrics(config.MetricsOutputConfig{Enabled: true, PushgatewayURL: gateway.URL + "/"})
+	assert.Equal(t, RootCauseDeploy, m.Record(&models.AnalysisResult{ServiceName: "checkout", RootCause: "A recent deploy broke the cache."}))
+	m.Record(&models.AnalysisResult{ServiceName: "checkout", RootCause: "Commit 9f2e changed the retry policy."})
+	m.Record(&models.AnalysisResult{ServiceName: "checkout", RootCause: "Disk on the primary filled up."})
+	m.Record(&models.AnalysisResult{ServiceName: "cart", RootCause: "Redis was unavailable."})
+
+	assert.Equal(t, uint64(2), m.Count("checkout", RootCauseDeploy))
+	assert.Equal(t, uint64(1), m.Count("checkout", RootCauseSaturation))
+	assert.Equal(t, uint64(1), m.Count("cart", RootCauseDependency))
+	assert.Zero(t, m.Count("cart", RootCauseDeploy))
+
+	var b strings.Builder
+	_, err := m.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Contains(t, b.String(), "# TYPE helixops_root_cause_analyses_total counter\n")
+	assert.Contains(t, b.String(), `helixops_root_cause_analyses_total{service="checkout",category="deploy-related"} 2`)
+
+	assert.Equal(t, "/metrics/job/helixops", pushedPath)
+	assert.Equal(t, b.String(), pushed, "each push carries every counter")
+}
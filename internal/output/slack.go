@@ -5,29 +5,57 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"helixops/internal/config"
+	"helixops/internal/httpx"
 	"helixops/internal/models"
 	"helixops/internal/postmortem"
 )
 
 // SlackSender handles the dispatch of rich-text incident notifications to a Slack webhook.
+// When configured with a bot token it uses chat.postMessage instead, which allows threading
+// the RCA and postmortem under the original firing message.
 type SlackSender struct {
 	webhookURL string
+	botToken   string
+	channel    string
+	apiURL     string
 	client     *http.Client
+	// maxText caps the analysis text, which Slack rejects above 3000 characters per section
+	maxText int
 }
 
+// defaultSlackAPIURL is the Slack Web API root used in bot-token mode.
+const defaultSlackAPIURL = "https://slack.com/api"
+
 // NewSlackSender initializes a SlackSender with a configured webhook URL and HTTP client.
 func NewSlackSender(webhookURL string) *SlackSender {
 	return &SlackSender{
 		webhookURL: webhookURL,
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client:     httpx.NewClient(10 * time.Second),
+		maxText:    config.MaxSlackTextChars,
+	}
+}
+
+// NewSlackBotSender initializes a SlackSender that posts through chat.postMessage with a bot token.
+func NewSlackBotSender(botToken, channel string) *SlackSender {
+	return &SlackSender{
+		botToken: botToken,
+		channel:  channel,
+		apiURL:   defaultSlackAPIURL,
+		client:   httpx.NewClient(10 * time.Second),
+		maxText:  config.MaxSlackTextChars,
 	}
 }
 
+// Threaded reports whether messages can be posted as thread replies (bot-token mode).
+func (s *SlackSender) Threaded() bool {
+	return s.botToken != ""
+}
+
 // SlackBlock represents a Slack message block
 type SlackBlock struct {
 	Type      string            `json:"type"`
@@ -57,69 +85,236 @@ type SlackAccessory struct {
 
 // SlackMessage represents a Slack message
 type SlackMessage struct {
-	Blocks []SlackBlock `json:"blocks"`
+	Channel  string       `json:"channel,omitempty"`
+	Text     string       `json:"text,omitempty"`
+	ThreadTS string       `json:"thread_ts,omitempty"`
+	Blocks   []SlackBlock `json:"blocks"`
+}
+
+// slackPostMessageResponse captures the fields of a chat.postMessage reply we rely on.
+type slackPostMessageResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	TS    string `json:"ts"`
 }
 
 // SendPostmortem sends a generated postmortem to Slack
 func (s *SlackSender) SendPostmortem(pm *postmortem.Postmortem) error {
-	if s.webhookURL == "" {
-		return fmt.Errorf("slack webhook URL not configured")
+	return s.SendPostmortemInThread(pm, "")
+}
+
+// SendPostmortemInThread sends a postmortem as a reply to threadTS when threading is available.
+func (s *SlackSender) SendPostmortemInThread(pm *postmortem.Postmortem, threadTS string) error {
+	_, err := s.post(s.buildPostmortemMessage(pm), threadTS)
+	return err
+}
+
+// SendAnalysis sends an analysis result to Slack
+func (s *SlackSender) SendAnalysis(result *models.AnalysisResult) error {
+	return s.SendAnalysisInThread(result, "")
+}
+
+// SendAnalysisInThread sends an analysis result as a reply to threadTS when threading is available.
+func (s *SlackSender) SendAnalysisInThread(result *models.AnalysisResult, threadTS string) error {
+	_, err := s.post(s.buildMessage(result), threadTS)
+	return err
+}
+
+// SendNoAnomaly posts a one-line note for an alert whose context showed no sign of a problem, in
+// place of the full RCA message.
+func (s *SlackSender) SendNoAnomaly(result *models.AnalysisResult, threadTS string) error {
+	_, err := s.post(s.buildNoAnomalyMessage(result), threadTS)
+	return err
+}
+
+// SendRecurring posts the short notice that replaces the full analysis while a service keeps
+// producing the same root cause.
+func (s *SlackSender) SendRecurring(result *models.AnalysisResult, threadTS string) error {
+	_, err := s.post(s.buildRecurringMessage(result), threadTS)
+	return err
+}
+
+// SendFiring posts the initial firing notification and returns its message timestamp, which
+// later replies use as thread_ts. The timestamp is empty in webhook mode.
+func (s *SlackSender) SendFiring(serviceName string, alert models.AlertInfo) (string, error) {
+	return s.post(s.buildFiringMessage(serviceName, alert), "")
+}
+
+// SendDigest posts a periodic incident digest as a standalone message.
+func (s *SlackSender) SendDigest(text string) error {
+	_, err := s.post(SlackMessage{
+		Text: text,
+		Blocks: []SlackBlock{
+			{
+				Type: "section",
+				Text: &SlackText{
+					Type: "mrkdwn",
+					Text: "*📊 Incident digest*\n" + text,
+				},
+			},
+		},
+	}, "")
+	return err
+}
+
+// post delivers a message via chat.postMessage (bot mode) or the incoming webhook.
+func (s *SlackSender) post(message SlackMessage, threadTS string) (string, error) {
+	if s.botToken == "" && s.webhookURL == "" {
+		return "", fmt.Errorf("slack webhook URL not configured")
+	}
+
+	url := s.webhookURL
+	if s.botToken != "" {
+		url = s.apiURL + "/chat.postMessage"
+		message.Channel = s.channel
+		message.ThreadTS = threadTS
+		if message.Text == "" && len(message.Blocks) > 0 && message.Blocks[0].Text != nil {
+			message.Text = message.Blocks[0].Text.Text
+		}
 	}
 
-	message := s.buildPostmortemMessage(pm)
 	body, err := json.Marshal(message)
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return "", fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewBuffer(body))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if s.botToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.botToken)
+	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+		return "", fmt.Errorf("failed to send message: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack returned status: %d", resp.StatusCode)
+		return "", fmt.Errorf("slack returned status: %d", resp.StatusCode)
 	}
 
-	return nil
-}
-
-// SendAnalysis sends an analysis result to Slack
-func (s *SlackSender) SendAnalysis(result *models.AnalysisResult) error {
-	if s.webhookURL == "" {
-		return fmt.Errorf("slack webhook URL not configured")
+	if s.botToken == "" {
+		return "", nil
 	}
 
-	message := s.buildMessage(result)
-	body, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+	var result slackPostMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode slack response: %w", err)
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack API error: %s", result.Error)
 	}
+	return result.TS, nil
+}
 
-	req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+// buildFiringMessage creates the short notification that opens an incident thread.
+func (s *SlackSender) buildFiringMessage(serviceName string, alert models.AlertInfo) SlackMessage {
+	return SlackMessage{
+		Blocks: []SlackBlock{
+			{
+				Type: "header",
+				Text: &SlackText{
+					Type: "plain_text",
+					Text: fmt.Sprintf("🔥 Firing: %s on %s", alert.Name, serviceName),
+				},
+			},
+			{
+				Type: "section",
+				Fields: []SlackField{
+					{
+						Type: "mrkdwn",
+						Text: fmt.Sprintf("*Severity:*\n%s", alert.Severity),
+					},
+					{
+						Type: "mrkdwn",
+						Text: fmt.Sprintf("*Started:*\n%s", alert.StartedAt.Format(time.RFC3339)),
+					},
+				},
+			},
+			{
+				Type: "context",
+				Fields: []SlackField{
+					{
+						Type: "mrkdwn",
+						Text: fmt.Sprintf("%s — analysis in progress, updates will follow in this thread.", alert.Summary),
+					},
+				},
+			},
+		},
 	}
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+// buildNoAnomalyMessage creates the compact notification for an alert without any anomaly.
+func (s *SlackSender) buildNoAnomalyMessage(result *models.AnalysisResult) SlackMessage {
+	return SlackMessage{
+		Blocks: []SlackBlock{
+			{
+				Type: "section",
+				Text: &SlackText{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("✅ *No anomaly detected* for %s on %s (%s): %s.", result.AlertName, result.ServiceName, result.Severity, result.TriageReason),
+				},
+			},
+			{
+				Type: "context",
+				Fields: []SlackField{
+					{
+						Type: "mrkdwn",
+						Text: fmt.Sprintf("Analyzed at: %s | ID: %s", result.AnalyzedAt.Format(time.RFC3339), result.ID),
+					},
+				},
+			},
+		},
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack returned status: %d", resp.StatusCode)
+// buildRecurringMessage creates the muted notification for a root cause seen again on the same service.
+func (s *SlackSender) buildRecurringMessage(result *models.AnalysisResult) SlackMessage {
+	rootCause := strings.Join(strings.Fields(result.RootCause), " ")
+	if len(rootCause) > 200 {
+		rootCause = rootCause[:200] + "..."
+	}
+	return SlackMessage{
+		Blocks: []SlackBlock{
+			{
+				Type: "section",
+				Text: &SlackText{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("🔁 *Recurring: same cause, %s time* for %s on %s (%s): %s", ordinal(result.Recurrence), result.AlertName, result.ServiceName, result.Severity, rootCause),
+				},
+			},
+			{
+				Type: "context",
+				Fields: []SlackField{
+					{
+						Type: "mrkdwn",
+						Text: fmt.Sprintf("Full analyses are muted while the cause repeats | Analyzed at: %s | ID: %s", result.AnalyzedAt.Format(time.RFC3339), result.ID),
+					},
+				},
+			},
+		},
 	}
+}
 
-	return nil
+// ordinal renders n as 1st, 2nd, 3rd, 4th, ..., 11th, 12th, 13th, 21st.
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
 }
 
 // buildMessage constructs a visually formatted Slack block kit payload from an analysis result.
@@ -130,8 +325,9 @@ func (s *SlackSender) buildMessage(result *models.AnalysisResult) SlackMessage {
 	} else if result.Severity == "warning" {
 		emoji = "⚠️"
 	}
+	analysis, _ := postmortem.TruncateBody(result.RootCause, s.maxText)
 
-	return SlackMessage{
+	msg := SlackMessage{
 		Blocks: []SlackBlock{
 			{
 				Type: "header",
@@ -145,7 +341,7 @@ func (s *SlackSender) buildMessage(result *models.AnalysisResult) SlackMessage {
 				Fields: []SlackField{
 					{
 						Type: "mrkdwn",
-						Text: fmt.Sprintf("*Severity:*\n%s", result.Severity),
+						Text: "*Severity:*\n" + severityText(result),
 					},
 					{
 						Type: "mrkdwn",
@@ -157,7 +353,7 @@ func (s *SlackSender) buildMessage(result *models.AnalysisResult) SlackMessage {
 				Type: "section",
 				Text: &SlackText{
 					Type: "mrkdwn",
-					Text: fmt.Sprintf("*AI Analysis:*\n%s", result.RootCause),
+					Text: fmt.Sprintf("*AI Analysis:*\n%s", analysis),
 				},
 			},
 			{
@@ -165,11 +361,15 @@ func (s *SlackSender) buildMessage(result *models.AnalysisResult) SlackMessage {
 				Fields: []SlackField{
 					{
 						Type: "mrkdwn",
-						Text: fmt.Sprintf("*Latency:*\n%.2fms (baseline: %.2fms)", result.Metrics.LatencyP99, result.Metrics.BaselineLatency),
+						Text: "*Latency:*\n" + metricDelta(result.Metrics.LatencyP99, result.Metrics.BaselineLatency, formatMs),
 					},
 					{
 						Type: "mrkdwn",
-						Text: fmt.Sprintf("*Error Rate:*\n%.2f%% (baseline: %.2f%%)", result.Metrics.ErrorRate*100, result.Metrics.BaselineErrorRate*100),
+						Text: "*Error Rate:*\n" + metricDelta(result.Metrics.ErrorRate, result.Metrics.BaselineErrorRate, formatPercent),
+					},
+					{
+						Type: "mrkdwn",
+						Text: "*Blast Radius:*\n" + blastRadiusText(result.BlastRadius),
 					},
 				},
 			},
@@ -181,17 +381,94 @@ func (s *SlackSender) buildMessage(result *models.AnalysisResult) SlackMessage {
 				Fields: []SlackField{
 					{
 						Type: "mrkdwn",
-						Text: fmt.Sprintf("Analyzed at: %s | ID: %s", result.AnalyzedAt.Format(time.RFC3339), result.ID),
+						Text: analysisFooter(result),
 					},
 				},
 			},
 		},
 	}
+	if len(result.Dashboards) > 0 {
+		// Above the divider, next to the metrics the dashboards expand on
+		msg.Blocks = slices.Insert(msg.Blocks, len(msg.Blocks)-2, dashboardsBlock(result.Dashboards))
+	}
+	return msg
 }
 
-// NewSlackSenderFromConfig constructs a SlackSender using the provided configuration block.
+// dashboardsBlock links each dashboard by name.
+func dashboardsBlock(links []models.DashboardLink) SlackBlock {
+	parts := make([]string, 0, len(links))
+	for _, l := range links {
+		parts = append(parts, fmt.Sprintf("<%s|%s>", l.URL, l.Name))
+	}
+	return SlackBlock{
+		Type: "section",
+		Text: &SlackText{
+			Type: "mrkdwn",
+			Text: "*Dashboards:*\n" + strings.Join(parts, " · "),
+		},
+	}
+}
+
+// severityText shows the model's reassessment next to the alert severity when they disagree.
+func severityText(result *models.AnalysisResult) string {
+	if result.AssessedSeverity == "" || result.AssessedSeverity == result.Severity {
+		return result.Severity
+	}
+	return fmt.Sprintf("%s (assessed: %s)", result.Severity, result.AssessedSeverity)
+}
+
+// analysisFooter identifies the analysis and, when triage ran, how the alert was classified.
+func analysisFooter(result *models.AnalysisResult) string {
+	footer := fmt.Sprintf("Analyzed at: %s | ID: %s", result.AnalyzedAt.Format(time.RFC3339), result.ID)
+	if result.Triage != "" {
+		footer += fmt.Sprintf(" | Triage: %s (%s)", result.Triage, result.TriageReason)
+	}
+	if result.PriorIncidents > 0 {
+		footer += fmt.Sprintf(" | Recurrence: %d prior incident(s)", result.PriorIncidents)
+	}
+	if lang := languageText(result); lang != "English" {
+		footer += " | Language: " + lang
+	}
+	return footer
+}
+
+// languageText is the language the analysis was written in; results from before localization are English.
+func languageText(result *models.AnalysisResult) string {
+	if result.Language == "" {
+		return "English"
+	}
+	return result.Language
+}
+
+// blastRadiusText summarizes the other services an incident reached and the request rate it affected.
+func blastRadiusText(br *models.BlastRadius) string {
+	if br == nil {
+		return "Not estimated"
+	}
+	services := "no other services"
+	switch n := len(br.Services); {
+	case n == 1:
+		services = "1 other service (" + br.Services[0] + ")"
+	case n > 1:
+		services = fmt.Sprintf("%d other services (%s)", n, strings.Join(br.Services, ", "))
+	}
+	return fmt.Sprintf("%s, %.1f req/s affected", services, br.AffectedRPS)
+}
+
+// NewSlackSenderFromConfig constructs a SlackSender using the provided configuration block,
+// preferring bot-token mode when both a token and a channel are configured.
 func NewSlackSenderFromConfig(cfg config.SlackOutputConfig) *SlackSender {
-	return NewSlackSender(cfg.WebhookURL)
+	var sender *SlackSender
+	if cfg.BotToken != "" && cfg.Channel != "" {
+		sender = NewSlackBotSender(cfg.BotToken, cfg.Channel)
+		if cfg.APIURL != "" {
+			sender.apiURL = strings.TrimSuffix(cfg.APIURL, "/")
+		}
+	} else {
+		sender = NewSlackSender(cfg.WebhookURL)
+	}
+	sender.maxText = cfg.GetMaxTextChars()
+	return sender
 }
 
 // buildPostmortemMessage creates a Slack message from a postmortem
@@ -209,7 +486,7 @@ func (s *SlackSender) buildPostmortemMessage(pm *postmortem.Postmortem) SlackMes
 			Fields: []SlackField{
 				{
 					Type: "mrkdwn",
-					Text: fmt.Sprintf("*Duration:*\n%s", pm.Duration.String()),
+					Text: fmt.Sprintf("*Duration:*\n%s", pm.FormattedDuration()),
 				},
 				{
 					Type: "mrkdwn",
@@ -226,6 +503,39 @@ func (s *SlackSender) buildPostmortemMessage(pm *postmortem.Postmortem) SlackMes
 		},
 	}
 
+	if pm.Recovery != nil && pm.Recovery.Status == models.RecoveryElevated {
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{
+				Type: "mrkdwn",
+				Text: ":warning: *" + pm.Recovery.Note + "*",
+			},
+		})
+	}
+
+	if len(pm.Dashboards) > 0 {
+		blocks = append(blocks, dashboardsBlock(pm.Dashboards))
+	}
+
+	if len(pm.ActionItems) > 0 {
+		var lines []string
+		for i, item := range pm.ActionItems {
+			if i >= 5 { // Full list lives in the postmortem
+				lines = append(lines, fmt.Sprintf("_…and %d more in the postmortem_", len(pm.ActionItems)-i))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("• *%s* %s", item.ID, item.Text))
+		}
+		blocks = append(blocks, SlackBlock{Type: "divider"})
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{
+				Type: "mrkdwn",
+				Text: "*Action Items*\n" + strings.Join(lines, "\n"),
+			},
+		})
+	}
+
 	if len(pm.RemediationRules) > 0 {
 		blocks = append(blocks, SlackBlock{Type: "divider"})
 		blocks = append(blocks, SlackBlock{
@@ -240,11 +550,15 @@ func (s *SlackSender) buildPostmortemMessage(pm *postmortem.Postmortem) SlackMes
 			if i >= 3 { // Limit to top 3 rules
 				break
 			}
+			text := fmt.Sprintf(">*%s: %s*\n>%s\n>`%s`", rule.ID, rule.Title, rule.Description, rule.Action)
+			if rule.RequiresConfirmation {
+				text += "\n>:warning: _Requires confirmation: changes the running system_"
+			}
 			blocks = append(blocks, SlackBlock{
 				Type: "section",
 				Text: &SlackText{
 					Type: "mrkdwn",
-					Text: fmt.Sprintf(">*%s*\n>%s\n>`%s`", rule.Title, rule.Description, rule.Action),
+					Text: text,
 				},
 			})
 		}
@@ -0,0 +1,197 @@
+package output
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSlackAPI records chat.postMessage calls and hands out sequential message timestamps.
+type fakeSlackAPI struct {
+	mu       sync.Mutex
+	messages []SlackMessage
+	auth     []string
+}
+
+func (f *fakeSlackAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var msg SlackMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.messages = append(f.messages, msg)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	ts := "1700000000.00000" + string(rune('0'+len(f.messages)))
+	f.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": ts})
+}
+
+func TestSlackBotSenderThreadsReplies(t *testing.T) {
+	api := &fakeSlackAPI{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	sender := NewSlackBotSender("xoxb-test", "#incidents")
+	sender.apiURL = srv.URL
+	require.True(t, sender.Threaded())
+
+	ts, err := sender.SendFiring("checkout", models.AlertInfo{Name: "HighLatency", Severity: "critical", StartedAt: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(t, "1700000000.000001", ts)
+
+	require.NoError(t, sender.SendAnalysisInThread(&models.AnalysisResult{ServiceName: "checkout", AlertName: "HighLatency"}, ts))
+	require.NoError(t, sender.SendPostmortemInThread(&postmortem.Postmortem{IncidentName: "HighLatency", RootCause: "pool exhaustion"}, ts))
+
+	require.Len(t, api.messages, 3)
+	assert.Empty(t, api.messages[0].ThreadTS)
+	assert.Equal(t, ts, api.messages[1].ThreadTS)
+	assert.Equal(t, ts, api.messages[2].ThreadTS)
+	for i, msg := range api.messages {
+		assert.Equal(t, "#incidents", msg.Channel)
+		assert.NotEmpty(t, msg.Text)
+		assert.Equal(t, "Bearer xoxb-test", api.auth[i])
+	}
+}
+
+func TestSlackBotSenderAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "channel_not_found"})
+	}))
+	defer srv.Close()
+
+	sender := NewSlackBotSender("xoxb-test", "#missing")
+	sender.apiURL = srv.URL
+
+	_, err := sender.SendFiring("checkout", models.AlertInfo{Name: "HighLatency"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel_not_found")
+}
+
+func TestSlackWebhookSenderIsNotThreaded(t *testing.T) {
+	var got SlackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	sender := NewSlackSender(srv.URL)
+	assert.False(t, sender.Threaded())
+	require.NoError(t, sender.SendAnalysisInThread(&models.AnalysisResult{ServiceName: "checkout"}, "123.456"))
+	assert.Empty(t, got.ThreadTS)
+	assert.Empty(t, got.Channel)
+}
+
+func TestPostmortemMessageReferencesActionItemIDs(t *testing.T) {
+	pm := &postmortem.Postmortem{
+		IncidentName: "Incident: HighLatency on checkout",
+		ActionItems:  []postmortem.ActionItem{{ID: "AI-1", Text: "Restore the DB pool size"}},
+		RemediationRules: []remediation.Suggestion{
+			{ID: "REM-1", Title: "Check Database Query Performance", Action: "review slow queries"},
+		},
+	}
+
+	body, err := json.Marshal(NewSlackSender("http://unused").buildPostmortemMessage(pm))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "*AI-1* Restore the DB pool size")
+	assert.Contains(t, string(body), "REM-1: Check Database Query Performance")
+}
+
+func TestAnalysisMessageShowsMetricDeltas(t *testing.T) {
+	msg := NewSlackSender("").buildMessage(&models.AnalysisResult{
+		ServiceName: "checkout",
+		AlertName:   "HighLatency",
+		Metrics: models.MetricsSummary{
+			LatencyP99:        1250,
+			BaselineLatency:   250,
+			ErrorRate:         0.02,
+			BaselineErrorRate: 0,
+		},
+	})
+
+	var fields []string
+	for _, block := range msg.Blocks {
+		for _, f := range block.Fields {
+			fields = append(fields, f.Text)
+		}
+	}
+	assert.Contains(t, fields, "*Latency:*\n🔴 1250.00ms ▲ 5.0x baseline (250.00ms)")
+	assert.Contains(t, fields, "*Error Rate:*\n⚪ 2.00% ▲ (no baseline)")
+}
+
+func TestAnalysisMessageLinksDashboards(t *testing.T) {
+	msg := NewSlackSender("").buildMessage(&models.AnalysisResult{
+		ServiceName: "checkout",
+		AlertName:   "HighLatency",
+		Dashboards: []models.DashboardLink{
+			{Name: "Overview", URL: "https://grafana.example.com/d/abc?from=1&to=2"},
+			{Name: "Database", URL: "https://grafana.example.com/d/db?from=1&to=2"},
+		},
+	})
+
+	// Linked just above the divider and footer
+	links := msg.Blocks[len(msg.Blocks)-3]
+	require.NotNil(t, links.Text)
+	assert.Equal(t, "*Dashboards:*\n<https://grafana.example.com/d/abc?from=1&to=2|Overview> · <https://grafana.example.com/d/db?from=1&to=2|Database>", links.Text.Text)
+}
+
+func TestAnalysisMessageTruncatesLongAnalysis(t *testing.T) {
+	sender := NewSlackSenderFromConfig(config.SlackOutputConfig{WebhookURL: "http://unused", MaxTextChars: 500})
+	msg := sender.buildMessage(&models.AnalysisResult{
+		ServiceName: "checkout",
+		AlertName:   "HighLatency",
+		RootCause:   "## 1. Summary\n" + strings.Repeat("Pool exhausted again.\n", 500) + "## 3. Root Cause\nPool size.\n",
+	})
+
+	analysis := msg.Blocks[2].Text.Text
+	assert.Less(t, len(analysis), 3000, "Slack rejects section text over 3000 characters")
+	assert.Contains(t, analysis, "**Truncated:** the response was longer than 500 characters")
+	assert.Contains(t, analysis, "## 3. Root Cause\n_Omitted (truncated)._")
+}
+
+func TestBlastRadiusText(t *testing.T) {
+	assert.Equal(t, "Not estimated", blastRadiusText(nil))
+	assert.Equal(t, "no other services, 12.0 req/s affected", blastRadiusText(&models.BlastRadius{AffectedRPS: 12}))
+	assert.Equal(t, "2 other services (frontend, payments), 42.5 req/s affected",
+		blastRadiusText(&models.BlastRadius{Services: []string{"frontend", "payments"}, AffectedRPS: 42.5}))
+}
+
+func TestMetricDelta(t *testing.T) {
+	cases := []struct {
+		current, baseline float64
+		want              string
+	}{
+		{1250, 250, "🔴 1250.00ms ▲ 5.0x baseline (250.00ms)"},
+		{300, 200, "🟠 300.00ms ▲ +50% vs baseline (200.00ms)"},
+		{230, 200, "🟡 230.00ms ▲ +15% vs baseline (200.00ms)"},
+		{200, 200, "🟢 200.00ms = baseline (200.00ms)"},
+		{120, 200, "🟢 120.00ms ▼ -40% vs baseline (200.00ms)"},
+		{0, 0, "🟢 0.00ms (no change)"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, metricDelta(tc.current, tc.baseline, formatMs))
+	}
+}
+
+func TestRecurringMessageCountsOccurrences(t *testing.T) {
+	sender := NewSlackSender("http://unused")
+	msg := sender.buildRecurringMessage(&models.AnalysisResult{ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+		RootCause: "Connection pool\nexhausted", Recurrence: 3})
+	assert.Contains(t, msg.Blocks[0].Text.Text, "Recurring: same cause, 3rd time* for HighLatency on checkout (critical): Connection pool exhausted")
+
+	for n, want := range map[int]string{1: "1st", 2: "2nd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 22: "22nd", 101: "101st"} {
+		assert.Equal(t, want, ordinal(n))
+	}
+}
@@ -0,0 +1,204 @@
+package output
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/httpx"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+)
+
+// Headers set on every outbound webhook request.
+const (
+	// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the raw body, keyed with
+	// the shared secret. It is omitted when no secret is configured.
+	SignatureHeader = "X-HelixOps-Signature"
+	// EventHeader names the event type, mirroring WebhookEvent.Type
+	EventHeader = "X-HelixOps-Event"
+)
+
+// maxWebhookBackoff caps the doubling wait between delivery attempts.
+const maxWebhookBackoff = 30 * time.Second
+
+// Webhook event types.
+const (
+	WebhookEventAnalysis   = "analysis"
+	WebhookEventPostmortem = "postmortem"
+)
+
+// WebhookEvent is the JSON body POSTed by WebhookSender; exactly one of Analysis or Postmortem is set.
+type WebhookEvent struct {
+	Type       string                 `json:"type"`
+	SentAt     time.Time              `json:"sent_at"`
+	Analysis   *models.AnalysisResult `json:"analysis,omitempty"`
+	Postmortem *PostmortemPayload     `json:"postmortem,omitempty"`
+}
+
+// PostmortemPayload is the JSON form of a generated postmortem.
+type PostmortemPayload struct {
+	ID              string                   `json:"id"`
+	IncidentName    string                   `json:"incident_name"`
+	Date            time.Time                `json:"date"`
+	DurationSeconds float64                  `json:"duration_seconds"`
+	RootCause       string                   `json:"root_cause"`
+	Impact          string                   `json:"impact,omitempty"`
+	ActionItems     []WebhookActionItem      `json:"action_items,omitempty"`
+	Remediations    []WebhookRemediationItem `json:"remediations,omitempty"`
+	Markdown        string                   `json:"markdown"`
+	Language        string                   `json:"language,omitempty"`
+	// DurationSuspect explains why duration_seconds was rejected (0) or capped
+	DurationSuspect string `json:"duration_suspect,omitempty"`
+	// Dashboards link the service's dashboards over the incident window
+	Dashboards []models.DashboardLink `json:"dashboards,omitempty"`
+	// Recovery compares the signals at resolution with the pre-incident baseline
+	Recovery *models.Recovery `json:"recovery,omitempty"`
+}
+
+// WebhookActionItem is a postmortem follow-up task.
+type WebhookActionItem struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// WebhookRemediationItem is a rule-based remediation attached to a postmortem.
+type WebhookRemediationItem struct {
+	ID                   string `json:"id"`
+	Title                string `json:"title"`
+	Action               string `json:"action,omitempty"`
+	RequiresConfirmation bool   `json:"requires_confirmation,omitempty"`
+}
+
+// WebhookSender POSTs analysis results and postmortems to a user-supplied endpoint, signing each
+// body so the receiver can verify it came from HelixOps.
+type WebhookSender struct {
+	url     string
+	secret  []byte
+	headers map[string]string
+	retries int
+	backoff time.Duration
+	client  *http.Client
+	now     func() time.Time
+}
+
+// NewWebhookSender initializes a WebhookSender from the output.webhook config section.
+func NewWebhookSender(cfg config.WebhookOutputConfig) *WebhookSender {
+	return &WebhookSender{
+		url:     cfg.URL,
+		secret:  []byte(cfg.Secret),
+		headers: cfg.Headers,
+		retries: cfg.GetRetries(),
+		backoff: cfg.GetRetryBackoffDuration(),
+		client:  httpx.NewClient(cfg.GetTimeoutDuration()),
+		now:     time.Now,
+	}
+}
+
+// Sign returns the SignatureHeader value for body: "sha256=" and the hex HMAC-SHA256 keyed with secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendAnalysis delivers an analysis result; retries stop when ctx is done.
+func (s *WebhookSender) SendAnalysis(ctx context.Context, result *models.AnalysisResult) error {
+	return s.send(ctx, WebhookEvent{Type: WebhookEventAnalysis, Analysis: result})
+}
+
+// SendPostmortem delivers a generated postmortem; retries stop when ctx is done.
+func (s *WebhookSender) SendPostmortem(ctx context.Context, pm *postmortem.Postmortem) error {
+	return s.send(ctx, WebhookEvent{Type: WebhookEventPostmortem, Postmortem: newPostmortemPayload(pm)})
+}
+
+// send marshals the event once and POSTs it, retrying network errors, 429s, and 5xx responses
+// with doubling backoff capped at maxWebhookBackoff. Other 4xx responses are not retried since
+// resending cannot fix them.
+func (s *WebhookSender) send(ctx context.Context, event WebhookEvent) error {
+	event.SentAt = s.now().UTC()
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, event.Type, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.retries {
+			return err
+		}
+		slog.Warn("Webhook delivery failed, retrying", "event", event.Type, "attempt", attempt+1, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWebhookBackoff)
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying.
+func (s *WebhookSender) post(ctx context.Context, eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook returned status: %d", resp.StatusCode)
+}
+
+// newPostmortemPayload converts a postmortem to its JSON form.
+func newPostmortemPayload(pm *postmortem.Postmortem) *PostmortemPayload {
+	p := &PostmortemPayload{
+		ID:              pm.ID,
+		IncidentName:    pm.IncidentName,
+		Date:            pm.Date,
+		DurationSeconds: pm.Duration.Seconds(),
+		RootCause:       pm.RootCause,
+		Impact:          pm.Impact,
+		Markdown:        pm.Markdown,
+		Language:        pm.Language,
+		DurationSuspect: pm.DurationSuspect,
+		Dashboards:      pm.Dashboards,
+		Recovery:        pm.Recovery,
+	}
+	for _, ai := range pm.ActionItems {
+		p.ActionItems = append(p.ActionItems, WebhookActionItem{ID: ai.ID, Text: ai.Text})
+	}
+	for _, r := range pm.RemediationRules {
+		p.Remediations = append(p.Remediations, WebhookRemediationItem{ID: r.ID, Title: r.Title, Action: r.Action, RequiresConfirmation: r.RequiresConfirmation})
+	}
+	return p
+}
@@ -0,0 +1,98 @@
+package output
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSenderSignsBodyAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	var body []byte
+	var signature, apiKey, eventType string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		apiKey = r.Header.Get("X-Api-Key")
+		eventType = r.Header.Get(EventHeader)
+	}))
+	defer api.Close()
+
+	sender := NewWebhookSender(config.WebhookOutputConfig{
+		URL:          api.URL,
+		Secret:       "s3cret",
+		Headers:      map[string]string{"X-Api-Key": "team-key"},
+		RetryBackoff: "1ms",
+	})
+	result := &models.AnalysisResult{
+		ID:          "inc-1",
+		ServiceName: "checkout",
+		AlertName:   "HighLatency",
+		Severity:    "critical",
+		RootCause:   "Connection pool exhausted",
+		NextSteps:   []string{"Raise the pool size"},
+		AnalyzedAt:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, sender.SendAnalysis(context.Background(), result))
+
+	assert.Equal(t, int32(2), attempts.Load(), "a 503 is retried")
+	assert.Equal(t, Sign([]byte("s3cret"), body), signature, "signature covers the exact body")
+	assert.Equal(t, "team-key", apiKey)
+	assert.Equal(t, WebhookEventAnalysis, eventType)
+
+	var event WebhookEvent
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, WebhookEventAnalysis, event.Type)
+	assert.Equal(t, result, event.Analysis)
+	assert.Nil(t, event.Postmortem)
+}
+
+func TestWebhookSenderDoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}))
+	defer api.Close()
+
+	sender := NewWebhookSender(config.WebhookOutputConfig{URL: api.URL, RetryBackoff: "1ms"})
+	err := sender.SendPostmortem(context.Background(), &postmortem.Postmortem{ID: "pm-1", Duration: 90 * time.Second})
+
+	assert.ErrorContains(t, err, "status: 401")
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestWebhookSenderStopsRetryingWhenContextEnds(t *testing.T) {
+	var attempts atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer api.Close()
+
+	sender := NewWebhookSender(config.WebhookOutputConfig{URL: api.URL, Retries: 5, RetryBackoff: "10s"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := sender.SendAnalysis(ctx, &models.AnalysisResult{ID: "inc-1"})
+
+	assert.ErrorContains(t, err, "status: 502")
+	assert.Less(t, time.Since(start), 5*time.Second, "the backoff is not slept through")
+	assert.Equal(t, int32(1), attempts.Load())
+}
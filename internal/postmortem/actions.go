@@ -0,0 +1,95 @@
+package postmortem
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"helixops/internal/remediation"
+)
+
+// listItem matches markdown bullets ("- x", "* x") and numbered items ("1. x", "2) x").
+var listItem = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.+)$`)
+
+// extractActionItems returns the list entries under the LLM's "Action Items" heading, in the
+// order written and without duplicates.
+func extractActionItems(llmBody string) []string {
+	var items []string
+	inSection := false
+	for _, line := range strings.Split(llmBody, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			inSection = strings.Contains(strings.ToLower(trimmed), "action items")
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		m := listItem.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(m[1], "[ ]"), "[x]"))
+		if text != "" && !contains(items, text) {
+			items = append(items, text)
+		}
+	}
+	return items
+}
+
+// extractSection returns the body of the first markdown section whose heading contains name
+// (case-insensitive), or "" when absent.
+func extractSection(llmBody, name string) string {
+	var lines []string
+	inSection := false
+	for _, line := range strings.Split(llmBody, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			if inSection {
+				break
+			}
+			inSection = strings.Contains(strings.ToLower(trimmed), name)
+			continue
+		}
+		if inSection {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// numberActionItems assigns AI-n IDs in list order.
+func numberActionItems(texts []string) []ActionItem {
+	items := make([]ActionItem, len(texts))
+	for i, text := range texts {
+		items[i] = ActionItem{ID: fmt.Sprintf("AI-%d", i+1), Text: text}
+	}
+	return items
+}
+
+// numberSuggestions sorts rule suggestions by title, drops duplicate titles, and assigns REM-n IDs,
+// so the same alert always yields the same IDs regardless of rule declaration order.
+func numberSuggestions(suggestions []remediation.Suggestion) []remediation.Suggestion {
+	sorted := append([]remediation.Suggestion(nil), suggestions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Title < sorted[j].Title })
+
+	result := make([]remediation.Suggestion, 0, len(sorted))
+	for _, s := range sorted {
+		if len(result) > 0 && result[len(result)-1].Title == s.Title {
+			continue
+		}
+		s.ID = fmt.Sprintf("REM-%d", len(result)+1)
+		result = append(result, s)
+	}
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
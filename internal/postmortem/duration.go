@@ -0,0 +1,38 @@
+package postmortem
+
+import (
+	"fmt"
+	"time"
+)
+
+// earliestPlausibleStart predates any real alert; earlier start times are unset or corrupt.
+var earliestPlausibleStart = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// incidentDuration returns the duration to show for an incident and, when the timestamps look wrong,
+// why it was adjusted: an unset or far-past start is rejected (zero duration), a start after the
+// resolution is clamped to zero, and anything longer than max is capped at max. Without this a zero
+// StartsAt reports an incident lasting decades.
+func incidentDuration(started, resolved time.Time, max time.Duration) (time.Duration, string) {
+	switch d := resolved.Sub(started); {
+	case started.Before(earliestPlausibleStart):
+		return 0, "the alert's start time is unset or implausible"
+	case d < 0:
+		return 0, fmt.Sprintf("the alert started %s after it resolved; check for clock skew", (-d).Round(time.Second))
+	case d > max:
+		return max, fmt.Sprintf("capped at %s; the reported start time may be wrong", max)
+	default:
+		return d, ""
+	}
+}
+
+// formatDuration renders a duration from incidentDuration with its warning, e.g. "unknown ⚠️ (...)".
+func formatDuration(d time.Duration, suspect string) string {
+	switch {
+	case suspect == "":
+		return d.String()
+	case d == 0:
+		return fmt.Sprintf("unknown ⚠️ (%s)", suspect)
+	default:
+		return fmt.Sprintf("%s ⚠️ (%s)", d, suspect)
+	}
+}
@@ -4,9 +4,13 @@ package postmortem
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
+	"unicode/utf8"
 	"github.com/google/uuid"
 
+	"helixops/internal/config"
 	"helixops/internal/models"
 	"helixops/pkg/llm"
 	"helixops/internal/remediation"
@@ -21,15 +25,42 @@ type Postmortem struct {
 	RootCause          string
 	Impact             string
 	DetectionMethod    string
-	ActionItems        []string
+	ActionItems        []ActionItem
 	RemediationRules   []remediation.Suggestion
 	Markdown           string
+	Language           string
+	// AffectedServices lists every service of an aggregated incident, lead service first
+	AffectedServices   []string
+	// DurationSuspect explains why Duration was rejected or capped; empty when the timestamps looked sane
+	DurationSuspect    string
+	// Dashboards link the service's dashboards over the incident window
+	Dashboards         []models.DashboardLink
+	// Recovery records whether the signals returned to baseline; nil when it was not checked
+	Recovery           *models.Recovery
+}
+
+// FormattedDuration renders Duration for display, flagged when it was rejected or capped.
+func (pm *Postmortem) FormattedDuration() string {
+	return formatDuration(pm.Duration, pm.DurationSuspect)
+}
+
+// ActionItem is a follow-up task from the postmortem, addressable by a stable ordinal ID (AI-1, AI-2, ...).
+type ActionItem struct {
+	ID   string
+	Text string
 }
 
 // Generator orchestrates the compilation of metrics, traces, and LLM summaries into a coherent postmortem.
 type Generator struct {
 	provider llm.Provider
 	rules    *remediation.Engine
+	template *Template
+	// language is the prose language requested from the LLM; empty means English
+	language string
+	// maxDuration caps the displayed incident duration
+	maxDuration time.Duration
+	// maxBodyChars caps the LLM body embedded in the Markdown
+	maxBodyChars int
 }
 
 // NewGenerator initializes a Generator with the necessary LLM provider and rule engine dependencies.
@@ -37,38 +68,111 @@ func NewGenerator(provider llm.Provider, rules *remediation.Engine) *Generator {
 	return &Generator{
 		provider: provider,
 		rules:    rules,
+		template: DefaultTemplate(),
+
+		maxDuration:  config.PostmortemConfig{}.GetMaxDuration(),
+		maxBodyChars: config.PostmortemConfig{}.GetMaxBodyChars(),
+	}
+}
+
+// NewGeneratorFromConfig initializes a Generator using the configured postmortem sections and template.
+func NewGeneratorFromConfig(provider llm.Provider, rules *remediation.Engine, cfg config.PostmortemConfig) (*Generator, error) {
+	tmpl, err := LoadTemplate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	g := NewGenerator(provider, rules)
+	g.template = tmpl
+	g.maxDuration = cfg.GetMaxDuration()
+	g.maxBodyChars = cfg.GetMaxBodyChars()
+	return g, nil
+}
+
+// UseLanguage asks the LLM to write the postmortem prose in language, keeping the section headings
+// in English so sections can still be extracted. Empty or "English" leaves the prompt unchanged.
+func (g *Generator) UseLanguage(language string) {
+	g.language = language
+	if strings.EqualFold(language, "english") || strings.EqualFold(language, "en") {
+		g.language = ""
 	}
 }
 
 // Generate executes the postmortem creation workflow, invoking the LLM and rule engine concurrently.
 func (g *Generator) Generate(ctx context.Context, ac *models.AnalysisContext) (*Postmortem, error) {
+	resolved := resolvedAt(ac)
+	duration, suspect := incidentDuration(ac.Alert.StartedAt, resolved, g.maxDuration)
+
 	// 1. Get LLM Postmortem Summary
-	prompt := g.buildPrompt(ac)
+	prompt := g.buildPrompt(ac, resolved, formatDuration(duration, suspect))
 	llmResponse, err := g.provider.Analyze(ctx, prompt)
 	if err != nil {
 		return nil, fmt.Errorf("postmortem generation failed: %w", err)
 	}
 
 	// 2. Fetch Rule-Based Remediations
-	ruleSuggestions := g.rules.GetSuggestions(ac.Alert)
+	ruleSuggestions := numberSuggestions(g.rules.GetSuggestions(ac.Alert))
 
+	services := ac.AffectedServices()
 	pm := &Postmortem{
 		ID:               uuid.New().String(),
-		IncidentName:     fmt.Sprintf("Incident: %s on %s", ac.Alert.Name, ac.ServiceName),
+		IncidentName:     fmt.Sprintf("Incident: %s on %s", ac.Alert.Name, strings.Join(services, ", ")),
 		Date:             time.Now(),
-		Duration:         time.Since(ac.Alert.StartedAt),
+		Duration:         duration,
+		DurationSuspect:  suspect,
+		RootCause:        extractSection(llmResponse, "root cause"),
+		ActionItems:      numberActionItems(extractActionItems(llmResponse)),
 		RemediationRules: ruleSuggestions,
+		Language:         g.language,
+		AffectedServices: services,
+		Dashboards:       ac.Dashboards,
+		Recovery:         ac.Recovery,
 		// LLM Response acts as the bulk markdown body for now, which we merge below
 	}
 
-	// 3. Assemble Markdown
-	pm.Markdown = g.assembleMarkdown(pm, llmResponse)
+	// 3. Assemble Markdown, cutting a runaway response down so the file and notifications stay usable
+	body, truncated := TruncateBody(llmResponse, g.maxBodyChars)
+	if truncated {
+		slog.Warn("Truncated postmortem body", "service", ac.ServiceName, "chars", utf8.RuneCountInString(llmResponse), "max_body_chars", g.maxBodyChars)
+		pm.RootCause, _ = TruncateBody(pm.RootCause, g.maxBodyChars)
+	}
+	pm.Markdown, err = g.template.render(TemplateData{
+		IncidentName:     pm.IncidentName,
+		Date:             pm.Date,
+		Duration:         pm.Duration,
+		DurationSuspect:  pm.DurationSuspect,
+		Service:          ac.ServiceName,
+		Alert:            ac.Alert,
+		Metrics:          ac.Metrics,
+		Started:          ac.Alert.StartedAt,
+		Resolved:         resolved,
+		Timeline:         buildTimeline(ac, resolved),
+		Context:          ac,
+		RootCause:        pm.RootCause,
+		ActionItems:      pm.ActionItems,
+		RemediationRules: pm.RemediationRules,
+		Language:         pm.Language,
+		AffectedServices: pm.AffectedServices,
+		Dashboards:       pm.Dashboards,
+		Recovery:         pm.Recovery,
+		Body:             body,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return pm, nil
 }
 
-func (g *Generator) buildPrompt(ctx *models.AnalysisContext) string {
-	return fmt.Sprintf(`
+// resolvedAt returns when the incident ended: the alert's EndsAt when known, otherwise now.
+func resolvedAt(ac *models.AnalysisContext) time.Time {
+	if !ac.Alert.EndsAt.IsZero() {
+		return ac.Alert.EndsAt
+	}
+	return time.Now()
+}
+
+func (g *Generator) buildPrompt(ctx *models.AnalysisContext, resolved time.Time, duration string) string {
+	prompt := fmt.Sprintf(`
 You are an expert SRE writing a formal incident postmortem.
 An alert that was previously firing has now RESOLVED.
 
@@ -80,13 +184,7 @@ INCIDENT DETAILS:
 - Total Duration: %s
 
 Please write a structured postmortem with the following sections in Markdown:
-## 1. Summary
-## 2. Impact
-## 3. Root Cause Analysis
-## 4. Resolution and Recovery
-## 5. What went well & What went wrong
-## 6. Action Items (LLM Suggested)
-
+%s
 Use this alert context to inform your writeup:
 - Alert Summary: %s
 - Commits found during window: %d
@@ -94,30 +192,30 @@ Use this alert context to inform your writeup:
 		ctx.ServiceName, 
 		ctx.Alert.Name, 
 		ctx.Alert.StartedAt.Format(time.RFC3339),
-		time.Now().Format(time.RFC3339),
-		time.Since(ctx.Alert.StartedAt).String(),
+		resolved.Format(time.RFC3339),
+		duration,
+		g.template.promptSections(),
 		ctx.Alert.Summary,
 		len(ctx.RecentCommits),
 	)
-}
-
-func (g *Generator) assembleMarkdown(pm *Postmortem, llmBody string) string {
-	md := fmt.Sprintf("# %s\n", pm.IncidentName)
-	md += fmt.Sprintf("**Date:** %s\n", pm.Date.Format("2006-01-02 15:04:05"))
-	md += fmt.Sprintf("**Duration:** %s\n\n", pm.Duration.String())
-	
-	md += llmBody + "\n\n"
-
-	md += "## Automated Rule-Based Suggestions\n"
-	if len(pm.RemediationRules) == 0 {
-		md += "No automated rules matched this incident type.\n"
-	} else {
-		for _, rule := range pm.RemediationRules {
-			md += fmt.Sprintf("### %s\n", rule.Title)
-			md += fmt.Sprintf("%s\n\n", rule.Description)
-			md += fmt.Sprintf("```bash\n%s\n```\n\n", rule.Action)
+	if len(ctx.DeployEvents) > 0 {
+		prompt += "\nDeploys recorded by the CD system before the alert; place them in the timeline:\n"
+		for _, d := range ctx.DeployEvents {
+			prompt += fmt.Sprintf("- %s at %s\n", d.Label(), d.Time.UTC().Format(time.RFC3339))
 		}
 	}
-
-	return md
+	if len(ctx.RelatedAlerts) > 0 {
+		prompt += "\nThis incident merges alerts from several services that share one root cause. Cover the impact on each:\n"
+		prompt += fmt.Sprintf("- %s: %s (started %s)\n", ctx.ServiceName, ctx.Alert.Name, ctx.Alert.StartedAt.Format(time.RFC3339))
+		for _, r := range ctx.RelatedAlerts {
+			prompt += fmt.Sprintf("- %s: %s (started %s)\n", r.ServiceName, r.AlertName, r.StartedAt.Format(time.RFC3339))
+		}
+	}
+	if ctx.Recovery != nil {
+		prompt += fmt.Sprintf("\nRecovery check from metrics at resolution: %s Reflect this in the resolution section; do not claim a full recovery the metrics do not show.\n", ctx.Recovery.Note)
+	}
+	if g.language != "" {
+		prompt += fmt.Sprintf("\nWrite all prose in %s, but keep the section headings above exactly as given in English.\n", g.language)
+	}
+	return prompt
 }
@@ -0,0 +1,253 @@
+package postmortem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samplePostmortem = `## 1. Summary
+Checkout latency spiked after a pool size change.
+
+## 3. Root Cause Analysis
+The DB pool was reduced to 5 connections.
+
+## 6. Action Items (LLM Suggested)
+- Restore the DB pool size to 50
+- [ ] Add a pool saturation alert
+1. Load test pool changes before rollout
+- Add a pool saturation alert
+`
+
+func TestGenerateAssignsStableActionItemIDs(t *testing.T) {
+	ac := &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert: models.AlertInfo{
+			Name:      "HighLatencyErrorRate",
+			StartedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+			EndsAt:    time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+		},
+	}
+	g := NewGenerator(llm.NewFakeProvider(samplePostmortem), remediation.NewEngine())
+
+	first, err := g.Generate(context.Background(), ac)
+	require.NoError(t, err)
+	second, err := g.Generate(context.Background(), ac)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ActionItem{
+		{ID: "AI-1", Text: "Restore the DB pool size to 50"},
+		{ID: "AI-2", Text: "Add a pool saturation alert"},
+		{ID: "AI-3", Text: "Load test pool changes before rollout"},
+	}, first.ActionItems)
+	assert.Equal(t, first.ActionItems, second.ActionItems)
+
+	ruleIDs := func(pm *Postmortem) []string {
+		var ids []string
+		for _, r := range pm.RemediationRules {
+			ids = append(ids, r.ID+" "+r.Title)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{
+		"REM-1 Check Database Query Performance",
+		"REM-2 Check Downstream Dependencies",
+		"REM-3 Investigate Recent Deployments",
+		"REM-4 Scale Up Service Replicas",
+	}, ruleIDs(first))
+	assert.Equal(t, ruleIDs(first), ruleIDs(second))
+
+	assert.Contains(t, first.Markdown, "- **AI-2** Add a pool saturation alert")
+	assert.Contains(t, first.Markdown, "### REM-1: Check Database Query Performance")
+	assert.Equal(t, 30*time.Minute, first.Duration)
+	assert.Equal(t, "The DB pool was reduced to 5 connections.", first.RootCause)
+}
+
+func TestGenerateRendersCustomTemplate(t *testing.T) {
+	tmpl, err := NewTemplate([]string{"Customer Impact", "Why It Happened", "Action Items"}, `# {{.Service}}: {{.Alert.Name}} ({{.Duration}})
+
+## Follow-ups
+{{range .ActionItems}}- [{{.ID}}] {{.Text}}
+{{end}}
+## Why it happened
+{{.Section "why it happened"}}
+
+## Who was affected
+{{.Section "customer impact"}}
+
+## Timeline
+{{range .Timeline}}- {{.Time.Format "15:04"}} {{.Event}}
+{{end}}`)
+	require.NoError(t, err)
+
+	provider := llm.NewFakeProvider(`## 1. Customer Impact
+Checkout was slow for EU users.
+
+## 2. Why It Happened
+The DB pool was reduced.
+
+## 3. Action Items
+- Restore the pool size
+`)
+	g := NewGenerator(provider, remediation.NewEngine())
+	g.template = tmpl
+
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pm, err := g.Generate(context.Background(), &models.AnalysisContext{
+		ServiceName:   "checkout",
+		Alert:         models.AlertInfo{Name: "HighLatency", StartedAt: started, EndsAt: started.Add(30 * time.Minute)},
+		RecentCommits: []models.CommitInfo{{SHA: "abc1234def", Message: "Reduce DB pool size", Author: "dev", Timestamp: started.Add(-10 * time.Minute)}},
+		DeployEvents:  []models.DeployEvent{{Service: "checkout", Version: "v1.2.3", SHA: "abc1234def", Time: started.Add(-5 * time.Minute)}},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, provider.LastPrompt(), "## 1. Customer Impact\n## 2. Why It Happened\n## 3. Action Items\n")
+	assert.Contains(t, provider.LastPrompt(), "- v1.2.3 (abc1234) at 2024-01-01T11:55:00Z")
+	assert.Equal(t, `# checkout: HighLatency (30m0s)
+
+## Follow-ups
+- [AI-1] Restore the pool size
+
+## Why it happened
+The DB pool was reduced.
+
+## Who was affected
+Checkout was slow for EU users.
+
+## Timeline
+- 11:50 Commit abc1234 by dev: Reduce DB pool size
+- 11:55 Deploy v1.2.3 (abc1234) of checkout
+- 12:00 Alert HighLatency fired
+- 12:30 Alert resolved
+`, pm.Markdown)
+}
+
+func TestNewTemplateRejectsInvalidTemplates(t *testing.T) {
+	_, err := NewTemplate(nil, "{{.Missing")
+	assert.Error(t, err)
+
+	_, err = NewTemplate(nil, "{{.NoSuchField}}")
+	assert.Error(t, err, "unknown fields are caught by the dry run at load")
+}
+
+func TestGenerateFlagsImplausibleDuration(t *testing.T) {
+	resolved := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	g := NewGenerator(llm.NewFakeProvider(samplePostmortem), remediation.NewEngine())
+
+	unset, err := g.Generate(context.Background(), &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert:       models.AlertInfo{Name: "HighLatency", EndsAt: resolved},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, unset.Duration, "a zero start must not report decades")
+	assert.NotEmpty(t, unset.DurationSuspect)
+	assert.Contains(t, unset.Markdown, "**Duration:** unknown ⚠️")
+
+	long, err := g.Generate(context.Background(), &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert:       models.AlertInfo{Name: "HighLatency", StartedAt: resolved.Add(-30 * 24 * time.Hour), EndsAt: resolved},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, long.Duration)
+	assert.Contains(t, long.FormattedDuration(), "⚠️")
+}
+
+func TestGenerateTruncatesOversizedBody(t *testing.T) {
+	runaway := "## 1. Summary\nCheckout latency spiked.\n\n## 2. Impact\n" +
+		strings.Repeat("The same sentence, repeated by a model that would not stop.\n", 2000) +
+		"\n## 3. Root Cause Analysis\nThe DB pool was reduced.\n"
+	g, err := NewGeneratorFromConfig(llm.NewFakeProvider(runaway), remediation.NewEngine(), config.PostmortemConfig{MaxBodyChars: 1000})
+	require.NoError(t, err)
+
+	pm, err := g.Generate(context.Background(), &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert:       models.AlertInfo{Name: "HighLatency", StartedAt: time.Now().Add(-time.Hour), EndsAt: time.Now()},
+	})
+	require.NoError(t, err)
+
+	assert.Less(t, len(pm.Markdown), 3000, "the runaway body is not embedded whole")
+	assert.Contains(t, pm.Markdown, "## 1. Summary\nCheckout latency spiked.")
+	assert.Contains(t, pm.Markdown, "> ⚠️ **Truncated:** the response was longer than 1000 characters")
+	assert.Contains(t, pm.Markdown, "## 3. Root Cause Analysis\n_Omitted (truncated)._", "headings past the cut are kept")
+	assert.Contains(t, pm.Markdown, "## Automated Rule-Based Suggestions", "the rest of the template still renders")
+}
+
+func TestGenerateReportsIncompleteRecovery(t *testing.T) {
+	provider := llm.NewFakeProvider(samplePostmortem)
+	g := NewGenerator(provider, remediation.NewEngine())
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	pm, err := g.Generate(context.Background(), &models.AnalysisContext{
+		ServiceName: "checkout",
+		Alert:       models.AlertInfo{Name: "HighErrorRate", StartedAt: started, EndsAt: started.Add(30 * time.Minute)},
+		Recovery: &models.Recovery{
+			Baseline:     prometheus.Signals{LatencyP99: 120, ErrorRate: 0.001, RPS: 40},
+			AtStart:      prometheus.Signals{LatencyP99: 900, ErrorRate: 0.08, RPS: 40},
+			AtResolution: prometheus.Signals{LatencyP99: 130, ErrorRate: 0.05, RPS: 40},
+			Status:       models.RecoveryElevated,
+			Note:         "Not fully recovered: at resolution error rate 5.00% (baseline 0.10%).",
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, pm.Markdown, "## Recovery Verification\n⚠️ Not fully recovered: at resolution error rate 5.00% (baseline 0.10%).")
+	assert.Contains(t, pm.Markdown, "| Error rate | 0.10% | 8.00% | 5.00% |")
+	assert.False(t, pm.Recovery.Recovered())
+	assert.Contains(t, provider.Prompts()[0], "do not claim a full recovery", "the LLM is told about the incomplete recovery")
+}
+
+func TestTruncateBody(t *testing.T) {
+	body := "## Fix\n```bash\n# scale up\n" + strings.Repeat("kubectl scale deploy/checkout --replicas=5\n", 3) + "```\n## Follow-up\nDone.\n"
+	out, truncated := TruncateBody(body, 170)
+	assert.True(t, truncated)
+	assert.Equal(t, "## Fix\n```bash\n# scale up\n```\n\n> ⚠️ **Truncated:** the response was longer than 170 characters; the rest was omitted.\n"+
+		"\n## Follow-up\n_Omitted (truncated)._\n", out, "the fence is closed and the comment inside it is not taken for a heading")
+
+	out, truncated = TruncateBody(strings.Repeat("é", 300), 120)
+	assert.True(t, truncated)
+	assert.True(t, strings.HasPrefix(out, strings.Repeat("é", 30)+"…\n"), "a single long line keeps what fits, on a rune boundary")
+	assert.LessOrEqual(t, utf8.RuneCountInString(out), 120)
+
+	var sections strings.Builder
+	for i := 1; i <= 60; i++ {
+		fmt.Fprintf(&sections, "## %d. Section\nSome findings.\n", i)
+	}
+	out, truncated = TruncateBody(sections.String(), 1000)
+	assert.True(t, truncated)
+	assert.LessOrEqual(t, utf8.RuneCountInString(out), 1000, "kept headings and the notice count against the limit")
+	assert.Contains(t, out, "## 1. Section\nSome findings.\n")
+	assert.Contains(t, out, "_Omitted (truncated)._")
+
+	out, truncated = TruncateBody(body, 0)
+	assert.False(t, truncated)
+	assert.Equal(t, body, out)
+}
+
+func TestIncidentDuration(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	max := 24 * time.Hour
+
+	d, suspect := incidentDuration(start, start.Add(30*time.Minute), max)
+	assert.Equal(t, 30*time.Minute, d)
+	assert.Empty(t, suspect)
+
+	d, suspect = incidentDuration(start, start.Add(-time.Minute), max)
+	assert.Zero(t, d)
+	assert.Contains(t, suspect, "clock skew")
+
+	d, suspect = incidentDuration(start, start.Add(48*time.Hour), max)
+	assert.Equal(t, max, d)
+	assert.NotEmpty(t, suspect)
+}
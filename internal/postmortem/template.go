@@ -0,0 +1,228 @@
+package postmortem
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/remediation"
+)
+
+// defaultSections are the headings the LLM writes when no custom sections are configured.
+var defaultSections = []string{
+	"Summary",
+	"Impact",
+	"Root Cause Analysis",
+	"Resolution and Recovery",
+	"What went well & What went wrong",
+	"Action Items (LLM Suggested)",
+}
+
+// defaultTemplateText renders the LLM body followed by tracked action items and rule-based suggestions.
+//
+//go:embed default.md.tmpl
+var defaultTemplateText string
+
+// Template controls which sections the LLM is asked for and how the final Markdown is laid out.
+type Template struct {
+	sections []string
+	tmpl     *template.Template
+}
+
+// TemplateData is the view rendered by a postmortem template.
+type TemplateData struct {
+	IncidentName string
+	Date         time.Time
+	Duration     time.Duration
+	// DurationSuspect explains why Duration was rejected or capped; empty when the timestamps looked sane
+	DurationSuspect string
+	Service         string
+	Alert           models.AlertInfo
+	Metrics         models.MetricsSummary
+	Started         time.Time
+	Resolved        time.Time
+	// Timeline lists the alert start, commits and deploys inside the analysis window, and the resolution, oldest first
+	Timeline         []TimelineEvent
+	Context          *models.AnalysisContext
+	RootCause        string
+	ActionItems      []ActionItem
+	RemediationRules []remediation.Suggestion
+	// Body is the LLM's full response; use Section to pick out a single heading
+	Body string
+	// Language the prose was requested in; empty means English
+	Language string
+	// AffectedServices lists every service of the incident, lead service first; more than one when alerts were aggregated
+	AffectedServices []string
+	// Dashboards are deep links to the service's dashboards over the incident window
+	Dashboards []models.DashboardLink
+	// Recovery compares the signals at resolution with the pre-incident baseline; nil when not checked
+	Recovery *models.Recovery
+}
+
+// RecoveryRow is one golden signal of the recovery verification, formatted for display.
+type RecoveryRow struct {
+	Signal       string
+	Baseline     string
+	AtStart      string
+	AtResolution string
+}
+
+// RecoveryTable formats the recovery readings as rows of latency, error rate, and request rate;
+// nil when recovery was not checked.
+func (d TemplateData) RecoveryTable() []RecoveryRow {
+	r := d.Recovery
+	if r == nil {
+		return nil
+	}
+	ms := func(v float64) string { return fmt.Sprintf("%.2fms", v) }
+	pct := func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) }
+	rps := func(v float64) string { return fmt.Sprintf("%.1f req/s", v) }
+	return []RecoveryRow{
+		{"Latency p99", ms(r.Baseline.LatencyP99), ms(r.AtStart.LatencyP99), ms(r.AtResolution.LatencyP99)},
+		{"Error rate", pct(r.Baseline.ErrorRate), pct(r.AtStart.ErrorRate), pct(r.AtResolution.ErrorRate)},
+		{"Request rate", rps(r.Baseline.RPS), rps(r.AtStart.RPS), rps(r.AtResolution.RPS)},
+	}
+}
+
+// TimelineEvent is one timestamped entry of the incident timeline.
+type TimelineEvent struct {
+	Time  time.Time
+	Event string
+}
+
+// FormattedDuration renders Duration for display, flagged when it was rejected or capped.
+func (d TemplateData) FormattedDuration() string {
+	return formatDuration(d.Duration, d.DurationSuspect)
+}
+
+// Section returns the body of the LLM's section whose heading contains name (case-insensitive).
+func (d TemplateData) Section(name string) string {
+	return extractSection(d.Body, strings.ToLower(name))
+}
+
+// DefaultTemplate returns the built-in six-section postmortem layout.
+func DefaultTemplate() *Template {
+	t, err := NewTemplate(nil, defaultTemplateText)
+	if err != nil {
+		panic(fmt.Sprintf("default postmortem template: %v", err))
+	}
+	return t
+}
+
+// NewTemplate parses text as a postmortem template and dry-runs it against sample data, so a
+// broken template fails at load rather than when an incident resolves. Empty sections use the defaults.
+func NewTemplate(sections []string, text string) (*Template, error) {
+	tmpl, err := template.New("postmortem").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid postmortem template: %w", err)
+	}
+	if len(sections) == 0 {
+		sections = defaultSections
+	}
+	t := &Template{sections: sections, tmpl: tmpl}
+	if err := t.tmpl.Execute(io.Discard, sampleTemplateData()); err != nil {
+		return nil, fmt.Errorf("invalid postmortem template: %w", err)
+	}
+	return t, nil
+}
+
+// LoadTemplate builds the template described by the postmortem config section, reading
+// template_file when set.
+func LoadTemplate(cfg config.PostmortemConfig) (*Template, error) {
+	text := defaultTemplateText
+	if cfg.TemplateFile != "" {
+		data, err := os.ReadFile(cfg.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read postmortem template: %w", err)
+		}
+		text = string(data)
+	}
+	return NewTemplate(cfg.Sections, text)
+}
+
+// render executes the template.
+func (t *Template) render(data TemplateData) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render postmortem template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// promptSections formats the configured sections as the numbered headings requested from the LLM.
+func (t *Template) promptSections() string {
+	var b strings.Builder
+	for i, s := range t.sections {
+		fmt.Fprintf(&b, "## %d. %s\n", i+1, s)
+	}
+	return b.String()
+}
+
+// buildTimeline orders the alert start, in-window commits, recorded deploys, and resolution.
+func buildTimeline(ac *models.AnalysisContext, resolved time.Time) []TimelineEvent {
+	events := []TimelineEvent{{Time: ac.Alert.StartedAt, Event: fmt.Sprintf("Alert %s fired", ac.Alert.Name)}}
+	for _, c := range ac.RecentCommits {
+		if c.Timestamp.IsZero() {
+			continue
+		}
+		events = append(events, TimelineEvent{Time: c.Timestamp, Event: fmt.Sprintf("Commit %s by %s: %s", models.ShortSHA(c.SHA), c.Author, firstLine(c.Message))})
+	}
+	for _, d := range ac.DeployEvents {
+		events = append(events, TimelineEvent{Time: d.Time, Event: fmt.Sprintf("Deploy %s of %s", d.Label(), d.Service)})
+	}
+	events = append(events, TimelineEvent{Time: resolved, Event: "Alert resolved"})
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events
+}
+
+// sampleTemplateData fills every field so a dry run reaches all template branches that depend on data.
+func sampleTemplateData() TemplateData {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ac := &models.AnalysisContext{
+		ServiceName:   "checkout",
+		Alert:         models.AlertInfo{Name: "HighLatency", Severity: "critical", StartedAt: started},
+		RecentCommits: []models.CommitInfo{{SHA: "abc1234def", Message: "Reduce DB pool size", Author: "dev", Timestamp: started.Add(-5 * time.Minute)}},
+		BlastRadius:   &models.BlastRadius{Services: []string{"frontend"}},
+	}
+	resolved := started.Add(30 * time.Minute)
+	return TemplateData{
+		IncidentName:     "Incident: HighLatency on checkout",
+		Date:             resolved,
+		Duration:         resolved.Sub(started),
+		Service:          ac.ServiceName,
+		Alert:            ac.Alert,
+		Metrics:          ac.Metrics,
+		Started:          started,
+		Resolved:         resolved,
+		Timeline:         buildTimeline(ac, resolved),
+		Context:          ac,
+		RootCause:        "The DB pool was reduced.",
+		ActionItems:      []ActionItem{{ID: "AI-1", Text: "Restore the pool size"}},
+		RemediationRules: []remediation.Suggestion{{ID: "REM-1", Title: "Scale Up Service Replicas"}},
+		Body:             "## 3. Root Cause Analysis\nThe DB pool was reduced.\n",
+		AffectedServices: []string{"checkout", "payments"},
+		Dashboards:       []models.DashboardLink{{Name: "Service overview", URL: "https://grafana.example.com/d/abc?var-service=checkout"}},
+		Recovery: &models.Recovery{
+			Baseline:     prometheus.Signals{Time: started.Add(-15 * time.Minute), LatencyP99: 120, ErrorRate: 0.001, RPS: 40},
+			AtStart:      prometheus.Signals{Time: started, LatencyP99: 900, ErrorRate: 0.05, RPS: 38},
+			AtResolution: prometheus.Signals{Time: resolved, LatencyP99: 130, ErrorRate: 0.001, RPS: 41},
+			Status:       models.RecoveryRecovered,
+			Note:         "Latency and error rate returned to their pre-incident baseline.",
+		},
+	}
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package postmortem
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TruncateBody shortens a Markdown body to at most limit characters and reports whether it did. A
+// notice marks where the body was cut, an open code fence at the cut is closed, and the headings of
+// the sections that no longer fit are kept, each marked as omitted, so the outline of the document
+// survives. All of these count against limit: the outline takes at most half of it, dropping the last
+// headings when there are more, and only a limit too short for the notice itself is exceeded. A
+// limit of 0 or less leaves body unchanged.
+func TruncateBody(body string, limit int) (string, bool) {
+	if limit <= 0 || utf8.RuneCountInString(body) <= limit {
+		return body, false
+	}
+	lines := strings.SplitAfter(body, "\n")
+
+	// Note which lines open or close a code block and which start inside one, and prepare the omitted
+	// marker of each heading in case the cut comes before it
+	fences := make([]bool, len(lines))
+	inFence := make([]bool, len(lines)+1)
+	omitted := make([]string, len(lines))
+	for i, line := range lines {
+		fences[i] = strings.HasPrefix(strings.TrimSpace(line), "```")
+		if !inFence[i] && strings.HasPrefix(line, "#") {
+			omitted[i] = fmt.Sprintf("\n%s\n_Omitted (truncated)._\n", strings.TrimRight(line, "\r\n"))
+		}
+		inFence[i+1] = inFence[i] != fences[i]
+	}
+	// outline[i] is the length of the omitted headings from line i on
+	outline := make([]int, len(lines)+1)
+	for i := len(lines) - 1; i >= 0; i-- {
+		outline[i] = outline[i+1] + utf8.RuneCountInString(omitted[i])
+	}
+
+	notice := fmt.Sprintf("\n> ⚠️ **Truncated:** the response was longer than %d characters; the rest was omitted.\n", limit)
+	// tail is the room taken after a cut before line i: the line break ending the kept text, the fence
+	// closing an open code block, the notice, and the outline
+	tail := func(i int) int {
+		n := 1 + utf8.RuneCountInString(notice) + min(outline[i], limit/2)
+		if inFence[i] {
+			n += len("```\n")
+		}
+		return n
+	}
+
+	var b strings.Builder
+	used, cut := 0, 0
+	for ; cut < len(lines); cut++ {
+		n := utf8.RuneCountInString(lines[cut])
+		if used+n+tail(cut+1) > limit {
+			break
+		}
+		b.WriteString(lines[cut])
+		used += n
+	}
+
+	if room := limit - used - tail(cut) - 1; room > 0 && omitted[cut] == "" && !fences[cut] && !inFence[cut] {
+		// Keep what fits of a prose line too long to take whole, such as a reply without line breaks;
+		// a half command in a code block would only mislead
+		text := []rune(strings.TrimRight(lines[cut], "\r\n"))
+		b.WriteString(string(text[:min(room, len(text))]) + "…")
+	}
+	if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+	if inFence[cut] {
+		b.WriteString("```\n")
+	}
+	b.WriteString(notice)
+
+	size := utf8.RuneCountInString(b.String())
+	for _, heading := range omitted[cut:] {
+		n := utf8.RuneCountInString(heading)
+		if size+n > limit {
+			break
+		}
+		b.WriteString(heading)
+		size += n
+	}
+	return b.String(), true
+}
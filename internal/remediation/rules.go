@@ -2,23 +2,127 @@
 package remediation
 
 import (
+	"fmt"
 	"strings"
+
+	"helixops/internal/config"
 	"helixops/internal/models"
 )
 
 // Suggestion defines an actionable, context-aware remediation step for an alert.
 type Suggestion struct {
-	Title       string
-	Description string
-	Action      string // E.g., a CLI command, link, or Terraform snippet
+	ID          string `json:"id,omitempty"` // Stable ordinal (REM-1, REM-2, ...) assigned when attached to a postmortem
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Action      string `json:"action"` // E.g., a CLI command, link, or Terraform snippet
+	// RequiresConfirmation marks actions that change the running system; outputs flag them so they are not run blindly
+	RequiresConfirmation bool `json:"requires_confirmation"`
 }
 
-// Engine evaluates incoming alerts against a set of predefined heuristic rules.
-type Engine struct{}
+// Engine evaluates incoming alerts against a set of predefined heuristic rules. Its settings are
+// fixed at construction, so it is safe for concurrent use.
+type Engine struct {
+	// services supplies the deployment and namespace of each service for generated commands
+	services map[string]config.ServiceConfig
+	// platform selects the CLI generated commands use: kubectl, aws ecs, or systemctl
+	platform string
+}
 
-// NewEngine initializes a generic heuristic remediation engine.
+// NewEngine initializes a generic heuristic remediation engine that generates Kubernetes commands.
 func NewEngine() *Engine {
-	return &Engine{}
+	return &Engine{platform: config.PlatformKubernetes}
+}
+
+// NewEngineFromConfig initializes an engine that generates commands for platform, targeting the
+// deployment and namespace configured under services.<name>.
+func NewEngineFromConfig(services map[string]config.ServiceConfig, platform string) *Engine {
+	if platform == "" {
+		platform = config.PlatformKubernetes
+	}
+	return &Engine{services: services, platform: platform}
+}
+
+// workload returns the deployment and namespace for an alert's service: the services config first,
+// then the alert's deployment and namespace labels. Unknown values are left as <placeholders> so a
+// pasted command fails instead of touching a guessed workload. On ECS the deployment is the ECS
+// service and the namespace its cluster; on systemd hosts the deployment is the unit name.
+func (e *Engine) workload(alert models.AlertInfo) (deployment, namespace string) {
+	svc := e.services[alert.Labels["service_name"]]
+	deployment, namespace = svc.Deployment, svc.Namespace
+	if deployment == "" {
+		deployment = alert.Labels["deployment"]
+	}
+	if namespace == "" {
+		namespace = alert.Labels["namespace"]
+	}
+	placeholders := map[string][2]string{
+		config.PlatformECS:     {"<service>", "<cluster>"},
+		config.PlatformSystemd: {"<unit>", ""},
+	}
+	p, ok := placeholders[e.platform]
+	if !ok {
+		p = [2]string{"<deployment>", "<namespace>"}
+	}
+	if deployment == "" {
+		deployment = p[0]
+	}
+	if namespace == "" {
+		namespace = p[1]
+	}
+	return deployment, namespace
+}
+
+// capacitySuggestion is the platform's command for adding capacity to a slow service.
+func (e *Engine) capacitySuggestion(alert models.AlertInfo) Suggestion {
+	deployment, namespace := e.workload(alert)
+	switch e.platform {
+	case config.PlatformECS:
+		return Suggestion{
+			Title:                "Scale Up Service Tasks",
+			Description:          "If CPU/Memory is also high, the service might be underprovisioned for current traffic.",
+			Action:               fmt.Sprintf("aws ecs update-service --cluster %s --service %s --desired-count 3", namespace, deployment),
+			RequiresConfirmation: true,
+		}
+	case config.PlatformSystemd:
+		return Suggestion{
+			Title:                "Restart the Service Unit",
+			Description:          "If the process is wedged or leaking resources, a restart restores capacity while the cause is investigated.",
+			Action:               fmt.Sprintf("systemctl status %s && sudo systemctl restart %s", deployment, deployment),
+			RequiresConfirmation: true,
+		}
+	default:
+		return Suggestion{
+			Title:                "Scale Up Service Replicas",
+			Description:          "If CPU/Memory is also high, the service might be underprovisioned for current traffic.",
+			Action:               fmt.Sprintf("kubectl -n %s scale deployment/%s --replicas=3", namespace, deployment),
+			RequiresConfirmation: true,
+		}
+	}
+}
+
+// cpuLimitSuggestion points at where the platform caps a service's CPU.
+func (e *Engine) cpuLimitSuggestion(alert models.AlertInfo) Suggestion {
+	switch e.platform {
+	case config.PlatformECS:
+		return Suggestion{
+			Title:       "Review CPU Limits",
+			Description: "The task might be getting throttled by the CPU units of its task definition.",
+			Action:      "Consider raising the task definition's cpu value and redeploying the service.",
+		}
+	case config.PlatformSystemd:
+		deployment, _ := e.workload(alert)
+		return Suggestion{
+			Title:       "Review CPU Limits",
+			Description: "The unit might be throttled by a systemd CPUQuota, or the host may be saturated.",
+			Action:      fmt.Sprintf("systemctl show %s -p CPUQuotaPerSecUSec and check host load with top.", deployment),
+		}
+	default:
+		return Suggestion{
+			Title:       "Review CPU Limits",
+			Description: "The container might be getting heavily throttled by Kubernetes CPU limits.",
+			Action:      "Consider increasing the CPU limit in the pod's resources configuration.",
+		}
+	}
 }
 
 // GetSuggestions parses the alert's labels and triggers any matching heuristic rules for immediate action.
@@ -32,11 +136,7 @@ func (e *Engine) GetSuggestions(alert models.AlertInfo) []Suggestion {
 			Description: "High latency is often caused by unoptimized queries or missing indexes.",
 			Action:      "Review slow query logs in your database provider or check APM traces for bottleneck spans.",
 		})
-		suggestions = append(suggestions, Suggestion{
-			Title:       "Scale Up Service Replicas",
-			Description: "If CPU/Memory is also high, the service might be underprovisioned for current traffic.",
-			Action:      "kubectl scale deployment " + alert.Labels["service_name"] + " --replicas=3",
-		})
+		suggestions = append(suggestions, e.capacitySuggestion(alert))
 	}
 
 	if strings.Contains(alertName, "errorrate") || strings.Contains(alertName, "high_error_rate") {
@@ -53,11 +153,7 @@ func (e *Engine) GetSuggestions(alert models.AlertInfo) []Suggestion {
 	}
 
 	if strings.Contains(alertName, "cpu") || strings.Contains(alertName, "throttling") {
-		suggestions = append(suggestions, Suggestion{
-			Title:       "Review CPU Limits",
-			Description: "The container might be getting heavily throttled by Kubernetes CPU limits.",
-			Action:      "Consider increasing the CPU limit in the pod's resources configuration.",
-		})
+		suggestions = append(suggestions, e.cpuLimitSuggestion(alert))
 	}
 
 	if strings.Contains(alertName, "memory") || strings.Contains(alertName, "oom") {
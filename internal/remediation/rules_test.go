@@ -0,0 +1,81 @@
+package remediation
+
+import (
+	"testing"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scaleSuggestion(t *testing.T, suggestions []Suggestion) Suggestion {
+	t.Helper()
+	for _, s := range suggestions {
+		if s.Title == "Scale Up Service Replicas" {
+			return s
+		}
+	}
+	require.FailNow(t, "no scale suggestion")
+	return Suggestion{}
+}
+
+func TestScaleSuggestionTargetsMappedDeployment(t *testing.T) {
+	engine := NewEngineFromConfig(map[string]config.ServiceConfig{
+		"checkout": {Deployment: "checkout-api", Namespace: "shop"},
+	}, config.PlatformKubernetes)
+	alert := models.AlertInfo{Name: "HighLatency", Labels: map[string]string{"service_name": "checkout", "namespace": "default"}}
+
+	s := scaleSuggestion(t, engine.GetSuggestions(alert))
+	assert.Equal(t, "kubectl -n shop scale deployment/checkout-api --replicas=3", s.Action)
+	assert.True(t, s.RequiresConfirmation)
+
+	for _, other := range engine.GetSuggestions(alert) {
+		if other.Title != s.Title {
+			assert.False(t, other.RequiresConfirmation, other.Title)
+		}
+	}
+}
+
+func TestScaleSuggestionNeverGuessesDeployment(t *testing.T) {
+	alert := models.AlertInfo{Name: "HighLatency", Labels: map[string]string{"service_name": "checkout", "namespace": "shop"}}
+
+	s := scaleSuggestion(t, NewEngine().GetSuggestions(alert))
+	assert.Equal(t, "kubectl -n shop scale deployment/<deployment> --replicas=3", s.Action,
+		"the service name is not assumed to be the deployment name")
+	assert.True(t, s.RequiresConfirmation)
+}
+
+func TestSuggestionsFollowConfiguredPlatform(t *testing.T) {
+	services := map[string]config.ServiceConfig{"checkout": {Deployment: "checkout-api", Namespace: "shop"}}
+	alert := models.AlertInfo{Name: "HighLatency", Labels: map[string]string{"service_name": "checkout"}}
+
+	tests := []struct {
+		platform string
+		action   string
+	}{
+		{config.PlatformKubernetes, "kubectl -n shop scale deployment/checkout-api --replicas=3"},
+		{config.PlatformECS, "aws ecs update-service --cluster shop --service checkout-api --desired-count 3"},
+		{config.PlatformSystemd, "systemctl status checkout-api && sudo systemctl restart checkout-api"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			var actions []string
+			for _, s := range NewEngineFromConfig(services, tt.platform).GetSuggestions(alert) {
+				if s.RequiresConfirmation {
+					actions = append(actions, s.Action)
+				}
+			}
+			assert.Equal(t, []string{tt.action}, actions)
+		})
+	}
+
+	ecs := NewEngineFromConfig(nil, config.PlatformECS).GetSuggestions(alert)
+	assert.Contains(t, ecs[1].Action, "--cluster <cluster> --service <service>", "unknown ECS targets stay placeholders")
+
+	cpu := NewEngineFromConfig(services, config.PlatformSystemd).GetSuggestions(models.AlertInfo{Name: "CPUThrottling", Labels: alert.Labels})
+	require.Len(t, cpu, 1)
+	assert.NotContains(t, cpu[0].Description, "Kubernetes")
+	assert.Contains(t, cpu[0].Action, "systemctl show checkout-api")
+}
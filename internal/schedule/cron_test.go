@@ -0,0 +1,55 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	// Saturday
+	from := time.Date(2024, 6, 1, 9, 30, 15, 0, time.UTC)
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 6, 1, 9, 31, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, 6, 2, 9, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2024, 6, 1, 9, 40, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 8,17 * * *", time.Date(2024, 6, 1, 17, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC)},
+		{"0 12 15 * 1", time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC)}, // day of month or day of week
+		{"30 9 29 2 *", time.Date(2028, 2, 29, 9, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		c, err := Parse(tc.spec, nil)
+		require.NoError(t, err, tc.spec)
+		assert.Equal(t, tc.want, c.Next(from), tc.spec)
+	}
+
+	c, err := Parse("0 0 30 2 *", nil)
+	require.NoError(t, err)
+	assert.True(t, c.Next(from).IsZero(), "February 30th never comes")
+}
+
+func TestCronNextInLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	c, err := Parse("0 9 * * *", berlin)
+	require.NoError(t, err)
+
+	next := c.Next(time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 6, 2, 7, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseRejectsInvalidExpressions(t *testing.T) {
+	for _, spec := range []string{"", "0 9 * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := Parse(spec, nil)
+		assert.Error(t, err, spec)
+	}
+}
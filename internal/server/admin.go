@@ -0,0 +1,64 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"helixops/internal/orchestrator"
+)
+
+// requireAdmin serves next only to requests carrying "Authorization: Bearer <app.admin_token>".
+// Admin endpoints answer 404 while no admin token is configured, so they are off by default.
+func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		want := h.cfg.App.AdminToken
+		if want == "" {
+			http.NotFound(w, r)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="helixops"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// HandleConfig returns the effective configuration with secrets redacted and durations resolved,
+// to confirm that config.yaml and HELIX_* overrides took effect.
+func (h *Handler) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.cfg.Redacted())
+}
+
+// HandleValidateQueries runs every configured service's PromQL, LogQL, and TraceQL queries against the
+// live backends and reports which ones the backends reject, so broken templates surface before an incident.
+func (h *Handler) HandleValidateQueries(w http.ResponseWriter, r *http.Request) {
+	if h.orchestrator == nil {
+		http.Error(w, "Orchestrator not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	checks := h.orchestrator.ValidateQueries(r.Context())
+	valid := true
+	for _, c := range checks {
+		if !c.OK {
+			valid = false
+			slog.Warn("Query rejected by backend", "service", c.Service, "language", c.Language, "query", c.Query, "error", c.Error)
+		}
+	}
+	if checks == nil {
+		checks = []orchestrator.QueryCheck{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valid":  valid,
+		"checks": checks,
+	})
+}
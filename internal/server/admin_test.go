@@ -0,0 +1,55 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleConfigRequiresAdminTokenAndRedacts(t *testing.T) {
+	cfg := &config.Config{
+		App: config.AppConfig{AdminToken: "admin-secret"},
+		LLM: config.LLMConfig{Provider: "anthropic", APIKey: "sk-ant-secret"},
+	}
+	router := SetupRouter(NewHandler(cfg, nil, nil, nil, nil, nil, nil))
+
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/config", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get("").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("Bearer wrong").Code)
+
+	w := get("Bearer admin-secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "sk-ant-secret")
+	assert.NotContains(t, w.Body.String(), "admin-secret")
+
+	var body map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "***", body["llm"]["api_key"])
+	assert.Equal(t, "anthropic", body["llm"]["provider"])
+	assert.Equal(t, "1h0m0s", body["analysis"]["logs_lookback"])
+}
+
+func TestHandleConfigDisabledWithoutAdminToken(t *testing.T) {
+	router := SetupRouter(NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"helixops/internal/db"
+	"helixops/internal/httpx"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+
+	"github.com/google/uuid"
+)
+
+// Alert aggregation merges alerts that share a root cause, such as a database outage paging every
+// service that depends on it, into the open incident of the first alert (the group leader). Merged
+// alerts get no RCA of their own; the group gets one postmortem once its last alert resolves. The
+// leader's incident is recorded before its RCA runs, so alerts arriving meanwhile are grouped too. If
+// that RCA fails, the group is released and its alerts are handled one by one.
+
+// recordGroupLeader records the incident of a firing alert before it is analyzed, so alerts it causes
+// on other services while the RCA runs can be grouped under it, and stores the alert so relatedTo can
+// match its labels before a context snapshot exists. It reports whether the incident was recorded,
+// which only happens with aggregation on; processFiring then updates it with the analysis.
+func (h *Handler) recordGroupLeader(alert models.AlertItem, serviceName, incidentID, threadTS string) bool {
+	if !h.cfg.Alerting.Aggregation.Enabled || h.database == nil {
+		return false
+	}
+	incident := &db.Incident{
+		ID:            incidentID,
+		ServiceName:   serviceName,
+		AlertName:     alert.Labels["alertname"],
+		Severity:      alert.Labels["severity"],
+		StartedAt:     alert.StartsAt,
+		Fingerprint:   alert.GetFingerprint(),
+		SlackThreadTS: threadTS,
+	}
+	if err := h.database.CreateIncident(incident); err != nil {
+		slog.Error("Failed to record incident before analysis", "error", err)
+		return false
+	}
+	if err := h.database.SaveAnalysisResult(incidentID, db.AnalysisTypeAlertPayload, alert); err != nil {
+		slog.Warn("Failed to store alert payload for aggregation", "incident_id", incidentID, "error", err)
+	}
+	return true
+}
+
+// mergeIntoGroup records a firing alert as a member of a related open incident instead of analyzing
+// it. It reports false when aggregation is off or no open incident is related.
+func (h *Handler) mergeIntoGroup(alert models.AlertItem, serviceName string) bool {
+	cfg := h.cfg.Alerting.Aggregation
+	if !cfg.Enabled || h.database == nil {
+		return false
+	}
+
+	// Alertmanager re-sends firing alerts every repeat_interval; an alert already merged stays merged
+	if existing, err := h.database.FindOpenIncident(alert.GetFingerprint()); err == nil && existing != nil && existing.GroupID != "" {
+		return true
+	}
+
+	window := cfg.GetWindowDuration()
+	leaders, err := h.database.OpenGroupLeaders(alert.StartsAt.Add(-window))
+	if err != nil {
+		slog.Warn("Failed to look up incidents for aggregation", "error", err)
+		return false
+	}
+
+	for _, leader := range leaders {
+		if leader.Fingerprint == alert.GetFingerprint() || leader.StartedAt.After(alert.StartsAt.Add(window)) {
+			continue
+		}
+		reason, ok := h.relatedTo(leader, alert, serviceName)
+		if !ok {
+			continue
+		}
+
+		member := &db.Incident{
+			ID:          uuid.New().String(),
+			ServiceName: serviceName,
+			AlertName:   alert.Labels["alertname"],
+			Severity:    alert.Labels["severity"],
+			StartedAt:   alert.StartsAt,
+			Fingerprint: alert.GetFingerprint(),
+			GroupID:     leader.ID,
+		}
+		if err := h.database.CreateIncident(member); err != nil {
+			slog.Error("Failed to record merged alert", "error", err)
+			return false
+		}
+		if err := h.database.SaveAnalysisResult(member.ID, db.AnalysisTypeAlertPayload, alert); err != nil {
+			slog.Warn("Failed to store alert payload of merged alert", "incident_id", member.ID, "error", err)
+		}
+		slog.Info("Merged alert into related incident", "alert", member.AlertName, "service", serviceName,
+			"incident_id", leader.ID, "leader_service", leader.ServiceName, "reason", reason)
+		return true
+	}
+	return false
+}
+
+// relatedTo reports why an alert belongs to a leader's incident: a shared value of one of
+// alerting.aggregation.match_labels, or the alert's service being a trace peer of the leader. While
+// the leader is still being analyzed only its stored alert is known, so only labels can match.
+func (h *Handler) relatedTo(leader db.Incident, alert models.AlertItem, serviceName string) (string, bool) {
+	ac, err := h.database.LoadContextSnapshot(leader.ID)
+	if err != nil {
+		return "", false
+	}
+	if ac == nil {
+		var leaderAlert models.AlertItem
+		if found, err := h.database.LoadAnalysisResult(leader.ID, db.AnalysisTypeAlertPayload, &leaderAlert); err != nil || !found {
+			return "", false
+		}
+		ac = &models.AnalysisContext{Alert: models.AlertInfo{Labels: leaderAlert.Labels}}
+	}
+
+	for _, label := range h.cfg.Alerting.Aggregation.MatchLabels {
+		if v := alert.Labels[label]; v != "" && ac.Alert.Labels[label] == v {
+			return fmt.Sprintf("shares %s=%s", label, v), true
+		}
+	}
+	if ac.BlastRadius != nil && slices.Contains(ac.BlastRadius.Services, serviceName) {
+		return fmt.Sprintf("trace peer of %s", leader.ServiceName), true
+	}
+	return "", false
+}
+
+// resolveGroupMember handles the resolution of an alert that belongs to an aggregated incident: the
+// alert's incident is closed, a member with status grouped, and once every alert of the group has
+// resolved, one postmortem covering all affected services is generated for the leader. It reports
+// false for standalone incidents and for members of a failed leader, which get postmortems of their own.
+func (h *Handler) resolveGroupMember(alert models.AlertItem, serviceName string) bool {
+	if !h.cfg.Alerting.Aggregation.Enabled || h.database == nil {
+		return false
+	}
+
+	incident, err := h.database.FindOpenIncident(alert.GetFingerprint())
+	if err != nil {
+		return false
+	}
+	if incident == nil {
+		// A re-delivered resolution of a member that already closed waits for its group like the first
+		grouped, err := h.database.FindGroupedIncident(alert.GetFingerprint(), alert.StartsAt)
+		return err == nil && grouped != nil
+	}
+	if incident.Status != db.IncidentStatusOpen {
+		return false
+	}
+	leaderID := incident.GroupID
+	if leaderID == "" {
+		leaderID = incident.ID
+	}
+	group, err := h.database.IncidentGroup(leaderID)
+	if err != nil {
+		slog.Error("Failed to load incident group", "incident_id", leaderID, "error", err)
+		return false
+	}
+	if len(group) < 2 || group[0].Status == db.IncidentStatusFailed {
+		return false
+	}
+
+	closedAt := alert.EndsAt
+	if closedAt.IsZero() {
+		closedAt = time.Now().UTC()
+	}
+	// The leader stays open so related alerts keep joining its group until the postmortem
+	closeIncident := h.database.CloseIncident
+	if incident.GroupID != "" {
+		closeIncident = h.database.CloseGroupMember
+	}
+	if err := closeIncident(incident.ID, closedAt); err != nil {
+		slog.Error("Failed to close merged incident", "incident_id", incident.ID, "error", err)
+		return true
+	}
+
+	var pending int
+	for i := range group {
+		if group[i].ID == incident.ID {
+			group[i].ResolvedAt = &closedAt
+		}
+		if group[i].ResolvedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		slog.Info("Waiting for related alerts before writing the postmortem", "incident_id", leaderID, "service", serviceName, "open_alerts", pending)
+		return true
+	}
+
+	h.publishGroupPostmortem(group)
+	return true
+}
+
+// releaseGroup ungroups the alerts merged into a leader whose analysis failed, since the group
+// postmortem needs the leader's context and would never be written. Each alert is then handled as if
+// it had not been grouped: one still firing is analyzed under its incident, and one that already
+// resolved gets its postmortem.
+func (h *Handler) releaseGroup(leaderID string) {
+	if h.database == nil {
+		return
+	}
+	members, err := h.database.UngroupIncidents(leaderID)
+	if err != nil {
+		slog.Error("Failed to release alerts grouped under failed incident", "incident_id", leaderID, "error", err)
+		return
+	}
+	for _, member := range members {
+		slog.Info("Processing grouped alert on its own after its group leader failed", "incident_id", member.ID, "service", member.ServiceName, "leader_id", leaderID)
+		alert := h.groupedAlert(member)
+		if member.ResolvedAt != nil {
+			alert.Status, alert.EndsAt = "resolved", *member.ResolvedAt
+			err = h.processResolved(alert, member.ServiceName)
+		} else {
+			err = h.processFiringIncident(alert, member.ServiceName, member.ID)
+		}
+		if err != nil {
+			slog.Error("Failed to process released alert", "incident_id", member.ID, "error", err)
+		}
+	}
+}
+
+// groupedAlert returns the alert a group member was recorded for, rebuilt from the incident when its
+// payload was not stored.
+func (h *Handler) groupedAlert(member db.Incident) models.AlertItem {
+	var alert models.AlertItem
+	if found, err := h.database.LoadAnalysisResult(member.ID, db.AnalysisTypeAlertPayload, &alert); err == nil && found {
+		return alert
+	}
+	return models.AlertItem{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": member.AlertName, "service_name": member.ServiceName, "severity": member.Severity},
+		StartsAt:    member.StartedAt,
+		Fingerprint: member.Fingerprint,
+	}
+}
+
+// publishGroupPostmortem writes the single postmortem of a fully resolved group from the leader's
+// stored context, then resolves every incident of the group.
+func (h *Handler) publishGroupPostmortem(group []db.Incident) {
+	if h.generator == nil {
+		return
+	}
+	leader := group[0]
+
+	ac, err := h.database.LoadContextSnapshot(leader.ID)
+	if err != nil || ac == nil {
+		slog.Error("Failed to load context for merged postmortem", "incident_id", leader.ID, "error", err)
+		return
+	}
+	ended := *leader.ResolvedAt
+	for _, member := range group[1:] {
+		ac.RelatedAlerts = append(ac.RelatedAlerts, models.RelatedAlert{
+			ServiceName: member.ServiceName,
+			AlertName:   member.AlertName,
+			StartedAt:   member.StartedAt,
+			ResolvedAt:  *member.ResolvedAt,
+		})
+		if member.ResolvedAt.After(ended) {
+			ended = *member.ResolvedAt
+		}
+	}
+	ac.Alert.EndsAt = ended
+	reqCtx := httpx.WithRequestID(context.Background(), h.cfg.App.RequestIDHeader, leader.ID)
+	if h.orchestrator != nil {
+		h.orchestrator.AttachDashboards(ac)
+		h.orchestrator.AttachRecovery(reqCtx, ac)
+	}
+
+	var pm *postmortem.Postmortem
+	err = h.withRetry(leader.ServiceName, func(bool) error {
+		var err error
+		pm, err = h.generator.Generate(reqCtx, ac)
+		return err
+	})
+	if err != nil {
+		// The group stays unresolved and the leader can still be resolved via POST /incidents/{id}/resolve
+		slog.Error("Giving up on merged postmortem", "incident_id", leader.ID, "error", err)
+		return
+	}
+
+	if err := h.database.ResolveIncidentAt(leader.ID, ended, pm.RootCause, pm.Markdown); err != nil {
+		slog.Error("Failed to resolve incident in database", "incident_id", leader.ID, "error", err)
+	}
+	for _, member := range group[1:] {
+		if err := h.database.ResolveIncidentAt(member.ID, *member.ResolvedAt, "Merged into incident "+leader.ID, ""); err != nil {
+			slog.Error("Failed to resolve merged incident", "incident_id", member.ID, "error", err)
+		}
+	}
+	slog.Info("Generated merged postmortem", "postmortem_id", pm.ID, "incident_id", leader.ID, "services", pm.AffectedServices)
+
+	h.publishPostmortem(reqCtx, leader.ServiceName, pm, leader.SlackThreadTS, notificationKey(leader.Fingerprint, leader.StartedAt), false)
+}
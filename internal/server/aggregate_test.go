@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var groupStarted = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// aggregationHandler groups alerts sharing a cluster label within 5m, analyzing and writing
+// postmortems with provider.
+func aggregationHandler(t *testing.T, provider *llm.FakeProvider) (*Handler, *db.DB) {
+	t.Helper()
+	handler, database := analysisHandler(t, &config.Config{Alerting: config.AlertingConfig{Aggregation: config.AggregationConfig{
+		Enabled:     true,
+		Window:      "5m",
+		MatchLabels: []string{"cluster"},
+	}}}, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	return handler, database
+}
+
+// clusterAlert fires HighErrorRate on service in cluster, offset after groupStarted.
+func clusterAlert(service string, offset time.Duration, cluster string) models.AlertItem {
+	return models.AlertItem{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "HighErrorRate", "service_name": service, "severity": "critical", "cluster": cluster},
+		StartsAt:    groupStarted.Add(offset),
+		Fingerprint: "fp-" + service,
+	}
+}
+
+const groupAnalysis = "# Incident Analysis: test\n**Confidence Score:** 70%\n## 3. Root Cause Analysis\nThe shared database went down.\n"
+
+func TestRelatedAlertsShareOnePostmortem(t *testing.T) {
+	provider := llm.NewFakeProvider(groupAnalysis)
+	handler, database := aggregationHandler(t, provider)
+
+	firing := []models.AlertItem{
+		clusterAlert("checkout", 0, "prod-eu"),
+		clusterAlert("payments", time.Minute, "prod-eu"),
+		clusterAlert("cart", 3*time.Minute, "prod-eu"),
+		clusterAlert("search", 2*time.Minute, "prod-us"), // same window, different cluster
+	}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: firing})
+
+	assert.Equal(t, 2, provider.CallCount(), "only the group leader and the unrelated alert are analyzed")
+	leader, err := database.FindOpenIncident("fp-checkout")
+	require.NoError(t, err)
+	require.NotNil(t, leader)
+	group, err := database.IncidentGroup(leader.ID)
+	require.NoError(t, err)
+	require.Len(t, group, 3)
+	assert.Equal(t, []string{"checkout", "payments", "cart"}, []string{group[0].ServiceName, group[1].ServiceName, group[2].ServiceName})
+
+	var resolved []models.AlertItem
+	for i, a := range firing[:3] {
+		a.Status = "resolved"
+		a.EndsAt = groupStarted.Add(time.Duration(20+i) * time.Minute)
+		resolved = append(resolved, a)
+	}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: resolved[:2]})
+	assert.Equal(t, 2, provider.CallCount(), "no postmortem while cart is still firing")
+
+	handler.processAlerts(models.AlertManagerPayload{Alerts: resolved[2:]})
+	assert.Equal(t, 3, provider.CallCount(), "one postmortem for the whole group")
+	assert.Contains(t, provider.LastPrompt(), "- payments: HighErrorRate")
+
+	incident, err := database.GetIncident(leader.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, incident.Status)
+	require.NotNil(t, incident.AISummary)
+	assert.Contains(t, *incident.AISummary, "# Incident: HighErrorRate on checkout, payments, cart")
+	assert.Contains(t, *incident.AISummary, "**Affected services:** checkout, payments, cart")
+	require.NotNil(t, incident.ResolvedAt)
+	assert.True(t, groupStarted.Add(22*time.Minute).Equal(*incident.ResolvedAt), "the group ends with its last alert")
+
+	member, err := database.GetIncident(group[2].ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, member.Status)
+}
+
+func TestAlertsFiringDuringLeaderAnalysisAreGrouped(t *testing.T) {
+	provider := llm.NewFakeProvider()
+	handler, database := aggregationHandler(t, provider)
+
+	// payments fires while the RCA of checkout is still running
+	provider.AnalyzeFunc = func(context.Context, string) (string, error) {
+		if provider.CallCount() == 1 {
+			handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{clusterAlert("payments", time.Minute, "prod-eu")}})
+		}
+		return groupAnalysis, nil
+	}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{clusterAlert("checkout", 0, "prod-eu")}})
+
+	assert.Equal(t, 1, provider.CallCount(), "payments is not analyzed on its own")
+	leader, err := database.FindOpenIncident("fp-checkout")
+	require.NoError(t, err)
+	require.NotNil(t, leader)
+	assert.Equal(t, "70%", leader.Confidence, "the leader recorded before analysis gets its results")
+	group, err := database.IncidentGroup(leader.ID)
+	require.NoError(t, err)
+	require.Len(t, group, 2)
+	assert.Equal(t, "payments", group[1].ServiceName)
+}
+
+func TestResolvedGroupMembersCloseAsGrouped(t *testing.T) {
+	provider := llm.NewFakeProvider(groupAnalysis)
+	handler, database := aggregationHandler(t, provider)
+
+	firing := []models.AlertItem{clusterAlert("checkout", 0, "prod-eu"), clusterAlert("payments", time.Minute, "prod-eu")}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: firing})
+
+	member := firing[1]
+	member.Status = "resolved"
+	member.EndsAt = groupStarted.Add(10 * time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{member}})
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{member}}) // re-delivered
+
+	assert.Equal(t, 1, provider.CallCount(), "no postmortem while checkout is still firing")
+	grouped, err := database.ListIncidents(db.IncidentStatusGrouped)
+	require.NoError(t, err)
+	require.Len(t, grouped, 1)
+	assert.Equal(t, "payments", grouped[0].ServiceName)
+	require.NotNil(t, grouped[0].ResolvedAt)
+	open, err := database.FindOpenIncident("fp-payments")
+	require.NoError(t, err)
+	assert.Nil(t, open, "a closed member is no longer open")
+
+	leader := firing[0]
+	leader.Status = "resolved"
+	leader.EndsAt = groupStarted.Add(20 * time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{leader}})
+	assert.Equal(t, 2, provider.CallCount(), "one postmortem for the group")
+	incident, err := database.GetIncident(grouped[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, incident.Status)
+}
+
+func TestFailedLeaderReleasesItsGroup(t *testing.T) {
+	provider := llm.NewFakeProvider()
+	handler, database := aggregationHandler(t, provider)
+	handler.cfg.Analysis.ProcessingRetries = 1
+
+	// payments and cart join the group while the RCA of checkout runs, and cart resolves before it fails
+	cart := clusterAlert("cart", 2*time.Minute, "prod-eu")
+	provider.AnalyzeFunc = func(context.Context, string) (string, error) {
+		if provider.CallCount() == 1 {
+			handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{clusterAlert("payments", time.Minute, "prod-eu"), cart}})
+			resolved := cart
+			resolved.Status, resolved.EndsAt = "resolved", groupStarted.Add(5*time.Minute)
+			handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{resolved}})
+			return "", errors.New("model overloaded")
+		}
+		return groupAnalysis, nil
+	}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{clusterAlert("checkout", 0, "prod-eu")}})
+
+	assert.Equal(t, 3, provider.CallCount(), "payments is analyzed and cart gets its postmortem once the leader fails")
+	incidents, err := database.ListIncidents("")
+	require.NoError(t, err)
+	byService := make(map[string]db.Incident)
+	for _, i := range incidents {
+		assert.Empty(t, i.GroupID, i.ServiceName)
+		byService[i.ServiceName] = i
+	}
+	assert.Equal(t, db.IncidentStatusFailed, byService["checkout"].Status)
+	assert.Equal(t, db.IncidentStatusResolved, byService["cart"].Status)
+	assert.Equal(t, db.IncidentStatusOpen, byService["payments"].Status)
+	assert.Equal(t, "70%", byService["payments"].Confidence)
+
+	payments := clusterAlert("payments", time.Minute, "prod-eu")
+	payments.Status, payments.EndsAt = "resolved", groupStarted.Add(20*time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{payments}})
+
+	assert.Equal(t, 4, provider.CallCount(), "payments gets a postmortem of its own")
+	resolved, err := database.GetIncident(byService["payments"].ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, resolved.Status)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxWebhookBodySize caps a webhook body, applied both on the wire and after decompression.
+const maxWebhookBodySize = int64(1 << 20) // 1MB
+
+// bodyError is a request body problem mapped to the HTTP status it should be answered with.
+type bodyError struct {
+	status int
+	msg    string
+}
+
+func (e *bodyError) Error() string { return e.msg }
+
+// readJSONBody reads a JSON request body of at most limit bytes. A missing Content-Type is accepted
+// for senders that omit it; anything other than application/json in UTF-8 is rejected with 415.
+// Gzip-encoded bodies are decompressed, and a leading UTF-8 byte order mark is dropped.
+func readJSONBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, params, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return nil, &bodyError{http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Type %q (expected application/json)", ct)}
+		}
+		if charset := params["charset"]; charset != "" && !strings.EqualFold(charset, "utf-8") {
+			return nil, &bodyError{http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported charset %q (expected utf-8)", charset)}
+		}
+	}
+
+	if r.ContentLength > limit {
+		return nil, &bodyError{http.StatusRequestEntityTooLarge, "Request body too large"}
+	}
+	var reader io.Reader = http.MaxBytesReader(w, r.Body, limit)
+
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, &bodyError{http.StatusBadRequest, "Invalid gzip body"}
+		}
+		defer gz.Close()
+		reader = gz
+	default:
+		return nil, &bodyError{http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported Content-Encoding %q (expected gzip)", encoding)}
+	}
+
+	// Read one byte past the limit so an oversized decompressed body is detected rather than truncated
+	body, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &bodyError{http.StatusRequestEntityTooLarge, "Request body too large"}
+		}
+		return nil, &bodyError{http.StatusBadRequest, "Failed to read request body"}
+	}
+	if int64(len(body)) > limit {
+		return nil, &bodyError{http.StatusRequestEntityTooLarge, "Request body too large"}
+	}
+
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(body) {
+		return nil, &bodyError{http.StatusBadRequest, "Request body is not valid UTF-8"}
+	}
+	return body, nil
+}
+
+// writeBodyError answers a readJSONBody failure with its status code.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var be *bodyError
+	if errors.As(err, &be) {
+		http.Error(w, be.msg, be.status)
+		return
+	}
+	http.Error(w, "Failed to read request body", http.StatusBadRequest)
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"helixops/internal/models"
+)
+
+// HandleDeployEvent records a deploy reported by a CD system, so alerts on the service that fire soon
+// after are correlated with it in the RCA prompt and the postmortem timeline.
+func (h *Handler) HandleDeployEvent(w http.ResponseWriter, r *http.Request) {
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var event models.DeployEvent
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&event); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	event.Service = strings.TrimSpace(event.Service)
+	if event.Service == "" {
+		http.Error(w, "service is required", http.StatusBadRequest)
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	if err := h.database.SaveDeployEvent(event); err != nil {
+		slog.Error("Failed to save deploy event", "service", event.Service, "error", err)
+		http.Error(w, "Failed to save deploy event", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Recorded deploy event", "service", event.Service, "version", event.Version, "sha", event.SHA, "time", event.Time)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   event,
+	})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordedDeployAppearsInIncidentTimeline(t *testing.T) {
+	provider := llm.NewFakeProvider("# Incident Analysis: test\n**Confidence Score:** 70%\n## 3. Root Cause Analysis\nThe v1.2.3 deploy shrank the pool.\n")
+	handler, database := analysisHandler(t, &config.Config{}, provider)
+	handler.orchestrator.UseDeployEvents(database)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events/deploy", strings.NewReader(body)))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, post(`{"version": "v1.2.3"}`).Code, "service is required")
+	require.Equal(t, http.StatusCreated, post(`{"service": "checkout", "version": "v1.2.3", "sha": "abc1234def", "time": "2024-06-01T14:03:00Z"}`).Code)
+	require.Equal(t, http.StatusCreated, post(`{"service": "checkout", "version": "v1.1.0", "time": "2024-05-20T09:00:00Z"}`).Code)
+	require.Equal(t, http.StatusCreated, post(`{"service": "payments", "version": "v7", "time": "2024-06-01T14:04:00Z"}`).Code)
+
+	started := time.Date(2024, 6, 1, 14, 5, 0, 0, time.UTC)
+	alert := firingAlert()
+	alert.StartsAt = started
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	require.Equal(t, 1, provider.CallCount())
+	prompt := provider.LastPrompt()
+	assert.Contains(t, prompt, "- deploy v1.2.3 (abc1234) at 14:03 UTC, alert at 14:05 UTC (2m0s later)")
+	assert.Contains(t, prompt, "1. Regression introduced by deploy v1.2.3 (abc1234)")
+	assert.NotContains(t, prompt, "v1.1.0", "deploys before the commits lookback are left out")
+	assert.NotContains(t, prompt, "v7", "deploys of other services are left out")
+
+	alert.Status = "resolved"
+	alert.EndsAt = started.Add(20 * time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	require.Equal(t, 2, provider.CallCount())
+	assert.Contains(t, provider.LastPrompt(), "- v1.2.3 (abc1234) at 2024-06-01T14:03:00Z", "the postmortem timeline includes the deploy")
+}
+
+func TestDeployEventRequiresDatabase(t *testing.T) {
+	router := chi.NewRouter()
+	NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil).RegisterRoutes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events/deploy", strings.NewReader(`{"service": "checkout"}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/output"
+	"helixops/internal/schedule"
+)
+
+// digestPoster delivers a digest message to the configured channel.
+type digestPoster interface {
+	SendDigest(text string) error
+}
+
+// digest periodically posts incident stats for the time since the previous digest: how many incidents
+// started, their mean time to resolve, and the service that alerted most. Where the previous digest
+// ended is kept in the database, so incidents that start between jittered runs or while HelixOps is
+// restarting are reported by the next one.
+type digest struct {
+	database db.Store
+	poster   digestPoster
+	interval time.Duration
+	// schedule, when set, replaces interval as the posting times
+	schedule  *schedule.Cron
+	jitter    time.Duration
+	postEmpty bool
+	now       func() time.Time
+	// rand returns a delay in [0, n); injectable so tests are deterministic
+	rand func(n int64) int64
+}
+
+// Run posts a digest after every interval, or at every time the schedule matches, plus jitter, until
+// ctx is cancelled. Nothing is posted at startup, so restarts do not produce duplicate digests.
+func (d *digest) Run(ctx context.Context) {
+	for {
+		delay, ok := d.nextDelay(d.now())
+		if !ok {
+			slog.Error("Incident digest stopped: its schedule never matches again")
+			return
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		d.post()
+	}
+}
+
+// nextDelay is the time from now until the next post: the interval, or until the schedule next
+// matches, plus a random share of the configured jitter. It reports false when the schedule never
+// matches again.
+func (d *digest) nextDelay(now time.Time) (time.Duration, bool) {
+	delay := d.interval
+	if d.schedule != nil {
+		next := d.schedule.Next(now)
+		if next.IsZero() {
+			return 0, false
+		}
+		delay = next.Sub(now)
+	}
+	if d.jitter > 0 {
+		delay += time.Duration(d.rand(int64(d.jitter)))
+	}
+	return delay, true
+}
+
+// post builds the digest for the time since the previous one and sends it. The window only advances
+// once the digest was sent or skipped as empty, so a failed post is covered by the next one.
+func (d *digest) post() {
+	now := d.now()
+	from, err := d.database.LastDigestEnd()
+	if err != nil {
+		slog.Error("Failed to load incident digest state", "error", err)
+		return
+	}
+	// The first digest covers one interval
+	if from.IsZero() || from.After(now) {
+		from = now.Add(-d.interval)
+	}
+
+	text, ok, err := d.build(from, now)
+	if err != nil {
+		slog.Error("Failed to build incident digest", "error", err)
+		return
+	}
+	if !ok {
+		slog.Debug("Skipping incident digest with no incidents")
+	} else if err := d.poster.SendDigest(text); err != nil {
+		slog.Error("Failed to post incident digest", "error", err)
+		return
+	}
+	if err := d.database.SaveDigestEnd(now); err != nil {
+		slog.Error("Failed to save incident digest state", "error", err)
+	}
+}
+
+// build renders the digest for incidents that started from from until now. The second result is
+// false when there is nothing to post.
+func (d *digest) build(from, now time.Time) (string, bool, error) {
+	incidents, err := d.database.ListIncidentsStartedBetween(from, now)
+	if err != nil {
+		return "", false, err
+	}
+	if len(incidents) == 0 && !d.postEmpty {
+		return "", false, nil
+	}
+	return formatDigest(summarizeIncidents(incidents), now.Sub(from)), true, nil
+}
+
+// incidentStats aggregates the incidents of one digest window.
+type incidentStats struct {
+	Total    int
+	Resolved int
+	Open     int
+	Failed   int
+	// Maintenance counts alerts recorded inside a maintenance window without analysis
+	Maintenance int
+	// Triaged counts alerts triage auto-resolved without an LLM call
+	Triaged int
+	// MTTR is the mean started-to-resolved duration of the resolved incidents
+	MTTR time.Duration
+	// TopService alerted most often (ties go to the alphabetically first name)
+	TopService      string
+	TopServiceCount int
+}
+
+func summarizeIncidents(incidents []db.Incident) incidentStats {
+	stats := incidentStats{Total: len(incidents)}
+	perService := make(map[string]int)
+	var resolvedTotal time.Duration
+
+	for _, i := range incidents {
+		perService[i.ServiceName]++
+		switch i.Status {
+		case db.IncidentStatusResolved, db.IncidentStatusGrouped:
+			stats.Resolved++
+			if i.ResolvedAt != nil {
+				resolvedTotal += i.ResolvedAt.Sub(i.StartedAt)
+			}
+		case db.IncidentStatusFailed:
+			stats.Failed++
+		case db.IncidentStatusMaintenance:
+			stats.Maintenance++
+		case db.IncidentStatusTriaged:
+			stats.Triaged++
+		default:
+			stats.Open++
+		}
+	}
+	if stats.Resolved > 0 {
+		stats.MTTR = resolvedTotal / time.Duration(stats.Resolved)
+	}
+
+	services := make([]string, 0, len(perService))
+	for name := range perService {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	for _, name := range services {
+		if perService[name] > stats.TopServiceCount {
+			stats.TopService, stats.TopServiceCount = name, perService[name]
+		}
+	}
+	return stats
+}
+
+// formatDigest renders stats as a one-line Slack message, e.g.
+// "3 incidents in the last 24h (2 resolved, 1 open) · MTTR 22m · top offender: cart (2)".
+func formatDigest(stats incidentStats, window time.Duration) string {
+	if stats.Total == 0 {
+		return fmt.Sprintf("No incidents in the last %s.", shortDuration(window))
+	}
+
+	noun := "incidents"
+	if stats.Total == 1 {
+		noun = "incident"
+	}
+	var states []string
+	for _, s := range []struct {
+		n     int
+		label string
+	}{{stats.Resolved, "resolved"}, {stats.Open, "open"}, {stats.Failed, "failed"}, {stats.Maintenance, "maintenance"}, {stats.Triaged, "triaged"}} {
+		if s.n > 0 {
+			states = append(states, fmt.Sprintf("%d %s", s.n, s.label))
+		}
+	}
+
+	parts := []string{fmt.Sprintf("%d %s in the last %s (%s)", stats.Total, noun, shortDuration(window), strings.Join(states, ", "))}
+	if stats.Resolved > 0 {
+		parts = append(parts, "MTTR "+shortDuration(stats.MTTR))
+	}
+	parts = append(parts, fmt.Sprintf("top offender: %s (%d)", stats.TopService, stats.TopServiceCount))
+	return strings.Join(parts, " · ")
+}
+
+// shortDuration formats d without trailing zero units, e.g. "22m" or "1h30m".
+func shortDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	s := d.Round(time.Minute).String()
+	s = strings.TrimSuffix(s, "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// newDigest builds the digest worker from output.digest, or returns nil with a warning when the
+// database or Slack it depends on is unavailable.
+func newDigest(cfg *config.Config, database db.Store) *digest {
+	if database == nil {
+		slog.Warn("Incident digest disabled: database is not available")
+		return nil
+	}
+	slackCfg := cfg.Output.Slack
+	if slackCfg.WebhookURL == "" && slackCfg.BotToken == "" {
+		slog.Warn("Incident digest disabled: Slack is not configured")
+		return nil
+	}
+	if cfg.Output.Digest.Channel != "" {
+		slackCfg.Channel = cfg.Output.Digest.Channel
+	}
+	// Validated at startup
+	sched, _ := cfg.Output.Digest.GetSchedule()
+	return &digest{
+		database:  database,
+		poster:    output.NewSlackSenderFromConfig(slackCfg),
+		interval:  cfg.Output.Digest.GetIntervalDuration(),
+		schedule:  sched,
+		jitter:    cfg.Output.Digest.GetJitterDuration(),
+		postEmpty: cfg.Output.Digest.PostEmpty,
+		now:       time.Now,
+		rand:      rand.Int63n,
+	}
+}
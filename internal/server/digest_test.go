@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+	"helixops/internal/schedule"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPoster struct {
+	texts chan string
+}
+
+func (p *recordingPoster) SendDigest(text string) error {
+	p.texts <- text
+	return nil
+}
+
+func TestDigestSummarizesSeededIncidents(t *testing.T) {
+	database := dbtest.New(t)
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	seed := func(id, service string, started time.Time, resolveAfter time.Duration) {
+		require.NoError(t, database.CreateIncident(&db.Incident{ID: id, ServiceName: service, AlertName: "HighLatency", Severity: "critical", StartedAt: started}))
+		if resolveAfter > 0 {
+			require.NoError(t, database.ResolveIncidentAt(id, started.Add(resolveAfter), "", ""))
+		}
+	}
+	seed("inc-1", "cart", now.Add(-10*time.Hour), 20*time.Minute)
+	seed("inc-2", "cart", now.Add(-5*time.Hour), 24*time.Minute)
+	seed("inc-3", "checkout", now.Add(-time.Hour), 0)
+	seed("inc-old", "checkout", now.Add(-30*time.Hour), time.Hour)
+
+	d := &digest{database: database, interval: 24 * time.Hour}
+	text, ok, err := d.build(now.Add(-24*time.Hour), now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "3 incidents in the last 24h (2 resolved, 1 open) · MTTR 22m · top offender: cart (2)", text)
+
+	_, ok, err = d.build(now.Add(24*time.Hour), now.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, ok, "quiet windows are skipped by default")
+
+	d.postEmpty = true
+	text, ok, err = d.build(now.Add(24*time.Hour), now.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "No incidents in the last 24h.", text)
+}
+
+func TestDigestRunPostsUntilCancelled(t *testing.T) {
+	database := dbtest.New(t)
+	started := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "inc-1", ServiceName: "cart", AlertName: "HighLatency", Severity: "critical", StartedAt: started}))
+
+	poster := &recordingPoster{texts: make(chan string, 10)}
+	var jitterCalls int
+	d := &digest{
+		database: database,
+		poster:   poster,
+		interval: time.Hour,
+		jitter:   time.Minute,
+		now:      func() time.Time { return started.Add(time.Millisecond) },
+		rand:     func(n int64) int64 { jitterCalls++; return n - 1 },
+	}
+	delay, ok := d.nextDelay(started)
+	require.True(t, ok)
+	assert.Equal(t, time.Hour+time.Minute-1, delay)
+
+	d.interval, d.jitter = 10*time.Millisecond, 0
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case text := <-poster.texts:
+		assert.Contains(t, text, "1 incident in the last")
+		assert.Contains(t, text, "top offender: cart (1)")
+	case <-time.After(2 * time.Second):
+		t.Fatal("digest was not posted")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("digest did not stop on cancel")
+	}
+	assert.Equal(t, 1, jitterCalls)
+}
+
+func TestDigestCoversTheTimeSinceTheLastDigest(t *testing.T) {
+	database := dbtest.New(t)
+	poster := &recordingPoster{texts: make(chan string, 10)}
+	now := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)
+	d := &digest{database: database, poster: poster, interval: time.Hour, now: func() time.Time { return now }}
+
+	d.post()
+	assert.Empty(t, poster.texts, "a quiet first hour is skipped")
+
+	// The next run came 10m of jitter late; the incident in that gap is not lost
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "inc-1", ServiceName: "cart", AlertName: "HighLatency", Severity: "critical", StartedAt: now.Add(5 * time.Minute)}))
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "inc-2", ServiceName: "cart", AlertName: "HighLatency", Severity: "critical", StartedAt: now.Add(65 * time.Minute)}))
+	now = now.Add(70 * time.Minute)
+	d.post()
+	require.Len(t, poster.texts, 1)
+	assert.Equal(t, "2 incidents in the last 1h10m (2 open) · top offender: cart (2)", <-poster.texts)
+
+	// A restarted process continues from the same point
+	restarted := &digest{database: database, poster: poster, interval: time.Hour, postEmpty: true, now: func() time.Time { return now.Add(30 * time.Minute) }}
+	restarted.post()
+	assert.Equal(t, "No incidents in the last 30m.", <-poster.texts)
+}
+
+func TestDigestScheduleSetsPostingTimes(t *testing.T) {
+	weekdays, err := schedule.Parse("0 9 * * 1-5", nil)
+	require.NoError(t, err)
+	d := &digest{interval: 24 * time.Hour, schedule: weekdays}
+
+	// Saturday evening waits for Monday morning
+	delay, ok := d.nextDelay(time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, 39*time.Hour, delay)
+
+	never, err := schedule.Parse("0 0 30 2 *", nil)
+	require.NoError(t, err)
+	d.schedule = never
+	_, ok = d.nextDelay(time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
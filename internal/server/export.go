@@ -0,0 +1,147 @@
+package server
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"helixops/internal/db"
+)
+
+// exportManifest describes the contents of a postmortem export archive.
+type exportManifest struct {
+	GeneratedAt time.Time        `json:"generated_at"`
+	From        *time.Time       `json:"from,omitempty"`
+	To          *time.Time       `json:"to,omitempty"`
+	Count       int              `json:"count"`
+	Postmortems []exportedReport `json:"postmortems"`
+}
+
+// exportedReport is one manifest entry, pointing at its file in the archive.
+type exportedReport struct {
+	IncidentID  string     `json:"incident_id"`
+	ServiceName string     `json:"service_name"`
+	AlertName   string     `json:"alert_name"`
+	Severity    string     `json:"severity"`
+	StartedAt   time.Time  `json:"started_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	File        string     `json:"file"`
+}
+
+// unsafeFileChars are replaced in service names used as archive file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// HandleExportPostmortems streams a ZIP of every stored postmortem resolved within the optional
+// from/to range, plus a manifest.json. Entries are written as rows are read, so the archive is
+// never held in memory.
+func (h *Handler) HandleExportPostmortems(w http.ResponseWriter, r *http.Request) {
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	from, err := parseExportBound(r.URL.Query().Get("from"), false)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid from: %v", err), http.StatusBadRequest)
+		return
+	}
+	to, err := parseExportBound(r.URL.Query().Get("to"), true)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid to: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="postmortems-%s.zip"`, now.Format("20060102-150405")))
+
+	manifest := exportManifest{GeneratedAt: now, Postmortems: []exportedReport{}}
+	if !from.IsZero() {
+		manifest.From = &from
+	}
+	if !to.IsZero() {
+		manifest.To = &to
+	}
+
+	zw := zip.NewWriter(w)
+	err = h.database.ForEachPostmortem(from, to, func(incident db.Incident) error {
+		entry := exportedReport{
+			IncidentID:  incident.ID,
+			ServiceName: incident.ServiceName,
+			AlertName:   incident.AlertName,
+			Severity:    incident.Severity,
+			StartedAt:   incident.StartedAt,
+			ResolvedAt:  incident.ResolvedAt,
+			File:        exportFileName(incident),
+		}
+
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: entry.File, Method: zip.Deflate, Modified: resolvedOrStarted(incident)})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write([]byte(*incident.AISummary)); err != nil {
+			return err
+		}
+		manifest.Postmortems = append(manifest.Postmortems, entry)
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent; leave the archive unterminated so the client sees it is truncated
+		slog.Error("Postmortem export failed", "error", err)
+		return
+	}
+
+	manifest.Count = len(manifest.Postmortems)
+	f, err := zw.Create("manifest.json")
+	if err == nil {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(manifest)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		slog.Error("Failed to finish postmortem export", "error", err)
+		return
+	}
+	slog.Info("Exported postmortems", "count", manifest.Count)
+}
+
+// parseExportBound accepts an RFC 3339 timestamp or a YYYY-MM-DD date. A date used as the
+// upper bound includes that whole day.
+func parseExportBound(s string, upper bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD, got %q", s)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// exportFileName names an archive entry by resolution date, service, and incident ID.
+func exportFileName(incident db.Incident) string {
+	return fmt.Sprintf("postmortems/%s_%s_%s.md",
+		resolvedOrStarted(incident).UTC().Format("2006-01-02"),
+		unsafeFileChars.ReplaceAllString(incident.ServiceName, "_"),
+		unsafeFileChars.ReplaceAllString(incident.ID, "_"))
+}
+
+func resolvedOrStarted(incident db.Incident) time.Time {
+	if incident.ResolvedAt != nil {
+		return *incident.ResolvedAt
+	}
+	return incident.StartedAt
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedPostmortems stores resolved incidents on each day given, plus an open one that is never exported.
+func seedPostmortems(t *testing.T, database *db.DB, days ...int) {
+	t.Helper()
+	for _, day := range days {
+		started := time.Date(2024, 3, day, 9, 0, 0, 0, time.UTC)
+		id := started.Format("inc-0102")
+		require.NoError(t, database.CreateIncident(&db.Incident{ID: id, ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical", StartedAt: started}))
+		require.NoError(t, database.ResolveIncidentAt(id, started.Add(time.Hour), "pool exhausted", "# Postmortem "+id))
+	}
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "inc-open", ServiceName: "cart", AlertName: "HighErrorRate", StartedAt: time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)}))
+}
+
+func exportZip(t *testing.T, h *Handler, query string) *zip.Reader {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/postmortems/export"+query, nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+	return zr
+}
+
+func readManifest(t *testing.T, zr *zip.Reader) exportManifest {
+	t.Helper()
+	f, err := zr.Open("manifest.json")
+	require.NoError(t, err)
+	defer f.Close()
+	var m exportManifest
+	require.NoError(t, json.NewDecoder(f).Decode(&m))
+	return m
+}
+
+func TestExportPostmortemsZip(t *testing.T) {
+	database := dbtest.New(t)
+	seedPostmortems(t, database, 1, 10, 20)
+	h := NewHandler(&config.Config{}, nil, nil, nil, nil, nil, database)
+
+	zr := exportZip(t, h, "")
+	require.Len(t, zr.File, 4, "three postmortems and the manifest")
+	assert.Equal(t, "postmortems/2024-03-01_checkout_inc-0301.md", zr.File[0].Name)
+
+	f, err := zr.File[0].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "# Postmortem inc-0301", string(content))
+
+	m := readManifest(t, zr)
+	assert.Equal(t, 3, m.Count)
+	assert.Equal(t, "inc-0320", m.Postmortems[2].IncidentID)
+	assert.Equal(t, "postmortems/2024-03-20_checkout_inc-0320.md", m.Postmortems[2].File)
+}
+
+func TestExportPostmortemsDateRange(t *testing.T) {
+	database := dbtest.New(t)
+	seedPostmortems(t, database, 1, 10, 20)
+	h := NewHandler(&config.Config{}, nil, nil, nil, nil, nil, database)
+
+	zr := exportZip(t, h, "?from=2024-03-05&to=2024-03-20")
+	require.Len(t, zr.File, 3, "the to date is inclusive")
+	m := readManifest(t, zr)
+	assert.Equal(t, 2, m.Count)
+	assert.Equal(t, "inc-0310", m.Postmortems[0].IncidentID)
+}
+
+func TestExportPostmortemsRejectsBadRange(t *testing.T) {
+	h := NewHandler(&config.Config{}, nil, nil, nil, nil, nil, dbtest.New(t))
+	rec := httptest.NewRecorder()
+	SetupRouter(h).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/postmortems/export?from=last-week", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
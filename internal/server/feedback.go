@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"helixops/internal/db"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// feedbackRequest is the body accepted by HandleIncidentFeedback.
+type feedbackRequest struct {
+	Correct     *bool  `json:"correct"`
+	ActualCause string `json:"actual_cause"`
+}
+
+// HandleIncidentFeedback records a responder's verdict on whether an incident's RCA was correct,
+// replacing any earlier verdict for the incident.
+func (h *Handler) HandleIncidentFeedback(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req feedbackRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Correct == nil {
+		http.Error(w, "correct is required", http.StatusBadRequest)
+		return
+	}
+
+	incident, err := h.database.GetIncident(id)
+	if err != nil {
+		slog.Error("Failed to load incident", "id", id, "error", err)
+		http.Error(w, "Failed to retrieve incident", http.StatusInternalServerError)
+		return
+	}
+	if incident == nil {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	}
+
+	f := db.Feedback{IncidentID: id, Correct: *req.Correct, ActualCause: strings.TrimSpace(req.ActualCause)}
+	if err := h.database.SaveFeedback(f); err != nil {
+		slog.Error("Failed to save feedback", "id", id, "error", err)
+		http.Error(w, "Failed to save feedback", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Recorded RCA feedback", "incident_id", id, "correct", f.Correct, "confidence", incident.Confidence)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "success",
+		"incident_id":     id,
+		"correct":         f.Correct,
+		"confidence":      incident.Confidence,
+		"confidence_band": db.ConfidenceBand(incident.Confidence),
+	})
+}
+
+// HandleConfidenceCalibration reports, per band of stated RCA confidence, how many RCAs with feedback
+// were judged correct, so overconfident bands stand out.
+func (h *Handler) HandleConfidenceCalibration(w http.ResponseWriter, r *http.Request) {
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	bands, err := h.database.ConfidenceCalibration()
+	if err != nil {
+		slog.Error("Failed to compute confidence calibration", "error", err)
+		http.Error(w, "Failed to retrieve confidence calibration", http.StatusInternalServerError)
+		return
+	}
+	if bands == nil {
+		bands = []db.CalibrationBand{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   bands,
+	})
+}
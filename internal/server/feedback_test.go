@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidentFeedbackFeedsCalibration(t *testing.T) {
+	database := dbtest.New(t)
+	require.NoError(t, database.CreateIncident(&db.Incident{
+		ID: "inc-1", ServiceName: "checkout", AlertName: "HighLatency", Severity: "critical",
+		StartedAt: time.Now(), Confidence: "85%",
+	}))
+
+	router := chi.NewRouter()
+	NewHandler(&config.Config{}, nil, nil, nil, nil, nil, database).RegisterRoutes(router)
+	post := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/"+id+"/feedback", strings.NewReader(body)))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, post("inc-1", `{"actual_cause": "DNS"}`).Code, "correct is required")
+	assert.Equal(t, http.StatusNotFound, post("missing", `{"correct": true}`).Code)
+
+	rec := post("inc-1", `{"correct": false, "actual_cause": "DNS outage"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"confidence_band":"high"`)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feedback/calibration", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Data []db.CalibrationBand `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, []db.CalibrationBand{{Band: db.ConfidenceBandHigh, Total: 1, Correct: 0, Accuracy: 0}}, resp.Data)
+}
@@ -1,23 +1,32 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"helixops/internal/analyzer"
+	"helixops/internal/capture"
+	"helixops/internal/clients/alertmanager"
 	"helixops/internal/config"
 	"helixops/internal/db"
+	"helixops/internal/httpx"
 	"helixops/internal/models"
 	"helixops/internal/orchestrator"
 	"helixops/internal/output"
 	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type Handler struct {
@@ -27,12 +36,73 @@ type Handler struct {
 	generator    *postmortem.Generator
 	mdReporter   *output.MarkdownReporter
 	slackSender  *output.SlackSender
-	database     *db.DB
+	database     db.Store
+	// incidents records incident state: the database when configured, otherwise an optional
+	// in-memory registry; nil when neither is available
+	incidents db.IncidentStore
+	// notifications remembers which notifications went out per incident, so duplicates are skipped;
+	// backed by the same store as incidents
+	notifications db.NotificationLog
+
+	// silences is optional; when set, firing alerts covered by an active Alertmanager silence are skipped
+	silences silenceChecker
+	// issues is optional; when set, each postmortem opens an issue in the service's mapped repo
+	issues issueCreator
+	// webhook is optional; when set, analyses and postmortems are also POSTed as signed JSON
+	webhook *output.WebhookSender
+	// rootCauses is optional; when set, each analysis is counted by root-cause category on /metrics
+	rootCauses *output.RootCauseMetrics
+	// captures is optional; when set, every raw webhook body is saved, redacted, for replay
+	captures *capture.Store
+	// receivers are the named profile handlers served at /webhook/{receiver}
+	receivers map[string]*Handler
+	// receiver is this profile's name under /webhook/{receiver}; empty for the default handler
+	receiver string
+	// severityPipelines analyze alerts of the severities in analysis.severity_profiles with their
+	// profile's settings; other severities use orchestrator and analyzer
+	severityPipelines map[string]analysisPipeline
+	// rules answers POST /remediations without running the LLM
+	rules *remediation.Engine
+	// postmortems decides which resolved incidents get a full postmortem
+	postmortems *postmortemSampler
+	// recurrence mutes full analysis notifications for repeated root causes; nil unless output.recurring is enabled
+	recurrence *recurrenceTracker
+
+	// inflight tracks asynchronous alert processing so shutdown can wait for it
+	inflight inflightWork
+	// stopping is cancelled when shutdown begins, so retries stop waiting to try again
+	stopping context.Context
+	stop     context.CancelFunc
+	// scheduled holds the timers of delayed postmortems that are not yet due
+	scheduled scheduledRuns
+	// resolving holds the IDs of incidents whose manual resolution is generating a postmortem
+	resolving sync.Map
+}
+
+// analysisPipeline gathers context and runs the RCA with the settings of one analysis profile.
+type analysisPipeline struct {
+	profile      string
+	orchestrator *orchestrator.Orchestrator
+	analyzer     *analyzer.Analyzer
+}
+
+// pipelineFor returns the pipeline alerts of a normalized severity are analyzed with: the severity's
+// analysis profile when one is configured, otherwise the handler's own.
+func (h *Handler) pipelineFor(severity string) analysisPipeline {
+	if p, ok := h.severityPipelines[severity]; ok {
+		return p
+	}
+	return analysisPipeline{orchestrator: h.orchestrator, analyzer: h.analyzer}
+}
+
+// silenceChecker looks up an active silence covering an alert's labels.
+type silenceChecker interface {
+	MatchingSilence(ctx context.Context, labels map[string]string) (*alertmanager.Silence, error)
 }
 
 // NewHandler constructs a Handler struct with the necessary dependencies injected.
-func NewHandler(cfg *config.Config, orch *orchestrator.Orchestrator, anlz *analyzer.Analyzer, gen *postmortem.Generator, md *output.MarkdownReporter, slack *output.SlackSender, database *db.DB) *Handler {
-	return &Handler{
+func NewHandler(cfg *config.Config, orch *orchestrator.Orchestrator, anlz *analyzer.Analyzer, gen *postmortem.Generator, md *output.MarkdownReporter, slack *output.SlackSender, database db.Store) *Handler {
+	h := &Handler{
 		cfg:          cfg,
 		orchestrator: orch,
 		analyzer:     anlz,
@@ -40,17 +110,47 @@ func NewHandler(cfg *config.Config, orch *orchestrator.Orchestrator, anlz *analy
 		mdReporter:   md,
 		slackSender:  slack,
 		database:     database,
+		receivers:    make(map[string]*Handler),
+		rules:        remediation.NewEngineFromConfig(cfg.Services, cfg.Analysis.GetPlatform()),
+		postmortems:  &postmortemSampler{policy: cfg.Postmortem.Policy},
+		recurrence:   newRecurrenceTracker(cfg.Output.Recurring),
+
+		severityPipelines: make(map[string]analysisPipeline),
+	}
+	h.stopping, h.stop = context.WithCancel(context.Background())
+	if database != nil {
+		h.incidents = database
+		h.notifications = database
 	}
+	return h
 }
 
 // RegisterRoutes maps REST API paths to their corresponding HTTP handler methods on the provided router.
 func (h *Handler) RegisterRoutes(r chi.Router) {
 	r.Post("/webhook", h.HandleWebhook)
+	r.Post("/webhook/{receiver}", h.HandleReceiverWebhook)
 	r.Get("/health", h.HandleHealth)
 	r.Get("/ready", h.HandleReady)
+	r.Get("/config", h.requireAdmin(h.HandleConfig))
+	r.Post("/admin/validate-queries", h.requireAdmin(h.HandleValidateQueries))
+	r.Get("/metrics", h.HandleMetrics)
 
 	r.Get("/postmortems", h.HandleListPostmortems)
+	r.Get("/postmortems/export", h.HandleExportPostmortems)
 	r.Get("/postmortems/{id}", h.HandleGetPostmortem)
+
+	r.Get("/incidents/{id}/context", h.HandleGetIncidentContext)
+	r.Post("/incidents/merge", h.requireAdmin(h.HandleMergeIncidents))
+	r.Post("/incidents/{id}/resolve", h.HandleResolveIncident)
+	r.Post("/incidents/{id}/feedback", h.HandleIncidentFeedback)
+	r.Get("/feedback/calibration", h.HandleConfidenceCalibration)
+
+	r.Post("/remediations", h.HandleRemediations)
+
+	r.Post("/events/deploy", h.HandleDeployEvent)
+
+	r.Get("/service-mappings", h.HandleListServiceMappings)
+	r.Post("/service-mappings/{service}/confirm", h.HandleConfirmServiceMapping)
 }
 
 // HandleWebhook parses incoming HTTP POST payloads from Prometheus Alertmanager.
@@ -60,33 +160,30 @@ func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Limit request body size (max 1MB)
-	maxBodySize := int64(1 << 20) // 1MB
-	if r.ContentLength > maxBodySize {
-		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
-		return
-	}
-
-	// Read request body
-	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	// Read the body: JSON only, gzip decoded, capped at 1MB after decompression
+	body, err := readJSONBody(w, r, maxWebhookBodySize)
 	if err != nil {
-		log.Printf("Failed to read request body: %v", err)
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		slog.Error("Failed to read request body", "error", err)
+		writeBodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
 
-	// Parse AlertManager webhook payload
+	// Parse AlertManager webhook payload, capturing the raw body first so unparseable payloads are kept too
 	var alertPayload models.AlertManagerPayload
-	if err := json.Unmarshal(body, &alertPayload); err != nil {
-		log.Printf("Failed to parse webhook payload: %v", err)
+	err = json.Unmarshal(body, &alertPayload)
+	if r.Header.Get(capture.ReplayHeader) == "" {
+		h.capturePayload(body, alertPayload)
+	}
+	if err != nil {
+		slog.Error("Failed to parse webhook payload", "error", err)
 		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
 		return
 	}
 
 	// Validate alerts
 	if len(alertPayload.Alerts) == 0 {
-		log.Printf("No alerts in payload")
+		slog.Warn("No alerts in payload")
 		http.Error(w, "No alerts in payload", http.StatusBadRequest)
 		return
 	}
@@ -94,12 +191,12 @@ func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	// Validate each alert has required fields
 	for i, alert := range alertPayload.Alerts {
 		if alert.Labels == nil {
-			log.Printf("Alert %d missing labels", i)
+			slog.Warn("Alert missing labels", "index", i)
 			alertPayload.Alerts = append(alertPayload.Alerts[:i], alertPayload.Alerts[i+1:]...)
 			continue
 		}
 		if alert.Labels["alertname"] == "" {
-			log.Printf("Alert %d missing alertname label", i)
+			slog.Warn("Alert missing alertname label", "index", i)
 			alertPayload.Alerts = append(alertPayload.Alerts[:i], alertPayload.Alerts[i+1:]...)
 			continue
 		}
@@ -111,12 +208,12 @@ func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("Received %d alerts from %s", len(alertPayload.Alerts), alertPayload.Receiver)
+	slog.Info("Received alerts", "count", len(alertPayload.Alerts), "receiver", alertPayload.Receiver)
 
-	// Process alerts asynchronously
-	go h.processAlerts(alertPayload)
+	// Queue and process alerts asynchronously
+	h.dispatch(alertPayload)
 
-	// Acknowledge immediately
+	// Acknowledge immediately; the payload is persisted, so a restart does not lose it
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "accepted",
@@ -124,130 +221,648 @@ func (h *Handler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// capturePayload saves a raw webhook body when alerting.capture is enabled, named after the first
+// alert's fingerprint. HandleWebhook skips it for replays of earlier captures. A failed capture is
+// logged and never rejects the webhook.
+func (h *Handler) capturePayload(body []byte, payload models.AlertManagerPayload) {
+	if h.captures == nil {
+		return
+	}
+	var fingerprint string
+	if len(payload.Alerts) > 0 {
+		fingerprint = payload.Alerts[0].GetFingerprint()
+	}
+	path, err := h.captures.Save(h.receiver, fingerprint, body)
+	if err != nil {
+		slog.Warn("Failed to capture webhook payload", "error", err)
+		return
+	}
+	slog.Debug("Captured webhook payload", "path", path)
+}
+
+// HandleReceiverWebhook routes an Alertmanager payload to the named receiver profile, so each team's
+// alerts are analyzed with that profile's LLM, outputs, and service mappings.
+func (h *Handler) HandleReceiverWebhook(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "receiver")
+	rh, ok := h.receivers[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown webhook receiver %q", name), http.StatusNotFound)
+		return
+	}
+	rh.HandleWebhook(w, r)
+}
+
+// dispatch queues a payload durably and processes it in the background, tracked so shutdown waits for it.
+func (h *Handler) dispatch(payload models.AlertManagerPayload) {
+	h.run(h.enqueue(payload), payload)
+}
+
+// run processes a payload in the background and marks its queue entry done, or failed with the alerts
+// that could not be processed; id 0 means it was not queued.
+func (h *Handler) run(id int64, payload models.AlertManagerPayload) {
+	if !h.inflight.add() {
+		slog.Warn("Shutting down; leaving alerts queued for the next start", "queue_id", id, "count", len(payload.Alerts))
+		return
+	}
+	go func() {
+		defer h.inflight.done()
+		h.finishQueued(id, h.processAlerts(payload))
+	}()
+}
+
+// wait blocks until in-flight alert processing on this handler and its receivers finishes,
+// or until ctx is done. Delayed postmortems that are not yet due are cancelled; they stay queued and
+// are rescheduled on the next start.
+func (h *Handler) wait(ctx context.Context) error {
+	h.scheduled.stop()
+	h.inflight.close()
+	h.stop()
+	for _, rh := range h.receivers {
+		rh.scheduled.stop()
+		rh.inflight.close()
+		rh.stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.inflight.wg.Wait()
+		for _, rh := range h.receivers {
+			rh.inflight.wg.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// inflightWork counts background processing for shutdown. Once closed it refuses new work, so the
+// WaitGroup never gains members while shutdown is waiting on it.
+type inflightWork struct {
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// add registers one unit of work, reporting false once shutdown has begun.
+func (w *inflightWork) add() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return false
+	}
+	w.wg.Add(1)
+	return true
+}
+
+// done marks a unit of work registered with add as finished.
+func (w *inflightWork) done() {
+	w.wg.Done()
+}
+
+// close refuses further work.
+func (w *inflightWork) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+}
+
 // processAlerts iterates through webhook payloads and asynchronously orchestrates RCA analysis or postmortem generation.
-func (h *Handler) processAlerts(payload models.AlertManagerPayload) {
-	for _, alert := range payload.Alerts {
+// It returns the failures of alerts whose processing was given up on.
+func (h *Handler) processAlerts(payload models.AlertManagerPayload) error {
+	payload.ApplyCommonAnnotations()
+	for i := range payload.Alerts {
+		payload.Alerts[i] = h.normalizeSeverity(h.applyAnnotationFallbacks(payload.Alerts[i]))
+	}
+
+	inhibited := inhibitedAlerts(payload.Alerts, h.cfg.Alerting.InhibitRules)
+	resolveDelay := h.cfg.Postmortem.GetResolveDelayDuration()
+	var (
+		delayed []models.AlertItem
+		errs    []error
+	)
+
+	for i, alert := range payload.Alerts {
 		serviceName := extractServiceName(alert.Labels)
 		if serviceName == "" {
-			log.Printf("Skipping alert %s: missing service_name label", alert.Labels["alertname"])
+			slog.Warn("Skipping alert: missing service_name label", "alert", alert.Labels["alertname"])
 			continue
 		}
 
-		if alert.Status == "resolved" {
-			log.Printf("Processing RESOLVED alert %s for service %s", alert.Labels["alertname"], serviceName)
-			if h.generator == nil || h.orchestrator == nil {
-				continue
-			}
-
-			// Prepare context mapping back to incident start for full postmortem view
-			ctx, err := h.orchestrator.PrepareContext(context.Background(), serviceName, alert.StartsAt)
-			if err != nil {
-				log.Printf("Failed to prepare context for postmortem on %s: %v", serviceName, err)
-				continue
-			}
+		if reason, ok := ignoreReason(alert, serviceName, h.cfg.Alerting); ok {
+			slog.Info("Skipping ignored alert", "alert", alert.Labels["alertname"], "service", serviceName, "reason", reason)
+			continue
+		}
 
-			// Map Alert Info
-			ctx.Alert = models.AlertInfo{
-				Name:      alert.Labels["alertname"],
-				Severity:  alert.Labels["severity"],
-				Summary:   alert.GetAnnotation("summary"),
-				Labels:    alert.Labels,
-				StartedAt: alert.StartsAt,
-			}
+		if reason, ok := inhibited[i]; ok {
+			slog.Info("Skipping inhibited alert", "alert", alert.Labels["alertname"], "service", serviceName, "reason", reason)
+			continue
+		}
 
-			pm, err := h.generator.Generate(context.Background(), ctx)
-			if err != nil {
-				log.Printf("Failed to generate postmortem for %s: %v", serviceName, err)
-				continue
+		if alert.Status == "resolved" {
+			// Resolutions close incidents even inside a maintenance window; only incidents that were
+			// recorded as maintenance or auto-resolved by triage skip the postmortem. Aggregated
+			// incidents get one postmortem per group.
+			// With a resolve delay the postmortem waits until backends have flushed late logs and traces.
+			switch {
+			case h.closeUnanalyzedIncident(alert, serviceName), h.resolveGroupMember(alert, serviceName):
+			case resolveDelay > 0:
+				delayed = append(delayed, alert)
+			default:
+				errs = append(errs, h.processResolved(alert, serviceName))
 			}
+			continue
+		}
 
-			log.Printf("Generated Postmortem ID: %s for service: %s", pm.ID, serviceName)
+		if alert.Status != "firing" {
+			continue
+		}
 
-			// Resolve incident in database if available
-			if h.database != nil {
-				if err := h.database.ResolveIncident(pm.ID, pm.RootCause, pm.Markdown); err != nil {
-					log.Printf("Failed to resolve incident in database: %v", err)
-				} else {
-					log.Printf("Resolved incident %s in database", pm.ID)
-				}
-			}
+		if h.isSilenced(alert, serviceName) {
+			continue
+		}
 
-			if h.mdReporter != nil {
-				if err := h.mdReporter.SendPostmortem(pm); err != nil {
-					log.Printf("Failed to save postmortem markdown: %v", err)
-				}
-			}
+		if window, ok := activeMaintenanceWindow(h.cfg.Alerting.MaintenanceWindows, serviceName, time.Now()); ok {
+			h.recordMaintenanceIncident(alert, serviceName, window)
 			continue
 		}
 
-		if alert.Status != "firing" {
+		if h.mergeIntoGroup(alert, serviceName) {
 			continue
 		}
 
-		log.Printf("Processing alert %s for service %s", alert.Labels["alertname"], serviceName)
+		errs = append(errs, h.processFiring(alert, serviceName))
+	}
 
-		// Guard against nil dependencies (for tests)
-		if h.orchestrator == nil || h.analyzer == nil {
-			log.Printf("Skipping alert processing: missing orchestrator or analyzer")
-			continue
+	if len(delayed) > 0 {
+		h.scheduleResolved(delayed, time.Now().Add(resolveDelay))
+	}
+	return errors.Join(errs...)
+}
+
+// processFiring runs RCA for a firing alert, persists the incident, and notifies output channels.
+// Context gathering and analysis are retried with backoff; if they keep failing, a failed incident
+// row is recorded together with the alert payload so the incident stays visible and can be replayed,
+// and the failure is returned.
+func (h *Handler) processFiring(alert models.AlertItem, serviceName string) error {
+	return h.processFiringIncident(alert, serviceName, "")
+}
+
+// processFiringIncident is processFiring for an incident already recorded as incidentID, such as an
+// alert released from the group of a failed leader; with an empty incidentID a new incident is recorded.
+func (h *Handler) processFiringIncident(alert models.AlertItem, serviceName, incidentID string) error {
+	slog.Info("Processing alert", "alert", alert.Labels["alertname"], "service", serviceName)
+
+	// Guard against nil dependencies (for tests)
+	if h.orchestrator == nil || h.analyzer == nil {
+		slog.Warn("Skipping alert processing: missing orchestrator or analyzer")
+		return nil
+	}
+
+	// In bot-token mode, open a Slack thread now so responders see the alert before the RCA lands,
+	// unless the service keeps repeating a cause whose notifications are muted
+	incidentKey := notificationKey(alert.GetFingerprint(), alert.StartsAt)
+	var threadTS string
+	if h.slackSender != nil && h.slackSender.Threaded() && (h.recurrence == nil || !h.recurrence.muting(serviceName, time.Now())) {
+		// A re-delivered alert gets the thread its first delivery started
+		var err error
+		threadTS, err = h.notifyOnceRef(incidentKey, notifySlackFiring, false, func() (string, error) {
+			return h.slackSender.SendFiring(serviceName, alert.ToAlertInfo())
+		})
+		if err != nil {
+			slog.Error("Failed to send Slack firing notification", "error", err)
 		}
+	}
+
+	pipeline := h.pipelineFor(alert.Labels["severity"])
+	if pipeline.profile != "" {
+		slog.Info("Analyzing with analysis profile", "alert", alert.Labels["alertname"], "service", serviceName, "profile", pipeline.profile)
+	}
+
+	// Downstream requests made for this incident carry its ID, so backend logs can be tied back to it
+	recorded := incidentID != ""
+	if !recorded {
+		incidentID = uuid.New().String()
+		recorded = h.recordGroupLeader(alert, serviceName, incidentID, threadTS)
+	}
+	reqCtx := httpx.WithRequestID(context.Background(), h.cfg.App.RequestIDHeader, incidentID)
 
+	var (
+		ctx    *models.AnalysisContext
+		result *models.AnalysisResult
+	)
+	err := h.withRetry(serviceName, func(last bool) error {
 		// Create analysis context with metrics, logs, commits, and traces
-		ctx, err := h.orchestrator.PrepareContext(context.Background(), serviceName, alert.StartsAt)
+		var err error
+		ctx, err = pipeline.orchestrator.PrepareAlertContext(reqCtx, serviceName, alert.Labels["severity"], alert.StartsAt)
 		if err != nil {
-			log.Printf("Failed to prepare context for %s: %v", serviceName, err)
-			continue
+			return fmt.Errorf("failed to prepare context: %w", err)
+		}
+		if err := h.checkRequiredSources(pipeline.orchestrator, ctx, last); err != nil {
+			return fmt.Errorf("failed to prepare context: %w", err)
 		}
 
 		// Map alert info to context
-		ctx.Alert = models.AlertInfo{
-			Name:      alert.Labels["alertname"],
-			Severity:  alert.Labels["severity"],
-			Summary:   alert.GetAnnotation("summary"),
-			Labels:    alert.Labels,
-			StartedAt: alert.StartsAt,
-		}
+		ctx.Alert = alert.ToAlertInfo()
+		pipeline.orchestrator.AttachPriorIncidents(ctx)
+		pipeline.orchestrator.AttachDashboards(ctx)
 
 		// Analyze with full context (metrics, commits, traces)
-		result, err := h.analyzer.AnalyzeWithContext(context.Background(), ctx)
+		result, err = pipeline.analyzer.AnalyzeWithContext(reqCtx, ctx)
 		if err != nil {
-			log.Printf("Failed to analyze alert for %s: %v", serviceName, err)
-			continue
+			return fmt.Errorf("failed to analyze alert: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Giving up on alert", "alert", alert.Labels["alertname"], "service", serviceName, "error", err)
+		if recorded {
+			failed := &db.Incident{ID: incidentID, Severity: alert.Labels["severity"], Status: db.IncidentStatusFailed, SlackThreadTS: threadTS, LastError: err.Error()}
+			if err := h.database.UpdateIncident(failed); err != nil {
+				slog.Error("Failed to record failed incident", "incident_id", incidentID, "error", err)
+			}
+			h.releaseGroup(incidentID)
+		} else {
+			h.recordFailedIncident(alert, serviceName, threadTS, err)
+		}
+		return fmt.Errorf("%s on %s: %w", alert.Labels["alertname"], serviceName, err)
+	}
+	result.ID = incidentID
 
-		log.Printf("Analysis complete for %s: %s", serviceName, result.Summary)
+	slog.Info("Analysis complete", "service", serviceName, "summary", result.Summary)
+	h.applyAssessedSeverity(result)
+	if h.rootCauses != nil {
+		category := h.rootCauses.Record(result)
+		slog.Debug("Counted root cause", "service", serviceName, "category", category)
+	}
 
-		// Store incident in database if available
-		if h.database != nil && result != nil {
-			incident := &db.Incident{
-				ID:          result.ID,
-				ServiceName: serviceName,
-				AlertName:   alert.Labels["alertname"],
-				Severity:    alert.Labels["severity"],
-				StartedAt:   alert.StartsAt,
-			}
-			if err := h.database.CreateIncident(incident); err != nil {
-				log.Printf("Failed to create incident in database: %v", err)
-			} else {
-				log.Printf("Created incident %s in database", result.ID)
-			}
+	// Record the incident if a store is available
+	if h.incidents != nil {
+		incident := &db.Incident{
+			ID:            result.ID,
+			ServiceName:   serviceName,
+			AlertName:     alert.Labels["alertname"],
+			Severity:      result.Severity,
+			StartedAt:     alert.StartsAt,
+			Fingerprint:   alert.GetFingerprint(),
+			SlackThreadTS: threadTS,
+			Confidence:    result.Confidence,
 		}
+		if result.Triage == analyzer.TriageAutoResolved {
+			incident.Status = db.IncidentStatusTriaged
+		}
+		store := h.incidents.CreateIncident
+		if recorded {
+			store = h.database.UpdateIncident
+		}
+		if err := store(incident); err != nil {
+			slog.Error("Failed to create incident in database", "error", err)
+		} else {
+			slog.Info("Created incident in database", "incident_id", result.ID)
+			h.saveAnalysisArtifacts(result, ctx)
+		}
+	}
 
-		// Send to output channels (Slack and Markdown)
-		if h.slackSender != nil {
-			if err := h.slackSender.SendAnalysis(result); err != nil {
-				log.Printf("Failed to send Slack notification: %v", err)
-			} else {
-				log.Printf("Sent Slack notification for %s", serviceName)
-			}
+	// Send to output channels (Slack and Markdown); triage auto-resolutions and suppressed no-anomaly
+	// results are recorded but do not notify, and muted recurring causes skip the webhook
+	quiet := result.Triage == analyzer.TriageAutoResolved ||
+		result.Triage == analyzer.TriageNoAnomaly && h.cfg.Analysis.GetNoAnomaly() == config.NoAnomalySuppress
+	if h.recurrence != nil && !quiet && result.Triage != analyzer.TriageNoAnomaly {
+		if n, muted := h.recurrence.observe(serviceName, incidentKey, result.RootCause, time.Now()); muted {
+			result.Recurrence = n
+		}
+	}
+	switch {
+	case quiet:
+		slog.Info("Recorded alert without notifying", "service", serviceName, "alert", result.AlertName, "triage", result.Triage, "reason", result.TriageReason)
+	case h.slackSender != nil && result.Triage == analyzer.TriageNoAnomaly:
+		if err := h.notifyOnce(incidentKey, notifySlackAnalysis, false, func() error { return h.slackSender.SendNoAnomaly(result, threadTS) }); err != nil {
+			slog.Error("Failed to send Slack notification", "error", err)
+		}
+	case h.slackSender != nil && result.Recurrence > 0:
+		if err := h.notifyOnce(incidentKey, notifySlackAnalysis, false, func() error { return h.slackSender.SendRecurring(result, threadTS) }); err != nil {
+			slog.Error("Failed to send Slack notification", "error", err)
+		} else {
+			slog.Info("Sent recurring-cause notification instead of the full analysis", "service", serviceName, "recurrence", result.Recurrence)
+		}
+	case h.slackSender != nil:
+		if err := h.notifyOnce(incidentKey, notifySlackAnalysis, false, func() error { return h.slackSender.SendAnalysisInThread(result, threadTS) }); err != nil {
+			slog.Error("Failed to send Slack notification", "error", err)
+		} else {
+			slog.Info("Sent Slack notification", "service", serviceName)
 		}
+	}
 
-		if h.mdReporter != nil {
-			if err := h.mdReporter.Report(result); err != nil {
-				log.Printf("Failed to save analysis markdown: %v", err)
-			}
+	if h.webhook != nil && !quiet && result.Recurrence == 0 {
+		webhookCtx, cancel := h.untilStopping(reqCtx)
+		defer cancel()
+		if err := h.notifyOnce(incidentKey, notifyWebhookAnalysis, false, func() error { return h.webhook.SendAnalysis(webhookCtx, result) }); err != nil {
+			slog.Error("Failed to send analysis webhook", "error", err)
+		}
+	}
+
+	if h.mdReporter != nil {
+		if err := h.mdReporter.Report(result); err != nil {
+			slog.Error("Failed to save analysis markdown", "error", err)
+		}
+	}
+	return nil
+}
+
+// saveAnalysisArtifacts stores the context snapshot and raw LLM response of a recorded incident. Both
+// need the database; the in-memory registry keeps incident state only.
+func (h *Handler) saveAnalysisArtifacts(result *models.AnalysisResult, ctx *models.AnalysisContext) {
+	if h.database == nil {
+		return
+	}
+	if err := h.database.SaveContextSnapshot(result.ID, ctx); err != nil {
+		slog.Error("Failed to store context snapshot", "incident_id", result.ID, "error", err)
+	}
+	if result.LLMResponse != nil {
+		if err := h.database.SaveRawLLMResponse(result.ID, result.LLMResponse); err != nil {
+			slog.Error("Failed to store raw LLM response", "incident_id", result.ID, "error", err)
+		}
+	}
+}
+
+// processResolved generates a postmortem for a resolved alert and closes the matching open incident.
+// It returns the failure when postmortem generation is given up on.
+func (h *Handler) processResolved(alert models.AlertItem, serviceName string) error {
+	slog.Info("Processing resolved alert", "alert", alert.Labels["alertname"], "service", serviceName)
+	if h.generator == nil || h.orchestrator == nil {
+		return nil
+	}
+	// A duplicate merged into another incident is covered by that incident's postmortem
+	if h.mergedDuplicate(alert) {
+		return nil
+	}
+	if ok, reason := h.postmortems.allow(alert.Labels["severity"], alertDuration(alert, time.Now()), time.Now()); !ok {
+		h.resolveWithoutPostmortem(alert, serviceName, reason)
+		return nil
+	}
+
+	// Find the open incident recorded when this alert fired; downstream requests carry its ID
+	var incident *db.Incident
+	var lookupErr error
+	reqCtx := context.Background()
+	if h.incidents != nil {
+		if incident, lookupErr = h.incidents.FindOpenIncident(alert.GetFingerprint()); incident != nil {
+			reqCtx = httpx.WithRequestID(reqCtx, h.cfg.App.RequestIDHeader, incident.ID)
+		}
+	}
+
+	var pm *postmortem.Postmortem
+	err := h.withRetry(serviceName, func(last bool) error {
+		// Prepare context mapping back to incident start for full postmortem view
+		ctx, err := h.orchestrator.PrepareAlertContext(reqCtx, serviceName, alert.Labels["severity"], alert.StartsAt)
+		if err != nil {
+			return fmt.Errorf("failed to prepare context for postmortem: %w", err)
+		}
+		if err := h.checkRequiredSources(h.orchestrator, ctx, last); err != nil {
+			return fmt.Errorf("failed to prepare context for postmortem: %w", err)
+		}
+
+		// Map Alert Info
+		ctx.Alert = alert.ToAlertInfo()
+		h.attachMergedDuplicates(ctx, incident)
+		h.orchestrator.AttachDashboards(ctx)
+		h.orchestrator.AttachRecovery(reqCtx, ctx)
+
+		pm, err = h.generator.Generate(reqCtx, ctx)
+		if err != nil {
+			return fmt.Errorf("failed to generate postmortem: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// The incident stays open and can still be resolved via POST /incidents/{id}/resolve
+		slog.Error("Giving up on resolved alert", "alert", alert.Labels["alertname"], "service", serviceName, "error", err)
+		return fmt.Errorf("%s on %s: %w", alert.Labels["alertname"], serviceName, err)
+	}
+
+	slog.Info("Generated postmortem", "postmortem_id", pm.ID, "service", serviceName)
+
+	// Resolve the open incident recorded when this alert fired
+	var threadTS string
+	if h.incidents != nil {
+		if lookupErr != nil {
+			slog.Error("Failed to look up open incident", "error", lookupErr)
+		} else if incident == nil {
+			slog.Warn("No open incident found for resolved alert", "alert", alert.Labels["alertname"], "fingerprint", alert.GetFingerprint())
+		} else if err := h.incidents.ResolveIncident(incident.ID, pm.RootCause, pm.Markdown); err != nil {
+			slog.Error("Failed to resolve incident in database", "error", err)
+		} else {
+			threadTS = incident.SlackThreadTS
+			slog.Info("Resolved incident in database", "incident_id", incident.ID)
+		}
+	}
+
+	h.publishPostmortem(reqCtx, serviceName, pm, threadTS, notificationKey(alert.GetFingerprint(), alert.StartsAt), false)
+	return nil
+}
+
+// withRetry runs fn up to analysis.processing_retries times, doubling the wait between attempts; fn is
+// told when it runs the last one. Shutdown cuts a wait short and gives up.
+func (h *Handler) withRetry(serviceName string, fn func(last bool) error) error {
+	attempts := h.cfg.Analysis.GetProcessingRetries()
+	backoff := h.cfg.Analysis.GetProcessingBackoffDuration()
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(attempt == attempts); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		slog.Warn("Alert processing failed, retrying", "service", serviceName, "attempt", attempt, "max_attempts", attempts, "backoff", backoff, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-h.stopping.Done():
+			timer.Stop()
+			return fmt.Errorf("shutting down after %d of %d attempts: %w", attempt, attempts, err)
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+// untilStopping returns ctx cancelled once shutdown begins, for outputs that retry on their own.
+func (h *Handler) untilStopping(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(h.stopping, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// checkRequiredSources turns failed analysis.required_sources into an error, so context gathering is
+// retried while a required backend is down. On the last attempt the failure is only logged and the
+// context is used as gathered, so an outage of one backend does not hold back every analysis.
+func (h *Handler) checkRequiredSources(orch *orchestrator.Orchestrator, ac *models.AnalysisContext, last bool) error {
+	err := orch.RequiredSourceErrors(ac)
+	if err == nil || !last {
+		return err
+	}
+	slog.Warn("Continuing without required sources after retries", "service", ac.ServiceName, "error", err)
+	return nil
+}
+
+// recordFailedIncident stores a failed incident and its alert payload so the failure is visible and replayable.
+func (h *Handler) recordFailedIncident(alert models.AlertItem, serviceName, threadTS string, cause error) {
+	if h.incidents == nil {
+		return
+	}
+
+	incident := &db.Incident{
+		ID:            uuid.New().String(),
+		ServiceName:   serviceName,
+		AlertName:     alert.Labels["alertname"],
+		Severity:      alert.Labels["severity"],
+		StartedAt:     alert.StartsAt,
+		Status:        db.IncidentStatusFailed,
+		Fingerprint:   alert.GetFingerprint(),
+		SlackThreadTS: threadTS,
+		LastError:     cause.Error(),
+	}
+	if err := h.incidents.CreateIncident(incident); err != nil {
+		slog.Error("Failed to record failed incident", "error", err)
+		return
+	}
+	if h.database != nil {
+		if err := h.database.SaveAnalysisResult(incident.ID, db.AnalysisTypeAlertPayload, alert); err != nil {
+			slog.Error("Failed to store alert payload for failed incident", "incident_id", incident.ID, "error", err)
 		}
 	}
+	slog.Info("Recorded failed incident", "incident_id", incident.ID, "service", serviceName)
+}
+
+// isSilenced reports whether an active Alertmanager silence covers the alert. Lookup failures
+// are logged and treated as not silenced so analysis is never lost to an Alertmanager outage.
+func (h *Handler) isSilenced(alert models.AlertItem, serviceName string) bool {
+	if h.silences == nil {
+		return false
+	}
+
+	silence, err := h.silences.MatchingSilence(context.Background(), alert.Labels)
+	if err != nil {
+		slog.Warn("Failed to check Alertmanager silences", "alert", alert.Labels["alertname"], "error", err)
+		return false
+	}
+	if silence == nil {
+		return false
+	}
+
+	slog.Info("Skipping silenced alert", "alert", alert.Labels["alertname"], "service", serviceName,
+		"silence_id", silence.ID, "created_by", silence.CreatedBy)
+	return true
+}
+
+// publishPostmortem delivers a generated postmortem to the configured output channels and,
+// when enabled, tracks its remediations as a GitHub issue. Slack and the webhook are skipped when the
+// incident's postmortem already went out there, unless resend is set.
+func (h *Handler) publishPostmortem(ctx context.Context, serviceName string, pm *postmortem.Postmortem, threadTS, incidentKey string, resend bool) {
+	h.createIssue(ctx, serviceName, pm)
+
+	if h.slackSender != nil {
+		if err := h.notifyOnce(incidentKey, notifySlackPostmortem, resend, func() error { return h.slackSender.SendPostmortemInThread(pm, threadTS) }); err != nil {
+			slog.Error("Failed to send Slack postmortem", "error", err)
+		}
+	}
+
+	if h.webhook != nil {
+		webhookCtx, cancel := h.untilStopping(ctx)
+		defer cancel()
+		if err := h.notifyOnce(incidentKey, notifyWebhookPostmortem, resend, func() error { return h.webhook.SendPostmortem(webhookCtx, pm) }); err != nil {
+			slog.Error("Failed to send postmortem webhook", "error", err)
+		}
+	}
+
+	if h.mdReporter != nil {
+		if err := h.mdReporter.SendPostmortem(pm); err != nil {
+			slog.Error("Failed to save postmortem markdown", "error", err)
+		}
+	}
+}
+
+// applyAssessedSeverity lets the model's reassessed severity replace the alert's for storage and
+// output routing when analysis.use_assessed_severity is enabled.
+func (h *Handler) applyAssessedSeverity(result *models.AnalysisResult) {
+	if !h.cfg.Analysis.UseAssessedSeverity || result.AssessedSeverity == "" || result.AssessedSeverity == result.Severity {
+		return
+	}
+	slog.Info("Using assessed severity", "service", result.ServiceName, "alert", result.AlertName,
+		"alert_severity", result.Severity, "assessed_severity", result.AssessedSeverity)
+	result.Severity = result.AssessedSeverity
+}
+
+// normalizeSeverity rewrites the severity label to critical, warning, or info before inhibition,
+// routing, and rendering. The labels are copied so the caller's map is left untouched.
+func (h *Handler) normalizeSeverity(alert models.AlertItem) models.AlertItem {
+	raw := alert.Labels["severity"]
+	severity, ok := h.cfg.Alerting.NormalizeSeverity(raw)
+	if !ok && raw != "" {
+		slog.Warn("Unknown alert severity, treating as info", "alert", alert.Labels["alertname"], "severity", raw)
+	}
+	if severity == raw {
+		return alert
+	}
+
+	labels := make(map[string]string, len(alert.Labels)+1)
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	labels["severity"] = severity
+	alert.Labels = labels
+	return alert
+}
+
+// applyAnnotationFallbacks sets the severity label, and the service_name label when no service label is
+// present, from the first configured fallback annotation with a value. Labels that are set are kept.
+// The labels are copied so the caller's map is left untouched.
+func (h *Handler) applyAnnotationFallbacks(alert models.AlertItem) models.AlertItem {
+	fallbacks := h.cfg.Alerting.AnnotationFallbacks
+	found := make(map[string]string)
+	if alert.Labels["severity"] == "" {
+		if v := firstAnnotation(alert.Annotations, fallbacks.Severity); v != "" {
+			found["severity"] = v
+		}
+	}
+	if extractServiceName(alert.Labels) == "" {
+		if v := firstAnnotation(alert.Annotations, fallbacks.Service); v != "" {
+			found["service_name"] = v
+		}
+	}
+	if len(found) == 0 {
+		return alert
+	}
+
+	labels := make(map[string]string, len(alert.Labels)+len(found))
+	for k, v := range alert.Labels {
+		labels[k] = v
+	}
+	for k, v := range found {
+		slog.Debug("Using annotation for missing label", "alert", alert.Labels["alertname"], "label", k, "value", v)
+		labels[k] = v
+	}
+	alert.Labels = labels
+	return alert
+}
+
+// firstAnnotation returns the first non-empty value among keys, in order.
+func firstAnnotation(annotations map[string]string, keys []string) string {
+	for _, key := range keys {
+		if v := strings.TrimSpace(annotations[key]); v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
 // extractServiceName attempts to identify the impacted service by scanning common metric label keys.
@@ -265,6 +880,16 @@ func extractServiceName(labels map[string]string) string {
 	return ""
 }
 
+// HandleMetrics serves HelixOps' outbound request metrics, followed by the root-cause category counters
+// when output.metrics is enabled, in the Prometheus text format.
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	httpx.DefaultMetrics.WriteTo(w)
+	if h.rootCauses != nil {
+		h.rootCauses.WriteTo(w)
+	}
+}
+
 // HandleHealth returns health status
 func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
@@ -297,7 +922,7 @@ func (h *Handler) HandleReady(w http.ResponseWriter, r *http.Request) {
 
 // HandleListPostmortems lists generated postmortems
 func (h *Handler) HandleListPostmortems(w http.ResponseWriter, r *http.Request) {
-	if h.database == nil {
+	if h.incidents == nil {
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":  "success",
@@ -307,9 +932,9 @@ func (h *Handler) HandleListPostmortems(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	incidents, err := h.database.ListIncidents("resolved")
+	incidents, err := h.incidents.ListIncidents(db.IncidentStatusResolved)
 	if err != nil {
-		log.Printf("Failed to list incidents: %v", err)
+		slog.Error("Failed to list incidents", "error", err)
 		http.Error(w, "Failed to retrieve incidents", http.StatusInternalServerError)
 		return
 	}
@@ -326,14 +951,14 @@ func (h *Handler) HandleListPostmortems(w http.ResponseWriter, r *http.Request)
 func (h *Handler) HandleGetPostmortem(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	if h.database == nil {
+	if h.incidents == nil {
 		http.Error(w, "Database not configured", http.StatusNotFound)
 		return
 	}
 
-	incident, err := h.database.GetIncident(id)
+	incident, err := h.incidents.GetIncident(id)
 	if err != nil {
-		log.Printf("Failed to get incident: %v", err)
+		slog.Error("Failed to get incident", "id", id, "error", err)
 		http.Error(w, "Failed to retrieve incident", http.StatusInternalServerError)
 		return
 	}
@@ -342,6 +967,11 @@ func (h *Handler) HandleGetPostmortem(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Incident not found", http.StatusNotFound)
 		return
 	}
+	if incident.MergedInto != "" {
+		// Merged duplicates are not shown on their own; their analysis lives with the primary incident
+		http.Redirect(w, r, "/postmortems/"+incident.MergedInto, http.StatusMovedPermanently)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -355,3 +985,160 @@ func (h *Handler) HandleGetPostmortem(w http.ResponseWriter, r *http.Request) {
 		"status":       incident.Status,
 	})
 }
+
+// HandleGetIncidentContext returns the analysis context snapshot stored for an incident: the metrics,
+// commits, logs, spans, and time window exactly as HelixOps assembled them for the RCA.
+func (h *Handler) HandleGetIncidentContext(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ac, err := h.database.LoadContextSnapshot(id)
+	if err != nil {
+		slog.Error("Failed to load context snapshot", "id", id, "error", err)
+		http.Error(w, "Failed to retrieve incident context", http.StatusInternalServerError)
+		return
+	}
+	if ac == nil {
+		http.Error(w, "No context snapshot for this incident", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ac)
+}
+
+// incidentContext returns the context snapshot stored for incident. Incidents recorded before snapshots
+// existed (or kept in memory) get one built from the stored metadata, so they can still get a postmortem.
+func (h *Handler) incidentContext(incident *db.Incident) (*models.AnalysisContext, error) {
+	if h.database != nil {
+		ac, err := h.database.LoadContextSnapshot(incident.ID)
+		if err != nil || ac != nil {
+			return ac, err
+		}
+	}
+	return &models.AnalysisContext{
+		ServiceName: incident.ServiceName,
+		Alert: models.AlertInfo{
+			Name:        incident.AlertName,
+			Severity:    incident.Severity,
+			Fingerprint: incident.Fingerprint,
+			StartedAt:   incident.StartedAt,
+		},
+	}, nil
+}
+
+// resolveIncidentRequest is the optional body accepted by HandleResolveIncident.
+type resolveIncidentRequest struct {
+	ResolvedAt *time.Time `json:"resolved_at"`
+	// Resend posts the postmortem even if one was already sent for this incident
+	Resend bool `json:"resend"`
+}
+
+// HandleResolveIncident manually resolves an open incident and generates its postmortem from the
+// stored context snapshot, for incidents whose resolved notification never arrived. Generation runs in
+// the background, as it can outlast the server's write timeout; the incident is resolved once its
+// postmortem is stored, and GET /postmortems/{id} serves it under the incident ID.
+func (h *Handler) HandleResolveIncident(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if h.incidents == nil || h.generator == nil {
+		http.Error(w, "Database or postmortem generator not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req resolveIncidentRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<16))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resolvedAt := time.Now().UTC()
+	if req.ResolvedAt != nil {
+		resolvedAt = req.ResolvedAt.UTC()
+	}
+
+	incident, err := h.incidents.GetIncident(id)
+	if err != nil {
+		slog.Error("Failed to get incident", "id", id, "error", err)
+		http.Error(w, "Failed to retrieve incident", http.StatusInternalServerError)
+		return
+	}
+	if incident == nil {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	}
+	if incident.Status != db.IncidentStatusOpen {
+		http.Error(w, "Incident is not open", http.StatusConflict)
+		return
+	}
+	if resolvedAt.Before(incident.StartedAt) {
+		http.Error(w, "resolved_at is before the incident started", http.StatusBadRequest)
+		return
+	}
+
+	// Concurrent requests for the same incident generate only one postmortem
+	if _, busy := h.resolving.LoadOrStore(id, struct{}{}); busy {
+		http.Error(w, "Incident is already being resolved", http.StatusConflict)
+		return
+	}
+	ac, err := h.incidentContext(incident)
+	if err != nil {
+		h.resolving.Delete(id)
+		slog.Error("Failed to load context snapshot", "id", id, "error", err)
+		http.Error(w, "Failed to load incident context", http.StatusInternalServerError)
+		return
+	}
+	if !h.inflight.add() {
+		h.resolving.Delete(id)
+		http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	go func() {
+		defer h.inflight.done()
+		defer h.resolving.Delete(id)
+		h.resolveWithPostmortem(incident, ac, resolvedAt, req.Resend)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "resolving",
+		"incident_id": id,
+		"resolved_at": resolvedAt,
+	})
+}
+
+// resolveWithPostmortem generates the postmortem of a manually resolved incident from its stored
+// context, resolves the incident at resolvedAt, and publishes the postmortem. When generation fails the
+// incident stays open, so the request can be repeated.
+func (h *Handler) resolveWithPostmortem(incident *db.Incident, ac *models.AnalysisContext, resolvedAt time.Time, resend bool) {
+	ctx := httpx.WithRequestID(context.Background(), h.cfg.App.RequestIDHeader, incident.ID)
+	ac.Alert.EndsAt = resolvedAt
+	if h.orchestrator != nil {
+		h.orchestrator.AttachDashboards(ac)
+		h.orchestrator.AttachRecovery(ctx, ac)
+	}
+
+	pm, err := h.generator.Generate(ctx, ac)
+	if err != nil {
+		slog.Error("Failed to generate postmortem", "incident_id", incident.ID, "error", err)
+		return
+	}
+	if err := h.incidents.ResolveIncidentAt(incident.ID, resolvedAt, pm.RootCause, pm.Markdown); err != nil {
+		slog.Error("Failed to resolve incident in database", "incident_id", incident.ID, "error", err)
+		return
+	}
+	slog.Info("Manually resolved incident", "incident_id", incident.ID, "postmortem_id", pm.ID)
+
+	h.publishPostmortem(ctx, incident.ServiceName, pm, incident.SlackThreadTS, notificationKey(incident.Fingerprint, incident.StartedAt), resend)
+}
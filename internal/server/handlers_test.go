@@ -2,14 +2,34 @@ package server
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"helixops/internal/analyzer"
+	"helixops/internal/capture"
+	"helixops/internal/clients/alertmanager"
+	"helixops/internal/clients/loki"
+	"helixops/internal/clients/tempo"
 	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
 	"helixops/internal/models"
+	"helixops/internal/orchestrator"
+	"helixops/internal/output"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -107,6 +127,59 @@ func TestHandleWebhookInvalidPayload(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestHandleWebhookAcceptsGzippedJSON(t *testing.T) {
+	router := SetupRouter(NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil))
+
+	body, err := json.Marshal(models.AlertManagerPayload{Alerts: []models.AlertItem{{
+		Status: "firing",
+		Labels: map[string]string{"service_name": "test-service", "alertname": "HighLatency"},
+	}}})
+	require.NoError(t, err)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err = gz.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", &compressed)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestHandleWebhookRejectsNonJSONContentType(t *testing.T) {
+	router := SetupRouter(NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString("alertname=HighLatency"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestHandleWebhookCapsDecompressedBody(t *testing.T) {
+	router := SetupRouter(NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil))
+
+	// Highly compressible padding stays small on the wire but exceeds the limit once inflated
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(bytes.Repeat([]byte(" "), int(maxWebhookBodySize)+1))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", &compressed)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
 func TestHandleWebhookMethodNotAllowed(t *testing.T) {
 	cfg := &config.Config{
 		App: config.AppConfig{
@@ -174,3 +247,836 @@ func TestHandleReady(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "ready", response["status"])
 }
+
+func TestHandleResolveIncident(t *testing.T) {
+	database := dbtest.New(t)
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, database.CreateIncident(&db.Incident{
+		ID:          "inc-1",
+		ServiceName: "checkout",
+		AlertName:   "HighLatency",
+		Severity:    "critical",
+		StartedAt:   started,
+	}))
+	require.NoError(t, database.SaveContextSnapshot("inc-1", &models.AnalysisContext{
+		ServiceName:   "checkout",
+		Alert:         models.AlertInfo{Name: "HighLatency", Summary: "p99 above 1s", StartedAt: started},
+		RecentCommits: []models.CommitInfo{{SHA: "abc1234"}},
+	}))
+
+	provider := llm.NewFakeProvider("## 1. Summary\nPool exhaustion.")
+	handler := NewHandler(&config.Config{}, nil, nil, postmortem.NewGenerator(provider, remediation.NewEngine()), nil, nil, database)
+	router := SetupRouter(handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/incidents/inc-1/resolve", bytes.NewBufferString(`{"resolved_at": "2024-01-01T12:45:00Z"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "resolving", response["status"])
+	assert.Equal(t, "inc-1", response["incident_id"])
+	settle(handler)
+
+	// The postmortem was generated from the stored snapshot, ending at the manual resolution time
+	prompt := provider.LastPrompt()
+	assert.Contains(t, prompt, "- Alert Summary: p99 above 1s")
+	assert.Contains(t, prompt, "- Resolved: 2024-01-01T12:45:00Z")
+	assert.Contains(t, prompt, "- Commits found during window: 1")
+
+	incident, err := database.GetIncident("inc-1")
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", incident.Status)
+	require.NotNil(t, incident.ResolvedAt)
+	assert.True(t, incident.ResolvedAt.Equal(time.Date(2024, 1, 1, 12, 45, 0, 0, time.UTC)))
+	require.NotNil(t, incident.AISummary)
+	assert.Contains(t, *incident.AISummary, "Pool exhaustion.")
+
+	// A second resolution is rejected
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/incidents/inc-1/resolve", nil))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/incidents/missing/resolve", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleGetIncidentContext(t *testing.T) {
+	database := dbtest.New(t)
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "inc-1", ServiceName: "checkout", AlertName: "HighLatency", StartedAt: started}))
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "inc-2", ServiceName: "checkout", AlertName: "HighLatency", StartedAt: started}))
+	snapshot := &models.AnalysisContext{
+		ServiceName:   "checkout",
+		Alert:         models.AlertInfo{Name: "HighLatency", Severity: "critical", StartedAt: started},
+		Metrics:       models.MetricsSummary{LatencyP99: 1250, BaselineLatency: 250, ErrorRate: 0.02},
+		RecentCommits: []models.CommitInfo{{SHA: "abc1234", Message: "Reduce DB pool size", Author: "dev", Timestamp: started.Add(-time.Hour)}},
+		ErrorLogs:     []models.LogEntry{{Timestamp: started, Message: "pool exhausted", Level: "error"}},
+		Traces:        tempo.TraceContext{TraceCount: 3, SlowSpans: []tempo.Span{{TraceID: "t1", ServiceName: "checkout", OperationName: "SELECT", DurationMs: 900}}},
+		TimeWindow:    models.TimeWindow{Start: started.Add(-15 * time.Minute), End: started, Duration: "15m0s"},
+	}
+	require.NoError(t, database.SaveContextSnapshot("inc-1", snapshot))
+
+	router := SetupRouter(NewHandler(&config.Config{}, nil, nil, nil, nil, nil, database))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incidents/inc-1/context", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var got models.AnalysisContext
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, snapshot, &got, "the endpoint returns the snapshot as stored")
+
+	// An incident without a snapshot, and an unknown incident, are not found
+	for _, path := range []string{"/incidents/inc-2/context", "/incidents/missing/context"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+
+	// Without a database the endpoint is unavailable, like the other incident data endpoints
+	w = httptest.NewRecorder()
+	SetupRouter(NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/incidents/inc-1/context", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestProcessAlertsSkipsSilencedAlerts(t *testing.T) {
+	am := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{
+			"id": "silence-1",
+			"matchers": [{"name": "alertname", "value": "DiskFull", "isRegex": false}],
+			"createdBy": "oncall",
+			"status": {"state": "active"}
+		}]`))
+	}))
+	defer am.Close()
+
+	provider := llm.NewFakeProvider("# Incident Analysis: test")
+	handler, _ := analysisHandler(t, &config.Config{}, provider)
+	handler.silences = alertmanager.NewClient(am.URL, time.Second)
+
+	silenced := firingAlert()
+	silenced.Labels["alertname"] = "DiskFull"
+	silenced.Fingerprint = "fp-silenced"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{silenced}})
+	assert.Equal(t, 0, provider.CallCount(), "silenced alert must not reach the LLM")
+
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+	assert.Equal(t, 1, provider.CallCount())
+}
+
+// testAnalysis is the RCA a fake provider answers with when a test does not look at its content.
+const testAnalysis = "# Incident Analysis: test\n**Confidence Score:** 80%\n"
+
+// analysisHandler returns a Handler that analyzes alerts with provider, gathering context from no
+// sources, and records incidents in a fresh database. Tests add outputs or collectors as they need.
+func analysisHandler(t *testing.T, cfg *config.Config, provider *llm.FakeProvider) (*Handler, *db.DB) {
+	t.Helper()
+	database := dbtest.New(t)
+	handler := NewHandler(cfg, orchestrator.New(nil, nil, nil, nil, cfg), analyzer.New(provider, cfg.Analysis), nil, nil, nil, database)
+	return handler, database
+}
+
+// retryTestHandler is analysisHandler with three processing attempts and no wait between them.
+func retryTestHandler(t *testing.T, provider *llm.FakeProvider) (*Handler, *db.DB) {
+	t.Helper()
+	return analysisHandler(t, &config.Config{Analysis: config.AnalysisConfig{ProcessingRetries: 3, ProcessingBackoff: "1ms"}}, provider)
+}
+
+// settle waits for the handler's background processing without starting shutdown, so a test can
+// keep sending alerts afterwards.
+func settle(h *Handler) {
+	h.inflight.wg.Wait()
+}
+
+func firingAlert() models.AlertItem {
+	return models.AlertItem{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "HighLatency", "service_name": "checkout", "severity": "critical"},
+		StartsAt:    time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Fingerprint: "fp-retry",
+	}
+}
+
+func TestProcessAlertsRetriesTransientFailure(t *testing.T) {
+	provider := llm.NewFakeProvider("# Incident Analysis: pool\n**Confidence Score:** 80%\n")
+	provider.Errors = []error{errors.New("upstream timeout")}
+	handler, database := retryTestHandler(t, provider)
+
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+
+	assert.Equal(t, 2, provider.CallCount(), "first attempt fails, second succeeds")
+
+	incidents, err := database.ListIncidents("")
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, db.IncidentStatusOpen, incidents[0].Status)
+	assert.Empty(t, incidents[0].LastError)
+}
+
+func TestProcessAlertsRecordsFailedIncident(t *testing.T) {
+	provider := llm.NewFakeProvider()
+	provider.Err = errors.New("model overloaded")
+	handler, database := retryTestHandler(t, provider)
+
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+
+	assert.Equal(t, 3, provider.CallCount())
+
+	failed, err := database.ListIncidents(db.IncidentStatusFailed)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "checkout", failed[0].ServiceName)
+	assert.Equal(t, "fp-retry", failed[0].Fingerprint)
+	assert.Contains(t, failed[0].LastError, "after 3 attempts")
+	assert.Contains(t, failed[0].LastError, "model overloaded")
+
+	// The original alert is kept so the incident can be replayed
+	var payload models.AlertItem
+	found, err := database.LoadAnalysisResult(failed[0].ID, db.AnalysisTypeAlertPayload, &payload)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, "HighLatency", payload.Labels["alertname"])
+}
+
+func TestProcessAlertsRetriesFailedRequiredSource(t *testing.T) {
+	provider := llm.NewFakeProvider("# Incident Analysis: pool\n**Confidence Score:** 80%\n")
+	handler, database := retryTestHandler(t, provider)
+	var calls atomic.Int32
+	handler.orchestrator.Register(orchestrator.NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		if calls.Add(1) == 1 {
+			return nil, errors.New("prometheus unavailable")
+		}
+		return func(ac *models.AnalysisContext) { ac.Metrics.LatencyP99 = 900 }, nil
+	}))
+
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+
+	assert.Equal(t, int32(2), calls.Load(), "context is gathered again after the required source failed")
+	assert.Equal(t, 1, provider.CallCount(), "the analysis runs once, on the complete context")
+	assert.Contains(t, provider.LastPrompt(), "Latency P99: 900.00ms")
+
+	incidents, err := database.ListIncidents(db.IncidentStatusOpen)
+	require.NoError(t, err)
+	assert.Len(t, incidents, 1)
+}
+
+func TestProcessAlertsAnalyzesWithoutRequiredSourceAfterRetries(t *testing.T) {
+	provider := llm.NewFakeProvider("# Incident Analysis: pool\n**Confidence Score:** 80%\n")
+	handler, database := retryTestHandler(t, provider)
+	var calls atomic.Int32
+	handler.orchestrator.Register(orchestrator.NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+		calls.Add(1)
+		return nil, errors.New("prometheus unavailable")
+	}))
+
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+
+	assert.Equal(t, int32(3), calls.Load())
+	incidents, err := database.ListIncidents(db.IncidentStatusOpen)
+	require.NoError(t, err)
+	assert.Len(t, incidents, 1)
+}
+
+func TestRetryBackoffStopsOnShutdown(t *testing.T) {
+	cfg := &config.Config{Analysis: config.AnalysisConfig{ProcessingRetries: 3, ProcessingBackoff: "1h"}}
+	handler := NewHandler(cfg, nil, nil, nil, nil, nil, nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.withRetry("checkout", func(bool) error { return errors.New("upstream timeout") })
+	}()
+	require.NoError(t, handler.wait(context.Background()))
+
+	select {
+	case err := <-done:
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "shutting down after 1 of 3 attempts")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retry kept waiting through shutdown")
+	}
+}
+
+// slackRecorder serves the Slack API and returns the messages posted to it so far. Each post is
+// answered with its own timestamp, 1700000000.000001 for the first, which bot mode uses as thread ID.
+func slackRecorder(t *testing.T) (*httptest.Server, func() []output.SlackMessage) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		messages []output.SlackMessage
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg output.SlackMessage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		mu.Lock()
+		messages = append(messages, msg)
+		ts := fmt.Sprintf("1700000000.%06d", len(messages))
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": ts})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []output.SlackMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(messages)
+	}
+}
+
+// slackBot returns a Slack sender in bot mode posting to the API at url, so replies are threaded.
+func slackBot(url string) *output.SlackSender {
+	return output.NewSlackSenderFromConfig(config.SlackOutputConfig{Enabled: true, BotToken: "xoxb-test", Channel: "#incidents", APIURL: url})
+}
+
+func TestProcessAlertsThreadsSlackRepliesPerIncident(t *testing.T) {
+	slackAPI, messages := slackRecorder(t)
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, &config.Config{}, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	handler.slackSender = slackBot(slackAPI.URL)
+
+	alert := firingAlert()
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	incident, err := database.FindOpenIncident(alert.Fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, incident)
+	assert.Equal(t, "1700000000.000001", incident.SlackThreadTS)
+
+	snapshot, err := database.LoadContextSnapshot(incident.ID)
+	require.NoError(t, err)
+	require.NotNil(t, snapshot, "firing path persists the analysis context")
+	assert.Equal(t, "HighLatency", snapshot.Alert.Name)
+
+	alert.Status = "resolved"
+	alert.EndsAt = alert.StartsAt.Add(30 * time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	resolved, err := database.GetIncident(incident.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", resolved.Status)
+
+	sent := messages()
+	require.Len(t, sent, 3)
+	assert.Empty(t, sent[0].ThreadTS, "firing message opens the thread")
+	assert.Equal(t, "1700000000.000001", sent[1].ThreadTS, "RCA replies in the thread")
+	assert.Equal(t, "1700000000.000001", sent[2].ThreadTS, "postmortem replies in the stored thread")
+}
+
+func TestP1AlertTakesCriticalPath(t *testing.T) {
+	slack, messages := slackRecorder(t)
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, &config.Config{}, provider)
+	handler.slackSender = output.NewSlackSender(slack.URL)
+
+	labels := map[string]string{"alertname": "CheckoutDown", "service_name": "checkout", "severity": "P1"}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{{
+		Status:      "firing",
+		Labels:      labels,
+		StartsAt:    time.Now(),
+		Fingerprint: "fp-p1",
+	}}})
+
+	assert.Equal(t, "P1", labels["severity"], "the caller's labels are not mutated")
+	assert.Contains(t, provider.LastPrompt(), "- Severity: critical")
+
+	incident, err := database.FindOpenIncident("fp-p1")
+	require.NoError(t, err)
+	require.NotNil(t, incident)
+	assert.Equal(t, "critical", incident.Severity)
+
+	sent := messages()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "🚨 Alert: CheckoutDown on checkout", sent[0].Blocks[0].Text.Text)
+}
+
+func TestUnknownSeverityDefaultsToInfo(t *testing.T) {
+	handler := NewHandler(&config.Config{}, nil, nil, nil, nil, nil, nil)
+	alert := handler.normalizeSeverity(models.AlertItem{Labels: map[string]string{"severity": "disaster"}})
+	assert.Equal(t, "info", alert.Labels["severity"])
+}
+
+func TestAnnotationSuppliesMissingSeverityAndService(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Alerting.AnnotationFallbacks = config.AnnotationFallbacks{
+		Service:  []string{"routing_service"},
+		Severity: []string{"routing_severity", "priority"},
+	}
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, cfg, provider)
+
+	annotations := map[string]string{"routing_service": "checkout", "routing_severity": "", "priority": "P1"}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "CheckoutDown"},
+		Annotations: annotations,
+		StartsAt:    time.Now(),
+		Fingerprint: "fp-annotated",
+	}}})
+
+	incident, err := database.FindOpenIncident("fp-annotated")
+	require.NoError(t, err)
+	require.NotNil(t, incident, "the service annotation routes an alert without a service label")
+	assert.Equal(t, "checkout", incident.ServiceName)
+	assert.Equal(t, "critical", incident.Severity, "the first non-empty severity annotation is normalized like a label")
+	assert.Contains(t, provider.LastPrompt(), "- Severity: critical")
+
+	alert := handler.applyAnnotationFallbacks(models.AlertItem{
+		Labels:      map[string]string{"alertname": "CheckoutDown", "service": "cart", "severity": "warning"},
+		Annotations: annotations,
+	})
+	assert.Equal(t, "warning", alert.Labels["severity"], "labels win over annotations")
+	assert.Empty(t, alert.Labels["service_name"])
+}
+
+const downgradeResponse = `# Incident Analysis: Cache warmup
+**Confidence Score:** 90%
+**Status:** Confirmed
+**Assessed Severity:** info
+
+## 3. Root Cause Analysis
+Latency rose briefly while the cache warmed after a routine restart; no user-facing errors.
+`
+
+func runAssessedSeverity(t *testing.T, useAssessed bool) (header, severity string) {
+	t.Helper()
+	slack, messages := slackRecorder(t)
+	handler, database := analysisHandler(t, &config.Config{Analysis: config.AnalysisConfig{UseAssessedSeverity: useAssessed}}, llm.NewFakeProvider(downgradeResponse))
+	handler.slackSender = output.NewSlackSender(slack.URL)
+
+	alert := firingAlert()
+	alert.Labels["severity"] = "warning"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	incident, err := database.FindOpenIncident(alert.Fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, incident)
+	sent := messages()
+	require.Len(t, sent, 1)
+	return sent[0].Blocks[0].Text.Text, incident.Severity
+}
+
+func TestAssessedSeverityDrivesRoutingWhenEnabled(t *testing.T) {
+	header, severity := runAssessedSeverity(t, true)
+	assert.Equal(t, "🔍 Alert: HighLatency on checkout", header, "downgraded from the warning treatment")
+	assert.Equal(t, "info", severity)
+}
+
+func TestAssessedSeverityIgnoredWhenDisabled(t *testing.T) {
+	header, severity := runAssessedSeverity(t, false)
+	assert.Equal(t, "⚠️ Alert: HighLatency on checkout", header)
+	assert.Equal(t, "warning", severity)
+}
+
+func TestDownstreamRequestsCarryIncidentID(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		mu.Unlock()
+		w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[]}}`))
+	}))
+	defer backend.Close()
+	requests := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ids...)
+	}
+
+	cfg := &config.Config{App: config.AppConfig{RequestIDHeader: "X-Request-Id"}}
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, cfg, provider)
+	handler.orchestrator = orchestrator.New(nil, nil, loki.NewClient(backend.URL, 5*time.Second), nil, cfg)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+
+	alert := firingAlert()
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	incident, err := database.FindOpenIncident(alert.Fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, incident)
+	fired := requests()
+	require.NotEmpty(t, fired, "the analysis queries Loki")
+	for _, id := range fired {
+		assert.Equal(t, incident.ID, id)
+	}
+
+	alert.Status = "resolved"
+	alert.EndsAt = alert.StartsAt.Add(30 * time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	resolved := requests()[len(fired):]
+	require.NotEmpty(t, resolved, "the postmortem queries Loki")
+	for _, id := range resolved {
+		assert.Equal(t, incident.ID, id, "postmortem requests carry the ID of the incident they resolve")
+	}
+}
+
+func TestSeverityProfileSelectsModelAndSources(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		requested = append(requested, req.Model)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": "# Incident Analysis: test\n"}}},
+		})
+	}))
+	defer llmServer.Close()
+
+	var logQueries atomic.Int32
+	lokiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logQueries.Add(1)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[{"stream":{"service":"checkout"},"values":[["` +
+			strconv.FormatInt(time.Now().UnixNano(), 10) + `","error: pool exhausted"]]}]}}`))
+	}))
+	defer lokiServer.Close()
+	lokiClient, err := loki.New(lokiServer.URL, 5*time.Second)
+	require.NoError(t, err)
+
+	llmConfig := func(model string) config.LLMConfig {
+		return config.LLMConfig{Provider: "openai", Model: model, BaseURL: llmServer.URL, APIKey: "test", MaxTokens: 512}
+	}
+	deep := llmConfig("deep-model")
+	cfg := &config.Config{
+		LLM: llmConfig("base-model"),
+		AnalysisProfiles: map[string]config.AnalysisProfile{
+			"deep": {LLM: &deep, Sources: []string{"metrics", "logs"}},
+		},
+		Analysis: config.AnalysisConfig{SeverityProfiles: map[string]string{config.SeverityWarning: "deep"}},
+	}
+	require.NoError(t, cfg.Validate())
+
+	h, err := newProfileHandler(cfg, backends{loki: lokiClient})
+	require.NoError(t, err)
+
+	alert := func(severity string) models.AlertItem {
+		return models.AlertItem{
+			Status:   "firing",
+			Labels:   map[string]string{"alertname": "HighLatency", "service_name": "checkout", "severity": severity},
+			StartsAt: time.Now(),
+		}
+	}
+
+	h.processFiring(alert(config.SeverityWarning), "checkout")
+	assert.Equal(t, []string{"deep-model"}, requested, "the warning profile's model analyzes warnings")
+	assert.Equal(t, int32(1), logQueries.Load(), "the profile's sources include logs, which warnings skip by default")
+
+	h.processFiring(alert(config.SeverityInfo), "checkout")
+	assert.Equal(t, []string{"deep-model", "base-model"}, requested, "severities without a profile keep the base settings")
+	assert.Equal(t, int32(1), logQueries.Load(), "info alerts still gather metrics only")
+}
+
+func TestTriageAutoResolvedAlertIsRecordedWithoutLLMOrSlack(t *testing.T) {
+	slack, messages := slackRecorder(t)
+	cfg := &config.Config{Analysis: config.AnalysisConfig{Triage: config.TriageConfig{
+		Enabled:         true,
+		TransientAlerts: []string{"KubePodRestarted*"},
+	}}}
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, cfg, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	handler.slackSender = output.NewSlackSender(slack.URL)
+
+	alert := firingAlert()
+	alert.Labels["alertname"] = "KubePodRestartedOnce"
+	alert.Labels["severity"] = "warning"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	incident, err := database.FindOpenIncident(alert.Fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, incident, "auto-resolved alerts are still recorded")
+	assert.Equal(t, db.IncidentStatusTriaged, incident.Status)
+	assert.Equal(t, 0, provider.CallCount())
+	assert.Empty(t, messages())
+
+	alert.Status = "resolved"
+	alert.EndsAt = alert.StartsAt.Add(time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	settle(handler)
+
+	closed, err := database.GetIncident(incident.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusTriaged, closed.Status)
+	require.NotNil(t, closed.ResolvedAt)
+	assert.Equal(t, 0, provider.CallCount(), "the resolution generates no postmortem")
+	assert.Empty(t, messages())
+}
+
+func TestNoAnomalyNotifiesCompactlyOrSuppresses(t *testing.T) {
+	for _, mode := range []string{config.NoAnomalyNotify, config.NoAnomalySuppress} {
+		slack, messages := slackRecorder(t)
+		provider := llm.NewFakeProvider(testAnalysis)
+		handler, _ := analysisHandler(t, &config.Config{Analysis: config.AnalysisConfig{NoAnomaly: mode}}, provider)
+		handler.slackSender = output.NewSlackSender(slack.URL)
+		handler.orchestrator.Register(orchestrator.NewCollector("metrics", func(ctx context.Context, service string, w models.TimeWindow) (func(*models.AnalysisContext), error) {
+			return func(ac *models.AnalysisContext) {
+				ac.Metrics = models.MetricsSummary{LatencyP99: 110, BaselineLatency: 100, RPS: 40}
+			}, nil
+		}))
+
+		alert := firingAlert()
+		alert.Labels["severity"] = "warning"
+		handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+		assert.Equal(t, 0, provider.CallCount(), mode)
+		sent := messages()
+		if mode == config.NoAnomalySuppress {
+			assert.Empty(t, sent)
+			continue
+		}
+		require.Len(t, sent, 1)
+		text, err := json.Marshal(sent[0])
+		require.NoError(t, err)
+		assert.Contains(t, string(text), "No anomaly detected")
+		assert.NotContains(t, string(text), "Alert: HighLatency on checkout", "the full RCA message is not sent")
+	}
+}
+
+func TestPriorIncidentRootCauseReachesPrompt(t *testing.T) {
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, &config.Config{}, provider)
+	handler.orchestrator.UseIncidentHistory(database)
+
+	started := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	seed := func(id, service, alert string, ago time.Duration, rootCause string) {
+		require.NoError(t, database.CreateIncident(&db.Incident{ID: id, ServiceName: service, AlertName: alert, Severity: "critical", StartedAt: started.Add(-ago)}))
+		require.NoError(t, database.ResolveIncidentAt(id, started.Add(-ago+20*time.Minute), rootCause, "# Postmortem"))
+	}
+	seed("inc-match", "checkout", "HighLatency", 14*24*time.Hour, "Connection pool reduced to 5 in abc1234")
+	seed("inc-other-alert", "checkout", "HighErrorRate", 7*24*time.Hour, "Bad feature flag")
+	seed("inc-other-service", "payments", "HighLatency", 3*24*time.Hour, "Card processor outage")
+
+	alert := firingAlert()
+	alert.StartsAt = started
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	require.Equal(t, 1, provider.CallCount())
+	prompt := provider.LastPrompt()
+	assert.Contains(t, prompt, "PRIOR INCIDENTS (same alert on this service, most recent first):")
+	assert.Contains(t, prompt, "RECURRENCE: this alert was resolved 1 time(s) before on this service.")
+	assert.Contains(t, prompt, "- 2024-05-18T12:00:00Z (resolved after 20m0s): Connection pool reduced to 5 in abc1234")
+	assert.NotContains(t, prompt, "Bad feature flag")
+	assert.NotContains(t, prompt, "Card processor outage")
+}
+
+func TestIncidentRegistryBacksEndpointsWithoutDatabase(t *testing.T) {
+	provider := llm.NewFakeProvider("## 1. Summary\nPool exhaustion.")
+	handler, _ := analysisHandler(t, &config.Config{}, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	registry := db.NewIncidentRegistry(10)
+	handler.database = nil
+	handler.incidents, handler.notifications = registry, registry
+	router := SetupRouter(handler)
+
+	alert := firingAlert()
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	open, err := registry.FindOpenIncident(alert.Fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, open, "the firing alert is recorded in memory")
+
+	alert.Status, alert.EndsAt = "resolved", alert.StartsAt.Add(time.Hour)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/postmortems", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list struct {
+		Data []db.Incident `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	assert.Equal(t, open.ID, list.Data[0].ID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/postmortems/"+open.ID, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, db.IncidentStatusResolved, got["status"])
+}
+
+// receiverHandler is an analysisHandler answering with response that posts to a Slack webhook, and
+// returns the messages posted there so far.
+func receiverHandler(t *testing.T, response string) (*Handler, *llm.FakeProvider, func() []output.SlackMessage) {
+	t.Helper()
+	slack, messages := slackRecorder(t)
+	provider := llm.NewFakeProvider(response)
+	h, _ := analysisHandler(t, &config.Config{}, provider)
+	h.slackSender = output.NewSlackSender(slack.URL)
+	return h, provider, messages
+}
+
+// postAlert posts firingAlert for service to path.
+func postAlert(t *testing.T, router http.Handler, path, service string) *httptest.ResponseRecorder {
+	t.Helper()
+	alert := firingAlert()
+	alert.Labels["service_name"] = service
+	body, err := json.Marshal(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body)))
+	return rec
+}
+
+func TestReceiverWebhooksRouteToTheirOwnProfile(t *testing.T) {
+	global, globalLLM, globalSlack := receiverHandler(t, "# Incident Analysis: global\n")
+	infra, infraLLM, infraSlack := receiverHandler(t, "# Incident Analysis: infra\n")
+	app, appLLM, appSlack := receiverHandler(t, "# Incident Analysis: app\n")
+	global.receivers["infra"] = infra
+	global.receivers["app"] = app
+	router := SetupRouter(global)
+
+	require.Equal(t, http.StatusOK, postAlert(t, router, "/webhook/infra", "node-exporter").Code)
+	require.Eventually(t, func() bool { return len(infraSlack()) == 1 }, time.Second, 5*time.Millisecond)
+
+	require.Equal(t, http.StatusOK, postAlert(t, router, "/webhook/app", "checkout").Code)
+	require.Eventually(t, func() bool { return len(appSlack()) == 1 }, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, 1, infraLLM.CallCount())
+	assert.Contains(t, infraLLM.LastPrompt(), "- Service: node-exporter")
+	assert.Equal(t, 1, appLLM.CallCount())
+	assert.Contains(t, appLLM.LastPrompt(), "- Service: checkout")
+	assert.Len(t, infraSlack(), 1, "app alerts never reach the infra channel")
+	assert.Zero(t, globalLLM.CallCount())
+	assert.Empty(t, globalSlack())
+}
+
+func TestReceiverWebhookUnknownReceiver(t *testing.T) {
+	global, _, _ := receiverHandler(t, "")
+	rec := postAlert(t, SetupRouter(global), "/webhook/payments", "checkout")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"payments"`)
+}
+
+func TestMetricsCountAnalysesByRootCauseCategory(t *testing.T) {
+	h, _, slack := receiverHandler(t, "# Incident Analysis: pool\n\n## 3. Root Cause Analysis\nCommit abc1234 shrank the connection pool.\n")
+	h.rootCauses = output.NewRootCauseMetrics(config.MetricsOutputConfig{Enabled: true})
+	router := SetupRouter(h)
+
+	require.Equal(t, http.StatusOK, postAlert(t, router, "/webhook", "checkout").Code)
+	require.Eventually(t, func() bool { return len(slack()) == 1 }, time.Second, 5*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "helixops_http_client_requests_total", "request metrics are still served")
+	assert.Contains(t, rec.Body.String(), `helixops_root_cause_analyses_total{service="checkout",category="deploy-related"} 1`)
+}
+
+func TestCapturedPayloadReplaysToSameProcessing(t *testing.T) {
+	h, fake, slack := receiverHandler(t, "# Incident Analysis: replay\n")
+	store, err := capture.New(t.TempDir(), 10)
+	require.NoError(t, err)
+	h.captures = store
+	router := SetupRouter(h)
+
+	body := `{"receiver":"helixops","alerts":[{"status":"firing","fingerprint":"fp-capture",
+		"labels":{"alertname":"HighLatency","service_name":"checkout","severity":"critical"},
+		"annotations":{"summary":"p99 above 2s"},
+		"startsAt":"2024-01-01T12:00:00Z"}]}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewBufferString(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Eventually(t, func() bool { return len(slack()) == 1 }, time.Second, 5*time.Millisecond)
+
+	files, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Contains(t, files[0], "_default_fp-capture.json")
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	_, err = capture.Replay(context.Background(), srv.Client(), srv.URL+"/webhook", files[0])
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return fake.CallCount() == 2 }, time.Second, 5*time.Millisecond)
+
+	prompts := fake.Prompts()
+	assert.Contains(t, prompts[0], "- Fingerprint: fp-capture")
+	assert.Equal(t, prompts[0], prompts[1], "the replayed payload is analyzed exactly like the original")
+
+	files, err = store.List()
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "a replay is not captured again")
+}
+
+// blockedServer returns a Server whose analyses block until release is closed.
+func blockedServer(t *testing.T) (*Server, *Handler, chan struct{}, chan struct{}) {
+	t.Helper()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	provider := llm.NewFakeProvider()
+	provider.AnalyzeFunc = func(ctx context.Context, prompt string) (string, error) {
+		close(started)
+		<-release
+		return "# Incident Analysis: pool\n**Confidence Score:** 80%\n", nil
+	}
+
+	handler, _ := analysisHandler(t, &config.Config{Analysis: config.AnalysisConfig{ProcessingRetries: 1}}, provider)
+	return &Server{cfg: handler.cfg, srv: &http.Server{}, handler: handler}, handler, started, release
+}
+
+func TestShutdownWaitsForInFlightAnalysis(t *testing.T) {
+	s, handler, started, release := blockedServer(t)
+
+	require.Equal(t, http.StatusOK, postAlert(t, SetupRouter(handler), "/webhook", "checkout").Code)
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned before the analysis finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdown)
+
+	incidents, err := handler.database.ListIncidents("")
+	require.NoError(t, err)
+	assert.Len(t, incidents, 1, "the analysis completed and was persisted before shutdown returned")
+}
+
+func TestShutdownGivesUpAtDeadline(t *testing.T) {
+	s, handler, started, release := blockedServer(t)
+	defer close(release)
+
+	require.Equal(t, http.StatusOK, postAlert(t, SetupRouter(handler), "/webhook", "checkout").Code)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestShutdownRefusesNewWorkAndKeepsItQueued(t *testing.T) {
+	s, handler, _, release := blockedServer(t)
+	close(release)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.Equal(t, http.StatusOK, postAlert(t, SetupRouter(handler), "/webhook", "checkout").Code)
+	require.NoError(t, handler.wait(context.Background()))
+
+	incidents, err := handler.database.ListIncidents("")
+	require.NoError(t, err)
+	assert.Empty(t, incidents, "alerts received during shutdown are not processed")
+
+	pending, err := handler.database.ResumePendingAlerts(maxQueueAttempts)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1, "they stay queued for the next start")
+}
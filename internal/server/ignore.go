@@ -0,0 +1,25 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+)
+
+// ignoreReason reports why an alert is dropped by alerting.ignore_services or
+// alerting.ignore_matchers, or false when it should be processed.
+func ignoreReason(alert models.AlertItem, serviceName string, cfg config.AlertingConfig) (string, bool) {
+	for _, ignored := range cfg.IgnoreServices {
+		if strings.EqualFold(ignored, serviceName) {
+			return fmt.Sprintf("service %s is ignored", serviceName), true
+		}
+	}
+	for _, matcher := range cfg.IgnoreMatchers {
+		if labelsMatch(alert.Labels, matcher) {
+			return fmt.Sprintf("labels match ignore matcher %v", matcher), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/orchestrator"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoredAlertsSkipOrchestrator(t *testing.T) {
+	cfg := &config.Config{Alerting: config.AlertingConfig{
+		IgnoreServices: []string{"HelixOps"},
+		IgnoreMatchers: []map[string]string{{"probe": "synthetic"}},
+	}}
+
+	var collected []string
+	provider := llm.NewFakeProvider("# Incident Analysis: test")
+	handler, _ := analysisHandler(t, cfg, provider)
+	handler.orchestrator.Register(orchestrator.NewCollector("probe", func(ctx context.Context, service string, window models.TimeWindow) (func(*models.AnalysisContext), error) {
+		collected = append(collected, service)
+		return nil, nil
+	}))
+
+	self := firingAlert()
+	self.Labels = map[string]string{"alertname": "HighLatency", "service_name": "helixops"}
+	self.Fingerprint = "fp-self"
+	probe := firingAlert()
+	probe.Labels = map[string]string{"alertname": "ProbeFailed", "service_name": "checkout", "probe": "synthetic"}
+	probe.Fingerprint = "fp-probe"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{self, probe, firingAlert()}})
+
+	assert.Equal(t, []string{"checkout"}, collected)
+	assert.Equal(t, 1, provider.CallCount())
+}
+
+func TestIgnoreReason(t *testing.T) {
+	cfg := config.AlertingConfig{IgnoreMatchers: []map[string]string{{}}}
+	_, ok := ignoreReason(models.AlertItem{Labels: map[string]string{"alertname": "X"}}, "checkout", cfg)
+	assert.False(t, ok, "an empty matcher must not drop every alert")
+
+	reason, ok := ignoreReason(models.AlertItem{}, "helixops", config.AlertingConfig{IgnoreServices: []string{"helixops"}})
+	assert.True(t, ok)
+	assert.Equal(t, "service helixops is ignored", reason)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"fmt"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+)
+
+// inhibitedAlerts evaluates inhibition rules against the firing alerts of a payload and returns
+// the indexes of suppressed alerts mapped to a human-readable reason.
+func inhibitedAlerts(alerts []models.AlertItem, rules []config.InhibitRule) map[int]string {
+	suppressed := make(map[int]string)
+	if len(rules) == 0 {
+		return suppressed
+	}
+
+	for i, target := range alerts {
+		if !target.IsFiring() {
+			continue
+		}
+		for _, rule := range rules {
+			if !labelsMatch(target.Labels, rule.TargetMatch) {
+				continue
+			}
+			for j, source := range alerts {
+				if i == j || !source.IsFiring() || !labelsMatch(source.Labels, rule.SourceMatch) {
+					continue
+				}
+				if !equalLabels(source.Labels, target.Labels, rule.Equal) {
+					continue
+				}
+				suppressed[i] = fmt.Sprintf("inhibited by %s", source.Labels["alertname"])
+				break
+			}
+			if _, ok := suppressed[i]; ok {
+				break
+			}
+		}
+	}
+
+	return suppressed
+}
+
+// labelsMatch reports whether every matcher label is present with the same value.
+func labelsMatch(labels, matchers map[string]string) bool {
+	if len(matchers) == 0 {
+		return false
+	}
+	for k, v := range matchers {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// equalLabels reports whether the source and target agree on every listed label.
+func equalLabels(source, target map[string]string, names []string) bool {
+	for _, name := range names {
+		if source[name] != target[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"testing"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInhibitedAlertsParentSuppressesChildren(t *testing.T) {
+	rules := []config.InhibitRule{{
+		SourceMatch: map[string]string{"alertname": "NodeDown"},
+		TargetMatch: map[string]string{"severity": "warning"},
+		Equal:       []string{"node"},
+	}}
+
+	alerts := []models.AlertItem{
+		{Status: "firing", Labels: map[string]string{"alertname": "NodeDown", "severity": "critical", "node": "n1"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "PodCrashLooping", "severity": "warning", "node": "n1"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "PodNotReady", "severity": "warning", "node": "n1"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "PodNotReady", "severity": "warning", "node": "n2"}},
+		{Status: "resolved", Labels: map[string]string{"alertname": "PodNotReady", "severity": "warning", "node": "n1"}},
+	}
+
+	suppressed := inhibitedAlerts(alerts, rules)
+
+	assert.Len(t, suppressed, 2)
+	assert.Equal(t, "inhibited by NodeDown", suppressed[1])
+	assert.Contains(t, suppressed, 2)
+	assert.NotContains(t, suppressed, 0, "the parent itself must still be analyzed")
+	assert.NotContains(t, suppressed, 3, "children on other nodes are unaffected")
+	assert.NotContains(t, suppressed, 4, "resolved alerts still close their incidents")
+}
+
+func TestInhibitedAlertsRequiresFiringSource(t *testing.T) {
+	rules := []config.InhibitRule{{
+		SourceMatch: map[string]string{"alertname": "NodeDown"},
+		TargetMatch: map[string]string{"alertname": "PodNotReady"},
+	}}
+
+	alerts := []models.AlertItem{
+		{Status: "resolved", Labels: map[string]string{"alertname": "NodeDown"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "PodNotReady"}},
+	}
+
+	assert.Empty(t, inhibitedAlerts(alerts, rules))
+	assert.Empty(t, inhibitedAlerts(alerts, nil))
+}
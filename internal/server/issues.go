@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"helixops/internal/clients/github"
+	"helixops/internal/postmortem"
+)
+
+// issueCreator opens issues in a GitHub repository.
+type issueCreator interface {
+	CreateIssue(ctx context.Context, repo string, issue github.IssueRequest) (*github.Issue, error)
+}
+
+// createIssue files the postmortem's follow-ups as an issue in the service's mapped repository.
+// Services without an explicit or operator-confirmed repo mapping are skipped rather than guessed.
+func (h *Handler) createIssue(ctx context.Context, serviceName string, pm *postmortem.Postmortem) {
+	if h.issues == nil {
+		return
+	}
+
+	repo := h.confirmedRepo(serviceName)
+	if repo == "" {
+		slog.Debug("Skipping GitHub issue: no repo mapping", "service", serviceName)
+		return
+	}
+
+	issue, err := h.issues.CreateIssue(ctx, repo, buildIssue(pm, h.cfg.GitHub.IssueLabels))
+	if err != nil {
+		slog.Error("Failed to create GitHub issue", "service", serviceName, "repo", repo, "error", err)
+		return
+	}
+	slog.Info("Created GitHub issue", "service", serviceName, "repo", repo, "number", issue.Number, "url", issue.HTMLURL)
+}
+
+// buildIssue renders the postmortem's root cause and remediation actions as an issue with a checklist.
+func buildIssue(pm *postmortem.Postmortem, labels []string) github.IssueRequest {
+	var b strings.Builder
+
+	b.WriteString("## Root Cause\n\n")
+	if pm.RootCause != "" {
+		b.WriteString(pm.RootCause)
+	} else {
+		b.WriteString("_Not determined; see the postmortem._")
+	}
+	b.WriteString("\n\n## Remediation\n\n")
+
+	if len(pm.ActionItems) == 0 && len(pm.RemediationRules) == 0 {
+		b.WriteString("_No remediation actions were suggested._\n")
+	}
+	for _, item := range pm.ActionItems {
+		fmt.Fprintf(&b, "- [ ] **%s** %s\n", item.ID, item.Text)
+	}
+	for _, rule := range pm.RemediationRules {
+		fmt.Fprintf(&b, "- [ ] **%s** %s: %s", rule.ID, rule.Title, rule.Action)
+		if rule.RequiresConfirmation {
+			b.WriteString(" ⚠️ requires confirmation")
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\n---\nGenerated by HelixOps from postmortem `%s`.\n", pm.ID)
+
+	return github.IssueRequest{
+		Title:  pm.IncidentName,
+		Body:   b.String(),
+		Labels: labels,
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"helixops/internal/clients/github"
+	"helixops/internal/config"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateIssueForMappedService(t *testing.T) {
+	var created []github.IssueRequest
+	var paths []string
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req github.IssueRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		created = append(created, req)
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/checkout/issues/7"}`))
+	}))
+	defer gh.Close()
+
+	cfg := &config.Config{GitHub: config.GitHubConfig{
+		DefaultOrg:     "acme",
+		ServiceMapping: map[string]string{"checkout": "acme/checkout"},
+		IssueLabels:    []string{"postmortem"},
+	}}
+	handler := NewHandler(cfg, nil, nil, nil, nil, nil, nil)
+	handler.issues = github.NewClient(gh.URL, "token")
+
+	pm := &postmortem.Postmortem{
+		ID:           "pm-1",
+		IncidentName: "Incident: HighLatency on checkout",
+		RootCause:    "The DB pool was reduced to 5 connections.",
+		ActionItems:  []postmortem.ActionItem{{ID: "AI-1", Text: "Restore the pool size"}},
+		RemediationRules: []remediation.Suggestion{
+			{ID: "REM-1", Title: "Scale Up Service Replicas", Action: "kubectl scale deployment checkout --replicas=3"},
+		},
+	}
+
+	handler.createIssue(context.Background(), "checkout", pm)
+	// default_org alone is not an explicit mapping, so no issue is opened for search
+	handler.createIssue(context.Background(), "search", pm)
+
+	require.Len(t, created, 1)
+	assert.Equal(t, "/repos/acme/checkout/issues", paths[0])
+	assert.Equal(t, "Incident: HighLatency on checkout", created[0].Title)
+	assert.Equal(t, []string{"postmortem"}, created[0].Labels)
+	assert.Contains(t, created[0].Body, "The DB pool was reduced to 5 connections.")
+	assert.Contains(t, created[0].Body, "- [ ] **AI-1** Restore the pool size")
+	assert.Contains(t, created[0].Body, "- [ ] **REM-1** Scale Up Service Replicas: kubectl scale deployment checkout --replicas=3")
+}
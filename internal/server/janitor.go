@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"helixops/internal/db"
+	"helixops/internal/output"
+)
+
+// janitor periodically purges incidents, analysis rows, and Markdown reports past the retention period.
+type janitor struct {
+	database  db.Store
+	reports   *output.MarkdownReporter
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+// Run purges once immediately and then on every tick until ctx is cancelled.
+func (j *janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		j.purge()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge deletes everything that ended before now minus the retention period.
+func (j *janitor) purge() {
+	cutoff := j.now().Add(-j.retention)
+
+	if j.database != nil {
+		n, err := j.database.PurgeBefore(cutoff)
+		if err != nil {
+			slog.Error("Retention cleanup failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Purged expired incidents", "count", n, "cutoff", cutoff)
+		}
+	}
+
+	if j.reports != nil {
+		n, err := j.reports.PurgeBefore(cutoff)
+		if err != nil {
+			slog.Error("Report cleanup failed", "error", err)
+		} else if n > 0 {
+			slog.Info("Purged expired reports", "count", n, "cutoff", cutoff)
+		}
+	}
+}
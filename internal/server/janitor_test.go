@@ -0,0 +1,37 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"helixops/internal/output"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJanitorPurgesExpiredReports(t *testing.T) {
+	dir := t.TempDir()
+	reports, err := output.NewMarkdownReporter(dir)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	write := func(name string, modified time.Time) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("# report"), 0o644))
+		require.NoError(t, os.Chtimes(path, modified, modified))
+		return path
+	}
+	oldReport := write("postmortem_old.md", now.Add(-100*24*time.Hour))
+	newReport := write("incident-checkout-1234.md", now.Add(-time.Hour))
+	otherFile := write("notes.txt", now.Add(-100*24*time.Hour))
+
+	j := &janitor{reports: reports, retention: 90 * 24 * time.Hour, interval: time.Hour, now: func() time.Time { return now }}
+	j.purge()
+
+	assert.NoFileExists(t, oldReport)
+	assert.FileExists(t, newReport)
+	assert.FileExists(t, otherFile)
+}
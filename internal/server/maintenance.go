@@ -0,0 +1,85 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// activeMaintenanceWindow returns the first configured window covering the service at t.
+func activeMaintenanceWindow(windows []config.MaintenanceWindow, serviceName string, t time.Time) (config.MaintenanceWindow, bool) {
+	for _, w := range windows {
+		if w.AppliesTo(serviceName) && w.Contains(t) {
+			return w, true
+		}
+	}
+	return config.MaintenanceWindow{}, false
+}
+
+// recordMaintenanceIncident stores a firing alert as a maintenance incident without analyzing
+// or notifying, so it still shows up in the incident list and can be closed when it resolves.
+// Alertmanager re-sends firing alerts every repeat_interval; a firing that already has an open
+// incident, recorded in the window or analyzed before it began, gets no second row, so its
+// resolution still finds the analyzed incident and generates the postmortem.
+func (h *Handler) recordMaintenanceIncident(alert models.AlertItem, serviceName string, window config.MaintenanceWindow) {
+	slog.Info("Skipping analysis during maintenance window", "alert", alert.Labels["alertname"], "service", serviceName, "window", window.Name)
+	if h.incidents == nil {
+		return
+	}
+
+	existing, err := h.incidents.FindOpenIncident(alert.GetFingerprint())
+	if err != nil {
+		slog.Error("Failed to look up open incident", "error", err)
+		return
+	}
+	if existing != nil && existing.StartedAt.Equal(alert.StartsAt) {
+		slog.Debug("Firing already recorded", "incident_id", existing.ID, "status", existing.Status)
+		return
+	}
+
+	incident := &db.Incident{
+		ID:          uuid.New().String(),
+		ServiceName: serviceName,
+		AlertName:   alert.Labels["alertname"],
+		Severity:    alert.Labels["severity"],
+		StartedAt:   alert.StartsAt,
+		Status:      db.IncidentStatusMaintenance,
+		Fingerprint: alert.GetFingerprint(),
+	}
+	if err := h.incidents.CreateIncident(incident); err != nil {
+		slog.Error("Failed to record maintenance incident", "error", err)
+	}
+}
+
+// closeUnanalyzedIncident closes the maintenance or triage auto-resolved incident recorded for a
+// resolved alert. It reports false when the alert's open incident was analyzed normally, so the
+// caller generates a postmortem.
+func (h *Handler) closeUnanalyzedIncident(alert models.AlertItem, serviceName string) bool {
+	if h.incidents == nil {
+		return false
+	}
+
+	incident, err := h.incidents.FindOpenIncident(alert.GetFingerprint())
+	if err != nil || incident == nil {
+		return false
+	}
+	if incident.Status != db.IncidentStatusMaintenance && incident.Status != db.IncidentStatusTriaged {
+		return false
+	}
+
+	closedAt := alert.EndsAt
+	if closedAt.IsZero() {
+		closedAt = time.Now().UTC()
+	}
+	if err := h.incidents.CloseIncident(incident.ID, closedAt); err != nil {
+		slog.Error("Failed to close incident", "incident_id", incident.ID, "status", incident.Status, "error", err)
+		return true
+	}
+	slog.Info("Closed incident without postmortem", "incident_id", incident.ID, "status", incident.Status, "service", serviceName)
+	return true
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertsInsideMaintenanceWindowAreRecordedNotAnalyzed(t *testing.T) {
+	now := time.Now().UTC()
+	cfg := &config.Config{Alerting: config.AlertingConfig{MaintenanceWindows: []config.MaintenanceWindow{{
+		Name:     "checkout db upgrade",
+		Services: []string{"checkout"},
+		Start:    now.Add(-time.Hour).Format(time.RFC3339),
+		End:      now.Add(time.Hour).Format(time.RFC3339),
+	}}}}
+	provider := llm.NewFakeProvider("# Incident Analysis: test\n**Confidence Score:** 70%")
+	handler, database := analysisHandler(t, cfg, provider)
+
+	firing := func(service, fingerprint string) models.AlertItem {
+		alert := firingAlert()
+		alert.Labels["service_name"] = service
+		alert.StartsAt = now.Add(-5 * time.Minute)
+		alert.Fingerprint = fingerprint
+		return alert
+	}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{
+		firing("checkout", "fp-maintenance"),
+		firing("cart", "fp-outside"),
+	}})
+
+	assert.Equal(t, 1, provider.CallCount(), "only the alert outside the window reaches the LLM")
+
+	suppressed, err := database.FindOpenIncident("fp-maintenance")
+	require.NoError(t, err)
+	require.NotNil(t, suppressed)
+	assert.Equal(t, db.IncidentStatusMaintenance, suppressed.Status)
+
+	processed, err := database.FindOpenIncident("fp-outside")
+	require.NoError(t, err)
+	require.NotNil(t, processed)
+	assert.Equal(t, db.IncidentStatusOpen, processed.Status)
+
+	resolved := firing("checkout", "fp-maintenance")
+	resolved.Status = "resolved"
+	resolved.EndsAt = now
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{resolved}})
+
+	open, err := database.FindOpenIncident("fp-maintenance")
+	require.NoError(t, err)
+	assert.Nil(t, open, "the resolution closes the maintenance incident")
+	closed, err := database.GetIncident(suppressed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusMaintenance, closed.Status)
+	require.NotNil(t, closed.ResolvedAt)
+}
+
+func TestMaintenanceWindowDoesNotDuplicateRecordedFirings(t *testing.T) {
+	now := time.Now().UTC()
+	provider := llm.NewFakeProvider("# Incident Analysis: pool\n**Confidence Score:** 80%\n")
+	handler, database := retryTestHandler(t, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+
+	// Analyzed before the window began, then re-sent during it
+	analyzed := firingAlert()
+	analyzed.StartsAt = now.Add(-time.Hour)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{analyzed}})
+
+	handler.cfg.Alerting.MaintenanceWindows = []config.MaintenanceWindow{{
+		Name:  "checkout db upgrade",
+		Start: now.Add(-time.Minute).Format(time.RFC3339),
+		End:   now.Add(time.Hour).Format(time.RFC3339),
+	}}
+	silenced := firingAlert()
+	silenced.Fingerprint = "fp-silenced"
+	silenced.StartsAt = now
+	for range 3 {
+		handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{analyzed, silenced}})
+	}
+
+	incidents, err := database.ListIncidents("")
+	require.NoError(t, err)
+	assert.Len(t, incidents, 2, "re-sent firings are recorded once")
+
+	calls := provider.CallCount()
+	analyzed.Status = "resolved"
+	analyzed.EndsAt = now
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{analyzed}})
+	assert.Equal(t, calls+1, provider.CallCount(), "the analyzed incident still gets its postmortem")
+	open, err := database.FindOpenIncident(analyzed.Fingerprint)
+	require.NoError(t, err)
+	assert.Nil(t, open)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"helixops/internal/db"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// repoDiscoverer matches a service to a repository in the configured GitHub org.
+type repoDiscoverer interface {
+	Discover(ctx context.Context, serviceName string) (string, error)
+}
+
+// repoDiscovery resolves unmapped services from the service_mappings table, falling back to GitHub
+// discovery and seeding the table with an unconfirmed mapping when a repository matches.
+type repoDiscovery struct {
+	database   db.Store
+	discoverer repoDiscoverer
+	// mappingsWarned is set once a failed service_mappings lookup was logged at warning level
+	mappingsWarned atomic.Bool
+}
+
+// ResolveRepo implements orchestrator.RepoResolver. A service_mappings table that cannot be read,
+// e.g. because migrations have not run, is skipped in favor of discovery and the config defaults
+// rather than failing every alert; it is tried again on the next lookup.
+func (d *repoDiscovery) ResolveRepo(ctx context.Context, serviceName string) (string, error) {
+	stored := d.database != nil
+	if stored {
+		m, err := d.database.GetServiceMapping(serviceName)
+		if err != nil {
+			d.mappingsUnavailable(serviceName, err)
+			stored = false
+		} else if m != nil {
+			return m.Repo, nil
+		}
+	}
+
+	repo, err := d.discoverer.Discover(ctx, serviceName)
+	if err != nil || repo == "" {
+		return "", err
+	}
+	slog.Info("Discovered repository for service", "service", serviceName, "repo", repo)
+	if stored {
+		if err := d.database.SeedServiceMapping(serviceName, repo); err != nil {
+			slog.Warn("Failed to store discovered service mapping", "service", serviceName, "error", err)
+		}
+	}
+	return repo, nil
+}
+
+// mappingsUnavailable logs a failed service_mappings lookup, at warning level only the first time so
+// a missing table does not flood the log with one warning per alert.
+func (d *repoDiscovery) mappingsUnavailable(serviceName string, err error) {
+	if d.mappingsWarned.CompareAndSwap(false, true) {
+		slog.Warn("Service mappings table unavailable; falling back to discovery and config defaults", "service", serviceName, "error", err)
+		return
+	}
+	slog.Debug("Service mappings table unavailable", "service", serviceName, "error", err)
+}
+
+// confirmedRepo returns the service's repository from config, or from an operator-confirmed
+// service_mappings row. Unconfirmed discoveries are ignored so nothing is written to a guessed repo.
+func (h *Handler) confirmedRepo(serviceName string) string {
+	if repo := h.cfg.MappedRepo(serviceName); repo != "" || h.database == nil {
+		return repo
+	}
+	m, err := h.database.GetServiceMapping(serviceName)
+	if err != nil {
+		slog.Warn("Failed to load service mapping", "service", serviceName, "error", err)
+		return ""
+	}
+	if m == nil || !m.Confirmed {
+		return ""
+	}
+	return m.Repo
+}
+
+// HandleListServiceMappings lists stored service-to-repository mappings, discovered and confirmed.
+func (h *Handler) HandleListServiceMappings(w http.ResponseWriter, r *http.Request) {
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	mappings, err := h.database.ListServiceMappings()
+	if err != nil {
+		slog.Error("Failed to list service mappings", "error", err)
+		http.Error(w, "Failed to retrieve service mappings", http.StatusInternalServerError)
+		return
+	}
+	if mappings == nil {
+		mappings = []db.ServiceMapping{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   mappings,
+	})
+}
+
+// confirmMappingRequest is the optional body accepted by HandleConfirmServiceMapping.
+type confirmMappingRequest struct {
+	Repo string `json:"repo"` // replaces the discovered repository when set
+}
+
+// HandleConfirmServiceMapping marks a service's mapping as operator-confirmed, optionally correcting its repo.
+func (h *Handler) HandleConfirmServiceMapping(w http.ResponseWriter, r *http.Request) {
+	service := chi.URLParam(r, "service")
+
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req confirmMappingRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<16))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Repo != "" && strings.Count(req.Repo, "/") != 1 {
+		http.Error(w, "repo must be in owner/repo format", http.StatusBadRequest)
+		return
+	}
+
+	err = h.database.ConfirmServiceMapping(service, req.Repo)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Service mapping not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to confirm service mapping", "service", service, "error", err)
+		http.Error(w, "Failed to confirm service mapping", http.StatusInternalServerError)
+		return
+	}
+
+	m, err := h.database.GetServiceMapping(service)
+	if err != nil || m == nil {
+		http.Error(w, "Failed to retrieve service mapping", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Confirmed service mapping", "service", service, "repo", m.Repo)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/github"
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+	"helixops/internal/orchestrator"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubDiscoverer map[string]string
+
+func (s stubDiscoverer) Discover(ctx context.Context, serviceName string) (string, error) {
+	return s[serviceName], nil
+}
+
+func TestDiscoveredMappingIsSeededAndNeedsConfirmation(t *testing.T) {
+	database := dbtest.New(t)
+	resolver := &repoDiscovery{database: database, discoverer: stubDiscoverer{"checkout": "acme/checkout"}}
+
+	repo, err := resolver.ResolveRepo(context.Background(), "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, "acme/checkout", repo)
+
+	m, err := database.GetServiceMapping("checkout")
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.False(t, m.Confirmed)
+
+	handler := NewHandler(&config.Config{}, nil, nil, nil, nil, nil, database)
+	assert.Empty(t, handler.confirmedRepo("checkout"), "unconfirmed discoveries are not used for writes")
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/service-mappings/checkout/confirm", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "acme/checkout", handler.confirmedRepo("checkout"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/service-mappings/checkout/confirm", strings.NewReader(`{"repo": "acme/checkout-api"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "acme/checkout-api", handler.confirmedRepo("checkout"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/service-mappings/payments/confirm", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	repo, err = resolver.ResolveRepo(context.Background(), "checkout")
+	require.NoError(t, err)
+	assert.Equal(t, "acme/checkout-api", repo, "stored mappings win over discovery")
+}
+
+func TestCommitsFetchedWhenServiceMappingsUnavailable(t *testing.T) {
+	var paths []string
+	gh := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`[{"sha": "abc1234", "commit": {"message": "Tune pool", "author": {"name": "dev", "date": "` +
+			time.Now().Add(-time.Hour).UTC().Format(time.RFC3339) + `"}}}]`))
+	}))
+	defer gh.Close()
+
+	missingTable := dbtest.New(t)
+	_, err := missingTable.Exec(`DROP TABLE service_mappings`)
+	require.NoError(t, err)
+
+	for name, store := range map[string]db.Store{"no database": nil, "missing table": missingTable} {
+		t.Run(name, func(t *testing.T) {
+			paths = nil
+			cfg := &config.Config{GitHub: config.GitHubConfig{DefaultOrg: "acme"}}
+			o := orchestrator.New(nil, github.NewClient(gh.URL, "token"), nil, nil, cfg)
+			o.UseRepoResolver(&repoDiscovery{database: store, discoverer: stubDiscoverer{"checkout": "acme/checkout-api"}})
+
+			for _, service := range []string{"checkout", "checkout", "cart"} {
+				ac, err := o.PrepareAlertContext(context.Background(), service, config.SeverityCritical, time.Now())
+				require.NoError(t, err)
+				assert.Len(t, ac.RecentCommits, 1, service)
+				assert.NotContains(t, ac.SourceErrors, "commits")
+			}
+			assert.Equal(t, []string{"/repos/acme/checkout-api/commits", "/repos/acme/checkout-api/commits", "/repos/acme/cart/commits"}, paths,
+				"discovery still runs, and undiscovered services fall back to github.default_org/<service>")
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"helixops/internal/db"
+	"helixops/internal/httpx"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+)
+
+// mergeIncidentsRequest is the body of POST /incidents/merge.
+type mergeIncidentsRequest struct {
+	PrimaryID string   `json:"primary_id"`
+	MergeIDs  []string `json:"merge_ids"`
+}
+
+// HandleMergeIncidents folds duplicate incidents that alert aggregation did not catch into a primary
+// incident. The duplicates' analysis rows move to the primary and they are marked merged, which hides
+// them from incident listings. The primary's context then lists them as related alerts, and a resolved
+// primary gets its postmortem regenerated in the background to cover them; an open one covers them
+// when it resolves. Incidents in an aggregation group, as leader or member, cannot be merged.
+func (h *Handler) HandleMergeIncidents(w http.ResponseWriter, r *http.Request) {
+	if h.database == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req mergeIncidentsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.PrimaryID = strings.TrimSpace(req.PrimaryID)
+	if req.PrimaryID == "" || len(req.MergeIDs) == 0 {
+		http.Error(w, "primary_id and merge_ids are required", http.StatusBadRequest)
+		return
+	}
+	seen := map[string]bool{req.PrimaryID: true}
+	for i, id := range req.MergeIDs {
+		id = strings.TrimSpace(id)
+		if seen[id] {
+			http.Error(w, fmt.Sprintf("merge_ids: %q is the primary or listed twice", id), http.StatusBadRequest)
+			return
+		}
+		seen[id] = true
+		req.MergeIDs[i] = id
+	}
+
+	primary, ok := h.mergeCandidate(w, req.PrimaryID)
+	if !ok {
+		return
+	}
+	for _, id := range req.MergeIDs {
+		dup, ok := h.mergeCandidate(w, id)
+		if !ok {
+			return
+		}
+		// Aggregation groups are resolved together, leader and members alike, which would undo the merge
+		if dup.GroupID != "" {
+			http.Error(w, fmt.Sprintf("Incident %s is merged into alert group %s; merge into it instead", id, dup.GroupID), http.StatusConflict)
+			return
+		}
+		group, err := h.database.IncidentGroup(id)
+		if err != nil {
+			slog.Error("Failed to load incident group", "incident_id", id, "error", err)
+			http.Error(w, "Failed to retrieve incident", http.StatusInternalServerError)
+			return
+		}
+		if len(group) > 1 {
+			http.Error(w, fmt.Sprintf("Incident %s leads an alert group; merge into it instead", id), http.StatusConflict)
+			return
+		}
+	}
+
+	// Read before the merge: the duplicates' reassigned rows are newer and would shadow the primary's own
+	ac, err := h.incidentContext(primary)
+	if err != nil {
+		slog.Error("Failed to load context snapshot", "incident_id", primary.ID, "error", err)
+		http.Error(w, "Failed to load incident context", http.StatusInternalServerError)
+		return
+	}
+	raw, err := h.database.LoadRawLLMResponse(primary.ID)
+	if err != nil {
+		slog.Warn("Failed to load raw LLM response", "incident_id", primary.ID, "error", err)
+	}
+
+	if err := h.database.MergeIncidents(primary.ID, req.MergeIDs, time.Now().UTC()); err != nil {
+		slog.Error("Failed to merge incidents", "incident_id", primary.ID, "merge_ids", req.MergeIDs, "error", err)
+		http.Error(w, "Failed to merge incidents", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("Merged duplicate incidents", "incident_id", primary.ID, "service", primary.ServiceName, "merge_ids", req.MergeIDs)
+
+	ac.RelatedAlerts = nil
+	h.attachMergedDuplicates(ac, primary)
+	if err := h.database.SaveContextSnapshot(primary.ID, ac); err != nil {
+		slog.Error("Failed to store combined context snapshot", "incident_id", primary.ID, "error", err)
+	}
+	if raw != nil {
+		if err := h.database.SaveRawLLMResponse(primary.ID, raw); err != nil {
+			slog.Error("Failed to store raw LLM response", "incident_id", primary.ID, "error", err)
+		}
+	}
+
+	resp := map[string]interface{}{
+		"status":     db.IncidentStatusMerged,
+		"primary_id": primary.ID,
+		"merged_ids": req.MergeIDs,
+	}
+	code := http.StatusOK
+	if primary.Status == db.IncidentStatusResolved && primary.ResolvedAt != nil && h.generator != nil && h.inflight.add() {
+		go func() {
+			defer h.inflight.done()
+			h.regenerateMergedPostmortem(primary, ac)
+		}()
+		resp["regenerating_postmortem"] = true
+		code = http.StatusAccepted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// regenerateMergedPostmortem rewrites the postmortem of a resolved primary from its combined context so
+// it covers the incidents merged into it. The previous postmortem stays in place if generation fails.
+func (h *Handler) regenerateMergedPostmortem(primary *db.Incident, ac *models.AnalysisContext) {
+	ac.Alert.EndsAt = *primary.ResolvedAt
+	reqCtx := httpx.WithRequestID(context.Background(), h.cfg.App.RequestIDHeader, primary.ID)
+	if h.orchestrator != nil {
+		h.orchestrator.AttachDashboards(ac)
+	}
+
+	var pm *postmortem.Postmortem
+	err := h.withRetry(primary.ServiceName, func(bool) error {
+		var err error
+		pm, err = h.generator.Generate(reqCtx, ac)
+		return err
+	})
+	if err != nil {
+		slog.Error("Giving up on combined postmortem", "incident_id", primary.ID, "error", err)
+		return
+	}
+	if err := h.database.ResolveIncidentAt(primary.ID, *primary.ResolvedAt, pm.RootCause, pm.Markdown); err != nil {
+		slog.Error("Failed to store combined postmortem", "incident_id", primary.ID, "error", err)
+		return
+	}
+	slog.Info("Regenerated combined postmortem", "postmortem_id", pm.ID, "incident_id", primary.ID)
+}
+
+// mergedDuplicate reports whether the firing a resolved alert belongs to was merged into another
+// incident, whose postmortem covers it; its resolution publishes nothing of its own.
+func (h *Handler) mergedDuplicate(alert models.AlertItem) bool {
+	if h.database == nil || alert.GetFingerprint() == "" {
+		return false
+	}
+	incident, err := h.database.FindMergedIncident(alert.GetFingerprint(), alert.StartsAt)
+	if err != nil {
+		slog.Warn("Failed to look up merged incident", "fingerprint", alert.GetFingerprint(), "error", err)
+		return false
+	}
+	if incident == nil {
+		return false
+	}
+	slog.Info("Skipping postmortem of merged incident", "incident_id", incident.ID, "merged_into", incident.MergedInto)
+	return true
+}
+
+// mergeCandidate loads an incident named in a merge request, writing the error response and
+// reporting false when it does not exist or was itself merged already.
+func (h *Handler) mergeCandidate(w http.ResponseWriter, id string) (*db.Incident, bool) {
+	incident, err := h.database.GetIncident(id)
+	if err != nil {
+		slog.Error("Failed to get incident", "id", id, "error", err)
+		http.Error(w, "Failed to retrieve incident", http.StatusInternalServerError)
+		return nil, false
+	}
+	if incident == nil {
+		http.Error(w, fmt.Sprintf("Incident %s not found", id), http.StatusNotFound)
+		return nil, false
+	}
+	if incident.MergedInto != "" {
+		http.Error(w, fmt.Sprintf("Incident %s is already merged into %s", id, incident.MergedInto), http.StatusConflict)
+		return nil, false
+	}
+	return incident, true
+}
+
+// attachMergedDuplicates lists the incidents merged into incident as related alerts, so its
+// postmortem covers them.
+func (h *Handler) attachMergedDuplicates(ac *models.AnalysisContext, incident *db.Incident) {
+	if h.database == nil || incident == nil {
+		return
+	}
+	merged, err := h.database.MergedIncidents(incident.ID)
+	if err != nil {
+		slog.Warn("Failed to load merged incidents", "incident_id", incident.ID, "error", err)
+		return
+	}
+	for _, d := range merged {
+		related := models.RelatedAlert{ServiceName: d.ServiceName, AlertName: d.AlertName, StartedAt: d.StartedAt}
+		if d.ResolvedAt != nil {
+			related.ResolvedAt = *d.ResolvedAt
+		}
+		ac.RelatedAlerts = append(ac.RelatedAlerts, related)
+	}
+}
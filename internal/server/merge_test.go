@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mergeAdmin is the admin token POST /incidents/merge is called with.
+const mergeAdmin = "admin-secret"
+
+// mergeRequest is an authenticated POST /incidents/merge with body.
+func mergeRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/incidents/merge", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+mergeAdmin)
+	return req
+}
+
+func TestMergeIncidentsRegeneratesCombinedPostmortem(t *testing.T) {
+	database := dbtest.New(t)
+	started := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	for i, inc := range []db.Incident{
+		{ID: "primary", ServiceName: "checkout", AlertName: "HighLatency"},
+		{ID: "dup", ServiceName: "payments", AlertName: "HighErrorRate"},
+		{ID: "other", ServiceName: "search", AlertName: "HighLatency"},
+	} {
+		inc.Severity = "critical"
+		inc.StartedAt = started.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, database.CreateIncident(&inc))
+	}
+	require.NoError(t, database.SaveContextSnapshot("primary", &models.AnalysisContext{ServiceName: "checkout"}))
+	require.NoError(t, database.SaveContextSnapshot("dup", &models.AnalysisContext{ServiceName: "payments"}))
+	require.NoError(t, database.ResolveIncidentAt("primary", started.Add(30*time.Minute), "pool exhausted", "# Postmortem"))
+
+	provider := llm.NewFakeProvider("# Postmortem\n## Root Cause\nThe checkout pool was exhausted.\n")
+	cfg := &config.Config{App: config.AppConfig{AdminToken: mergeAdmin}}
+	router := chi.NewRouter()
+	handler := NewHandler(cfg, nil, nil, postmortem.NewGenerator(provider, remediation.NewEngine()), nil, nil, database)
+	handler.RegisterRoutes(router)
+	merge := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, mergeRequest(body))
+		return rec
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/incidents/merge", strings.NewReader(`{"primary_id": "primary", "merge_ids": ["dup"]}`)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code, "merging requires the admin token")
+
+	assert.Equal(t, http.StatusBadRequest, merge(`{"primary_id": "primary"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, merge(`{"primary_id": "primary", "merge_ids": ["primary"]}`).Code)
+	assert.Equal(t, http.StatusNotFound, merge(`{"primary_id": "primary", "merge_ids": ["missing"]}`).Code)
+
+	rec = merge(`{"primary_id": "primary", "merge_ids": ["dup"]}`)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "merged", resp["status"])
+	assert.Equal(t, true, resp["regenerating_postmortem"], "a resolved primary gets its postmortem regenerated")
+	settle(handler)
+	assert.Contains(t, provider.LastPrompt(), "payments", "the combined postmortem covers the duplicate")
+	primary, err := database.GetIncident("primary")
+	require.NoError(t, err)
+	assert.Contains(t, *primary.AISummary, "checkout pool was exhausted")
+
+	ac, err := database.LoadContextSnapshot("primary")
+	require.NoError(t, err)
+	assert.Equal(t, "checkout", ac.ServiceName, "the duplicate's snapshot does not shadow the primary's")
+	assert.Equal(t, []string{"checkout", "payments"}, ac.AffectedServices())
+
+	assert.Equal(t, http.StatusConflict, merge(`{"primary_id": "other", "merge_ids": ["dup"]}`).Code, "already merged")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/postmortems/dup", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/postmortems/primary", rec.Header().Get("Location"))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/postmortems", nil))
+	assert.NotContains(t, rec.Body.String(), `"dup"`, "merged duplicates are not listed")
+}
+
+func TestMergeIncidentsRejectsAggregationGroups(t *testing.T) {
+	database := dbtest.New(t)
+	started := time.Date(2024, 6, 1, 14, 0, 0, 0, time.UTC)
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "primary", ServiceName: "checkout", StartedAt: started}))
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "leader", ServiceName: "db", StartedAt: started}))
+	require.NoError(t, database.CreateIncident(&db.Incident{ID: "member", ServiceName: "cart", StartedAt: started, GroupID: "leader"}))
+
+	router := chi.NewRouter()
+	NewHandler(&config.Config{App: config.AppConfig{AdminToken: mergeAdmin}}, nil, nil, nil, nil, nil, database).RegisterRoutes(router)
+	for _, id := range []string{"leader", "member"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, mergeRequest(`{"primary_id": "primary", "merge_ids": ["`+id+`"]}`))
+		assert.Equal(t, http.StatusConflict, rec.Code, id)
+	}
+}
+
+func TestResolvedMergedDuplicatePublishesNoPostmortem(t *testing.T) {
+	provider := llm.NewFakeProvider("# Incident Analysis: pool\n**Confidence Score:** 80%\n")
+	handler, database := retryTestHandler(t, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+
+	primary, dup := firingAlert(), firingAlert()
+	dup.Fingerprint = "fp-dup"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{primary, dup}})
+	primaryIncident, err := database.FindOpenIncident(primary.Fingerprint)
+	require.NoError(t, err)
+	dupIncident, err := database.FindOpenIncident(dup.Fingerprint)
+	require.NoError(t, err)
+	require.NoError(t, database.MergeIncidents(primaryIncident.ID, []string{dupIncident.ID}, time.Now()))
+	calls := provider.CallCount()
+
+	dup.Status = "resolved"
+	dup.EndsAt = dup.StartsAt.Add(10 * time.Minute)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{dup}})
+
+	assert.Equal(t, calls, provider.CallCount(), "the duplicate is covered by the primary's postmortem")
+	merged, err := database.GetIncident(dupIncident.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusMerged, merged.Status)
+}
+
+func TestMergeIncidentsRequiresDatabase(t *testing.T) {
+	router := chi.NewRouter()
+	NewHandler(&config.Config{App: config.AppConfig{AdminToken: mergeAdmin}}, nil, nil, nil, nil, nil, nil).RegisterRoutes(router)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, mergeRequest(`{"primary_id": "a", "merge_ids": ["b"]}`))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
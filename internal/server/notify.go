@@ -0,0 +1,70 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Notification channels; each is delivered at most once per incident unless a resend is requested.
+const (
+	notifySlackFiring       = "slack_firing"
+	notifySlackAnalysis     = "slack_analysis"
+	notifySlackPostmortem   = "slack_postmortem"
+	notifyWebhookAnalysis   = "webhook_analysis"
+	notifyWebhookPostmortem = "webhook_postmortem"
+)
+
+// notificationKey identifies an incident across processing retries and repeated deliveries of its
+// alert: the alert fingerprint and the time it started firing. It is empty without a fingerprint.
+func notificationKey(fingerprint string, startedAt time.Time) string {
+	if fingerprint == "" {
+		return ""
+	}
+	return fingerprint + "@" + startedAt.UTC().Format(time.RFC3339)
+}
+
+// notifyOnce runs send unless the notification for incidentKey on channel already went out. The marker
+// is taken as pending before sending, completed after a successful send, and released when send fails
+// so a later attempt can deliver it. With resend the notification is sent again regardless. Without a
+// notification log or incident key every notification is sent, and a failing log never holds one back.
+func (h *Handler) notifyOnce(incidentKey, channel string, resend bool, send func() error) error {
+	_, err := h.notifyOnceRef(incidentKey, channel, resend, func() (string, error) { return "", send() })
+	return err
+}
+
+// notifyOnceRef is notifyOnce for a send that returns a reference to what it posted, such as the Slack
+// thread a firing message started. The reference is stored with the marker, and when the notification
+// already went out the stored reference is returned instead, so later replies still find the thread.
+func (h *Handler) notifyOnceRef(incidentKey, channel string, resend bool, send func() (string, error)) (string, error) {
+	if h.notifications == nil || incidentKey == "" {
+		return send()
+	}
+	// Each receiver profile notifies its own team, so markers are kept per receiver
+	if h.receiver != "" {
+		channel = h.receiver + "/" + channel
+	}
+
+	claimed, ref, err := h.notifications.ClaimNotification(incidentKey, channel)
+	if err != nil {
+		slog.Warn("Failed to check sent notifications; sending anyway", "incident_key", incidentKey, "channel", channel, "error", err)
+		return send()
+	}
+	if !claimed && !resend {
+		slog.Info("Skipping notification already sent for incident", "incident_key", incidentKey, "channel", channel)
+		return ref, nil
+	}
+
+	ref, err = send()
+	if err != nil {
+		if claimed {
+			if err := h.notifications.ReleaseNotification(incidentKey, channel); err != nil {
+				slog.Warn("Failed to release notification marker", "incident_key", incidentKey, "channel", channel, "error", err)
+			}
+		}
+		return "", err
+	}
+	if err := h.notifications.CompleteNotification(incidentKey, channel, ref); err != nil {
+		slog.Warn("Failed to record sent notification", "incident_key", incidentKey, "channel", channel, "error", err)
+	}
+	return ref, nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/output"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessFiringSendsSlackOncePerIncident(t *testing.T) {
+	var posts, failures atomic.Int32
+	slackHook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		posts.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slackHook.Close()
+
+	cfg := &config.Config{}
+	cfg.Output.Slack = config.SlackOutputConfig{Enabled: true, WebhookURL: slackHook.URL}
+	newHandler := func(t *testing.T) *Handler {
+		handler, _ := analysisHandler(t, cfg, llm.NewFakeProvider(testAnalysis))
+		handler.slackSender = output.NewSlackSenderFromConfig(cfg.Output.Slack)
+		return handler
+	}
+	alert := firingAlert()
+	alert.StartsAt = time.Now().Add(-10 * time.Minute)
+
+	t.Run("duplicate analysis", func(t *testing.T) {
+		posts.Store(0)
+		handler := newHandler(t)
+		handler.processFiring(alert, "checkout")
+		handler.processFiring(alert, "checkout")
+		assert.Equal(t, int32(1), posts.Load(), "a second send for the same incident and channel is suppressed")
+
+		later := alert
+		later.StartsAt = alert.StartsAt.Add(time.Hour)
+		handler.processFiring(later, "checkout")
+		assert.Equal(t, int32(2), posts.Load(), "a new firing of the alert is a new incident")
+	})
+
+	t.Run("failed send is retried", func(t *testing.T) {
+		posts.Store(0)
+		failures.Store(1)
+		handler := newHandler(t)
+		handler.processFiring(alert, "checkout")
+		require.Equal(t, int32(0), posts.Load())
+		handler.processFiring(alert, "checkout")
+		assert.Equal(t, int32(1), posts.Load(), "a failed send releases its marker")
+	})
+
+	t.Run("receivers keep separate markers", func(t *testing.T) {
+		posts.Store(0)
+		handler := newHandler(t)
+		infra := NewHandler(cfg, handler.orchestrator, handler.analyzer, nil, nil, handler.slackSender, handler.database)
+		infra.receiver = "infra"
+		handler.processFiring(alert, "checkout")
+		infra.processFiring(alert, "checkout")
+		assert.Equal(t, int32(2), posts.Load(), "each receiver's team is notified once")
+	})
+
+	t.Run("claim left pending by a crash", func(t *testing.T) {
+		posts.Store(0)
+		handler := newHandler(t)
+		claimed, _, err := handler.notifications.ClaimNotification(notificationKey(alert.Fingerprint, alert.StartsAt), notifySlackAnalysis)
+		require.NoError(t, err)
+		require.True(t, claimed)
+
+		handler.resumeQueue()
+		handler.processFiring(alert, "checkout")
+		assert.Equal(t, int32(1), posts.Load(), "the interrupted notification is sent after the restart")
+	})
+
+	t.Run("in-memory registry", func(t *testing.T) {
+		posts.Store(0)
+		handler := newHandler(t)
+		handler.database = nil
+		registry := db.NewIncidentRegistry(0)
+		handler.incidents, handler.notifications = registry, registry
+		handler.processFiring(alert, "checkout")
+		handler.processFiring(alert, "checkout")
+		assert.Equal(t, int32(1), posts.Load())
+	})
+}
+
+func TestRedeliveredAlertRepliesInTheFirstThread(t *testing.T) {
+	slackAPI, messages := slackRecorder(t)
+	handler, _ := retryTestHandler(t, llm.NewFakeProvider(testAnalysis))
+	handler.slackSender = slackBot(slackAPI.URL)
+
+	// The first delivery started the thread but stopped before the analysis was posted
+	alert := firingAlert()
+	ref, err := handler.notifyOnceRef(notificationKey(alert.Fingerprint, alert.StartsAt), notifySlackFiring, false, func() (string, error) {
+		return handler.slackSender.SendFiring("checkout", alert.ToAlertInfo())
+	})
+	require.NoError(t, err)
+
+	handler.processFiring(alert, "checkout")
+
+	posted := messages()
+	require.Len(t, posted, 2, "the firing message is not posted again")
+	assert.Equal(t, ref, posted[1].ThreadTS, "the analysis replies in the thread of the first delivery")
+}
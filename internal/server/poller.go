@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/models"
+)
+
+// alertLister lists the alerts Prometheus is currently evaluating.
+type alertLister interface {
+	ActiveAlerts(ctx context.Context) ([]prometheus.Alert, error)
+}
+
+// alertPoller pulls active alerts from Prometheus and turns state changes between polls into
+// firing and resolved alerts for the webhook processing path.
+type alertPoller struct {
+	source   alertLister
+	handler  *Handler
+	interval time.Duration
+	now      func() time.Time
+
+	// firing holds the alerts seen firing on the last successful poll, keyed by fingerprint
+	firing map[string]models.AlertItem
+}
+
+func newAlertPoller(source alertLister, handler *Handler, interval time.Duration) *alertPoller {
+	return &alertPoller{
+		source:   source,
+		handler:  handler,
+		interval: interval,
+		now:      time.Now,
+		firing:   make(map[string]models.AlertItem),
+	}
+}
+
+// Run polls once immediately and then on every tick until ctx is cancelled.
+func (p *alertPoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll diffs the currently firing alerts against the previous poll and dispatches the transitions.
+// A failed poll keeps the previous state so an API outage does not resolve every alert.
+func (p *alertPoller) poll(ctx context.Context) {
+	alerts, err := p.source.ActiveAlerts(ctx)
+	if err != nil {
+		slog.Warn("Failed to poll Prometheus alerts", "error", err)
+		return
+	}
+
+	current := make(map[string]models.AlertItem, len(alerts))
+	for _, a := range alerts {
+		// Pending alerts have not crossed their "for" duration yet
+		if a.State != "firing" {
+			continue
+		}
+		item := models.AlertItem{
+			Status:      "firing",
+			Labels:      a.Labels,
+			Annotations: a.Annotations,
+			StartsAt:    a.ActiveAt,
+		}
+		item.Fingerprint = item.GetFingerprint()
+		current[item.Fingerprint] = item
+	}
+
+	var transitions []models.AlertItem
+	for fp, item := range current {
+		if _, seen := p.firing[fp]; !seen && !p.alreadyOpen(fp) {
+			transitions = append(transitions, item)
+		}
+	}
+	for fp, item := range p.firing {
+		if _, still := current[fp]; !still {
+			item.Status = "resolved"
+			item.EndsAt = p.now()
+			transitions = append(transitions, item)
+		}
+	}
+	p.firing = current
+
+	if len(transitions) == 0 {
+		return
+	}
+	slog.Info("Polled alert transitions", "count", len(transitions), "firing", len(current))
+	p.handler.dispatch(models.AlertManagerPayload{Receiver: "prometheus-poll", Alerts: transitions})
+}
+
+// alreadyOpen reports whether an alert already has an open incident, so alerts that were firing
+// before a restart are not analyzed twice.
+func (p *alertPoller) alreadyOpen(fingerprint string) bool {
+	if p.handler.incidents == nil {
+		return false
+	}
+	incident, err := p.handler.incidents.FindOpenIncident(fingerprint)
+	if err != nil {
+		slog.Warn("Failed to look up open incident", "fingerprint", fingerprint, "error", err)
+		return false
+	}
+	return incident != nil
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helixops/internal/clients/prometheus"
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const firingAlertsJSON = `{
+	"status": "success",
+	"data": {
+		"alerts": [
+			{
+				"labels": {"alertname": "HighLatency", "service": "checkout", "severity": "critical"},
+				"annotations": {"summary": "p99 above 1s"},
+				"state": "firing",
+				"activeAt": "2024-01-01T12:00:00Z"
+			},
+			{
+				"labels": {"alertname": "HighErrorRate", "service": "cart"},
+				"state": "pending",
+				"activeAt": "2024-01-01T12:05:00Z"
+			}
+		]
+	}
+}`
+
+func TestAlertPollerDrivesFiringToResolved(t *testing.T) {
+	var resolved atomic.Bool
+	promAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if resolved.Load() {
+			w.Write([]byte(`{"status": "success", "data": {"alerts": []}}`))
+			return
+		}
+		w.Write([]byte(firingAlertsJSON))
+	}))
+	defer promAPI.Close()
+
+	cfg := &config.Config{Analysis: config.AnalysisConfig{ProcessingRetries: 1}}
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := analysisHandler(t, cfg, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	p := newAlertPoller(prometheus.NewClient(promAPI.URL, time.Second), handler, time.Minute)
+	resolvedAt := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	p.now = func() time.Time { return resolvedAt }
+
+	p.poll(context.Background())
+	settle(handler)
+
+	incidents, err := database.ListIncidents("")
+	require.NoError(t, err)
+	require.Len(t, incidents, 1, "pending alerts are not analyzed")
+	assert.Equal(t, "checkout", incidents[0].ServiceName)
+	assert.Equal(t, db.IncidentStatusOpen, incidents[0].Status)
+
+	// Unchanged state between polls dispatches nothing
+	p.poll(context.Background())
+	settle(handler)
+	assert.Equal(t, 1, provider.CallCount())
+
+	resolved.Store(true)
+	p.poll(context.Background())
+	settle(handler)
+
+	incident, err := database.GetIncident(incidents[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, incident.Status)
+	assert.Empty(t, p.firing)
+}
+
+func TestAlertPollerSkipsAlertsWithOpenIncident(t *testing.T) {
+	promAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(firingAlertsJSON))
+	}))
+	defer promAPI.Close()
+
+	provider := llm.NewFakeProvider("# Incident Analysis: test")
+	handler, _ := analysisHandler(t, &config.Config{}, provider)
+	p := newAlertPoller(prometheus.NewClient(promAPI.URL, time.Second), handler, time.Minute)
+
+	// An incident left open from before a restart
+	p.poll(context.Background())
+	settle(handler)
+	require.Equal(t, 1, provider.CallCount())
+
+	restarted := newAlertPoller(prometheus.NewClient(promAPI.URL, time.Second), handler, time.Minute)
+	restarted.poll(context.Background())
+	settle(handler)
+	assert.Equal(t, 1, provider.CallCount(), "already-open incidents are not re-analyzed")
+	assert.Len(t, restarted.firing, 1, "but they are still tracked so their resolution is seen")
+}
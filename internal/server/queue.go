@@ -0,0 +1,157 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"helixops/internal/models"
+)
+
+// maxQueueAttempts bounds how often a queued payload is processed, counting resumes after restarts, so
+// a payload that crashes HelixOps every time is abandoned instead of retried on every start.
+const maxQueueAttempts = 3
+
+// enqueue persists a received payload before it is processed so it survives a restart. It returns 0
+// without a database or when the write fails; the payload is then still processed, just not durably.
+func (h *Handler) enqueue(payload models.AlertManagerPayload) int64 {
+	if h.database == nil {
+		return 0
+	}
+	id, err := h.database.EnqueueAlerts(h.receiver, payload)
+	if err != nil {
+		slog.Error("Failed to queue alerts; processing without persistence", "error", err)
+		return 0
+	}
+	return id
+}
+
+// resumeQueue processes payloads that were queued but not finished before the last stop, each on the
+// receiver profile it arrived on. It runs once at startup, before new alerts are accepted, and first
+// releases notification claims the stop interrupted.
+func (h *Handler) resumeQueue() {
+	if h.database == nil {
+		return
+	}
+	// Notifications claimed but not sent before the stop are sent when their alerts are processed again
+	if err := h.database.ReleasePendingNotifications(); err != nil {
+		slog.Error("Failed to release pending notifications", "error", err)
+	}
+	pending, err := h.database.ResumePendingAlerts(maxQueueAttempts)
+	if err != nil {
+		slog.Error("Failed to resume queued alerts", "error", err)
+		return
+	}
+
+	for _, p := range pending {
+		target := h
+		if p.Receiver != "" {
+			rh, ok := h.receivers[p.Receiver]
+			if !ok {
+				if err := h.database.FinishPendingAlert(p.ID, fmt.Errorf("unknown webhook receiver %q", p.Receiver)); err != nil {
+					slog.Error("Failed to mark queued alerts failed", "queue_id", p.ID, "error", err)
+				}
+				continue
+			}
+			target = rh
+		}
+		if !p.NotBefore.IsZero() {
+			slog.Info("Rescheduling delayed postmortems", "queue_id", p.ID, "receiver", p.Receiver, "count", len(p.Payload.Alerts), "not_before", p.NotBefore)
+			target.runAt(p.ID, p.Payload, p.NotBefore)
+			continue
+		}
+		slog.Info("Resuming queued alerts", "queue_id", p.ID, "receiver", p.Receiver, "count", len(p.Payload.Alerts), "attempt", p.Attempts)
+		target.run(p.ID, p.Payload)
+	}
+}
+
+// finishQueued marks a processed queue entry done, or failed with procErr, the alerts that were given
+// up on; id 0 means it was not queued. A failed entry is not resumed again: each of its alerts already
+// had analysis.processing_retries attempts, and its failed incidents can be replayed.
+func (h *Handler) finishQueued(id int64, procErr error) {
+	if procErr != nil {
+		slog.Error("Failed to process queued alerts", "queue_id", id, "error", procErr)
+	}
+	if id == 0 {
+		return
+	}
+	if err := h.database.FinishPendingAlert(id, procErr); err != nil {
+		slog.Error("Failed to mark queued alerts finished", "queue_id", id, "error", err)
+	}
+}
+
+// scheduledRuns holds the timers of queued payloads that are not yet due. Once stopped, pending timers
+// are cancelled and no new ones start; their queue rows stay pending for the next start.
+type scheduledRuns struct {
+	mu      sync.Mutex
+	timers  map[*time.Timer]struct{}
+	stopped bool
+}
+
+// stop cancels every timer that has not fired and refuses new ones.
+func (s *scheduledRuns) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	for t := range s.timers {
+		t.Stop()
+	}
+	s.timers = nil
+}
+
+// scheduleResolved queues resolved alerts durably and generates their postmortems once notBefore has
+// passed, so logs and traces that backends are still flushing are part of the postmortem context.
+func (h *Handler) scheduleResolved(alerts []models.AlertItem, notBefore time.Time) {
+	payload := models.AlertManagerPayload{Alerts: alerts}
+	var id int64
+	if h.database != nil {
+		var err error
+		if id, err = h.database.ScheduleAlerts(h.receiver, payload, notBefore); err != nil {
+			slog.Error("Failed to queue delayed postmortems; scheduling without persistence", "error", err)
+			id = 0
+		}
+	}
+	slog.Info("Delaying postmortems for late telemetry", "count", len(alerts), "not_before", notBefore)
+	h.runAt(id, payload, notBefore)
+}
+
+// runAt generates the postmortems of a scheduled payload of resolved alerts once notBefore has passed
+// and marks its queue entry done or failed; id 0 means it was not queued. The attempt is counted when
+// processing starts, so one that crashes the process counts towards maxQueueAttempts. Processing
+// counts as in flight only once it starts, so shutdown does not wait for postmortems that are not yet due.
+func (h *Handler) runAt(id int64, payload models.AlertManagerPayload, notBefore time.Time) {
+	h.scheduled.mu.Lock()
+	defer h.scheduled.mu.Unlock()
+	if h.scheduled.stopped {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(notBefore), func() {
+		h.scheduled.mu.Lock()
+		delete(h.scheduled.timers, timer)
+		stopped := h.scheduled.stopped
+		h.scheduled.mu.Unlock()
+		if stopped || !h.inflight.add() {
+			return
+		}
+		defer h.inflight.done()
+
+		if id != 0 {
+			if err := h.database.StartPendingAlert(id); err != nil {
+				slog.Error("Failed to count queued alerts attempt", "queue_id", id, "error", err)
+			}
+		}
+		var errs []error
+		for _, alert := range payload.Alerts {
+			errs = append(errs, h.processResolved(alert, extractServiceName(alert.Labels)))
+		}
+		h.finishQueued(id, errors.Join(errs...))
+	})
+	if h.scheduled.timers == nil {
+		h.scheduled.timers = make(map[*time.Timer]struct{})
+	}
+	h.scheduled.timers[timer] = struct{}{}
+}
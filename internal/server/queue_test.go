@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/db/dbtest"
+	"helixops/internal/models"
+	"helixops/internal/orchestrator"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func queueStatus(t *testing.T, database *db.DB, id int64) string {
+	t.Helper()
+	var status string
+	require.NoError(t, database.QueryRow(`SELECT status FROM pending_alerts WHERE id = $1`, id).Scan(&status))
+	return status
+}
+
+func TestPendingAlertsResumeAfterRestart(t *testing.T) {
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := retryTestHandler(t, provider)
+
+	// The first process acknowledged the webhook and queued the payload, then stopped before processing it
+	id, err := database.EnqueueAlerts("", models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+	require.NoError(t, err)
+	assert.Equal(t, db.PendingAlertPending, queueStatus(t, database, id))
+
+	// A new process on the same database resumes it
+	restarted := restart(handler)
+	restarted.resumeQueue()
+	require.NoError(t, restarted.wait(context.Background()))
+
+	assert.Equal(t, 1, provider.CallCount())
+	incidents, err := database.ListIncidents("")
+	require.NoError(t, err)
+	require.Len(t, incidents, 1)
+	assert.Equal(t, "checkout", incidents[0].ServiceName)
+	assert.Equal(t, db.PendingAlertDone, queueStatus(t, database, id))
+
+	resumed, err := database.ResumePendingAlerts(maxQueueAttempts)
+	require.NoError(t, err)
+	assert.Empty(t, resumed, "finished payloads are not resumed again")
+}
+
+func TestPendingAlertsAbandonedAfterMaxAttempts(t *testing.T) {
+	provider := llm.NewFakeProvider("ok")
+	handler, database := retryTestHandler(t, provider)
+
+	id, err := database.EnqueueAlerts("", models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+	require.NoError(t, err)
+	for i := 1; i < maxQueueAttempts; i++ {
+		_, err := database.ResumePendingAlerts(maxQueueAttempts) // each start that crashed mid-processing
+		require.NoError(t, err)
+	}
+
+	handler.resumeQueue()
+	require.NoError(t, handler.wait(context.Background()))
+
+	assert.Zero(t, provider.CallCount())
+	assert.Equal(t, db.PendingAlertFailed, queueStatus(t, database, id))
+}
+
+func TestFailedQueuedAlertsAreMarkedFailed(t *testing.T) {
+	provider := llm.NewFakeProvider()
+	provider.Err = errors.New("model overloaded")
+	handler, database := retryTestHandler(t, provider)
+
+	id, err := database.EnqueueAlerts("", models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+	require.NoError(t, err)
+	handler.run(id, models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}})
+	settle(handler)
+
+	var (
+		lastError string
+		attempts  int
+	)
+	require.NoError(t, database.QueryRow(`SELECT last_error, attempts FROM pending_alerts WHERE id = $1`, id).Scan(&lastError, &attempts))
+	assert.Equal(t, db.PendingAlertFailed, queueStatus(t, database, id))
+	assert.Contains(t, lastError, "HighLatency on checkout")
+	assert.Contains(t, lastError, "model overloaded")
+	assert.Equal(t, 1, attempts)
+
+	resumed, err := database.ResumePendingAlerts(maxQueueAttempts)
+	require.NoError(t, err)
+	assert.Empty(t, resumed, "failed payloads are not resumed")
+}
+
+func TestScheduledPostmortemsCountAttemptsWhenDue(t *testing.T) {
+	database := dbtest.New(t)
+	id, err := database.ScheduleAlerts("", models.AlertManagerPayload{Alerts: []models.AlertItem{firingAlert()}}, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Restarts before the payload is due do not use up its attempts
+	for i := 0; i <= maxQueueAttempts; i++ {
+		resumed, err := database.ResumePendingAlerts(maxQueueAttempts)
+		require.NoError(t, err)
+		require.Len(t, resumed, 1)
+		assert.Zero(t, resumed[0].Attempts)
+	}
+
+	// Each start of its processing does, so one that keeps crashing is abandoned
+	for i := 0; i < maxQueueAttempts; i++ {
+		require.NoError(t, database.StartPendingAlert(id))
+	}
+	resumed, err := database.ResumePendingAlerts(maxQueueAttempts)
+	require.NoError(t, err)
+	assert.Empty(t, resumed)
+	assert.Equal(t, db.PendingAlertFailed, queueStatus(t, database, id))
+}
+
+func TestWebhookQueuesPayloadBeforeAcknowledging(t *testing.T) {
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := retryTestHandler(t, provider)
+
+	require.Equal(t, 200, postAlert(t, SetupRouter(handler), "/webhook", "checkout").Code)
+	require.NoError(t, handler.wait(context.Background()))
+
+	var count int
+	require.NoError(t, database.QueryRow(`SELECT COUNT(*) FROM pending_alerts WHERE status = 'done'`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+// restart returns a Handler built from h's configuration, pipeline, and database, as the next process
+// would be after h stopped.
+func restart(h *Handler) *Handler {
+	return NewHandler(h.cfg, orchestrator.New(nil, nil, nil, nil, h.cfg), h.analyzer, h.generator, nil, nil, h.database)
+}
+
+// delayedPostmortemHandler analyzes and writes postmortems with provider, delaying postmortems by delay.
+func delayedPostmortemHandler(t *testing.T, provider *llm.FakeProvider, delay string) (*Handler, *db.DB) {
+	t.Helper()
+	handler, database := analysisHandler(t, &config.Config{Postmortem: config.PostmortemConfig{ResolveDelay: delay}}, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+	return handler, database
+}
+
+func TestPostmortemWaitsForResolveDelay(t *testing.T) {
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := delayedPostmortemHandler(t, provider, "300ms")
+
+	alert := firingAlert()
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	analyzed := provider.CallCount()
+
+	alert.Status = "resolved"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+
+	var id int64
+	require.NoError(t, database.QueryRow(`SELECT id FROM pending_alerts WHERE not_before IS NOT NULL`).Scan(&id))
+	assert.Equal(t, db.PendingAlertPending, queueStatus(t, database, id))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, analyzed, provider.CallCount(), "no postmortem before the delay elapses")
+	open, err := database.FindOpenIncident(alert.Fingerprint)
+	require.NoError(t, err)
+	require.NotNil(t, open, "the incident stays open until the postmortem runs")
+
+	require.Eventually(t, func() bool { return queueStatus(t, database, id) == db.PendingAlertDone }, 5*time.Second, 20*time.Millisecond)
+	require.NoError(t, handler.wait(context.Background()))
+	assert.Greater(t, provider.CallCount(), analyzed)
+	resolved, err := database.GetIncident(open.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, resolved.Status)
+}
+
+func TestDelayedPostmortemResumesAfterRestart(t *testing.T) {
+	provider := llm.NewFakeProvider(testAnalysis)
+	handler, database := delayedPostmortemHandler(t, provider, "1h")
+
+	alert := firingAlert()
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	alert.Status = "resolved"
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	analyzed := provider.CallCount()
+
+	// Shutdown cancels the pending timer without generating the postmortem
+	require.NoError(t, handler.wait(context.Background()))
+	var id int64
+	require.NoError(t, database.QueryRow(`SELECT id FROM pending_alerts WHERE not_before IS NOT NULL`).Scan(&id))
+	assert.Equal(t, db.PendingAlertPending, queueStatus(t, database, id))
+	assert.Equal(t, analyzed, provider.CallCount())
+
+	// The delay has passed by the time a new process starts
+	_, err := database.Exec(`UPDATE pending_alerts SET not_before = $1 WHERE id = $2`, time.Now().Add(-time.Minute).UTC(), id)
+	require.NoError(t, err)
+
+	restarted := restart(handler)
+	restarted.resumeQueue()
+	require.Eventually(t, func() bool { return queueStatus(t, database, id) == db.PendingAlertDone }, 5*time.Second, 20*time.Millisecond)
+	require.NoError(t, restarted.wait(context.Background()))
+
+	incidents, err := database.ListIncidents(db.IncidentStatusResolved)
+	require.NoError(t, err)
+	assert.Len(t, incidents, 1)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/output"
+)
+
+// causeWord matches the words root causes are compared by, digits the numbers inside them, and
+// sentenceEnd the end of a root cause's first sentence.
+var (
+	causeWord   = regexp.MustCompile(`[a-z0-9]+`)
+	digits      = regexp.MustCompile(`[0-9]+`)
+	sentenceEnd = regexp.MustCompile(`[.!?](\s|$)|\n`)
+)
+
+// recurrenceTracker applies output.recurring: it follows, per service, the streak of consecutive
+// incidents whose root cause matches the one that started the streak.
+type recurrenceTracker struct {
+	cfg config.RecurringConfig
+
+	mu      sync.Mutex
+	streaks map[string]*causeStreak
+}
+
+// causeStreak is a run of incidents with the same root cause on one service.
+type causeStreak struct {
+	// cause is that of the incident that started the streak, so gradual drift does not extend it
+	cause normalizedCause
+	// incidents holds the notification keys already counted, so retries and repeated deliveries of
+	// one incident count once
+	incidents map[string]struct{}
+	count     int
+	last      time.Time
+}
+
+// normalizedCause is what root causes are compared by: their category and the words of their first
+// sentence, which states the cause; the rest of the paragraph is evidence that varies between runs.
+type normalizedCause struct {
+	category string
+	words    map[string]struct{}
+}
+
+// newRecurrenceTracker returns nil when output.recurring is disabled.
+func newRecurrenceTracker(cfg config.RecurringConfig) *recurrenceTracker {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &recurrenceTracker{cfg: cfg, streaks: make(map[string]*causeStreak)}
+}
+
+// observe records the analysis of an incident of service and returns how many distinct incidents in a
+// row, this one included, named the same root cause, and whether that reaches the muting threshold.
+// An incident already counted, identified by incidentKey, does not extend the streak again.
+func (t *recurrenceTracker) observe(service, incidentKey, rootCause string, now time.Time) (int, bool) {
+	cause := normalizeCause(rootCause)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.streaks[service]
+	if ok && incidentKey != "" {
+		if _, seen := s.incidents[incidentKey]; seen {
+			return s.count, s.count >= t.cfg.GetThreshold()
+		}
+	}
+	if !ok || now.Sub(s.last) > t.cfg.GetWindowDuration() || !s.cause.matches(cause, t.cfg.GetSimilarity()) {
+		s = &causeStreak{cause: cause, incidents: make(map[string]struct{})}
+		t.streaks[service] = s
+	}
+	if incidentKey != "" {
+		s.incidents[incidentKey] = struct{}{}
+	}
+	s.count++
+	s.last = now
+	return s.count, s.count >= t.cfg.GetThreshold()
+}
+
+// muting reports whether the next incident of service is muted should it repeat the streak's cause,
+// so its firing message can be held back before the analysis is known.
+func (t *recurrenceTracker) muting(service string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.streaks[service]
+	return ok && now.Sub(s.last) <= t.cfg.GetWindowDuration() && s.count+1 >= t.cfg.GetThreshold()
+}
+
+// normalizeCause classifies a root cause and lowercases its first sentence into a set of words, with
+// numbers normalized so pool sizes, durations, and counts that differ between occurrences do not make
+// the same cause look new.
+func normalizeCause(rootCause string) normalizedCause {
+	first := strings.TrimSpace(rootCause)
+	if loc := sentenceEnd.FindStringIndex(first); loc != nil {
+		first = first[:loc[0]]
+	}
+	words := make(map[string]struct{})
+	for _, w := range causeWord.FindAllString(strings.ToLower(first), -1) {
+		words[digits.ReplaceAllString(w, "N")] = struct{}{}
+	}
+	return normalizedCause{category: output.ClassifyRootCause(rootCause), words: words}
+}
+
+// matches reports whether two causes are the same: the same category, with first sentences whose
+// words overlap by at least similarity.
+func (c normalizedCause) matches(other normalizedCause, similarity float64) bool {
+	return c.category == other.category && wordOverlap(c.words, other.words) >= similarity
+}
+
+// wordOverlap is the Jaccard similarity of two word sets; two empty sets are identical.
+func wordOverlap(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for w := range a {
+		if _, ok := b[w]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
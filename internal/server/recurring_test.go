@@ -0,0 +1,152 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+	"helixops/internal/output"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatedRootCauseSendsOneRecurringNotification(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []string
+	)
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		messages = append(messages, string(body))
+		mu.Unlock()
+	}))
+	defer slack.Close()
+
+	cfg := &config.Config{Output: config.OutputConfig{Recurring: config.RecurringConfig{Enabled: true, Threshold: 3}}}
+	provider := llm.NewFakeProvider(
+		"# Incident Analysis: pool\n## 3. Root Cause Analysis\nThe checkout database connection pool was exhausted after 30s of load.\n",
+		"# Incident Analysis: pool\n## 3. Root Cause Analysis\nThe checkout database connection pool was exhausted after 45s of load.\n",
+		"# Incident Analysis: pool\n## 3. Root Cause Analysis\nThe checkout database connection pool was exhausted after 12s of load.\n",
+	)
+	h, _ := analysisHandler(t, cfg, provider)
+	h.slackSender = output.NewSlackSender(slack.URL)
+
+	started := time.Now().Add(-time.Hour)
+	for i, fp := range []string{"fp-1", "fp-2", "fp-3"} {
+		alert := firingAlert()
+		alert.StartsAt = started.Add(time.Duration(i) * 10 * time.Minute)
+		alert.Fingerprint = fp
+		h.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{alert}})
+	}
+
+	require.Equal(t, 3, provider.CallCount())
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, messages, 3)
+	var recurring []int
+	for i, m := range messages {
+		if strings.Contains(m, "Recurring: same cause") {
+			recurring = append(recurring, i)
+		}
+	}
+	assert.Equal(t, []int{2}, recurring, "the first two analyses are sent in full, the third is muted")
+	assert.Contains(t, messages[2], "Recurring: same cause, 3rd time")
+	assert.Contains(t, messages[2], "connection pool was exhausted")
+}
+
+func TestRecurrenceStreakResetsOnNewCauseOrAfterWindow(t *testing.T) {
+	tracker := newRecurrenceTracker(config.RecurringConfig{Enabled: true, Threshold: 2, Window: "1h"})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	n, muted := tracker.observe("checkout", "inc-1", "Connection pool exhausted after 30s", now)
+	assert.Equal(t, 1, n)
+	assert.False(t, muted)
+	assert.True(t, tracker.muting("checkout", now), "the next repeat would be muted")
+	n, muted = tracker.observe("checkout", "inc-2", "Connection pool exhausted after 45s", now.Add(time.Minute))
+	assert.Equal(t, 2, n)
+	assert.True(t, muted)
+
+	n, _ = tracker.observe("payments", "inc-3", "Connection pool exhausted after 45s", now.Add(time.Minute))
+	assert.Equal(t, 1, n, "streaks are per service")
+	n, _ = tracker.observe("checkout", "inc-4", "Bad feature flag rollout broke the cart page", now.Add(2*time.Minute))
+	assert.Equal(t, 1, n, "a different cause starts a new streak")
+	n, _ = tracker.observe("checkout", "inc-5", "Bad feature flag rollout broke the cart page", now.Add(3*time.Hour))
+	assert.Equal(t, 1, n, "a repeat after the window starts a new streak")
+	assert.False(t, tracker.muting("cart", now))
+
+	assert.Nil(t, newRecurrenceTracker(config.RecurringConfig{}))
+}
+
+func TestRecurrenceCountsDistinctIncidents(t *testing.T) {
+	tracker := newRecurrenceTracker(config.RecurringConfig{Enabled: true, Threshold: 2})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker.observe("checkout", "fp-1@12:00", "Connection pool exhausted", now)
+	n, muted := tracker.observe("checkout", "fp-1@12:00", "Connection pool exhausted", now.Add(time.Minute))
+	assert.Equal(t, 1, n, "a retry or repeated delivery of one incident counts once")
+	assert.False(t, muted)
+}
+
+func TestRecurrenceComparesTheStatedCause(t *testing.T) {
+	tracker := newRecurrenceTracker(config.RecurringConfig{Enabled: true, Threshold: 2})
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker.observe("checkout", "inc-1", "The checkout connection pool was exhausted. Latency rose from 120ms to 2.4s while "+
+		"errors stayed flat, and the orders table showed 40 waiting queries.", now)
+	n, _ := tracker.observe("checkout", "inc-2", "The checkout connection pool was exhausted. A traffic spike from a "+
+		"marketing campaign doubled checkout RPS within five minutes of the alert.", now.Add(time.Minute))
+	assert.Equal(t, 2, n, "differing evidence after the first sentence does not make the cause new")
+
+	n, _ = tracker.observe("checkout", "inc-3", "Commit abc123 removed the orders index. The checkout connection pool "+
+		"was exhausted. Latency rose from 120ms to 2.4s while errors stayed flat.", now.Add(2*time.Minute))
+	assert.Equal(t, 1, n, "a different stated cause starts a new streak despite shared evidence")
+}
+
+func TestMutedIncidentSendsNoFiringMessageOrWebhook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []string
+		webhooks int
+	)
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		messages = append(messages, string(body))
+		mu.Unlock()
+		w.Write([]byte(`{"ok": true, "ts": "1700000000.000100"}`))
+	}))
+	defer slackAPI.Close()
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		webhooks++
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	provider := llm.NewFakeProvider("# Incident Analysis: pool\n## 3. Root Cause Analysis\nThe checkout connection pool was exhausted.\n")
+	h, _ := retryTestHandler(t, provider)
+	h.cfg.Output.Recurring = config.RecurringConfig{Enabled: true, Threshold: 2}
+	h.recurrence = newRecurrenceTracker(h.cfg.Output.Recurring)
+	h.slackSender = slackBot(slackAPI.URL)
+	h.webhook = output.NewWebhookSender(config.WebhookOutputConfig{Enabled: true, URL: webhook.URL})
+
+	first, second := firingAlert(), firingAlert()
+	second.Fingerprint = "fp-second"
+	h.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{first}})
+	h.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{second}})
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, messages, 3, "firing and analysis for the first incident, only the notice for the second")
+	assert.Contains(t, messages[2], "Recurring: same cause, 2nd time")
+	assert.Equal(t, 1, webhooks, "the muted analysis is not sent to the webhook")
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"helixops/internal/models"
+	"helixops/internal/remediation"
+)
+
+// remediationsRequest is the body accepted by HandleRemediations.
+type remediationsRequest struct {
+	AlertName string            `json:"alert_name"` // defaults to the alertname label
+	Labels    map[string]string `json:"labels"`
+}
+
+// HandleRemediations returns the rule-based remediation suggestions for an alert without gathering
+// context or calling the LLM, so integrations get a fast, deterministic answer.
+func (h *Handler) HandleRemediations(w http.ResponseWriter, r *http.Request) {
+	var req remediationsRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<16))
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	alert := models.AlertInfo{Name: req.AlertName, Labels: req.Labels}
+	if alert.Name == "" {
+		alert.Name = req.Labels["alertname"]
+	}
+	if alert.Name == "" {
+		http.Error(w, "alert_name or an alertname label is required", http.StatusBadRequest)
+		return
+	}
+
+	suggestions := h.rules.GetSuggestions(alert)
+	if suggestions == nil {
+		suggestions = []remediation.Suggestion{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(suggestions)
+}
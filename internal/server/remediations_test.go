@@ -0,0 +1,42 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"helixops/internal/config"
+	"helixops/internal/remediation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRemediationsReturnsRuleSuggestions(t *testing.T) {
+	cfg := &config.Config{Services: map[string]config.ServiceConfig{"checkout": {Deployment: "checkout-api", Namespace: "shop"}}}
+	router := SetupRouter(NewHandler(cfg, nil, nil, nil, nil, nil, nil))
+
+	body := []byte(`{"labels": {"alertname": "HighLatency", "service_name": "checkout"}}`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/remediations", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var suggestions []remediation.Suggestion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestions))
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "Check Database Query Performance", suggestions[0].Title)
+	assert.Equal(t, "Scale Up Service Replicas", suggestions[1].Title)
+	assert.Equal(t, "kubectl -n shop scale deployment/checkout-api --replicas=3", suggestions[1].Action)
+	assert.True(t, suggestions[1].RequiresConfirmation)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/remediations", bytes.NewReader([]byte(`{"alert_name": "DiskFull"}`))))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/remediations", bytes.NewReader([]byte(`{"labels": {}}`))))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
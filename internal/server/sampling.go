@@ -0,0 +1,87 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/models"
+)
+
+// postmortemSampler applies postmortem.policy to resolved incidents. It keeps the times of recent
+// non-critical postmortems for the hourly cap.
+type postmortemSampler struct {
+	policy config.PostmortemPolicy
+
+	mu     sync.Mutex
+	recent []time.Time
+}
+
+// allow reports whether a resolved incident gets a full postmortem, and otherwise why not. Critical
+// incidents always qualify and do not count toward the hourly cap.
+func (s *postmortemSampler) allow(severity string, duration time.Duration, now time.Time) (bool, string) {
+	if severity == config.SeverityCritical {
+		return true, ""
+	}
+	p := s.policy
+	if len(p.Severities) > 0 && !slices.ContainsFunc(p.Severities, func(v string) bool { return strings.EqualFold(v, severity) }) {
+		return false, fmt.Sprintf("severity %q is not in postmortem.policy.severities", severity)
+	}
+	if minimum := p.GetMinDurationDuration(); duration < minimum {
+		return false, fmt.Sprintf("lasted %s, less than postmortem.policy.min_duration %s", duration.Round(time.Second), minimum)
+	}
+	if p.MaxPerHour <= 0 {
+		return true, ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recent = slices.DeleteFunc(s.recent, func(t time.Time) bool { return now.Sub(t) >= time.Hour })
+	if len(s.recent) >= p.MaxPerHour {
+		return false, fmt.Sprintf("postmortem.policy.max_per_hour (%d) reached", p.MaxPerHour)
+	}
+	s.recent = append(s.recent, now)
+	return true, ""
+}
+
+// alertDuration is how long a resolved alert fired; alerts without an end time are measured to now.
+func alertDuration(alert models.AlertItem, now time.Time) time.Duration {
+	end := alert.EndsAt
+	if end.IsZero() {
+		end = now
+	}
+	return end.Sub(alert.StartsAt)
+}
+
+// resolveWithoutPostmortem resolves the alert's open incident with a stored record only, noting why
+// no postmortem was written.
+func (h *Handler) resolveWithoutPostmortem(alert models.AlertItem, serviceName, reason string) {
+	slog.Info("Skipping postmortem", "alert", alert.Labels["alertname"], "service", serviceName, "reason", reason)
+	if h.incidents == nil {
+		return
+	}
+
+	incident, err := h.incidents.FindOpenIncident(alert.GetFingerprint())
+	if err != nil {
+		slog.Error("Failed to look up open incident", "error", err)
+		return
+	}
+	if incident == nil {
+		slog.Warn("No open incident found for resolved alert", "alert", alert.Labels["alertname"], "fingerprint", alert.GetFingerprint())
+		return
+	}
+
+	resolvedAt := alert.EndsAt
+	if resolvedAt.IsZero() {
+		resolvedAt = time.Now().UTC()
+	}
+	if err := h.incidents.ResolveIncidentAt(incident.ID, resolvedAt, "No postmortem generated: "+reason, ""); err != nil {
+		slog.Error("Failed to resolve incident in database", "error", err)
+		return
+	}
+	slog.Info("Resolved incident in database", "incident_id", incident.ID)
+}
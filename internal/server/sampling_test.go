@@ -0,0 +1,84 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+	"helixops/internal/db"
+	"helixops/internal/models"
+	"helixops/internal/postmortem"
+	"helixops/internal/remediation"
+	"helixops/pkg/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostmortemPolicySkipsShortLowSeverityIncidents(t *testing.T) {
+	now := time.Now().UTC()
+	cfg := &config.Config{Postmortem: config.PostmortemConfig{Policy: config.PostmortemPolicy{
+		Severities:  []string{"warning"},
+		MinDuration: "10m",
+	}}}
+	provider := llm.NewFakeProvider("# Incident Analysis: test\n**Confidence Score:** 70%")
+	handler, database := analysisHandler(t, cfg, provider)
+	handler.generator = postmortem.NewGenerator(provider, remediation.NewEngine())
+
+	alert := func(severity, fingerprint string, lasted time.Duration) models.AlertItem {
+		a := firingAlert()
+		a.Labels["severity"] = severity
+		a.StartsAt = now.Add(-lasted)
+		a.Fingerprint = fingerprint
+		return a
+	}
+	blip, outage := alert("warning", "fp-blip", 2*time.Minute), alert("critical", "fp-outage", 2*time.Hour)
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{blip, outage}})
+	blipIncident, err := database.FindOpenIncident("fp-blip")
+	require.NoError(t, err)
+	outageIncident, err := database.FindOpenIncident("fp-outage")
+	require.NoError(t, err)
+	firingCalls := provider.CallCount()
+
+	for _, a := range []*models.AlertItem{&blip, &outage} {
+		a.Status, a.EndsAt = "resolved", now
+	}
+	handler.processAlerts(models.AlertManagerPayload{Alerts: []models.AlertItem{blip, outage}})
+
+	assert.Equal(t, firingCalls+1, provider.CallCount(), "only the critical incident gets an LLM postmortem")
+
+	recorded, err := database.GetIncident(blipIncident.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, recorded.Status)
+	require.NotNil(t, recorded.RootCause)
+	assert.Contains(t, *recorded.RootCause, "min_duration")
+	assert.True(t, recorded.AISummary == nil || *recorded.AISummary == "")
+
+	full, err := database.GetIncident(outageIncident.ID)
+	require.NoError(t, err)
+	assert.Equal(t, db.IncidentStatusResolved, full.Status)
+	require.NotNil(t, full.AISummary)
+	assert.NotEmpty(t, *full.AISummary)
+}
+
+func TestPostmortemSamplerRateLimit(t *testing.T) {
+	s := &postmortemSampler{policy: config.PostmortemPolicy{MaxPerHour: 2}}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		ok, _ := s.allow("warning", time.Hour, now)
+		assert.True(t, ok)
+	}
+	ok, reason := s.allow("warning", time.Hour, now)
+	assert.False(t, ok)
+	assert.Contains(t, reason, "max_per_hour")
+
+	ok, _ = s.allow("critical", time.Minute, now)
+	assert.True(t, ok, "critical incidents always qualify")
+	ok, _ = s.allow("warning", time.Hour, now.Add(time.Hour))
+	assert.True(t, ok, "the cap is a rolling hour")
+
+	ok, reason = (&postmortemSampler{policy: config.PostmortemPolicy{Severities: []string{"warning"}}}).allow("info", time.Hour, now)
+	assert.False(t, ok)
+	assert.Contains(t, reason, "severities")
+}
@@ -4,19 +4,21 @@ package server
 import (
 	"context"
 	"fmt"
-	"log"
 	"log/slog"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"helixops/internal/analyzer"
+	"helixops/internal/capture"
+	"helixops/internal/clients/alertmanager"
 	"helixops/internal/clients/github"
 	"helixops/internal/clients/loki"
 	"helixops/internal/clients/prometheus"
 	"helixops/internal/clients/tempo"
 	"helixops/internal/config"
 	"helixops/internal/db"
+	"helixops/internal/logging"
 	"helixops/internal/orchestrator"
 	"helixops/internal/output"
 	"helixops/internal/postmortem"
@@ -29,79 +31,126 @@ type Server struct {
 	cfg     *config.Config
 	srv     *http.Server
 	handler *Handler
+	janitor *janitor
+	poller  *alertPoller
+	digest  *digest
+
+	// ctx scopes background work (the retention janitor, alert poller, and digest); cancel stops it on
+	// shutdown. Both are set in New, so Shutdown may run concurrently with Start.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // New initializes a complete Server instance, bootstrapping all clients and handlers.
 func New(cfg *config.Config) (*Server, error) {
+	// Install the process-wide logger before anything else logs
+	if _, err := logging.Setup(cfg.App); err != nil {
+		return nil, fmt.Errorf("failed to configure logging: %w", err)
+	}
+
 	// Initialize clients
-	promClient := prometheus.NewClient(cfg.Prometheus.URL, cfg.Prometheus.GetTimeoutDuration())
-	githubClient := github.NewClient(cfg.GitHub.APIURL, cfg.GitHub.Token)
-	lokiClient := loki.NewClient(cfg.Loki.URL, cfg.Loki.GetTimeoutDuration())
+	promClient, err := prometheus.New(cfg.Prometheus.URL, cfg.Prometheus.GetTimeoutDuration())
+	if err != nil {
+		return nil, err
+	}
+	promClient.UseStatusBreakdownQuery(cfg.Prometheus.StatusBreakdownQuery)
+	promClient.UseHeaders(cfg.Prometheus.Headers)
+	githubClient, err := github.New(cfg.GitHub.APIURL, cfg.GitHub.Token)
+	if err != nil {
+		return nil, err
+	}
+	githubClient.AllowRepos(cfg.GitHub.AllowedRepos)
+	lokiClient, err := loki.New(cfg.Loki.URL, cfg.Loki.GetTimeoutDuration())
+	if err != nil {
+		return nil, err
+	}
+	lokiClient.UseHeaders(cfg.Loki.Headers)
+	lokiClient.UseConcurrencyLimit(cfg.Loki.Concurrency.GetMaxQueries(), cfg.Loki.Concurrency.GetQueueTimeoutDuration())
+	lokiClient.UseQuerySplitting(cfg.Loki.GetMaxQueryWindowDuration())
+	lokiClient.UseRetries(cfg.Loki.GetRetries(), cfg.Loki.GetRetryBackoffDuration())
 
 	// Optional Tempo client
 	var tempoClient *tempo.Client
 	if cfg.Tempo.Enabled {
 		logger := slog.Default() // basic logger
-		tempoClient = tempo.NewClient(cfg.Tempo.URL, cfg.Prometheus.GetTimeoutDuration(), logger)
+		tempoClient, err = tempo.New(cfg.Tempo.URL, cfg.Prometheus.GetTimeoutDuration(), logger)
+		if err != nil {
+			return nil, err
+		}
+		tempoClient.UseHeaders(cfg.Tempo.Headers)
+		tempoClient.UseConcurrencyLimit(cfg.Tempo.Concurrency.GetMaxQueries(), cfg.Tempo.Concurrency.GetQueueTimeoutDuration())
 	}
 
-	// Initialize LLM provider
-	llmProvider, err := llm.NewProvider(cfg.LLM)
-	if err != nil {
-		log.Fatalf("Failed to create LLM provider: %v", err)
+	// Initialize database if enabled
+	var database db.Store
+	if cfg.Database.Enabled {
+		driver, dsn := cfg.Database.Connection()
+		conn, err := db.Open(driver, dsn)
+		if err != nil {
+			slog.Warn("Failed to initialize database", "driver", driver, "error", err)
+		} else {
+			if err := conn.Migrate(); err != nil {
+				slog.Warn("Database migration failed", "driver", driver, "error", err)
+			} else {
+				slog.Info("Database connected", "driver", driver, "host", cfg.Database.Host, "port", cfg.Database.Port, "dbname", cfg.Database.DBName)
+			}
+			database = conn
+		}
 	}
 
-	// Initialize orchestrator
-	orch := orchestrator.New(promClient, githubClient, lokiClient, tempoClient, cfg)
-
-	// Initialize analyzer
-	anlz := analyzer.New(llmProvider)
+	deps := backends{
+		prom:     promClient,
+		github:   githubClient,
+		loki:     lokiClient,
+		tempo:    tempoClient,
+		database: database,
+	}
 
-	// Initialize Remediation Engine and Postmortem Generator
-	rulesEngine := remediation.NewEngine()
-	generator := postmortem.NewGenerator(llmProvider, rulesEngine)
-	mdReporter, err := output.NewMarkdownReporterFromConfig(cfg.Output.Markdown)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize markdown reporter: %w", err)
+	// Without a database, keep a bounded incident registry in memory so incident state and the
+	// postmortem endpoints still work in ephemeral deployments
+	if database == nil && cfg.Database.MemoryRegistryEnabled() {
+		deps.incidents = db.NewIncidentRegistry(cfg.Database.MemoryIncidents)
+		slog.Info("Database not available; keeping incidents in memory until restart")
 	}
 
-	// Initialize database if enabled
-	var database *db.DB
-	if cfg.Database.Enabled {
-		// Get password from environment if set
-		password := os.Getenv("HELIX_DB_PASSWORD")
-		if password == "" && cfg.Database.Password != "" {
-			password = cfg.Database.Password
+	// Optional discovery of repositories for services without a configured mapping
+	if cfg.GitHub.Discovery.Enabled && githubClient != nil {
+		deps.repos = &repoDiscovery{
+			database:   database,
+			discoverer: github.NewDiscoverer(githubClient, cfg.GitHub.DefaultOrg, cfg.GitHub.Discovery.GetCacheTTLDuration()),
 		}
+	}
 
-		var err error
-		database, err = db.New(
-			cfg.Database.Host,
-			cfg.Database.Port,
-			cfg.Database.User,
-			password,
-			cfg.Database.DBName,
-			cfg.Database.SSLMode,
-		)
-		if err != nil {
-			log.Printf("Warning: Failed to initialize database: %v", err)
-		} else {
-			if err := database.Migrate(); err != nil {
-				log.Printf("Warning: Database migration failed: %v", err)
-			} else {
-				log.Printf("Database connected to %s:%d/%s", cfg.Database.Host, cfg.Database.Port, cfg.Database.DBName)
-			}
+	// Optional root-cause category counters, shared by every receiver and served on /metrics
+	if cfg.Output.Metrics.Enabled {
+		deps.rootCauses = output.NewRootCauseMetrics(cfg.Output.Metrics)
+	}
+
+	// Optional capture of raw webhook bodies, shared by every receiver
+	if cfg.Alerting.Capture.Enabled {
+		if deps.captures, err = capture.New(cfg.Alerting.Capture.GetDir(), cfg.Alerting.Capture.GetMaxPayloads()); err != nil {
+			return nil, err
 		}
+		slog.Info("Capturing webhook payloads", "dir", cfg.Alerting.Capture.GetDir(), "max_payloads", cfg.Alerting.Capture.GetMaxPayloads())
 	}
 
-	// Initialize Slack sender if enabled
-	var slackSender *output.SlackSender
-	if cfg.Output.Slack.Enabled && cfg.Output.Slack.WebhookURL != "" {
-		slackSender = output.NewSlackSender(cfg.Output.Slack.WebhookURL)
+	// Create the default handler served at /webhook
+	handler, err := newProfileHandler(cfg, deps)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create handler
-	handler := NewHandler(cfg, orch, anlz, generator, mdReporter, slackSender, database)
+	// Named receiver profiles served at /webhook/{name}
+	for name := range cfg.Receivers {
+		rcfg, _ := cfg.ForReceiver(name)
+		rh, err := newProfileHandler(rcfg, deps)
+		if err != nil {
+			return nil, fmt.Errorf("receiver %q: %w", name, err)
+		}
+		rh.receiver = name
+		handler.receivers[name] = rh
+		slog.Info("Registered webhook receiver", "receiver", name, "path", "/webhook/"+name, "llm_provider", rcfg.LLM.Provider)
+	}
 
 	// Create router
 	router := SetupRouter(handler)
@@ -115,29 +164,198 @@ func New(cfg *config.Config) (*Server, error) {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Retention janitor for incidents and reports
+	var jan *janitor
+	if database != nil || handler.mdReporter != nil {
+		jan = &janitor{
+			database:  database,
+			reports:   handler.mdReporter,
+			retention: cfg.Database.GetRetentionDuration(),
+			interval:  cfg.Database.GetCleanupIntervalDuration(),
+			now:       time.Now,
+		}
+	}
+
+	// Optional polling of Prometheus alerts for setups without Alertmanager webhooks
+	var poller *alertPoller
+	if cfg.Alerting.Poll.Enabled {
+		poller = newAlertPoller(promClient, handler, cfg.Alerting.Poll.GetIntervalDuration())
+	}
+
+	// Optional periodic incident digest posted to Slack
+	var dig *digest
+	if cfg.Output.Digest.Enabled {
+		dig = newDigest(cfg, database)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
 		cfg:     cfg,
 		srv:     srv,
 		handler: handler,
+		janitor: jan,
+		poller:  poller,
+		digest:  dig,
+		ctx:     ctx,
+		cancel:  cancel,
 	}, nil
 }
 
+// backends are the telemetry clients and storage shared by every webhook profile.
+type backends struct {
+	prom     *prometheus.Client
+	github   *github.Client
+	loki     *loki.Client
+	tempo    *tempo.Client
+	database db.Store
+	// incidents stands in for the database's incident table when no database is available
+	incidents *db.IncidentRegistry
+	repos     *repoDiscovery // nil unless github.discovery is enabled
+	// rootCauses counts analyses by root-cause category; nil unless output.metrics is enabled
+	rootCauses *output.RootCauseMetrics
+	// captures keeps raw webhook bodies; nil unless alerting.capture is enabled
+	captures *capture.Store
+}
+
+// newAnalysisPipeline builds the LLM provider, orchestrator, and analyzer for one config profile.
+func newAnalysisPipeline(cfg *config.Config, deps backends) (analysisPipeline, llm.Provider, error) {
+	// Initialize LLM provider
+	llmProvider, err := llm.NewProvider(cfg.LLM)
+	if err != nil {
+		return analysisPipeline{}, nil, fmt.Errorf("failed to create LLM provider: %w", err)
+	}
+
+	// Initialize orchestrator
+	orch := orchestrator.New(deps.prom, deps.github, deps.loki, deps.tempo, cfg)
+	if deps.repos != nil {
+		orch.UseRepoResolver(deps.repos)
+	}
+	if deps.database != nil {
+		orch.UseIncidentHistory(deps.database)
+		orch.UseCommitCache(deps.database)
+		orch.UseDeployEvents(deps.database)
+	}
+
+	// Initialize analyzer
+	anlz := analyzer.New(llmProvider, cfg.Analysis)
+	return analysisPipeline{orchestrator: orch, analyzer: anlz}, llmProvider, nil
+}
+
+// newProfileHandler builds the analysis pipeline (LLM, orchestrator, outputs) for one config profile.
+func newProfileHandler(cfg *config.Config, deps backends) (*Handler, error) {
+	pipeline, llmProvider, err := newAnalysisPipeline(cfg, deps)
+	if err != nil {
+		return nil, err
+	}
+	orch, anlz := pipeline.orchestrator, pipeline.analyzer
+
+	// Initialize Remediation Engine and Postmortem Generator
+	rulesEngine := remediation.NewEngineFromConfig(cfg.Services, cfg.Analysis.GetPlatform())
+	postmortemProvider := llmProvider
+	if pmCfg := cfg.LLM.ForPostmortem(); pmCfg.MaxTokens != cfg.LLM.MaxTokens {
+		if postmortemProvider, err = llm.NewProvider(pmCfg); err != nil {
+			return nil, fmt.Errorf("failed to create postmortem LLM provider: %w", err)
+		}
+	}
+	generator, err := postmortem.NewGeneratorFromConfig(postmortemProvider, rulesEngine, cfg.Postmortem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize postmortem generator: %w", err)
+	}
+	generator.UseLanguage(cfg.Analysis.GetLanguage())
+	mdReporter, err := output.NewMarkdownReporterFromConfig(cfg.Output.Markdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize markdown reporter: %w", err)
+	}
+
+	// Initialize Slack sender if enabled
+	var slackSender *output.SlackSender
+	if cfg.Output.Slack.Enabled && (cfg.Output.Slack.WebhookURL != "" || cfg.Output.Slack.BotToken != "") {
+		slackSender = output.NewSlackSenderFromConfig(cfg.Output.Slack)
+	}
+
+	// Create handler
+	handler := NewHandler(cfg, orch, anlz, generator, mdReporter, slackSender, deps.database)
+	if deps.database == nil && deps.incidents != nil {
+		handler.incidents = deps.incidents
+		handler.notifications = deps.incidents
+	}
+
+	// Alerts of severities mapped to an analysis profile are analyzed with that profile's pipeline
+	built := make(map[string]analysisPipeline)
+	for severity, name := range cfg.Analysis.SeverityProfiles {
+		p, ok := built[name]
+		if !ok {
+			pcfg, _ := cfg.ForAnalysisProfile(name)
+			if p, _, err = newAnalysisPipeline(pcfg, deps); err != nil {
+				return nil, fmt.Errorf("analysis profile %q: %w", name, err)
+			}
+			p.profile = name
+			built[name] = p
+		}
+		handler.severityPipelines[strings.ToLower(severity)] = p
+		slog.Info("Using analysis profile for severity", "severity", severity, "profile", name)
+	}
+
+	handler.rootCauses = deps.rootCauses
+	handler.captures = deps.captures
+
+	// Optional GitHub issue creation for postmortem remediations
+	if cfg.GitHub.CreateIssues {
+		handler.issues = deps.github
+	}
+
+	// Optional signed JSON webhook for analyses and postmortems
+	if cfg.Output.Webhook.Enabled {
+		handler.webhook = output.NewWebhookSender(cfg.Output.Webhook)
+	}
+
+	// Optional Alertmanager client so silenced alerts are not analyzed
+	if cfg.Alerting.Alertmanager.URL != "" {
+		silences, err := alertmanager.New(cfg.Alerting.Alertmanager.URL, cfg.Alerting.Alertmanager.GetTimeoutDuration())
+		if err != nil {
+			return nil, err
+		}
+		handler.silences = silences
+	}
+
+	return handler, nil
+}
+
 // Start begins listening for incoming HTTP requests in a blocking manner on the configured port.
 func (s *Server) Start() error {
-	log.Printf("Server listening on %s", s.srv.Addr)
+	if s.janitor != nil {
+		go s.janitor.Run(s.ctx)
+	}
+	if s.poller != nil {
+		go s.poller.Run(s.ctx)
+	}
+	if s.digest != nil {
+		go s.digest.Run(s.ctx)
+	}
+	s.handler.resumeQueue()
+
+	slog.Info("Server listening", "addr", s.srv.Addr)
 	return s.srv.ListenAndServe()
 }
 
-// Shutdown initiates a graceful termination of the HTTP server, ensuring all active connections finish before exiting.
-func (s *Server) Shutdown() {
-	log.Println("Shutting down server...")
+// Shutdown stops accepting requests, then waits for in-flight alert analyses to finish so
+// incidents and reports are not lost mid-write. It returns ctx's error if the deadline passes
+// first; the caller decides how to exit.
+func (s *Server) Shutdown(ctx context.Context) error {
+	slog.Info("Shutting down server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	if s.cancel != nil {
+		s.cancel()
+	}
 
 	if err := s.srv.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+		return fmt.Errorf("http shutdown: %w", err)
+	}
+
+	if err := s.handler.wait(ctx); err != nil {
+		return fmt.Errorf("waiting for in-flight analyses: %w", err)
 	}
 
-	os.Exit(0)
+	slog.Info("Server stopped")
+	return nil
 }
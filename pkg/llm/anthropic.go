@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"helixops/internal/config"
+	"helixops/internal/httpx"
 )
 
 // AnthropicProvider implements the Provider interface for interacting with the Anthropic Messages API.
@@ -72,14 +73,16 @@ func NewAnthropicProvider(apiKey, model string, temperature float64, maxTokens i
 	if model == "" {
 		model = "claude-3-5-sonnet-20241022"
 	}
+	maxTokens, err := resolveMaxTokens(ProviderAnthropic, model, maxTokens)
+	if err != nil {
+		return nil, err
+	}
 
 	return &AnthropicProvider{
 		client: &AnthropicClient{
 			apiKey:  apiKey,
 			baseURL: "https://api.anthropic.com/v1",
-			client: &http.Client{
-				Timeout: 60 * time.Second,
-			},
+			client:  httpx.NewClient(60 * time.Second),
 		},
 		model:       model,
 		temperature: temperature,
@@ -131,11 +134,34 @@ func (p *AnthropicProvider) Analyze(ctx context.Context, prompt string) (string,
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(anthropicResp.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
+	var text string
+	if len(anthropicResp.Content) > 0 {
+		text = anthropicResp.Content[0].Text
 	}
+	// "max_tokens" means the reply was cut off; "refusal" means it was withheld
+	if err := checkReply(p.Name(), text, anthropicResp.StopReason, "max_tokens", "refusal"); err != nil {
+		return "", err
+	}
+
+	model := anthropicResp.Model
+	if model == "" {
+		model = p.model
+	}
+	recordCompletion(ctx, Completion{
+		Model:    model,
+		Response: text,
+		Usage: Usage{
+			PromptTokens:     anthropicResp.Usage.InputTokens,
+			CompletionTokens: anthropicResp.Usage.OutputTokens,
+			TotalTokens:      anthropicResp.Usage.InputTokens + anthropicResp.Usage.OutputTokens,
+		},
+	})
+	return text, nil
+}
 
-	return anthropicResp.Content[0].Text, nil
+// Capabilities reports what the configured model supports, from the capability registry.
+func (p *AnthropicProvider) Capabilities() Capabilities {
+	return LookupCapabilities(ProviderAnthropic, p.model)
 }
 
 // Name identifies this provider instance as "anthropic".
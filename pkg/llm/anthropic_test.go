@@ -87,6 +87,44 @@ func TestAnthropicProviderNoContent(t *testing.T) {
 	assert.Contains(t, err.Error(), "no content")
 }
 
+func TestAnthropicProviderRejectsEmptyOrCutOffReplies(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		stopReason string
+		want       error
+	}{
+		{"empty text", "", "end_turn", ErrEmptyResponse},
+		{"whitespace text", "  \n", "end_turn", ErrEmptyResponse},
+		{"cut off at max_tokens", "# Incident Analysis: Pool exh", "max_tokens", &IncompleteResponseError{Provider: "anthropic", Reason: "max_tokens"}},
+		{"refused", "", "refusal", &IncompleteResponseError{Provider: "anthropic", Reason: "refusal"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(AnthropicResponse{
+					Content:    []AnthropicContent{{Type: "text", Text: tt.text}},
+					StopReason: tt.stopReason,
+				})
+			}))
+			defer server.Close()
+
+			provider, err := NewAnthropicProvider("test-key", "claude-3-5-sonnet", 0.1, 1000)
+			require.NoError(t, err)
+			provider.client.baseURL = server.URL
+
+			_, err = provider.Analyze(context.Background(), "Test prompt")
+			if incomplete, ok := tt.want.(*IncompleteResponseError); ok {
+				var got *IncompleteResponseError
+				require.ErrorAs(t, err, &got)
+				assert.Equal(t, incomplete, got)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
 func TestAnthropicProviderName(t *testing.T) {
 	provider, err := NewAnthropicProvider("test-key", "claude-3-5-sonnet", 0.1, 1000)
 	require.NoError(t, err)
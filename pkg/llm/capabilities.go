@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Capabilities describes what a provider's model supports, so features degrade per model rather than
+// assuming every backend behaves like OpenAI.
+type Capabilities struct {
+	// JSONMode means the model can be constrained to reply with a single JSON object
+	JSONMode        bool
+	FunctionCalling bool
+	Vision          bool
+	// MaxOutputTokens is the completion budget used when llm.max_tokens is unset; 0 leaves the API default
+	MaxOutputTokens int
+	// OutputTokenLimit is the most output tokens the model accepts; 0 when unknown, which skips the check
+	OutputTokenLimit int
+}
+
+// CapabilityReporter is implemented by providers that know what their configured model supports.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// JSONAnalyzer is implemented by providers that can constrain a response to a JSON object.
+type JSONAnalyzer interface {
+	AnalyzeJSON(ctx context.Context, prompt string) (string, error)
+}
+
+// capabilityRegistry is keyed by provider, then by model name prefix; the longest matching prefix wins
+// and the empty prefix is the provider's fallback for models not listed.
+var capabilityRegistry = map[ProviderType]map[string]Capabilities{
+	ProviderOpenAI: {
+		// unknown models are often OpenAI-compatible servers (vLLM, LocalAI) that may reject response_format
+		"":              {MaxOutputTokens: 4096},
+		"gpt-4":         {FunctionCalling: true, MaxOutputTokens: 4096, OutputTokenLimit: 8192},
+		"gpt-4-turbo":   {JSONMode: true, FunctionCalling: true, Vision: true, MaxOutputTokens: 4096, OutputTokenLimit: 4096},
+		"gpt-4o":        {JSONMode: true, FunctionCalling: true, Vision: true, MaxOutputTokens: 4096, OutputTokenLimit: 16384},
+		"gpt-4.1":       {JSONMode: true, FunctionCalling: true, Vision: true, MaxOutputTokens: 8192, OutputTokenLimit: 32768},
+		"gpt-3.5-turbo": {JSONMode: true, FunctionCalling: true, MaxOutputTokens: 4096, OutputTokenLimit: 4096},
+	},
+	ProviderAnthropic: {
+		// the Messages API requires max_tokens, so the fallback must be non-zero
+		"":                  {FunctionCalling: true, Vision: true, MaxOutputTokens: 4096},
+		"claude-2":          {MaxOutputTokens: 4096, OutputTokenLimit: 4096},
+		"claude-3":          {FunctionCalling: true, Vision: true, MaxOutputTokens: 4096, OutputTokenLimit: 4096},
+		"claude-3-5":        {FunctionCalling: true, Vision: true, MaxOutputTokens: 4096, OutputTokenLimit: 8192},
+		"claude-3-7-sonnet": {FunctionCalling: true, Vision: true, MaxOutputTokens: 8192, OutputTokenLimit: 64000},
+		"claude-sonnet-4":   {FunctionCalling: true, Vision: true, MaxOutputTokens: 8192, OutputTokenLimit: 64000},
+		"claude-opus-4":     {FunctionCalling: true, Vision: true, MaxOutputTokens: 8192, OutputTokenLimit: 32000},
+	},
+	ProviderOllama: {
+		"":      {JSONMode: true, MaxOutputTokens: 2048},
+		"llava": {JSONMode: true, Vision: true, MaxOutputTokens: 2048},
+	},
+}
+
+// LookupCapabilities returns the registered capabilities of model on provider. Unknown providers
+// report none, which keeps callers on the plain-text path.
+func LookupCapabilities(provider ProviderType, model string) Capabilities {
+	models := capabilityRegistry[provider]
+	model = strings.ToLower(model)
+	var best Capabilities
+	bestLen := -1
+	for prefix, caps := range models {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = caps, len(prefix)
+		}
+	}
+	return best
+}
+
+// resolveMaxTokens returns the completion budget for model: maxTokens when set, the model's registered
+// default otherwise. A budget above the model's known output limit is an error, since the API would
+// reject every request.
+func resolveMaxTokens(provider ProviderType, model string, maxTokens int) (int, error) {
+	caps := LookupCapabilities(provider, model)
+	if maxTokens == 0 {
+		return caps.MaxOutputTokens, nil
+	}
+	if caps.OutputTokenLimit > 0 && maxTokens > caps.OutputTokenLimit {
+		return 0, fmt.Errorf("max_tokens %d exceeds the %d output tokens %s supports", maxTokens, caps.OutputTokenLimit, model)
+	}
+	return maxTokens, nil
+}
+
+// CapabilitiesOf returns what p supports. Providers that do not report capabilities are treated as
+// plain-text only, and JSON mode is dropped when p cannot actually be asked for JSON.
+func CapabilitiesOf(p Provider) Capabilities {
+	r, ok := p.(CapabilityReporter)
+	if !ok {
+		return Capabilities{}
+	}
+	caps := r.Capabilities()
+	if _, ok := p.(JSONAnalyzer); !ok {
+		caps.JSONMode = false
+	}
+	return caps
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCapabilitiesLongestPrefixWins(t *testing.T) {
+	assert.True(t, LookupCapabilities(ProviderOpenAI, "gpt-4o-mini").JSONMode)
+	assert.False(t, LookupCapabilities(ProviderOpenAI, "gpt-4-0613").JSONMode, "plain gpt-4 predates JSON mode")
+	assert.False(t, LookupCapabilities(ProviderOpenAI, "mistral-7b-instruct").JSONMode, "unknown OpenAI-compatible models stay on text")
+	assert.Equal(t, 8192, LookupCapabilities(ProviderOpenAI, "gpt-4.1").MaxOutputTokens)
+	assert.False(t, LookupCapabilities(ProviderAnthropic, "claude-3-5-sonnet-20241022").JSONMode)
+	assert.True(t, LookupCapabilities(ProviderOllama, "llava:13b").Vision)
+	assert.Equal(t, Capabilities{}, LookupCapabilities("gemini", "gemini-pro"))
+}
+
+func TestNewProviderChoosesModelMaxTokens(t *testing.T) {
+	p, err := NewProvider(config.LLMConfig{Provider: "anthropic", APIKey: "key", Model: "claude-3-5-sonnet-20241022"})
+	require.NoError(t, err)
+	assert.Equal(t, 4096, p.(*AnthropicProvider).maxTokens, "an unset max_tokens uses the model default")
+
+	p, err = NewProvider(config.LLMConfig{Provider: "anthropic", APIKey: "key", Model: "claude-3-5-sonnet-20241022", PostmortemMaxTokens: 8192}.ForPostmortem())
+	require.NoError(t, err)
+	assert.Equal(t, 8192, p.(*AnthropicProvider).maxTokens)
+
+	_, err = NewProvider(config.LLMConfig{Provider: "openai", APIKey: "key", Model: "gpt-4-turbo", MaxTokens: 8000})
+	assert.ErrorContains(t, err, "exceeds the 4096 output tokens gpt-4-turbo supports")
+
+	p, err = NewProvider(config.LLMConfig{Provider: "openai", APIKey: "key", Model: "mistral-7b-instruct", MaxTokens: 32000})
+	require.NoError(t, err, "models without a known limit are not checked")
+	assert.Equal(t, 32000, p.(*OpenAIProvider).maxTokens)
+}
+
+func TestCapabilitiesOfSurvivesPromptLogging(t *testing.T) {
+	fake := NewFakeProvider("ok")
+	fake.Caps = Capabilities{JSONMode: true, MaxOutputTokens: 1024}
+	p := WithPromptLogging(fake, true, nil)
+
+	assert.Equal(t, fake.Caps, CapabilitiesOf(p))
+	_, err := p.(JSONAnalyzer).AnalyzeJSON(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.JSONCallCount())
+}
+
+func TestOpenAIAnalyzeJSONSetsResponseFormat(t *testing.T) {
+	var req OpenAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = OpenAIChatRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(OpenAIChatResponse{Choices: []Choice{{Message: Message{Content: `{"analysis":"ok"}`}}}})
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider(server.URL, "key", "gpt-4o", 0, 0)
+	require.NoError(t, err)
+	_, err = provider.AnalyzeJSON(context.Background(), "prompt")
+	require.NoError(t, err)
+	require.NotNil(t, req.ResponseFormat)
+	assert.Equal(t, "json_object", req.ResponseFormat.Type)
+	assert.Equal(t, 4096, req.MaxTokens, "an unset max_tokens falls back to the model's registered limit")
+
+	_, err = provider.Analyze(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Nil(t, req.ResponseFormat)
+}
+
+func TestOllamaSendsMaxTokensAsNumPredict(t *testing.T) {
+	var req OllamaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = OllamaRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(OllamaResponse{Response: "ok", Done: true})
+	}))
+	defer server.Close()
+
+	cfg := config.LLMConfig{Provider: "ollama", OllamaURL: server.URL, OllamaModel: "llama3", PostmortemMaxTokens: 6000}
+	for name, tc := range map[string]struct {
+		cfg  config.LLMConfig
+		want int
+	}{
+		"model default": {cfg, 2048},
+		"max_tokens":    {config.LLMConfig{Provider: "ollama", OllamaURL: server.URL, OllamaModel: "llama3", MaxTokens: 1500}, 1500},
+		"postmortem":    {cfg.ForPostmortem(), 6000},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, err := NewProvider(tc.cfg)
+			require.NoError(t, err)
+			_, err = p.Analyze(context.Background(), "prompt")
+			require.NoError(t, err)
+			require.NotNil(t, req.Options)
+			assert.Equal(t, tc.want, req.Options.NumPredict)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Completion is the model, token usage, and raw text of one LLM reply, captured for auditing.
+type Completion struct {
+	Model    string
+	Response string
+	Usage    Usage
+}
+
+// ErrEmptyResponse is returned when a provider answers with no text, which some do when a reply is
+// filtered or cut off. Callers treat it like any failed request, so the reply is retried, not stored.
+var ErrEmptyResponse = errors.New("no content in response")
+
+// IncompleteResponseError is returned when a provider reports it stopped before finishing the reply,
+// because it hit the token limit or a content filter.
+type IncompleteResponseError struct {
+	Provider string
+	// Reason is the provider's finish or stop reason, e.g. "length" or "max_tokens"
+	Reason string
+}
+
+func (e *IncompleteResponseError) Error() string {
+	return fmt.Sprintf("%s response incomplete (stop reason %q)", e.Provider, e.Reason)
+}
+
+// checkReply rejects a reply that is empty or whose stop reason is one of incomplete.
+func checkReply(provider, text, reason string, incomplete ...string) error {
+	for _, r := range incomplete {
+		if reason == r {
+			return &IncompleteResponseError{Provider: provider, Reason: reason}
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		if reason != "" {
+			return fmt.Errorf("%s: %w (stop reason %q)", provider, ErrEmptyResponse, reason)
+		}
+		return fmt.Errorf("%s: %w", provider, ErrEmptyResponse)
+	}
+	return nil
+}
+
+type completionKey struct{}
+
+// CaptureCompletion returns a context under which a provider records its reply into the returned
+// Completion. The Provider interface stays text-only; providers that cannot report usage leave it zero.
+func CaptureCompletion(ctx context.Context) (context.Context, *Completion) {
+	c := &Completion{}
+	return context.WithValue(ctx, completionKey{}, c), c
+}
+
+// recordCompletion fills the Completion captured by ctx, if any.
+func recordCompletion(ctx context.Context, c Completion) {
+	if dst, ok := ctx.Value(completionKey{}).(*Completion); ok {
+		*dst = c
+	}
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"helixops/internal/config"
+)
+
+// limitedProvider holds a slot of a semaphore for the duration of each request, so no more than the
+// semaphore's capacity of requests are in flight at once. Callers beyond that wait for a free slot.
+type limitedProvider struct {
+	Provider
+	slots chan struct{}
+}
+
+// accountSlots are the semaphores shared by every provider built for the same account, so the RCA
+// and postmortem providers of all receivers and profiles draw from one limit.
+var (
+	accountSlotsMu sync.Mutex
+	accountSlots   = make(map[string]chan struct{})
+)
+
+// WithConcurrencyLimit wraps p so at most limit requests are in flight at once; limit <= 0 returns p unchanged.
+func WithConcurrencyLimit(p Provider, limit int) Provider {
+	if limit <= 0 {
+		return p
+	}
+	return &limitedProvider{Provider: p, slots: make(chan struct{}, limit)}
+}
+
+// withAccountConcurrencyLimit wraps p with the semaphore shared by every provider configured with the
+// same backend, endpoint, and API key. The semaphore is sized by the first llm.max_concurrent_requests
+// seen for the account; Config.Validate rejects settings that disagree on it.
+func withAccountConcurrencyLimit(p Provider, cfg config.LLMConfig) Provider {
+	if cfg.MaxConcurrentRequests <= 0 {
+		return p
+	}
+
+	accountSlotsMu.Lock()
+	defer accountSlotsMu.Unlock()
+	slots, ok := accountSlots[cfg.Account()]
+	if !ok {
+		slots = make(chan struct{}, cfg.MaxConcurrentRequests)
+		accountSlots[cfg.Account()] = slots
+	}
+	return &limitedProvider{Provider: p, slots: slots}
+}
+
+// Analyze waits for a free slot, then delegates to the wrapped provider.
+func (l *limitedProvider) Analyze(ctx context.Context, prompt string) (string, error) {
+	return l.limited(ctx, prompt, l.Provider.Analyze)
+}
+
+// AnalyzeJSON waits for a free slot like Analyze, then uses the wrapped provider's JSON mode.
+func (l *limitedProvider) AnalyzeJSON(ctx context.Context, prompt string) (string, error) {
+	if j, ok := l.Provider.(JSONAnalyzer); ok {
+		return l.limited(ctx, prompt, j.AnalyzeJSON)
+	}
+	return l.Analyze(ctx, prompt)
+}
+
+// Capabilities forwards the wrapped provider's capabilities, which embedding alone would hide.
+func (l *limitedProvider) Capabilities() Capabilities {
+	return CapabilitiesOf(l.Provider)
+}
+
+func (l *limitedProvider) limited(ctx context.Context, prompt string, analyze func(context.Context, string) (string, error)) (string, error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for a free %s request slot: %w", l.Name(), ctx.Err())
+	}
+	defer func() { <-l.slots }()
+	return analyze(ctx, prompt)
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxConcurrentRequestsQueuesExcessCalls(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		json.NewEncoder(w).Encode(OpenAIChatResponse{Choices: []Choice{{Message: Message{Role: "assistant", Content: "ok"}}}})
+	}))
+	defer server.Close()
+
+	cfg := config.LLMConfig{Provider: "openai", Model: "gpt-4o", BaseURL: server.URL, APIKey: "test-key", MaxConcurrentRequests: 2}
+	// the RCA and postmortem providers are built separately but share the account's limit
+	rca, err := NewProvider(cfg)
+	require.NoError(t, err)
+	postmortem, err := NewProvider(cfg.ForPostmortem())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		p := rca
+		if i%2 == 1 {
+			p = postmortem
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := p.Analyze(context.Background(), "prompt")
+			assert.NoError(t, err)
+			assert.Equal(t, "ok", result)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), peak.Load(), "excess requests queue instead of exceeding the limit")
+}
+
+func TestConcurrencyLimitGivesUpWhenContextEnds(t *testing.T) {
+	release := make(chan struct{})
+	p := WithConcurrencyLimit(blockingProvider{release: release}, 1)
+
+	go p.Analyze(context.Background(), "holds the only slot")
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := p.Analyze(ctx, "queued")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	close(release)
+}
+
+func TestConcurrencyLimitDisabled(t *testing.T) {
+	fake := NewFakeProvider("ok")
+	assert.Same(t, fake, WithConcurrencyLimit(fake, 0))
+}
+
+// blockingProvider answers only once release is closed.
+type blockingProvider struct {
+	release chan struct{}
+}
+
+func (b blockingProvider) Analyze(ctx context.Context, prompt string) (string, error) {
+	<-b.release
+	return "ok", nil
+}
+
+func (b blockingProvider) Name() string { return "blocking" }
+
+func TestAccountLimitIsSharedAcrossSettings(t *testing.T) {
+	cfg := config.LLMConfig{Provider: "openai", Model: "gpt-4o", BaseURL: "http://shared.example", APIKey: "shared-key", MaxConcurrentRequests: 1}
+	other := cfg
+	other.Model, other.MaxConcurrentRequests = "gpt-4.1", 3
+	first := withAccountConcurrencyLimit(NewFakeProvider("ok"), cfg).(*limitedProvider)
+	second := withAccountConcurrencyLimit(NewFakeProvider("ok"), other).(*limitedProvider)
+
+	assert.Equal(t, first.slots, second.slots, "settings for one account draw from one semaphore")
+	assert.Equal(t, 1, cap(second.slots))
+}
@@ -0,0 +1,13 @@
+package llm
+
+import "unicode/utf8"
+
+// charsPerToken is the rough ratio of characters to tokens for English text and Markdown across the
+// GPT and Claude tokenizers.
+const charsPerToken = 4
+
+// EstimateTokens approximates how many tokens text costs without running a real tokenizer. It is
+// meant for sizing prompts before they are sent; providers report the exact count afterwards.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
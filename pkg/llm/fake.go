@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeProvider is an in-memory Provider used to exercise analyzer, postmortem, and server
+// logic without an HTTP backend. It returns canned responses, injects errors, and records prompts.
+type FakeProvider struct {
+	// Responses are returned in call order; the last response is repeated once exhausted.
+	Responses []string
+	// Errors are returned per call index; a nil entry lets that call succeed.
+	Errors []error
+	// Err, when set, fails every call regardless of Errors.
+	Err error
+	// AnalyzeFunc, when set, replaces the canned behaviour entirely (e.g. to block or inspect ctx).
+	AnalyzeFunc func(ctx context.Context, prompt string) (string, error)
+	// Caps is reported by Capabilities; the zero value keeps callers on the plain-text path.
+	Caps Capabilities
+
+	mu        sync.Mutex
+	prompts   []string
+	jsonCalls int
+}
+
+// NewFakeProvider returns a FakeProvider that replies with the given responses in order.
+func NewFakeProvider(responses ...string) *FakeProvider {
+	return &FakeProvider{Responses: responses}
+}
+
+// Analyze records the prompt and returns the next canned response or injected error.
+func (f *FakeProvider) Analyze(ctx context.Context, prompt string) (string, error) {
+	f.mu.Lock()
+	call := len(f.prompts)
+	f.prompts = append(f.prompts, prompt)
+	fn := f.AnalyzeFunc
+	f.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, prompt)
+	}
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if call < len(f.Errors) && f.Errors[call] != nil {
+		return "", f.Errors[call]
+	}
+	var response string
+	switch {
+	case len(f.Responses) == 0:
+	case call >= len(f.Responses):
+		response = f.Responses[len(f.Responses)-1]
+	default:
+		response = f.Responses[call]
+	}
+	recordCompletion(ctx, Completion{Model: "fake", Response: response})
+	return response, nil
+}
+
+// AnalyzeJSON counts a JSON-mode call and otherwise behaves like Analyze.
+func (f *FakeProvider) AnalyzeJSON(ctx context.Context, prompt string) (string, error) {
+	f.mu.Lock()
+	f.jsonCalls++
+	f.mu.Unlock()
+	return f.Analyze(ctx, prompt)
+}
+
+// Capabilities returns Caps.
+func (f *FakeProvider) Capabilities() Capabilities {
+	return f.Caps
+}
+
+// JSONCallCount reports how many calls went through AnalyzeJSON.
+func (f *FakeProvider) JSONCallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jsonCalls
+}
+
+// Name identifies this provider instance as "fake".
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Prompts returns a copy of every prompt received so far.
+func (f *FakeProvider) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
+
+// CallCount reports how many times Analyze has been invoked.
+func (f *FakeProvider) CallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.prompts)
+}
+
+// LastPrompt returns the most recent prompt, or an empty string if none was sent.
+func (f *FakeProvider) LastPrompt() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.prompts) == 0 {
+		return ""
+	}
+	return f.prompts[len(f.prompts)-1]
+}
@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeProviderSequencing(t *testing.T) {
+	fake := NewFakeProvider("first", "second")
+	fake.Errors = []error{errors.New("transient")}
+
+	_, err := fake.Analyze(context.Background(), "p1")
+	assert.Error(t, err)
+
+	out, err := fake.Analyze(context.Background(), "p2")
+	require.NoError(t, err)
+	assert.Equal(t, "second", out)
+
+	out, err = fake.Analyze(context.Background(), "p3")
+	require.NoError(t, err)
+	assert.Equal(t, "second", out)
+	assert.Equal(t, []string{"p1", "p2", "p3"}, fake.Prompts())
+	assert.Equal(t, "p3", fake.LastPrompt())
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// loggingProvider logs every prompt and raw response at debug level, redacted, for diagnosing
+// poor RCA quality. Enabled with llm.log_prompts.
+type loggingProvider struct {
+	Provider
+	logger *slog.Logger
+}
+
+// WithPromptLogging wraps p so prompts and responses are logged when enabled; otherwise p is returned unchanged.
+func WithPromptLogging(p Provider, enabled bool, logger *slog.Logger) Provider {
+	if !enabled {
+		return p
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &loggingProvider{Provider: p, logger: logger}
+}
+
+// Analyze logs the redacted prompt, delegates to the wrapped provider, and logs the redacted response.
+func (l *loggingProvider) Analyze(ctx context.Context, prompt string) (string, error) {
+	return l.logged(ctx, prompt, l.Provider.Analyze)
+}
+
+// AnalyzeJSON logs like Analyze around the wrapped provider's JSON mode. Capabilities only reports
+// JSON mode when the wrapped provider implements it, so callers never reach the fallback.
+func (l *loggingProvider) AnalyzeJSON(ctx context.Context, prompt string) (string, error) {
+	if j, ok := l.Provider.(JSONAnalyzer); ok {
+		return l.logged(ctx, prompt, j.AnalyzeJSON)
+	}
+	return l.Analyze(ctx, prompt)
+}
+
+// Capabilities forwards the wrapped provider's capabilities, which embedding alone would hide.
+func (l *loggingProvider) Capabilities() Capabilities {
+	return CapabilitiesOf(l.Provider)
+}
+
+func (l *loggingProvider) logged(ctx context.Context, prompt string, analyze func(context.Context, string) (string, error)) (string, error) {
+	l.logger.DebugContext(ctx, "LLM prompt", "provider", l.Name(), "prompt", Redact(prompt))
+
+	start := time.Now()
+	response, err := analyze(ctx, prompt)
+	if err != nil {
+		l.logger.DebugContext(ctx, "LLM request failed", "provider", l.Name(), "duration", time.Since(start), "error", err)
+		return response, err
+	}
+
+	l.logger.DebugContext(ctx, "LLM response", "provider", l.Name(), "duration", time.Since(start), "response", Redact(response))
+	return response, nil
+}
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"helixops/internal/config"
+	"helixops/internal/httpx"
 )
 
 // OllamaProvider implements the Provider interface for interacting with localized Ollama instances.
@@ -18,7 +20,9 @@ type OllamaProvider struct {
 	url         string
 	model       string
 	temperature float64
+	maxTokens   int
 	client      *http.Client
+	retryDelay  time.Duration // wait before HasModel retries a failed model listing
 }
 
 // OllamaRequest models the payload for the Ollama /api/generate endpoint.
@@ -27,12 +31,23 @@ type OllamaRequest struct {
 	Prompt      string  `json:"prompt"`
 	Temperature float64 `json:"temperature,omitempty"`
 	Stream      bool    `json:"stream,omitempty"`
+	// Format is "json" to constrain the reply to a JSON object
+	Format  string         `json:"format,omitempty"`
+	Options *OllamaOptions `json:"options,omitempty"`
+}
+
+// OllamaOptions holds the model parameters of a generate request.
+type OllamaOptions struct {
+	// NumPredict caps the number of tokens generated
+	NumPredict int `json:"num_predict,omitempty"`
 }
 
 // OllamaResponse captures the results from the Ollama /api/generate endpoint.
 type OllamaResponse struct {
-	Response        string `json:"response"`
-	Done            bool   `json:"done"`
+	Response string `json:"response"`
+	Done     bool   `json:"done"`
+	// DoneReason is "length" when the num_predict limit cut the reply off
+	DoneReason      string `json:"done_reason,omitempty"`
 	TotalDuration   int64  `json:"total_duration,omitempty"`
 	LoadDuration    int64  `json:"load_duration,omitempty"`
 	SampleCount     int64  `json:"sample_count,omitempty"`
@@ -42,7 +57,7 @@ type OllamaResponse struct {
 }
 
 // NewOllamaProvider initializes the Ollama integration with the given host URL and model parameters.
-func NewOllamaProvider(url, model string, temperature float64) (*OllamaProvider, error) {
+func NewOllamaProvider(url, model string, temperature float64, maxTokens int) (*OllamaProvider, error) {
 	if url == "" {
 		url = "http://localhost:11434"
 	}
@@ -51,24 +66,45 @@ func NewOllamaProvider(url, model string, temperature float64) (*OllamaProvider,
 	}
 
 	url = strings.TrimSuffix(url, "/")
+	maxTokens, err := resolveMaxTokens(ProviderOllama, model, maxTokens)
+	if err != nil {
+		return nil, err
+	}
 
 	return &OllamaProvider{
 		url:         url,
 		model:       model,
 		temperature: temperature,
-		client: &http.Client{
-			Timeout: 600 * time.Second, // 10 minutes for CPU-only inference
-		},
+		maxTokens:   maxTokens,
+		client:      httpx.NewClient(600 * time.Second), // 10 minutes for CPU-only inference
+		retryDelay:  time.Second,
 	}, nil
 }
 
 // Analyze issues a prompt to the configured local Ollama instance and returns the generated diagnostic response.
 func (p *OllamaProvider) Analyze(ctx context.Context, prompt string) (string, error) {
+	return p.generate(ctx, prompt, "")
+}
+
+// AnalyzeJSON is Analyze with the reply constrained to a single JSON object.
+func (p *OllamaProvider) AnalyzeJSON(ctx context.Context, prompt string) (string, error) {
+	return p.generate(ctx, prompt, "json")
+}
+
+// Capabilities reports what the configured model supports, from the capability registry.
+func (p *OllamaProvider) Capabilities() Capabilities {
+	return LookupCapabilities(ProviderOllama, p.model)
+}
+
+// generate sends one non-streaming generate request in the given output format ("" for free text).
+func (p *OllamaProvider) generate(ctx context.Context, prompt, format string) (string, error) {
 	req := OllamaRequest{
 		Model:       p.model,
 		Prompt:      prompt,
 		Temperature: p.temperature,
 		Stream:      false,
+		Format:      format,
+		Options:     &OllamaOptions{NumPredict: p.maxTokens},
 	}
 
 	body, err := json.Marshal(req)
@@ -99,6 +135,19 @@ func (p *OllamaProvider) Analyze(ctx context.Context, prompt string) (string, er
 		return "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	if err := checkReply(p.Name(), ollamaResp.Response, ollamaResp.DoneReason, "length"); err != nil {
+		return "", err
+	}
+
+	recordCompletion(ctx, Completion{
+		Model:    p.model,
+		Response: ollamaResp.Response,
+		Usage: Usage{
+			PromptTokens:     int(ollamaResp.PromptEvalCount),
+			CompletionTokens: int(ollamaResp.EvalCount),
+			TotalTokens:      int(ollamaResp.PromptEvalCount + ollamaResp.EvalCount),
+		},
+	})
 	return ollamaResp.Response, nil
 }
 
@@ -133,6 +182,7 @@ func (p *OllamaProvider) Health(ctx context.Context) error {
 }
 
 // ListModels queries the local Ollama daemon for all currently pulled models.
+// Entries without a name are skipped rather than failing the whole listing.
 func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"/api/tags", nil)
 	if err != nil {
@@ -145,6 +195,10 @@ func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama returned status: %d", resp.StatusCode)
+	}
+
 	var result struct {
 		Models []struct {
 			Name string `json:"name"`
@@ -155,15 +209,52 @@ func (p *OllamaProvider) ListModels(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	models := make([]string, len(result.Models))
-	for i, m := range result.Models {
-		models[i] = m.Name
+	models := make([]string, 0, len(result.Models))
+	for _, m := range result.Models {
+		if m.Name != "" {
+			models = append(models, m.Name)
+		}
 	}
 
 	return models, nil
 }
 
+// HasModel reports whether the named model has been pulled. An untagged name matches its
+// ":latest" tag, as Ollama resolves it. A failed listing is retried once before giving up.
+func (p *OllamaProvider) HasModel(ctx context.Context, name string) (bool, error) {
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		select {
+		case <-ctx.Done():
+			return false, err
+		case <-time.After(p.retryDelay):
+		}
+		if models, err = p.ListModels(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	for _, m := range models {
+		if m == name || (!strings.Contains(name, ":") && m == name+":latest") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyModel warns when the configured model has not been pulled; analysis requests would
+// otherwise fail later with a less obvious error. It never fails provider construction.
+func (p *OllamaProvider) verifyModel(ctx context.Context) {
+	ok, err := p.HasModel(ctx, p.model)
+	switch {
+	case err != nil:
+		slog.Warn("Could not verify Ollama model", "model", p.model, "url", p.url, "error", err)
+	case !ok:
+		slog.Warn("Configured Ollama model is not pulled; run `ollama pull`", "model", p.model, "url", p.url)
+	}
+}
+
 // NewOllamaProviderFromConfig constructs an OllamaProvider using a standard LLMConfig block.
 func NewOllamaProviderFromConfig(cfg config.LLMConfig) (*OllamaProvider, error) {
-	return NewOllamaProvider(cfg.OllamaURL, cfg.OllamaModel, cfg.Temperature)
+	return NewOllamaProvider(cfg.OllamaURL, cfg.OllamaModel, cfg.Temperature, cfg.MaxTokens)
 }
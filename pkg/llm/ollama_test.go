@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ollamaTags = `{"models": [{"name": "qwen2.5:0.5b"}, {"name": "llama3:latest"}, {"name": ""}]}`
+
+func ollamaTagsServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(ollamaTags))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestOllamaListModelsSkipsUnnamedEntries(t *testing.T) {
+	server, _ := ollamaTagsServer(t, 0)
+	p, err := NewOllamaProvider(server.URL, "llama3", 0, 0)
+	require.NoError(t, err)
+
+	models, err := p.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qwen2.5:0.5b", "llama3:latest"}, models)
+}
+
+func TestOllamaHasModel(t *testing.T) {
+	server, _ := ollamaTagsServer(t, 0)
+	p, err := NewOllamaProvider(server.URL, "llama3", 0, 0)
+	require.NoError(t, err)
+
+	for name, want := range map[string]bool{
+		"qwen2.5:0.5b": true,
+		"llama3":       true, // resolves to llama3:latest
+		"llama3:8b":    false,
+		"mistral":      false,
+	} {
+		ok, err := p.HasModel(context.Background(), name)
+		require.NoError(t, err)
+		assert.Equal(t, want, ok, name)
+	}
+}
+
+func TestOllamaHasModelRetriesTransientFailure(t *testing.T) {
+	server, calls := ollamaTagsServer(t, 1)
+	p, err := NewOllamaProvider(server.URL, "llama3", 0, 0)
+	require.NoError(t, err)
+	p.retryDelay = 0
+
+	ok, err := p.HasModel(context.Background(), "qwen2.5:0.5b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestOllamaHasModelGivesUpAfterOneRetry(t *testing.T) {
+	server, calls := ollamaTagsServer(t, 2)
+	p, err := NewOllamaProvider(server.URL, "llama3", 0, 0)
+	require.NoError(t, err)
+	p.retryDelay = 0
+
+	_, err = p.HasModel(context.Background(), "llama3")
+	require.ErrorContains(t, err, "status: 503")
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestNewProviderWarnsWhenOllamaModelMissing(t *testing.T) {
+	server, _ := ollamaTagsServer(t, 0)
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(prev)
+
+	provider, err := NewProvider(config.LLMConfig{Provider: "ollama", OllamaURL: server.URL, OllamaModel: "mistral", VerifyModel: true})
+	require.NoError(t, err, "a missing model warns but does not fail construction")
+	assert.Equal(t, "ollama", provider.Name())
+	assert.Contains(t, buf.String(), "Configured Ollama model is not pulled")
+	assert.Contains(t, buf.String(), "model=mistral")
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"helixops/internal/config"
+	"helixops/internal/httpx"
 )
 
 // OpenAIProvider implements the Provider interface for interacting with the OpenAI API.
@@ -33,6 +35,13 @@ type OpenAIChatRequest struct {
 	Messages    []Message `json:"messages"`
 	Temperature float64   `json:"temperature,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
+	// ResponseFormat constrains the reply to a JSON object when set; only models with JSON mode accept it
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat selects the output format of a chat completion, e.g. "json_object".
+type ResponseFormat struct {
+	Type string `json:"type"`
 }
 
 // Message defines a single conversational turn in the prompt.
@@ -58,13 +67,16 @@ type Choice struct {
 	FinishReason string  `json:"finish_reason"`
 }
 
-// Usage tracks the token consumption for a given API request.
+// Usage tracks the token consumption for a given API request; other providers report theirs in the same shape.
 type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// defaultOpenAIBaseURL is the public OpenAI API root used when no custom base URL is configured.
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
 // NewOpenAIProvider initializes the OpenAI integration with the given authentication and model parameters.
 func NewOpenAIProvider(apiKey, model string, temperature float64, maxTokens int) (*OpenAIProvider, error) {
 	if apiKey == "" {
@@ -73,14 +85,16 @@ func NewOpenAIProvider(apiKey, model string, temperature float64, maxTokens int)
 	if model == "" {
 		model = "gpt-4o"
 	}
+	maxTokens, err := resolveMaxTokens(ProviderOpenAI, model, maxTokens)
+	if err != nil {
+		return nil, err
+	}
 
 	return &OpenAIProvider{
 		client: &OpenAIClient{
 			apiKey:  apiKey,
-			baseURL: "https://api.openai.com/v1",
-			client: &http.Client{
-				Timeout: 60 * time.Second,
-			},
+			baseURL: defaultOpenAIBaseURL,
+			client:  httpx.NewClient(60 * time.Second),
 		},
 		model:       model,
 		temperature: temperature,
@@ -88,14 +102,51 @@ func NewOpenAIProvider(apiKey, model string, temperature float64, maxTokens int)
 	}, nil
 }
 
+// NewOpenAICompatibleProvider initializes an OpenAI provider that targets any server speaking the
+// OpenAI chat completions API (vLLM, LocalAI, Together). An empty baseURL keeps the OpenAI default.
+func NewOpenAICompatibleProvider(baseURL, apiKey, model string, temperature float64, maxTokens int) (*OpenAIProvider, error) {
+	p, err := NewOpenAIProvider(apiKey, model, temperature, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/"); baseURL != "" {
+		p.client.baseURL = baseURL
+	}
+	return p, nil
+}
+
+// BaseURL returns the API root requests are sent to.
+func (p *OpenAIProvider) BaseURL() string {
+	return p.client.baseURL
+}
+
 // Analyze issues a prompt to the configured OpenAI model and returns the generated diagnostic response.
 func (p *OpenAIProvider) Analyze(ctx context.Context, prompt string) (string, error) {
+	return p.complete(ctx, prompt, false)
+}
+
+// AnalyzeJSON is Analyze with the reply constrained to a single JSON object via response_format.
+func (p *OpenAIProvider) AnalyzeJSON(ctx context.Context, prompt string) (string, error) {
+	return p.complete(ctx, prompt, true)
+}
+
+// Capabilities reports what the configured model supports, from the capability registry.
+func (p *OpenAIProvider) Capabilities() Capabilities {
+	return LookupCapabilities(ProviderOpenAI, p.model)
+}
+
+// complete sends one chat completion, asking for a JSON object when jsonMode is set.
+func (p *OpenAIProvider) complete(ctx context.Context, prompt string, jsonMode bool) (string, error) {
+	system := "You are an SRE assistant analyzing incidents."
+	if jsonMode {
+		system += " Respond with JSON only."
+	}
 	req := OpenAIChatRequest{
 		Model: p.model,
 		Messages: []Message{
 			{
 				Role:    "system",
-				Content: "You are an SRE assistant analyzing incidents. Respond with JSON only.",
+				Content: system,
 			},
 			{
 				Role:    "user",
@@ -105,6 +156,9 @@ func (p *OpenAIProvider) Analyze(ctx context.Context, prompt string) (string, er
 		Temperature: p.temperature,
 		MaxTokens:   p.maxTokens,
 	}
+	if jsonMode {
+		req.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -138,7 +192,16 @@ func (p *OpenAIProvider) Analyze(ctx context.Context, prompt string) (string, er
 	if len(chatResp.Choices) == 0 {
 		return "", fmt.Errorf("no choices in response")
 	}
+	// "length" means max_tokens cut the reply off; "content_filter" means it was withheld
+	if err := checkReply(p.Name(), chatResp.Choices[0].Message.Content, chatResp.Choices[0].FinishReason, "length", "content_filter"); err != nil {
+		return "", err
+	}
 
+	model := chatResp.Model
+	if model == "" {
+		model = p.model // some OpenAI-compatible servers omit it
+	}
+	recordCompletion(ctx, Completion{Model: model, Response: chatResp.Choices[0].Message.Content, Usage: chatResp.Usage})
 	return chatResp.Choices[0].Message.Content, nil
 }
 
@@ -154,5 +217,5 @@ func (p *OpenAIProvider) GetModel() string {
 
 // NewOpenAIProviderFromConfig constructs an OpenAIProvider using a standard LLMConfig block.
 func NewOpenAIProviderFromConfig(cfg config.LLMConfig) (*OpenAIProvider, error) {
-	return NewOpenAIProvider(cfg.APIKey, cfg.Model, cfg.Temperature, cfg.MaxTokens)
+	return NewOpenAICompatibleProvider(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Temperature, cfg.MaxTokens)
 }
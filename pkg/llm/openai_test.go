@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"helixops/internal/config"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -96,6 +98,35 @@ func TestOpenAIProviderNoChoices(t *testing.T) {
 	assert.Contains(t, err.Error(), "no choices")
 }
 
+func TestOpenAIProviderRejectsEmptyOrCutOffReplies(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice Choice
+		want   string
+	}{
+		{"empty content", Choice{Message: Message{Role: "assistant", Content: ""}, FinishReason: "stop"}, "no content"},
+		{"whitespace content", Choice{Message: Message{Role: "assistant", Content: " \n\t"}}, "no content"},
+		{"cut off at max_tokens", Choice{Message: Message{Role: "assistant", Content: "# Incident Analysis: Pool exh"}, FinishReason: "length"}, `stop reason "length"`},
+		{"content filtered", Choice{Message: Message{Role: "assistant", Content: ""}, FinishReason: "content_filter"}, `stop reason "content_filter"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(OpenAIChatResponse{Choices: []Choice{tt.choice}})
+			}))
+			defer server.Close()
+
+			provider, err := NewOpenAIProvider("test-key", "gpt-4o", 0.1, 1000)
+			require.NoError(t, err)
+			provider.client.baseURL = server.URL
+
+			_, err = provider.Analyze(context.Background(), "Test prompt")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
 func TestOpenAIProviderName(t *testing.T) {
 	provider, err := NewOpenAIProvider("test-key", "gpt-4o", 0.1, 1000)
 	require.NoError(t, err)
@@ -108,3 +139,59 @@ func TestNewOpenAIProviderMissingKey(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "API key is required")
 }
+
+func TestOpenAIProviderCustomBaseURL(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OpenAIChatResponse{
+			Choices: []Choice{{Message: Message{Role: "assistant", Content: "from vllm"}}},
+		})
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(config.LLMConfig{
+		Provider: "openai",
+		Model:    "meta-llama/Llama-3-8b",
+		APIKey:   "local-key",
+		BaseURL:  server.URL + "/v1/",
+	})
+	require.NoError(t, err)
+
+	openai, ok := provider.(*OpenAIProvider)
+	require.True(t, ok)
+	assert.Equal(t, server.URL+"/v1", openai.BaseURL())
+
+	result, err := provider.Analyze(context.Background(), "Test prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "from vllm", result)
+	assert.Equal(t, 1, hits)
+}
+
+func TestOpenAIProviderDefaultBaseURL(t *testing.T) {
+	provider, err := NewOpenAIProviderFromConfig(config.LLMConfig{APIKey: "k"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.openai.com/v1", provider.BaseURL())
+}
+
+func TestOpenAIProviderCapturesCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OpenAIChatResponse{
+			Model:   "gpt-4o-2024-08-06",
+			Choices: []Choice{{Message: Message{Content: "analysis"}}},
+			Usage:   Usage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150},
+		})
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAICompatibleProvider(server.URL, "key", "gpt-4o", 0, 0)
+	require.NoError(t, err)
+
+	ctx, completion := CaptureCompletion(context.Background())
+	_, err = provider.Analyze(ctx, "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, Completion{Model: "gpt-4o-2024-08-06", Response: "analysis", Usage: Usage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150}}, *completion)
+}
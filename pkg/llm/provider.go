@@ -4,6 +4,8 @@ package llm
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"helixops/internal/config"
 )
@@ -23,18 +25,110 @@ const (
 	ProviderOllama    ProviderType = "ollama"
 )
 
+// SupportedProviders lists every llm.provider value NewProvider accepts.
+var SupportedProviders = []ProviderType{ProviderOpenAI, ProviderAnthropic, ProviderOllama}
+
+// providerAliases maps common model or vendor names to the provider that serves them.
+var providerAliases = map[string]ProviderType{
+	"claude":  ProviderAnthropic,
+	"gpt":     ProviderOpenAI,
+	"chatgpt": ProviderOpenAI,
+	"llama":   ProviderOllama,
+}
+
+// UnsupportedProviderError is returned by NewProvider for an unknown llm.provider value.
+type UnsupportedProviderError struct {
+	Provider string
+	// Suggestion is the closest supported provider, or empty when nothing is close
+	Suggestion ProviderType
+}
+
+func (e *UnsupportedProviderError) Error() string {
+	valid := make([]string, len(SupportedProviders))
+	for i, p := range SupportedProviders {
+		valid[i] = string(p)
+	}
+	msg := fmt.Sprintf("unsupported provider: %s (valid providers: %s)", e.Provider, strings.Join(valid, ", "))
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// suggestProvider returns the supported provider closest to name by edit distance, or empty when
+// even the closest one needs more than a third of the name changed.
+func suggestProvider(name string) ProviderType {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := providerAliases[name]; ok {
+		return alias
+	}
+
+	var best ProviderType
+	bestDist := len(name)/3 + 1
+	for _, p := range SupportedProviders {
+		if d := editDistance(name, string(p)); d < bestDist {
+			best, bestDist = p, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// verifyModelTimeout bounds the startup check that the configured Ollama model is pulled.
+const verifyModelTimeout = 10 * time.Second
+
 // NewProvider evaluates the configuration to instantiate and route to the correct LLM backend implementation.
+// With llm.log_prompts enabled the provider is wrapped to log redacted prompts and responses at debug level.
+// With llm.max_concurrent_requests set, requests beyond the limit queue until one to the same account finishes.
 func NewProvider(cfg config.LLMConfig) (Provider, error) {
+	p, err := newBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return withAccountConcurrencyLimit(WithPromptLogging(p, cfg.LogPrompts, nil), cfg), nil
+}
+
+// newBackend instantiates the configured provider implementation.
+func newBackend(cfg config.LLMConfig) (Provider, error) {
 	providerType := ProviderType(cfg.ProviderType())
 
 	switch providerType {
 	case ProviderOpenAI:
-		return NewOpenAIProvider(cfg.APIKey, cfg.Model, cfg.Temperature, cfg.MaxTokens)
+		return NewOpenAICompatibleProvider(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Temperature, cfg.MaxTokens)
 	case ProviderAnthropic:
 		return NewAnthropicProvider(cfg.APIKey, cfg.Model, cfg.Temperature, cfg.MaxTokens)
 	case ProviderOllama:
-		return NewOllamaProvider(cfg.OllamaURL, cfg.OllamaModel, cfg.Temperature)
+		p, err := NewOllamaProvider(cfg.OllamaURL, cfg.OllamaModel, cfg.Temperature, cfg.MaxTokens)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.VerifyModel {
+			ctx, cancel := context.WithTimeout(context.Background(), verifyModelTimeout)
+			defer cancel()
+			p.verifyModel(ctx)
+		}
+		return p, nil
 	default:
-		return nil, fmt.Errorf("unsupported provider: %s", cfg.Provider)
+		return nil, &UnsupportedProviderError{Provider: cfg.Provider, Suggestion: suggestProvider(cfg.Provider)}
 	}
 }
@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"testing"
+
+	"helixops/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProviderSuggestsClosestProvider(t *testing.T) {
+	_, err := NewProvider(config.LLMConfig{Provider: "anthropics"})
+	var unsupported *UnsupportedProviderError
+	require.ErrorAs(t, err, &unsupported)
+	assert.Equal(t, ProviderAnthropic, unsupported.Suggestion)
+	assert.Equal(t, `unsupported provider: anthropics (valid providers: openai, anthropic, ollama); did you mean "anthropic"?`, err.Error())
+
+	tests := map[string]ProviderType{
+		"opnai":   ProviderOpenAI,
+		"Claude":  ProviderAnthropic,
+		"olama":   ProviderOllama,
+		"bedrock": "",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, suggestProvider(name), name)
+	}
+}
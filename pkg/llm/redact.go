@@ -0,0 +1,39 @@
+package llm
+
+import "regexp"
+
+type redaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// secretRedactions mask credentials.
+var secretRedactions = []redaction{
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]+`), "$1 [REDACTED]"},
+	{regexp.MustCompile(`\b(sk-ant-|sk-|ghp_|gho_|github_pat_|xox[abpr]-)[A-Za-z0-9_-]{8,}`), "${1}[REDACTED]"},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), "[REDACTED_AWS_KEY]"},
+	{regexp.MustCompile(`(?i)\b(password|passwd|secret|token|api[_-]?key)(["']?\s*[:=]\s*["']?)[^\s"',]+`), "$1$2[REDACTED]"},
+}
+
+// personalRedactions mask personal data and addresses before text is written to logs.
+var personalRedactions = []redaction{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[REDACTED_EMAIL]"},
+	{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), "[REDACTED_IP]"},
+}
+
+// Redact masks credentials, email addresses, and IP addresses in s.
+func Redact(s string) string {
+	return redact(RedactSecrets(s), personalRedactions)
+}
+
+// RedactSecrets masks credentials in s only, leaving addresses such as instance labels intact.
+func RedactSecrets(s string) string {
+	return redact(s, secretRedactions)
+}
+
+func redact(s string, redactions []redaction) string {
+	for _, r := range redactions {
+		s = r.pattern.ReplaceAllString(s, r.replacement)
+	}
+	return s
+}